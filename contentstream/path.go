@@ -0,0 +1,75 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package contentstream
+
+import (
+	"math"
+
+	"github.com/unidoc/unipdf/v3/internal/transform"
+)
+
+// PathSegmentType represents the type of a path segment.
+type PathSegmentType int
+
+// Path segment types.
+const (
+	PathSegmentMoveTo  PathSegmentType = iota // Begins a new subpath at Points[0].
+	PathSegmentLineTo                         // Straight line to Points[0].
+	PathSegmentCurveTo                        // Cubic Bézier curve with control points Points[0], Points[1] to Points[2].
+	PathSegmentClose                          // Closes the current subpath. Has no points.
+)
+
+// PathSegment is a single segment of a Path.
+type PathSegment struct {
+	Type   PathSegmentType
+	Points []transform.Point
+}
+
+// Path is a path built by the path construction operators (m, l, c, v, y, h, re).
+// The coordinates of a path tracked by the ContentStreamProcessor are in device space, i.e. they
+// have been transformed by the CTM in effect when the path was constructed.
+type Path struct {
+	Segments []PathSegment
+}
+
+// Empty returns true if `p` has no segments.
+func (p *Path) Empty() bool {
+	return len(p.Segments) == 0
+}
+
+// Copy returns a deep copy of `p`.
+func (p *Path) Copy() Path {
+	segs := make([]PathSegment, len(p.Segments))
+	for i, seg := range p.Segments {
+		segs[i] = PathSegment{Type: seg.Type, Points: append([]transform.Point(nil), seg.Points...)}
+	}
+	return Path{Segments: segs}
+}
+
+// Bounds returns the bounding box of the points of `p` (including the Bézier control points) as
+// llx, lly, urx, ury. All values are zero for an empty path.
+func (p *Path) Bounds() (llx, lly, urx, ury float64) {
+	llx, lly = math.Inf(1), math.Inf(1)
+	urx, ury = math.Inf(-1), math.Inf(-1)
+	for _, seg := range p.Segments {
+		for _, pt := range seg.Points {
+			llx, lly = math.Min(llx, pt.X), math.Min(lly, pt.Y)
+			urx, ury = math.Max(urx, pt.X), math.Max(ury, pt.Y)
+		}
+	}
+	if math.IsInf(llx, 1) {
+		return 0, 0, 0, 0
+	}
+	return llx, lly, urx, ury
+}
+
+// ClippingPath is a path that has been intersected with the clipping path by a W or W* operator.
+type ClippingPath struct {
+	Path Path
+	// EvenOdd is true if the inside of Path is determined by the even-odd rule (W*) rather than
+	// the nonzero winding number rule (W).
+	EvenOdd bool
+}
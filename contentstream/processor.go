@@ -14,14 +14,90 @@ import (
 	"github.com/unidoc/unipdf/v3/model"
 )
 
-// GraphicsState is a basic graphics state implementation for PDF processing.
-// Initially only implementing and tracking a portion of the information specified. Easy to add more.
+// GraphicsState is the PDF graphics state (8.4 Graphics State, p. 121) maintained by the
+// ContentStreamProcessor. Device-dependent parameters without an effect on the page description
+// (transfer functions, halftones, black generation and undercolor removal) are not tracked.
 type GraphicsState struct {
 	ColorspaceStroking    model.PdfColorspace
 	ColorspaceNonStroking model.PdfColorspace
 	ColorStroking         model.PdfColor
 	ColorNonStroking      model.PdfColor
 	CTM                   transform.Matrix
+
+	// Line parameters. Set by the w, J, j, M and d operators or an ExtGState.
+	LineWidth  float64
+	LineCap    int
+	LineJoin   int
+	MiterLimit float64
+	DashArray  []float64
+	DashPhase  float64
+
+	RenderingIntent  core.PdfObjectName // Set by ri or an ExtGState.
+	Flatness         float64            // Set by i or an ExtGState.
+	StrokeAdjustment bool               // SA.
+
+	// Transparency and overprint parameters. Set by an ExtGState (gs operator).
+	StrokeAlpha          float64                   // CA.
+	FillAlpha            float64                   // ca.
+	BlendMode            core.PdfObjectName        // BM.
+	SoftMask             *core.PdfObjectDictionary // SMask. nil for None.
+	AlphaIsShape         bool                      // AIS.
+	OverprintStroking    bool                      // OP.
+	OverprintNonStroking bool                      // op.
+	OverprintMode        int                       // OPM.
+
+	// ClipPath is the current clipping path: the intersection of the clipping paths set by W and
+	// W* in device space. An empty ClipPath does not clip. Glyph outlines added to the clipping
+	// path by the text rendering modes 4-7 are not tracked.
+	ClipPath []ClippingPath
+
+	TextState TextState
+}
+
+// TextState holds the text state parameters (9.3 Text State Parameters and Operators, p. 243)
+// together with the text matrices of the current text object.
+type TextState struct {
+	CharSpacing       float64            // Tc.
+	WordSpacing       float64            // Tw.
+	HorizontalScaling float64            // Tz. Percentage, 100 for no scaling.
+	Leading           float64            // TL.
+	FontName          core.PdfObjectName // Resource name of the font set by Tf.
+	FontObject        core.PdfObject     // Font dictionary set by Tf or an ExtGState, nil if none.
+	Font              *model.PdfFont     // nil if no font is set or it could not be loaded.
+	FontSize          float64            // Tfs.
+	RenderMode        int                // Tr.
+	Rise              float64            // Ts.
+	Knockout          bool               // TK.
+
+	// Tm and Tlm are the text matrix and the text line matrix. They are not part of the graphics
+	// state and are not restored by Q. Handlers of the text showing operators (Tj, TJ, ', ")
+	// receive the text matrix of the start of the text; it is advanced after the handlers return.
+	Tm  transform.Matrix
+	Tlm transform.Matrix
+}
+
+// newGraphicsState returns the graphics state at the start of a content stream.
+func newGraphicsState() GraphicsState {
+	return GraphicsState{
+		ColorspaceStroking:    model.NewPdfColorspaceDeviceGray(),
+		ColorspaceNonStroking: model.NewPdfColorspaceDeviceGray(),
+		ColorStroking:         model.NewPdfColorDeviceGray(0),
+		ColorNonStroking:      model.NewPdfColorDeviceGray(0),
+		CTM:                   transform.IdentityMatrix(),
+		LineWidth:             1,
+		MiterLimit:            10,
+		RenderingIntent:       "RelativeColorimetric",
+		Flatness:              1,
+		StrokeAlpha:           1,
+		FillAlpha:             1,
+		BlendMode:             "Normal",
+		TextState: TextState{
+			HorizontalScaling: 100,
+			Knockout:          true,
+			Tm:                transform.IdentityMatrix(),
+			Tlm:               transform.IdentityMatrix(),
+		},
+	}
 }
 
 // GraphicStateStack represents a stack of GraphicsState.
@@ -32,7 +108,8 @@ func (gsStack *GraphicStateStack) Push(gs GraphicsState) {
 	*gsStack = append(*gsStack, gs)
 }
 
-// Pop pops and returns the topmost GraphicsState off the `gsStack`.
+// Pop pops and returns the topmost GraphicsState off the `gsStack`. It panics if `gsStack` is
+// empty.
 func (gsStack *GraphicStateStack) Pop() GraphicsState {
 	gs := (*gsStack)[len(*gsStack)-1]
 	*gsStack = (*gsStack)[:len(*gsStack)-1]
@@ -51,6 +128,16 @@ type ContentStreamProcessor struct {
 	graphicsStack GraphicStateStack
 	operations    []*ContentStreamOperation
 	graphicsState GraphicsState
+	initialState  *GraphicsState // Set by SetInitialState.
+
+	// Current path under construction, its current point and the start of its current subpath.
+	path        Path
+	pathCur     transform.Point
+	pathStart   transform.Point
+	pendingClip *ClippingPath // Set by W and W*, applied by the next path painting operator.
+
+	// fonts caches the fonts loaded by Tf and gs, keyed by the font objects.
+	fonts map[core.PdfObject]*model.PdfFont
 
 	handlers     []handlerEntry
 	currentIndex int
@@ -98,6 +185,7 @@ func NewContentStreamProcessor(ops []*ContentStreamOperation) *ContentStreamProc
 	csp.handlers = []handlerEntry{}
 	csp.currentIndex = 0
 	csp.operations = ops
+	csp.fonts = map[core.PdfObject]*model.PdfFont{}
 
 	return &csp
 }
@@ -211,15 +299,22 @@ func (proc *ContentStreamProcessor) getInitialColor(cs model.PdfColorspace) (mod
 	return nil, errors.New("unsupported colorspace")
 }
 
+// SetInitialState sets the graphics state at the start of the content stream to `gs` instead of
+// the initial graphics state of a page. Content streams painted by other content streams, such as
+// form XObjects and the glyph descriptions of Type 3 fonts, inherit the graphics state in which
+// they are painted.
+func (proc *ContentStreamProcessor) SetInitialState(gs GraphicsState) {
+	proc.initialState = &gs
+}
+
 // Process processes the entire list of operations. Maintains the graphics state that is passed to any
 // handlers that are triggered during processing (either on specific operators or all).
 func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) error {
 	// Initialize graphics state
-	proc.graphicsState.ColorspaceStroking = model.NewPdfColorspaceDeviceGray()
-	proc.graphicsState.ColorspaceNonStroking = model.NewPdfColorspaceDeviceGray()
-	proc.graphicsState.ColorStroking = model.NewPdfColorDeviceGray(0)
-	proc.graphicsState.ColorNonStroking = model.NewPdfColorDeviceGray(0)
-	proc.graphicsState.CTM = transform.IdentityMatrix()
+	proc.graphicsState = newGraphicsState()
+	if proc.initialState != nil {
+		proc.graphicsState = *proc.initialState
+	}
 
 	for _, op := range proc.operations {
 		var err error
@@ -229,7 +324,14 @@ func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) e
 		case "q":
 			proc.graphicsStack.Push(proc.graphicsState)
 		case "Q":
+			if len(proc.graphicsStack) == 0 {
+				common.Log.Debug("WARN: Q without matching q, skipping over")
+				break
+			}
+			// The text matrices belong to the text object rather than the graphics state.
+			tm, tlm := proc.graphicsState.TextState.Tm, proc.graphicsState.TextState.Tlm
 			proc.graphicsState = proc.graphicsStack.Pop()
+			proc.graphicsState.TextState.Tm, proc.graphicsState.TextState.Tlm = tm, tlm
 
 		// Color operations (Table 74 p. 179)
 		case "CS":
@@ -258,6 +360,28 @@ func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) e
 			err = proc.handleCommand_k(op, resources)
 		case "cm":
 			err = proc.handleCommand_cm(op, resources)
+
+		// Graphics state parameters (Table 57 p. 127).
+		case "w", "J", "j", "M", "d", "ri", "i":
+			proc.handleGraphicsStateParam(op)
+		case "gs":
+			proc.handleCommand_gs(op, resources)
+
+		// Path construction, painting and clipping (Tables 59, 60 and 61 p. 133-137).
+		case "m", "l", "c", "v", "y", "h", "re":
+			proc.handlePathConstruction(op)
+		case "W":
+			proc.pendingClip = &ClippingPath{}
+		case "W*":
+			proc.pendingClip = &ClippingPath{EvenOdd: true}
+
+		// Text objects, state and positioning (Tables 103, 105 and 108 p. 243-250).
+		case "BT", "Tc", "Tw", "Tz", "TL", "Tr", "Ts", "Td", "TD", "Tm", "T*":
+			proc.handleTextParam(op)
+		case "Tf":
+			proc.handleCommand_Tf(op, resources)
+		case "'", "\"":
+			proc.handleTextLine(op)
 		}
 		if err != nil {
 			common.Log.Debug("Processor handling error (%s): %v", op.Operand, err)
@@ -278,6 +402,15 @@ func (proc *ContentStreamProcessor) Process(resources *model.PdfPageResources) e
 				return err
 			}
 		}
+
+		// The text matrix is advanced after the handlers have seen the start of the text and the
+		// path is ended after the handlers have seen the path being painted.
+		switch op.Operand {
+		case "Tj", "TJ", "'", "\"":
+			proc.advanceText(op)
+		case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+			proc.handlePathPainting()
+		}
 	}
 
 	return nil
@@ -584,3 +717,487 @@ func (proc *ContentStreamProcessor) handleCommand_cm(op *ContentStreamOperation,
 
 	return nil
 }
+
+// handleGraphicsStateParam handles the operators that set the device-independent graphics state
+// parameters: w, J, j, M, d, ri and i. Malformed operands are logged and the operator is skipped
+// over, so that a single bad operator does not stop the processing.
+func (proc *ContentStreamProcessor) handleGraphicsStateParam(op *ContentStreamOperation) {
+	gs := &proc.graphicsState
+	switch op.Operand {
+	case "d":
+		if len(op.Params) != 2 {
+			common.Log.Debug("Invalid number of parameters for d: %d", len(op.Params))
+			return
+		}
+		dash, phase, err := dashFromObjects(op.Params[0], op.Params[1])
+		if err != nil {
+			common.Log.Debug("Invalid parameters for d: %v", err)
+			return
+		}
+		gs.DashArray, gs.DashPhase = dash, phase
+		return
+	case "ri":
+		if len(op.Params) != 1 {
+			common.Log.Debug("Invalid number of parameters for ri: %d", len(op.Params))
+			return
+		}
+		name, ok := core.GetName(op.Params[0])
+		if !ok {
+			common.Log.Debug("Invalid parameter for ri: %T", op.Params[0])
+			return
+		}
+		gs.RenderingIntent = *name
+		return
+	}
+
+	if len(op.Params) != 1 {
+		common.Log.Debug("Invalid number of parameters for %s: %d", op.Operand, len(op.Params))
+		return
+	}
+	val, err := core.GetNumberAsFloat(op.Params[0])
+	if err != nil {
+		common.Log.Debug("Invalid parameter for %s: %v", op.Operand, err)
+		return
+	}
+	switch op.Operand {
+	case "w":
+		gs.LineWidth = val
+	case "J":
+		gs.LineCap = int(val)
+	case "j":
+		gs.LineJoin = int(val)
+	case "M":
+		gs.MiterLimit = val
+	case "i":
+		gs.Flatness = val
+	}
+}
+
+// dashFromObjects returns the dash array and phase represented by `arrObj` and `phaseObj`.
+func dashFromObjects(arrObj, phaseObj core.PdfObject) ([]float64, float64, error) {
+	arr, ok := core.GetArray(arrObj)
+	if !ok {
+		return nil, 0, errors.New("dash array not an array")
+	}
+	dash, err := arr.ToFloat64Array()
+	if err != nil {
+		return nil, 0, err
+	}
+	phase, err := core.GetNumberAsFloat(core.TraceToDirectObject(phaseObj))
+	if err != nil {
+		return nil, 0, err
+	}
+	return dash, phase, nil
+}
+
+// gs: Set the parameters of the graphics state from an ExtGState dictionary in the resources.
+// A missing or malformed ExtGState is logged and skipped over.
+func (proc *ContentStreamProcessor) handleCommand_gs(op *ContentStreamOperation,
+	resources *model.PdfPageResources) {
+	if len(op.Params) != 1 {
+		common.Log.Debug("Invalid number of parameters for gs: %d", len(op.Params))
+		return
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		common.Log.Debug("Invalid parameter for gs: %T", op.Params[0])
+		return
+	}
+	if resources == nil {
+		common.Log.Debug("ExtGState %s used without resources", *name)
+		return
+	}
+	obj, ok := resources.GetExtGState(*name)
+	if !ok {
+		common.Log.Debug("ExtGState %s not found", *name)
+		return
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ExtGState %s not a dictionary: %T", *name, obj)
+		return
+	}
+
+	gs := &proc.graphicsState
+	number := func(key core.PdfObjectName) (float64, bool) {
+		obj := dict.Get(key)
+		if obj == nil {
+			return 0, false
+		}
+		val, err := core.GetNumberAsFloat(core.TraceToDirectObject(obj))
+		if err != nil {
+			common.Log.Debug("Invalid ExtGState %s: %v", key, err)
+			return 0, false
+		}
+		return val, true
+	}
+	boolean := func(key core.PdfObjectName) (bool, bool) {
+		obj := dict.Get(key)
+		if obj == nil {
+			return false, false
+		}
+		val, ok := core.GetBoolVal(obj)
+		if !ok {
+			common.Log.Debug("Invalid ExtGState %s: %T", key, obj)
+		}
+		return val, ok
+	}
+
+	if val, ok := number("LW"); ok {
+		gs.LineWidth = val
+	}
+	if val, ok := number("LC"); ok {
+		gs.LineCap = int(val)
+	}
+	if val, ok := number("LJ"); ok {
+		gs.LineJoin = int(val)
+	}
+	if val, ok := number("ML"); ok {
+		gs.MiterLimit = val
+	}
+	if arr, ok := core.GetArray(dict.Get("D")); ok {
+		if arr.Len() == 2 {
+			dash, phase, err := dashFromObjects(arr.Get(0), arr.Get(1))
+			if err == nil {
+				gs.DashArray, gs.DashPhase = dash, phase
+			} else {
+				common.Log.Debug("Invalid ExtGState D: %v", err)
+			}
+		}
+	}
+	if ri, ok := core.GetName(dict.Get("RI")); ok {
+		gs.RenderingIntent = *ri
+	}
+	if val, ok := number("FL"); ok {
+		gs.Flatness = val
+	}
+	if val, ok := boolean("SA"); ok {
+		gs.StrokeAdjustment = val
+	}
+	if val, ok := number("CA"); ok {
+		gs.StrokeAlpha = val
+	}
+	if val, ok := number("ca"); ok {
+		gs.FillAlpha = val
+	}
+	if bm := core.TraceToDirectObject(dict.Get("BM")); bm != nil {
+		// The blend mode may be an array of names of which the first supported one is used.
+		// All standard blend modes are accepted here.
+		if arr, ok := bm.(*core.PdfObjectArray); ok && arr.Len() > 0 {
+			bm = core.TraceToDirectObject(arr.Get(0))
+		}
+		if name, ok := core.GetName(bm); ok {
+			gs.BlendMode = *name
+		}
+	}
+	if smask := core.TraceToDirectObject(dict.Get("SMask")); smask != nil {
+		if d, ok := smask.(*core.PdfObjectDictionary); ok {
+			gs.SoftMask = d
+		} else {
+			// The only other allowed value is /None.
+			gs.SoftMask = nil
+		}
+	}
+	if val, ok := boolean("AIS"); ok {
+		gs.AlphaIsShape = val
+	}
+	if val, ok := boolean("TK"); ok {
+		gs.TextState.Knockout = val
+	}
+	if val, ok := boolean("OP"); ok {
+		gs.OverprintStroking = val
+		if dict.Get("op") == nil {
+			gs.OverprintNonStroking = val
+		}
+	}
+	if val, ok := boolean("op"); ok {
+		gs.OverprintNonStroking = val
+	}
+	if val, ok := number("OPM"); ok {
+		gs.OverprintMode = int(val)
+	}
+	if arr, ok := core.GetArray(dict.Get("Font")); ok && arr.Len() == 2 {
+		size, err := core.GetNumberAsFloat(core.TraceToDirectObject(arr.Get(1)))
+		if err != nil {
+			common.Log.Debug("Invalid ExtGState Font size: %v", err)
+		} else {
+			gs.TextState.FontName = ""
+			gs.TextState.FontObject = arr.Get(0)
+			gs.TextState.Font = proc.loadFont(arr.Get(0))
+			gs.TextState.FontSize = size
+		}
+	}
+}
+
+// handlePathConstruction handles the path construction operators m, l, c, v, y, h and re. The
+// points are transformed to device space by the CTM.
+func (proc *ContentStreamProcessor) handlePathConstruction(op *ContentStreamOperation) {
+	numParams := map[string]int{"m": 2, "l": 2, "c": 6, "v": 4, "y": 4, "h": 0, "re": 4}[op.Operand]
+	if len(op.Params) != numParams {
+		common.Log.Debug("Invalid number of parameters for %s: %d", op.Operand, len(op.Params))
+		return
+	}
+	f, err := core.GetNumbersAsFloat(op.Params)
+	if err != nil {
+		common.Log.Debug("Invalid parameters for %s: %v", op.Operand, err)
+		return
+	}
+	pt := func(i int) transform.Point {
+		x, y := proc.graphicsState.CTM.Transform(f[i], f[i+1])
+		return transform.Point{X: x, Y: y}
+	}
+	path := &proc.path
+	switch op.Operand {
+	case "m":
+		proc.moveTo(pt(0))
+	case "l":
+		proc.lineTo(pt(0))
+	case "c":
+		proc.curveTo(pt(0), pt(2), pt(4))
+	case "v":
+		proc.curveTo(proc.pathCur, pt(0), pt(2))
+	case "y":
+		p3 := pt(2)
+		proc.curveTo(pt(0), p3, p3)
+	case "h":
+		path.Segments = append(path.Segments, PathSegment{Type: PathSegmentClose})
+		proc.pathCur = proc.pathStart
+	case "re":
+		x, y, w, h := f[0], f[1], f[2], f[3]
+		corner := func(x, y float64) transform.Point {
+			x, y = proc.graphicsState.CTM.Transform(x, y)
+			return transform.Point{X: x, Y: y}
+		}
+		proc.moveTo(corner(x, y))
+		proc.lineTo(corner(x+w, y))
+		proc.lineTo(corner(x+w, y+h))
+		proc.lineTo(corner(x, y+h))
+		path.Segments = append(path.Segments, PathSegment{Type: PathSegmentClose})
+		proc.pathCur = proc.pathStart
+	}
+}
+
+// moveTo begins a new subpath of the current path at `p`.
+func (proc *ContentStreamProcessor) moveTo(p transform.Point) {
+	proc.path.Segments = append(proc.path.Segments,
+		PathSegment{Type: PathSegmentMoveTo, Points: []transform.Point{p}})
+	proc.pathCur, proc.pathStart = p, p
+}
+
+// lineTo appends a straight line to `p` to the current path.
+func (proc *ContentStreamProcessor) lineTo(p transform.Point) {
+	if proc.path.Empty() {
+		common.Log.Debug("Path segment without a current point")
+		proc.moveTo(p)
+		return
+	}
+	proc.path.Segments = append(proc.path.Segments,
+		PathSegment{Type: PathSegmentLineTo, Points: []transform.Point{p}})
+	proc.pathCur = p
+}
+
+// curveTo appends a cubic Bézier curve with control points `p1`, `p2` to `p3` to the current path.
+func (proc *ContentStreamProcessor) curveTo(p1, p2, p3 transform.Point) {
+	if proc.path.Empty() {
+		common.Log.Debug("Path segment without a current point")
+		proc.moveTo(p3)
+		return
+	}
+	proc.path.Segments = append(proc.path.Segments,
+		PathSegment{Type: PathSegmentCurveTo, Points: []transform.Point{p1, p2, p3}})
+	proc.pathCur = p3
+}
+
+// CurrentPath returns the current path in device space. Handlers of the path painting operators
+// (S, s, f, F, f*, B, B*, b, b*, n) receive the path being painted: the path is ended and a clip
+// set by W or W* is applied after the handlers return.
+func (proc *ContentStreamProcessor) CurrentPath() Path {
+	return proc.path
+}
+
+// handlePathPainting ends the current path. If W or W* preceded the path painting operator, the
+// path is intersected with the clipping path.
+func (proc *ContentStreamProcessor) handlePathPainting() {
+	if proc.pendingClip != nil {
+		clip := proc.pendingClip
+		clip.Path = proc.path
+		// Copy the clipping paths so that the graphics states on the stack are not modified.
+		gs := &proc.graphicsState
+		clipPath := make([]ClippingPath, len(gs.ClipPath), len(gs.ClipPath)+1)
+		copy(clipPath, gs.ClipPath)
+		gs.ClipPath = append(clipPath, *clip)
+		proc.pendingClip = nil
+	}
+	proc.path = Path{}
+}
+
+// handleTextParam handles the BT operator and the text state and text positioning operators with
+// numeric operands. Malformed operands are logged and the operator is skipped over.
+func (proc *ContentStreamProcessor) handleTextParam(op *ContentStreamOperation) {
+	ts := &proc.graphicsState.TextState
+	switch op.Operand {
+	case "BT":
+		ts.Tm = transform.IdentityMatrix()
+		ts.Tlm = transform.IdentityMatrix()
+		return
+	case "T*":
+		proc.nextLine(0, -ts.Leading)
+		return
+	}
+
+	numParams := map[string]int{"Td": 2, "TD": 2, "Tm": 6}[op.Operand]
+	if numParams == 0 {
+		numParams = 1
+	}
+	if len(op.Params) != numParams {
+		common.Log.Debug("Invalid number of parameters for %s: %d", op.Operand, len(op.Params))
+		return
+	}
+	f, err := core.GetNumbersAsFloat(op.Params)
+	if err != nil {
+		common.Log.Debug("Invalid parameters for %s: %v", op.Operand, err)
+		return
+	}
+	switch op.Operand {
+	case "Tc":
+		ts.CharSpacing = f[0]
+	case "Tw":
+		ts.WordSpacing = f[0]
+	case "Tz":
+		ts.HorizontalScaling = f[0]
+	case "TL":
+		ts.Leading = f[0]
+	case "Tr":
+		ts.RenderMode = int(f[0])
+	case "Ts":
+		ts.Rise = f[0]
+	case "Td":
+		proc.nextLine(f[0], f[1])
+	case "TD":
+		ts.Leading = -f[1]
+		proc.nextLine(f[0], f[1])
+	case "Tm":
+		ts.Tm = transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5])
+		ts.Tlm = ts.Tm
+	}
+}
+
+// nextLine moves to the start of the next line, offset from the start of the current line by
+// `tx`,`ty`.
+func (proc *ContentStreamProcessor) nextLine(tx, ty float64) {
+	ts := &proc.graphicsState.TextState
+	ts.Tlm.Concat(transform.TranslationMatrix(tx, ty))
+	ts.Tm = ts.Tlm
+}
+
+// Tf: Set the text font and size. A font that cannot be loaded leaves TextState.Font nil.
+func (proc *ContentStreamProcessor) handleCommand_Tf(op *ContentStreamOperation,
+	resources *model.PdfPageResources) {
+	if len(op.Params) != 2 {
+		common.Log.Debug("Invalid number of parameters for Tf: %d", len(op.Params))
+		return
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		common.Log.Debug("Invalid font name for Tf: %T", op.Params[0])
+		return
+	}
+	size, err := core.GetNumberAsFloat(op.Params[1])
+	if err != nil {
+		common.Log.Debug("Invalid font size for Tf: %v", err)
+		return
+	}
+	ts := &proc.graphicsState.TextState
+	ts.FontName, ts.FontSize, ts.FontObject, ts.Font = *name, size, nil, nil
+	if resources == nil {
+		return
+	}
+	obj, ok := resources.GetFontByName(*name)
+	if !ok {
+		common.Log.Debug("Font %s not found", *name)
+		return
+	}
+	ts.FontObject, ts.Font = obj, proc.loadFont(obj)
+}
+
+// loadFont returns the font represented by `obj`, or nil if it cannot be loaded.
+func (proc *ContentStreamProcessor) loadFont(obj core.PdfObject) *model.PdfFont {
+	if font, ok := proc.fonts[obj]; ok {
+		return font
+	}
+	font, err := model.NewPdfFontFromPdfObject(obj)
+	if err != nil {
+		common.Log.Debug("Unable to load font: %v", err)
+		font = nil
+	}
+	proc.fonts[obj] = font
+	return font
+}
+
+// handleTextLine handles the ' and " operators, which move to the next line before showing text.
+// " also sets the word and character spacing.
+func (proc *ContentStreamProcessor) handleTextLine(op *ContentStreamOperation) {
+	ts := &proc.graphicsState.TextState
+	if op.Operand == "\"" {
+		if len(op.Params) != 3 {
+			common.Log.Debug("Invalid number of parameters for \": %d", len(op.Params))
+			return
+		}
+		f, err := core.GetNumbersAsFloat(op.Params[:2])
+		if err != nil {
+			common.Log.Debug("Invalid parameters for \": %v", err)
+			return
+		}
+		ts.WordSpacing, ts.CharSpacing = f[0], f[1]
+	}
+	proc.nextLine(0, -ts.Leading)
+}
+
+// advanceText advances the text matrix by the displacement of the text shown by the text showing
+// operator `op` (9.4.4 Text Space Details, p. 252). The displacement can only be computed if the
+// current font has been loaded. Vertical writing is not supported.
+func (proc *ContentStreamProcessor) advanceText(op *ContentStreamOperation) {
+	if len(op.Params) == 0 {
+		return
+	}
+	ts := &proc.graphicsState.TextState
+	th := ts.HorizontalScaling / 100
+	switch op.Operand {
+	case "TJ":
+		arr, ok := core.GetArray(op.Params[0])
+		if !ok {
+			common.Log.Debug("Invalid parameter for TJ: %T", op.Params[0])
+			return
+		}
+		for _, obj := range arr.Elements() {
+			if s, ok := core.GetString(obj); ok {
+				proc.advanceString(s.Bytes())
+			} else if val, err := core.GetNumberAsFloat(obj); err == nil {
+				ts.Tm.Concat(transform.TranslationMatrix(-val/1000*ts.FontSize*th, 0))
+			}
+		}
+	default:
+		if s, ok := core.GetString(op.Params[len(op.Params)-1]); ok {
+			proc.advanceString(s.Bytes())
+		}
+	}
+}
+
+// advanceString advances the text matrix by the displacement of the glyphs of `data`.
+func (proc *ContentStreamProcessor) advanceString(data []byte) {
+	ts := &proc.graphicsState.TextState
+	font := ts.Font
+	if font == nil {
+		return
+	}
+	th := ts.HorizontalScaling / 100
+	for _, code := range font.BytesToCharcodes(data) {
+		m, _ := font.GetCharMetrics(code)
+		tx := m.Wx/1000*ts.FontSize + ts.CharSpacing
+		// Word spacing applies to the single-byte code 32 only.
+		if code == 32 && !font.IsCID() {
+			tx += ts.WordSpacing
+		}
+		ts.Tm.Concat(transform.TranslationMatrix(tx*th, 0))
+	}
+}
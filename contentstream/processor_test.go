@@ -0,0 +1,223 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package contentstream
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// processStates processes `content` with `resources` and returns the graphics states passed to the
+// handlers of the operators, keyed by the operator. For repeated operators the last state is kept.
+func processStates(t *testing.T, content string, resources *model.PdfPageResources) map[string]GraphicsState {
+	ops, err := NewContentStreamParser(content).Parse()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	states := map[string]GraphicsState{}
+	proc := NewContentStreamProcessor(*ops)
+	proc.AddHandler(HandlerConditionEnumAllOperands, "",
+		func(op *ContentStreamOperation, gs GraphicsState, resources *model.PdfPageResources) error {
+			states[op.Operand] = gs
+			return nil
+		})
+	if err := proc.Process(resources); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return states
+}
+
+func TestProcessorLineState(t *testing.T) {
+	states := processStates(t, "q 3 w 1 J 2 j 5 M [2 1] 1 d 0 0 m 10 0 l S Q n", nil)
+
+	gs := states["S"]
+	if gs.LineWidth != 3 || gs.LineCap != 1 || gs.LineJoin != 2 || gs.MiterLimit != 5 {
+		t.Errorf("Wrong line parameters: %v %v %v %v", gs.LineWidth, gs.LineCap, gs.LineJoin, gs.MiterLimit)
+	}
+	if len(gs.DashArray) != 2 || gs.DashArray[0] != 2 || gs.DashArray[1] != 1 || gs.DashPhase != 1 {
+		t.Errorf("Wrong dash pattern: %v %v", gs.DashArray, gs.DashPhase)
+	}
+
+	gs = states["n"]
+	if gs.LineWidth != 1 || gs.MiterLimit != 10 || gs.DashArray != nil {
+		t.Errorf("Line parameters not restored: %v %v %v", gs.LineWidth, gs.MiterLimit, gs.DashArray)
+	}
+}
+
+func TestProcessorUnbalancedRestore(t *testing.T) {
+	// A Q without a matching q must not stop the processing.
+	states := processStates(t, "Q Q 2 w 0 0 m 1 1 l S", nil)
+	if gs := states["S"]; gs.LineWidth != 2 {
+		t.Errorf("Wrong line width: %v", gs.LineWidth)
+	}
+}
+
+func TestProcessorClipPath(t *testing.T) {
+	states := processStates(t, "q 2 0 0 2 0 0 cm 10 10 20 20 re W n 0 0 m 5 5 l W* n 1 0 0 rg Q f", nil)
+
+	gs := states["rg"]
+	if len(gs.ClipPath) != 2 {
+		t.Fatalf("Wrong number of clipping paths: %d", len(gs.ClipPath))
+	}
+	if gs.ClipPath[0].EvenOdd || !gs.ClipPath[1].EvenOdd {
+		t.Errorf("Wrong fill rules")
+	}
+	// The clipping path is in device space.
+	llx, lly, urx, ury := gs.ClipPath[0].Path.Bounds()
+	if llx != 20 || lly != 20 || urx != 60 || ury != 60 {
+		t.Errorf("Wrong clipping path bounds: %v %v %v %v", llx, lly, urx, ury)
+	}
+	if n := len(gs.ClipPath[0].Path.Segments); n != 5 {
+		t.Errorf("Wrong number of segments: %d", n)
+	}
+
+	if gs := states["f"]; len(gs.ClipPath) != 0 {
+		t.Errorf("Clipping path not restored: %d", len(gs.ClipPath))
+	}
+}
+
+func TestProcessorCurrentPath(t *testing.T) {
+	ops, err := NewContentStreamParser("0 0 m 10 0 l 10 10 l W n 0 0 5 5 re f 1 1 m").Parse()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	type state struct {
+		segments int
+		clips    int
+	}
+	states := map[string]state{}
+	proc := NewContentStreamProcessor(*ops)
+	proc.AddHandler(HandlerConditionEnumAllOperands, "",
+		func(op *ContentStreamOperation, gs GraphicsState, resources *model.PdfPageResources) error {
+			states[op.Operand] = state{segments: len(proc.CurrentPath().Segments), clips: len(gs.ClipPath)}
+			return nil
+		})
+	if err := proc.Process(nil); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// The handlers of the path painting operators see the path being painted. The path is ended
+	// and the clip is applied after the handlers return.
+	if s := states["n"]; s.segments != 3 || s.clips != 0 {
+		t.Errorf("Wrong state for n: %+v", s)
+	}
+	if s := states["f"]; s.segments != 5 || s.clips != 1 {
+		t.Errorf("Wrong state for f: %+v", s)
+	}
+	if s := states["m"]; s.segments != 1 {
+		t.Errorf("Path not ended: %+v", s)
+	}
+}
+
+func TestProcessorExtGState(t *testing.T) {
+	smask := core.MakeDict()
+	smask.Set("S", core.MakeName("Luminosity"))
+	egs := core.MakeDict()
+	egs.Set("CA", core.MakeFloat(0.5))
+	egs.Set("ca", core.MakeFloat(0.25))
+	egs.Set("BM", core.MakeName("Multiply"))
+	egs.Set("SMask", smask)
+	egs.Set("LW", core.MakeInteger(4))
+	egs.Set("OP", core.MakeBool(true))
+
+	resources := model.NewPdfPageResources()
+	if err := resources.AddExtGState("GS0", egs); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	states := processStates(t, "q /GS0 gs 0 g Q 1 g", resources)
+
+	gs := states["g"]
+	if gs.StrokeAlpha != 1 || gs.FillAlpha != 1 || gs.BlendMode != "Normal" || gs.SoftMask != nil {
+		t.Errorf("ExtGState parameters not restored")
+	}
+
+	gs = states["gs"]
+	if gs.StrokeAlpha != 0.5 || gs.FillAlpha != 0.25 {
+		t.Errorf("Wrong alpha: %v %v", gs.StrokeAlpha, gs.FillAlpha)
+	}
+	if gs.BlendMode != "Multiply" {
+		t.Errorf("Wrong blend mode: %s", gs.BlendMode)
+	}
+	if gs.SoftMask != smask {
+		t.Errorf("Wrong soft mask: %v", gs.SoftMask)
+	}
+	if gs.LineWidth != 4 {
+		t.Errorf("Wrong line width: %v", gs.LineWidth)
+	}
+	if !gs.OverprintStroking || !gs.OverprintNonStroking {
+		t.Errorf("Wrong overprint: %v %v", gs.OverprintStroking, gs.OverprintNonStroking)
+	}
+}
+
+func TestProcessorTextState(t *testing.T) {
+	font := model.NewStandard14FontMustCompile(model.HelveticaName)
+	resources := model.NewPdfPageResources()
+	if err := resources.SetFontByName("F1", font.ToPdfObject()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	states := processStates(t, `BT /F1 10 Tf 1 Tc 2 Tw 50 Tz 12 TL 2 Tr 3 Ts
+		100 200 Td (A) Tj T* [(A) -1000 (A)] TJ ET`, resources)
+
+	ts := states["Tj"].TextState
+	if ts.FontName != "F1" || ts.Font == nil || ts.FontSize != 10 {
+		t.Fatalf("Wrong font: %s %v %v", ts.FontName, ts.Font, ts.FontSize)
+	}
+	if obj, _ := resources.GetFontByName("F1"); ts.FontObject != obj {
+		t.Errorf("Wrong font object: %v", ts.FontObject)
+	}
+	if ts.CharSpacing != 1 || ts.WordSpacing != 2 || ts.HorizontalScaling != 50 || ts.Leading != 12 ||
+		ts.RenderMode != 2 || ts.Rise != 3 {
+		t.Errorf("Wrong text state: %+v", ts)
+	}
+	// Handlers see the text matrix at the start of the text.
+	if x, y := ts.Tm.Translation(); x != 100 || y != 200 {
+		t.Errorf("Wrong text position for Tj: %v %v", x, y)
+	}
+
+	ts = states["TJ"].TextState
+	if x, y := ts.Tm.Translation(); x != 100 || y != 188 {
+		t.Errorf("Wrong text position for TJ: %v %v", x, y)
+	}
+
+	// The width of "A" in Helvetica is 667. Tx = ((0.667*10 + 1) * 0.5) for each A and
+	// 1000/1000*10*0.5 for the adjustment.
+	ts = states["ET"].TextState
+	x, _ := ts.Tm.Translation()
+	if want := 100 + 2*(0.667*10+1)*0.5 + 5; math.Abs(x-want) > 1e-6 {
+		t.Errorf("Wrong text position after TJ: %v, want %v", x, want)
+	}
+}
+
+func TestProcessorInitialState(t *testing.T) {
+	ops, err := NewContentStreamParser("0 0 m 10 0 l S q 1 w Q n").Parse()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	initial := newGraphicsState()
+	initial.LineWidth = 3
+	initial.FillAlpha = 0.5
+	states := map[string]GraphicsState{}
+	proc := NewContentStreamProcessor(*ops)
+	proc.SetInitialState(initial)
+	proc.AddHandler(HandlerConditionEnumAllOperands, "",
+		func(op *ContentStreamOperation, gs GraphicsState, resources *model.PdfPageResources) error {
+			states[op.Operand] = gs
+			return nil
+		})
+	if err := proc.Process(nil); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// The content stream starts with the initial state and restores it.
+	for _, operand := range []string{"S", "n"} {
+		if gs := states[operand]; gs.LineWidth != 3 || gs.FillAlpha != 0.5 {
+			t.Errorf("Wrong state for %s: %v %v", operand, gs.LineWidth, gs.FillAlpha)
+		}
+	}
+}
@@ -6,8 +6,10 @@
 package core
 
 import (
+	cryptolib "crypto"
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"time"
@@ -57,20 +59,10 @@ func PdfCryptNewEncrypt(cf crypto.Filter, userPass, ownerPass []byte, perm secur
 		crypter.streamFilter = defaultFilter
 		crypter.stringFilter = defaultFilter
 	}
-	ed := crypter.newEncryptDict()
+	ed := crypter.newEncryptDict("Standard")
 
-	// Prepare the ID object for the trailer.
-	hashcode := md5.Sum([]byte(time.Now().Format(time.RFC850)))
-	id0 := string(hashcode[:])
-	b := make([]byte, 100)
-	rand.Read(b)
-	hashcode = md5.Sum(b)
-	id1 := string(hashcode[:])
-	common.Log.Trace("Random b: % x", b)
-
-	common.Log.Trace("Gen Id 0: % x", id0)
-
-	crypter.id0 = string(id0)
+	id0, id1 := newFileIDs()
+	crypter.id0 = id0
 
 	err := crypter.generateParams(userPass, ownerPass)
 	if err != nil {
@@ -91,6 +83,105 @@ func PdfCryptNewEncrypt(cf crypto.Filter, userPass, ownerPass []byte, perm secur
 	}, nil
 }
 
+// PdfCryptNewEncryptPubSec makes the document crypt handler of the public-key security handler
+// (Adobe.PubSec) based on a specified crypt filter. The document is encrypted for the certificates
+// of the `recipients`. `subFilter` is security.PubSecSubFilterS4 or security.PubSecSubFilterS5;
+// the latter requires a V4 or V5 crypt filter (AESV2 or AESV3).
+func PdfCryptNewEncryptPubSec(cf crypto.Filter, recipients []security.PubSecRecipient, subFilter string) (*PdfCrypt, *EncryptInfo, error) {
+	if cf == nil {
+		return nil, nil, errors.New("crypt filter not specified")
+	}
+	crypter := &PdfCrypt{
+		encryptedObjects: make(map[PdfObject]bool),
+		cryptFilters:     make(cryptFilters),
+		encryptStd: security.StdEncryptDict{
+			P:               security.PermOwner,
+			EncryptMetadata: true,
+		},
+	}
+	v := cf.PDFVersion()
+	vers := Version{Major: v[0], Minor: v[1]}
+	crypter.encrypt.V, _ = cf.HandlerVersion()
+	crypter.encrypt.Length = cf.KeyLength() * 8
+	crypter.encrypt.SubFilter = subFilter
+	switch subFilter {
+	case security.PubSecSubFilterS4:
+	case security.PubSecSubFilterS5:
+		if crypter.encrypt.V < 4 {
+			return nil, nil, fmt.Errorf("%s requires a V4 or V5 crypt filter", subFilter)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported public-key sub-filter: %q", subFilter)
+	}
+	if vers.Major == 1 && vers.Minor < 5 {
+		// The public-key security handler was introduced in PDF 1.5.
+		vers.Minor = 5
+	}
+
+	seed, err := security.NewPubSecSeed()
+	if err != nil {
+		return nil, nil, err
+	}
+	envelopes, err := security.PubSecEnvelopes(seed, recipients)
+	if err != nil {
+		return nil, nil, err
+	}
+	crypter.pubSecRecipients = envelopes
+	crypter.encryptionKey = security.PubSecKey(seed, envelopes, true, cf.KeyLength(), cf.Name() == "AESV3")
+
+	const defaultFilter = pubSecCryptFilter
+	if crypter.encrypt.V >= 4 {
+		crypter.cryptFilters[defaultFilter] = cf
+		crypter.streamFilter = defaultFilter
+		crypter.stringFilter = defaultFilter
+	} else {
+		// Legacy algorithms (V < 4) do not use named crypt filters.
+		crypter.cryptFilters[stdCryptFilter] = cf
+	}
+
+	ed := crypter.newEncryptDict("Adobe.PubSec")
+	ed.Set("SubFilter", MakeName(subFilter))
+	recipientsArr := MakeArray()
+	for _, env := range envelopes {
+		recipientsArr.Append(MakeStringFromBytes(env))
+	}
+	if crypter.encrypt.V >= 4 {
+		if err := crypter.saveCryptFilters(ed); err != nil {
+			return nil, nil, err
+		}
+	}
+	if subFilter == security.PubSecSubFilterS5 {
+		cfd, _ := GetDict(ed.Get("CF"))
+		filter, _ := GetDict(cfd.Get(defaultFilter))
+		filter.Set("Recipients", recipientsArr)
+		filter.Set("EncryptMetadata", MakeBool(true))
+	} else {
+		ed.Set("Recipients", recipientsArr)
+	}
+
+	id0, id1 := newFileIDs()
+	crypter.id0 = id0
+	return crypter, &EncryptInfo{
+		Version: vers,
+		Encrypt: ed,
+		ID0:     id0, ID1: id1,
+	}, nil
+}
+
+// newFileIDs returns new identifiers for the ID entry of the trailer.
+func newFileIDs() (id0, id1 string) {
+	hashcode := md5.Sum([]byte(time.Now().Format(time.RFC850)))
+	id0 = string(hashcode[:])
+	b := make([]byte, 100)
+	rand.Read(b)
+	hashcode = md5.Sum(b)
+	id1 = string(hashcode[:])
+	common.Log.Trace("Random b: % x", b)
+
+	common.Log.Trace("Gen Id 0: % x", id0)
+	return id0, id1
+}
+
 // PdfCrypt provides PDF encryption/decryption support.
 // The PDF standard supports encryption of strings and streams (Section 7.6).
 type PdfCrypt struct {
@@ -110,8 +201,15 @@ type PdfCrypt struct {
 	parser *PdfParser
 
 	decryptedObjNum map[int]struct{}
+
+	// pubSecRecipients holds the PKCS#7 enveloped data of the Recipients array of the
+	// public-key security handler (Adobe.PubSec).
+	pubSecRecipients [][]byte
 }
 
+// pubSecCryptFilter is a default name for a crypt filter of the public-key security handler.
+const pubSecCryptFilter = "DefaultCryptFilter"
+
 // encodeEncryptStd encodes fields of standard security handler to an Encrypt dictionary.
 func encodeEncryptStd(d *security.StdEncryptDict, ed *PdfObjectDictionary) {
 	ed.Set("R", MakeInteger(int64(d.R)))
@@ -243,10 +341,11 @@ func decodeCryptFilter(cf *crypto.FilterDict, d *PdfObjectDictionary) error {
 	return nil
 }
 
-func (crypt *PdfCrypt) newEncryptDict() *PdfObjectDictionary {
+func (crypt *PdfCrypt) newEncryptDict(filter string) *PdfObjectDictionary {
+	crypt.encrypt.Filter = filter
 	// Generate the encryption dictionary.
 	ed := MakeDict()
-	ed.Set("Filter", MakeName("Standard"))
+	ed.Set("Filter", MakeName(filter))
 	ed.Set("V", MakeInteger(int64(crypt.encrypt.V)))
 	ed.Set("Length", MakeInteger(int64(crypt.encrypt.Length)))
 	return ed
@@ -434,15 +533,19 @@ func PdfCryptNewDecrypt(parser *PdfParser, ed, trailer *PdfObjectDictionary) (*P
 		common.Log.Debug("ERROR Crypt dictionary missing required Filter field!")
 		return crypter, errors.New("required crypt field Filter missing")
 	}
-	if *filter != "Standard" {
+	if *filter != "Standard" && *filter != "Adobe.PubSec" {
 		common.Log.Debug("ERROR Unsupported filter (%s)", *filter)
 		return crypter, errors.New("unsupported Filter")
 	}
 	crypter.encrypt.Filter = string(*filter)
 
-	if subfilter, ok := ed.Get("SubFilter").(*PdfObjectString); ok {
+	switch subfilter := ed.Get("SubFilter").(type) {
+	case *PdfObjectString:
 		crypter.encrypt.SubFilter = subfilter.Str()
 		common.Log.Debug("Using subfilter %s", subfilter)
+	case *PdfObjectName:
+		crypter.encrypt.SubFilter = string(*subfilter)
+		common.Log.Debug("Using subfilter %s", subfilter)
 	}
 
 	if L, ok := ed.Get("Length").(*PdfObjectInteger); ok {
@@ -472,9 +575,16 @@ func PdfCryptNewDecrypt(parser *PdfParser, ed, trailer *PdfObjectDictionary) (*P
 		}
 	}
 
-	// decode Standard security handler parameters
-	if err := decodeEncryptStd(&crypter.encryptStd, ed); err != nil {
-		return crypter, err
+	if crypter.isPubSec() {
+		// The permissions are granted per recipient when authenticating.
+		if err := crypter.loadPubSecRecipients(ed); err != nil {
+			return crypter, err
+		}
+	} else {
+		// decode Standard security handler parameters
+		if err := decodeEncryptStd(&crypter.encryptStd, ed); err != nil {
+			return crypter, err
+		}
 	}
 
 	// Default: empty ID.
@@ -495,6 +605,96 @@ func PdfCryptNewDecrypt(parser *PdfParser, ed, trailer *PdfObjectDictionary) (*P
 	return crypter, nil
 }
 
+// isPubSec returns true if the document is encrypted with the public-key security handler.
+func (crypt *PdfCrypt) isPubSec() bool {
+	return crypt.encrypt.Filter == "Adobe.PubSec"
+}
+
+// loadPubSecRecipients loads the Recipients array and EncryptMetadata flag of the public-key
+// security handler. With the adbe.pkcs7.s4 sub-filter they are stored in the encryption
+// dictionary, with adbe.pkcs7.s5 in the crypt filter for streams.
+func (crypt *PdfCrypt) loadPubSecRecipients(ed *PdfObjectDictionary) error {
+	resolve := func(obj PdfObject) PdfObject {
+		if ref, isRef := obj.(*PdfObjectReference); isRef {
+			o, err := crypt.parser.LookupByReference(*ref)
+			if err != nil {
+				common.Log.Debug("Error looking up reference: %v", err)
+				return nil
+			}
+			obj = o
+		}
+		return TraceToDirectObject(obj)
+	}
+
+	dict := ed
+	if ed.Get("Recipients") == nil && crypt.encrypt.V >= 4 {
+		cf, ok := resolve(ed.Get("CF")).(*PdfObjectDictionary)
+		if !ok {
+			return errors.New("invalid CF")
+		}
+		dict, ok = resolve(cf.Get(PdfObjectName(crypt.streamFilter))).(*PdfObjectDictionary)
+		if !ok {
+			return fmt.Errorf("crypt filter %s not found", crypt.streamFilter)
+		}
+	}
+
+	var recipients []PdfObject
+	switch obj := resolve(dict.Get("Recipients")).(type) {
+	case *PdfObjectArray:
+		recipients = obj.Elements()
+	case *PdfObjectString:
+		recipients = []PdfObject{obj}
+	default:
+		return errors.New("encrypt dictionary missing Recipients")
+	}
+	for _, obj := range recipients {
+		s, ok := resolve(obj).(*PdfObjectString)
+		if !ok {
+			return errors.New("invalid Recipients entry")
+		}
+		crypt.pubSecRecipients = append(crypt.pubSecRecipients, s.Bytes())
+	}
+
+	crypt.encryptStd.EncryptMetadata = true
+	if em, ok := resolve(dict.Get("EncryptMetadata")).(*PdfObjectBool); ok {
+		crypt.encryptStd.EncryptMetadata = bool(*em)
+	}
+	return nil
+}
+
+// pubSecKeyLength returns the length of the file encryption key in bytes and whether it is
+// derived with SHA-256 (AESV3).
+func (crypt *PdfCrypt) pubSecKeyLength() (int, bool) {
+	if crypt.encrypt.V >= 4 {
+		if cf, ok := crypt.cryptFilters[crypt.streamFilter]; ok && cf.KeyLength() > 0 {
+			return cf.KeyLength(), cf.Name() == "AESV3"
+		}
+	}
+	return crypt.encrypt.Length / 8, false
+}
+
+// authenticateCert checks whether the document can be decrypted with the certificate `cert` and
+// its private key `pkey` (public-key security handler). It also builds the encryption key and
+// sets the permissions granted to the recipient.
+func (crypt *PdfCrypt) authenticateCert(cert *x509.Certificate, pkey cryptolib.PrivateKey) (bool, error) {
+	crypt.authenticated = false
+	if !crypt.isPubSec() {
+		return false, errors.New("document not encrypted with the public-key security handler")
+	}
+	seed, perm, err := security.PubSecOpen(crypt.pubSecRecipients, cert, pkey)
+	if err != nil {
+		return false, err
+	} else if seed == nil {
+		return false, nil
+	}
+	keyLen, sha256 := crypt.pubSecKeyLength()
+	crypt.encryptionKey = security.PubSecKey(seed, crypt.pubSecRecipients, crypt.encryptStd.EncryptMetadata,
+		keyLen, sha256)
+	crypt.encryptStd.P = perm
+	crypt.authenticated = true
+	return true, nil
+}
+
 // GetAccessPermissions returns the PDF access permissions as an AccessPermissions object.
 func (crypt *PdfCrypt) GetAccessPermissions() security.Permissions {
 	return crypt.encryptStd.P
@@ -507,10 +707,17 @@ func (crypt *PdfCrypt) securityHandler() security.StdHandler {
 	return security.NewHandlerR4(crypt.id0, crypt.encrypt.Length)
 }
 
+// errPubSecPassword is returned when authenticating a document encrypted with the public-key
+// security handler with a password.
+var errPubSecPassword = errors.New("document encrypted for certificates: decrypt with a certificate and private key")
+
 // Check whether the specified password can be used to decrypt the document.
 // Also build the encryption/decryption key.
 func (crypt *PdfCrypt) authenticate(password []byte) (bool, error) {
 	crypt.authenticated = false
+	if crypt.isPubSec() {
+		return false, errPubSecPassword
+	}
 	h := crypt.securityHandler()
 	fkey, perm, err := h.Authenticate(&crypt.encryptStd, password)
 	if err != nil {
@@ -530,6 +737,9 @@ func (crypt *PdfCrypt) authenticate(password []byte) (bool, error) {
 // The AccessPermissions shows what access the user has for editing etc.
 // An error is returned if there was a problem performing the authentication.
 func (crypt *PdfCrypt) checkAccessRights(password []byte) (bool, security.Permissions, error) {
+	if crypt.isPubSec() {
+		return false, 0, errPubSecPassword
+	}
 	h := crypt.securityHandler()
 	// TODO(dennwc): it computes an encryption key as well; if necessary, define a new interface method to optimize this
 	fkey, perm, err := h.Authenticate(&crypt.encryptStd, password)
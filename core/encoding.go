@@ -14,8 +14,8 @@ package core
 // - ASCII Hex
 // - ASCII85
 // - CCITT Fax (dummy)
-// - JBIG2 (dummy)
-// - JPX (dummy)
+// - JBIG2 (decoding only)
+// - JPX (decoding only)
 
 import (
 	"bytes"
@@ -35,6 +35,8 @@ import (
 
 	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/internal/ccittfax"
+	"github.com/unidoc/unipdf/v3/internal/jbig2"
+	"github.com/unidoc/unipdf/v3/internal/jpx"
 )
 
 // Stream encoding filter names.
@@ -1976,15 +1978,63 @@ func (enc *CCITTFaxEncoder) EncodeBytes(data []byte) ([]byte, error) {
 	return encoder.Encode(pixels), nil
 }
 
-// JBIG2Encoder implements JBIG2 encoder/decoder (dummy, for now)
-// FIXME: implement
-type JBIG2Encoder struct{}
+// JBIG2Encoder implements the JBIG2 decoder. JBIG2 encoding is not supported.
+type JBIG2Encoder struct {
+	// Globals holds the decoded contents of the JBIG2Globals stream, the JBIG2 segments that are
+	// shared between several images. It is nil if the stream has no JBIG2Globals.
+	Globals []byte
+}
 
 // NewJBIG2Encoder returns a new instance of JBIG2Encoder.
 func NewJBIG2Encoder() *JBIG2Encoder {
 	return &JBIG2Encoder{}
 }
 
+// newJBIG2EncoderFromStream creates a new JBIG2 decoder from a stream object, getting the
+// JBIG2Globals from the DecodeParms stream object dictionary entry.
+func newJBIG2EncoderFromStream(streamObj *PdfObjectStream, decodeParams *PdfObjectDictionary) (*JBIG2Encoder, error) {
+	encoder := NewJBIG2Encoder()
+
+	encDict := streamObj.PdfObjectDictionary
+	if encDict == nil {
+		// No encoding dictionary.
+		return encoder, nil
+	}
+
+	// If decodeParams not provided, see if we can get from the stream.
+	if decodeParams == nil {
+		obj := encDict.Get("DecodeParms")
+		if obj != nil {
+			switch t := obj.(type) {
+			case *PdfObjectDictionary:
+				decodeParams = t
+			case *PdfObjectArray:
+				if t.Len() == 1 {
+					if dp, ok := GetDict(t.Get(0)); ok {
+						decodeParams = dp
+					}
+				}
+			default:
+				common.Log.Debug("ERROR: DecodeParms not a dictionary %#v", obj)
+				return nil, errors.New("invalid DecodeParms")
+			}
+		}
+	}
+	if decodeParams == nil {
+		return encoder, nil
+	}
+
+	if globals, ok := GetStream(decodeParams.Get("JBIG2Globals")); ok {
+		data, err := DecodeStream(globals)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to decode JBIG2Globals: %v", err)
+			return nil, err
+		}
+		encoder.Globals = data
+	}
+	return encoder, nil
+}
+
 // GetFilterName returns the name of the encoding filter.
 func (enc *JBIG2Encoder) GetFilterName() string {
 	return StreamEncodingFilterNameJBIG2
@@ -2006,16 +2056,27 @@ func (enc *JBIG2Encoder) UpdateParams(params *PdfObjectDictionary) {
 }
 
 // DecodeBytes decodes a slice of JBIG2 encoded bytes and returns the result.
+// The result is 1 bit per pixel image data where, as for DeviceGray images, 0 is black and 1 is
+// white. Each image row starts on a byte boundary.
 func (enc *JBIG2Encoder) DecodeBytes(encoded []byte) ([]byte, error) {
-	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
-	return encoded, ErrNoJBIG2Decode
+	bitmap, err := jbig2.Decode(encoded, enc.Globals)
+	if err != nil {
+		common.Log.Debug("ERROR: JBIG2 decoding failed: %v", err)
+		return nil, err
+	}
+
+	// JBIG2 bitmaps use 1 for black pixels.
+	decoded := make([]byte, len(bitmap.Data))
+	for i, b := range bitmap.Data {
+		decoded[i] = ^b
+	}
+	return decoded, nil
 }
 
 // DecodeStream decodes a JBIG2 encoded stream and returns the result as a
 // slice of bytes.
 func (enc *JBIG2Encoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error) {
-	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
-	return streamObj.Stream, ErrNoJBIG2Decode
+	return enc.DecodeBytes(streamObj.Stream)
 }
 
 // EncodeBytes JBIG2 encodes the passed in slice of bytes.
@@ -2024,13 +2085,62 @@ func (enc *JBIG2Encoder) EncodeBytes(data []byte) ([]byte, error) {
 	return data, ErrNoJBIG2Decode
 }
 
-// JPXEncoder implements JPX encoder/decoder (dummy, for now)
-// FIXME: implement
-type JPXEncoder struct{}
+// JPXEncoder implements the JPX (JPEG 2000) decoder. JPX encoding is not supported.
+type JPXEncoder struct {
+	// The image parameters, as read from the JPEG 2000 data. The decoded samples always have 8 bits
+	// per component. ColorComponents does not include the alpha channel, if any.
+	ColorComponents  int
+	BitsPerComponent int
+	Width            int
+	Height           int
+
+	// ICCProfile is the ICC profile embedded in the JPEG 2000 data, or nil if there is none.
+	ICCProfile []byte
+
+	// SMaskInData is the SMaskInData entry of the image dictionary. If it is nonzero, the opacity
+	// channel of the JPEG 2000 data is used as the soft mask of the image.
+	SMaskInData int
+}
 
 // NewJPXEncoder returns a new instance of JPXEncoder.
 func NewJPXEncoder() *JPXEncoder {
-	return &JPXEncoder{}
+	return &JPXEncoder{BitsPerComponent: 8}
+}
+
+// newJPXEncoderFromStream creates a new JPX decoder from a stream object, reading the image
+// parameters from the JPEG 2000 data and SMaskInData from the stream dictionary. When JPXDecode
+// follows other filters, `multiEnc` holds the preceding filters, which are applied to the stream
+// data before reading the image parameters.
+func newJPXEncoderFromStream(streamObj *PdfObjectStream, multiEnc *MultiEncoder) (*JPXEncoder, error) {
+	encoder := NewJPXEncoder()
+
+	if encDict := streamObj.PdfObjectDictionary; encDict != nil {
+		if smask, ok := GetIntVal(encDict.Get("SMaskInData")); ok {
+			encoder.SMaskInData = smask
+		}
+	}
+
+	encoded := streamObj.Stream
+	if multiEnc != nil {
+		e, err := multiEnc.DecodeBytes(encoded)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to decode the filters preceding JPXDecode: %v", err)
+			return encoder, nil
+		}
+		encoded = e
+	}
+
+	cfg, err := jpx.DecodeConfig(encoded)
+	if err != nil {
+		// The image dictionary may still describe the image, so only decoding fails.
+		common.Log.Debug("ERROR: Unable to read JPX image header: %v", err)
+		return encoder, nil
+	}
+	encoder.ColorComponents = cfg.ColorComponents
+	encoder.Width = cfg.Width
+	encoder.Height = cfg.Height
+	encoder.ICCProfile = cfg.ICCProfile
+	return encoder, nil
 }
 
 // GetFilterName returns the name of the encoding filter.
@@ -2046,7 +2156,9 @@ func (enc *JPXEncoder) MakeDecodeParams() PdfObject {
 
 // MakeStreamDict makes a new instance of an encoding dictionary for a stream object.
 func (enc *JPXEncoder) MakeStreamDict() *PdfObjectDictionary {
-	return MakeDict()
+	dict := MakeDict()
+	dict.Set("Filter", MakeName(enc.GetFilterName()))
+	return dict
 }
 
 // UpdateParams updates the parameter values of the encoder.
@@ -2054,16 +2166,29 @@ func (enc *JPXEncoder) UpdateParams(params *PdfObjectDictionary) {
 }
 
 // DecodeBytes decodes a slice of JPX encoded bytes and returns the result.
+// The result holds the colour samples of the image with 8 bits per component. The opacity channel,
+// if any, is not included (see DecodeWithAlpha).
 func (enc *JPXEncoder) DecodeBytes(encoded []byte) ([]byte, error) {
-	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
-	return encoded, ErrNoJPXDecode
+	data, _, err := enc.DecodeWithAlpha(encoded)
+	return data, err
+}
+
+// DecodeWithAlpha decodes a slice of JPX encoded bytes and returns the colour samples and the
+// opacity samples of the image, both with 8 bits per component. `alpha` is nil if the image has
+// no opacity channel.
+func (enc *JPXEncoder) DecodeWithAlpha(encoded []byte) (data, alpha []byte, err error) {
+	img, err := jpx.Decode(encoded)
+	if err != nil {
+		common.Log.Debug("ERROR: JPX decoding failed: %v", err)
+		return nil, nil, err
+	}
+	return img.Data, img.Alpha, nil
 }
 
 // DecodeStream decodes a JPX encoded stream and returns the result as a
 // slice of bytes.
 func (enc *JPXEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error) {
-	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
-	return streamObj.Stream, ErrNoJPXDecode
+	return enc.DecodeBytes(streamObj.Stream)
 }
 
 // EncodeBytes JPX encodes the passed in slice of bytes.
@@ -2178,6 +2303,18 @@ func newMultiEncoderFromStream(streamObj *PdfObjectStream) (*MultiEncoder, error
 		} else if *name == StreamEncodingFilterNameASCII85 {
 			encoder := NewASCII85Encoder()
 			mencoder.AddEncoder(encoder)
+		} else if *name == StreamEncodingFilterNameJBIG2 {
+			encoder, err := newJBIG2EncoderFromStream(streamObj, dParams)
+			if err != nil {
+				return nil, err
+			}
+			mencoder.AddEncoder(encoder)
+		} else if *name == StreamEncodingFilterNameJPX {
+			encoder, err := newJPXEncoderFromStream(streamObj, mencoder)
+			if err != nil {
+				return nil, err
+			}
+			mencoder.AddEncoder(encoder)
 		} else if *name == StreamEncodingFilterNameDCT {
 			encoder, err := newDCTEncoderFromStream(streamObj, mencoder)
 			if err != nil {
@@ -367,3 +367,140 @@ func TestMultiEncoder(t *testing.T) {
 		return
 	}
 }
+
+// Test JBIG2 decoding of an image whose symbol dictionary is stored in the JBIG2Globals stream.
+// The image shows two "L" shaped symbols.
+func TestJBIG2Decoding(t *testing.T) {
+	globals := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x19, 0x00, 0x00, 0x03, 0xff, 0xfd,
+		0xff, 0x02, 0xfe, 0xfe, 0xfe, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x55, 0x67, 0x69,
+		0x8c, 0x7f, 0xff, 0xac,
+	}
+	encoded := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x30, 0x00, 0x01, 0x00, 0x00, 0x00, 0x13, 0x00, 0x00, 0x00, 0x0c, 0x00,
+		0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x01, 0x06, 0x20, 0x00, 0x01, 0x00, 0x00, 0x00, 0x1b, 0x00, 0x00, 0x00, 0x0c, 0x00, 0x00,
+		0x00, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00,
+		0x02, 0xa5, 0xcc, 0xff, 0xac, 0x00, 0x00, 0x00, 0x02, 0x31, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+	}
+	// 12x6 pixels, 0 is black.
+	expected := []byte{0xff, 0xff, 0xbb, 0xff, 0xbb, 0xff, 0xbb, 0xff, 0x88, 0xff, 0xff, 0xff}
+
+	decodeParams := MakeDict()
+	decodeParams.Set("JBIG2Globals", &PdfObjectStream{
+		PdfObjectDictionary: MakeDict(),
+		Stream:              globals,
+	})
+	dict := MakeDict()
+	dict.Set("Filter", MakeName(StreamEncodingFilterNameJBIG2))
+	dict.Set("DecodeParms", decodeParams)
+	streamObj := &PdfObjectStream{PdfObjectDictionary: dict, Stream: encoded}
+
+	encoder, err := NewEncoderFromStream(streamObj)
+	if err != nil {
+		t.Fatalf("Failed to create encoder: %v", err)
+	}
+	decoded, err := encoder.DecodeStream(streamObj)
+	if err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if !compareSlices(decoded, expected) {
+		t.Errorf("Slices not matching")
+		t.Errorf("Decoded  (%d): % x", len(decoded), decoded)
+		t.Errorf("Expected (%d): % x", len(expected), expected)
+	}
+
+	// Without the globals the symbols are not defined.
+	if _, err := NewJBIG2Encoder().DecodeBytes(encoded); err == nil {
+		t.Errorf("Decoding without JBIG2Globals should fail")
+	}
+}
+
+// Test JPX decoding of a raw JPEG 2000 codestream with a grey and an opacity component.
+func TestJPXDecoding(t *testing.T) {
+	encoded := []byte{
+		0xff, 0x4f, 0xff, 0x51, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x03,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x03,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x07, 0x01, 0x01, 0x07, 0x01, 0x01,
+		0xff, 0x52, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0xff, 0x5c,
+		0x00, 0x07, 0x60, 0x48, 0x50, 0x50, 0x58, 0xff, 0x90, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x38, 0x00, 0x01, 0xff, 0x93, 0xc3, 0xed, 0x05, 0x0b, 0x71, 0x80, 0x17, 0x73, 0xc7, 0xe0, 0x0c,
+		0x03, 0x92, 0x73, 0xfa, 0x92, 0x8f, 0xc1, 0xf6, 0x82, 0xc3, 0xf0, 0x04, 0x83, 0xf0, 0x03, 0x04,
+		0xcd, 0x70, 0xb5, 0x90, 0x02, 0xb0, 0xee, 0x7f, 0x04, 0x3d, 0x21, 0xa0, 0x02, 0x10, 0x01, 0xff,
+		0xd9,
+	}
+	// 4x3 pixels.
+	expectedGray := []byte{0, 50, 100, 150, 200, 250, 30, 60, 90, 120, 180, 255}
+	expectedAlpha := []byte{255, 255, 255, 255, 128, 128, 128, 128, 0, 0, 0, 0}
+
+	dict := MakeDict()
+	dict.Set("Filter", MakeName(StreamEncodingFilterNameJPX))
+	dict.Set("SMaskInData", MakeInteger(1))
+	streamObj := &PdfObjectStream{PdfObjectDictionary: dict, Stream: encoded}
+
+	encoder, err := NewEncoderFromStream(streamObj)
+	if err != nil {
+		t.Fatalf("Failed to create encoder: %v", err)
+	}
+	jpxEnc, ok := encoder.(*JPXEncoder)
+	if !ok {
+		t.Fatalf("Wrong encoder type %T", encoder)
+	}
+	if jpxEnc.Width != 4 || jpxEnc.Height != 3 || jpxEnc.ColorComponents != 1 || jpxEnc.SMaskInData != 1 {
+		t.Errorf("Wrong image parameters %+v", jpxEnc)
+	}
+
+	decoded, err := encoder.DecodeStream(streamObj)
+	if err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if !compareSlices(decoded, expectedGray) {
+		t.Errorf("Slices not matching")
+		t.Errorf("Decoded  (%d): %v", len(decoded), decoded)
+		t.Errorf("Expected (%d): %v", len(expectedGray), expectedGray)
+	}
+
+	_, alpha, err := jpxEnc.DecodeWithAlpha(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if !compareSlices(alpha, expectedAlpha) {
+		t.Errorf("Alpha not matching: %v", alpha)
+	}
+
+	// Truncated data.
+	if _, err := jpxEnc.DecodeBytes(encoded[:40]); err == nil {
+		t.Errorf("Decoding truncated data should fail")
+	}
+
+	// The image parameters are read after the filters preceding JPXDecode.
+	flated, err := NewFlateEncoder().EncodeBytes(encoded)
+	if err != nil {
+		t.Fatalf("Failed to encode data: %v", err)
+	}
+	dict = MakeDict()
+	dict.Set("Filter", MakeArray(MakeName(StreamEncodingFilterNameFlate), MakeName(StreamEncodingFilterNameJPX)))
+	streamObj = &PdfObjectStream{PdfObjectDictionary: dict, Stream: flated}
+	encoder, err = NewEncoderFromStream(streamObj)
+	if err != nil {
+		t.Fatalf("Failed to create encoder: %v", err)
+	}
+	multiEnc, ok := encoder.(*MultiEncoder)
+	if !ok || len(multiEnc.encoders) != 2 {
+		t.Fatalf("Wrong encoder %#v", encoder)
+	}
+	jpxEnc, ok = multiEnc.encoders[1].(*JPXEncoder)
+	if !ok {
+		t.Fatalf("Wrong encoder type %T", multiEnc.encoders[1])
+	}
+	if jpxEnc.Width != 4 || jpxEnc.Height != 3 || jpxEnc.ColorComponents != 1 {
+		t.Errorf("Wrong image parameters %+v", jpxEnc)
+	}
+	decoded, err = encoder.DecodeStream(streamObj)
+	if err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if !compareSlices(decoded, expectedGray) {
+		t.Errorf("Slices not matching: %v", decoded)
+	}
+}
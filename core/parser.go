@@ -8,6 +8,8 @@ package core
 import (
 	"bufio"
 	"bytes"
+	"crypto"
+	"crypto/x509"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -1786,6 +1788,16 @@ func (parser *PdfParser) Decrypt(password []byte) (bool, error) {
 	return authenticated, err
 }
 
+// DecryptWithCertificate attempts to decrypt a PDF file encrypted with the public-key security handler
+// (Adobe.PubSec) with the recipient certificate `cert` and its private key `pkey`. Returns true if successful,
+// false if the document is not encrypted for `cert`. An error is returned when there is a problem with decrypting.
+func (parser *PdfParser) DecryptWithCertificate(cert *x509.Certificate, pkey crypto.PrivateKey) (bool, error) {
+	if parser.crypter == nil {
+		return false, errors.New("check encryption first")
+	}
+	return parser.crypter.authenticateCert(cert, pkey)
+}
+
 // CheckAccessRights checks access rights and permissions for a specified password. If either user/owner password is
 // specified, full rights are granted, otherwise the access rights are specified by the Permissions flag.
 //
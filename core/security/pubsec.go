@@ -0,0 +1,349 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package security
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/gunnsth/pkcs7"
+
+	"github.com/unidoc/unipdf/v3/common"
+)
+
+// Sub-filters of the public-key security handler (Adobe.PubSec).
+const (
+	// PubSecSubFilterS4 stores the recipients in the encryption dictionary.
+	PubSecSubFilterS4 = "adbe.pkcs7.s4"
+	// PubSecSubFilterS5 stores the recipients in the crypt filters (requires V 4 or 5).
+	PubSecSubFilterS5 = "adbe.pkcs7.s5"
+)
+
+// PubSecRecipient is a recipient of a document encrypted with the public-key security handler.
+// Only certificates with RSA keys are supported.
+type PubSecRecipient struct {
+	Certificate *x509.Certificate
+	Permissions Permissions // Permissions granted to the recipient.
+}
+
+// pubSecSeedLen is the length of the seed used to derive the file encryption key.
+const pubSecSeedLen = 20
+
+// NewPubSecSeed returns a random seed for the file encryption key.
+func NewPubSecSeed() ([]byte, error) {
+	seed := make([]byte, pubSecSeedLen)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// PubSecEnvelopes returns the PKCS#7 enveloped data of the entries of the Recipients array
+// (7.6.5.2 Public-Key Encryption Dictionary, p. 92). Each envelope holds `seed` and the
+// permissions of the recipients it is addressed to. Recipients with the same permissions share an
+// envelope.
+func PubSecEnvelopes(seed []byte, recipients []PubSecRecipient) ([][]byte, error) {
+	if len(seed) != pubSecSeedLen {
+		return nil, errors.New("invalid seed length")
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+
+	var order []Permissions
+	groups := make(map[Permissions][]*x509.Certificate)
+	for _, r := range recipients {
+		if r.Certificate == nil {
+			return nil, errors.New("recipient without a certificate")
+		}
+		if _, ok := groups[r.Permissions]; !ok {
+			order = append(order, r.Permissions)
+		}
+		groups[r.Permissions] = append(groups[r.Permissions], r.Certificate)
+	}
+
+	var envelopes [][]byte
+	for _, perm := range order {
+		content := make([]byte, pubSecSeedLen+4)
+		copy(content, seed)
+		binary.BigEndian.PutUint32(content[pubSecSeedLen:], uint32(perm))
+		env, err := envelope(content, groups[perm])
+		if err != nil {
+			return nil, err
+		}
+		envelopes = append(envelopes, env)
+	}
+	return envelopes, nil
+}
+
+// PubSecOpen searches `envelopes` for one addressed to `cert` and decrypts it with `key`.
+// It returns the seed and the permissions granted to the recipient. If no envelope is addressed
+// to `cert`, the returned seed is nil and no error is returned.
+// The content of the envelopes may be encrypted with AES or triple DES in CBC mode.
+func PubSecOpen(envelopes [][]byte, cert *x509.Certificate, key crypto.PrivateKey) ([]byte, Permissions, error) {
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, 0, errors.New("only RSA private keys are supported")
+	}
+	for _, env := range envelopes {
+		content, err := openEnvelope(env, cert, rsaKey)
+		if err != nil {
+			return nil, 0, err
+		}
+		if content == nil {
+			continue
+		}
+		if len(content) < pubSecSeedLen+4 {
+			return nil, 0, errInvalidField{Func: "PubSecOpen", Field: "Recipients", Exp: pubSecSeedLen + 4,
+				Got: len(content)}
+		}
+		perm := Permissions(binary.BigEndian.Uint32(content[pubSecSeedLen:]))
+		return content[:pubSecSeedLen], perm, nil
+	}
+	return nil, 0, nil
+}
+
+// PubSecKey computes the file encryption key of `keyLen` bytes from `seed` and all the `envelopes`
+// of the Recipients array (7.6.5.3 Public-Key Encryption Algorithms, p. 93). AESV3 (`sha256`)
+// uses SHA-256 instead of SHA-1.
+func PubSecKey(seed []byte, envelopes [][]byte, encryptMetadata bool, keyLen int, sha256Hash bool) []byte {
+	h := sha1.New()
+	if sha256Hash {
+		h = sha256.New()
+	}
+	h.Write(seed)
+	for _, env := range envelopes {
+		h.Write(env)
+	}
+	if !encryptMetadata {
+		h.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	key := h.Sum(nil)
+	if keyLen < len(key) {
+		key = key[:keyLen]
+	}
+	return key
+}
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidAES128CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC    = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+// PKCS#7 (RFC 2315) structures of the enveloped data.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type envelopedData struct {
+	Version              int
+	RecipientInfos       []recipientInfo `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type recipientInfo struct {
+	Version                int
+	IssuerAndSerialNumber  issuerAndSerial
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type issuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	// EncryptedContent is an implicitly tagged [0] OCTET STRING, either primitive or constructed.
+	EncryptedContent asn1.RawValue `asn1:"optional"`
+}
+
+// envelope encrypts `content` with a random AES-256 key in CBC mode and returns the DER encoded
+// PKCS#7 enveloped data in which the key is encrypted for each of the `certs`.
+func envelope(content []byte, certs []*x509.Certificate) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	// PKCS#7 padding.
+	pad := aes.BlockSize - len(content)%aes.BlockSize
+	data := make([]byte, len(content)+pad)
+	copy(data, content)
+	for i := len(content); i < len(data); i++ {
+		data[i] = byte(pad)
+	}
+	cipher.NewCBCEncrypter(newAESCipher(key), iv).CryptBlocks(data, data)
+
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]recipientInfo, len(certs))
+	for i, cert := range certs {
+		pub, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("only RSA recipient certificates are supported")
+		}
+		if len(cert.RawIssuer) == 0 {
+			return nil, errors.New("recipient certificate without issuer")
+		}
+		encKey, err := rsa.EncryptPKCS1v15(rand.Reader, pub, key)
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = recipientInfo{
+			IssuerAndSerialNumber: issuerAndSerial{
+				Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+				SerialNumber: cert.SerialNumber,
+			},
+			KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidRSAEncryption,
+				Parameters: asn1.NullRawValue,
+			},
+			EncryptedKey: encKey,
+		}
+	}
+
+	env, err := asn1.Marshal(envelopedData{
+		RecipientInfos: infos,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType: oidData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidAES256CBC,
+				Parameters: asn1.RawValue{FullBytes: ivParam},
+			},
+			EncryptedContent: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: data},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidEnvelopedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: env},
+	})
+}
+
+// openEnvelope decrypts the content of the PKCS#7 enveloped data `env` with `key`. It returns nil
+// if `env` is not addressed to `cert`.
+func openEnvelope(env []byte, cert *x509.Certificate, key *rsa.PrivateKey) ([]byte, error) {
+	var info contentInfo
+	var ed envelopedData
+	if _, err := asn1.Unmarshal(env, &info); err != nil {
+		// Not DER. Let the pkcs7 package convert from BER.
+		return openEnvelopeBER(env, cert, key)
+	}
+	if !info.ContentType.Equal(oidEnvelopedData) {
+		return nil, errors.New("recipient not enveloped data")
+	}
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &ed); err != nil {
+		return openEnvelopeBER(env, cert, key)
+	}
+
+	var encKey []byte
+	for _, ri := range ed.RecipientInfos {
+		ias := ri.IssuerAndSerialNumber
+		if ias.SerialNumber != nil && ias.SerialNumber.Cmp(cert.SerialNumber) == 0 &&
+			bytes.Equal(ias.Issuer.FullBytes, cert.RawIssuer) {
+			encKey = ri.EncryptedKey
+			break
+		}
+	}
+	if encKey == nil {
+		return nil, nil
+	}
+	ckey, err := rsa.DecryptPKCS1v15(rand.Reader, key, encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	eci := ed.EncryptedContentInfo
+	var block cipher.Block
+	switch alg := eci.ContentEncryptionAlgorithm.Algorithm; {
+	case alg.Equal(oidAES128CBC), alg.Equal(oidAES192CBC), alg.Equal(oidAES256CBC):
+		block, err = aes.NewCipher(ckey)
+	case alg.Equal(oidDESEDE3CBC):
+		block, err = des.NewTripleDESCipher(ckey)
+	default:
+		return nil, fmt.Errorf("unsupported content encryption algorithm: %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	iv := eci.ContentEncryptionAlgorithm.Parameters.Bytes
+	if len(iv) != block.BlockSize() {
+		return nil, errors.New("invalid content encryption parameters")
+	}
+
+	data := eci.EncryptedContent.Bytes
+	if eci.EncryptedContent.IsCompound {
+		// Constructed encoding: concatenate the OCTET STRING segments.
+		var buf bytes.Buffer
+		rest := data
+		for len(rest) > 0 {
+			var part []byte
+			rest, err = asn1.Unmarshal(rest, &part)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(part)
+		}
+		data = buf.Bytes()
+	}
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errors.New("invalid encrypted content length")
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > block.BlockSize() {
+		return nil, errors.New("invalid padding")
+	}
+	return plain[:len(plain)-pad], nil
+}
+
+// openEnvelopeBER decrypts the content of the BER encoded PKCS#7 enveloped data `env` with `key`.
+// It returns nil if `env` is not addressed to `cert`.
+func openEnvelopeBER(env []byte, cert *x509.Certificate, key *rsa.PrivateKey) ([]byte, error) {
+	p7, err := pkcs7.Parse(env)
+	if err != nil {
+		return nil, err
+	}
+	content, err := p7.Decrypt(cert, key)
+	if err != nil {
+		// The pkcs7 package does not distinguish a missing recipient from other failures.
+		common.Log.Debug("Unable to open PKCS#7 envelope: %v", err)
+		return nil, nil
+	}
+	return content, nil
+}
@@ -0,0 +1,114 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package security
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCert returns a self-signed certificate and its private key.
+func newTestCert(t *testing.T, name string, serial int64) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return cert, key
+}
+
+func TestPubSecEnvelopes(t *testing.T) {
+	cert1, key1 := newTestCert(t, "Alice", 1)
+	cert2, key2 := newTestCert(t, "Bob", 2)
+	cert3, key3 := newTestCert(t, "Carol", 3)
+	cert4, key4 := newTestCert(t, "Mallory", 4)
+
+	perm := PermPrinting | PermExtractGraphics
+	recipients := []PubSecRecipient{
+		{Certificate: cert1, Permissions: PermOwner},
+		{Certificate: cert2, Permissions: perm},
+		{Certificate: cert3, Permissions: PermOwner},
+	}
+	seed, err := NewPubSecSeed()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	envelopes, err := PubSecEnvelopes(seed, recipients)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(envelopes) != 2 {
+		t.Fatalf("Recipients with the same permissions should share an envelope: %d", len(envelopes))
+	}
+
+	var cases = []struct {
+		cert *x509.Certificate
+		key  *rsa.PrivateKey
+		perm Permissions
+	}{
+		{cert1, key1, PermOwner},
+		{cert2, key2, perm},
+		{cert3, key3, PermOwner},
+	}
+	for _, c := range cases {
+		got, p, err := PubSecOpen(envelopes, c.cert, c.key)
+		if err != nil {
+			t.Fatalf("%s: Error: %v", c.cert.Subject.CommonName, err)
+		}
+		if !bytes.Equal(got, seed) {
+			t.Errorf("%s: Wrong seed", c.cert.Subject.CommonName)
+		}
+		if p != c.perm {
+			t.Errorf("%s: Wrong permissions: %v, want %v", c.cert.Subject.CommonName, p, c.perm)
+		}
+	}
+
+	got, _, err := PubSecOpen(envelopes, cert4, key4)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if got != nil {
+		t.Errorf("Envelope opened for a certificate that is not a recipient")
+	}
+}
+
+func TestPubSecKey(t *testing.T) {
+	seed := bytes.Repeat([]byte{1}, pubSecSeedLen)
+	envelopes := [][]byte{[]byte("first"), []byte("second")}
+
+	key := PubSecKey(seed, envelopes, true, 16, false)
+	if len(key) != 16 {
+		t.Fatalf("Wrong key length: %d", len(key))
+	}
+	if other := PubSecKey(seed, envelopes, false, 16, false); bytes.Equal(key, other) {
+		t.Errorf("EncryptMetadata should change the key")
+	}
+	if other := PubSecKey(seed, envelopes[:1], true, 16, false); bytes.Equal(key, other) {
+		t.Errorf("All recipients should contribute to the key")
+	}
+	if key := PubSecKey(seed, envelopes, true, 32, true); len(key) != 32 {
+		t.Errorf("Wrong key length: %d", len(key))
+	}
+}
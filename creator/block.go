@@ -439,7 +439,11 @@ func mergeContents(contents *contentstream.ContentStreamOperations, resources *m
 							useName = resourcesNextUnusedFontName(name.String(), obj, resources)
 						}
 
-						resources.SetFontByName(useName, obj)
+						if font, has := resourcesToAdd.GetFont(*name); has {
+							resources.SetFont(useName, font)
+						} else {
+							resources.SetFontByName(useName, obj)
+						}
 						fontMap[*name] = useName
 					}
 
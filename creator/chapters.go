@@ -59,15 +59,23 @@ type Chapter struct {
 	// The item of the chapter in the outline.
 	outlineItem *model.OutlineItem
 
+	// Reference to the named destinations of the creator.
+	destinations *namedDestinations
+
+	// The name of the destination of the chapter heading.
+	destName string
+
 	// The level of the chapter in the chapters hierarchy.
 	level uint
 }
 
 // newChapter creates a new chapter with the specified title as the heading.
-func newChapter(parent *Chapter, toc *TOC, outline *model.Outline, title string, number int, style TextStyle) *Chapter {
+func newChapter(parent *Chapter, toc *TOC, outline *model.Outline, destinations *namedDestinations, title string, number int, style TextStyle) *Chapter {
 	var level uint = 1
+	destName := "chapter." + strconv.Itoa(number)
 	if parent != nil {
 		level = parent.level + 1
+		destName = parent.destName + "." + strconv.Itoa(number)
 	}
 
 	chapter := &Chapter{
@@ -78,6 +86,8 @@ func newChapter(parent *Chapter, toc *TOC, outline *model.Outline, title string,
 		parent:        parent,
 		toc:           toc,
 		outline:       outline,
+		destinations:  destinations,
+		destName:      destName,
 		contents:      []Drawable{},
 		level:         level,
 	}
@@ -86,6 +96,13 @@ func newChapter(parent *Chapter, toc *TOC, outline *model.Outline, title string,
 	p.SetFont(style.Font)
 	p.SetFontSize(style.FontSize)
 
+	// Tagged as H1 to H6 by chapter level.
+	headingLevel := level
+	if headingLevel > 6 {
+		headingLevel = 6
+	}
+	p.structType = "H" + strconv.Itoa(int(headingLevel))
+
 	chapter.heading = p
 	return chapter
 }
@@ -96,12 +113,19 @@ func (chap *Chapter) NewSubchapter(title string) *Chapter {
 	style.FontSize = 14
 
 	chap.subchapters++
-	subchapter := newChapter(chap, chap.toc, chap.outline, title, chap.subchapters, style)
+	subchapter := newChapter(chap, chap.toc, chap.outline, chap.destinations, title, chap.subchapters, style)
 	chap.Add(subchapter)
 
 	return subchapter
 }
 
+// DestinationName returns the name of the named destination of the chapter heading, e.g.
+// "chapter.2.1" for the first subchapter of the second chapter. It can be used as the target of
+// links, see StyledParagraph.AddNamedLink.
+func (chap *Chapter) DestinationName() string {
+	return chap.destName
+}
+
 // SetShowNumbering sets a flag to indicate whether or not to show chapter numbers as part of title.
 func (chap *Chapter) SetShowNumbering(show bool) {
 	chap.heading.SetText(chap.headingText())
@@ -192,6 +216,11 @@ func (chap *Chapter) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 		ctx.Height -= chap.margins.top
 	}
 
+	// The heading and the contents are grouped in a section in tagged output.
+	if elem := ctx.newStructElem("Sect"); elem != nil {
+		ctx.structElem = elem
+	}
+
 	blocks, c, err := chap.heading.GeneratePageBlocks(ctx)
 	if err != nil {
 		return blocks, ctx, err
@@ -206,11 +235,14 @@ func (chap *Chapter) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 	chapNumber := chap.headingNumber()
 	chapTitle := chap.headingText()
 
+	// Register the destination of the heading, targeted by the TOC and the outline.
+	chap.destinations.add(chap.destName, int(page), posX, posY)
+
 	// Add to TOC.
 	if chap.includeInTOC {
 		line := chap.toc.Add(chapNumber, chap.title, strconv.FormatInt(page, 10), chap.level)
 		if chap.toc.showLinks {
-			line.SetLink(page, posX, posY)
+			line.SetNamedLink(chap.destName)
 		}
 	}
 
@@ -218,7 +250,7 @@ func (chap *Chapter) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 	if chap.outlineItem == nil {
 		chap.outlineItem = model.NewOutlineItem(
 			chapTitle,
-			model.NewOutlineDest(page-1, posX, posY),
+			model.NewOutlineNamedDest(chap.destName),
 		)
 
 		if chap.parent != nil {
@@ -226,11 +258,6 @@ func (chap *Chapter) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 		} else {
 			chap.outline.Add(chap.outlineItem)
 		}
-	} else {
-		outlineDest := &chap.outlineItem.Dest
-		outlineDest.Page = page - 1
-		outlineDest.X = posX
-		outlineDest.Y = posY
 	}
 
 	for _, d := range chap.contents {
@@ -253,6 +280,7 @@ func (chap *Chapter) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 		// Move back X to same start of line.
 		ctx.X = origCtx.X
 	}
+	ctx.structElem = origCtx.structElem
 
 	if chap.positioning.isAbsolute() {
 		// If absolute: return original context.
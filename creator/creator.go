@@ -54,11 +54,36 @@ type Creator struct {
 	// Outline.
 	outline *model.Outline
 
+	// Controls whether the output is a tagged PDF. The paragraphs, chapters, tables, lists, images
+	// and the table of contents drawn while set are added to the structure tree of the document.
+	Tagged bool
+
+	// Structure tree of tagged output.
+	structTree *structTree
+
 	// Forms.
 	acroForm *model.PdfAcroForm
 
+	// Embedded files.
+	attachments []*model.PdfFilespec
+
+	// Page labels, keyed by the number of the first page of each range. The page numbers of the
+	// ranges set by the user do not include the generated front page and TOC pages.
+	pageLabels       map[int]model.PdfPageLabel
+	frontMatterLabel *model.PdfPageLabel
+	finalPageLabels  map[int]model.PdfPageLabel
+
 	optimizer model.Optimizer
 
+	// PDF/A conformance level of the output.
+	pdfa model.PdfAConformance
+
+	// Document information of the output, defaults to the model package settings if nil.
+	info *model.PdfInfo
+
+	// Named destinations registered by chapters and the user.
+	destinations *namedDestinations
+
 	// Default fonts used by all components instantiated through the creator.
 	defaultFontRegular *model.PdfFont
 	defaultFontBold    *model.PdfFont
@@ -70,6 +95,86 @@ func (c *Creator) SetForms(form *model.PdfAcroForm) error {
 	return nil
 }
 
+// AttachFile embeds the file specified by `fs` in the output PDF.
+func (c *Creator) AttachFile(fs *model.PdfFilespec) {
+	for _, attached := range c.attachments {
+		if attached == fs {
+			return
+		}
+	}
+	c.attachments = append(c.attachments, fs)
+}
+
+// replaceAttachment replaces the attached file `old` with `fs`, or attaches `fs` if `old` is not
+// attached.
+func (c *Creator) replaceAttachment(old, fs *model.PdfFilespec) {
+	for i, attached := range c.attachments {
+		if attached == old {
+			c.attachments[i] = fs
+			return
+		}
+	}
+	c.AttachFile(fs)
+}
+
+// AddPageLabelRange labels the pages from page number `pageNum` up to the start of the next range
+// with `label`. The page numbers do not include the front page and the table of contents pages
+// generated by the creator, which are labelled separately (see SetFrontMatterPageLabel).
+// When page labels are defined, the page numbers of the table of contents show the labels.
+func (c *Creator) AddPageLabelRange(pageNum int, label model.PdfPageLabel) {
+	if c.pageLabels == nil {
+		c.pageLabels = map[int]model.PdfPageLabel{}
+	}
+	c.pageLabels[pageNum] = label
+}
+
+// SetFrontMatterPageLabel sets the label of the front page and the table of contents pages
+// generated by the creator. Lowercase roman numerals are used when page labels are defined for
+// the other pages only.
+func (c *Creator) SetFrontMatterPageLabel(label model.PdfPageLabel) {
+	c.frontMatterLabel = &label
+}
+
+// pageLabelRanges returns the page label ranges of the output document, keyed by the number of
+// the first page of each range, accounting for `genpages` generated front matter pages.
+func (c *Creator) pageLabelRanges(genpages int) map[int]model.PdfPageLabel {
+	if len(c.pageLabels) == 0 && c.frontMatterLabel == nil {
+		return nil
+	}
+
+	ranges := map[int]model.PdfPageLabel{}
+	for pageNum, label := range c.pageLabels {
+		ranges[pageNum+genpages] = label
+	}
+	if genpages > 0 {
+		front := model.PdfPageLabel{Style: model.PageLabelStyleLowerRoman}
+		if c.frontMatterLabel != nil {
+			front = *c.frontMatterLabel
+		}
+		ranges[1] = front
+
+		// Restart the numbering at the first page following the front matter.
+		if _, has := ranges[genpages+1]; !has {
+			ranges[genpages+1] = model.PdfPageLabel{Style: model.PageLabelStyleDecimal}
+		}
+	}
+	return ranges
+}
+
+// pageLabel returns the label of page `pageNum` with the page label ranges `ranges`.
+func pageLabel(ranges map[int]model.PdfPageLabel, pageNum int) string {
+	start := 0
+	for first := range ranges {
+		if first <= pageNum && first > start {
+			start = first
+		}
+	}
+	if start == 0 {
+		return strconv.Itoa(pageNum)
+	}
+	return ranges[start].Label(pageNum - start)
+}
+
 // FrontpageFunctionArgs holds the input arguments to a front page drawing function.
 // It is designed as a struct, so additional parameters can be added in the future with backwards
 // compatibility.
@@ -134,6 +239,8 @@ func New() *Creator {
 	c.AddOutlines = true
 	c.outline = model.NewOutline()
 
+	c.destinations = &namedDestinations{}
+
 	return c
 }
 
@@ -147,6 +254,19 @@ func (c *Creator) GetOptimizer() model.Optimizer {
 	return c.optimizer
 }
 
+// SetPdfAConformance sets the PDF/A conformance level of the output (see
+// model.PdfWriter.SetPdfAConformance). All fonts used must be embedded, e.g. loaded with
+// model.NewPdfFontFromTTFFile, as the standard 14 fonts used by default are not.
+func (c *Creator) SetPdfAConformance(conformance model.PdfAConformance) {
+	c.pdfa = conformance
+}
+
+// SetPdfInfo sets the document information dictionary of the output (see
+// model.PdfWriter.SetPdfInfo).
+func (c *Creator) SetPdfInfo(info *model.PdfInfo) {
+	c.info = info
+}
+
 // SetPageMargins sets the page margins: left, right, top, bottom.
 // The default page margins are 10% of document width.
 func (c *Creator) SetPageMargins(left, right, top, bottom float64) {
@@ -277,6 +397,20 @@ func (c *Creator) initContext() {
 	c.context.Margins = c.pageMargins
 }
 
+// initStructTree sets up the drawing context for adding the drawn components to the structure
+// tree when the output is tagged.
+func (c *Creator) initStructTree() {
+	if !c.Tagged {
+		c.context = c.context.untagged()
+		return
+	}
+	if c.structTree == nil {
+		c.structTree = newStructTree()
+	}
+	c.context.structTree = c.structTree
+	c.context.structElem = c.structTree.root
+}
+
 // NewPage adds a new Page to the Creator and sets as the active Page.
 func (c *Creator) NewPage() {
 	page := c.newPage()
@@ -336,6 +470,7 @@ func (c *Creator) Context() DrawContext {
 // as generating front Page and table of contents.
 func (c *Creator) finalize() error {
 	totPages := len(c.pages)
+	c.initStructTree()
 
 	// Estimate number of additional generated pages and update TOC.
 	genpages := 0
@@ -353,7 +488,7 @@ func (c *Creator) finalize() error {
 		}
 
 		// Make an estimate of the number of pages.
-		blocks, _, err := c.toc.GeneratePageBlocks(c.context)
+		blocks, _, err := c.toc.GeneratePageBlocks(c.context.untagged())
 		if err != nil {
 			common.Log.Debug("Failed to generate blocks: %v", err)
 			return err
@@ -361,6 +496,7 @@ func (c *Creator) finalize() error {
 		genpages += len(blocks)
 
 		// Update the table of content Page numbers, accounting for front Page and TOC.
+		labels := c.pageLabelRanges(genpages)
 		lines := c.toc.Lines()
 		for _, line := range lines {
 			pageNum, err := strconv.Atoi(line.Page.Text)
@@ -368,9 +504,15 @@ func (c *Creator) finalize() error {
 				continue
 			}
 
-			line.Page.Text = strconv.Itoa(pageNum + genpages)
+			if labels != nil {
+				line.Page.Text = pageLabel(labels, pageNum+genpages)
+			} else {
+				line.Page.Text = strconv.Itoa(pageNum + genpages)
+			}
 		}
 	}
+	c.finalPageLabels = c.pageLabelRanges(genpages)
+	c.destinations.shift(genpages)
 
 	hasFrontPage := false
 	// Generate the front Page.
@@ -480,6 +622,9 @@ func (c *Creator) finalize() error {
 			}
 			c.drawHeaderFunc(headerBlock, args)
 			headerBlock.SetPos(0, 0)
+			if c.Tagged {
+				markArtifact(headerBlock, 0)
+			}
 			err := c.Draw(headerBlock)
 			if err != nil {
 				common.Log.Debug("ERROR: drawing header: %v", err)
@@ -497,6 +642,9 @@ func (c *Creator) finalize() error {
 			}
 			c.drawFooterFunc(footerBlock, args)
 			footerBlock.SetPos(0, c.pageHeight-footerBlock.height)
+			if c.Tagged {
+				markArtifact(footerBlock, 0)
+			}
 			err := c.Draw(footerBlock)
 			if err != nil {
 				common.Log.Debug("ERROR: drawing footer: %v", err)
@@ -543,6 +691,7 @@ func (c *Creator) Draw(d Drawable) error {
 		// Add a new Page if none added already.
 		c.NewPage()
 	}
+	c.initStructTree()
 
 	blocks, ctx, err := d.GeneratePageBlocks(c.context)
 	if err != nil {
@@ -555,6 +704,9 @@ func (c *Creator) Draw(d Drawable) error {
 		}
 
 		p := c.getActivePage()
+		if c.context.structTree != nil {
+			c.context.structTree.assignMCIDs(blk, p)
+		}
 		err := blk.drawToPage(p)
 		if err != nil {
 			return err
@@ -577,6 +729,10 @@ func (c *Creator) Write(ws io.Writer) error {
 
 	pdfWriter := model.NewPdfWriter()
 	pdfWriter.SetOptimizer(c.optimizer)
+	pdfWriter.SetPdfAConformance(c.pdfa)
+	if c.info != nil {
+		pdfWriter.SetPdfInfo(c.info)
+	}
 
 	// Form fields.
 	if c.acroForm != nil {
@@ -592,6 +748,27 @@ func (c *Creator) Write(ws io.Writer) error {
 		pdfWriter.AddOutlineTree(&c.outline.ToPdfOutline().PdfOutlineTreeNode)
 	}
 
+	// Page labels.
+	for pageNum, label := range c.finalPageLabels {
+		if err := pdfWriter.AddPageLabelRange(pageNum, label); err != nil {
+			common.Log.Debug("Failure: %v", err)
+			return err
+		}
+	}
+
+	// Structure tree.
+	if c.structTree != nil {
+		pdfWriter.SetStructTreeRoot(c.structTree.toStructTreeRoot(c.pages))
+	}
+
+	// Embedded files.
+	for _, fs := range c.attachments {
+		if err := pdfWriter.AttachFile(fs); err != nil {
+			common.Log.Debug("Failure: %v", err)
+			return err
+		}
+	}
+
 	// Pdf Writer access hook. Can be used to encrypt, etc. via the PdfWriter instance.
 	if c.pdfWriterAccessFunc != nil {
 		err := c.pdfWriterAccessFunc(&pdfWriter)
@@ -609,6 +786,12 @@ func (c *Creator) Write(ws io.Writer) error {
 		}
 	}
 
+	// Named destinations.
+	if err := c.destinations.addToWriter(&pdfWriter, c.pages, c.pageHeight); err != nil {
+		common.Log.Debug("Failure: %v", err)
+		return err
+	}
+
 	err := pdfWriter.Write(ws)
 	if err != nil {
 		return err
@@ -716,7 +899,7 @@ func (c *Creator) NewChapter(title string) *Chapter {
 	style := c.NewTextStyle()
 	style.FontSize = 16
 
-	return newChapter(nil, c.toc, c.outline, title, c.chapters, style)
+	return newChapter(nil, c.toc, c.outline, c.destinations, title, c.chapters, style)
 }
 
 // NewInvoice returns an instance of an empty invoice.
@@ -724,7 +907,9 @@ func (c *Creator) NewInvoice() *Invoice {
 	headingStyle := c.NewTextStyle()
 	headingStyle.Font = c.defaultFontBold
 
-	return newInvoice(c.NewTextStyle(), headingStyle)
+	invoice := newInvoice(c.NewTextStyle(), headingStyle)
+	invoice.attachFile = c.replaceAttachment
+	return invoice
 }
 
 // NewList creates a new list.
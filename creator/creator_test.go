@@ -3132,3 +3132,262 @@ func copyFile(src, dst string) error {
 	_, err = io.Copy(out, in)
 	return err
 }
+
+// Test page labels of a report with a front page and a table of contents.
+func TestPageLabelsTOC(t *testing.T) {
+	c := New()
+	c.AddTOC = true
+
+	c.CreateFrontPage(func(args FrontpageFunctionArgs) {
+		c.Draw(c.NewParagraph("Report"))
+	})
+
+	for i := 0; i < 3; i++ {
+		ch := c.NewChapter(fmt.Sprintf("Chapter %d", i+1))
+		ch.Add(c.NewParagraph("Lorem ipsum dolor sit amet."))
+		require.NoError(t, c.Draw(ch))
+		c.NewPage()
+	}
+	c.AddPageLabelRange(3, model.PdfPageLabel{Style: model.PageLabelStyleDecimal, Prefix: "A-"})
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	labels, err := reader.GetPageLabels()
+	require.NoError(t, err)
+	// Front page, TOC page, 3 chapters and a blank page.
+	require.Equal(t, []string{"i", "ii", "1", "2", "A-1", "A-2"}, labels)
+
+	var pages []string
+	for _, line := range c.TOC().Lines() {
+		pages = append(pages, line.Page.Text)
+	}
+	require.Equal(t, []string{"1", "2", "A-1"}, pages)
+}
+
+func TestTaggedPDF(t *testing.T) {
+	c := New()
+	c.Tagged = true
+	c.AddTOC = true
+
+	c.CreateFrontPage(func(args FrontpageFunctionArgs) {
+		c.Draw(c.NewParagraph("Report"))
+	})
+	c.DrawHeader(func(block *Block, args HeaderFunctionArgs) {
+		block.Draw(c.NewParagraph("Header"))
+	})
+
+	ch := c.NewChapter("Introduction")
+	ch.Add(c.NewParagraph("Lorem ipsum dolor sit amet."))
+
+	table := c.NewTable(2)
+	require.NoError(t, table.SetHeaderRows(1, 1))
+	for _, text := range []string{"Name", "Value", "A", "1"} {
+		table.NewCell().SetContent(c.NewParagraph(text))
+	}
+	ch.Add(table)
+
+	imgData, err := ioutil.ReadFile(testImageFile1)
+	require.NoError(t, err)
+	img, err := c.NewImageFromData(imgData)
+	require.NoError(t, err)
+	img.ScaleToWidth(50)
+	img.SetAltText("Company logo")
+	ch.Add(img)
+
+	require.NoError(t, c.Draw(ch))
+
+	list := c.NewList()
+	_, _, err = list.AddTextItem("Item")
+	require.NoError(t, err)
+	require.NoError(t, c.Draw(list))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	catalog, ok := core.GetDict(trailer.Get("Root"))
+	require.True(t, ok)
+
+	markInfo, ok := core.GetDict(catalog.Get("MarkInfo"))
+	require.True(t, ok)
+	marked, ok := core.GetBoolVal(markInfo.Get("Marked"))
+	require.True(t, ok)
+	require.True(t, marked)
+
+	root, ok := core.GetDict(catalog.Get("StructTreeRoot"))
+	require.True(t, ok)
+	_, ok = core.GetDict(root.Get("ParentTree"))
+	require.True(t, ok)
+
+	// Structure types in logical reading order, the front page and the TOC first.
+	var types []string
+	var alt string
+	var walk func(obj core.PdfObject)
+	walk = func(obj core.PdfObject) {
+		if arr, ok := core.GetArray(obj); ok {
+			for _, kid := range arr.Elements() {
+				walk(kid)
+			}
+			return
+		}
+		dict, ok := core.GetDict(obj)
+		if !ok {
+			return
+		}
+		s, ok := core.GetNameVal(dict.Get("S"))
+		if !ok {
+			return
+		}
+		types = append(types, s)
+		if s == "Figure" {
+			altStr, _ := core.GetString(dict.Get("Alt"))
+			require.NotNil(t, altStr)
+			alt = altStr.Decoded()
+		}
+		walk(dict.Get("K"))
+	}
+	walk(root.Get("K"))
+	require.Equal(t, []string{
+		"Document",
+		"P",
+		"H1", "TOC", "TOCI", "P",
+		"Sect", "H1", "P",
+		"Table", "TR", "TH", "P", "TH", "P", "TR", "TD", "P", "TD", "P",
+		"Figure",
+		"L", "LI", "Lbl", "LBody", "P",
+	}, types)
+	require.Equal(t, "Company logo", alt)
+
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 3, numPages)
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		key, ok := core.GetIntVal(page.GetPageDict().Get("StructParents"))
+		require.True(t, ok)
+		require.Equal(t, i-1, key)
+
+		content, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		require.Contains(t, content, "<</MCID 0>> BDC")
+		require.Contains(t, content, "/Artifact BMC")
+	}
+}
+
+func TestPdfAConformance(t *testing.T) {
+	roboto, err := model.NewPdfFontFromTTFFile(testRobotoRegularTTFFile)
+	require.NoError(t, err)
+
+	c := New()
+	c.SetPdfAConformance(model.PdfAConformance3B)
+	p := c.NewParagraph("Statement")
+	p.SetFont(roboto)
+	p.SetColor(ColorRGBFromHex("#336699"))
+	require.NoError(t, c.Draw(p))
+
+	// Only the font of the watermark of unlicensed copies is not embedded.
+	err = c.Write(&bytes.Buffer{})
+	pdfaErr, ok := err.(*model.PdfAError)
+	require.True(t, ok, "%v", err)
+	require.Equal(t, []string{"font Helvetica is not embedded"}, pdfaErr.Violations)
+}
+
+func TestPdfInfo(t *testing.T) {
+	c := New()
+	info := model.NewPdfInfo()
+	info.Title = "Statement"
+	info.SetCustomInfo("Account", "0001")
+	c.SetPdfInfo(info)
+	require.NoError(t, c.Draw(c.NewParagraph("Statement")))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	read, err := reader.GetPdfInfo()
+	require.NoError(t, err)
+	require.Equal(t, "Statement", read.Title)
+	require.Equal(t, "0001", read.CustomInfo("Account"))
+}
+
+func TestChapterNamedDestinations(t *testing.T) {
+	c := New()
+	c.AddTOC = true
+	ch1 := c.NewChapter("Introduction")
+	sub := ch1.NewSubchapter("Scope")
+	require.NoError(t, c.Draw(ch1))
+	c.NewPage()
+	ch2 := c.NewChapter("Summary")
+	p := c.NewStyledParagraph()
+	p.AddNamedLink("Back to the scope", sub.DestinationName())
+	require.NoError(t, ch2.Add(p))
+	require.NoError(t, c.Draw(ch2))
+	require.Equal(t, "chapter.1.1", sub.DestinationName())
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	// The destinations account for the TOC page.
+	dests, err := reader.GetNamedDestinations()
+	require.NoError(t, err)
+	require.Len(t, dests, 3)
+	require.Equal(t, 2, dests["chapter.1"].PageNumber)
+	require.Equal(t, 2, dests["chapter.1.1"].PageNumber)
+	require.Equal(t, 3, dests["chapter.2"].PageNumber)
+	require.Equal(t, model.DestinationXYZ, dests["chapter.2"].Type)
+
+	// The TOC lines link to the destinations by name.
+	tocPage, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annots, err := tocPage.GetAnnotations()
+	require.NoError(t, err)
+	names := map[string]bool{}
+	for _, annot := range annots {
+		link, ok := annot.GetContext().(*model.PdfAnnotationLink)
+		require.True(t, ok)
+		name, ok := core.GetStringVal(link.Dest)
+		require.True(t, ok)
+		names[name] = true
+	}
+	require.Equal(t, map[string]bool{"chapter.1": true, "chapter.1.1": true, "chapter.2": true}, names)
+
+	page, err := reader.GetPage(3)
+	require.NoError(t, err)
+	annots, err = page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 1)
+	name, _ := core.GetStringVal(annots[0].GetContext().(*model.PdfAnnotationLink).Dest)
+	require.Equal(t, "chapter.1.1", name)
+}
+
+func TestNamedDestinationsPageSizes(t *testing.T) {
+	c := New()
+	c.SetPageSize(PageSizeA4)
+	c.NewPage()
+	c.SetPageSize(PageSizeA5)
+	c.NewPage()
+	c.AddNamedDestination("first", 1, 10, 20)
+	c.AddNamedDestination("second", 2, 10, 20)
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	// The positions are converted with the height of the page of each destination.
+	dests, err := reader.GetNamedDestinations()
+	require.NoError(t, err)
+	require.Len(t, dests, 2)
+	require.InDelta(t, PageSizeA4[1]-20, *dests["first"].Top, 1e-6)
+	require.InDelta(t, PageSizeA5[1]-20, *dests["second"].Top, 1e-6)
+	require.InDelta(t, 10, *dests["second"].Left, 1e-6)
+}
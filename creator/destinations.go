@@ -0,0 +1,84 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package creator
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// namedDestination is a destination registered by name while drawing.
+type namedDestination struct {
+	name string
+
+	// Page number, starting from 1, and position on the page. Position 0, 0 is at the top left
+	// of the page.
+	page int
+	x, y float64
+}
+
+// namedDestinations holds the named destinations of the creator, in the order they were
+// registered.
+type namedDestinations struct {
+	dests []*namedDestination
+	index map[string]*namedDestination
+}
+
+// add registers destination `name` at position `x`, `y` of page `page`, replacing the position of
+// a destination previously registered with the same name.
+func (d *namedDestinations) add(name string, page int, x, y float64) {
+	if d.index == nil {
+		d.index = map[string]*namedDestination{}
+	}
+	dest, has := d.index[name]
+	if !has {
+		dest = &namedDestination{name: name}
+		d.index[name] = dest
+		d.dests = append(d.dests, dest)
+	}
+	dest.page = page
+	dest.x = x
+	dest.y = y
+}
+
+// shift moves the destinations `n` pages forward, accounting for pages inserted at the front.
+func (d *namedDestinations) shift(n int) {
+	for _, dest := range d.dests {
+		dest.page += n
+	}
+}
+
+// addToWriter adds the destinations to `w`, targeting `pages`. The top of pages without a valid
+// media box is at `pageHeight`.
+func (d *namedDestinations) addToWriter(w *model.PdfWriter, pages []*model.PdfPage, pageHeight float64) error {
+	for _, dest := range d.dests {
+		if dest.page < 1 || dest.page > len(pages) {
+			common.Log.Debug("ERROR: Destination %q page %d out of range", dest.name, dest.page)
+			continue
+		}
+		// Pages can have different sizes.
+		page := pages[dest.page-1]
+		top := pageHeight
+		if mbox, err := page.GetMediaBox(); err == nil {
+			top = mbox.Ury
+		}
+		pdfDest, err := model.NewPdfDestination(page.GetPageAsIndirectObject(), model.DestinationXYZ,
+			dest.x, top-dest.y, 0)
+		if err != nil {
+			return err
+		}
+		w.AddNamedDestination(dest.name, pdfDest)
+	}
+	return nil
+}
+
+// AddNamedDestination registers destination `name` at position `x`, `y` of page `page`, counting
+// from 1 the pages drawn, without the generated front page and table of contents pages. Position
+// 0, 0 is at the top left of the page. Links can target the destination by name, e.g. with
+// StyledParagraph.AddNamedLink, and stay valid when pages are inserted or merged.
+func (c *Creator) AddNamedDestination(name string, page int, x, y float64) {
+	c.destinations.add(name, page, x, y)
+}
@@ -5,6 +5,10 @@
 
 package creator
 
+import (
+	"github.com/unidoc/unipdf/v3/model"
+)
+
 // Drawable is a widget that can be used to draw with the Creator.
 type Drawable interface {
 	// GeneratePageBlocks draw onto blocks representing Page contents. As the content can wrap over many pages, multiple
@@ -46,4 +50,9 @@ type DrawContext struct {
 
 	// Controls whether the components are stacked horizontally
 	Inline bool
+
+	// Structure tree of tagged output (nil if untagged) and the structure element the drawn
+	// components are added to.
+	structTree *structTree
+	structElem *model.PdfStructElement
 }
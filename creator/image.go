@@ -53,6 +53,9 @@ type Image struct {
 
 	// Encoder
 	encoder core.StreamEncoder
+
+	// Alternate description of the image in tagged output.
+	altText string
 }
 
 // newImage create a new image from a unidoc image (model.Image).
@@ -120,6 +123,12 @@ func (img *Image) SetEncoder(encoder core.StreamEncoder) {
 	img.encoder = encoder
 }
 
+// SetAltText sets the alternate description of the image, which is used by assistive technology
+// when the output is a tagged PDF.
+func (img *Image) SetAltText(text string) {
+	img.altText = text
+}
+
 // Height returns Image's document height.
 func (img *Image) Height() float64 {
 	return img.height
@@ -224,6 +233,10 @@ func (img *Image) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, er
 
 	blocks = append(blocks, blk)
 
+	if elem := tagBlocks(origCtx, blocks, "Figure"); elem != nil {
+		elem.Alt = img.altText
+	}
+
 	if img.positioning.isAbsolute() {
 		// Absolute drawing should not affect context.
 		ctx = origCtx
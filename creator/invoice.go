@@ -5,7 +5,11 @@
 
 package creator
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
 
 // InvoiceAddress contains contact information that can be displayed
 // in an invoice. It is used for the seller and buyer information in the
@@ -90,6 +94,11 @@ type Invoice struct {
 	itemProps  InvoiceCellProps
 	totalProps InvoiceCellProps
 
+	// Machine readable invoice embedded in the document, attached to the output of the creator
+	// of the invoice by attachFile in place of the previous one.
+	facturX    *model.PdfFilespec
+	attachFile func(old, fs *model.PdfFilespec)
+
 	// Positioning: relative/absolute.
 	positioning positioning
 }
@@ -426,6 +435,29 @@ func (i *Invoice) AddSection(title, content string) {
 	})
 }
 
+// FacturX returns the file specification of the Factur-X/ZUGFeRD XML embedded with the invoice.
+// Returns nil if not set.
+func (i *Invoice) FacturX() *model.PdfFilespec {
+	return i.facturX
+}
+
+// SetFacturX sets the Factur-X/ZUGFeRD XML (Cross Industry Invoice) of the invoice. The XML is
+// embedded in the output PDF of the Creator of the invoice as "factur-x.xml" with the Alternative
+// AFRelationship, in place of the XML set previously. Returns the file specification, which can
+// be used to change the file name (e.g. "zugferd-invoice.xml" for ZUGFeRD 1.0) or the
+// relationship.
+func (i *Invoice) SetFacturX(xml []byte) *model.PdfFilespec {
+	fs := model.NewPdfFilespec("factur-x.xml", xml)
+	fs.Description = "Factur-X invoice"
+	fs.AFRelationship = model.AFRelationshipAlternative
+	fs.EmbeddedFile.Subtype = "text/xml"
+	if i.attachFile != nil {
+		i.attachFile(i.facturX, fs)
+	}
+	i.facturX = fs
+	return fs
+}
+
 // TitleStyle returns the style properties used to render the invoice title.
 func (i *Invoice) TitleStyle() TextStyle {
 	return i.titleStyle
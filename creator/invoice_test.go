@@ -1,6 +1,7 @@
 package creator
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -225,3 +226,79 @@ func TestInvoiceAdvanced(t *testing.T) {
 		t.Fatalf("Fail: %v\n", err)
 	}
 }
+
+func TestInvoiceFacturX(t *testing.T) {
+	c := New()
+	invoice := c.NewInvoice()
+	invoice.SetNumber("0001")
+	invoice.AddLine("Test product", "1", "$10", "$10")
+	invoice.SetTotal("$10.00")
+
+	xml := []byte(`<?xml version="1.0" encoding="UTF-8"?><rsm:CrossIndustryInvoice/>`)
+	invoice.SetFacturX(xml)
+
+	if err := c.Draw(invoice); err != nil {
+		t.Fatalf("Error drawing: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("Fail: %v\n", err)
+	}
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Fail: %v\n", err)
+	}
+	files, err := reader.GetEmbeddedFiles()
+	if err != nil {
+		t.Fatalf("Fail: %v\n", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Wrong number of embedded files: %d", len(files))
+	}
+	fs := files[0]
+	if fs.FileName != "factur-x.xml" || fs.AFRelationship != model.AFRelationshipAlternative {
+		t.Errorf("Wrong file specification: %s %s", fs.FileName, fs.AFRelationship)
+	}
+	if fs.EmbeddedFile == nil || !bytes.Equal(fs.EmbeddedFile.Content, xml) || fs.EmbeddedFile.Subtype != "text/xml" {
+		t.Errorf("Wrong embedded file")
+	}
+}
+
+// TestInvoiceFacturXBlock checks that the Factur-X XML of an invoice drawn in a block is embedded,
+// once and as last set.
+func TestInvoiceFacturXBlock(t *testing.T) {
+	c := New()
+	invoice := c.NewInvoice()
+	invoice.SetNumber("0001")
+	invoice.AddLine("Test product", "1", "$10", "$10")
+	invoice.SetTotal("$10.00")
+	invoice.SetFacturX([]byte("<rsm:CrossIndustryInvoice/>"))
+	xml := []byte("<rsm:CrossIndustryInvoice><rsm:ExchangedDocument/></rsm:CrossIndustryInvoice>")
+	invoice.SetFacturX(xml)
+
+	block := NewBlock(c.Width(), c.Height())
+	if err := block.Draw(invoice); err != nil {
+		t.Fatalf("Error drawing: %v", err)
+	}
+	if err := c.Draw(block); err != nil {
+		t.Fatalf("Error drawing: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("Fail: %v\n", err)
+	}
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Fail: %v\n", err)
+	}
+	files, err := reader.GetEmbeddedFiles()
+	if err != nil {
+		t.Fatalf("Fail: %v\n", err)
+	}
+	if len(files) != 1 || files[0].EmbeddedFile == nil || !bytes.Equal(files[0].EmbeddedFile.Content, xml) {
+		t.Fatalf("Wrong embedded files: %d", len(files))
+	}
+}
@@ -187,6 +187,7 @@ func (l *List) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error
 		marker := newStyledParagraph(l.defaultStyle)
 		marker.SetEnableWrap(false)
 		marker.SetTextAlignment(TextAlignmentRight)
+		marker.structType = "Lbl"
 		marker.Append(item.marker.Text).Style = item.marker.Style
 
 		width := marker.getTextWidth() / 1000.0 / ctx.Width
@@ -199,6 +200,7 @@ func (l *List) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error
 
 	// Draw items.
 	table := newTable(2)
+	table.isList = true
 	table.SetColumnWidths(markerWidth, 1-markerWidth)
 	table.SetMargins(l.indent, 0, 0, 0)
 
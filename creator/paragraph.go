@@ -64,6 +64,9 @@ type Paragraph struct {
 
 	// Text lines after wrapping to available width.
 	textLines []string
+
+	// Structure type in tagged output (P if not set).
+	structType string
 }
 
 // newParagraph create a new text paragraph. Uses default parameters: Helvetica, WinAnsiEncoding and
@@ -389,6 +392,13 @@ func (p *Paragraph) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 	}
 
 	blocks = append(blocks, blk)
+
+	structType := p.structType
+	if structType == "" {
+		structType = "P"
+	}
+	tagBlocks(origContext, blocks, structType)
+
 	if p.positioning.isRelative() {
 		ctx.X -= p.margins.left // Move back.
 		ctx.Width = origContext.Width
@@ -410,7 +420,7 @@ func drawParagraphOnBlock(blk *Block, p *Paragraph, ctx DrawContext) (DrawContex
 	}
 
 	// Add to the Page resources.
-	err := blk.resources.SetFontByName(fontName, p.textFont.ToPdfObject())
+	err := blk.resources.SetFont(fontName, p.textFont)
 	if err != nil {
 		return ctx, err
 	}
@@ -0,0 +1,192 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package creator
+
+import (
+	"math"
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// structTree is the structure tree of tagged output. The components drawn in a context with a
+// structure tree wrap their content in marked-content sequences belonging to new structure
+// elements. The sequences are assigned their marked-content identifiers (MCIDs) when the blocks
+// are drawn on the pages.
+type structTree struct {
+	// The Document element, root of the structure elements.
+	root *model.PdfStructElement
+
+	// Maps the property lists of the marked-content sequences that have not been drawn on a page
+	// yet to their structure elements.
+	marks map[*core.PdfObjectDictionary]*model.PdfStructElement
+
+	// Next free MCID of each page.
+	mcids map[*model.PdfPage]int
+}
+
+// newStructTree returns a new structure tree with an empty Document element.
+func newStructTree() *structTree {
+	return &structTree{
+		root:  model.NewPdfStructElement("Document"),
+		marks: map[*core.PdfObjectDictionary]*model.PdfStructElement{},
+		mcids: map[*model.PdfPage]int{},
+	}
+}
+
+// newStructElem returns a new structure element of type `s`, added to the kids of the structure
+// element of the context. Returns nil if the output is not tagged.
+func (ctx DrawContext) newStructElem(s string) *model.PdfStructElement {
+	if ctx.structTree == nil {
+		return nil
+	}
+	elem := model.NewPdfStructElement(s)
+	ctx.structElem.AddKid(elem)
+	return elem
+}
+
+// untagged returns a copy of the context without structure tree. Used for content that is not
+// part of the logical structure, e.g. for estimating the size of components.
+func (ctx DrawContext) untagged() DrawContext {
+	ctx.structTree = nil
+	ctx.structElem = nil
+	return ctx
+}
+
+// tagBlocks wraps the contents of `blocks` generated in context `ctx` in marked-content
+// sequences belonging to a new structure element of type `s`. Returns the new element, or nil if
+// the output is not tagged.
+func tagBlocks(ctx DrawContext, blocks []*Block, s string) *model.PdfStructElement {
+	elem := ctx.newStructElem(s)
+	if elem == nil {
+		return nil
+	}
+	for _, blk := range blocks {
+		ctx.structTree.markContent(blk, 0, elem)
+	}
+	return elem
+}
+
+// markContent wraps the contents of `blk` starting at operation index `start` in a marked-content
+// sequence belonging to structure element `elem`.
+func (t *structTree) markContent(blk *Block, start int, elem *model.PdfStructElement) {
+	if start >= len(*blk.contents) {
+		return
+	}
+	// The property list is a placeholder until the MCID is assigned.
+	props := core.MakeDict()
+	t.marks[props] = elem
+	wrapMarkedContent(blk, start, core.MakeName(elem.S), props)
+}
+
+// markArtifact wraps the contents of `blk` starting at operation index `start` in an Artifact
+// marked-content sequence, for content that is not part of the logical structure, such as
+// borders, backgrounds, headers and footers.
+func markArtifact(blk *Block, start int) {
+	if start >= len(*blk.contents) {
+		return
+	}
+	wrapMarkedContent(blk, start, core.MakeName("Artifact"), nil)
+}
+
+// wrapMarkedContent wraps the contents of `blk` starting at operation index `start` in a
+// marked-content sequence with tag `tag` and property list `props` (BMC if nil).
+func wrapMarkedContent(blk *Block, start int, tag *core.PdfObjectName, props *core.PdfObjectDictionary) {
+	begin := &contentstream.ContentStreamOperation{Operand: "BMC", Params: []core.PdfObject{tag}}
+	if props != nil {
+		begin = &contentstream.ContentStreamOperation{Operand: "BDC", Params: []core.PdfObject{tag, props}}
+	}
+	end := &contentstream.ContentStreamOperation{Operand: "EMC"}
+
+	ops := *blk.contents
+	wrapped := make(contentstream.ContentStreamOperations, 0, len(ops)+2)
+	wrapped = append(wrapped, ops[:start]...)
+	wrapped = append(wrapped, begin)
+	wrapped = append(wrapped, ops[start:]...)
+	wrapped = append(wrapped, end)
+	*blk.contents = wrapped
+}
+
+// assignMCIDs assigns the MCIDs of `page` to the marked-content sequences of `blk`, which is
+// about to be drawn on the page, and adds the marked content to the structure elements.
+func (t *structTree) assignMCIDs(blk *Block, page *model.PdfPage) {
+	for i, op := range *blk.contents {
+		if op.Operand != "BDC" || len(op.Params) != 2 {
+			continue
+		}
+		props, ok := op.Params[1].(*core.PdfObjectDictionary)
+		if !ok {
+			continue
+		}
+		elem, has := t.marks[props]
+		if !has {
+			continue
+		}
+
+		mcid := t.mcids[page]
+		t.mcids[page] = mcid + 1
+		elem.AddMarkedContent(page, mcid)
+
+		// The operations may be shared with other blocks, replace rather than modify.
+		mcidProps := core.MakeDict()
+		mcidProps.Set("MCID", core.MakeInteger(int64(mcid)))
+		(*blk.contents)[i] = &contentstream.ContentStreamOperation{
+			Operand: "BDC",
+			Params:  []core.PdfObject{op.Params[0], mcidProps},
+		}
+	}
+}
+
+// toStructTreeRoot returns the structure tree root for writing the document with pages `pages`.
+// The top level elements are ordered by the position of their first marked content, as the
+// front page and the table of contents are generated after the other content.
+func (t *structTree) toStructTreeRoot(pages []*model.PdfPage) *model.PdfStructTreeRoot {
+	pageIndex := map[*model.PdfPage]int{}
+	for i, page := range pages {
+		pageIndex[page] = i
+	}
+
+	// firstContent returns the page index and MCID of the first marked content of `e`.
+	var firstContent func(e *model.PdfStructElement) (int, int, bool)
+	firstContent = func(e *model.PdfStructElement) (int, int, bool) {
+		for _, kid := range e.Kids {
+			if kid.Element == nil {
+				return pageIndex[kid.Page], kid.MCID, true
+			}
+			if p, m, ok := firstContent(kid.Element); ok {
+				return p, m, ok
+			}
+		}
+		return 0, 0, false
+	}
+
+	// Elements without content keep their position after the preceding element.
+	type positionedKid struct {
+		kid        *model.PdfStructKid
+		page, mcid int
+	}
+	kids := make([]positionedKid, len(t.root.Kids))
+	page, mcid := -1, math.MaxInt32
+	for i, kid := range t.root.Kids {
+		if p, m, ok := firstContent(kid.Element); ok {
+			page, mcid = p, m
+		}
+		kids[i] = positionedKid{kid: kid, page: page, mcid: mcid}
+	}
+	sort.SliceStable(kids, func(i, j int) bool {
+		if kids[i].page != kids[j].page {
+			return kids[i].page < kids[j].page
+		}
+		return kids[i].mcid < kids[j].mcid
+	})
+	for i := range kids {
+		t.root.Kids[i] = kids[i].kid
+	}
+
+	return &model.PdfStructTreeRoot{Kids: []*model.PdfStructElement{t.root}}
+}
@@ -67,6 +67,9 @@ type StyledParagraph struct {
 
 	// Before render callback.
 	beforeRender func(p *StyledParagraph, ctx DrawContext)
+
+	// Structure type in tagged output (P if not set).
+	structType string
 }
 
 // newStyledParagraph creates a new styled paragraph.
@@ -135,6 +138,16 @@ func (p *StyledParagraph) AddInternalLink(text string, page int64, x, y, zoom fl
 	return p.appendChunk(chunk)
 }
 
+// AddNamedLink adds a new internal link to the paragraph.
+// The text parameter represents the text that is displayed.
+// The user is taken to the named destination `name` (see Creator.AddNamedDestination
+// and Chapter.DestinationName), which stays valid when pages are inserted or merged.
+func (p *StyledParagraph) AddNamedLink(text, name string) *TextChunk {
+	chunk := newTextChunk(text, p.defaultLinkStyle)
+	chunk.annotation = newNamedLinkAnnotation(name)
+	return p.appendChunk(chunk)
+}
+
 // Reset removes all the text chunks the paragraph contains.
 func (p *StyledParagraph) Reset() {
 	p.chunks = []*TextChunk{}
@@ -511,6 +524,13 @@ func (p *StyledParagraph) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawCon
 	}
 
 	blocks = append(blocks, blk)
+
+	structType := p.structType
+	if structType == "" {
+		structType = "P"
+	}
+	tagBlocks(origContext, blocks, structType)
+
 	if p.positioning.isRelative() {
 		ctx.X -= p.margins.left // Move back.
 		ctx.Width = origContext.Width
@@ -531,7 +551,7 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 	}
 
 	// Add default font to the page resources.
-	err := blk.resources.SetFontByName(fontName, p.defaultStyle.Font.ToPdfObject())
+	err := blk.resources.SetFont(fontName, p.defaultStyle.Font)
 	if err != nil {
 		return ctx, err
 	}
@@ -552,7 +572,7 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 		for _, chunk := range line {
 			fontName = core.PdfObjectName(fmt.Sprintf("Font%d", num))
 
-			err := blk.resources.SetFontByName(fontName, chunk.Style.Font.ToPdfObject())
+			err := blk.resources.SetFont(fontName, chunk.Style.Font)
 			if err != nil {
 				return ctx, err
 			}
@@ -54,6 +54,9 @@ type Table struct {
 	// Header rows.
 	headerStartRow int
 	headerEndRow   int
+
+	// Specifies whether the table lays out a list, tagged as L in tagged output.
+	isList bool
 }
 
 // newTable create a new Table with a specified number of columns.
@@ -407,6 +410,15 @@ func (table *Table) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 		}
 	}
 
+	// Structure elements of the table and the current row in tagged output.
+	tableStructType, rowStructType := "Table", "TR"
+	if table.isList {
+		tableStructType, rowStructType = "L", "LI"
+	}
+	tableElem := ctx.newStructElem(tableStructType)
+	var rowElem *model.PdfStructElement
+	structRow := 0
+
 	// Draw cells.
 	// row height, cell height
 	var drawingHeaders bool
@@ -510,10 +522,39 @@ func (table *Table) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 		border.SetWidthRight(cell.borderWidthRight)
 		border.SetWidthTop(cell.borderWidthTop)
 
+		// The headers repeated on new pages are not part of the logical structure.
+		cellStart := len(*block.contents)
+		var cellElem *model.PdfStructElement
+		if tableElem != nil && !drawingHeaders {
+			if cell.row != structRow {
+				structRow = cell.row
+				rowElem = model.NewPdfStructElement(rowStructType)
+				tableElem.AddKid(rowElem)
+			}
+
+			switch {
+			case table.isList && cell.col == 1:
+				// The item marker is tagged as the label of the item.
+				cellElem = rowElem
+			case table.isList:
+				cellElem = model.NewPdfStructElement("LBody")
+			case table.hasHeader && cell.row >= table.headerStartRow && cell.row <= table.headerEndRow:
+				cellElem = model.NewPdfStructElement("TH")
+			default:
+				cellElem = model.NewPdfStructElement("TD")
+			}
+			if cellElem != rowElem {
+				rowElem.AddKid(cellElem)
+			}
+		}
+
 		err := block.Draw(border)
 		if err != nil {
 			common.Log.Debug("ERROR: %v", err)
 		}
+		if tableElem != nil && !drawingHeaders {
+			markArtifact(block, cellStart)
+		}
 
 		if cell.content != nil {
 			// content width.
@@ -571,11 +612,19 @@ func (table *Table) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext,
 				}
 			}
 
-			err := block.DrawWithContext(cell.content, ctx)
+			cellCtx := ctx.untagged()
+			if cellElem != nil {
+				cellCtx.structTree = ctx.structTree
+				cellCtx.structElem = cellElem
+			}
+			err := block.DrawWithContext(cell.content, cellCtx)
 			if err != nil {
 				common.Log.Debug("ERROR: %v", err)
 			}
 		}
+		if tableElem != nil && drawingHeaders {
+			markArtifact(block, cellStart)
+		}
 
 		ctx.Y += h
 
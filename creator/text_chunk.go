@@ -44,10 +44,9 @@ func newExternalLinkAnnotation(url string) *model.PdfAnnotation {
 	annotation.BS = bs.ToPdfObject()
 
 	// Set link destination.
-	action := core.MakeDict()
-	action.Set(core.PdfObjectName("S"), core.MakeName("URI"))
-	action.Set(core.PdfObjectName("URI"), core.MakeString(url))
-	annotation.A = action
+	action := model.NewPdfActionURI()
+	action.URI = core.MakeString(url)
+	annotation.SetAction(action.PdfAction)
 
 	return annotation.PdfAnnotation
 }
@@ -77,6 +76,21 @@ func newInternalLinkAnnotation(page int64, x, y, zoom float64) *model.PdfAnnotat
 	return annotation.PdfAnnotation
 }
 
+// newNamedLinkAnnotation returns a new internal link annotation to named destination `name`.
+func newNamedLinkAnnotation(name string) *model.PdfAnnotation {
+	annotation := model.NewPdfAnnotationLink()
+
+	// Set border style.
+	bs := model.NewBorderStyle()
+	bs.SetBorderWidth(0)
+	annotation.BS = bs.ToPdfObject()
+
+	// Set link destination.
+	annotation.Dest = core.MakeString(name)
+
+	return annotation.PdfAnnotation
+}
+
 // copyLinkAnnotation returns a new link annotation based on an existing one.
 func copyLinkAnnotation(link *model.PdfAnnotationLink) *model.PdfAnnotationLink {
 	if link == nil {
@@ -89,6 +103,8 @@ func copyLinkAnnotation(link *model.PdfAnnotationLink) *model.PdfAnnotationLink
 
 	if annotDest, ok := link.Dest.(*core.PdfObjectArray); ok {
 		annotation.Dest = core.MakeArray(annotDest.Elements()...)
+	} else {
+		annotation.Dest = link.Dest
 	}
 
 	return annotation
@@ -58,6 +58,7 @@ func newTOC(title string, style, styleHeading TextStyle) *TOC {
 	heading.SetEnableWrap(true)
 	heading.SetTextAlignment(TextAlignmentLeft)
 	heading.SetMargins(0, 0, 0, 5)
+	heading.structType = "H1"
 
 	chunk := heading.Append(title)
 	chunk.Style = headingStyle
@@ -215,6 +216,11 @@ func (t *TOC) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error)
 		return blocks, ctx, err
 	}
 
+	// The lines are the items of the TOC structure element following the heading.
+	if elem := ctx.newStructElem("TOC"); elem != nil {
+		ctx.structElem = elem
+	}
+
 	// Generate blocks for the table of contents lines.
 	for _, line := range t.lines {
 		linkPage := line.linkPage
@@ -243,6 +249,7 @@ func (t *TOC) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error)
 		// Move back X to same start of line.
 		ctx.X = origCtx.X
 	}
+	ctx.structElem = origCtx.structElem
 
 	if t.positioning.isAbsolute() {
 		// If absolute: return original context.
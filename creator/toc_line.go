@@ -49,6 +49,9 @@ type TOCLine struct {
 	linkX    float64
 	linkY    float64
 	linkPage int64
+
+	// Named destination of the line link, if any. Takes precedence over the page and position.
+	linkDest string
 }
 
 // newTOCLine creates a new table of contents line with the default style.
@@ -158,8 +161,19 @@ func (tl *TOCLine) SetLink(page int64, x, y float64) {
 	tl.SetStyle(tl.sp.defaultLinkStyle)
 }
 
+// SetNamedLink makes the line an internal link to the named destination `name`
+// (see Creator.AddNamedDestination and Chapter.DestinationName).
+func (tl *TOCLine) SetNamedLink(name string) {
+	tl.linkDest = name
+
+	tl.SetStyle(tl.sp.defaultLinkStyle)
+}
+
 // getLineLink returns a new annotation if the line has a link set.
 func (tl *TOCLine) getLineLink() *model.PdfAnnotation {
+	if tl.linkDest != "" {
+		return newNamedLinkAnnotation(tl.linkDest)
+	}
 	if tl.linkPage <= 0 {
 		return nil
 	}
@@ -241,6 +255,10 @@ func (tl *TOCLine) prepareParagraph(sp *StyledParagraph, ctx DrawContext) {
 func (tl *TOCLine) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error) {
 	origCtx := ctx
 
+	if elem := ctx.newStructElem("TOCI"); elem != nil {
+		ctx.structElem = elem
+	}
+
 	blocks, ctx, err := tl.sp.GeneratePageBlocks(ctx)
 	if err != nil {
 		return blocks, ctx, err
@@ -250,6 +268,7 @@ func (tl *TOCLine) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, e
 		// Move back X to same start of line.
 		ctx.X = origCtx.X
 	}
+	ctx.structElem = origCtx.structElem
 
 	if tl.positioning.isAbsolute() {
 		// If absolute: return original context.
@@ -0,0 +1,174 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// maxFormDepth is the maximum nesting of the form XObjects whose paths are extracted. It guards
+// against forms that paint themselves.
+const maxFormDepth = 10
+
+// pathMark is a path painted on a page.
+type pathMark struct {
+	path        contentstream.Path // In device coordinates.
+	stroke      bool               // Stroked by S, s, B, B*, b or b*.
+	fill        bool               // Filled by f, F, f*, B, B*, b or b*.
+	evenOdd     bool               // Filled with the even-odd rule.
+	lineWidth   float64            // Line width in device coordinates.
+	strokeColor model.PdfColor
+	fillColor   model.PdfColor
+}
+
+// pathExtractContext provides the context for extracting the paths painted by content streams.
+type pathExtractContext struct {
+	paths []pathMark
+	depth int // Nesting of the form XObject being processed.
+}
+
+// extractPagePaths returns the paths painted on the page of `e`, including the paths of the form
+// XObjects it paints, in the order they are painted. Paths that are only used to clip (n) are
+// skipped.
+func (e *Extractor) extractPagePaths() ([]pathMark, error) {
+	ctx := &pathExtractContext{}
+	err := ctx.extractContentStreamPaths(e.contents, e.resources, nil)
+	if err != nil {
+		common.Log.Debug("ERROR: Extracting paths: err=%v", err)
+		return nil, err
+	}
+	return ctx.paths, nil
+}
+
+// extractContentStreamPaths processes `contents` starting with graphics state `gs`, or the initial
+// graphics state of a page if `gs` is nil.
+func (ctx *pathExtractContext) extractContentStreamPaths(contents string,
+	resources *model.PdfPageResources, gs *contentstream.GraphicsState) error {
+	cstreamParser := contentstream.NewContentStreamParser(contents)
+	operations, err := cstreamParser.Parse()
+	if err != nil {
+		return err
+	}
+
+	processor := contentstream.NewContentStreamProcessor(*operations)
+	if gs != nil {
+		processor.SetInitialState(*gs)
+	}
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
+			return ctx.processOperand(processor, op, gs, resources)
+		})
+	return processor.Process(resources)
+}
+
+// processOperand records the path painted by `op` and recurses into the form XObjects painted by
+// Do.
+func (ctx *pathExtractContext) processOperand(processor *contentstream.ContentStreamProcessor,
+	op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+	resources *model.PdfPageResources) error {
+	mark := pathMark{strokeColor: gs.ColorStroking, fillColor: gs.ColorNonStroking}
+	closePath := false
+	switch op.Operand {
+	case "S":
+		mark.stroke = true
+	case "s":
+		mark.stroke, closePath = true, true
+	case "f", "F":
+		mark.fill = true
+	case "f*":
+		mark.fill, mark.evenOdd = true, true
+	case "B":
+		mark.stroke, mark.fill = true, true
+	case "B*":
+		mark.stroke, mark.fill, mark.evenOdd = true, true, true
+	case "b":
+		mark.stroke, mark.fill, closePath = true, true, true
+	case "b*":
+		mark.stroke, mark.fill, mark.evenOdd, closePath = true, true, true, true
+	case "Do":
+		if len(op.Params) != 1 {
+			return nil
+		}
+		name, ok := core.GetName(op.Params[0])
+		if !ok {
+			common.Log.Debug("ERROR: Invalid Do operand: %T", op.Params[0])
+			return errTypeCheck
+		}
+		if _, xtype := resources.GetXObjectByName(*name); xtype != model.XObjectTypeForm {
+			return nil
+		}
+		return ctx.extractFormPaths(name, gs, resources)
+	default:
+		return nil
+	}
+
+	path := processor.CurrentPath()
+	if path.Empty() {
+		return nil
+	}
+	mark.path = path.Copy()
+	if closePath {
+		mark.path.Segments = append(mark.path.Segments,
+			contentstream.PathSegment{Type: contentstream.PathSegmentClose})
+	}
+	// The line width is scaled by the mean scaling of the CTM.
+	ctm := gs.CTM
+	mark.lineWidth = gs.LineWidth * math.Sqrt(math.Abs(ctm[0]*ctm[4]-ctm[1]*ctm[3]))
+	ctx.paths = append(ctx.paths, mark)
+	return nil
+}
+
+// extractFormPaths extracts the paths of the form XObject `name` painted with graphics state `gs`.
+func (ctx *pathExtractContext) extractFormPaths(name *core.PdfObjectName, gs contentstream.GraphicsState,
+	resources *model.PdfPageResources) error {
+	if ctx.depth >= maxFormDepth {
+		common.Log.Debug("ERROR: Form XObjects nested too deeply: %s", *name)
+		return nil
+	}
+	xform, err := resources.GetXObjectFormByName(*name)
+	if err != nil {
+		return err
+	}
+	if xform == nil {
+		return nil
+	}
+	formContent, err := xform.GetContentStream()
+	if err != nil {
+		return err
+	}
+	formResources := xform.Resources
+	if formResources == nil {
+		formResources = resources
+	}
+
+	// The form is painted in the graphics state of the Do operator. The form matrix maps form
+	// space to the user space the form is painted in.
+	ctm := gs.CTM
+	if xform.Matrix != nil {
+		arr, ok := core.GetArray(xform.Matrix)
+		if !ok || arr.Len() != 6 {
+			common.Log.Debug("ERROR: Invalid form matrix: %s", xform.Matrix)
+			return errTypeCheck
+		}
+		f, err := arr.ToFloat64Array()
+		if err != nil {
+			return err
+		}
+		ctm = ctm.Mult(transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5]))
+	}
+
+	gs.CTM = ctm
+	ctx.depth++
+	err = ctx.extractContentStreamPaths(string(formContent), formResources, &gs)
+	ctx.depth--
+	return err
+}
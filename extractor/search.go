@@ -0,0 +1,216 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// searchContextLength is the number of runes of text around the matches in SearchHit.Context.
+const searchContextLength = 30
+
+// Point is a point in device coordinates.
+type Point struct {
+	X, Y float64
+}
+
+// Quad is a quadrilateral bounding text in device coordinates. LL, LR, UR and UL are its lower
+// left, lower right, upper right and upper left corners relative to the direction of the text,
+// so that LL to LR runs along the baseline of rotated text too.
+// The device coordinates of the text of a page are in the default user space of the page, the
+// space of the annotation rectangles.
+type Quad struct {
+	LL, LR, UR, UL Point
+}
+
+// QuadPoints returns the coordinates of the corners of `q` in the order of the QuadPoints of text
+// markup annotations: upper left, upper right, lower left and lower right.
+func (q Quad) QuadPoints() []float64 {
+	return []float64{q.UL.X, q.UL.Y, q.UR.X, q.UR.Y, q.LL.X, q.LL.Y, q.LR.X, q.LR.Y}
+}
+
+// BBox returns the bounding box of `q`.
+func (q Quad) BBox() model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(math.Min(q.LL.X, q.LR.X), math.Min(q.UR.X, q.UL.X)),
+		Lly: math.Min(math.Min(q.LL.Y, q.LR.Y), math.Min(q.UR.Y, q.UL.Y)),
+		Urx: math.Max(math.Max(q.LL.X, q.LR.X), math.Max(q.UR.X, q.UL.X)),
+		Ury: math.Max(math.Max(q.LL.Y, q.LR.Y), math.Max(q.UR.Y, q.UL.Y)),
+	}
+}
+
+// SearchHit is a match of a text search.
+type SearchHit struct {
+	// PageNum is the number of the page of the match, starting from 1. It is 0 for the matches
+	// of PageText searches.
+	PageNum int
+
+	// Text is the matched text, as returned by PageText.ToText.
+	Text string
+
+	// Quads bound the glyphs of the match, one per line of text.
+	Quads []Quad
+
+	// Context is the text surrounding the match, with line breaks replaced by spaces.
+	// Text starts at byte ContextOffset of Context.
+	Context       string
+	ContextOffset int
+}
+
+// QuadPoints returns the QuadPoints of the quadrilaterals of `h`, as set in the text markup
+// annotations highlighting the match.
+func (h SearchHit) QuadPoints() []float64 {
+	var points []float64
+	for _, q := range h.Quads {
+		points = append(points, q.QuadPoints()...)
+	}
+	return points
+}
+
+// BBox returns the bounding box of the quadrilaterals of `h`.
+func (h SearchHit) BBox() model.PdfRectangle {
+	var bbox model.PdfRectangle
+	for i, q := range h.Quads {
+		bbox = unionBBox(bbox, q.BBox(), i == 0)
+	}
+	return bbox
+}
+
+// Search returns the matches of `term` in the text of `pt` as returned by ToText. The whitespace
+// in `term` matches any whitespace, including line breaks. The search is case insensitive if
+// `ignoreCase` is true.
+func (pt PageText) Search(term string, ignoreCase bool) []SearchHit {
+	return pt.SearchRegexp(termRegexp(term, ignoreCase))
+}
+
+// SearchRegexp returns the non-overlapping matches of `re` in the text of `pt` as returned by
+// ToText. Empty matches are skipped.
+func (pt PageText) SearchRegexp(re *regexp.Regexp) []SearchHit {
+	text, marks := pt.toTextMarks()
+	var hits []SearchHit
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if start == end {
+			continue
+		}
+		hit := SearchHit{Text: text[start:end], Quads: matchQuads(text, marks, start, end)}
+		first, last := start, end
+		for i := 0; i < searchContextLength && first > 0; i++ {
+			_, size := utf8.DecodeLastRuneInString(text[:first])
+			first -= size
+		}
+		for i := 0; i < searchContextLength && last < len(text); i++ {
+			_, size := utf8.DecodeRuneInString(text[last:])
+			last += size
+		}
+		hit.Context = strings.Replace(text[first:last], "\n", " ", -1)
+		hit.ContextOffset = start - first
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+// SearchDocument returns the matches of `term` in the pages of `reader`, in page order. See
+// PageText.Search.
+func SearchDocument(reader *model.PdfReader, term string, ignoreCase bool) ([]SearchHit, error) {
+	return SearchDocumentRegexp(reader, termRegexp(term, ignoreCase))
+}
+
+// SearchDocumentRegexp returns the matches of `re` in the pages of `reader`, in page order. See
+// PageText.SearchRegexp.
+func SearchDocumentRegexp(reader *model.PdfReader, re *regexp.Regexp) ([]SearchHit, error) {
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	var hits []SearchHit
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		page, err := reader.GetPage(pageNum)
+		if err != nil {
+			return nil, err
+		}
+		e, err := New(page)
+		if err != nil {
+			return nil, err
+		}
+		pageText, _, _, err := e.ExtractPageText()
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range pageText.SearchRegexp(re) {
+			hit.PageNum = pageNum
+			hits = append(hits, hit)
+		}
+	}
+	return hits, nil
+}
+
+// termRegexp returns the regular expression matching `term`, with whitespace matching any
+// whitespace, case insensitive if `ignoreCase` is true.
+func termRegexp(term string, ignoreCase bool) *regexp.Regexp {
+	words := strings.Fields(term)
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	expr := strings.Join(words, `\s+`)
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.MustCompile(expr)
+}
+
+// toTextMarks returns the text of `pt` as returned by ToText and the text marks of its bytes,
+// nil for the spaces and line breaks inserted between the marks.
+func (pt PageText) toTextMarks() (string, []*textMark) {
+	var b strings.Builder
+	var marks []*textMark
+	for i, line := range pt.lines() {
+		if i > 0 {
+			b.WriteByte('\n')
+			marks = append(marks, nil)
+		}
+		for j, w := range line.words {
+			b.WriteString(w)
+			for k := 0; k < len(w); k++ {
+				marks = append(marks, line.marks[j])
+			}
+		}
+	}
+	return b.String(), marks
+}
+
+// matchQuads returns the quadrilaterals bounding the marks of the bytes `start` to `end` of
+// `text`, one per line.
+func matchQuads(text string, marks []*textMark, start, end int) []Quad {
+	var quads []Quad
+	var first, last *textMark
+	flush := func() {
+		if first != nil {
+			quads = append(quads, Quad{LL: first.quad.LL, LR: last.quad.LR, UR: last.quad.UR, UL: first.quad.UL})
+		}
+		first, last = nil, nil
+	}
+	for i := start; i < end; i++ {
+		if text[i] == '\n' && marks[i] == nil {
+			flush()
+			continue
+		}
+		if marks[i] == nil {
+			continue
+		}
+		if first == nil {
+			first = marks[i]
+		}
+		last = marks[i]
+	}
+	flush()
+	return quads
+}
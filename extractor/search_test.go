@@ -0,0 +1,102 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// newSearchPage returns a page with a line of horizontal text and a line of text rotated by 90
+// degrees counterclockwise.
+func newSearchPage(t *testing.T) *model.PdfPage {
+	resources := model.NewPdfPageResources()
+	helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
+	require.NoError(t, resources.SetFontByName("F1", helvetica.ToPdfObject()))
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 600, Ury: 800}
+	page.Resources = resources
+	contents := `
+BT /F1 10 Tf 100 700 Td (Call John at 555-1234) Tj ET
+BT /F1 10 Tf 0 1 -1 0 300 100 Tm (Rotated john) Tj ET
+`
+	require.NoError(t, page.SetContentStreams([]string{contents}, core.NewRawEncoder()))
+	return page
+}
+
+// requirePoint checks that `p` is at `x`, `y`.
+func requirePoint(t *testing.T, p Point, x, y float64) {
+	require.InDelta(t, x, p.X, 0.01)
+	require.InDelta(t, y, p.Y, 0.01)
+}
+
+func TestSearch(t *testing.T) {
+	e, err := New(newSearchPage(t))
+	require.NoError(t, err)
+	pageText, _, _, err := e.ExtractPageText()
+	require.NoError(t, err)
+
+	require.Len(t, pageText.Search("JOHN", false), 0)
+	hits := pageText.Search("john", true)
+	require.Len(t, hits, 2)
+
+	// "Call " is 20 wide and "John" 21.68 wide at 10 points.
+	hit := hits[0]
+	require.Equal(t, "John", hit.Text)
+	require.Equal(t, "Call John at 555-1234 Rotated john", hit.Context)
+	require.Equal(t, 5, hit.ContextOffset)
+	require.Len(t, hit.Quads, 1)
+	q := hit.Quads[0]
+	requirePoint(t, q.LL, 120, 698)
+	requirePoint(t, q.LR, 141.68, 698)
+	requirePoint(t, q.UR, 141.68, 708)
+	requirePoint(t, q.UL, 120, 708)
+	require.Equal(t, []float64{120, 708, q.UR.X, 708, 120, 698, q.LR.X, 698}, q.QuadPoints())
+
+	// The baseline of the rotated text runs upwards, "Rotated " is 37.8 wide and "john" 18.9.
+	q = hits[1].Quads[0]
+	requirePoint(t, q.LL, 302, 137.8)
+	requirePoint(t, q.UL, 292, 137.8)
+	requirePoint(t, q.UR, 292, 137.8+18.9)
+	bbox := hits[1].BBox()
+	require.InDelta(t, 292, bbox.Llx, 0.01)
+	require.InDelta(t, 302, bbox.Urx, 0.01)
+
+	// Whitespace matches the line breaks, with a quadrilateral per line.
+	hits = pageText.Search("1234  Rotated", false)
+	require.Len(t, hits, 1)
+	require.Equal(t, "1234\nRotated", hits[0].Text)
+	require.Len(t, hits[0].Quads, 2)
+	require.Len(t, hits[0].QuadPoints(), 16)
+
+	hits = pageText.SearchRegexp(regexp.MustCompile(`\d{3}-\d{4}`))
+	require.Len(t, hits, 1)
+	require.Equal(t, "555-1234", hits[0].Text)
+	requirePoint(t, hits[0].Quads[0].LL, hits[0].Quads[0].LL.X, 698)
+}
+
+func TestSearchDocument(t *testing.T) {
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(newSearchPage(t)))
+	require.NoError(t, w.AddPage(newSearchPage(t)))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	hits, err := SearchDocument(reader, "JOHN", true)
+	require.NoError(t, err)
+	require.Len(t, hits, 4)
+	require.Equal(t, 1, hits[1].PageNum)
+	require.Equal(t, 2, hits[2].PageNum)
+}
@@ -0,0 +1,198 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// ExtractPageShapes returns the paths stroked and filled on the page of `e`, including the paths
+// of the form XObjects it paints, in the order they are painted. Paths that only set the clipping
+// path (n operator) and paths without any lines or curves are not shapes.
+func (e *Extractor) ExtractPageShapes() (*PageShapes, error) {
+	paths, err := e.extractPagePaths()
+	if err != nil {
+		return nil, err
+	}
+	var shapes []Shape
+	for _, p := range paths {
+		// Paths of single points paint nothing.
+		if shape := p.toShape(); len(shape.Subpaths) > 0 {
+			shapes = append(shapes, shape)
+		}
+	}
+	return &PageShapes{Shapes: shapes}, nil
+}
+
+// PageShapes represents the vector graphics painted on a PDF page.
+type PageShapes struct {
+	Shapes []Shape
+}
+
+// Shape is a path painted on a page. All coordinates are in device coordinates.
+type Shape struct {
+	Subpaths []Subpath
+
+	// Stroke and Fill are true if the path is stroked and filled. EvenOdd is true if the inside of
+	// a filled path is determined by the even-odd rule rather than the nonzero winding number rule.
+	Stroke  bool
+	Fill    bool
+	EvenOdd bool
+
+	// StrokeColor and FillColor are the stroking and nonstroking colors, in the stroking and
+	// nonstroking color spaces current when the path is painted.
+	StrokeColor model.PdfColor
+	FillColor   model.PdfColor
+
+	// LineWidth is the line width of stroked paths scaled by the CTM. 0 is the thinnest line
+	// that can be rendered.
+	LineWidth float64
+
+	// BBox is the bounding box of the points of the subpaths, including the control points of
+	// curves. It does not include the line width.
+	BBox model.PdfRectangle
+}
+
+// Subpath is a sequence of connected straight lines and curves of a Shape starting at Start.
+type Subpath struct {
+	Start    Point
+	Segments []Segment
+
+	// Closed is true if the subpath is closed by a straight line from its last point back to
+	// Start, by the h operator, a rectangle (re operator) or a closing painting operator.
+	Closed bool
+}
+
+// Segment is a straight line or a cubic Bézier curve of a Subpath from the end of the previous
+// segment to End.
+type Segment struct {
+	// Curve is true for curves with control points C1 and C2.
+	Curve  bool
+	C1, C2 Point
+	End    Point
+}
+
+// Points returns the start of `sp` and the ends of its segments.
+func (sp Subpath) Points() []Point {
+	points := []Point{sp.Start}
+	for _, seg := range sp.Segments {
+		points = append(points, seg.End)
+	}
+	return points
+}
+
+// BBox returns the bounding box of the points of `sp`, including the control points of curves.
+func (sp Subpath) BBox() model.PdfRectangle {
+	bbox := model.PdfRectangle{Llx: sp.Start.X, Lly: sp.Start.Y, Urx: sp.Start.X, Ury: sp.Start.Y}
+	add := func(p Point) {
+		bbox = unionBBox(bbox, model.PdfRectangle{Llx: p.X, Lly: p.Y, Urx: p.X, Ury: p.Y}, false)
+	}
+	for _, seg := range sp.Segments {
+		if seg.Curve {
+			add(seg.C1)
+			add(seg.C2)
+		}
+		add(seg.End)
+	}
+	return bbox
+}
+
+// IsLine returns true if `sp` is a single straight line.
+func (sp Subpath) IsLine() bool {
+	return len(sp.Segments) == 1 && !sp.Segments[0].Curve && !sp.Closed
+}
+
+// Rect returns the rectangle outlined by `sp` if it is a closed rectangle with sides parallel to
+// the axes, as drawn by the re operator.
+func (sp Subpath) Rect() (model.PdfRectangle, bool) {
+	points := sp.Points()
+	if len(points) == 5 && points[4] == points[0] {
+		points = points[:4]
+	}
+	if !sp.Closed || len(points) != 4 {
+		return model.PdfRectangle{}, false
+	}
+	for _, seg := range sp.Segments {
+		if seg.Curve {
+			return model.PdfRectangle{}, false
+		}
+	}
+	// Consecutive corners share either x or y, alternately.
+	const tol = 1e-6
+	for i := range points {
+		p, q, r := points[i], points[(i+1)%4], points[(i+2)%4]
+		horizontal := math.Abs(p.Y-q.Y) < tol && math.Abs(q.X-r.X) < tol
+		vertical := math.Abs(p.X-q.X) < tol && math.Abs(q.Y-r.Y) < tol
+		if !horizontal && !vertical {
+			return model.PdfRectangle{}, false
+		}
+	}
+	return sp.BBox(), true
+}
+
+// toShape returns the Shape of `p`.
+func (p pathMark) toShape() Shape {
+	shape := Shape{
+		Stroke:      p.stroke,
+		Fill:        p.fill,
+		EvenOdd:     p.evenOdd,
+		StrokeColor: p.strokeColor,
+		FillColor:   p.fillColor,
+		LineWidth:   p.lineWidth,
+	}
+	point := func(p transform.Point) Point {
+		return Point{X: p.X, Y: p.Y}
+	}
+	var sp *Subpath
+	begin := func(start Point) {
+		shape.Subpaths = append(shape.Subpaths, Subpath{Start: start})
+		sp = &shape.Subpaths[len(shape.Subpaths)-1]
+	}
+	for _, seg := range p.path.Segments {
+		switch seg.Type {
+		case contentstream.PathSegmentMoveTo:
+			begin(point(seg.Points[0]))
+		case contentstream.PathSegmentLineTo:
+			// Like the content stream processor, a segment without a current point begins a
+			// subpath at its end.
+			if sp == nil {
+				begin(point(seg.Points[0]))
+				continue
+			}
+			sp.Segments = append(sp.Segments, Segment{End: point(seg.Points[0])})
+		case contentstream.PathSegmentCurveTo:
+			if sp == nil {
+				begin(point(seg.Points[2]))
+				continue
+			}
+			sp.Segments = append(sp.Segments, Segment{Curve: true, C1: point(seg.Points[0]),
+				C2: point(seg.Points[1]), End: point(seg.Points[2])})
+		case contentstream.PathSegmentClose:
+			// Segments after h start a new subpath at the start of the closed one.
+			if sp == nil {
+				continue
+			}
+			sp.Closed = true
+			begin(sp.Start)
+		}
+	}
+	// Drop the subpaths without segments, such as those started by h at the end of the path.
+	subpaths := shape.Subpaths[:0]
+	for _, sp := range shape.Subpaths {
+		if len(sp.Segments) > 0 {
+			subpaths = append(subpaths, sp)
+		}
+	}
+	shape.Subpaths = subpaths
+	for i, sp := range shape.Subpaths {
+		shape.BBox = unionBBox(shape.BBox, sp.BBox(), i == 0)
+	}
+	return shape
+}
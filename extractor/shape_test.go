@@ -0,0 +1,186 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+func TestExtractPageShapes(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+	xform.Matrix = core.MakeArrayFromFloats([]float64{2, 0, 0, 2, 0, 0})
+	require.NoError(t, xform.SetContentStream([]byte("0 0 1 rg 10 10 m 20 10 l 20 20 l f"),
+		core.NewRawEncoder()))
+	require.NoError(t, resources.SetXObjectFormByName("Fm1", xform))
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 600, Ury: 800}
+	page.Resources = resources
+	contents := `
+q 2 w 1 0 0 RG 10 10 m 110 10 l S Q
+q 1 0 0 1 100 200 cm 0 0 50 20 re 0 10 m 10 20 20 20 30 10 c B* Q
+0 0 600 800 re W n
+q 1 0 0 1 300 300 cm /Fm1 Do Q
+`
+	require.NoError(t, page.SetContentStreams([]string{contents}, core.NewRawEncoder()))
+	e, err := New(page)
+	require.NoError(t, err)
+	pageShapes, err := e.ExtractPageShapes()
+	require.NoError(t, err)
+
+	// The clipping path is not a shape.
+	shapes := pageShapes.Shapes
+	require.Len(t, shapes, 3)
+
+	line := shapes[0]
+	require.True(t, line.Stroke)
+	require.False(t, line.Fill)
+	require.Equal(t, 2.0, line.LineWidth)
+	color, ok := line.StrokeColor.(*model.PdfColorDeviceRGB)
+	require.True(t, ok)
+	require.Equal(t, 1.0, color.R())
+	require.Len(t, line.Subpaths, 1)
+	require.True(t, line.Subpaths[0].IsLine())
+	require.Equal(t, model.PdfRectangle{Llx: 10, Lly: 10, Urx: 110, Ury: 10}, line.BBox)
+
+	// The rectangle and the curve are transformed by the CTM.
+	shape := shapes[1]
+	require.True(t, shape.Stroke)
+	require.True(t, shape.Fill)
+	require.True(t, shape.EvenOdd)
+	require.Len(t, shape.Subpaths, 2)
+	rect, ok := shape.Subpaths[0].Rect()
+	require.True(t, ok)
+	require.Equal(t, model.PdfRectangle{Llx: 100, Lly: 200, Urx: 150, Ury: 220}, rect)
+	curve := shape.Subpaths[1]
+	_, ok = curve.Rect()
+	require.False(t, ok)
+	require.False(t, curve.Closed)
+	require.Equal(t, Point{X: 100, Y: 210}, curve.Start)
+	require.Equal(t, []Segment{{Curve: true, C1: Point{X: 110, Y: 220}, C2: Point{X: 120, Y: 220},
+		End: Point{X: 130, Y: 210}}}, curve.Segments)
+	require.Equal(t, model.PdfRectangle{Llx: 100, Lly: 200, Urx: 150, Ury: 220}, shape.BBox)
+
+	// The path of the form is transformed by the form matrix and the CTM.
+	triangle := shapes[2]
+	require.True(t, triangle.Fill)
+	require.False(t, triangle.Stroke)
+	require.Equal(t, []Point{{X: 320, Y: 320}, {X: 340, Y: 320}, {X: 340, Y: 340}},
+		triangle.Subpaths[0].Points())
+	require.Equal(t, model.PdfRectangle{Llx: 320, Lly: 320, Urx: 340, Ury: 340}, triangle.BBox)
+	fill, ok := triangle.FillColor.(*model.PdfColorDeviceRGB)
+	require.True(t, ok)
+	require.Equal(t, 1.0, fill.B())
+}
+
+// TestExtractPageShapesFormState checks that the paths of forms are painted in the graphics state
+// of the Do operator.
+func TestExtractPageShapesFormState(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+	xform.Matrix = core.MakeArrayFromFloats([]float64{1, 0, 0, 1, 100, 0})
+	require.NoError(t, xform.SetContentStream([]byte("10 10 m 20 10 l S"), core.NewRawEncoder()))
+	require.NoError(t, resources.SetXObjectFormByName("Fm1", xform))
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 600, Ury: 800}
+	page.Resources = resources
+	require.NoError(t, page.SetContentStreams([]string{"5 w 1 0 0 RG 2 0 0 2 0 0 cm /Fm1 Do"},
+		core.NewRawEncoder()))
+	e, err := New(page)
+	require.NoError(t, err)
+	pageShapes, err := e.ExtractPageShapes()
+	require.NoError(t, err)
+
+	require.Len(t, pageShapes.Shapes, 1)
+	line := pageShapes.Shapes[0]
+	require.Equal(t, 10.0, line.LineWidth)
+	color, ok := line.StrokeColor.(*model.PdfColorDeviceRGB)
+	require.True(t, ok)
+	require.Equal(t, 1.0, color.R())
+	require.Equal(t, model.PdfRectangle{Llx: 220, Lly: 20, Urx: 240, Ury: 20}, line.BBox)
+}
+
+// TestExtractPageShapesNoCurrentPoint checks paths whose first segment has no current point.
+func TestExtractPageShapesNoCurrentPoint(t *testing.T) {
+	extract := func(contents string) []Shape {
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Urx: 600, Ury: 800}
+		require.NoError(t, page.SetContentStreams([]string{contents}, core.NewRawEncoder()))
+		e, err := New(page)
+		require.NoError(t, err)
+		pageShapes, err := e.ExtractPageShapes()
+		require.NoError(t, err)
+		return pageShapes.Shapes
+	}
+
+	// The segments begin a subpath at their ends and paint nothing.
+	require.Empty(t, extract("h 10 10 l S"))
+	require.Empty(t, extract("10 10 l S"))
+	require.Empty(t, extract("h 0 10 10 20 20 10 c S"))
+
+	shapes := extract("h 10 10 l 20 10 l S")
+	require.Len(t, shapes, 1)
+	require.Len(t, shapes[0].Subpaths, 1)
+	require.Equal(t, []Point{{X: 10, Y: 10}, {X: 20, Y: 10}}, shapes[0].Subpaths[0].Points())
+	require.Equal(t, model.PdfRectangle{Llx: 10, Lly: 10, Urx: 20, Ury: 10}, shapes[0].BBox)
+}
+
+func TestExtractPageShapesRotatedText(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
+	require.NoError(t, resources.SetFontByName("F1", helvetica.ToPdfObject()))
+
+	// "Hello" is 27.336 wide in Helvetica at 12 points. It is underlined in the rotated frame.
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 600, Ury: 800}
+	page.Resources = resources
+	contents := `
+0 1 -1 0 300 100 cm
+BT /F1 12 Tf (Hello) Tj ET
+0 -2 m 27.336 -2 l S
+`
+	require.NoError(t, page.SetContentStreams([]string{contents}, core.NewRawEncoder()))
+	e, err := New(page)
+	require.NoError(t, err)
+	marks, _, err := e.ExtractPageTextMarks()
+	require.NoError(t, err)
+	pageShapes, err := e.ExtractPageShapes()
+	require.NoError(t, err)
+
+	// The text runs up the page from (300,100).
+	require.Len(t, marks, 5)
+	require.Equal(t, 270.0, marks[0].Rotation)
+	text := marks[0].BBox
+	for _, mark := range marks[1:] {
+		text.Llx = math.Min(text.Llx, mark.BBox.Llx)
+		text.Lly = math.Min(text.Lly, mark.BBox.Lly)
+		text.Urx = math.Max(text.Urx, mark.BBox.Urx)
+		text.Ury = math.Max(text.Ury, mark.BBox.Ury)
+	}
+	require.InDelta(t, 290.4, text.Llx, 0.001)
+	require.InDelta(t, 100, text.Lly, 0.001)
+	require.InDelta(t, 302.4, text.Urx, 0.001)
+	require.InDelta(t, 127.336, text.Ury, 0.001)
+
+	// The underline is transformed by the same rotation and runs along the text.
+	shapes := pageShapes.Shapes
+	require.Len(t, shapes, 1)
+	line := shapes[0].BBox
+	require.InDelta(t, 302, line.Llx, 0.001)
+	require.InDelta(t, 302, line.Urx, 0.001)
+	require.InDelta(t, text.Lly, line.Lly, 0.001)
+	require.InDelta(t, text.Ury, line.Ury, 0.001)
+}
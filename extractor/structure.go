@@ -0,0 +1,173 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// ExtractMarkedContentText returns the text of the marked-content sequences of the page with
+// marked-content identifiers (MCIDs), keyed by MCID. The text of each sequence is ordered by
+// position as in ExtractText.
+func (e *Extractor) ExtractMarkedContentText() (map[int]string, error) {
+	pageText, _, _, err := e.ExtractPageText()
+	if err != nil {
+		return nil, err
+	}
+
+	sequences := map[int]*PageText{}
+	for _, mark := range pageText.marks {
+		if mark.mcid < 0 {
+			continue
+		}
+		seq, has := sequences[mark.mcid]
+		if !has {
+			seq = &PageText{}
+			sequences[mark.mcid] = seq
+		}
+		seq.marks = append(seq.marks, mark)
+	}
+
+	texts := make(map[int]string, len(sequences))
+	for mcid, seq := range sequences {
+		texts[mcid] = seq.ToText()
+	}
+	return texts, nil
+}
+
+// StructText is the text of a structure element of a tagged PDF.
+type StructText struct {
+	// Element is the structure element, which gives access to its attributes and ancestors.
+	Element *model.PdfStructElement
+
+	// Type is the standard structure type of the element, e.g. P, H1, TD or Figure.
+	Type string
+
+	// Text is the text of the element including the text of its descendants. It is the
+	// ActualText of the element if set, and the Alt text of figures without text.
+	Text string
+}
+
+// ExtractStructText returns the text of the tagged document with structure tree `root` in logical
+// structure order. The text is returned by block: table cells (TD, TH), list items (LI), TOC
+// items (TOCI) and the other elements with content of their own, such as paragraphs and
+// headings. Elements without text are omitted.
+func ExtractStructText(root *model.PdfStructTreeRoot) ([]StructText, error) {
+	if root == nil {
+		return nil, nil
+	}
+	x := &structTextExtractor{root: root, pages: map[*model.PdfPage]map[int]string{}}
+	for _, e := range root.Kids {
+		if err := x.extract(e); err != nil {
+			return nil, err
+		}
+	}
+	return x.texts, nil
+}
+
+// structTextExtractor extracts the text of the structure elements of a structure tree.
+type structTextExtractor struct {
+	root *model.PdfStructTreeRoot
+
+	// Marked content text of the pages, keyed by MCID.
+	pages map[*model.PdfPage]map[int]string
+
+	texts []StructText
+}
+
+// blockTypes are the standard structure types whose text is extracted as a whole, even if their
+// content is in descendant elements.
+var blockTypes = map[string]bool{
+	"TD":   true,
+	"TH":   true,
+	"LI":   true,
+	"TOCI": true,
+}
+
+// extract appends the text of the blocks of structure element `e` in logical order.
+func (x *structTextExtractor) extract(e *model.PdfStructElement) error {
+	typ := x.root.StandardType(e.S)
+
+	isBlock := blockTypes[typ] || e.ActualText != "" || (typ == "Figure" && e.Alt != "")
+	for _, kid := range e.Kids {
+		if kid.Element == nil && kid.Object == nil {
+			isBlock = true
+			break
+		}
+	}
+	if !isBlock {
+		for _, kid := range e.Kids {
+			if kid.Element == nil {
+				continue
+			}
+			if err := x.extract(kid.Element); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	text, err := x.text(e)
+	if err != nil {
+		return err
+	}
+	if text == "" && typ == "Figure" {
+		text = e.Alt
+	}
+	if text != "" {
+		x.texts = append(x.texts, StructText{Element: e, Type: typ, Text: text})
+	}
+	return nil
+}
+
+// text returns the text of structure element `e` and its descendants.
+func (x *structTextExtractor) text(e *model.PdfStructElement) (string, error) {
+	if e.ActualText != "" {
+		return e.ActualText, nil
+	}
+
+	var parts []string
+	for _, kid := range e.Kids {
+		var part string
+		switch {
+		case kid.Element != nil:
+			text, err := x.text(kid.Element)
+			if err != nil {
+				return "", err
+			}
+			part = text
+		case kid.Object == nil && kid.Page != nil:
+			texts, err := x.pageTexts(kid.Page)
+			if err != nil {
+				return "", err
+			}
+			part = texts[kid.MCID]
+		}
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// pageTexts returns the marked content text of `page`, keyed by MCID.
+func (x *structTextExtractor) pageTexts(page *model.PdfPage) (map[int]string, error) {
+	if texts, has := x.pages[page]; has {
+		return texts, nil
+	}
+	e, err := New(page)
+	if err != nil {
+		return nil, err
+	}
+	texts, err := e.ExtractMarkedContentText()
+	if err != nil {
+		return nil, err
+	}
+	x.pages[page] = texts
+	return texts, nil
+}
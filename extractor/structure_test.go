@@ -0,0 +1,130 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// newMarkedContentPage returns a page whose marked-content sequences are drawn in an order that
+// differs from their position on the page.
+func newMarkedContentPage(t *testing.T) *model.PdfPage {
+	resources := model.NewPdfPageResources()
+	helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
+	resources.SetFontByName("F1", helvetica.ToPdfObject())
+	properties := core.MakeDict()
+	mc := core.MakeDict()
+	mc.Set("MCID", core.MakeInteger(3))
+	properties.Set("MC0", mc)
+	resources.Properties = properties
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 600, Ury: 800}
+	page.Resources = resources
+	contents := `
+/Artifact BMC
+BT /F1 10 Tf 20 780 Td (Header) Tj ET
+EMC
+/P <</MCID 0>> BDC
+BT /F1 12 Tf 20 600 Td (Body text) Tj ET
+EMC
+/H1 <</MCID 1>> BDC
+BT /F1 18 Tf 20 700 Td (Title) Tj ET
+EMC
+BT /F1 12 Tf
+/TD <</MCID 2>> BDC 20 500 Td (Cell) Tj EMC
+/Span /MC0 BDC 100 0 Td (Named) Tj EMC
+ET
+`
+	if err := page.SetContentStreams([]string{contents}, core.NewRawEncoder()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return page
+}
+
+func TestExtractMarkedContentText(t *testing.T) {
+	e, err := New(newMarkedContentPage(t))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	texts, err := e.ExtractMarkedContentText()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	expected := map[int]string{0: "Body text", 1: "Title", 2: "Cell", 3: "Named"}
+	if len(texts) != len(expected) {
+		t.Fatalf("Got %d sequences, expected %d: %v", len(texts), len(expected), texts)
+	}
+	for mcid, want := range expected {
+		if texts[mcid] != want {
+			t.Fatalf("MCID %d: got %q, expected %q", mcid, texts[mcid], want)
+		}
+	}
+}
+
+func TestExtractStructText(t *testing.T) {
+	page := newMarkedContentPage(t)
+
+	doc := model.NewPdfStructElement("Document")
+	heading := model.NewPdfStructElement("Title")
+	heading.AddMarkedContent(page, 1)
+	doc.AddKid(heading)
+	p := model.NewPdfStructElement("P")
+	p.AddMarkedContent(page, 0)
+	doc.AddKid(p)
+
+	table := model.NewPdfStructElement("Table")
+	tr := model.NewPdfStructElement("TR")
+	td := model.NewPdfStructElement("TD")
+	cellP := model.NewPdfStructElement("P")
+	cellP.AddMarkedContent(page, 2)
+	span := model.NewPdfStructElement("Span")
+	span.AddMarkedContent(page, 3)
+	cellP.AddKid(span)
+	td.AddKid(cellP)
+	tr.AddKid(td)
+	table.AddKid(tr)
+	doc.AddKid(table)
+
+	figure := model.NewPdfStructElement("Figure")
+	figure.Alt = "Logo"
+	doc.AddKid(figure)
+	replaced := model.NewPdfStructElement("P")
+	replaced.ActualText = "Replacement"
+	replaced.AddMarkedContent(page, 0)
+	doc.AddKid(replaced)
+
+	root := &model.PdfStructTreeRoot{
+		Kids:    []*model.PdfStructElement{doc},
+		RoleMap: map[string]string{"Title": "H1"},
+	}
+	texts, err := ExtractStructText(root)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	expected := []struct{ typ, text string }{
+		{"H1", "Title"},
+		{"P", "Body text"},
+		{"TD", "Cell Named"},
+		{"Figure", "Logo"},
+		{"P", "Replacement"},
+	}
+	if len(texts) != len(expected) {
+		t.Fatalf("Got %d texts, expected %d: %+v", len(texts), len(expected), texts)
+	}
+	for i, want := range expected {
+		if texts[i].Type != want.typ || texts[i].Text != want.text {
+			t.Fatalf("Text %d: got %s %q, expected %s %q", i, texts[i].Type, texts[i].Text, want.typ, want.text)
+		}
+	}
+	if texts[2].Element != td {
+		t.Fatalf("Wrong element for table cell")
+	}
+}
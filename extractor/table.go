@@ -0,0 +1,565 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Table detection parameters, in device coordinates unless stated otherwise.
+const (
+	rulingTol      = 1.0 // Rules closer than this are at the same position.
+	minRuleLength  = 3.0 // Shorter lines are not rules.
+	maxRuleWidth   = 2.0 // Filled rectangles thinner than this are rules.
+	columnGapRatio = 1.0 // Gaps between words wider than this times their height separate columns.
+	rowGapRatio    = 1.5 // Gaps between lines wider than this times their height end tables.
+	minAlignedRows = 3   // Minimum number of rows of the tables detected from text alignment.
+)
+
+// TableCell is a cell of a Table.
+type TableCell struct {
+	// Row and Col are the row and column of the upper left corner of the cell, from 0. Rows are
+	// numbered from the top of the table.
+	Row, Col int
+
+	// RowSpan and ColSpan are the numbers of rows and columns the cell spans, at least 1.
+	RowSpan, ColSpan int
+
+	// BBox is the bounding box of the cell in device coordinates.
+	BBox model.PdfRectangle
+
+	// Text is the text inside the cell, as returned by PageText.ToText.
+	Text string
+}
+
+// Table is a table detected on a page.
+type Table struct {
+	BBox             model.PdfRectangle
+	NumRows, NumCols int
+
+	// Cells are the cells of the table in row major order. Every row and column position is
+	// covered by exactly one cell.
+	Cells []TableCell
+
+	// Ruled is true if the table was detected from its ruling lines, false if it was detected
+	// from the alignment of its text in columns.
+	Ruled bool
+}
+
+// ExtractPageTables returns the tables on the page of `e`, from top to bottom.
+// Ruled tables are detected from the grids of horizontal and vertical lines drawn by stroked
+// paths and thin filled rectangles, with the cells spanning the positions between which a ruling
+// line is missing. Tables without rules are detected from runs of at least 3 consecutive lines of
+// horizontal text whose words are separated by wide gaps into columns.
+func (e *Extractor) ExtractPageTables() ([]Table, error) {
+	pageText, _, _, err := e.ExtractPageText()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := e.extractPagePaths()
+	if err != nil {
+		return nil, err
+	}
+	tables := ruledTables(findRulings(paths), pageText.marks)
+	tables = append(tables, alignedTables(pageText.Lines(), tables)...)
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].BBox.Ury > tables[j].BBox.Ury
+	})
+	return tables, nil
+}
+
+// Grid returns the text of the cells of `t` as NumRows rows of NumCols columns. The text of a
+// spanning cell is at its upper left position and the other positions it covers are empty.
+func (t *Table) Grid() [][]string {
+	grid := make([][]string, t.NumRows)
+	for i := range grid {
+		grid[i] = make([]string, t.NumCols)
+	}
+	for _, cell := range t.Cells {
+		if cell.Row < t.NumRows && cell.Col < t.NumCols {
+			grid[cell.Row][cell.Col] = cell.Text
+		}
+	}
+	return grid
+}
+
+// WriteCSV writes the Grid of `t` to `w` as comma separated values.
+func (t *Table) WriteCSV(w io.Writer) error {
+	return csv.NewWriter(w).WriteAll(t.Grid())
+}
+
+// ruling is a horizontal or vertical line segment drawn on a page.
+type ruling struct {
+	vertical bool
+	pos      float64 // x of vertical rulings, y of horizontal rulings.
+	lo, hi   float64 // Extent along the ruling.
+}
+
+// findRulings returns the rulings drawn by `paths`, with the overlapping rulings at the same
+// position merged.
+func findRulings(paths []pathMark) []ruling {
+	var rules []ruling
+	for _, p := range paths {
+		if p.stroke {
+			rules = append(rules, strokeRulings(p.path)...)
+		} else if p.fill {
+			rules = append(rules, fillRulings(p.path)...)
+		}
+	}
+	return mergeRulings(rules)
+}
+
+// strokeRulings returns the horizontal and vertical straight segments of the stroked `path`.
+func strokeRulings(path contentstream.Path) []ruling {
+	var rules []ruling
+	var cur, start transform.Point
+	add := func(p, q transform.Point) {
+		if r, ok := newRuling(p, q); ok {
+			rules = append(rules, r)
+		}
+	}
+	for _, seg := range path.Segments {
+		switch seg.Type {
+		case contentstream.PathSegmentMoveTo:
+			cur, start = seg.Points[0], seg.Points[0]
+		case contentstream.PathSegmentLineTo:
+			add(cur, seg.Points[0])
+			cur = seg.Points[0]
+		case contentstream.PathSegmentCurveTo:
+			cur = seg.Points[2]
+		case contentstream.PathSegmentClose:
+			add(cur, start)
+			cur = start
+		}
+	}
+	return rules
+}
+
+// fillRulings returns the rulings of the thin axis aligned rectangles of the filled `path`.
+func fillRulings(path contentstream.Path) []ruling {
+	var rules []ruling
+	var points []transform.Point
+	curved := false
+	flush := func() {
+		if r, ok := rectRuling(points); ok && !curved {
+			rules = append(rules, r)
+		}
+		points, curved = nil, false
+	}
+	for _, seg := range path.Segments {
+		switch seg.Type {
+		case contentstream.PathSegmentMoveTo:
+			flush()
+			points = append(points, seg.Points[0])
+		case contentstream.PathSegmentLineTo:
+			points = append(points, seg.Points[0])
+		case contentstream.PathSegmentCurveTo:
+			// Subpaths with curves are not rectangles.
+			curved = true
+		}
+	}
+	flush()
+	return rules
+}
+
+// newRuling returns the ruling from `p` to `q` if it is horizontal or vertical.
+func newRuling(p, q transform.Point) (ruling, bool) {
+	dx, dy := math.Abs(q.X-p.X), math.Abs(q.Y-p.Y)
+	switch {
+	case dy < rulingTol/2 && dx >= minRuleLength:
+		return ruling{pos: (p.Y + q.Y) / 2, lo: math.Min(p.X, q.X), hi: math.Max(p.X, q.X)}, true
+	case dx < rulingTol/2 && dy >= minRuleLength:
+		return ruling{vertical: true, pos: (p.X + q.X) / 2, lo: math.Min(p.Y, q.Y), hi: math.Max(p.Y, q.Y)},
+			true
+	}
+	return ruling{}, false
+}
+
+// rectRuling returns the ruling along the long axis of the rectangle with corners `points` if it
+// is an axis aligned rectangle at most maxRuleWidth thick.
+func rectRuling(points []transform.Point) (ruling, bool) {
+	if len(points) == 5 && points[0] == points[4] {
+		points = points[:4]
+	}
+	if len(points) != 4 {
+		return ruling{}, false
+	}
+	llx, lly := math.Inf(1), math.Inf(1)
+	urx, ury := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		llx, lly = math.Min(llx, p.X), math.Min(lly, p.Y)
+		urx, ury = math.Max(urx, p.X), math.Max(ury, p.Y)
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < rulingTol/2 }
+	for _, p := range points {
+		if !(near(p.X, llx) || near(p.X, urx)) || !(near(p.Y, lly) || near(p.Y, ury)) {
+			return ruling{}, false
+		}
+	}
+	w, h := urx-llx, ury-lly
+	switch {
+	case h <= maxRuleWidth && w >= minRuleLength && w > h:
+		return ruling{pos: (lly + ury) / 2, lo: llx, hi: urx}, true
+	case w <= maxRuleWidth && h >= minRuleLength && h > w:
+		return ruling{vertical: true, pos: (llx + urx) / 2, lo: lly, hi: ury}, true
+	}
+	return ruling{}, false
+}
+
+// mergeRulings returns `rules` with the rulings of the same orientation within rulingTol of each
+// other moved to their mean position, and the overlapping ones at the same position joined.
+func mergeRulings(rules []ruling) []ruling {
+	sort.Slice(rules, func(i, j int) bool {
+		ri, rj := rules[i], rules[j]
+		if ri.vertical != rj.vertical {
+			return !ri.vertical
+		}
+		return ri.pos < rj.pos
+	})
+	var merged []ruling
+	for i := 0; i < len(rules); {
+		j := i + 1
+		sum := rules[i].pos
+		for j < len(rules) && rules[j].vertical == rules[i].vertical && rules[j].pos-rules[i].pos <= rulingTol {
+			sum += rules[j].pos
+			j++
+		}
+		group := rules[i:j]
+		pos := sum / float64(len(group))
+		sort.Slice(group, func(a, b int) bool { return group[a].lo < group[b].lo })
+		cur := group[0]
+		cur.pos = pos
+		for _, r := range group[1:] {
+			if r.lo <= cur.hi+rulingTol {
+				cur.hi = math.Max(cur.hi, r.hi)
+				continue
+			}
+			merged = append(merged, cur)
+			cur = r
+			cur.pos = pos
+		}
+		merged = append(merged, cur)
+		i = j
+	}
+	return merged
+}
+
+// crosses returns true if the horizontal ruling `h` and the vertical ruling `v` intersect.
+func crosses(h, v ruling) bool {
+	return v.pos >= h.lo-rulingTol && v.pos <= h.hi+rulingTol &&
+		h.pos >= v.lo-rulingTol && h.pos <= v.hi+rulingTol
+}
+
+// covers returns true if `r` runs along `pos` across the middle of `lo` to `hi`.
+func (r ruling) covers(pos, lo, hi float64) bool {
+	mid := (lo + hi) / 2
+	return math.Abs(r.pos-pos) <= rulingTol && r.lo-rulingTol <= mid && mid <= r.hi+rulingTol
+}
+
+// ruledTables returns the tables formed by the grids of intersecting `rules`, with the cells
+// filled with the text of `marks`. A grid needs at least 2 horizontal and 2 vertical rulings and
+// 2 cells.
+func ruledTables(rules []ruling, marks []textMark) []Table {
+	// Group the rulings into grids of intersecting rulings.
+	parent := make([]int, len(rules))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i, h := range rules {
+		if h.vertical {
+			continue
+		}
+		for j, v := range rules {
+			if v.vertical && crosses(h, v) {
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+	groups := map[int][]ruling{}
+	var roots []int
+	for i, r := range rules {
+		root := find(i)
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], r)
+	}
+
+	used := make([]bool, len(marks))
+	var tables []Table
+	for _, root := range roots {
+		if table, ok := gridTable(groups[root], marks, used); ok {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// gridTable returns the table of the grid formed by `rules` with the text of the `marks` that are
+// not `used` yet.
+func gridTable(rules []ruling, marks []textMark, used []bool) (Table, bool) {
+	var xs, ys []float64
+	for _, r := range rules {
+		if r.vertical {
+			xs = append(xs, r.pos)
+		} else {
+			ys = append(ys, r.pos)
+		}
+	}
+	xs, ys = clusterPositions(xs), clusterPositions(ys)
+	if len(xs) < 2 || len(ys) < 2 || (len(xs)-1)*(len(ys)-1) < 2 {
+		return Table{}, false
+	}
+	// Rows run from the top.
+	for i, j := 0, len(ys)-1; i < j; i, j = i+1, j-1 {
+		ys[i], ys[j] = ys[j], ys[i]
+	}
+	hasRule := func(vertical bool, pos, lo, hi float64) bool {
+		for _, r := range rules {
+			if r.vertical == vertical && r.covers(pos, lo, hi) {
+				return true
+			}
+		}
+		return false
+	}
+
+	numRows, numCols := len(ys)-1, len(xs)-1
+	table := Table{
+		BBox:    model.PdfRectangle{Llx: xs[0], Lly: ys[numRows], Urx: xs[numCols], Ury: ys[0]},
+		NumRows: numRows,
+		NumCols: numCols,
+		Ruled:   true,
+	}
+	covered := make([][]bool, numRows)
+	for i := range covered {
+		covered[i] = make([]bool, numCols)
+	}
+	for row := 0; row < numRows; row++ {
+		for col := 0; col < numCols; col++ {
+			if covered[row][col] {
+				continue
+			}
+			// A cell extends right and down over the missing rulings.
+			colSpan := 1
+			for col+colSpan < numCols && !covered[row][col+colSpan] &&
+				!hasRule(true, xs[col+colSpan], ys[row+1], ys[row]) {
+				colSpan++
+			}
+			rowSpan := 1
+			for row+rowSpan < numRows {
+				open := true
+				for c := col; c < col+colSpan && open; c++ {
+					open = !covered[row+rowSpan][c] && !hasRule(false, ys[row+rowSpan], xs[c], xs[c+1])
+				}
+				if !open {
+					break
+				}
+				rowSpan++
+			}
+			for r := row; r < row+rowSpan; r++ {
+				for c := col; c < col+colSpan; c++ {
+					covered[r][c] = true
+				}
+			}
+			cell := TableCell{
+				Row:     row,
+				Col:     col,
+				RowSpan: rowSpan,
+				ColSpan: colSpan,
+				BBox: model.PdfRectangle{Llx: xs[col], Lly: ys[row+rowSpan], Urx: xs[col+colSpan],
+					Ury: ys[row]},
+			}
+			cell.Text = cellText(cell.BBox, marks, used)
+			table.Cells = append(table.Cells, cell)
+		}
+	}
+	return table, true
+}
+
+// clusterPositions returns the sorted distinct positions of `positions`, with the positions within
+// rulingTol of each other replaced by their mean.
+func clusterPositions(positions []float64) []float64 {
+	sort.Float64s(positions)
+	var clusters []float64
+	for i := 0; i < len(positions); {
+		j := i + 1
+		sum := positions[i]
+		for j < len(positions) && positions[j]-positions[i] <= rulingTol {
+			sum += positions[j]
+			j++
+		}
+		clusters = append(clusters, sum/float64(j-i))
+		i = j
+	}
+	return clusters
+}
+
+// cellText returns the text of the `marks` centered in `bbox` that are not `used` yet, and marks
+// them as used.
+func cellText(bbox model.PdfRectangle, marks []textMark, used []bool) string {
+	var cell PageText
+	for i, mark := range marks {
+		if used[i] {
+			continue
+		}
+		if b := mark.quad.BBox(); containsCenter(bbox, b) {
+			cell.marks = append(cell.marks, mark)
+			used[i] = true
+		}
+	}
+	return strings.TrimSpace(cell.ToText())
+}
+
+// containsCenter returns true if the center of `b` is inside `a`.
+func containsCenter(a, b model.PdfRectangle) bool {
+	x, y := (b.Llx+b.Urx)/2, (b.Lly+b.Ury)/2
+	return a.Llx <= x && x <= a.Urx && a.Lly <= y && y <= a.Ury
+}
+
+// textSegment is a run of words of a line of text without wide gaps.
+type textSegment struct {
+	bbox  model.PdfRectangle
+	words []string
+}
+
+// alignedTables returns the tables formed by runs of consecutive `lines` that are split into
+// segments by wide gaps, skipping the lines inside the `ruled` tables.
+func alignedTables(lines []TextLine, ruled []Table) []Table {
+	var tables []Table
+	var run [][]textSegment
+	flush := func() {
+		if len(run) >= minAlignedRows {
+			if table, ok := alignedTable(run); ok {
+				tables = append(tables, table)
+			}
+		}
+		run = nil
+	}
+	for _, line := range lines {
+		if len(line.Words) == 0 || !isHorizontal(line) || insideTables(line.BBox, ruled) {
+			flush()
+			continue
+		}
+		segments := lineSegments(line)
+		if len(segments) < 2 {
+			flush()
+			continue
+		}
+		if len(run) > 0 {
+			prev := run[len(run)-1][0].bbox
+			height := line.BBox.Ury - line.BBox.Lly
+			if prev.Lly-line.BBox.Ury > rowGapRatio*height {
+				flush()
+			}
+		}
+		run = append(run, segments)
+	}
+	flush()
+	return tables
+}
+
+// isHorizontal returns true if the text of `line` runs from left to right.
+func isHorizontal(line TextLine) bool {
+	angle := line.Words[0].Marks[0].Rotation
+	return angle < 1 || angle > 359
+}
+
+// insideTables returns true if the center of `bbox` is inside one of `tables`.
+func insideTables(bbox model.PdfRectangle, tables []Table) bool {
+	for _, t := range tables {
+		if containsCenter(t.BBox, bbox) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineSegments returns the words of `line` split at the gaps wider than columnGapRatio times
+// their height.
+func lineSegments(line TextLine) []textSegment {
+	var segments []textSegment
+	for i, w := range line.Words {
+		if i > 0 {
+			prev := line.Words[i-1].BBox
+			height := math.Max(prev.Ury-prev.Lly, w.BBox.Ury-w.BBox.Lly)
+			if w.BBox.Llx-prev.Urx <= columnGapRatio*height {
+				seg := &segments[len(segments)-1]
+				seg.bbox = unionBBox(seg.bbox, w.BBox, false)
+				seg.words = append(seg.words, w.Text)
+				continue
+			}
+		}
+		segments = append(segments, textSegment{bbox: w.BBox, words: []string{w.Text}})
+	}
+	return segments
+}
+
+// alignedTable returns the table of the lines of segments `rows`. Its columns are the unions of
+// the overlapping horizontal extents of the segments. There must be at least 2 columns.
+func alignedTable(rows [][]textSegment) (Table, bool) {
+	type band struct{ lo, hi float64 }
+	var bands []band
+	for _, row := range rows {
+		for _, seg := range row {
+			bands = append(bands, band{seg.bbox.Llx, seg.bbox.Urx})
+		}
+	}
+	sort.Slice(bands, func(i, j int) bool { return bands[i].lo < bands[j].lo })
+	columns := []band{bands[0]}
+	for _, b := range bands[1:] {
+		last := &columns[len(columns)-1]
+		if b.lo <= last.hi {
+			last.hi = math.Max(last.hi, b.hi)
+			continue
+		}
+		columns = append(columns, b)
+	}
+	if len(columns) < 2 {
+		return Table{}, false
+	}
+
+	table := Table{NumRows: len(rows), NumCols: len(columns)}
+	for r, row := range rows {
+		lly, ury := row[0].bbox.Lly, row[0].bbox.Ury
+		for _, seg := range row[1:] {
+			lly, ury = math.Min(lly, seg.bbox.Lly), math.Max(ury, seg.bbox.Ury)
+		}
+		for c, col := range columns {
+			cell := TableCell{
+				Row:     r,
+				Col:     c,
+				RowSpan: 1,
+				ColSpan: 1,
+				BBox:    model.PdfRectangle{Llx: col.lo, Lly: lly, Urx: col.hi, Ury: ury},
+			}
+			var words []string
+			for _, seg := range row {
+				if seg.bbox.Llx >= col.lo && seg.bbox.Urx <= col.hi {
+					words = append(words, seg.words...)
+				}
+			}
+			cell.Text = strings.Join(words, " ")
+			table.BBox = unionBBox(table.BBox, cell.BBox, r == 0 && c == 0)
+			table.Cells = append(table.Cells, cell)
+		}
+	}
+	return table, true
+}
@@ -0,0 +1,96 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// newTablePage returns a page with a ruled table of 3 rows and 3 columns whose header spans the
+// first 2 columns, and below it a table of 3 rows and 3 columns without rules. One rule of the
+// ruled table is drawn by a form XObject and one by a thin filled rectangle.
+func newTablePage(t *testing.T) *model.PdfPage {
+	resources := model.NewPdfPageResources()
+	helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
+	require.NoError(t, resources.SetFontByName("F1", helvetica.ToPdfObject()))
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 600, 800})
+	xform.Matrix = core.MakeArrayFromFloats([]float64{1, 0, 0, 1, 100, 0})
+	require.NoError(t, xform.SetContentStream([]byte("100 640 m 100 680 l S"), core.NewRawEncoder()))
+	require.NoError(t, resources.SetXObjectFormByName("Fm1", xform))
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 600, Ury: 800}
+	page.Resources = resources
+	contents := `
+100 640 300 60 re S
+100 680 m 400 680 l 100 660 m 400 660 l S
+/Fm1 Do
+299.5 640 1 60 re f
+BT /F1 10 Tf 105 685 Td (Name) Tj ET
+BT /F1 10 Tf 305 685 Td (Qty) Tj ET
+BT /F1 10 Tf 105 665 Td (Apple) Tj ET
+BT /F1 10 Tf 205 665 Td (Red) Tj ET
+BT /F1 10 Tf 305 665 Td (3) Tj ET
+BT /F1 10 Tf 105 645 Td (Pear) Tj ET
+BT /F1 10 Tf 205 645 Td (Green, ripe) Tj ET
+BT /F1 10 Tf 305 645 Td (12) Tj ET
+BT /F1 10 Tf 100 520 Td (Inventory) Tj ET
+BT /F1 10 Tf 100 500 Td (Item) Tj 150 0 Td (Price) Tj 150 0 Td (Stock) Tj ET
+BT /F1 10 Tf 100 485 Td (Bolt) Tj 150 0 Td (0.10) Tj 150 0 Td (250) Tj ET
+BT /F1 10 Tf 100 470 Td (Hex nut) Tj 150 0 Td (0.05) Tj ET
+`
+	require.NoError(t, page.SetContentStreams([]string{contents}, core.NewRawEncoder()))
+	return page
+}
+
+func TestExtractPageTables(t *testing.T) {
+	e, err := New(newTablePage(t))
+	require.NoError(t, err)
+	tables, err := e.ExtractPageTables()
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+
+	ruled := tables[0]
+	require.True(t, ruled.Ruled)
+	require.Equal(t, 3, ruled.NumRows)
+	require.Equal(t, 3, ruled.NumCols)
+	require.Equal(t, model.PdfRectangle{Llx: 100, Lly: 640, Urx: 400, Ury: 700}, ruled.BBox)
+	require.Len(t, ruled.Cells, 8)
+	header := ruled.Cells[0]
+	require.Equal(t, "Name", header.Text)
+	require.Equal(t, 2, header.ColSpan)
+	require.Equal(t, 1, header.RowSpan)
+	require.Equal(t, model.PdfRectangle{Llx: 100, Lly: 680, Urx: 300, Ury: 700}, header.BBox)
+	require.Equal(t, [][]string{
+		{"Name", "", "Qty"},
+		{"Apple", "Red", "3"},
+		{"Pear", "Green, ripe", "12"},
+	}, ruled.Grid())
+
+	var buf bytes.Buffer
+	require.NoError(t, ruled.WriteCSV(&buf))
+	require.Equal(t, "Name,,Qty\nApple,Red,3\nPear,\"Green, ripe\",12\n", buf.String())
+
+	// The heading above the table without rules has no columns.
+	aligned := tables[1]
+	require.False(t, aligned.Ruled)
+	require.Equal(t, [][]string{
+		{"Item", "Price", "Stock"},
+		{"Bolt", "0.10", "250"},
+		{"Hex nut", "0.05", ""},
+	}, aligned.Grid())
+	for _, cell := range aligned.Cells {
+		require.Equal(t, 1, cell.RowSpan)
+		require.Equal(t, 1, cell.ColSpan)
+	}
+}
@@ -56,6 +56,17 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 	fontStack := fontStacker{}
 	var to *textObject
 
+	// MCIDs of the enclosing marked-content sequences, -1 for sequences without MCID.
+	var mcids []int
+	currentMCID := func() int {
+		for i := len(mcids) - 1; i >= 0; i-- {
+			if mcids[i] >= 0 {
+				return mcids[i]
+			}
+		}
+		return -1
+	}
+
 	cstreamParser := contentstream.NewContentStreamParser(contents)
 	operations, err := cstreamParser.Parse()
 	if err != nil {
@@ -70,6 +81,10 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 			resources *model.PdfPageResources) error {
 
 			operand := op.Operand
+			if to != nil {
+				// Keeps the colors and text rendering mode of the text object up to date.
+				to.gs = gs
+			}
 
 			switch operand {
 			case "q":
@@ -102,6 +117,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 					common.Log.Debug("BT called while in a text object")
 				}
 				to = newTextObject(e, resources, gs, &state, &fontStack)
+				to.mcid = currentMCID()
 			case "ET": // End Text
 				pageText.marks = append(pageText.marks, to.marks...)
 				to = nil
@@ -198,6 +214,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				if to == nil {
 					// This is needed for 26-Hazard-Thermal-environment.pdf
 					to = newTextObject(e, resources, gs, &state, &fontStack)
+					to.mcid = currentMCID()
 				}
 				if ok, err := to.checkOp(op, 2, true); !ok {
 					common.Log.Debug("ERROR: Tf err=%v", err)
@@ -272,6 +289,22 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 					return err
 				}
 				to.setHorizScaling(y)
+			case "BMC": // Begin marked-content sequence.
+				mcids = append(mcids, -1)
+			case "BDC": // Begin marked-content sequence with property list.
+				mcids = append(mcids, markedContentID(op, resources))
+				if to != nil {
+					to.mcid = currentMCID()
+				}
+			case "EMC": // End marked-content sequence.
+				if len(mcids) == 0 {
+					common.Log.Debug("ERROR: EMC without marked-content sequence")
+					break
+				}
+				mcids = mcids[:len(mcids)-1]
+				if to != nil {
+					to.mcid = currentMCID()
+				}
 
 			case "Do":
 				// Handle XObjects by recursing through form XObjects.
@@ -307,7 +340,13 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 					e.formResults[string(name)] = formResult
 				}
 
-				pageText.marks = append(pageText.marks, formResult.pageText.marks...)
+				// The text of a form XObject belongs to the marked-content sequence the form is
+				// painted in. The MCIDs within the form refer to the form's own content stream.
+				mcid := currentMCID()
+				for _, mark := range formResult.pageText.marks {
+					mark.mcid = mcid
+					pageText.marks = append(pageText.marks, mark)
+				}
 				state.numChars += formResult.numChars
 				state.numMisses += formResult.numMisses
 			}
@@ -321,6 +360,31 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 	return pageText, state.numChars, state.numMisses, err
 }
 
+// markedContentID returns the MCID of the property list of BDC operation `op`, or -1 if it has
+// none. The property list is either inline or a named resource in the Properties subdictionary of
+// `resources`.
+func markedContentID(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources) int {
+	if len(op.Params) != 2 {
+		return -1
+	}
+	props, ok := core.GetDict(op.Params[1])
+	if !ok && resources != nil {
+		if name, isName := core.GetName(op.Params[1]); isName {
+			if properties, has := core.GetDict(resources.Properties); has {
+				props, ok = core.GetDict(properties.Get(*name))
+			}
+		}
+	}
+	if !ok {
+		return -1
+	}
+	mcid, ok := core.GetIntVal(props.Get("MCID"))
+	if !ok {
+		return -1
+	}
+	return mcid
+}
+
 type textResult struct {
 	pageText  PageText
 	numChars  int
@@ -628,6 +692,7 @@ type textObject struct {
 	tm        transform.Matrix // Text matrix. For the character pointer.
 	tlm       transform.Matrix // Text line matrix. For the start of line pointer.
 	marks     []textMark       // Text marks get written here.
+	mcid      int              // MCID of the marked-content sequence of the text, -1 if none.
 }
 
 // newTextState returns a default textState.
@@ -650,6 +715,7 @@ func newTextObject(e *Extractor, resources *model.PdfPageResources, gs contentst
 		state:     state,
 		tm:        transform.IdentityMatrix(),
 		tlm:       transform.IdentityMatrix(),
+		mcid:      -1,
 	}
 }
 
@@ -735,7 +801,8 @@ func (to *textObject) renderText(data []byte) error {
 			string(r),
 			trm,
 			translation(to.gs.CTM.Mult(to.tm).Mult(td0)),
-			spaceWidth*trm.ScalingFactorX())
+			spaceWidth*trm.ScalingFactorX(),
+			font)
 		common.Log.Trace("i=%d code=%d mark=%s trm=%s", i, code, mark, trm)
 		to.marks = append(to.marks, mark)
 
@@ -750,6 +817,13 @@ func (to *textObject) renderText(data []byte) error {
 // glyphTextRatio converts Glyph metrics units to unscaled text space units.
 const glyphTextRatio = 1.0 / 1000.0
 
+// glyphDescent and glyphAscent are the typical extents of glyphs below and above the baseline in
+// unscaled text space units. They bound the boxes of the text marks.
+const (
+	glyphDescent = 0.2
+	glyphAscent  = 0.8
+)
+
 // translation returns the translation part of `m`.
 func translation(m transform.Matrix) transform.Point {
 	tx, ty := m.Translation()
@@ -780,12 +854,20 @@ type textMark struct {
 	height        float64         // Text height.
 	spaceWidth    float64         // Best guess at the width of a space in the font the text was rendered with.
 	count         int64           // To help with reading debug logs.
+	mcid          int             // MCID of the marked-content sequence of the text, -1 if none.
+	quad          Quad            // Box of the text in device coordinates.
+	angle         float64         // The angle of the TRM in degrees.
+	font          *model.PdfFont  // Font the text is rendered with.
+	fontSize      float64         // Font size set by Tf.
+	fillColor     model.PdfColor  // Nonstroking color.
+	renderMode    int             // Text rendering mode set by Tr.
 }
 
 // newTextMark returns an textMark for text `text` rendered with text rendering matrix (TRM) `trm` and end
 // of character device coordinates `end`. `spaceWidth` is our best guess at the width of a space in
-// the font the text is rendered in device coordinates.
-func (to *textObject) newTextMark(text string, trm transform.Matrix, end transform.Point, spaceWidth float64) textMark {
+// `font`, the font the text is rendered in, in device coordinates.
+func (to *textObject) newTextMark(text string, trm transform.Matrix, end transform.Point, spaceWidth float64,
+	font *model.PdfFont) textMark {
 	to.e.textCount++
 	theta := trm.Angle()
 	orient := nearestMultiple(theta, 10)
@@ -796,14 +878,34 @@ func (to *textObject) newTextMark(text string, trm transform.Matrix, end transfo
 		height = trm.ScalingFactorX()
 	}
 
+	// The box extends from the start to the end of the text along the baseline, and from the
+	// descent to the ascent of the font perpendicularly, in the direction of the y axis of trm.
+	start := translation(trm)
+	up := transform.Point{X: trm[3], Y: trm[4]}
+	corner := func(p transform.Point, s float64) Point {
+		return Point{X: p.X + s*up.X, Y: p.Y + s*up.Y}
+	}
+
 	return textMark{
 		text:          text,
 		orient:        orient,
-		orientedStart: translation(trm).Rotate(theta),
+		orientedStart: start.Rotate(theta),
 		orientedEnd:   end.Rotate(theta),
 		height:        height,
 		spaceWidth:    spaceWidth,
 		count:         to.e.textCount,
+		mcid:          to.mcid,
+		angle:         theta,
+		font:          font,
+		fontSize:      to.state.tfs,
+		fillColor:     to.gs.ColorNonStroking,
+		renderMode:    to.gs.TextState.RenderMode,
+		quad: Quad{
+			LL: corner(start, -glyphDescent),
+			LR: corner(end, -glyphDescent),
+			UR: corner(end, glyphAscent),
+			UL: corner(start, glyphAscent),
+		},
 	}
 }
 
@@ -861,22 +963,29 @@ func (pt PageText) height() float64 {
 
 // ToText returns the contents of `pt` as a single string.
 func (pt PageText) ToText() string {
+	lines := pt.lines()
+	texts := make([]string, 0, len(lines))
+	for _, l := range lines {
+		texts = append(texts, l.text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// lines returns the lines of text of `pt` in reading order.
+func (pt PageText) lines() []textLine {
+	// The marks are sorted in a copy to keep the order they are drawn in.
+	pt.marks = append([]textMark(nil), pt.marks...)
 	fontHeight := pt.height()
 	// We sort with a y tolerance to allow for subscripts, diacritics etc.
 	tol := minFloat(fontHeight*0.2, 5.0)
-	common.Log.Trace("ToText: %d elements fontHeight=%.1f tol=%.1f", len(pt.marks), fontHeight, tol)
+	common.Log.Trace("lines: %d elements fontHeight=%.1f tol=%.1f", len(pt.marks), fontHeight, tol)
 
 	// Uncomment the 2 following Trace statements to see the effects of sorting/
-	// common.Log.Trace("ToText: Before sorting %s", pt)
+	// common.Log.Trace("lines: Before sorting %s", pt)
 	pt.sortPosition(tol)
-	// common.Log.Trace("ToText: After sorting %s", pt)
+	// common.Log.Trace("lines: After sorting %s", pt)
 
-	lines := pt.toLines(tol)
-	texts := make([]string, 0, len(lines))
-	for _, l := range lines {
-		texts = append(texts, l.text)
-	}
-	return strings.Join(texts, "\n")
+	return pt.toLines(tol)
 }
 
 // sortPosition sorts a text list by its elements' position on a page.
@@ -897,10 +1006,11 @@ func (pt *PageText) sortPosition(tol float64) {
 
 // textLine represents a line of text on a page.
 type textLine struct {
-	y      float64   // y position of line.
-	dxList []float64 // x distance between successive words in line.
-	text   string    // text in the line.
-	words  []string  // words in the line.
+	y      float64     // y position of line.
+	dxList []float64   // x distance between successive words in line.
+	text   string      // text in the line.
+	words  []string    // words in the line.
+	marks  []*textMark // marks of the words, nil for the spaces inserted between words.
 }
 
 // toLines returns the text and positions in `pt.marks` as a slice of textLine.
@@ -931,6 +1041,7 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 	}
 	var lines []textLine
 	var words []string
+	var marks []*textMark
 	var x []float64
 	y := pt.marks[0].orientedStart.Y
 
@@ -940,10 +1051,10 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 	wordSpacing := exponAve{}
 	lastEndX := 0.0 // lastEndX is pt.marks[i-1].orientedEnd.X
 
-	for _, t := range pt.marks {
+	for i, t := range pt.marks {
 		if t.orientedStart.Y+tol < y {
 			if len(words) > 0 {
-				line := newLine(y, x, words)
+				line := newLine(y, x, words, marks)
 				if averageCharWidth.running {
 					// FIXME(peterwilliams97): Fix and reinstate combineDiacritics.
 					// line = combineDiacritics(line, averageCharWidth.ave)
@@ -952,6 +1063,7 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 				lines = append(lines, line)
 			}
 			words = []string{}
+			marks = []*textMark{}
 			x = []float64{}
 			y = t.orientedStart.Y
 			scanning = false
@@ -986,18 +1098,20 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 
 		if isSpace {
 			words = append(words, " ")
+			marks = append(marks, nil)
 			x = append(x, (lastEndX+t.orientedStart.X)*0.5)
 		}
 
 		// Add the text to the line.
 		lastEndX = t.orientedEnd.X
 		words = append(words, t.text)
+		marks = append(marks, &pt.marks[i])
 		x = append(x, t.orientedStart.X)
 		scanning = true
 		common.Log.Trace("lastEndX=%.2f", lastEndX)
 	}
 	if len(words) > 0 {
-		line := newLine(y, x, words)
+		line := newLine(y, x, words, marks)
 		if averageCharWidth.running {
 			line = removeDuplicates(line, averageCharWidth.ave)
 		}
@@ -1036,14 +1150,14 @@ func (exp *exponAve) update(x float64) float64 {
 	return exp.ave
 }
 
-// newLine returns the textLine representation of strings `words` with y coordinate `y` and x
-// coordinates `x`.
-func newLine(y float64, x []float64, words []string) textLine {
+// newLine returns the textLine representation of strings `words` with y coordinate `y`, x
+// coordinates `x` and text marks `marks`.
+func newLine(y float64, x []float64, words []string, marks []*textMark) textLine {
 	dxList := make([]float64, 0, len(x))
 	for i := 1; i < len(x); i++ {
 		dxList = append(dxList, x[i]-x[i-1])
 	}
-	return textLine{y: y, dxList: dxList, text: strings.Join(words, ""), words: words}
+	return textLine{y: y, dxList: dxList, text: strings.Join(words, ""), words: words, marks: marks}
 }
 
 // removeDuplicates returns `line` with duplicate characters removed. `charWidth` is the average
@@ -1056,6 +1170,7 @@ func removeDuplicates(line textLine, charWidth float64) textLine {
 	// NOTE(peterwilliams97) 0.3 is a guess. It may be possible to tune this to a better value.
 	tol := charWidth * 0.3
 	words := []string{line.words[0]}
+	marks := []*textMark{line.marks[0]}
 	var dxList []float64
 
 	w0 := line.words[0]
@@ -1063,11 +1178,12 @@ func removeDuplicates(line textLine, charWidth float64) textLine {
 		w := line.words[i+1]
 		if w != w0 || dx > tol {
 			words = append(words, w)
+			marks = append(marks, line.marks[i+1])
 			dxList = append(dxList, dx)
 		}
 		w0 = w
 	}
-	return textLine{y: line.y, dxList: dxList, text: strings.Join(words, ""), words: words}
+	return textLine{y: line.y, dxList: dxList, text: strings.Join(words, ""), words: words, marks: marks}
 }
 
 // combineDiacritics returns `line` with diacritics close to characters combined with the characters.
@@ -1084,12 +1200,14 @@ func combineDiacritics(line textLine, charWidth float64) textLine {
 	common.Log.Trace("combineDiacritics: charWidth=%.2f tol=%.2f", charWidth, tol)
 
 	var words []string
+	var marks []*textMark
 	var dxList []float64
 	w := line.words[0]
 	w, c := countDiacritic(w)
 	delta := 0.0
 	dx0 := 0.0
 	parts := []string{w}
+	partsMark := line.marks[0]
 	numChars := c
 
 	for i := 0; i < len(line.dxList); i++ {
@@ -1110,8 +1228,10 @@ func combineDiacritics(line textLine, charWidth float64) textLine {
 					dxList = append(dxList, dx0)
 				}
 				words = append(words, combine(parts))
+				marks = append(marks, partsMark)
 			}
 			parts = []string{w}
+			partsMark = line.marks[i+1]
 			numChars = c
 			dx0 = dx
 			delta = 0.0
@@ -1122,6 +1242,7 @@ func combineDiacritics(line textLine, charWidth float64) textLine {
 			dxList = append(dxList, dx0)
 		}
 		words = append(words, combine(parts))
+		marks = append(marks, partsMark)
 	}
 
 	if len(words) != len(dxList)+1 {
@@ -1129,7 +1250,7 @@ func combineDiacritics(line textLine, charWidth float64) textLine {
 			len(words), words, len(dxList), dxList)
 		return line
 	}
-	return textLine{y: line.y, dxList: dxList, text: strings.Join(words, ""), words: words}
+	return textLine{y: line.y, dxList: dxList, text: strings.Join(words, ""), words: words, marks: marks}
 }
 
 // combine combines any diacritics in `parts` with the single non-diacritic character in `parts`.
@@ -0,0 +1,143 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TextMark is a character drawn on a page.
+type TextMark struct {
+	// Text is the text of the character.
+	Text string
+
+	// BBox is the bounding box of the character in device coordinates. The box extends from the
+	// typical descent to the typical ascent of glyphs.
+	BBox model.PdfRectangle
+
+	// Quad is the box of the character relative to the direction of the text. See Quad.
+	Quad Quad
+
+	// Rotation is the angle of the baseline of the character, clockwise in degrees from 0 to 360.
+	Rotation float64
+
+	// Font is the font of the character and FontName its base font name.
+	Font     *model.PdfFont
+	FontName string
+
+	// FontSize is the font size set by Tf. The size of the character in device coordinates also
+	// depends on the text matrix and the CTM.
+	FontSize float64
+
+	// FillColor is the nonstroking color of the character, in the current nonstroking color
+	// space.
+	FillColor model.PdfColor
+
+	// RenderMode is the text rendering mode of the character set by Tr, 0 to 7: 0 fills the
+	// glyphs, 3 makes them invisible.
+	RenderMode int
+
+	// MCID is the MCID of the marked-content sequence of the character, -1 if none.
+	MCID int
+}
+
+// TextWord is a word of a line of text: characters between spaces.
+type TextWord struct {
+	Text  string
+	BBox  model.PdfRectangle
+	Marks []TextMark
+}
+
+// TextLine is a line of text.
+type TextLine struct {
+	// Text is the text of the line as returned by PageText.ToText, with the words separated by
+	// spaces.
+	Text  string
+	BBox  model.PdfRectangle
+	Words []TextWord
+}
+
+// ExtractPageTextMarks returns the characters of the page of `e` in the order they are drawn and
+// the lines of text they form in reading order.
+func (e *Extractor) ExtractPageTextMarks() ([]TextMark, []TextLine, error) {
+	pageText, _, _, err := e.ExtractPageText()
+	if err != nil {
+		return nil, nil, err
+	}
+	return pageText.Marks(), pageText.Lines(), nil
+}
+
+// Marks returns the characters of `pt` in the order they are drawn.
+func (pt PageText) Marks() []TextMark {
+	marks := make([]TextMark, len(pt.marks))
+	for i := range pt.marks {
+		marks[i] = pt.marks[i].toTextMark()
+	}
+	return marks
+}
+
+// Lines returns the lines of text of `pt` in reading order. Characters drawn several times at
+// the same position to appear bold are only part of the lines once.
+func (pt PageText) Lines() []TextLine {
+	var lines []TextLine
+	for _, l := range pt.lines() {
+		line := TextLine{Text: l.text}
+		var word *TextWord
+		for _, mark := range l.marks {
+			if mark == nil || strings.TrimFunc(mark.text, unicode.IsSpace) == "" {
+				word = nil
+				continue
+			}
+			if word == nil {
+				line.Words = append(line.Words, TextWord{})
+				word = &line.Words[len(line.Words)-1]
+			}
+			m := mark.toTextMark()
+			word.Text += m.Text
+			word.BBox = unionBBox(word.BBox, m.BBox, len(word.Marks) == 0)
+			word.Marks = append(word.Marks, m)
+		}
+		for i, word := range line.Words {
+			line.BBox = unionBBox(line.BBox, word.BBox, i == 0)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// toTextMark returns the TextMark of `t`.
+func (t *textMark) toTextMark() TextMark {
+	mark := TextMark{
+		Text:       t.text,
+		BBox:       t.quad.BBox(),
+		Quad:       t.quad,
+		Rotation:   t.angle,
+		Font:       t.font,
+		FontSize:   t.fontSize,
+		FillColor:  t.fillColor,
+		RenderMode: t.renderMode,
+		MCID:       t.mcid,
+	}
+	if t.font != nil {
+		mark.FontName = t.font.BaseFont()
+	}
+	return mark
+}
+
+// unionBBox returns the bounding box of `a` and `b`, or `b` if `first` is true.
+func unionBBox(a, b model.PdfRectangle, first bool) model.PdfRectangle {
+	if first {
+		return b
+	}
+	return model.PdfRectangle{
+		Llx: math.Min(a.Llx, b.Llx), Lly: math.Min(a.Lly, b.Lly),
+		Urx: math.Max(a.Urx, b.Urx), Ury: math.Max(a.Ury, b.Ury),
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+func TestExtractPageTextMarks(t *testing.T) {
+	resources := model.NewPdfPageResources()
+	helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
+	courier := model.NewStandard14FontMustCompile(model.CourierName)
+	require.NoError(t, resources.SetFontByName("F1", helvetica.ToPdfObject()))
+	require.NoError(t, resources.SetFontByName("F2", courier.ToPdfObject()))
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 600, Ury: 800}
+	page.Resources = resources
+	contents := `
+q BT /F2 10 Tf 3 Tr 10 680 Td (Hidden) Tj ET Q
+BT /F1 12 Tf 1 0 0 rg 10 700 Td (Hello world) Tj ET
+`
+	require.NoError(t, page.SetContentStreams([]string{contents}, core.NewRawEncoder()))
+	e, err := New(page)
+	require.NoError(t, err)
+	marks, lines, err := e.ExtractPageTextMarks()
+	require.NoError(t, err)
+
+	// The marks are in drawing order.
+	require.Len(t, marks, 17)
+	hidden := marks[0]
+	require.Equal(t, "H", hidden.Text)
+	require.Equal(t, "Courier", hidden.FontName)
+	require.Equal(t, 3, hidden.RenderMode)
+	require.Equal(t, 10.0, hidden.FontSize)
+
+	// "H" of Helvetica is 8.664 wide at 12 points.
+	mark := marks[6]
+	require.Equal(t, "H", mark.Text)
+	require.Equal(t, "Helvetica", mark.FontName)
+	require.Equal(t, 12.0, mark.FontSize)
+	require.Equal(t, 0, mark.RenderMode)
+	require.Equal(t, 0.0, mark.Rotation)
+	require.Equal(t, -1, mark.MCID)
+	color, ok := mark.FillColor.(*model.PdfColorDeviceRGB)
+	require.True(t, ok)
+	require.Equal(t, 1.0, color.R())
+	require.InDelta(t, 10, mark.BBox.Llx, 0.001)
+	require.InDelta(t, 697.6, mark.BBox.Lly, 0.001)
+	require.InDelta(t, 18.664, mark.BBox.Urx, 0.001)
+	require.InDelta(t, 709.6, mark.BBox.Ury, 0.001)
+
+	// The lines are in reading order.
+	require.Len(t, lines, 2)
+	line := lines[0]
+	require.Equal(t, "Hello world", line.Text)
+	require.Len(t, line.Words, 2)
+	require.Equal(t, "Hello", line.Words[0].Text)
+	require.Equal(t, "world", line.Words[1].Text)
+	require.Len(t, line.Words[1].Marks, 5)
+	require.Equal(t, line.Words[0].BBox.Llx, line.BBox.Llx)
+	require.Equal(t, line.Words[1].BBox.Urx, line.BBox.Urx)
+	require.Equal(t, "Hidden", lines[1].Words[0].Text)
+}
@@ -0,0 +1,19 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package imposition arranges existing pages on new sheets for printing: N-up grids, saddle
+// stitch booklets and tiling of a large page across several smaller sheets. The pages are
+// placed as form XObjects, clipped to their crop box and displayed with their rotation, and can
+// be marked with crop marks. The annotations of the pages are not placed.
+//
+// Example:
+//
+//	sheets, err := imposition.NUp(pages, 2, 4, imposition.Options{Gutter: 10, CropMarks: true})
+//	...
+//	for _, sheet := range sheets {
+//		err := writer.AddPage(sheet)
+//		...
+//	}
+package imposition
@@ -0,0 +1,269 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package imposition
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Size of the A4 paper format in points.
+const (
+	a4Width  = 210 / 25.4 * 72
+	a4Height = 297 / 25.4 * 72
+)
+
+// Options are the options of the layouts. All lengths are in points.
+type Options struct {
+	// SheetWidth and SheetHeight are the size of the sheets. Default to A4, in portrait
+	// orientation for NUp and Tile and in landscape orientation for Booklet.
+	SheetWidth  float64
+	SheetHeight float64
+
+	// Margin is the space between the edges of the sheets and the pages.
+	Margin float64
+
+	// Gutter is the space between adjacent pages of a sheet.
+	Gutter float64
+
+	// AutoRotate rotates the pages by 90 degrees when their orientation differs from the
+	// orientation of their cell, to print them larger.
+	AutoRotate bool
+
+	// CropMarks draws marks at the corners of the pages placed.
+	CropMarks bool
+
+	// CropMarkLength is the length of the crop marks, 10 by default.
+	CropMarkLength float64
+
+	// CropMarkOffset is the distance between the crop marks and the corners of the pages, 3 by
+	// default.
+	CropMarkOffset float64
+
+	// Scale is the scale the page is printed at by Tile, 1 by default.
+	Scale float64
+
+	// Overlap is the width of the page area repeated on adjacent tiles by Tile.
+	Overlap float64
+}
+
+// sheetSize returns the size of the sheets, A4 in orientation `landscape` by default.
+func (o Options) sheetSize(landscape bool) (float64, float64) {
+	if o.SheetWidth > 0 && o.SheetHeight > 0 {
+		return o.SheetWidth, o.SheetHeight
+	}
+	if landscape {
+		return a4Height, a4Width
+	}
+	return a4Width, a4Height
+}
+
+// cropMarks returns the length and offset of the crop marks.
+func (o Options) cropMarks() (float64, float64) {
+	length, offset := o.CropMarkLength, o.CropMarkOffset
+	if length <= 0 {
+		length = 10
+	}
+	if offset <= 0 {
+		offset = 3
+	}
+	return length, offset
+}
+
+// source is a page placed on sheets.
+type source struct {
+	form *model.XObjectForm
+
+	// Visible area of the page, in the coordinates of the page.
+	box *model.PdfRectangle
+
+	// Rotation of the page, clockwise in degrees: 0, 90, 180 or 270.
+	rotate int
+}
+
+// newSource returns the form XObject of `page`, clipped to its crop box.
+func newSource(page *model.PdfPage) (*source, error) {
+	mediaBox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	box := *mediaBox
+	if crop := page.CropBox; crop != nil {
+		box.Llx = math.Max(box.Llx, math.Min(crop.Llx, crop.Urx))
+		box.Lly = math.Max(box.Lly, math.Min(crop.Lly, crop.Ury))
+		box.Urx = math.Min(box.Urx, math.Max(crop.Llx, crop.Urx))
+		box.Ury = math.Min(box.Ury, math.Max(crop.Lly, crop.Ury))
+	}
+	if box.Urx <= box.Llx || box.Ury <= box.Lly {
+		common.Log.Debug("ERROR: Empty page area %v", box)
+		return nil, errors.New("empty page area")
+	}
+
+	rotate := 0
+	if page.Rotate != nil {
+		rotate = int(*page.Rotate%360+360) % 360
+		if rotate%90 != 0 {
+			common.Log.Debug("ERROR: Invalid page rotation %d, ignoring", *page.Rotate)
+			rotate = 0
+		}
+	}
+
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+	form := model.NewXObjectForm()
+	form.Resources = page.Resources
+	if form.Resources == nil {
+		form.Resources = model.NewPdfPageResources()
+	}
+	form.BBox = core.MakeArrayFromFloats([]float64{box.Llx, box.Lly, box.Urx, box.Ury})
+	if err := form.SetContentStream([]byte(content), core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+	return &source{form: form, box: &box, rotate: rotate}, nil
+}
+
+// size returns the size of the page as displayed with rotation `rotate` added to its rotation.
+func (src *source) size(rotate int) (float64, float64) {
+	w, h := src.box.Width(), src.box.Height()
+	if (src.rotate+rotate)%180 != 0 {
+		return h, w
+	}
+	return w, h
+}
+
+// matrix returns the transformation matrix displaying the page with rotation `rotate` added to
+// its rotation, at scale `scale` with the lower left corner at `x`, `y`.
+func (src *source) matrix(rotate int, scale, x, y float64) [6]float64 {
+	bx, by := src.box.Llx, src.box.Lly
+	bw, bh := src.box.Width(), src.box.Height()
+	s := scale
+	switch (src.rotate + rotate) % 360 {
+	case 90:
+		return [6]float64{0, -s, s, 0, x - s*by, y + s*(bw+bx)}
+	case 180:
+		return [6]float64{-s, 0, 0, -s, x + s*(bw+bx), y + s*(bh+by)}
+	case 270:
+		return [6]float64{0, s, -s, 0, x + s*(bh+by), y - s*bx}
+	}
+	return [6]float64{s, 0, 0, s, x - s*bx, y - s*by}
+}
+
+// imposer places pages on sheets, sharing the form XObject of a page between sheets.
+type imposer struct {
+	opts    Options
+	sources map[*model.PdfPage]*source
+}
+
+// newImposer returns an imposer with options `opts`.
+func newImposer(opts Options) *imposer {
+	return &imposer{opts: opts, sources: map[*model.PdfPage]*source{}}
+}
+
+// source returns the source of `page`.
+func (imp *imposer) source(page *model.PdfPage) (*source, error) {
+	if src, has := imp.sources[page]; has {
+		return src, nil
+	}
+	src, err := newSource(page)
+	if err != nil {
+		return nil, err
+	}
+	imp.sources[page] = src
+	return src, nil
+}
+
+// sheet is a sheet being imposed.
+type sheet struct {
+	page  *model.PdfPage
+	cc    *contentstream.ContentCreator
+	names map[*source]core.PdfObjectName
+}
+
+// newSheet returns an empty sheet of size `width`, `height`.
+func newSheet(width, height float64) *sheet {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: width, Ury: height}
+	page.Resources = model.NewPdfPageResources()
+	return &sheet{
+		page:  page,
+		cc:    contentstream.NewContentCreator(),
+		names: map[*source]core.PdfObjectName{},
+	}
+}
+
+// draw draws `src` with transformation matrix `m`.
+func (s *sheet) draw(src *source, m [6]float64) error {
+	name, has := s.names[src]
+	if !has {
+		name = core.PdfObjectName(fmt.Sprintf("Page%d", len(s.names)+1))
+		if err := s.page.Resources.SetXObjectFormByName(name, src.form); err != nil {
+			return err
+		}
+		s.names[src] = name
+	}
+	s.cc.Add_q().Add_cm(m[0], m[1], m[2], m[3], m[4], m[5]).Add_Do(name).Add_Q()
+	return nil
+}
+
+// drawCropMarks draws crop marks with options `opts` at the corners of rectangle `x`, `y`,
+// `width`, `height`.
+func (s *sheet) drawCropMarks(x, y, width, height float64, opts Options) {
+	length, offset := opts.cropMarks()
+	s.cc.Add_q().Add_w(0.25).Add_G(0)
+	for _, corner := range [][4]float64{
+		{x, y, -1, -1}, {x + width, y, 1, -1}, {x, y + height, -1, 1}, {x + width, y + height, 1, 1},
+	} {
+		cx, cy, dx, dy := corner[0], corner[1], corner[2], corner[3]
+		s.cc.Add_m(cx+dx*offset, cy).Add_l(cx+dx*(offset+length), cy)
+		s.cc.Add_m(cx, cy+dy*offset).Add_l(cx, cy+dy*(offset+length))
+	}
+	s.cc.Add_S().Add_Q()
+}
+
+// finish sets the content of the sheet and returns its page.
+func (s *sheet) finish() (*model.PdfPage, error) {
+	if err := s.page.SetContentStreams([]string{s.cc.String()}, core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+	return s.page, nil
+}
+
+// place draws `page` fitted and centered in the cell of `s` at `x`, `y` of size `width`,
+// `height`.
+func (imp *imposer) place(s *sheet, page *model.PdfPage, x, y, width, height float64) error {
+	src, err := imp.source(page)
+	if err != nil {
+		return err
+	}
+	rotate := 0
+	pw, ph := src.size(0)
+	if imp.opts.AutoRotate && (pw > ph) != (width > height) && pw != ph && width != height {
+		// Undoes the rotation of the page when possible.
+		rotate = 90
+		if src.rotate == 90 {
+			rotate = 270
+		}
+		pw, ph = ph, pw
+	}
+	scale := math.Min(width/pw, height/ph)
+	px := x + (width-scale*pw)/2
+	py := y + (height-scale*ph)/2
+	if err := s.draw(src, src.matrix(rotate, scale, px, py)); err != nil {
+		return err
+	}
+	if imp.opts.CropMarks {
+		s.drawCropMarks(px, py, scale*pw, scale*ph, imp.opts)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package imposition
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// makePage returns a page with media box `width`, `height` filled with a rectangle.
+func makePage(t *testing.T, width, height float64) *model.PdfPage {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: width, Ury: height}
+	require.NoError(t, page.SetContentStreams([]string{"0 0 1 rg 10 10 50 50 re f"}, core.NewRawEncoder()))
+	return page
+}
+
+// sheetContent returns the content of `sheet`.
+func sheetContent(t *testing.T, sheet *model.PdfPage) string {
+	content, err := sheet.GetAllContentStreams()
+	require.NoError(t, err)
+	return content
+}
+
+func TestNUp(t *testing.T) {
+	var pages []*model.PdfPage
+	for i := 0; i < 5; i++ {
+		pages = append(pages, makePage(t, 100, 200))
+	}
+	opts := Options{SheetWidth: 420, SheetHeight: 420, Margin: 10, Gutter: 20, CropMarks: true}
+	sheets, err := NUp(pages, 2, 2, opts)
+	require.NoError(t, err)
+	require.Len(t, sheets, 2)
+
+	// Cells of 190x190, pages scaled by 0.95 and centered.
+	content := sheetContent(t, sheets[0])
+	require.Equal(t, 4, strings.Count(content, " Do"))
+	require.Contains(t, content, "0.95 0 0 0.95 57.5 220 cm")
+	require.True(t, sheets[0].Resources.HasXObjectByName("Page1"))
+	require.Contains(t, content, "\nS\n")
+	require.Equal(t, 1, strings.Count(sheetContent(t, sheets[1]), " Do"))
+
+	_, err = NUp(pages, 0, 2, opts)
+	require.Error(t, err)
+	opts.Gutter = 500
+	_, err = NUp(pages, 2, 2, opts)
+	require.Error(t, err)
+
+	w := model.NewPdfWriter()
+	for _, sheet := range sheets {
+		require.NoError(t, w.AddPage(sheet))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+}
+
+func TestRotationAndCropBox(t *testing.T) {
+	page := makePage(t, 200, 100)
+	page.CropBox = &model.PdfRectangle{Llx: 20, Lly: 10, Urx: 120, Ury: 60}
+	rotate := int64(90)
+	page.Rotate = &rotate
+
+	// Displayed as 50x100, fitting a 100x200 cell at scale 2.
+	sheets, err := NUp([]*model.PdfPage{page}, 1, 1, Options{SheetWidth: 100, SheetHeight: 200})
+	require.NoError(t, err)
+	content := sheetContent(t, sheets[0])
+	require.Contains(t, content, "0 -2 2 0 -20 240 cm")
+	form, err := sheets[0].Resources.GetXObjectFormByName("Page1")
+	require.NoError(t, err)
+	bbox, ok := core.GetArray(form.BBox)
+	require.True(t, ok)
+	require.Equal(t, "[20 10 120 60]", bbox.WriteString())
+
+	// Rotated back to landscape to fit a landscape cell.
+	sheets, err = NUp([]*model.PdfPage{page}, 1, 1, Options{SheetWidth: 200, SheetHeight: 100, AutoRotate: true})
+	require.NoError(t, err)
+	require.Contains(t, sheetContent(t, sheets[0]), "2 0 0 2 -40 -20 cm")
+}
+
+func TestBooklet(t *testing.T) {
+	require.Equal(t, []int{-1, 0, 1, -1, -1, 2, 3, 4}, BookletOrder(5))
+	require.Equal(t, []int{3, 0, 1, 2}, BookletOrder(4))
+
+	var pages []*model.PdfPage
+	for i := 0; i < 5; i++ {
+		pages = append(pages, makePage(t, 100, 200))
+	}
+	sheets, err := Booklet(pages, Options{})
+	require.NoError(t, err)
+	require.Len(t, sheets, 4)
+	mediaBox, err := sheets[0].GetMediaBox()
+	require.NoError(t, err)
+	require.True(t, mediaBox.Width() > mediaBox.Height())
+	require.Equal(t, 1, strings.Count(sheetContent(t, sheets[0]), " Do"))
+	require.Equal(t, 2, strings.Count(sheetContent(t, sheets[3]), " Do"))
+}
+
+func TestTile(t *testing.T) {
+	page := makePage(t, 300, 300)
+	opts := Options{SheetWidth: 120, SheetHeight: 120, Margin: 10, Overlap: 10, CropMarks: true}
+	sheets, err := Tile(page, opts)
+	require.NoError(t, err)
+	// Steps of 90 over 300 points: 4 columns and 4 rows.
+	require.Len(t, sheets, 16)
+
+	// Second tile of the first row: page area from 90 to 190 and 200 to 300.
+	content := sheetContent(t, sheets[1])
+	require.Contains(t, content, "10 10 100 100 re\nW\nn")
+	require.Contains(t, content, "1 0 0 1 -80 -190 cm")
+
+	// Last tile: page area from 270 to 300 and 0 to 30.
+	content = sheetContent(t, sheets[15])
+	require.Contains(t, content, "10 80 30 30 re\nW\nn")
+}
@@ -0,0 +1,172 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package imposition
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// NUp returns sheets with `pages` placed in grids of `cols` columns and `rows` rows, filled
+// from left to right and top to bottom. The pages are scaled to fit their cells and centered.
+func NUp(pages []*model.PdfPage, cols, rows int, opts Options) ([]*model.PdfPage, error) {
+	width, height := opts.sheetSize(false)
+	return grid(pages, cols, rows, width, height, opts)
+}
+
+// TwoUp returns sheets with two of `pages` side by side, or one above the other on portrait
+// sheets. See NUp.
+func TwoUp(pages []*model.PdfPage, opts Options) ([]*model.PdfPage, error) {
+	width, height := opts.sheetSize(false)
+	if width > height {
+		return grid(pages, 2, 1, width, height, opts)
+	}
+	return grid(pages, 1, 2, width, height, opts)
+}
+
+// FourUp returns sheets with four of `pages` in a 2x2 grid. See NUp.
+func FourUp(pages []*model.PdfPage, opts Options) ([]*model.PdfPage, error) {
+	return NUp(pages, 2, 2, opts)
+}
+
+// grid returns sheets of size `width`, `height` with `pages` placed in grids of `cols` columns
+// and `rows` rows. Nil pages leave their cell empty.
+func grid(pages []*model.PdfPage, cols, rows int, width, height float64, opts Options) ([]*model.PdfPage, error) {
+	if cols < 1 || rows < 1 {
+		common.Log.Debug("ERROR: Invalid grid %dx%d", cols, rows)
+		return nil, errors.New("invalid grid")
+	}
+	cellWidth := (width - 2*opts.Margin - float64(cols-1)*opts.Gutter) / float64(cols)
+	cellHeight := (height - 2*opts.Margin - float64(rows-1)*opts.Gutter) / float64(rows)
+	if cellWidth <= 0 || cellHeight <= 0 {
+		common.Log.Debug("ERROR: No space for %dx%d pages on sheet %.2fx%.2f", cols, rows, width, height)
+		return nil, errors.New("margins and gutters larger than the sheet")
+	}
+
+	imp := newImposer(opts)
+	var sheets []*model.PdfPage
+	perSheet := cols * rows
+	for start := 0; start < len(pages); start += perSheet {
+		s := newSheet(width, height)
+		for i := 0; i < perSheet && start+i < len(pages); i++ {
+			page := pages[start+i]
+			if page == nil {
+				continue
+			}
+			col, row := i%cols, i/cols
+			x := opts.Margin + float64(col)*(cellWidth+opts.Gutter)
+			y := height - opts.Margin - float64(row+1)*cellHeight - float64(row)*opts.Gutter
+			if err := imp.place(s, page, x, y, cellWidth, cellHeight); err != nil {
+				return nil, err
+			}
+		}
+		sheet, err := s.finish()
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets, nil
+}
+
+// BookletOrder returns the indices of the pages of a saddle stitch booklet of `numPages` pages,
+// in the order they are placed on the sheets: left then right page of the front of the first
+// sheet, of its back, then of the next sheets. The number of pages is rounded up to a multiple
+// of 4 with blank pages, of index -1.
+func BookletOrder(numPages int) []int {
+	n := (numPages + 3) / 4 * 4
+	index := func(i int) int {
+		if i >= numPages {
+			return -1
+		}
+		return i
+	}
+	var order []int
+	for i := 0; i < n/4; i++ {
+		order = append(order, index(n-1-2*i), index(2*i), index(2*i+1), index(n-2-2*i))
+	}
+	return order
+}
+
+// Booklet returns the sides of the sheets of a saddle stitch booklet of `pages`, fronts and backs
+// alternating, with two pages side by side on each side. Printed double sided, flipped on the
+// short edge, folded and stitched in the middle, the sheets form the booklet.
+func Booklet(pages []*model.PdfPage, opts Options) ([]*model.PdfPage, error) {
+	if len(pages) == 0 {
+		return nil, nil
+	}
+	var ordered []*model.PdfPage
+	for _, i := range BookletOrder(len(pages)) {
+		if i < 0 {
+			ordered = append(ordered, nil)
+		} else {
+			ordered = append(ordered, pages[i])
+		}
+	}
+	width, height := opts.sheetSize(true)
+	return grid(ordered, 2, 1, width, height, opts)
+}
+
+// Tile returns sheets covering `page` printed at scale Options.Scale, from left to right and top
+// to bottom. The adjacent tiles repeat Options.Overlap of the page, and crop marks show the edges
+// of the area of the page on each tile.
+func Tile(page *model.PdfPage, opts Options) ([]*model.PdfPage, error) {
+	width, height := opts.sheetSize(false)
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	cellWidth := width - 2*opts.Margin
+	cellHeight := height - 2*opts.Margin
+	stepX, stepY := cellWidth-opts.Overlap, cellHeight-opts.Overlap
+	if stepX <= 0 || stepY <= 0 {
+		common.Log.Debug("ERROR: No space for tiles on sheet %.2fx%.2f", width, height)
+		return nil, errors.New("margins and overlap larger than the sheet")
+	}
+
+	imp := newImposer(opts)
+	src, err := imp.source(page)
+	if err != nil {
+		return nil, err
+	}
+	pageWidth, pageHeight := src.size(0)
+	pageWidth *= scale
+	pageHeight *= scale
+	cols := int(math.Max(1, math.Ceil((pageWidth-opts.Overlap)/stepX)))
+	rows := int(math.Max(1, math.Ceil((pageHeight-opts.Overlap)/stepY)))
+
+	var sheets []*model.PdfPage
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			// Area of the page on the tile, in the coordinates of the page scaled.
+			left := float64(col) * stepX
+			top := pageHeight - float64(row)*stepY
+			tileWidth := math.Min(cellWidth, pageWidth-left)
+			tileHeight := math.Min(cellHeight, top)
+
+			s := newSheet(width, height)
+			x := opts.Margin
+			y := height - opts.Margin - tileHeight
+			s.cc.Add_q().Add_re(x, y, tileWidth, tileHeight).Add_W().Add_n()
+			if err := s.draw(src, src.matrix(0, scale, x-left, y-(top-tileHeight))); err != nil {
+				return nil, err
+			}
+			s.cc.Add_Q()
+			if opts.CropMarks {
+				s.drawCropMarks(x, y, tileWidth, tileHeight, opts)
+			}
+			sheet, err := s.finish()
+			if err != nil {
+				return nil, err
+			}
+			sheets = append(sheets, sheet)
+		}
+	}
+	return sheets, nil
+}
@@ -0,0 +1,298 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+import (
+	"errors"
+)
+
+// qeEntry is an entry of the probability estimation table of the arithmetic decoder.
+type qeEntry struct {
+	qe        uint32
+	nmps      byte
+	nlps      byte
+	switchMPS bool
+}
+
+// qeTable is the Qe value and probability estimation state transition table (Table E.1).
+var qeTable = [...]qeEntry{
+	{0x5601, 1, 1, true},
+	{0x3401, 2, 6, false},
+	{0x1801, 3, 9, false},
+	{0x0AC1, 4, 12, false},
+	{0x0521, 5, 29, false},
+	{0x0221, 38, 33, false},
+	{0x5601, 7, 6, true},
+	{0x5401, 8, 14, false},
+	{0x4801, 9, 14, false},
+	{0x3801, 10, 14, false},
+	{0x3001, 11, 17, false},
+	{0x2401, 12, 18, false},
+	{0x1C01, 13, 20, false},
+	{0x1601, 29, 21, false},
+	{0x5601, 15, 14, true},
+	{0x5401, 16, 14, false},
+	{0x5101, 17, 15, false},
+	{0x4801, 18, 16, false},
+	{0x3801, 19, 17, false},
+	{0x3401, 20, 18, false},
+	{0x3001, 21, 19, false},
+	{0x2801, 22, 19, false},
+	{0x2401, 23, 20, false},
+	{0x2201, 24, 21, false},
+	{0x1C01, 25, 22, false},
+	{0x1801, 26, 23, false},
+	{0x1601, 27, 24, false},
+	{0x1401, 28, 25, false},
+	{0x1201, 29, 26, false},
+	{0x1101, 30, 27, false},
+	{0x0AC1, 31, 28, false},
+	{0x09C1, 32, 29, false},
+	{0x08A1, 33, 30, false},
+	{0x0521, 34, 31, false},
+	{0x0441, 35, 32, false},
+	{0x02A1, 36, 33, false},
+	{0x0221, 37, 34, false},
+	{0x0141, 38, 35, false},
+	{0x0111, 39, 36, false},
+	{0x0085, 40, 37, false},
+	{0x0049, 41, 38, false},
+	{0x0025, 42, 39, false},
+	{0x0015, 43, 40, false},
+	{0x0009, 44, 41, false},
+	{0x0005, 45, 42, false},
+	{0x0001, 45, 43, false},
+	{0x5601, 46, 46, false},
+}
+
+// Limits of the work done by the arithmetic decoder. The decoder reads 0xFF fill bytes past the
+// end of its data and so never runs out of data by itself, while highly skewed contexts decode
+// many pixels or symbols per byte.
+const (
+	// maxFillBytes is the number of fill bytes the decoder reads past the end of its data, or past
+	// the terminating marker, before the data counts as exhausted. Valid data needs a few.
+	maxFillBytes = 256
+
+	// maxPixelsPerByte and maxSymbolsPerByte bound the size of the regions and the number of
+	// symbols and symbol instances decoded from coded data by its length. The bounds are
+	// generous as blank regions with typical prediction take only a few bytes.
+	maxPixelsPerByte  = 1 << 21
+	maxSymbolsPerByte = 1 << 8
+)
+
+var (
+	errDataExhausted = errors.New("JBIG2 arithmetic coded data exhausted")
+	errTooLarge      = errors.New("JBIG2 segment too large for its data")
+)
+
+// checkCodedSize returns errTooLarge if `n` pixels or symbols cannot have been coded with `dataLen`
+// bytes, at `perByte` pixels or symbols per byte.
+func checkCodedSize(n, dataLen, perByte int) error {
+	if uint64(n) > uint64(dataLen+1)*uint64(perByte) {
+		return errTooLarge
+	}
+	return nil
+}
+
+// arithDecoder is the MQ arithmetic decoder (Annex E).
+//
+// An arithmetic coding context is stored in a byte as the index of its probability estimation
+// state shifted left by one, ORed with its more probable symbol.
+type arithDecoder struct {
+	data []byte
+	pos  int
+	c    uint32
+	a    uint32
+	ct   int
+	fill int // Number of fill bytes read.
+}
+
+// newArithDecoder returns an arithmetic decoder for `data` (INITDEC, E.3.5).
+func newArithDecoder(data []byte) *arithDecoder {
+	d := &arithDecoder{data: data}
+	d.c = uint32(d.byteAt(0)) << 16
+	d.byteIn()
+	d.c <<= 7
+	d.ct -= 7
+	d.a = 0x8000
+	return d
+}
+
+// byteAt returns the byte at position `i`. Reading past the end of the data yields 0xFF bytes.
+func (d *arithDecoder) byteAt(i int) byte {
+	if i >= len(d.data) {
+		return 0xff
+	}
+	return d.data[i]
+}
+
+// byteIn reads the next byte into the C register (BYTEIN, E.3.4).
+func (d *arithDecoder) byteIn() {
+	if d.byteAt(d.pos) == 0xff {
+		if d.byteAt(d.pos+1) > 0x8f {
+			// A marker or the end of the data: fill with 1 bits.
+			d.c += 0xff00
+			d.ct = 8
+			d.fill++
+		} else {
+			d.pos++
+			d.c += uint32(d.byteAt(d.pos)) << 9
+			d.ct = 7
+		}
+	} else {
+		d.pos++
+		d.c += uint32(d.byteAt(d.pos)) << 8
+		d.ct = 8
+	}
+}
+
+// err returns errDataExhausted if `d` has read more than maxFillBytes fill bytes.
+func (d *arithDecoder) err() error {
+	if d.fill > maxFillBytes {
+		return errDataExhausted
+	}
+	return nil
+}
+
+// decodeBit decodes a bit with the context `cx[i]` (DECODE, E.3.2).
+func (d *arithDecoder) decodeBit(cx []byte, i int) int {
+	state := &qeTable[cx[i]>>1]
+	mps := int(cx[i] & 1)
+	index := cx[i] >> 1
+
+	var bit int
+	d.a -= state.qe
+	if d.c>>16 < state.qe {
+		// LPS_EXCHANGE.
+		if d.a < state.qe {
+			bit = mps
+			index = state.nmps
+		} else {
+			bit = 1 - mps
+			if state.switchMPS {
+				mps = bit
+			}
+			index = state.nlps
+		}
+		d.a = state.qe
+	} else {
+		d.c -= state.qe << 16
+		if d.a&0x8000 != 0 {
+			return mps
+		}
+		// MPS_EXCHANGE.
+		if d.a < state.qe {
+			bit = 1 - mps
+			if state.switchMPS {
+				mps = bit
+			}
+			index = state.nlps
+		} else {
+			bit = mps
+			index = state.nmps
+		}
+	}
+
+	// RENORMD.
+	for {
+		if d.ct == 0 {
+			d.byteIn()
+		}
+		d.a <<= 1
+		d.c <<= 1
+		d.ct--
+		if d.a&0x8000 != 0 {
+			break
+		}
+	}
+	cx[i] = index<<1 | byte(mps)
+	return bit
+}
+
+// intDecoder is an arithmetic integer decoder, e.g. IADH or IADW (Annex A.2).
+type intDecoder struct {
+	cx []byte
+}
+
+func newIntDecoder() *intDecoder {
+	return &intDecoder{cx: make([]byte, 512)}
+}
+
+// decode decodes an integer. The returned flag is false if the decoded value is OOB.
+func (id *intDecoder) decode(d *arithDecoder) (int, bool) {
+	prev := 1
+	readBits := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			bit := d.decodeBit(id.cx, prev)
+			if prev < 256 {
+				prev = prev<<1 | bit
+			} else {
+				prev = (prev<<1|bit)&511 | 256
+			}
+			v = v<<1 | bit
+		}
+		return v
+	}
+
+	sign := readBits(1)
+	var v int
+	switch {
+	case readBits(1) == 0:
+		v = readBits(2)
+	case readBits(1) == 0:
+		v = readBits(4) + 4
+	case readBits(1) == 0:
+		v = readBits(6) + 20
+	case readBits(1) == 0:
+		v = readBits(8) + 84
+	case readBits(1) == 0:
+		v = readBits(12) + 340
+	default:
+		v = readBits(32) + 4436
+	}
+	if sign == 0 {
+		return v, true
+	}
+	if v == 0 {
+		return 0, false
+	}
+	return -v, true
+}
+
+// errTooManySymbols is returned when a symbol ID code length is too large to be decoded.
+var errTooManySymbols = errors.New("too many JBIG2 symbols")
+
+// idDecoder is the arithmetic decoder of symbol IDs, IAID (Annex A.3).
+type idDecoder struct {
+	cx      []byte
+	codeLen int
+}
+
+func newIDDecoder(codeLen int) (*idDecoder, error) {
+	if codeLen > 24 {
+		return nil, errTooManySymbols
+	}
+	return &idDecoder{cx: make([]byte, 1<<uint(codeLen+1)), codeLen: codeLen}, nil
+}
+
+// decode decodes a symbol ID.
+func (id *idDecoder) decode(d *arithDecoder) int {
+	prev := 1
+	for i := 0; i < id.codeLen; i++ {
+		prev = prev<<1 | d.decodeBit(id.cx, prev)
+	}
+	return prev - 1<<uint(id.codeLen)
+}
+
+// codeLength returns the number of bits that are needed to represent `n` different values.
+func codeLength(n int) int {
+	l := 0
+	for 1<<uint(l) < n {
+		l++
+	}
+	return l
+}
@@ -0,0 +1,141 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+// Bitmap is a bi-level image. Pixels are packed 8 per byte with the most significant bit first,
+// and each row starts on a byte boundary. As in JBIG2, a pixel value of 1 is black and 0 is white.
+type Bitmap struct {
+	Width  int
+	Height int
+	// Stride is the number of bytes per row.
+	Stride int
+	Data   []byte
+}
+
+// NewBitmap returns a white bitmap of `width` x `height` pixels.
+func NewBitmap(width, height int) *Bitmap {
+	stride := (width + 7) / 8
+	return &Bitmap{
+		Width:  width,
+		Height: height,
+		Stride: stride,
+		Data:   make([]byte, stride*height),
+	}
+}
+
+// GetPixel returns the value of the pixel at (`x`, `y`). Pixels outside of the bitmap are white.
+func (b *Bitmap) GetPixel(x, y int) int {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return 0
+	}
+	return int(b.Data[y*b.Stride+x>>3]>>uint(7-x&7)) & 1
+}
+
+// SetPixel sets the pixel at (`x`, `y`) to `v`. Pixels outside of the bitmap are ignored.
+func (b *Bitmap) SetPixel(x, y, v int) {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return
+	}
+	i := y*b.Stride + x>>3
+	mask := byte(0x80) >> uint(x&7)
+	if v != 0 {
+		b.Data[i] |= mask
+	} else {
+		b.Data[i] &^= mask
+	}
+}
+
+// fill sets all pixels of `b` to `v`.
+func (b *Bitmap) fill(v int) {
+	var fill byte
+	if v != 0 {
+		fill = 0xff
+	}
+	for i := range b.Data {
+		b.Data[i] = fill
+	}
+}
+
+// copyRow copies row `src` of `b` to row `dst`.
+func (b *Bitmap) copyRow(dst, src int) {
+	copy(b.Data[dst*b.Stride:(dst+1)*b.Stride], b.Data[src*b.Stride:(src+1)*b.Stride])
+}
+
+// subBitmap returns a copy of the `width` x `height` area of `b` at (`x`, `y`).
+func (b *Bitmap) subBitmap(x, y, width, height int) *Bitmap {
+	sub := NewBitmap(width, height)
+	for j := 0; j < height; j++ {
+		for i := 0; i < width; i++ {
+			if b.GetPixel(x+i, y+j) != 0 {
+				sub.SetPixel(i, j, 1)
+			}
+		}
+	}
+	return sub
+}
+
+// grow increases the height of `b` to `height` and sets the added pixels to `v`.
+func (b *Bitmap) grow(height, v int) {
+	if height <= b.Height {
+		return
+	}
+	var fill byte
+	if v != 0 {
+		fill = 0xff
+	}
+	n := len(b.Data)
+	b.Data = append(b.Data, make([]byte, (height-b.Height)*b.Stride)...)
+	for i := n; i < len(b.Data); i++ {
+		b.Data[i] = fill
+	}
+	b.Height = height
+}
+
+// combinationOperator is a method of combining a bitmap with another one (7.4.1.5, 7.4.8.5).
+type combinationOperator int
+
+const (
+	combOr combinationOperator = iota
+	combAnd
+	combXor
+	combXnor
+	combReplace
+)
+
+// combine combines `src` into `b` at (`x`, `y`) with operator `op`. The parts of `src` that lie
+// outside of `b` are ignored.
+func (b *Bitmap) combine(src *Bitmap, x, y int, op combinationOperator) {
+	for j := 0; j < src.Height; j++ {
+		dy := y + j
+		if dy < 0 || dy >= b.Height {
+			continue
+		}
+		for i := 0; i < src.Width; i++ {
+			dx := x + i
+			if dx < 0 || dx >= b.Width {
+				continue
+			}
+			s := src.GetPixel(i, j)
+			if op == combOr && s == 0 {
+				continue
+			}
+			d := b.GetPixel(dx, dy)
+			switch op {
+			case combOr:
+				d |= s
+			case combAnd:
+				d &= s
+			case combXor:
+				d ^= s
+			case combXnor:
+				d = 1 ^ d ^ s
+			case combReplace:
+				d = s
+			}
+			b.SetPixel(dx, dy, d)
+		}
+	}
+}
@@ -0,0 +1,693 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/common"
+)
+
+// Segment types (7.3).
+const (
+	segSymbolDictionary            = 0
+	segIntermediateTextRegion      = 4
+	segImmediateTextRegion         = 6
+	segImmediateLosslessTextRegion = 7
+	segPatternDictionary           = 16
+	segIntermediateHalftoneRegion  = 20
+	segImmediateHalftoneRegion     = 22
+	segImmediateLosslessHalftone   = 23
+	segIntermediateGenericRegion   = 36
+	segImmediateGenericRegion      = 38
+	segImmediateLosslessGeneric    = 39
+	segIntermediateRefinement      = 40
+	segImmediateRefinement         = 42
+	segImmediateLosslessRefinement = 43
+	segPageInformation             = 48
+	segEndOfPage                   = 49
+	segEndOfStripe                 = 50
+	segEndOfFile                   = 51
+	segProfiles                    = 52
+	segTables                      = 53
+	segExtension                   = 62
+)
+
+// unknownLength is the data length of an immediate generic region segment whose length is not
+// known in advance (7.2.7).
+const unknownLength = 0xffffffff
+
+var errNoPage = errors.New("no JBIG2 page information segment")
+
+// segmentHeader is a segment header (7.2).
+type segmentHeader struct {
+	number     uint32
+	kind       int
+	referred   []uint32
+	page       uint32
+	dataLength uint32
+}
+
+// readSegmentHeader reads a segment header from `r`.
+func readSegmentHeader(r *reader) (*segmentHeader, error) {
+	h := &segmentHeader{}
+	var err error
+	if h.number, err = r.readUint32(); err != nil {
+		return nil, err
+	}
+	flags, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	h.kind = int(flags & 0x3f)
+	largePage := flags&0x40 != 0
+
+	// Referred-to segment count and retention flags (7.2.4).
+	b, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	count := int(b >> 5)
+	if count == 7 {
+		r.pos--
+		v, err := r.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		count = int(v & 0x1fffffff)
+		if _, err := r.readBytes((count + 8) / 8); err != nil {
+			return nil, err
+		}
+	} else if count > 4 {
+		return nil, errors.New("invalid JBIG2 referred-to segment count")
+	}
+
+	// Referred-to segment numbers (7.2.5).
+	size := 4
+	if h.number <= 256 {
+		size = 1
+	} else if h.number <= 65536 {
+		size = 2
+	}
+	h.referred = make([]uint32, count)
+	for i := range h.referred {
+		data, err := r.readBytes(size)
+		if err != nil {
+			return nil, err
+		}
+		var n uint32
+		for _, b := range data {
+			n = n<<8 | uint32(b)
+		}
+		h.referred[i] = n
+	}
+
+	// Segment page association (7.2.6).
+	if largePage {
+		h.page, err = r.readUint32()
+	} else {
+		var b byte
+		b, err = r.readByte()
+		h.page = uint32(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h.dataLength, err = r.readUint32()
+	return h, err
+}
+
+// regionInfo is a region segment information field (7.4.1).
+type regionInfo struct {
+	width, height int
+	x, y          int
+	combOp        combinationOperator
+}
+
+// readRegionInfo reads a region segment information field.
+func readRegionInfo(r *reader) (*regionInfo, error) {
+	var v [4]uint32
+	for i := range v {
+		var err error
+		if v[i], err = r.readUint32(); err != nil {
+			return nil, err
+		}
+	}
+	flags, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if v[1] != unknownLength && !validSize(v[0], v[1]) {
+		return nil, errors.New("invalid JBIG2 region size")
+	}
+	ri := &regionInfo{
+		width:  int(v[0]),
+		height: int(v[1]),
+		x:      int(int32(v[2])),
+		y:      int(int32(v[3])),
+		combOp: combinationOperator(flags & 7),
+	}
+	if ri.combOp > combReplace {
+		return nil, errors.New("invalid JBIG2 combination operator")
+	}
+	return ri, nil
+}
+
+// maxBitmapPixels is the largest number of pixels of a page or region bitmap.
+const maxBitmapPixels = 1 << 30
+
+// validSize returns true if a `width` x `height` bitmap is not larger than maxBitmapPixels.
+func validSize(width, height uint32) bool {
+	return uint64(width)*uint64(height) <= maxBitmapPixels
+}
+
+// region is a decoded intermediate region.
+type region struct {
+	info   *regionInfo
+	bitmap *Bitmap
+}
+
+// page is the page that is being decoded.
+type page struct {
+	bitmap        *Bitmap
+	defPixel      int
+	combOp        combinationOperator
+	overrideOp    bool
+	unknownHeight bool
+}
+
+// decoder decodes the segments of a JBIG2 embedded stream.
+type decoder struct {
+	// results are the decoded segments by segment number.
+	results map[uint32]interface{}
+	page    *page
+	done    bool
+}
+
+// Decode decodes the JBIG2 data `data` of a PDF image stream with the JBIG2Decode filter and
+// returns the image of its page. `globals` holds the contents of the JBIG2Globals stream and
+// may be nil.
+func Decode(data, globals []byte) (*Bitmap, error) {
+	dec := &decoder{results: make(map[uint32]interface{})}
+	if len(globals) > 0 {
+		if err := dec.decodeSegments(globals); err != nil {
+			return nil, fmt.Errorf("JBIG2Globals: %v", err)
+		}
+	}
+	if err := dec.decodeSegments(data); err != nil {
+		return nil, err
+	}
+	if dec.page == nil {
+		return nil, errNoPage
+	}
+	return dec.page.bitmap, nil
+}
+
+// decodeSegments decodes the segments in `data`, which are stored one after the other, each
+// header followed by its data (7.1).
+func (dec *decoder) decodeSegments(data []byte) error {
+	r := newReader(data)
+	for r.pos < len(data) && !dec.done {
+		h, err := readSegmentHeader(r)
+		if err != nil {
+			return err
+		}
+		length := int(h.dataLength)
+		if h.dataLength == unknownLength {
+			length, err = unknownDataLength(h, r.rest())
+			if err != nil {
+				return err
+			}
+		}
+		segData, err := r.readBytes(length)
+		if err != nil {
+			return err
+		}
+		if err := dec.decodeSegment(h, segData); err != nil {
+			return fmt.Errorf("segment %d (type %d): %v", h.number, h.kind, err)
+		}
+	}
+	return nil
+}
+
+// unknownDataLength returns the length of the data of an immediate generic region segment whose
+// data length is unknown. The data ends with a marker followed by the 4 byte row count (7.2.7).
+func unknownDataLength(h *segmentHeader, data []byte) (int, error) {
+	if h.kind != segImmediateGenericRegion && h.kind != segImmediateLosslessGeneric {
+		return 0, errors.New("unknown JBIG2 segment data length")
+	}
+	// The generic region segment data header is 18 bytes long.
+	if len(data) < 18 {
+		return 0, errEOD
+	}
+	marker := []byte{0xff, 0xac}
+	if data[17]&1 != 0 {
+		marker = []byte{0x00, 0x00}
+	}
+	for i := 18; i+len(marker)+4 <= len(data); i++ {
+		if bytes.Equal(data[i:i+len(marker)], marker) {
+			return i + len(marker) + 4, nil
+		}
+	}
+	return 0, errEOD
+}
+
+// decodeSegment decodes segment `h` with data `data`.
+func (dec *decoder) decodeSegment(h *segmentHeader, data []byte) error {
+	switch h.kind {
+	case segSymbolDictionary:
+		sd, err := dec.decodeSymbolDictionary(h, data)
+		if err != nil {
+			return err
+		}
+		dec.results[h.number] = sd
+	case segIntermediateTextRegion, segImmediateTextRegion, segImmediateLosslessTextRegion:
+		return dec.decodeRegionSegment(h, data, dec.decodeTextRegionSegment)
+	case segPatternDictionary:
+		pd, err := decodePatternDictionary(data)
+		if err != nil {
+			return err
+		}
+		dec.results[h.number] = pd
+	case segIntermediateHalftoneRegion, segImmediateHalftoneRegion, segImmediateLosslessHalftone:
+		return dec.decodeRegionSegment(h, data, dec.decodeHalftoneRegionSegment)
+	case segIntermediateGenericRegion, segImmediateGenericRegion, segImmediateLosslessGeneric:
+		return dec.decodeRegionSegment(h, data, dec.decodeGenericRegionSegment)
+	case segIntermediateRefinement, segImmediateRefinement, segImmediateLosslessRefinement:
+		return dec.decodeRegionSegment(h, data, dec.decodeRefinementRegionSegment)
+	case segPageInformation:
+		if dec.page != nil {
+			// Only the first page is decoded.
+			dec.done = true
+			return nil
+		}
+		return dec.decodePageInformation(data)
+	case segEndOfPage, segEndOfFile:
+		dec.done = true
+	case segEndOfStripe:
+		if dec.page == nil {
+			return errNoPage
+		}
+		r := newReader(data)
+		y, err := r.readUint32()
+		if err != nil {
+			return err
+		}
+		if dec.page.unknownHeight {
+			dec.page.bitmap.grow(int(y)+1, dec.page.defPixel)
+		}
+	case segTables:
+		t, err := readHuffmanTable(data)
+		if err != nil {
+			return err
+		}
+		dec.results[h.number] = t
+	case segProfiles, segExtension:
+	default:
+		common.Log.Debug("Unsupported JBIG2 segment type %d", h.kind)
+	}
+	return nil
+}
+
+// decodePageInformation decodes a page information segment (7.4.8).
+func (dec *decoder) decodePageInformation(data []byte) error {
+	r := newReader(data)
+	width, err := r.readUint32()
+	if err != nil {
+		return err
+	}
+	height, err := r.readUint32()
+	if err != nil {
+		return err
+	}
+	// Skip the resolution.
+	if _, err := r.readBytes(8); err != nil {
+		return err
+	}
+	flags, err := r.readByte()
+	if err != nil {
+		return err
+	}
+	if height != unknownLength && !validSize(width, height) {
+		return errors.New("invalid JBIG2 page size")
+	}
+
+	p := &page{
+		defPixel:      int(flags >> 2 & 1),
+		combOp:        combinationOperator(flags >> 3 & 3),
+		overrideOp:    flags&0x40 != 0,
+		unknownHeight: height == unknownLength,
+	}
+	if p.unknownHeight {
+		height = 0
+	}
+	p.bitmap = NewBitmap(int(width), int(height))
+	if p.defPixel != 0 {
+		p.bitmap.fill(1)
+	}
+	dec.page = p
+	return nil
+}
+
+// decodeRegionSegment decodes a region segment with `decode` and either stores the region for
+// later use by other segments (intermediate regions) or draws it onto the page (immediate
+// regions).
+func (dec *decoder) decodeRegionSegment(h *segmentHeader, data []byte,
+	decode func(h *segmentHeader, info *regionInfo, r *reader) (*Bitmap, error)) error {
+	r := newReader(data)
+	info, err := readRegionInfo(r)
+	if err != nil {
+		return err
+	}
+	if info.height == unknownLength && h.dataLength != unknownLength {
+		return errors.New("unknown JBIG2 region height")
+	}
+	if info.height != unknownLength {
+		if err := checkCodedSize(info.width*info.height, len(data), maxPixelsPerByte); err != nil {
+			return err
+		}
+	}
+
+	intermediate := false
+	switch h.kind {
+	case segIntermediateTextRegion, segIntermediateHalftoneRegion, segIntermediateGenericRegion,
+		segIntermediateRefinement:
+		intermediate = true
+	}
+	// Immediate regions are drawn on the page and are not larger than it.
+	p := dec.page
+	if !intermediate {
+		if p == nil {
+			return errNoPage
+		}
+		if info.width > p.bitmap.Width ||
+			!p.unknownHeight && info.height != unknownLength && info.height > p.bitmap.Height {
+			return errors.New("JBIG2 region larger than its page")
+		}
+	}
+
+	bm, err := decode(h, info, r)
+	if err != nil {
+		return err
+	}
+	if intermediate {
+		dec.results[h.number] = &region{info: info, bitmap: bm}
+		return nil
+	}
+
+	if p.unknownHeight {
+		p.bitmap.grow(info.y+bm.Height, p.defPixel)
+	}
+	op := p.combOp
+	if p.overrideOp {
+		op = info.combOp
+	}
+	p.bitmap.combine(bm, info.x, info.y, op)
+	return nil
+}
+
+// decodeGenericRegionSegment decodes the data of a generic region segment (7.4.6).
+func (dec *decoder) decodeGenericRegionSegment(h *segmentHeader, info *regionInfo, r *reader) (*Bitmap, error) {
+	flags, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	p := &genericParams{
+		mmr:      flags&1 != 0,
+		template: int(flags >> 1 & 3),
+		tpgdon:   flags&8 != 0,
+	}
+	if flags&0x10 != 0 {
+		return nil, errors.New("unsupported JBIG2 extended template")
+	}
+	if !p.mmr {
+		n := 1
+		if p.template == 0 {
+			n = 4
+		}
+		if err := readATPixels(r, p.at[:n]); err != nil {
+			return nil, err
+		}
+	}
+
+	data := r.rest()
+	height := info.height
+	if h.dataLength == unknownLength {
+		// The region height is given by the row count at the end of the data.
+		if len(data) < 4 {
+			return nil, errEOD
+		}
+		n := len(data) - 4
+		height = int(data[n])<<24 | int(data[n+1])<<16 | int(data[n+2])<<8 | int(data[n+3])
+		data = data[:n]
+		if height > info.height || !validSize(uint32(info.width), uint32(height)) {
+			return nil, errors.New("invalid JBIG2 region row count")
+		}
+	}
+
+	if p.mmr {
+		return decodeMMR(newReader(data), info.width, height)
+	}
+	return decodeGeneric(newArithDecoder(data), newGenericContexts(), info.width, height, p)
+}
+
+// decodeRefinementRegionSegment decodes the data of a generic refinement region segment (7.4.7).
+func (dec *decoder) decodeRefinementRegionSegment(h *segmentHeader, info *regionInfo, r *reader) (*Bitmap, error) {
+	flags, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	p := &refinementParams{
+		template: int(flags & 1),
+		tpgron:   flags&2 != 0,
+	}
+	if p.template == 0 {
+		if err := readATPixels(r, p.at[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	// The reference is the referred intermediate region or else the area of the page that is
+	// being refined (7.4.7.5).
+	for _, n := range h.referred {
+		if reg, ok := dec.results[n].(*region); ok {
+			p.reference = reg.bitmap
+			break
+		}
+	}
+	if p.reference == nil {
+		if dec.page == nil {
+			return nil, errNoPage
+		}
+		p.reference = dec.page.bitmap.subBitmap(info.x, info.y, info.width, info.height)
+	}
+	return decodeRefinement(newArithDecoder(r.rest()), newRefinementContexts(), info.width, info.height, p)
+}
+
+// decodeTextRegionSegment decodes the data of a text region segment (7.4.4).
+func (dec *decoder) decodeTextRegionSegment(h *segmentHeader, info *regionInfo, r *reader) (*Bitmap, error) {
+	flags, err := r.readUint16()
+	if err != nil {
+		return nil, err
+	}
+	p := &textParams{
+		huffman:    flags&1 != 0,
+		refine:     flags>>1&1 != 0,
+		logStrips:  int(flags >> 2 & 3),
+		refCorner:  int(flags >> 4 & 3),
+		transposed: flags>>6&1 != 0,
+		combOp:     combinationOperator(flags >> 7 & 3),
+		defPixel:   int(flags >> 9 & 1),
+		dsOffset:   int(flags >> 10 & 0x1f),
+		rtemplate:  int(flags >> 15 & 1),
+		width:      info.width,
+		height:     info.height,
+	}
+	if p.dsOffset > 0xf {
+		p.dsOffset -= 0x20
+	}
+
+	var huffFlags uint16
+	if p.huffman {
+		if huffFlags, err = r.readUint16(); err != nil {
+			return nil, err
+		}
+	}
+	if p.refine && p.rtemplate == 0 {
+		if err := readATPixels(r, p.rat[:]); err != nil {
+			return nil, err
+		}
+	}
+	numInstances, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCodedSize(int(numInstances), len(r.rest()), maxSymbolsPerByte); err != nil {
+		return nil, err
+	}
+	p.numInstances = int(numInstances)
+
+	for _, n := range h.referred {
+		if sd, ok := dec.results[n].(*symbolDictionary); ok {
+			p.symbols = append(p.symbols, sd.exported...)
+		}
+	}
+	p.codeLen = codeLength(len(p.symbols))
+
+	if !p.huffman {
+		td, err := newTextDecoders(p.codeLen)
+		if err != nil {
+			return nil, err
+		}
+		return decodeTextRegion(p, nil, newArithDecoder(r.rest()), td)
+	}
+
+	tables := dec.referredTables(h)
+	selections := []struct {
+		table    **huffmanTable
+		shift    uint
+		standard []int
+	}{
+		{&p.fs, 0, []int{6, 7, -1, 0}},
+		{&p.ds, 2, []int{8, 9, 10, 0}},
+		{&p.dt, 4, []int{11, 12, 13, 0}},
+		{&p.rdw, 6, []int{14, 15, -1, 0}},
+		{&p.rdh, 8, []int{14, 15, -1, 0}},
+		{&p.rdx, 10, []int{14, 15, -1, 0}},
+		{&p.rdy, 12, []int{14, 15, -1, 0}},
+		{&p.rsize, 14, []int{1, 0}},
+	}
+	for _, s := range selections {
+		selector := int(huffFlags>>s.shift) & (len(s.standard) - 1)
+		if !p.refine && s.shift >= 6 && s.standard[selector] == 0 {
+			// The refinement tables are not used.
+			continue
+		}
+		if *s.table, err = selectTable(selector, s.standard, tables); err != nil {
+			return nil, err
+		}
+	}
+	if p.symbolIDs, err = readSymbolIDTable(r, len(p.symbols)); err != nil {
+		return nil, err
+	}
+	return decodeTextRegion(p, r, nil, nil)
+}
+
+// readSymbolIDTable reads the Huffman table of the symbol ID codes of a text region (7.4.4.1.7).
+func readSymbolIDTable(r *reader, numSymbols int) (*huffmanTable, error) {
+	// The code lengths of the symbol IDs are run length coded with a table whose code lengths
+	// are coded with 4 bits each.
+	runLines := make([]huffmanLine, 35)
+	for i := range runLines {
+		v, err := r.readBits(4)
+		if err != nil {
+			return nil, err
+		}
+		runLines[i] = huffmanLine{rangeLow: i, prefLen: int(v)}
+	}
+	runTable, err := newHuffmanTable(runLines)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]huffmanLine, 0, numSymbols)
+	for len(lines) < numSymbols {
+		code, err := runTable.decodeValue(r)
+		if err != nil {
+			return nil, err
+		}
+		var prefLen, repeat int
+		switch {
+		case code < 32:
+			prefLen, repeat = code, 1
+		case code == 32:
+			if len(lines) == 0 {
+				return nil, errInvalidHuffmanCode
+			}
+			v, err := r.readBits(2)
+			if err != nil {
+				return nil, err
+			}
+			prefLen, repeat = lines[len(lines)-1].prefLen, int(v)+3
+		case code == 33:
+			v, err := r.readBits(3)
+			if err != nil {
+				return nil, err
+			}
+			repeat = int(v) + 3
+		default:
+			v, err := r.readBits(7)
+			if err != nil {
+				return nil, err
+			}
+			repeat = int(v) + 11
+		}
+		for i := 0; i < repeat && len(lines) < numSymbols; i++ {
+			lines = append(lines, huffmanLine{rangeLow: len(lines), prefLen: prefLen})
+		}
+	}
+	r.align()
+	return newHuffmanTable(lines)
+}
+
+// referredTables returns the code tables referred to by segment `h`.
+func (dec *decoder) referredTables(h *segmentHeader) *[]*huffmanTable {
+	var tables []*huffmanTable
+	for _, n := range h.referred {
+		if t, ok := dec.results[n].(*huffmanTable); ok {
+			tables = append(tables, t)
+		}
+	}
+	return &tables
+}
+
+// decodeHalftoneRegionSegment decodes the data of a halftone region segment (7.4.5).
+func (dec *decoder) decodeHalftoneRegionSegment(h *segmentHeader, info *regionInfo, r *reader) (*Bitmap, error) {
+	flags, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	p := &halftoneParams{
+		width:      info.width,
+		height:     info.height,
+		mmr:        flags&1 != 0,
+		template:   int(flags >> 1 & 3),
+		enableSkip: flags>>3&1 != 0,
+		combOp:     combinationOperator(flags >> 4 & 7),
+		defPixel:   int(flags >> 7 & 1),
+	}
+	if p.combOp > combReplace {
+		return nil, errInvalidHalftone
+	}
+	var v [4]uint32
+	for i := range v {
+		if v[i], err = r.readUint32(); err != nil {
+			return nil, err
+		}
+	}
+	p.gridWidth, p.gridHeight = int(v[0]), int(v[1])
+	p.gridX, p.gridY = int(int32(v[2])), int(int32(v[3]))
+	vx, err := r.readUint16()
+	if err != nil {
+		return nil, err
+	}
+	vy, err := r.readUint16()
+	if err != nil {
+		return nil, err
+	}
+	p.vectorX, p.vectorY = int(vx), int(vy)
+
+	for _, n := range h.referred {
+		if pd, ok := dec.results[n].(*patternDictionary); ok {
+			p.patterns = pd.patterns
+			break
+		}
+	}
+	return decodeHalftoneRegion(p, r.rest())
+}
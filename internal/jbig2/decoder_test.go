@@ -0,0 +1,536 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/internal/ccittfax"
+)
+
+// arithEncoder is the MQ arithmetic encoder (E.2), used to make test data.
+type arithEncoder struct {
+	a, c uint32
+	ct   int
+	// out holds the coded bytes. The first byte precedes the coded data and is dropped.
+	out []byte
+}
+
+func newArithEncoder() *arithEncoder {
+	return &arithEncoder{a: 0x8000, ct: 12, out: []byte{0}}
+}
+
+func (e *arithEncoder) encodeBit(cx []byte, i, bit int) {
+	state := &qeTable[cx[i]>>1]
+	mps := int(cx[i] & 1)
+	index := cx[i] >> 1
+	e.a -= state.qe
+	if bit == mps {
+		if e.a&0x8000 != 0 {
+			e.c += state.qe
+			return
+		}
+		if e.a < state.qe {
+			e.a = state.qe
+		} else {
+			e.c += state.qe
+		}
+		index = state.nmps
+	} else {
+		if e.a < state.qe {
+			e.c += state.qe
+		} else {
+			e.a = state.qe
+		}
+		if state.switchMPS {
+			mps = 1 - mps
+		}
+		index = state.nlps
+	}
+	cx[i] = index<<1 | byte(mps)
+	for {
+		e.a <<= 1
+		e.c <<= 1
+		e.ct--
+		if e.ct == 0 {
+			e.byteOut()
+		}
+		if e.a&0x8000 != 0 {
+			break
+		}
+	}
+}
+
+func (e *arithEncoder) byteOut() {
+	last := &e.out[len(e.out)-1]
+	if *last != 0xff && e.c >= 0x8000000 {
+		*last++
+		e.c &= 0x7ffffff
+	}
+	if *last == 0xff {
+		e.out = append(e.out, byte(e.c>>20))
+		e.c &= 0xfffff
+		e.ct = 7
+	} else {
+		e.out = append(e.out, byte(e.c>>19))
+		e.c &= 0x7ffff
+		e.ct = 8
+	}
+}
+
+func (e *arithEncoder) flush() []byte {
+	temp := e.c + e.a
+	e.c |= 0xffff
+	if e.c >= temp {
+		e.c -= 0x8000
+	}
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	if e.out[len(e.out)-1] != 0xff {
+		e.out = append(e.out, 0xff)
+	}
+	return append(e.out[1:], 0xac)
+}
+
+// encodeInt encodes `v` with the arithmetic integer encoder contexts `cx`, or OOB if `oob` is set.
+func (e *arithEncoder) encodeInt(cx []byte, v int, oob bool) {
+	prev := 1
+	bits := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bit := v >> uint(i) & 1
+			e.encodeBit(cx, prev, bit)
+			if prev < 256 {
+				prev = prev<<1 | bit
+			} else {
+				prev = (prev<<1|bit)&511 | 256
+			}
+		}
+	}
+	if oob {
+		bits(8, 4)
+		return
+	}
+	sign := 0
+	if v < 0 {
+		sign, v = 1, -v
+	}
+	bits(sign, 1)
+	switch {
+	case v < 4:
+		bits(0, 1)
+		bits(v, 2)
+	case v < 20:
+		bits(2, 2)
+		bits(v-4, 4)
+	case v < 84:
+		bits(6, 3)
+		bits(v-20, 6)
+	case v < 340:
+		bits(14, 4)
+		bits(v-84, 8)
+	case v < 4436:
+		bits(30, 5)
+		bits(v-340, 12)
+	default:
+		bits(31, 5)
+		bits(v-4436, 32)
+	}
+}
+
+// encodeID encodes the symbol ID `id` with the IAID contexts `cx`.
+func (e *arithEncoder) encodeID(cx []byte, id, codeLen int) {
+	prev := 1
+	for i := codeLen - 1; i >= 0; i-- {
+		bit := id >> uint(i) & 1
+		e.encodeBit(cx, prev, bit)
+		prev = prev<<1 | bit
+	}
+}
+
+// encodeGeneric encodes `bm` as a generic region with the contexts `cx`.
+func (e *arithEncoder) encodeGeneric(cx []byte, bm *Bitmap, template int, tpgdon bool, at [4]atPixel) {
+	ltp := 0
+	for y := 0; y < bm.Height; y++ {
+		if tpgdon {
+			same := y > 0 && bytes.Equal(bm.Data[y*bm.Stride:(y+1)*bm.Stride], bm.Data[(y-1)*bm.Stride:y*bm.Stride])
+			if !same && y == 0 {
+				same = bytes.Equal(bm.Data[:bm.Stride], make([]byte, bm.Stride))
+			}
+			sltp := 0
+			if same {
+				sltp = 1
+			}
+			e.encodeBit(cx, sltpContexts[template], sltp^ltp)
+			ltp = sltp
+			if same {
+				continue
+			}
+		}
+		for x := 0; x < bm.Width; x++ {
+			e.encodeBit(cx, genericContext(bm, x, y, template, &at), bm.GetPixel(x, y))
+		}
+	}
+}
+
+// testBitmap returns a bitmap with a pattern of rectangles and diagonal lines.
+func testBitmap(width, height, seed int) *Bitmap {
+	bm := NewBitmap(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if (x+y+seed)%7 == 0 || (x/5+y/3+seed)%4 == 0 || y == 10 {
+				bm.SetPixel(x, y, 1)
+			}
+		}
+	}
+	return bm
+}
+
+// makeSegment returns a segment with page association 1.
+func makeSegment(number uint32, kind int, referred []uint32, data []byte) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, number)
+	b.WriteByte(byte(kind))
+	b.WriteByte(byte(len(referred) << 5))
+	for _, n := range referred {
+		b.WriteByte(byte(n))
+	}
+	b.WriteByte(1)
+	binary.Write(&b, binary.BigEndian, uint32(len(data)))
+	b.Write(data)
+	return b.Bytes()
+}
+
+func makePageInfo(width, height int) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, []uint32{uint32(width), uint32(height), 0, 0})
+	b.WriteByte(0)
+	b.Write([]byte{0, 0})
+	return makeSegment(0, segPageInformation, nil, b.Bytes())
+}
+
+func makeRegionInfo(width, height, x, y int) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, []uint32{uint32(width), uint32(height), uint32(x), uint32(y)})
+	b.WriteByte(0)
+	return b.Bytes()
+}
+
+// TestArithDecoder decodes the test sequence of the arithmetic coder (H.2).
+func TestArithDecoder(t *testing.T) {
+	want := []byte{
+		0x00, 0x02, 0x00, 0x51, 0x00, 0x00, 0x00, 0xC0, 0x03, 0x52, 0x87, 0x2A, 0xAA, 0xAA, 0xAA, 0xAA,
+		0x82, 0xC0, 0x20, 0x00, 0xFC, 0xD7, 0x9E, 0xF6, 0xBF, 0x7F, 0xED, 0x90, 0x4F, 0x46, 0xA3, 0xBF,
+	}
+	coded := []byte{
+		0x84, 0xC7, 0x3B, 0xFC, 0xE1, 0xA1, 0x43, 0x04, 0x02, 0x20, 0x00, 0x00, 0x41, 0x0D, 0xBB, 0x86,
+		0xF4, 0x31, 0x7F, 0xFF, 0x88, 0xFF, 0x37, 0x47, 0x1A, 0xDB, 0x6A, 0xDF, 0xFF, 0xAC,
+	}
+
+	d := newArithDecoder(coded)
+	cx := make([]byte, 1)
+	got := make([]byte, len(want))
+	for i := range got {
+		for j := 0; j < 8; j++ {
+			got[i] = got[i]<<1 | byte(d.decodeBit(cx, 0))
+		}
+	}
+	require.Equal(t, want, got)
+
+	e := newArithEncoder()
+	cx = make([]byte, 1)
+	for _, b := range want {
+		for j := 7; j >= 0; j-- {
+			e.encodeBit(cx, 0, int(b>>uint(j)&1))
+		}
+	}
+	require.Equal(t, coded, e.flush())
+}
+
+func TestDecodeGenericRegion(t *testing.T) {
+	const width, height = 67, 45
+	want := testBitmap(width, height, 1)
+	for template := 0; template < 4; template++ {
+		for _, tpgdon := range []bool{false, true} {
+			at := [4]atPixel{{3, -1}, {-3, -1}, {2, -2}, {-2, -2}}
+			if template > 0 {
+				at[0] = atPixel{2, -1}
+			}
+			e := newArithEncoder()
+			e.encodeGeneric(newGenericContexts(), want, template, tpgdon, at)
+
+			var data bytes.Buffer
+			data.Write(makeRegionInfo(width, height, 0, 0))
+			flags := byte(template << 1)
+			if tpgdon {
+				flags |= 8
+			}
+			data.WriteByte(flags)
+			n := 1
+			if template == 0 {
+				n = 4
+			}
+			for _, p := range at[:n] {
+				data.Write([]byte{byte(p.x), byte(p.y)})
+			}
+			data.Write(e.flush())
+
+			stream := append(makePageInfo(width, height),
+				makeSegment(1, segImmediateLosslessGeneric, nil, data.Bytes())...)
+			stream = append(stream, makeSegment(2, segEndOfPage, nil, nil)...)
+			bm, err := Decode(stream, nil)
+			require.NoError(t, err)
+			require.Equal(t, want, bm, "template %d tpgdon %t", template, tpgdon)
+		}
+	}
+}
+
+func TestDecodeMMRGenericRegion(t *testing.T) {
+	const width, height = 150, 40
+	want := testBitmap(width, height, 2)
+	pixels := make([][]byte, height)
+	for y := range pixels {
+		pixels[y] = make([]byte, width)
+		for x := range pixels[y] {
+			pixels[y][x] = byte(want.GetPixel(x, y))
+		}
+	}
+	enc := &ccittfax.Encoder{K: -1, Columns: width, BlackIs1: true}
+	coded := enc.Encode(pixels)
+
+	var data bytes.Buffer
+	data.Write(makeRegionInfo(width, height, 0, 0))
+	data.WriteByte(1)
+	data.Write(coded)
+	stream := append(makePageInfo(width, height),
+		makeSegment(1, segImmediateLosslessGeneric, nil, data.Bytes())...)
+
+	bm, err := Decode(stream, nil)
+	require.NoError(t, err)
+	require.Equal(t, want, bm)
+}
+
+// TestDecodeTextRegion decodes a text region whose symbols are defined in a symbol dictionary in
+// the globals.
+func TestDecodeTextRegion(t *testing.T) {
+	symbols := []*Bitmap{testBitmap(5, 8, 0), testBitmap(7, 8, 3), testBitmap(6, 11, 1)}
+	at := [4]atPixel{{3, -1}, {-3, -1}, {2, -2}, {-2, -2}}
+
+	// The symbol dictionary has two height classes: the 8 pixel high symbols 0 and 1, and the 11
+	// pixel high symbol 2. All symbols are exported.
+	e := newArithEncoder()
+	iadh, iadw, iaex, gb := make([]byte, 512), make([]byte, 512), make([]byte, 512), newGenericContexts()
+	e.encodeInt(iadh, 8, false)
+	e.encodeInt(iadw, 5, false)
+	e.encodeGeneric(gb, symbols[0], 0, false, at)
+	e.encodeInt(iadw, 2, false)
+	e.encodeGeneric(gb, symbols[1], 0, false, at)
+	e.encodeInt(iadw, 0, true)
+	e.encodeInt(iadh, 3, false)
+	e.encodeInt(iadw, 6, false)
+	e.encodeGeneric(gb, symbols[2], 0, false, at)
+	e.encodeInt(iadw, 0, true)
+	e.encodeInt(iaex, 0, false)
+	e.encodeInt(iaex, 3, false)
+
+	var dict bytes.Buffer
+	dict.Write([]byte{0, 0})
+	for _, p := range at {
+		dict.Write([]byte{byte(p.x), byte(p.y)})
+	}
+	binary.Write(&dict, binary.BigEndian, []uint32{3, 3})
+	dict.Write(e.flush())
+	globals := makeSegment(0, segSymbolDictionary, nil, dict.Bytes())
+
+	// Two strips: symbols 0 and 1 at T = 5 and symbol 2 at T = 20, with top left reference corners.
+	const width, height = 40, 35
+	e = newArithEncoder()
+	iadt, iafs, iads, iaid := make([]byte, 512), make([]byte, 512), make([]byte, 512), make([]byte, 8)
+	e.encodeInt(iadt, 0, false)
+	e.encodeInt(iadt, 5, false)
+	e.encodeInt(iafs, 2, false)
+	e.encodeID(iaid, 0, 2)
+	e.encodeInt(iads, 4, false)
+	e.encodeID(iaid, 1, 2)
+	e.encodeInt(iads, 0, true)
+	e.encodeInt(iadt, 15, false)
+	e.encodeInt(iafs, 8, false)
+	e.encodeID(iaid, 2, 2)
+	e.encodeInt(iads, 0, true)
+
+	var text bytes.Buffer
+	text.Write(makeRegionInfo(width, height, 0, 0))
+	binary.Write(&text, binary.BigEndian, uint16(cornerTopLeft<<4))
+	binary.Write(&text, binary.BigEndian, uint32(3))
+	text.Write(e.flush())
+	stream := append(makePageInfo(width, height), makeSegment(1, segImmediateTextRegion, []uint32{0}, text.Bytes())...)
+
+	want := NewBitmap(width, height)
+	want.combine(symbols[0], 2, 5, combOr)
+	want.combine(symbols[1], 2+5-1+4, 5, combOr)
+	want.combine(symbols[2], 10, 20, combOr)
+
+	bm, err := Decode(stream, globals)
+	require.NoError(t, err)
+	require.Equal(t, want, bm)
+}
+
+func TestReadHuffmanTable(t *testing.T) {
+	// Table B.1 as a code table segment: HTPS = 2, HTRS = 5, HTLOW = 0 and HTHIGH = 65808.
+	var data bytes.Buffer
+	data.WriteByte(1<<1 | 4<<4)
+	binary.Write(&data, binary.BigEndian, []int32{0, 65808})
+	// The lines (PREFLEN, RANGELEN) (1, 4), (2, 8), (3, 16), the lower range line with PREFLEN 0
+	// and the upper range line with PREFLEN 3.
+	data.Write([]byte{0x49, 0x23, 0x81, 0x80})
+	table, err := readHuffmanTable(data.Bytes())
+	require.NoError(t, err)
+
+	for _, v := range []int{0, 15, 16, 271, 272, 65807, 65808, 70000} {
+		// Encode v with table B.1.
+		var bits []int
+		add := func(v uint32, n int) {
+			for i := n - 1; i >= 0; i-- {
+				bits = append(bits, int(v>>uint(i)&1))
+			}
+		}
+		switch {
+		case v < 16:
+			add(0, 1)
+			add(uint32(v), 4)
+		case v < 272:
+			add(2, 2)
+			add(uint32(v-16), 8)
+		case v < 65808:
+			add(6, 3)
+			add(uint32(v-272), 16)
+		default:
+			add(7, 3)
+			add(uint32(v-65808), 32)
+		}
+		coded := make([]byte, (len(bits)+7)/8)
+		for i, b := range bits {
+			coded[i/8] |= byte(b << uint(7-i%8))
+		}
+
+		for _, tb := range []*huffmanTable{table, standardTables[1]} {
+			got, err := tb.decodeValue(newReader(coded))
+			require.NoError(t, err)
+			require.Equal(t, v, got)
+		}
+	}
+}
+
+// TestDecodeHalftoneRegion decodes a halftone region of 3 x 2 patterns from a pattern dictionary
+// with 4 patterns.
+func TestDecodeHalftoneRegion(t *testing.T) {
+	const pw, ph = 4, 4
+	patterns := NewBitmap(4*pw, ph)
+	for i := 0; i < 4; i++ {
+		// Pattern i has i black pixels on its diagonal.
+		for j := 0; j < i; j++ {
+			patterns.SetPixel(i*pw+j, j, 1)
+		}
+	}
+	e := newArithEncoder()
+	e.encodeGeneric(newGenericContexts(), patterns, 0, false, [4]atPixel{{-pw, 0}, {-3, -1}, {2, -2}, {-2, -2}})
+	var dict bytes.Buffer
+	dict.Write([]byte{0, pw, ph})
+	binary.Write(&dict, binary.BigEndian, uint32(3))
+	dict.Write(e.flush())
+
+	// The gray-scale values are coded as two Gray coded bit planes, the most significant first.
+	gray := [2][3]int{{0, 1, 2}, {3, 2, 1}}
+	planes := [2]*Bitmap{NewBitmap(3, 2), NewBitmap(3, 2)}
+	for mg := range gray {
+		for ng, v := range gray[mg] {
+			planes[1].SetPixel(ng, mg, v>>1)
+			planes[0].SetPixel(ng, mg, v>>1^v&1)
+		}
+	}
+	e = newArithEncoder()
+	cx := newGenericContexts()
+	at := [4]atPixel{{3, -1}, {-3, -1}, {2, -2}, {-2, -2}}
+	e.encodeGeneric(cx, planes[1], 0, false, at)
+	e.encodeGeneric(cx, planes[0], 0, false, at)
+
+	const width, height = 3 * pw, 2 * ph
+	var halftone bytes.Buffer
+	halftone.Write(makeRegionInfo(width, height, 0, 0))
+	halftone.WriteByte(0)
+	binary.Write(&halftone, binary.BigEndian, []uint32{3, 2, 0, 0})
+	binary.Write(&halftone, binary.BigEndian, []uint16{pw << 8, 0})
+	halftone.Write(e.flush())
+
+	stream := append(makePageInfo(width, height), makeSegment(1, segPatternDictionary, nil, dict.Bytes())...)
+	stream = append(stream, makeSegment(2, segImmediateHalftoneRegion, []uint32{1}, halftone.Bytes())...)
+	bm, err := Decode(stream, nil)
+	require.NoError(t, err)
+
+	want := NewBitmap(width, height)
+	for mg := range gray {
+		for ng, v := range gray[mg] {
+			want.combine(patterns.subBitmap(v*pw, 0, pw, ph), ng*pw, mg*ph, combOr)
+		}
+	}
+	require.Equal(t, want, bm)
+}
+
+// TestDecodeLargeRegion checks that regions too large for their data or their page fail quickly
+// instead of being decoded from fill bytes.
+func TestDecodeLargeRegion(t *testing.T) {
+	var data bytes.Buffer
+	data.Write(makeRegionInfo(7800000, 45, 0, 0))
+	data.WriteByte(0)
+	data.Write([]byte{3, 0xff, 0xfd, 0xff, 2, 0xfe, 0xfe, 0xfe})
+	data.Write([]byte{0x12, 0x34})
+
+	stream := append(makePageInfo(100, 45), makeSegment(1, segImmediateLosslessGeneric, nil, data.Bytes())...)
+	_, err := Decode(stream, nil)
+	require.Error(t, err)
+
+	stream = append(makePageInfo(100, 45), makeSegment(1, segIntermediateGenericRegion, nil, data.Bytes())...)
+	_, err = Decode(stream, nil)
+	require.Error(t, err)
+
+	// A region that fits its page but whose data is exhausted.
+	data.Reset()
+	data.Write(makeRegionInfo(1000, 1000, 0, 0))
+	data.WriteByte(0)
+	data.Write([]byte{3, 0xff, 0xfd, 0xff, 2, 0xfe, 0xfe, 0xfe})
+	data.Write([]byte{0xff})
+	stream = append(makePageInfo(1000, 1000), makeSegment(1, segImmediateLosslessGeneric, nil, data.Bytes())...)
+	_, err = Decode(stream, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), errDataExhausted.Error())
+}
+
+// TestDecodeLargeSymbolDictionary checks that a symbol dictionary with more symbols than its data
+// can code fails quickly.
+func TestDecodeLargeSymbolDictionary(t *testing.T) {
+	var dict bytes.Buffer
+	dict.Write([]byte{0, 0})
+	dict.Write([]byte{3, 0xff, 0xfd, 0xff, 2, 0xfe, 0xfe, 0xfe})
+	binary.Write(&dict, binary.BigEndian, []uint32{0, 0xa10000})
+	dict.WriteByte(0)
+	_, err := Decode(makePageInfo(10, 10), makeSegment(0, segSymbolDictionary, nil, dict.Bytes()))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), errTooLarge.Error())
+
+	// A dictionary whose exported symbols are decoded from fill bytes.
+	dict.Reset()
+	dict.Write([]byte{0, 0})
+	dict.Write([]byte{3, 0xff, 0xfd, 0xff, 2, 0xfe, 0xfe, 0xfe})
+	binary.Write(&dict, binary.BigEndian, []uint32{0, 200})
+	dict.WriteByte(0xff)
+	_, err = Decode(makePageInfo(10, 10), makeSegment(0, segSymbolDictionary, nil, dict.Bytes()))
+	require.Error(t, err)
+}
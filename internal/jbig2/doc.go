@@ -0,0 +1,14 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package jbig2 implements a decoder for JBIG2 encoded bi-level images as described in
+// ITU-T Recommendation T.88 (JBIG2). It supports the embedded stream organisation used by the
+// PDF JBIG2Decode filter (PDF 32000-1:2008, 7.4.7), where the segments that are shared between
+// images are stored in a separate JBIG2Globals stream.
+//
+// The following segment types are supported: symbol dictionaries, text regions, pattern
+// dictionaries, halftone regions, generic regions (arithmetic and MMR coded), generic refinement
+// regions, code tables, page information, end of stripe and end of page segments.
+package jbig2
@@ -0,0 +1,174 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+// atPixel is the position of an adaptive template pixel relative to the pixel being decoded.
+type atPixel struct {
+	x, y int
+}
+
+// genericParams are the parameters of the generic region decoding procedure (6.2.2).
+type genericParams struct {
+	mmr      bool
+	template int
+	tpgdon   bool
+	at       [4]atPixel
+	// skip marks the pixels that are not coded and are set to 0. May be nil.
+	skip *Bitmap
+}
+
+// sltpContexts are the contexts used to decode the SLTP bit for each template (6.2.5.7).
+var sltpContexts = [4]int{0x9b25, 0x0795, 0x00e5, 0x0195}
+
+// newGenericContexts returns the arithmetic coding contexts of the generic region decoding
+// procedure. Template 0 has the largest context, of 16 pixels.
+func newGenericContexts() []byte {
+	return make([]byte, 1<<16)
+}
+
+// decodeGeneric decodes a `width` x `height` generic region (6.2.5) with the arithmetic decoder
+// `d` and the contexts `cx`.
+func decodeGeneric(d *arithDecoder, cx []byte, width, height int, p *genericParams) (*Bitmap, error) {
+	if err := checkCodedSize(width*height, len(d.data), maxPixelsPerByte); err != nil {
+		return nil, err
+	}
+	bm := NewBitmap(width, height)
+	ltp := 0
+	for y := 0; y < height; y++ {
+		if err := d.err(); err != nil {
+			return nil, err
+		}
+		if p.tpgdon {
+			ltp ^= d.decodeBit(cx, sltpContexts[p.template])
+			if ltp == 1 {
+				if y > 0 {
+					bm.copyRow(y, y-1)
+				}
+				continue
+			}
+		}
+		for x := 0; x < width; x++ {
+			if p.skip != nil && p.skip.GetPixel(x, y) == 1 {
+				continue
+			}
+			c := genericContext(bm, x, y, p.template, &p.at)
+			if d.decodeBit(cx, c) == 1 {
+				bm.SetPixel(x, y, 1)
+			}
+		}
+	}
+	return bm, nil
+}
+
+// genericContext returns the context of the pixel at (`x`, `y`) of the generic region `bm` for
+// template `template` with adaptive template pixels `at` (6.2.5.3).
+func genericContext(bm *Bitmap, x, y, template int, at *[4]atPixel) int {
+	g := bm.GetPixel
+	switch template {
+	case 0:
+		return g(x-1, y) | g(x-2, y)<<1 | g(x-3, y)<<2 | g(x-4, y)<<3 |
+			g(x+at[0].x, y+at[0].y)<<4 |
+			g(x+2, y-1)<<5 | g(x+1, y-1)<<6 | g(x, y-1)<<7 | g(x-1, y-1)<<8 | g(x-2, y-1)<<9 |
+			g(x+at[1].x, y+at[1].y)<<10 | g(x+at[2].x, y+at[2].y)<<11 |
+			g(x+1, y-2)<<12 | g(x, y-2)<<13 | g(x-1, y-2)<<14 |
+			g(x+at[3].x, y+at[3].y)<<15
+	case 1:
+		return g(x-1, y) | g(x-2, y)<<1 | g(x-3, y)<<2 |
+			g(x+at[0].x, y+at[0].y)<<3 |
+			g(x+2, y-1)<<4 | g(x+1, y-1)<<5 | g(x, y-1)<<6 | g(x-1, y-1)<<7 | g(x-2, y-1)<<8 |
+			g(x+2, y-2)<<9 | g(x+1, y-2)<<10 | g(x, y-2)<<11 | g(x-1, y-2)<<12
+	case 2:
+		return g(x-1, y) | g(x-2, y)<<1 |
+			g(x+at[0].x, y+at[0].y)<<2 |
+			g(x+1, y-1)<<3 | g(x, y-1)<<4 | g(x-1, y-1)<<5 | g(x-2, y-1)<<6 |
+			g(x+1, y-2)<<7 | g(x, y-2)<<8 | g(x-1, y-2)<<9
+	}
+	return g(x-1, y) | g(x-2, y)<<1 | g(x-3, y)<<2 | g(x-4, y)<<3 |
+		g(x+at[0].x, y+at[0].y)<<4 |
+		g(x+1, y-1)<<5 | g(x, y-1)<<6 | g(x-1, y-1)<<7 | g(x-2, y-1)<<8 | g(x-3, y-1)<<9
+}
+
+// refinementParams are the parameters of the generic refinement region decoding procedure
+// (6.3.2).
+type refinementParams struct {
+	template  int
+	reference *Bitmap
+	dx, dy    int
+	tpgron    bool
+	at        [2]atPixel
+}
+
+// newRefinementContexts returns the arithmetic coding contexts of the generic refinement region
+// decoding procedure.
+func newRefinementContexts() []byte {
+	return make([]byte, 1<<13)
+}
+
+// decodeRefinement decodes a `width` x `height` generic refinement region (6.3.5) with the
+// arithmetic decoder `d` and the contexts `cx`.
+func decodeRefinement(d *arithDecoder, cx []byte, width, height int, p *refinementParams) (*Bitmap, error) {
+	if err := checkCodedSize(width*height, len(d.data), maxPixelsPerByte); err != nil {
+		return nil, err
+	}
+	bm := NewBitmap(width, height)
+	ref := p.reference
+	at := p.at
+	g := bm.GetPixel
+	context := func(x, y int) int {
+		rx, ry := x-p.dx, y-p.dy
+		r := ref.GetPixel
+		if p.template == 0 {
+			return g(x-1, y) | g(x+1, y-1)<<1 | g(x, y-1)<<2 | g(x+at[0].x, y+at[0].y)<<3 |
+				r(rx+1, ry+1)<<4 | r(rx, ry+1)<<5 | r(rx-1, ry+1)<<6 |
+				r(rx+1, ry)<<7 | r(rx, ry)<<8 | r(rx-1, ry)<<9 |
+				r(rx+1, ry-1)<<10 | r(rx, ry-1)<<11 | r(rx+at[1].x, ry+at[1].y)<<12
+		}
+		return g(x-1, y) | g(x+1, y-1)<<1 | g(x, y-1)<<2 | g(x-1, y-1)<<3 |
+			r(rx+1, ry+1)<<4 | r(rx, ry+1)<<5 | r(rx+1, ry)<<6 | r(rx, ry)<<7 |
+			r(rx-1, ry)<<8 | r(rx, ry-1)<<9
+	}
+	// The SLTP bit is decoded in the context in which only the reference pixel corresponding
+	// to the pixel being decoded is set (6.3.5.6).
+	sltp := 0x100
+	if p.template == 1 {
+		sltp = 0x80
+	}
+
+	ltp := 0
+	for y := 0; y < height; y++ {
+		if err := d.err(); err != nil {
+			return nil, err
+		}
+		if p.tpgron {
+			ltp ^= d.decodeBit(cx, sltp)
+		}
+		for x := 0; x < width; x++ {
+			if ltp == 1 {
+				// Typical prediction: the pixel has the value of the reference pixel if all the
+				// pixels in the 3x3 neighbourhood of the reference pixel have the same value.
+				rx, ry := x-p.dx, y-p.dy
+				v := ref.GetPixel(rx, ry)
+				typical := true
+				for j := -1; j <= 1 && typical; j++ {
+					for i := -1; i <= 1; i++ {
+						if ref.GetPixel(rx+i, ry+j) != v {
+							typical = false
+							break
+						}
+					}
+				}
+				if typical {
+					bm.SetPixel(x, y, v)
+					continue
+				}
+			}
+			if d.decodeBit(cx, context(x, y)) == 1 {
+				bm.SetPixel(x, y, 1)
+			}
+		}
+	}
+	return bm, nil
+}
@@ -0,0 +1,178 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+import (
+	"errors"
+)
+
+var errInvalidHalftone = errors.New("invalid JBIG2 halftone region")
+
+// patternDictionary is a decoded pattern dictionary segment (7.4.4).
+type patternDictionary struct {
+	patterns []*Bitmap
+}
+
+// decodePatternDictionary decodes the data of a pattern dictionary segment (6.7.5).
+func decodePatternDictionary(data []byte) (*patternDictionary, error) {
+	r := newReader(data)
+	flags, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	mmr := flags&1 != 0
+	template := int(flags >> 1 & 3)
+	pw, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	ph, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	grayMax, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	if pw == 0 || ph == 0 || grayMax >= 1<<16 {
+		return nil, errors.New("invalid JBIG2 pattern dictionary")
+	}
+
+	width := (int(grayMax) + 1) * int(pw)
+	var collective *Bitmap
+	if mmr {
+		collective, err = decodeMMR(r, width, int(ph))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		p := &genericParams{
+			template: template,
+			at:       [4]atPixel{{-int(pw), 0}, {-3, -1}, {2, -2}, {-2, -2}},
+		}
+		collective, err = decodeGeneric(newArithDecoder(r.rest()), newGenericContexts(), width, int(ph), p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pd := &patternDictionary{}
+	for i := 0; i <= int(grayMax); i++ {
+		pd.patterns = append(pd.patterns, collective.subBitmap(i*int(pw), 0, int(pw), int(ph)))
+	}
+	return pd, nil
+}
+
+// halftoneParams are the parameters of the halftone region decoding procedure (6.6.2).
+type halftoneParams struct {
+	width, height int
+	mmr           bool
+	template      int
+	enableSkip    bool
+	combOp        combinationOperator
+	defPixel      int
+	gridWidth     int
+	gridHeight    int
+	gridX, gridY  int
+	vectorX       int
+	vectorY       int
+	patterns      []*Bitmap
+}
+
+// decodeHalftoneRegion decodes a halftone region (6.6.5) from the data `data`.
+func decodeHalftoneRegion(p *halftoneParams, data []byte) (*Bitmap, error) {
+	if len(p.patterns) == 0 {
+		return nil, errInvalidHalftone
+	}
+	if p.gridWidth > 1<<16 || p.gridHeight > 1<<16 {
+		return nil, errInvalidHalftone
+	}
+	bm := NewBitmap(p.width, p.height)
+	if p.defPixel != 0 {
+		bm.fill(1)
+	}
+	pw, ph := p.patterns[0].Width, p.patterns[0].Height
+
+	// gridPos returns the position of the pattern at grid position (mg, ng) (6.6.5.2).
+	gridPos := func(mg, ng int) (int, int) {
+		x := (p.gridX + mg*p.vectorY + ng*p.vectorX) >> 8
+		y := (p.gridY + mg*p.vectorX - ng*p.vectorY) >> 8
+		return x, y
+	}
+
+	var skip *Bitmap
+	if p.enableSkip {
+		// Skip the grid positions whose patterns lie entirely outside of the region (6.6.5.1).
+		skip = NewBitmap(p.gridWidth, p.gridHeight)
+		for mg := 0; mg < p.gridHeight; mg++ {
+			for ng := 0; ng < p.gridWidth; ng++ {
+				x, y := gridPos(mg, ng)
+				if x+pw <= 0 || x >= p.width || y+ph <= 0 || y >= p.height {
+					skip.SetPixel(ng, mg, 1)
+				}
+			}
+		}
+	}
+
+	// Decode the gray-scale image (C.5). The bit planes are Gray coded, most significant first.
+	bpp := codeLength(len(p.patterns))
+	planes := make([]*Bitmap, bpp)
+	r := newReader(data)
+	var d *arithDecoder
+	var cx []byte
+	if !p.mmr {
+		d = newArithDecoder(data)
+		cx = newGenericContexts()
+	}
+	atX := 3
+	if p.template > 1 {
+		atX = 2
+	}
+	gp := &genericParams{
+		template: p.template,
+		at:       [4]atPixel{{atX, -1}, {-3, -1}, {2, -2}, {-2, -2}},
+		skip:     skip,
+	}
+	for j := bpp - 1; j >= 0; j-- {
+		if p.mmr {
+			plane, err := decodeMMR(r, p.gridWidth, p.gridHeight)
+			if err != nil {
+				return nil, err
+			}
+			planes[j] = plane
+		} else {
+			plane, err := decodeGeneric(d, cx, p.gridWidth, p.gridHeight, gp)
+			if err != nil {
+				return nil, err
+			}
+			planes[j] = plane
+		}
+		if j < bpp-1 {
+			for i, b := range planes[j+1].Data {
+				planes[j].Data[i] ^= b
+			}
+		}
+	}
+
+	// Render the patterns (6.6.5.2).
+	for mg := 0; mg < p.gridHeight; mg++ {
+		for ng := 0; ng < p.gridWidth; ng++ {
+			if skip != nil && skip.GetPixel(ng, mg) == 1 {
+				continue
+			}
+			gray := 0
+			for j := 0; j < bpp; j++ {
+				gray |= planes[j].GetPixel(ng, mg) << uint(j)
+			}
+			if gray >= len(p.patterns) {
+				gray = len(p.patterns) - 1
+			}
+			x, y := gridPos(mg, ng)
+			bm.combine(p.patterns[gray], x, y, p.combOp)
+		}
+	}
+	return bm, nil
+}
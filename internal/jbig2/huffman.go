@@ -0,0 +1,244 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+import (
+	"errors"
+)
+
+var errInvalidHuffmanCode = errors.New("invalid JBIG2 Huffman code")
+
+// huffmanLine is a line of a Huffman table (Annex B).
+type huffmanLine struct {
+	rangeLow int
+	prefLen  int
+	rangeLen int
+	kind     lineKind
+}
+
+// lineKind distinguishes the special lines of a Huffman table.
+type lineKind int
+
+const (
+	lineNormal lineKind = iota
+	// lineLower is the lower range line that encodes values <= rangeLow.
+	lineLower
+	// lineUpper is the upper range line that encodes values >= rangeLow.
+	lineUpper
+	// lineOOB encodes the out-of-band value.
+	lineOOB
+)
+
+// huffmanTable is a Huffman table with the prefix codes assigned to its lines.
+type huffmanTable struct {
+	lines []huffmanLine
+	// codes maps prefLen<<32|code to the index of the line with that prefix code.
+	codes  map[uint64]int
+	maxLen int
+}
+
+// newHuffmanTable returns the Huffman table for `lines`, assigning the prefix codes as in B.3.
+// Lines with a zero prefix length are not used.
+func newHuffmanTable(lines []huffmanLine) (*huffmanTable, error) {
+	t := &huffmanTable{lines: lines, codes: make(map[uint64]int, len(lines))}
+	for _, l := range lines {
+		if l.prefLen > 32 || l.rangeLen > 32 || l.prefLen < 0 || l.rangeLen < 0 {
+			return nil, errInvalidHuffmanCode
+		}
+		if l.prefLen > t.maxLen {
+			t.maxLen = l.prefLen
+		}
+	}
+	lenCount := make([]int, t.maxLen+1)
+	for _, l := range lines {
+		lenCount[l.prefLen]++
+	}
+	lenCount[0] = 0
+
+	firstCode := 0
+	for curLen := 1; curLen <= t.maxLen; curLen++ {
+		firstCode = (firstCode + lenCount[curLen-1]) << 1
+		code := firstCode
+		for i, l := range lines {
+			if l.prefLen != curLen {
+				continue
+			}
+			t.codes[uint64(curLen)<<32|uint64(code)] = i
+			code++
+		}
+	}
+	return t, nil
+}
+
+// decode decodes a value from `r`. The returned flag is false if the decoded value is OOB.
+func (t *huffmanTable) decode(r *reader) (int, bool, error) {
+	var code uint64
+	for n := 1; n <= t.maxLen; n++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, false, err
+		}
+		code = code<<1 | uint64(bit)
+		i, ok := t.codes[uint64(n)<<32|code]
+		if !ok {
+			continue
+		}
+		l := t.lines[i]
+		switch l.kind {
+		case lineOOB:
+			return 0, false, nil
+		case lineLower:
+			v, err := r.readBits(32)
+			return l.rangeLow - int(v), true, err
+		case lineUpper:
+			v, err := r.readBits(32)
+			return l.rangeLow + int(v), true, err
+		}
+		v, err := r.readBits(l.rangeLen)
+		return l.rangeLow + int(v), true, err
+	}
+	return 0, false, errInvalidHuffmanCode
+}
+
+// decodeValue decodes a value from `r` that must not be OOB.
+func (t *huffmanTable) decodeValue(r *reader) (int, error) {
+	v, ok, err := t.decode(r)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, errInvalidHuffmanCode
+	}
+	return v, nil
+}
+
+// readHuffmanTable reads a code table segment (7.4.13) and returns the table it defines (B.2).
+func readHuffmanTable(data []byte) (*huffmanTable, error) {
+	r := newReader(data)
+	flags, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	htoob := flags&1 != 0
+	htps := int(flags>>1&7) + 1
+	htrs := int(flags>>4&7) + 1
+	low, err := r.readInt32()
+	if err != nil {
+		return nil, err
+	}
+	high, err := r.readInt32()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []huffmanLine
+	for cur := low; cur < high; {
+		prefLen, err := r.readBits(htps)
+		if err != nil {
+			return nil, err
+		}
+		rangeLen, err := r.readBits(htrs)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, huffmanLine{rangeLow: cur, prefLen: int(prefLen), rangeLen: int(rangeLen)})
+		cur += 1 << rangeLen
+	}
+
+	prefLen, err := r.readBits(htps)
+	if err != nil {
+		return nil, err
+	}
+	lines = append(lines, huffmanLine{rangeLow: low - 1, prefLen: int(prefLen), rangeLen: 32, kind: lineLower})
+	prefLen, err = r.readBits(htps)
+	if err != nil {
+		return nil, err
+	}
+	lines = append(lines, huffmanLine{rangeLow: high, prefLen: int(prefLen), rangeLen: 32, kind: lineUpper})
+	if htoob {
+		prefLen, err = r.readBits(htps)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, huffmanLine{prefLen: int(prefLen), kind: lineOOB})
+	}
+	return newHuffmanTable(lines)
+}
+
+// Shorthands for the lines of the standard tables.
+func hl(low, prefLen, rangeLen int) huffmanLine {
+	return huffmanLine{rangeLow: low, prefLen: prefLen, rangeLen: rangeLen}
+}
+
+func hlLower(low, prefLen int) huffmanLine {
+	return huffmanLine{rangeLow: low, prefLen: prefLen, rangeLen: 32, kind: lineLower}
+}
+
+func hlUpper(low, prefLen int) huffmanLine {
+	return huffmanLine{rangeLow: low, prefLen: prefLen, rangeLen: 32, kind: lineUpper}
+}
+
+func hlOOB(prefLen int) huffmanLine {
+	return huffmanLine{prefLen: prefLen, kind: lineOOB}
+}
+
+// standardTableLines are the lines of the standard Huffman tables B.1 to B.15 (B.5).
+var standardTableLines = [...][]huffmanLine{
+	1: {hl(0, 1, 4), hl(16, 2, 8), hl(272, 3, 16), hlUpper(65808, 3)},
+	2: {hl(0, 1, 0), hl(1, 2, 0), hl(2, 3, 0), hl(3, 4, 3), hl(11, 5, 6), hlUpper(75, 6), hlOOB(6)},
+	3: {hl(-256, 8, 8), hl(0, 1, 0), hl(1, 2, 0), hl(2, 3, 0), hl(3, 4, 3), hl(11, 5, 6),
+		hlLower(-257, 8), hlUpper(75, 7), hlOOB(6)},
+	4: {hl(1, 1, 0), hl(2, 2, 0), hl(3, 3, 0), hl(4, 4, 3), hl(12, 5, 6), hlUpper(76, 5)},
+	5: {hl(-255, 7, 8), hl(1, 1, 0), hl(2, 2, 0), hl(3, 3, 0), hl(4, 4, 3), hl(12, 5, 6),
+		hlLower(-256, 7), hlUpper(76, 6)},
+	6: {hl(-2048, 5, 10), hl(-1024, 4, 9), hl(-512, 4, 8), hl(-256, 4, 7), hl(-128, 5, 6),
+		hl(-64, 5, 5), hl(-32, 4, 5), hl(0, 2, 7), hl(128, 3, 7), hl(256, 3, 8), hl(512, 4, 9),
+		hl(1024, 4, 10), hlLower(-2049, 6), hlUpper(2048, 6)},
+	7: {hl(-1024, 4, 9), hl(-512, 3, 8), hl(-256, 4, 7), hl(-128, 5, 6), hl(-64, 5, 5),
+		hl(-32, 4, 5), hl(0, 4, 5), hl(32, 5, 5), hl(64, 5, 6), hl(128, 4, 7), hl(256, 3, 8),
+		hl(512, 3, 9), hl(1024, 3, 10), hlLower(-1025, 5), hlUpper(2048, 5)},
+	8: {hl(-15, 8, 3), hl(-7, 9, 1), hl(-5, 8, 1), hl(-3, 9, 0), hl(-2, 7, 0), hl(-1, 4, 0),
+		hl(0, 2, 1), hl(2, 5, 0), hl(3, 6, 0), hl(4, 3, 4), hl(20, 6, 1), hl(22, 4, 4),
+		hl(38, 4, 5), hl(70, 5, 6), hl(134, 5, 7), hl(262, 6, 7), hl(390, 7, 8), hl(646, 6, 10),
+		hlLower(-16, 9), hlUpper(1670, 9), hlOOB(2)},
+	9: {hl(-31, 8, 4), hl(-15, 9, 2), hl(-11, 8, 2), hl(-7, 9, 1), hl(-5, 7, 1), hl(-3, 4, 1),
+		hl(-1, 3, 1), hl(1, 3, 1), hl(3, 5, 1), hl(5, 6, 1), hl(7, 3, 5), hl(39, 6, 2),
+		hl(43, 4, 5), hl(75, 4, 6), hl(139, 5, 7), hl(267, 5, 8), hl(523, 6, 8), hl(779, 7, 9),
+		hl(1291, 6, 11), hlLower(-32, 9), hlUpper(3339, 9), hlOOB(2)},
+	10: {hl(-21, 7, 4), hl(-5, 8, 0), hl(-4, 7, 0), hl(-3, 5, 0), hl(-2, 2, 2), hl(2, 5, 0),
+		hl(3, 6, 0), hl(4, 7, 0), hl(5, 8, 0), hl(6, 2, 6), hl(70, 5, 5), hl(102, 6, 5),
+		hl(134, 6, 6), hl(198, 6, 7), hl(326, 6, 8), hl(582, 6, 9), hl(1094, 6, 10),
+		hl(2118, 7, 11), hlLower(-22, 8), hlUpper(4166, 8), hlOOB(2)},
+	11: {hl(1, 1, 0), hl(2, 2, 1), hl(4, 4, 0), hl(5, 4, 1), hl(7, 5, 1), hl(9, 5, 2),
+		hl(13, 6, 2), hl(17, 7, 2), hl(21, 7, 3), hl(29, 7, 4), hl(45, 7, 5), hl(77, 7, 6),
+		hlUpper(141, 7)},
+	12: {hl(1, 1, 0), hl(2, 2, 0), hl(3, 3, 1), hl(5, 5, 0), hl(6, 5, 1), hl(8, 6, 1),
+		hl(10, 7, 0), hl(11, 7, 1), hl(13, 7, 2), hl(17, 7, 3), hl(25, 7, 4), hl(41, 8, 5),
+		hlUpper(73, 8)},
+	13: {hl(1, 1, 0), hl(2, 3, 0), hl(3, 4, 0), hl(4, 5, 0), hl(5, 4, 1), hl(7, 3, 3),
+		hl(15, 6, 1), hl(17, 6, 2), hl(21, 6, 3), hl(29, 6, 4), hl(45, 6, 5), hl(77, 7, 6),
+		hlUpper(141, 7)},
+	14: {hl(-2, 3, 0), hl(-1, 3, 0), hl(0, 1, 0), hl(1, 3, 0), hl(2, 3, 0)},
+	15: {hl(-24, 7, 4), hl(-8, 6, 2), hl(-4, 5, 1), hl(-2, 4, 0), hl(-1, 3, 0), hl(0, 1, 0),
+		hl(1, 3, 0), hl(2, 4, 0), hl(3, 5, 1), hl(5, 6, 2), hl(9, 7, 4), hlLower(-25, 7),
+		hlUpper(25, 7)},
+}
+
+// standardTables are the standard Huffman tables indexed by their number in Annex B.
+var standardTables [len(standardTableLines)]*huffmanTable
+
+func init() {
+	for i, lines := range standardTableLines {
+		if lines == nil {
+			continue
+		}
+		t, err := newHuffmanTable(lines)
+		if err != nil {
+			panic(err)
+		}
+		standardTables[i] = t
+	}
+}
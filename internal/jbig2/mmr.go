@@ -0,0 +1,296 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+import (
+	"errors"
+)
+
+var (
+	errInvalidMMRCode = errors.New("invalid JBIG2 MMR code")
+	errInvalidMMRMode = errors.New("invalid JBIG2 MMR mode")
+)
+
+// runCode is the bit string of the code of a run length in the modified Huffman code of
+// ITU-T T.4.
+type runCode struct {
+	run  int
+	code string
+}
+
+// Terminating and make-up codes of ITU-T T.4, Tables 2 and 3.
+var (
+	whiteRunCodes = []runCode{
+		{0, "00110101"}, {1, "000111"}, {2, "0111"}, {3, "1000"}, {4, "1011"}, {5, "1100"},
+		{6, "1110"}, {7, "1111"}, {8, "10011"}, {9, "10100"}, {10, "00111"}, {11, "01000"},
+		{12, "001000"}, {13, "000011"}, {14, "110100"}, {15, "110101"}, {16, "101010"},
+		{17, "101011"}, {18, "0100111"}, {19, "0001100"}, {20, "0001000"}, {21, "0010111"},
+		{22, "0000011"}, {23, "0000100"}, {24, "0101000"}, {25, "0101011"}, {26, "0010011"},
+		{27, "0100100"}, {28, "0011000"}, {29, "00000010"}, {30, "00000011"}, {31, "00011010"},
+		{32, "00011011"}, {33, "00010010"}, {34, "00010011"}, {35, "00010100"}, {36, "00010101"},
+		{37, "00010110"}, {38, "00010111"}, {39, "00101000"}, {40, "00101001"}, {41, "00101010"},
+		{42, "00101011"}, {43, "00101100"}, {44, "00101101"}, {45, "00000100"}, {46, "00000101"},
+		{47, "00001010"}, {48, "00001011"}, {49, "01010010"}, {50, "01010011"}, {51, "01010100"},
+		{52, "01010101"}, {53, "00100100"}, {54, "00100101"}, {55, "01011000"}, {56, "01011001"},
+		{57, "01011010"}, {58, "01011011"}, {59, "01001010"}, {60, "01001011"}, {61, "00110010"},
+		{62, "00110011"}, {63, "00110100"},
+		{64, "11011"}, {128, "10010"}, {192, "010111"}, {256, "0110111"}, {320, "00110110"},
+		{384, "00110111"}, {448, "01100100"}, {512, "01100101"}, {576, "01101000"},
+		{640, "01100111"}, {704, "011001100"}, {768, "011001101"}, {832, "011010010"},
+		{896, "011010011"}, {960, "011010100"}, {1024, "011010101"}, {1088, "011010110"},
+		{1152, "011010111"}, {1216, "011011000"}, {1280, "011011001"}, {1344, "011011010"},
+		{1408, "011011011"}, {1472, "010011000"}, {1536, "010011001"}, {1600, "010011010"},
+		{1664, "011000"}, {1728, "010011011"},
+	}
+	blackRunCodes = []runCode{
+		{0, "0000110111"}, {1, "010"}, {2, "11"}, {3, "10"}, {4, "011"}, {5, "0011"},
+		{6, "0010"}, {7, "00011"}, {8, "000101"}, {9, "000100"}, {10, "0000100"},
+		{11, "0000101"}, {12, "0000111"}, {13, "00000100"}, {14, "00000111"}, {15, "000011000"},
+		{16, "0000010111"}, {17, "0000011000"}, {18, "0000001000"}, {19, "00001100111"},
+		{20, "00001101000"}, {21, "00001101100"}, {22, "00000110111"}, {23, "00000101000"},
+		{24, "00000010111"}, {25, "00000011000"}, {26, "000011001010"}, {27, "000011001011"},
+		{28, "000011001100"}, {29, "000011001101"}, {30, "000001101000"}, {31, "000001101001"},
+		{32, "000001101010"}, {33, "000001101011"}, {34, "000011010010"}, {35, "000011010011"},
+		{36, "000011010100"}, {37, "000011010101"}, {38, "000011010110"}, {39, "000011010111"},
+		{40, "000001101100"}, {41, "000001101101"}, {42, "000011011010"}, {43, "000011011011"},
+		{44, "000001010100"}, {45, "000001010101"}, {46, "000001010110"}, {47, "000001010111"},
+		{48, "000001100100"}, {49, "000001100101"}, {50, "000001010010"}, {51, "000001010011"},
+		{52, "000000100100"}, {53, "000000110111"}, {54, "000000111000"}, {55, "000000100111"},
+		{56, "000000101000"}, {57, "000001011000"}, {58, "000001011001"}, {59, "000000101011"},
+		{60, "000000101100"}, {61, "000001011010"}, {62, "000001100110"}, {63, "000001100111"},
+		{64, "0000001111"}, {128, "000011001000"}, {192, "000011001001"}, {256, "000001011011"},
+		{320, "000000110011"}, {384, "000000110100"}, {448, "000000110101"},
+		{512, "0000001101100"}, {576, "0000001101101"}, {640, "0000001001010"},
+		{704, "0000001001011"}, {768, "0000001001100"}, {832, "0000001001101"},
+		{896, "0000001110010"}, {960, "0000001110011"}, {1024, "0000001110100"},
+		{1088, "0000001110101"}, {1152, "0000001110110"}, {1216, "0000001110111"},
+		{1280, "0000001010010"}, {1344, "0000001010011"}, {1408, "0000001010100"},
+		{1472, "0000001010101"}, {1536, "0000001011010"}, {1600, "0000001011011"},
+		{1664, "0000001100100"}, {1728, "0000001100101"},
+	}
+	// extendedRunCodes are the make-up codes that are common to white and black runs.
+	extendedRunCodes = []runCode{
+		{1792, "00000001000"}, {1856, "00000001100"}, {1920, "00000001101"},
+		{1984, "000000010010"}, {2048, "000000010011"}, {2112, "000000010100"},
+		{2176, "000000010101"}, {2240, "000000010110"}, {2304, "000000010111"},
+		{2368, "000000011100"}, {2432, "000000011101"}, {2496, "000000011110"},
+		{2560, "000000011111"},
+	}
+)
+
+// runTables map len<<16|code of the run length codes to the run length, for white (0) and
+// black (1) runs.
+var runTables [2]map[uint32]int
+
+func init() {
+	for color, codes := range [][]runCode{whiteRunCodes, blackRunCodes} {
+		runTables[color] = make(map[uint32]int)
+		for _, codes := range [][]runCode{codes, extendedRunCodes} {
+			for _, c := range codes {
+				var v uint32
+				for _, b := range c.code {
+					v = v<<1 | uint32(b-'0')
+				}
+				runTables[color][uint32(len(c.code))<<16|v] = c.run
+			}
+		}
+	}
+}
+
+// mmrMode is a coding mode of the two-dimensional coding scheme of ITU-T T.6.
+type mmrMode int
+
+const (
+	modePass mmrMode = iota
+	modeHorizontal
+	modeVertical
+	modeEOFB
+)
+
+// eofbCode is the first half of the EOFB code: the two EOL codes 000000000001 000000000001.
+const eofbCode = 0x001
+
+// readMode reads a mode code. For the vertical mode the offset of a1 from b1 is returned as well.
+func readMode(r *reader) (mmrMode, int, error) {
+	// Count the leading zeros to identify the code.
+	zeros := 0
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, 0, err
+		}
+		if bit == 1 {
+			break
+		}
+		zeros++
+		if zeros > 11 {
+			return 0, 0, errInvalidMMRMode
+		}
+	}
+	switch zeros {
+	case 0:
+		return modeVertical, 0, nil
+	case 1:
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, 0, err
+		}
+		if bit == 1 {
+			return modeVertical, 1, nil
+		}
+		return modeVertical, -1, nil
+	case 2:
+		return modeHorizontal, 0, nil
+	case 3:
+		return modePass, 0, nil
+	case 4, 5:
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, 0, err
+		}
+		offset := zeros - 2
+		if bit == 0 {
+			offset = -offset
+		}
+		return modeVertical, offset, nil
+	case 11:
+		return modeEOFB, 0, nil
+	}
+	// Extension codes and uncompressed mode are not used by JBIG2.
+	return 0, 0, errInvalidMMRMode
+}
+
+// readRun reads a run length of `color` pixels.
+func readRun(r *reader, color int) (int, error) {
+	run := 0
+	for {
+		var code uint32
+		n := 0
+		for {
+			bit, err := r.readBit()
+			if err != nil {
+				return 0, err
+			}
+			code = code<<1 | uint32(bit)
+			n++
+			if v, ok := runTables[color][uint32(n)<<16|code]; ok {
+				run += v
+				if v < 64 {
+					return run, nil
+				}
+				break
+			}
+			if n > 13 {
+				return 0, errInvalidMMRCode
+			}
+		}
+	}
+}
+
+// decodeMMR decodes a `width` x `height` bitmap coded with the MMR (ITU-T T.6) coding scheme
+// (6.2.6). An EOFB code following the bitmap is skipped and the reader is left byte aligned after
+// the coded data.
+func decodeMMR(r *reader, width, height int) (*Bitmap, error) {
+	bm := NewBitmap(width, height)
+	// The changing elements of the reference line. A changing element with an even index starts
+	// a black run. The imaginary line above the first row is white.
+	ref := []int{width, width}
+	cur := make([]int, 0, 64)
+	for y := 0; y < height; y++ {
+		cur = cur[:0]
+		a0, color := -1, 0
+		for a0 < width {
+			mode, offset, err := readMode(r)
+			if err != nil {
+				return nil, err
+			}
+			// b1 is the first changing element on the reference line to the right of a0 and of
+			// the opposite colour to the colour of a0.
+			i := 0
+			for i < len(ref) && ref[i] <= a0 {
+				i++
+			}
+			if i%2 != color {
+				i++
+			}
+			b1, b2 := width, width
+			if i < len(ref) {
+				b1 = ref[i]
+			}
+			if i+1 < len(ref) {
+				b2 = ref[i+1]
+			}
+
+			switch mode {
+			case modePass:
+				if color == 1 {
+					fillRun(bm, y, maxInt(a0, 0), b2)
+				}
+				a0 = b2
+			case modeHorizontal:
+				start := maxInt(a0, 0)
+				run1, err := readRun(r, color)
+				if err != nil {
+					return nil, err
+				}
+				run2, err := readRun(r, 1-color)
+				if err != nil {
+					return nil, err
+				}
+				a1 := minInt(start+run1, width)
+				a2 := minInt(a1+run2, width)
+				if color == 1 {
+					fillRun(bm, y, start, a1)
+				} else {
+					fillRun(bm, y, a1, a2)
+				}
+				cur = append(cur, a1, a2)
+				a0 = a2
+			case modeVertical:
+				a1 := b1 + offset
+				if a1 < 0 || a1 > width || a1 < a0 {
+					return nil, errInvalidMMRCode
+				}
+				if color == 1 {
+					fillRun(bm, y, maxInt(a0, 0), a1)
+				}
+				cur = append(cur, a1)
+				a0 = a1
+				color = 1 - color
+			case modeEOFB:
+				return nil, errInvalidMMRMode
+			}
+		}
+		ref = append(append(ref[:0], cur...), width, width)
+	}
+
+	if r.peekBits(24) == eofbCode<<12|eofbCode {
+		r.readBits(24)
+	}
+	r.align()
+	return bm, nil
+}
+
+// fillRun sets the pixels from `x0` up to `x1` of row `y` of `bm` to black.
+func fillRun(bm *Bitmap, y, x0, x1 int) {
+	for x := x0; x < x1; x++ {
+		bm.SetPixel(x, y, 1)
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
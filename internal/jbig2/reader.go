@@ -0,0 +1,132 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+import (
+	"errors"
+)
+
+var errEOD = errors.New("unexpected end of JBIG2 data")
+
+// reader reads bits and big-endian integers from a byte slice. Bits are read most significant
+// bit first.
+type reader struct {
+	data []byte
+	pos  int  // position of the current byte.
+	bit  uint // number of bits of the current byte that have been read.
+}
+
+func newReader(data []byte) *reader {
+	return &reader{data: data}
+}
+
+// readBit reads a single bit.
+func (r *reader) readBit() (int, error) {
+	if r.pos >= len(r.data) {
+		return 0, errEOD
+	}
+	v := int(r.data[r.pos]>>(7-r.bit)) & 1
+	r.bit++
+	if r.bit == 8 {
+		r.bit = 0
+		r.pos++
+	}
+	return v, nil
+}
+
+// readBits reads `n` <= 32 bits.
+func (r *reader) readBits(n int) (uint32, error) {
+	var v uint32
+	for i := 0; i < n; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | uint32(bit)
+	}
+	return v, nil
+}
+
+// peekBits returns the next `n` bits without consuming them. Bits past the end of the data are 0.
+func (r *reader) peekBits(n int) uint32 {
+	pos, bit := r.pos, r.bit
+	var v uint32
+	for i := 0; i < n; i++ {
+		b, err := r.readBit()
+		if err != nil {
+			b = 0
+		}
+		v = v<<1 | uint32(b)
+	}
+	r.pos, r.bit = pos, bit
+	return v
+}
+
+// align skips the remaining bits of a partially read byte.
+func (r *reader) align() {
+	if r.bit != 0 {
+		r.bit = 0
+		r.pos++
+	}
+}
+
+// readByte reads the next byte. The reader must be byte aligned.
+func (r *reader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errEOD
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+// readInt8 reads a signed byte.
+func (r *reader) readInt8() (int, error) {
+	b, err := r.readByte()
+	return int(int8(b)), err
+}
+
+// readUint16 reads a big-endian 16-bit unsigned integer.
+func (r *reader) readUint16() (uint16, error) {
+	b, err := r.readBytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(b[0])<<8 | uint16(b[1]), nil
+}
+
+// readUint32 reads a big-endian 32-bit unsigned integer.
+func (r *reader) readUint32() (uint32, error) {
+	b, err := r.readBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]), nil
+}
+
+// readInt32 reads a big-endian 32-bit signed integer.
+func (r *reader) readInt32() (int, error) {
+	v, err := r.readUint32()
+	return int(int32(v)), err
+}
+
+// readBytes reads the next `n` bytes. The reader must be byte aligned.
+func (r *reader) readBytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errEOD
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// rest returns the unread bytes, starting with the current byte.
+func (r *reader) rest() []byte {
+	if r.pos >= len(r.data) {
+		return nil
+	}
+	return r.data[r.pos:]
+}
@@ -0,0 +1,401 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+import (
+	"errors"
+)
+
+var errInvalidSymbolDictionary = errors.New("invalid JBIG2 symbol dictionary")
+
+// symbolDictionary is a decoded symbol dictionary segment (7.4.3).
+type symbolDictionary struct {
+	exported []*Bitmap
+	// The arithmetic coding contexts, kept if the bitmap coding context is retained.
+	gb, gr []byte
+}
+
+// decodeSymbolDictionary decodes the data of the symbol dictionary segment `h` (7.4.3.2).
+func (dec *decoder) decodeSymbolDictionary(h *segmentHeader, data []byte) (*symbolDictionary, error) {
+	r := newReader(data)
+	flags, err := r.readUint16()
+	if err != nil {
+		return nil, err
+	}
+	huffman := flags&1 != 0
+	refAgg := flags&2 != 0
+	huffDH := int(flags >> 2 & 3)
+	huffDW := int(flags >> 4 & 3)
+	huffBMSize := flags>>6&1 != 0
+	huffAggInst := flags>>7&1 != 0
+	ctxUsed := flags>>8&1 != 0
+	ctxRetained := flags>>9&1 != 0
+	template := int(flags >> 10 & 3)
+	rtemplate := int(flags >> 12 & 1)
+
+	var at [4]atPixel
+	if !huffman {
+		n := 1
+		if template == 0 {
+			n = 4
+		}
+		if err := readATPixels(r, at[:n]); err != nil {
+			return nil, err
+		}
+	}
+	var rat [2]atPixel
+	if refAgg && rtemplate == 0 {
+		if err := readATPixels(r, rat[:]); err != nil {
+			return nil, err
+		}
+	}
+	numExported, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	numNew, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+
+	var inSymbols []*Bitmap
+	var lastDict *symbolDictionary
+	for _, n := range h.referred {
+		if sd, ok := dec.results[n].(*symbolDictionary); ok {
+			inSymbols = append(inSymbols, sd.exported...)
+			lastDict = sd
+		}
+	}
+	numSymbols := len(inSymbols) + int(numNew)
+	if numNew > 1<<24 || numExported > uint32(numSymbols) {
+		return nil, errInvalidSymbolDictionary
+	}
+	if err := checkCodedSize(int(numNew), len(r.rest()), maxSymbolsPerByte); err != nil {
+		return nil, err
+	}
+	codeLen := codeLength(numSymbols)
+
+	sd := &symbolDictionary{}
+	var tableDH, tableDW, tableBMSize, tableAggInst *huffmanTable
+	var d *arithDecoder
+	var iadh, iadw, iaai, iaex *intDecoder
+	var td *textDecoders
+	if huffman {
+		tables := dec.referredTables(h)
+		if tableDH, err = selectTable(huffDH, []int{4, 5, -1, 0}, tables); err != nil {
+			return nil, err
+		}
+		if tableDW, err = selectTable(huffDW, []int{2, 3, -1, 0}, tables); err != nil {
+			return nil, err
+		}
+		if tableBMSize, err = selectTable(b2i(huffBMSize), []int{1, 0}, tables); err != nil {
+			return nil, err
+		}
+		if tableAggInst, err = selectTable(b2i(huffAggInst), []int{1, 0}, tables); err != nil {
+			return nil, err
+		}
+		codeLen = maxInt(codeLen, 1)
+	} else {
+		d = newArithDecoder(r.rest())
+		iadh, iadw, iaai, iaex = newIntDecoder(), newIntDecoder(), newIntDecoder(), newIntDecoder()
+	}
+	if refAgg {
+		td, err = newTextDecoders(codeLen)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sd.gb, sd.gr = newGenericContexts(), newRefinementContexts()
+	if ctxUsed && lastDict != nil && lastDict.gb != nil {
+		copy(sd.gb, lastDict.gb)
+		copy(sd.gr, lastDict.gr)
+	}
+	if td != nil {
+		td.gr = sd.gr
+	}
+
+	decodeInt := func(t *huffmanTable, id *intDecoder) (int, bool, error) {
+		if huffman {
+			return t.decode(r)
+		}
+		v, ok := id.decode(d)
+		return v, ok, nil
+	}
+
+	newSymbols := make([]*Bitmap, 0, numNew)
+	height := 0
+	for len(newSymbols) < int(numNew) {
+		if d != nil {
+			if err := d.err(); err != nil {
+				return nil, err
+			}
+		}
+		dh, ok, err := decodeInt(tableDH, iadh)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errInvalidSymbolDictionary
+		}
+		height += dh
+		if height < 0 {
+			return nil, errInvalidSymbolDictionary
+		}
+
+		// Decode the symbols of the height class.
+		width, totalWidth := 0, 0
+		var widths []int
+		for {
+			if d != nil {
+				if err := d.err(); err != nil {
+					return nil, err
+				}
+			}
+			dw, ok, err := decodeInt(tableDW, iadw)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			width += dw
+			totalWidth += width
+			if width < 0 || len(newSymbols)+len(widths) >= int(numNew) {
+				return nil, errInvalidSymbolDictionary
+			}
+
+			switch {
+			case refAgg:
+				bm, err := dec.decodeAggregateSymbol(r, d, td, width, height, append(inSymbols, newSymbols...),
+					codeLen, rtemplate, rat, huffman, tableAggInst, iaai)
+				if err != nil {
+					return nil, err
+				}
+				newSymbols = append(newSymbols, bm)
+			case huffman:
+				widths = append(widths, width)
+			default:
+				bm, err := decodeGeneric(d, sd.gb, width, height, &genericParams{template: template, at: at})
+				if err != nil {
+					return nil, err
+				}
+				newSymbols = append(newSymbols, bm)
+			}
+		}
+
+		if huffman && !refAgg {
+			// The symbols of the height class are coded in a collective bitmap (6.5.9).
+			bm, err := readCollectiveBitmap(r, tableBMSize, totalWidth, height)
+			if err != nil {
+				return nil, err
+			}
+			x := 0
+			for _, w := range widths {
+				newSymbols = append(newSymbols, bm.subBitmap(x, 0, w, height))
+				x += w
+			}
+		}
+	}
+
+	// Determine the exported symbols (6.5.10).
+	var exported []*Bitmap
+	export := false
+	for i := 0; i < numSymbols; {
+		var run int
+		if huffman {
+			run, err = standardTables[1].decodeValue(r)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			if err := d.err(); err != nil {
+				return nil, err
+			}
+			run, _ = iaex.decode(d)
+		}
+		if run < 0 || i+run > numSymbols {
+			return nil, errInvalidSymbolDictionary
+		}
+		if export {
+			for j := i; j < i+run; j++ {
+				if j < len(inSymbols) {
+					exported = append(exported, inSymbols[j])
+				} else {
+					exported = append(exported, newSymbols[j-len(inSymbols)])
+				}
+			}
+		}
+		i += run
+		export = !export
+	}
+	sd.exported = exported
+
+	if !ctxRetained {
+		sd.gb, sd.gr = nil, nil
+	}
+	return sd, nil
+}
+
+// decodeAggregateSymbol decodes a `width` x `height` symbol that is coded with refinement/
+// aggregate coding (6.5.8.2).
+func (dec *decoder) decodeAggregateSymbol(r *reader, d *arithDecoder, td *textDecoders, width, height int,
+	symbols []*Bitmap, codeLen, rtemplate int, rat [2]atPixel, huffman bool, tableAggInst *huffmanTable,
+	iaai *intDecoder) (*Bitmap, error) {
+	var numInst int
+	if huffman {
+		v, err := tableAggInst.decodeValue(r)
+		if err != nil {
+			return nil, err
+		}
+		numInst = v
+	} else {
+		numInst, _ = iaai.decode(d)
+	}
+	dataLen := len(r.rest())
+	if d != nil {
+		dataLen = len(d.data)
+	}
+	if err := checkCodedSize(numInst, dataLen, maxSymbolsPerByte); err != nil {
+		return nil, err
+	}
+
+	if numInst > 1 {
+		// The symbol is an aggregation of the symbols decoded so far (6.5.8.2.1).
+		p := &textParams{
+			huffman:      huffman,
+			refine:       true,
+			width:        width,
+			height:       height,
+			numInstances: numInst,
+			symbols:      symbols,
+			codeLen:      codeLen,
+			refCorner:    cornerTopLeft,
+			combOp:       combOr,
+			rtemplate:    rtemplate,
+			rat:          rat,
+			fs:           standardTables[6],
+			ds:           standardTables[8],
+			dt:           standardTables[11],
+			rdw:          standardTables[15],
+			rdh:          standardTables[15],
+			rdx:          standardTables[15],
+			rdy:          standardTables[15],
+			rsize:        standardTables[1],
+		}
+		return decodeTextRegion(p, r, d, td)
+	}
+
+	// The symbol is a refinement of a single symbol (6.5.8.2.2).
+	var id, rdx, rdy int
+	if huffman {
+		v, err := r.readBits(codeLen)
+		if err != nil {
+			return nil, err
+		}
+		id = int(v)
+		if rdx, err = standardTables[15].decodeValue(r); err != nil {
+			return nil, err
+		}
+		if rdy, err = standardTables[15].decodeValue(r); err != nil {
+			return nil, err
+		}
+	} else {
+		id = td.iaid.decode(d)
+		rdx, _ = td.iardx.decode(d)
+		rdy, _ = td.iardy.decode(d)
+	}
+	if id < 0 || id >= len(symbols) {
+		return nil, errInvalidSymbolID
+	}
+	rp := &refinementParams{
+		template:  rtemplate,
+		reference: symbols[id],
+		dx:        rdx,
+		dy:        rdy,
+		at:        rat,
+	}
+	if !huffman {
+		return decodeRefinement(d, td.gr, width, height, rp)
+	}
+
+	size, err := standardTables[1].decodeValue(r)
+	if err != nil {
+		return nil, err
+	}
+	r.align()
+	data, err := r.readBytes(size)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRefinement(newArithDecoder(data), td.gr, width, height, rp)
+}
+
+// readCollectiveBitmap reads the collective bitmap of a height class of a Huffman coded symbol
+// dictionary (6.5.9).
+func readCollectiveBitmap(r *reader, tableBMSize *huffmanTable, width, height int) (*Bitmap, error) {
+	size, err := tableBMSize.decodeValue(r)
+	if err != nil {
+		return nil, err
+	}
+	r.align()
+	if size == 0 {
+		// The bitmap is uncompressed.
+		bm := NewBitmap(width, height)
+		data, err := r.readBytes(len(bm.Data))
+		if err != nil {
+			return nil, err
+		}
+		copy(bm.Data, data)
+		return bm, nil
+	}
+	data, err := r.readBytes(size)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMMR(newReader(data), width, height)
+}
+
+// readATPixels reads the positions of adaptive template pixels.
+func readATPixels(r *reader, at []atPixel) error {
+	for i := range at {
+		x, err := r.readInt8()
+		if err != nil {
+			return err
+		}
+		y, err := r.readInt8()
+		if err != nil {
+			return err
+		}
+		at[i] = atPixel{x: x, y: y}
+	}
+	return nil
+}
+
+// selectTable returns the Huffman table selected by `selector`. `standard` maps the selector
+// values to the numbers of the standard tables, where 0 selects the next user-defined table from
+// `tables` and -1 is an invalid selection.
+func selectTable(selector int, standard []int, tables *[]*huffmanTable) (*huffmanTable, error) {
+	if selector >= len(standard) || standard[selector] < 0 {
+		return nil, errors.New("invalid JBIG2 Huffman table selection")
+	}
+	if n := standard[selector]; n > 0 {
+		return standardTables[n], nil
+	}
+	if len(*tables) == 0 {
+		return nil, errMissingTable
+	}
+	t := (*tables)[0]
+	*tables = (*tables)[1:]
+	return t, nil
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,303 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jbig2
+
+import (
+	"errors"
+)
+
+var (
+	errInvalidSymbolID = errors.New("invalid JBIG2 symbol ID")
+	errMissingTable    = errors.New("missing JBIG2 Huffman table")
+)
+
+// Reference corners of the symbol instances of a text region (7.4.4.1.1).
+const (
+	cornerBottomLeft = iota
+	cornerTopLeft
+	cornerBottomRight
+	cornerTopRight
+)
+
+// textParams are the parameters of the text region decoding procedure (6.4.2).
+type textParams struct {
+	huffman      bool
+	refine       bool
+	width        int
+	height       int
+	defPixel     int
+	numInstances int
+	logStrips    int
+	symbols      []*Bitmap
+	codeLen      int
+	transposed   bool
+	dsOffset     int
+	refCorner    int
+	combOp       combinationOperator
+	rtemplate    int
+	rat          [2]atPixel
+
+	// Huffman tables, used when huffman is set.
+	fs, ds, dt, rdw, rdh, rdx, rdy, rsize *huffmanTable
+	// symbolIDs decodes the symbol IDs in Huffman mode. If nil, the symbol IDs are coded with
+	// codeLen bits.
+	symbolIDs *huffmanTable
+}
+
+// textDecoders are the arithmetic integer decoders and contexts of the text region decoding
+// procedure.
+type textDecoders struct {
+	iadt, iafs, iads, iait, iari *intDecoder
+	iardw, iardh, iardx, iardy   *intDecoder
+	iaid                         *idDecoder
+	gr                           []byte
+}
+
+func newTextDecoders(codeLen int) (*textDecoders, error) {
+	iaid, err := newIDDecoder(codeLen)
+	if err != nil {
+		return nil, err
+	}
+	return &textDecoders{
+		iadt:  newIntDecoder(),
+		iafs:  newIntDecoder(),
+		iads:  newIntDecoder(),
+		iait:  newIntDecoder(),
+		iari:  newIntDecoder(),
+		iardw: newIntDecoder(),
+		iardh: newIntDecoder(),
+		iardx: newIntDecoder(),
+		iardy: newIntDecoder(),
+		iaid:  iaid,
+		gr:    newRefinementContexts(),
+	}, nil
+}
+
+// textDecoder decodes the integers of a text region either with Huffman tables from `r` or with
+// the arithmetic decoder `d`.
+type textDecoder struct {
+	p  *textParams
+	r  *reader
+	d  *arithDecoder
+	td *textDecoders
+}
+
+// decodeInt decodes an integer with Huffman table `t` or arithmetic integer decoder `id`.
+func (dec *textDecoder) decodeInt(t *huffmanTable, id *intDecoder) (int, bool, error) {
+	if dec.p.huffman {
+		if t == nil {
+			return 0, false, errMissingTable
+		}
+		return t.decode(dec.r)
+	}
+	if err := dec.d.err(); err != nil {
+		return 0, false, err
+	}
+	v, ok := id.decode(dec.d)
+	return v, ok, nil
+}
+
+// decodeValue is like decodeInt but fails on OOB.
+func (dec *textDecoder) decodeValue(t *huffmanTable, id *intDecoder) (int, error) {
+	v, ok, err := dec.decodeInt(t, id)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, errInvalidHuffmanCode
+	}
+	return v, nil
+}
+
+// decodeTextRegion decodes a text region (6.4.5). In Huffman mode the data is read from `r`,
+// otherwise it is decoded with the arithmetic decoder `d` and decoders `td`.
+func decodeTextRegion(p *textParams, r *reader, d *arithDecoder, td *textDecoders) (*Bitmap, error) {
+	dec := &textDecoder{p: p, r: r, d: d, td: td}
+	if !p.huffman && td == nil {
+		return nil, errors.New("missing text region decoders")
+	}
+	if td == nil {
+		td = &textDecoders{}
+		dec.td = td
+	}
+	strips := 1 << uint(p.logStrips)
+
+	bm := NewBitmap(p.width, p.height)
+	if p.defPixel != 0 {
+		bm.fill(1)
+	}
+
+	stripT, err := dec.decodeValue(p.dt, td.iadt)
+	if err != nil {
+		return nil, err
+	}
+	stripT = -stripT * strips
+	firstS := 0
+	for n := 0; n < p.numInstances; {
+		dt, err := dec.decodeValue(p.dt, td.iadt)
+		if err != nil {
+			return nil, err
+		}
+		stripT += dt * strips
+		dfs, err := dec.decodeValue(p.fs, td.iafs)
+		if err != nil {
+			return nil, err
+		}
+		firstS += dfs
+		curS := firstS
+
+		for {
+			curT := 0
+			if strips > 1 {
+				if p.huffman {
+					v, err := r.readBits(p.logStrips)
+					if err != nil {
+						return nil, err
+					}
+					curT = int(v)
+				} else {
+					curT, _ = td.iait.decode(d)
+				}
+			}
+			t := stripT + curT
+
+			var id int
+			switch {
+			case !p.huffman:
+				id = td.iaid.decode(d)
+			case p.symbolIDs != nil:
+				id, err = p.symbolIDs.decodeValue(r)
+				if err != nil {
+					return nil, err
+				}
+			default:
+				v, err := r.readBits(p.codeLen)
+				if err != nil {
+					return nil, err
+				}
+				id = int(v)
+			}
+			if id < 0 || id >= len(p.symbols) {
+				return nil, errInvalidSymbolID
+			}
+			ib := p.symbols[id]
+
+			if p.refine {
+				var ri int
+				if p.huffman {
+					ri, err = r.readBit()
+					if err != nil {
+						return nil, err
+					}
+				} else {
+					ri, _ = td.iari.decode(d)
+				}
+				if ri != 0 {
+					ib, err = dec.refineSymbol(ib)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			w, h := ib.Width, ib.Height
+			if !p.transposed && p.refCorner > cornerTopLeft {
+				curS += w - 1
+			} else if p.transposed && p.refCorner&1 == 0 {
+				curS += h - 1
+			}
+			s := curS
+
+			var x, y int
+			if !p.transposed {
+				x, y = s, t
+			} else {
+				x, y = t, s
+			}
+			switch p.refCorner {
+			case cornerTopRight:
+				x -= w - 1
+			case cornerBottomLeft:
+				y -= h - 1
+			case cornerBottomRight:
+				x -= w - 1
+				y -= h - 1
+			}
+			bm.combine(ib, x, y, p.combOp)
+
+			if !p.transposed && p.refCorner < cornerBottomRight {
+				curS += w - 1
+			} else if p.transposed && p.refCorner&1 != 0 {
+				curS += h - 1
+			}
+			n++
+
+			ids, ok, err := dec.decodeInt(p.ds, td.iads)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			curS += ids + p.dsOffset
+		}
+	}
+	return bm, nil
+}
+
+// refineSymbol decodes the refinement of the symbol bitmap `ib` of a symbol instance (6.4.11).
+func (dec *textDecoder) refineSymbol(ib *Bitmap) (*Bitmap, error) {
+	p, td := dec.p, dec.td
+	rdw, err := dec.decodeValue(p.rdw, td.iardw)
+	if err != nil {
+		return nil, err
+	}
+	rdh, err := dec.decodeValue(p.rdh, td.iardh)
+	if err != nil {
+		return nil, err
+	}
+	rdx, err := dec.decodeValue(p.rdx, td.iardx)
+	if err != nil {
+		return nil, err
+	}
+	rdy, err := dec.decodeValue(p.rdy, td.iardy)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := ib.Width+rdw, ib.Height+rdh
+	if width < 0 || height < 0 {
+		return nil, errors.New("invalid JBIG2 refinement size")
+	}
+	rp := &refinementParams{
+		template:  p.rtemplate,
+		reference: ib,
+		dx:        rdw>>1 + rdx,
+		dy:        rdh>>1 + rdy,
+		at:        p.rat,
+	}
+	if !p.huffman {
+		return decodeRefinement(dec.d, td.gr, width, height, rp)
+	}
+
+	// In Huffman mode the refinement is arithmetic coded in the following BMSIZE bytes.
+	if p.rsize == nil {
+		return nil, errMissingTable
+	}
+	size, err := p.rsize.decodeValue(dec.r)
+	if err != nil {
+		return nil, err
+	}
+	dec.r.align()
+	data, err := dec.r.readBytes(size)
+	if err != nil {
+		return nil, err
+	}
+	if td.gr == nil {
+		td.gr = newRefinementContexts()
+	}
+	return decodeRefinement(newArithDecoder(data), td.gr, width, height, rp)
+}
@@ -0,0 +1,592 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/common"
+)
+
+// Codestream markers (A.2).
+const (
+	markerSOC = 0xff4f
+	markerSIZ = 0xff51
+	markerCOD = 0xff52
+	markerCOC = 0xff53
+	markerTLM = 0xff55
+	markerPLM = 0xff57
+	markerPLT = 0xff58
+	markerQCD = 0xff5c
+	markerQCC = 0xff5d
+	markerRGN = 0xff5e
+	markerPOC = 0xff5f
+	markerPPM = 0xff60
+	markerPPT = 0xff61
+	markerCRG = 0xff63
+	markerCOM = 0xff64
+	markerSOT = 0xff90
+	markerSOP = 0xff91
+	markerEPH = 0xff92
+	markerSOD = 0xff93
+	markerEOC = 0xffd9
+)
+
+// Progression orders (Table A.16).
+const (
+	progressionLRCP = iota
+	progressionRLCP
+	progressionRPCL
+	progressionPCRL
+	progressionCPRL
+)
+
+// Code-block style flags (Table A.19).
+const (
+	cbBypass       = 0x01
+	cbReset        = 0x02
+	cbTermAll      = 0x04
+	cbCausal       = 0x08
+	cbPredictable  = 0x10
+	cbSegmentation = 0x20
+)
+
+// Quantization styles (Table A.28).
+const (
+	quantNone     = 0
+	quantDerived  = 1
+	quantExpanded = 2
+)
+
+// maxImagePixels is the largest number of samples of an image component that is decoded.
+const maxImagePixels = 1 << 28
+
+var (
+	errEOD           = errors.New("unexpected end of JPEG 2000 data")
+	errInvalidHeader = errors.New("invalid JPEG 2000 codestream header")
+)
+
+// component holds the SIZ parameters of an image component.
+type component struct {
+	precision int
+	signed    bool
+	dx, dy    int
+}
+
+// siz holds the image and tile size parameters (A.5.1).
+type siz struct {
+	width, height         int // Xsiz and Ysiz, the reference grid size.
+	x0, y0                int // XOsiz and YOsiz, the image area offset.
+	tileWidth, tileHeight int
+	tileX0, tileY0        int
+	components            []component
+}
+
+// numTiles returns the number of tiles horizontally and vertically.
+func (s *siz) numTiles() (int, int) {
+	return ceilDiv(s.width-s.tileX0, s.tileWidth), ceilDiv(s.height-s.tileY0, s.tileHeight)
+}
+
+// precinctSize holds the precinct width and height exponents of a resolution level.
+type precinctSize struct {
+	ppx, ppy int
+}
+
+// codingStyle holds the coding style parameters of a component (SPcod and SPcoc, A.6.1).
+type codingStyle struct {
+	levels     int
+	xcb, ycb   int // Code-block width and height exponents.
+	cbStyle    byte
+	reversible bool
+	precincts  []precinctSize // nil if the maximal precincts are used.
+}
+
+// precinct returns the precinct size exponents of resolution level `r`.
+func (s *codingStyle) precinct(r int) precinctSize {
+	if s.precincts == nil {
+		return precinctSize{15, 15}
+	}
+	if r < len(s.precincts) {
+		return s.precincts[r]
+	}
+	return s.precincts[len(s.precincts)-1]
+}
+
+// cod holds the parameters of a COD marker segment (A.6.1).
+type cod struct {
+	sop, eph    bool
+	progression int
+	layers      int
+	mct         bool
+	style       codingStyle
+}
+
+// stepSize holds the exponent and mantissa of the quantization step size of a subband.
+type stepSize struct {
+	exp, mant int
+}
+
+// quantization holds the parameters of a QCD or QCC marker segment (A.6.4).
+type quantization struct {
+	style     int
+	guardBits int
+	steps     []stepSize
+}
+
+// step returns the quantization step size of subband `b` (in the order of Table A.30) whose
+// decomposition level is `nb` (E.1.1.1).
+func (q *quantization) step(b, nb, levels int) stepSize {
+	if q.style == quantDerived {
+		if len(q.steps) == 0 {
+			return stepSize{}
+		}
+		s := q.steps[0]
+		return stepSize{exp: s.exp - levels + nb, mant: s.mant}
+	}
+	if b < len(q.steps) {
+		return q.steps[b]
+	}
+	if len(q.steps) > 0 {
+		return q.steps[len(q.steps)-1]
+	}
+	return stepSize{}
+}
+
+// codingParams holds the coding parameters of the main header or a tile header.
+type codingParams struct {
+	cod *cod
+	coc map[int]*codingStyle
+	qcd *quantization
+	qcc map[int]*quantization
+	rgn map[int]int
+}
+
+func newCodingParams() *codingParams {
+	return &codingParams{
+		coc: map[int]*codingStyle{},
+		qcc: map[int]*quantization{},
+		rgn: map[int]int{},
+	}
+}
+
+// tileParams holds the coding parameters that apply to a tile: the parameters of the tile
+// header, falling back to those of the main header.
+type tileParams struct {
+	main, tile *codingParams
+}
+
+// cod returns the COD parameters that apply to the tile.
+func (p *tileParams) cod() *cod {
+	if p.tile.cod != nil {
+		return p.tile.cod
+	}
+	return p.main.cod
+}
+
+// style returns the coding style of component `c`.
+func (p *tileParams) style(c int) *codingStyle {
+	if s, ok := p.tile.coc[c]; ok {
+		return s
+	}
+	if p.tile.cod != nil {
+		return &p.tile.cod.style
+	}
+	if s, ok := p.main.coc[c]; ok {
+		return s
+	}
+	return &p.main.cod.style
+}
+
+// quantization returns the quantization parameters of component `c`.
+func (p *tileParams) quantization(c int) *quantization {
+	if q, ok := p.tile.qcc[c]; ok {
+		return q
+	}
+	if p.tile.qcd != nil {
+		return p.tile.qcd
+	}
+	if q, ok := p.main.qcc[c]; ok {
+		return q
+	}
+	return p.main.qcd
+}
+
+// roiShift returns the region of interest shift of component `c`.
+func (p *tileParams) roiShift(c int) int {
+	if s, ok := p.tile.rgn[c]; ok {
+		return s
+	}
+	return p.main.rgn[c]
+}
+
+// tileData holds the coding parameters and the concatenated tile-part data of a tile.
+type tileData struct {
+	index  int
+	params *tileParams
+	data   []byte
+}
+
+// codestream is a parsed JPEG 2000 codestream.
+type codestream struct {
+	siz   siz
+	main  *codingParams
+	tiles []*tileData
+}
+
+// markerReader reads marker segments from a codestream.
+type markerReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *markerReader) readUint16() (int, error) {
+	if r.pos+2 > len(r.data) {
+		return 0, errEOD
+	}
+	r.pos += 2
+	return int(binary.BigEndian.Uint16(r.data[r.pos-2:])), nil
+}
+
+// readSegment reads the parameters of a marker segment, whose first two bytes are its length.
+func (r *markerReader) readSegment() ([]byte, error) {
+	n, err := r.readUint16()
+	if err != nil {
+		return nil, err
+	}
+	if n < 2 || r.pos+n-2 > len(r.data) {
+		return nil, errEOD
+	}
+	seg := r.data[r.pos : r.pos+n-2]
+	r.pos += n - 2
+	return seg, nil
+}
+
+// parseCodestream parses the main header and the tile-parts of the codestream `data`.
+// If `headerOnly` is true, parsing stops after the SIZ marker segment.
+func parseCodestream(data []byte, headerOnly bool) (*codestream, error) {
+	r := &markerReader{data: data}
+	if m, err := r.readUint16(); err != nil || m != markerSOC {
+		return nil, errors.New("missing JPEG 2000 SOC marker")
+	}
+	cs := &codestream{main: newCodingParams()}
+	m, err := r.readUint16()
+	if err != nil {
+		return nil, err
+	}
+	if m != markerSIZ {
+		return nil, errors.New("missing JPEG 2000 SIZ marker")
+	}
+	seg, err := r.readSegment()
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.parseSIZ(seg); err != nil {
+		return nil, err
+	}
+	if headerOnly {
+		return cs, nil
+	}
+
+	tiles := map[int]*tileData{}
+	for {
+		m, err := r.readUint16()
+		if err != nil {
+			common.Log.Debug("JPEG 2000 codestream without EOC marker")
+			break
+		}
+		if m == markerEOC {
+			break
+		}
+		if m == markerSOT {
+			if err := cs.readTilePart(r, tiles); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		seg, err := r.readSegment()
+		if err != nil {
+			return nil, err
+		}
+		if err := cs.parseMarkerSegment(m, seg, cs.main); err != nil {
+			return nil, err
+		}
+	}
+	if cs.main.cod == nil || cs.main.qcd == nil {
+		return nil, errors.New("missing JPEG 2000 COD or QCD marker")
+	}
+	numX, numY := cs.siz.numTiles()
+	for i := 0; i < numX*numY; i++ {
+		if t, ok := tiles[i]; ok {
+			cs.tiles = append(cs.tiles, t)
+		}
+	}
+	return cs, nil
+}
+
+// parseSIZ parses the SIZ marker segment parameters `seg` (A.5.1).
+func (cs *codestream) parseSIZ(seg []byte) error {
+	if len(seg) < 36 {
+		return errInvalidHeader
+	}
+	u32 := func(i int) int {
+		return int(binary.BigEndian.Uint32(seg[i:]))
+	}
+	s := &cs.siz
+	s.width, s.height = u32(2), u32(6)
+	s.x0, s.y0 = u32(10), u32(14)
+	s.tileWidth, s.tileHeight = u32(18), u32(22)
+	s.tileX0, s.tileY0 = u32(26), u32(30)
+	n := int(binary.BigEndian.Uint16(seg[34:]))
+	if n == 0 || len(seg) < 36+3*n {
+		return errInvalidHeader
+	}
+	if s.x0 >= s.width || s.y0 >= s.height || s.tileWidth == 0 || s.tileHeight == 0 ||
+		s.tileX0 > s.x0 || s.tileY0 > s.y0 ||
+		s.tileX0+s.tileWidth <= s.x0 || s.tileY0+s.tileHeight <= s.y0 {
+		return errInvalidHeader
+	}
+	s.components = make([]component, n)
+	for i := range s.components {
+		b := seg[36+3*i:]
+		c := component{
+			precision: int(b[0]&0x7f) + 1,
+			signed:    b[0]&0x80 != 0,
+			dx:        int(b[1]),
+			dy:        int(b[2]),
+		}
+		if c.precision > 31 || c.dx == 0 || c.dy == 0 {
+			return errInvalidHeader
+		}
+		w := ceilDiv(s.width, c.dx) - ceilDiv(s.x0, c.dx)
+		h := ceilDiv(s.height, c.dy) - ceilDiv(s.y0, c.dy)
+		if int64(w)*int64(h) > maxImagePixels {
+			return errors.New("JPEG 2000 image too large")
+		}
+		s.components[i] = c
+	}
+	return nil
+}
+
+// readTilePart reads a tile-part starting with the SOT marker that has just been read (A.4.2).
+func (cs *codestream) readTilePart(r *markerReader, tiles map[int]*tileData) error {
+	start := r.pos - 2
+	seg, err := r.readSegment()
+	if err != nil {
+		return err
+	}
+	if len(seg) < 8 {
+		return errInvalidHeader
+	}
+	index := int(binary.BigEndian.Uint16(seg))
+	length := int(binary.BigEndian.Uint32(seg[2:]))
+	partIndex := int(seg[6])
+
+	numX, numY := cs.siz.numTiles()
+	if index >= numX*numY {
+		return fmt.Errorf("invalid JPEG 2000 tile index %d", index)
+	}
+	tile, ok := tiles[index]
+	if !ok {
+		tile = &tileData{
+			index:  index,
+			params: &tileParams{main: cs.main, tile: newCodingParams()},
+		}
+		tiles[index] = tile
+	}
+
+	// The tile-part header.
+	for {
+		m, err := r.readUint16()
+		if err != nil {
+			return err
+		}
+		if m == markerSOD {
+			break
+		}
+		seg, err := r.readSegment()
+		if err != nil {
+			return err
+		}
+		if partIndex > 0 && (m == markerCOD || m == markerQCD) {
+			common.Log.Debug("JPEG 2000 marker %04x in tile-part %d ignored", m, partIndex)
+			continue
+		}
+		if err := cs.parseMarkerSegment(m, seg, tile.params.tile); err != nil {
+			return err
+		}
+	}
+
+	end := len(r.data)
+	if length != 0 {
+		end = start + length
+	}
+	if end < r.pos || end > len(r.data) {
+		common.Log.Debug("Invalid JPEG 2000 tile-part length %d", length)
+		end = len(r.data)
+	}
+	tile.data = append(tile.data, r.data[r.pos:end]...)
+	r.pos = end
+	return nil
+}
+
+// parseMarkerSegment parses the parameters `seg` of the marker segment `m` of a main or tile-part
+// header into `p`.
+func (cs *codestream) parseMarkerSegment(m int, seg []byte, p *codingParams) error {
+	switch m {
+	case markerCOD:
+		c, err := parseCOD(seg)
+		if err != nil {
+			return err
+		}
+		p.cod = c
+	case markerCOC:
+		c, style, err := cs.parseCOC(seg)
+		if err != nil {
+			return err
+		}
+		p.coc[c] = style
+	case markerQCD:
+		q, err := parseQuantization(seg)
+		if err != nil {
+			return err
+		}
+		p.qcd = q
+	case markerQCC:
+		c, n := cs.readComponentIndex(seg)
+		if len(seg) < n {
+			return errInvalidHeader
+		}
+		q, err := parseQuantization(seg[n:])
+		if err != nil {
+			return err
+		}
+		p.qcc[c] = q
+	case markerRGN:
+		c, n := cs.readComponentIndex(seg)
+		if len(seg) < n+2 {
+			return errInvalidHeader
+		}
+		if seg[n] != 0 {
+			return errors.New("unsupported JPEG 2000 ROI style")
+		}
+		p.rgn[c] = int(seg[n+1])
+	case markerPOC:
+		return errors.New("unsupported JPEG 2000 progression order change")
+	case markerPPM, markerPPT:
+		return errors.New("unsupported JPEG 2000 packed packet headers")
+	case markerTLM, markerPLM, markerPLT, markerCRG, markerCOM:
+		// Informational only.
+	default:
+		common.Log.Debug("Unknown JPEG 2000 marker %04x", m)
+	}
+	return nil
+}
+
+// readComponentIndex returns the component index at the start of the parameters `seg` of a COC,
+// QCC or RGN marker segment and its size in bytes.
+func (cs *codestream) readComponentIndex(seg []byte) (int, int) {
+	if len(cs.siz.components) < 257 {
+		if len(seg) < 1 {
+			return 0, 1
+		}
+		return int(seg[0]), 1
+	}
+	if len(seg) < 2 {
+		return 0, 2
+	}
+	return int(binary.BigEndian.Uint16(seg)), 2
+}
+
+// parseCOD parses the parameters `seg` of a COD marker segment (A.6.1).
+func parseCOD(seg []byte) (*cod, error) {
+	if len(seg) < 5 {
+		return nil, errInvalidHeader
+	}
+	c := &cod{
+		sop:         seg[0]&0x02 != 0,
+		eph:         seg[0]&0x04 != 0,
+		progression: int(seg[1]),
+		layers:      int(binary.BigEndian.Uint16(seg[2:])),
+		mct:         seg[4] != 0,
+	}
+	if c.progression > progressionCPRL || c.layers == 0 {
+		return nil, errInvalidHeader
+	}
+	style, err := parseCodingStyle(seg[5:], seg[0]&0x01 != 0)
+	if err != nil {
+		return nil, err
+	}
+	c.style = *style
+	return c, nil
+}
+
+// parseCOC parses the parameters `seg` of a COC marker segment (A.6.2).
+func (cs *codestream) parseCOC(seg []byte) (int, *codingStyle, error) {
+	c, n := cs.readComponentIndex(seg)
+	if len(seg) < n+1 {
+		return 0, nil, errInvalidHeader
+	}
+	style, err := parseCodingStyle(seg[n+1:], seg[n]&0x01 != 0)
+	return c, style, err
+}
+
+// parseCodingStyle parses the SPcod or SPcoc parameters `b`.
+func parseCodingStyle(b []byte, precincts bool) (*codingStyle, error) {
+	if len(b) < 5 {
+		return nil, errInvalidHeader
+	}
+	s := &codingStyle{
+		levels:     int(b[0]),
+		xcb:        int(b[1]&0x0f) + 2,
+		ycb:        int(b[2]&0x0f) + 2,
+		cbStyle:    b[3],
+		reversible: b[4] == 1,
+	}
+	if s.levels > 32 || s.xcb > 10 || s.ycb > 10 || s.xcb+s.ycb > 12 {
+		return nil, errInvalidHeader
+	}
+	if precincts {
+		if len(b) < 5+s.levels+1 {
+			return nil, errInvalidHeader
+		}
+		for _, v := range b[5 : 5+s.levels+1] {
+			s.precincts = append(s.precincts, precinctSize{ppx: int(v & 0x0f), ppy: int(v >> 4)})
+		}
+	}
+	return s, nil
+}
+
+// parseQuantization parses the Sqcd and SPqcd parameters `b` of a QCD or QCC marker segment.
+func parseQuantization(b []byte) (*quantization, error) {
+	if len(b) < 1 {
+		return nil, errInvalidHeader
+	}
+	q := &quantization{style: int(b[0] & 0x1f), guardBits: int(b[0] >> 5)}
+	switch q.style {
+	case quantNone:
+		for _, v := range b[1:] {
+			q.steps = append(q.steps, stepSize{exp: int(v >> 3)})
+		}
+	case quantDerived, quantExpanded:
+		for i := 1; i+1 < len(b); i += 2 {
+			v := int(binary.BigEndian.Uint16(b[i:]))
+			q.steps = append(q.steps, stepSize{exp: v >> 11, mant: v & 0x7ff})
+		}
+	default:
+		return nil, errInvalidHeader
+	}
+	if len(q.steps) == 0 {
+		return nil, errInvalidHeader
+	}
+	return q, nil
+}
+
+// ceilDiv returns ceil(a / b) for non-negative `a` and positive `b`.
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
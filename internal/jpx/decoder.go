@@ -0,0 +1,422 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+)
+
+// Config holds the dimensions and the channel layout of a JPEG 2000 image.
+type Config struct {
+	Width, Height int
+
+	// ColorComponents is the number of colour channels, not including the alpha channel.
+	ColorComponents int
+
+	// HasAlpha is true if the image has an opacity channel.
+	HasAlpha bool
+
+	// ICCProfile is the ICC profile that specifies the colour space of the image, or nil if the
+	// colour space is not specified by an ICC profile.
+	ICCProfile []byte
+}
+
+// Image is a decoded JPEG 2000 image. The samples are scaled to 8 bits.
+type Image struct {
+	Config
+
+	// Data holds the colour samples, row by row with the channels of each pixel interleaved.
+	Data []byte
+
+	// Alpha holds the opacity samples, or nil if the image has no alpha channel.
+	Alpha []byte
+}
+
+// channel identifies the codestream component that a channel of the image is taken from, and the
+// palette column that the component values are mapped through, or -1 if none.
+type channel struct {
+	comp   int
+	column int
+}
+
+// layout maps the codestream components to the channels of the image.
+type layout struct {
+	color   []channel
+	alpha   *channel
+	palette *palette
+	ycc     bool
+	icc     []byte
+}
+
+// DecodeConfig returns the dimensions and the channel layout of the JPEG 2000 image `data`,
+// which is either a JP2 file or a raw codestream, without decoding the image data.
+func DecodeConfig(data []byte) (*Config, error) {
+	cs, l, err := parse(data, true)
+	if err != nil {
+		return nil, err
+	}
+	return l.config(cs), nil
+}
+
+// Decode decodes the JPEG 2000 image `data`, which is either a JP2 file or a raw codestream.
+func Decode(data []byte) (img *Image, err error) {
+	// The codestream is validated as it is parsed, but a malformed codestream that is missed must
+	// fail the decoding of its image only.
+	defer func() {
+		if r := recover(); r != nil {
+			common.Log.Debug("ERROR: JPEG 2000 decoding panicked: %v", r)
+			img, err = nil, fmt.Errorf("invalid JPEG 2000 data: %v", r)
+		}
+	}()
+	cs, l, err := parse(data, false)
+	if err != nil {
+		return nil, err
+	}
+	planes, err := cs.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	img = &Image{Config: *l.config(cs)}
+	w, h := img.Width, img.Height
+	n := len(l.color)
+	img.Data = make([]byte, w*h*n)
+	for i, ch := range l.color {
+		samples, err := l.samples(cs, planes, ch)
+		if err != nil {
+			return nil, err
+		}
+		for j, v := range samples {
+			img.Data[j*n+i] = v
+		}
+	}
+	if l.alpha != nil {
+		if img.Alpha, err = l.samples(cs, planes, *l.alpha); err != nil {
+			return nil, err
+		}
+	}
+	if l.ycc && n == 3 {
+		yccToRGB(img.Data)
+	}
+	return img, nil
+}
+
+// parse parses the JP2 header boxes, if any, and the codestream of `data`.
+func parse(data []byte, headerOnly bool) (*codestream, *layout, error) {
+	var h *jp2Header
+	if !bytes.HasPrefix(data, []byte{0xff, 0x4f}) {
+		var err error
+		if h, data, err = readJP2(data); err != nil {
+			return nil, nil, err
+		}
+	}
+	cs, err := parseCodestream(data, headerOnly)
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := newLayout(h, len(cs.siz.components))
+	if err != nil {
+		return nil, nil, err
+	}
+	return cs, l, nil
+}
+
+// newLayout returns the channel layout of an image with `numComps` codestream components and the
+// JP2 header `h`, which is nil for raw codestreams (I.5.3).
+func newLayout(h *jp2Header, numComps int) (*layout, error) {
+	l := &layout{}
+	var channels []channel
+	if h != nil && h.palette != nil {
+		if len(h.mapping) == 0 {
+			return nil, errors.New("JP2 palette without component mapping")
+		}
+		l.palette = h.palette
+		for _, m := range h.mapping {
+			if m.comp >= numComps || (m.palette && m.column >= len(h.palette.entries)) {
+				return nil, errors.New("invalid JP2 component mapping")
+			}
+			ch := channel{comp: m.comp, column: -1}
+			if m.palette {
+				ch.column = m.column
+			}
+			channels = append(channels, ch)
+		}
+	} else {
+		for c := 0; c < numComps; c++ {
+			channels = append(channels, channel{comp: c, column: -1})
+		}
+	}
+
+	// The number of colour channels.
+	n := 0
+	if h != nil {
+		switch h.enumCS {
+		case 0:
+			n = iccComponents(h.iccProfile)
+			l.icc = h.iccProfile
+		case enumGray:
+			n = 1
+		case enumCMYK:
+			n = 4
+		case enumSYCC:
+			n = 3
+			l.ycc = true
+		default:
+			n = 3
+		}
+	}
+	if n == 0 {
+		n = len(channels)
+		if n == 2 && (h == nil || len(h.channels) == 0) {
+			// A grey image with an opacity channel.
+			n = 1
+			l.alpha = &channels[1]
+		}
+	}
+	if n > len(channels) {
+		n = len(channels)
+		l.ycc = false
+	}
+
+	if h != nil && len(h.channels) > 0 {
+		color := make([]*channel, n)
+		for _, def := range h.channels {
+			if def.channel >= len(channels) {
+				continue
+			}
+			ch := &channels[def.channel]
+			switch def.kind {
+			case channelColor:
+				if def.assoc >= 1 && def.assoc <= n {
+					color[def.assoc-1] = ch
+				}
+			case channelOpacity, channelPremultOpacity:
+				if l.alpha == nil {
+					l.alpha = ch
+				}
+			}
+		}
+		for i, ch := range color {
+			if ch == nil {
+				l.color = nil
+				break
+			}
+			l.color = append(l.color, *color[i])
+		}
+	}
+	if l.color == nil {
+		l.color = channels[:n]
+	}
+	return l, nil
+}
+
+// config returns the image configuration of the codestream `cs` with the layout `l`.
+func (l *layout) config(cs *codestream) *Config {
+	return &Config{
+		Width:           cs.siz.width - cs.siz.x0,
+		Height:          cs.siz.height - cs.siz.y0,
+		ColorComponents: len(l.color),
+		HasAlpha:        l.alpha != nil,
+		ICCProfile:      l.icc,
+	}
+}
+
+// samples returns the 8 bit samples of the channel `ch` of the image with the decoded component
+// planes `planes`. Subsampled components are upsampled to the image size.
+func (l *layout) samples(cs *codestream, planes [][]int32, ch channel) ([]byte, error) {
+	s := &cs.siz
+	comp := s.components[ch.comp]
+	plane := planes[ch.comp]
+	cx0, cy0 := ceilDiv(s.x0, comp.dx), ceilDiv(s.y0, comp.dy)
+	cw, ch0 := ceilDiv(s.width, comp.dx)-cx0, ceilDiv(s.height, comp.dy)-cy0
+	if cw <= 0 || ch0 <= 0 || len(plane) < cw*ch0 {
+		return nil, errors.New("invalid JPEG 2000 component size")
+	}
+	precision := comp.precision
+
+	var lut []int
+	if ch.column >= 0 {
+		lut = l.palette.entries[ch.column]
+		precision = l.palette.depths[ch.column]
+	}
+
+	w, h := s.width-s.x0, s.height-s.y0
+	out := make([]byte, w*h)
+	for y := 0; y < h; y++ {
+		// The image origin need not be on the sampling grid of the component, so the first
+		// samples may come before the first sample of the component.
+		py := minInt(maxInt((s.y0+y)/comp.dy-cy0, 0), ch0-1)
+		for x := 0; x < w; x++ {
+			px := minInt(maxInt((s.x0+x)/comp.dx-cx0, 0), cw-1)
+			v := int(plane[py*cw+px])
+			if lut != nil {
+				v = lut[minInt(maxInt(v, 0), len(lut)-1)]
+			}
+			out[y*w+x] = scaleTo8(v, precision)
+		}
+	}
+	return out, nil
+}
+
+// scaleTo8 scales the unsigned sample `v` of `precision` bits to 8 bits.
+func scaleTo8(v, precision int) byte {
+	switch {
+	case precision > 8:
+		v >>= uint(precision - 8)
+	case precision < 8:
+		v = v * 255 / (1<<uint(precision) - 1)
+	}
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return byte(v)
+}
+
+// yccToRGB converts interleaved sYCC samples to RGB in place.
+func yccToRGB(data []byte) {
+	for i := 0; i+2 < len(data); i += 3 {
+		y := float64(data[i])
+		cb := float64(data[i+1]) - 128
+		cr := float64(data[i+2]) - 128
+		data[i] = clamp8(y + 1.402*cr)
+		data[i+1] = clamp8(y - 0.344136*cb - 0.714136*cr)
+		data[i+2] = clamp8(y + 1.772*cb)
+	}
+}
+
+func clamp8(v float64) byte {
+	v = math.Round(v)
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return byte(v)
+}
+
+// decode decodes the tiles of the codestream and returns the samples of each component. The
+// samples are offset to be unsigned.
+func (cs *codestream) decode() ([][]int32, error) {
+	s := &cs.siz
+	planes := make([][]int32, len(s.components))
+	for c, comp := range s.components {
+		w := ceilDiv(s.width, comp.dx) - ceilDiv(s.x0, comp.dx)
+		h := ceilDiv(s.height, comp.dy) - ceilDiv(s.y0, comp.dy)
+		planes[c] = make([]int32, w*h)
+	}
+	for _, td := range cs.tiles {
+		t := newTile(cs, td.index, td.params)
+		if err := t.decodePackets(td.data); err != nil {
+			return nil, err
+		}
+		samples := t.decodeSamples(cs)
+		for c, tc := range t.comps {
+			comp := s.components[c]
+			cx0, cy0 := ceilDiv(s.x0, comp.dx), ceilDiv(s.y0, comp.dy)
+			cw := ceilDiv(s.width, comp.dx) - cx0
+			tw := tc.x1 - tc.x0
+			offset := 1 << uint(comp.precision-1)
+			max := float32(int64(1)<<uint(comp.precision) - 1)
+			for y := tc.y0; y < tc.y1; y++ {
+				row := planes[c][(y-cy0)*cw+tc.x0-cx0:]
+				for x := 0; x < tw; x++ {
+					v := samples[c][(y-tc.y0)*tw+x] + float32(offset)
+					if v < 0 {
+						v = 0
+					} else if v > max {
+						v = max
+					}
+					row[x] = int32(math.Round(float64(v)))
+				}
+			}
+		}
+	}
+	return planes, nil
+}
+
+// decodeSamples decodes the code-blocks of the tile, applies the inverse wavelet and component
+// transforms, and returns the samples of each tile-component before the DC level shift.
+func (t *tile) decodeSamples(cs *codestream) [][]float32 {
+	samples := make([][]float32, len(t.comps))
+	for c, tc := range t.comps {
+		precision := cs.siz.components[c].precision
+		for _, res := range tc.resolutions {
+			for _, band := range res.bands {
+				tc.decodeBand(band, precision)
+			}
+		}
+		samples[c] = tc.inverseDWT()
+	}
+
+	// Inverse multiple component transformation (G.2, G.3).
+	cod := t.params.cod()
+	if cod.mct && len(t.comps) >= 3 {
+		a, b, c := samples[0], samples[1], samples[2]
+		if len(a) != len(b) || len(a) != len(c) {
+			return samples
+		}
+		if t.comps[0].style.reversible {
+			for i := range a {
+				g := a[i] - float32(math.Floor(float64(b[i]+c[i])/4))
+				a[i], b[i], c[i] = c[i]+g, g, b[i]+g
+			}
+		} else {
+			for i := range a {
+				y, cb, cr := a[i], b[i], c[i]
+				a[i] = y + 1.402*cr
+				b[i] = y - 0.34413*cb - 0.71414*cr
+				c[i] = y + 1.772*cb
+			}
+		}
+	}
+	return samples
+}
+
+// decodeBand decodes the code-blocks of the subband `band` and stores its dequantized
+// coefficients (E.1).
+func (tc *tileComponent) decodeBand(band *subband, precision int) {
+	bw, bh := band.x1-band.x0, band.y1-band.y0
+	band.coeffs = make([]float32, bw*bh)
+
+	q := tc.quant
+	step := q.step(band.index, band.level, tc.style.levels)
+	delta := float32(1)
+	if q.style != quantNone {
+		gain := 0
+		switch band.kind {
+		case bandHL, bandLH:
+			gain = 1
+		case bandHH:
+			gain = 2
+		}
+		delta = float32(math.Ldexp(1+float64(step.mant)/2048, precision+gain-step.exp))
+	}
+	mb := q.guardBits + step.exp - 1
+
+	for _, cb := range band.blocks {
+		if len(cb.segments) == 0 {
+			continue
+		}
+		w, h := cb.x1-cb.x0, cb.y1-cb.y0
+		d := newBlockDecoder(w, h, band.kind, tc.style.cbStyle)
+		d.decode(cb.segments, mb+tc.roiShift-cb.zeroBitPlanes)
+		for y := 0; y < h; y++ {
+			row := band.coeffs[(cb.y0-band.y0+y)*bw+cb.x0-band.x0:]
+			for x := 0; x < w; x++ {
+				row[x] = d.coefficient(x, y, tc.roiShift) * delta
+			}
+		}
+	}
+}
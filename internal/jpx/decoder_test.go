@@ -0,0 +1,1069 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// mqEncoder is the MQ arithmetic encoder (C.2), used to make test data.
+type mqEncoder struct {
+	a, c uint32
+	ct   int
+	// out holds the coded bytes. The first byte precedes the coded data and is dropped.
+	out []byte
+}
+
+func newMQEncoder() *mqEncoder {
+	return &mqEncoder{a: 0x8000, ct: 12, out: []byte{0}}
+}
+
+func (e *mqEncoder) encodeBit(cx []byte, i, bit int) {
+	state := &qeTable[cx[i]>>1]
+	mps := int(cx[i] & 1)
+	index := cx[i] >> 1
+	e.a -= state.qe
+	if bit == mps {
+		if e.a&0x8000 != 0 {
+			e.c += state.qe
+			return
+		}
+		if e.a < state.qe {
+			e.a = state.qe
+		} else {
+			e.c += state.qe
+		}
+		index = state.nmps
+	} else {
+		if e.a < state.qe {
+			e.c += state.qe
+		} else {
+			e.a = state.qe
+		}
+		if state.switchMPS {
+			mps = 1 - mps
+		}
+		index = state.nlps
+	}
+	cx[i] = index<<1 | byte(mps)
+	for {
+		e.a <<= 1
+		e.c <<= 1
+		e.ct--
+		if e.ct == 0 {
+			e.byteOut()
+		}
+		if e.a&0x8000 != 0 {
+			break
+		}
+	}
+}
+
+func (e *mqEncoder) byteOut() {
+	last := &e.out[len(e.out)-1]
+	if *last != 0xff && e.c >= 0x8000000 {
+		*last++
+		e.c &= 0x7ffffff
+	}
+	if *last == 0xff {
+		e.out = append(e.out, byte(e.c>>20))
+		e.c &= 0xfffff
+		e.ct = 7
+	} else {
+		e.out = append(e.out, byte(e.c>>19))
+		e.c &= 0x7ffff
+		e.ct = 8
+	}
+}
+
+func (e *mqEncoder) flush() []byte {
+	temp := e.c + e.a
+	e.c |= 0xffff
+	if e.c >= temp {
+		e.c -= 0x8000
+	}
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	out := e.out[1:]
+	for len(out) > 0 && out[len(out)-1] == 0xff {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// rawEncoder writes the raw bits of bypassed coding passes.
+type rawEncoder struct {
+	out []byte
+	ct  int
+}
+
+func (e *rawEncoder) encodeBit(bit int) {
+	if e.ct == 0 {
+		e.ct = 8
+		if n := len(e.out); n > 0 && e.out[n-1] == 0xff {
+			e.ct = 7
+		}
+		e.out = append(e.out, 0)
+	}
+	e.ct--
+	e.out[len(e.out)-1] |= byte(bit) << uint(e.ct)
+}
+
+// blockEncoder encodes a code-block, mirroring the coding passes of blockDecoder.
+type blockEncoder struct {
+	*blockDecoder
+	mags []int32
+	neg  []bool
+	mq   *mqEncoder
+	raw  *rawEncoder
+}
+
+func (e *blockEncoder) bit(x, y int) int {
+	return int(e.mags[y*e.w+x]>>uint(e.plane)) & 1
+}
+
+func (e *blockEncoder) encodeBit(cx, bit int) {
+	if e.useRaw {
+		e.raw.encodeBit(bit)
+	} else {
+		e.mq.encodeBit(e.cx, cx, bit)
+	}
+}
+
+func (e *blockEncoder) encodeSign(x, y int) int {
+	sign := 0
+	if e.neg[y*e.w+x] {
+		sign = 1
+	}
+	if e.useRaw {
+		e.raw.encodeBit(sign)
+	} else {
+		cx, xor := e.signContext((y+1)*e.stride+x+1, y)
+		e.mq.encodeBit(e.cx, cx, sign^xor)
+	}
+	return sign
+}
+
+func (e *blockEncoder) significancePass() {
+	for y0 := 0; y0 < e.h; y0 += 4 {
+		for x := 0; x < e.w; x++ {
+			for y := y0; y < y0+4 && y < e.h; y++ {
+				i := (y+1)*e.stride + x + 1
+				if e.flags[i]&flagSignificant != 0 {
+					continue
+				}
+				cx := e.zeroContext(i, y)
+				if cx == 0 {
+					continue
+				}
+				e.flags[i] |= flagVisited
+				b := e.bit(x, y)
+				e.encodeBit(ctxZeroCoding+cx, b)
+				if b == 1 {
+					e.setSignificant(x, y, e.encodeSign(x, y))
+				}
+			}
+		}
+	}
+}
+
+func (e *blockEncoder) refinementPass() {
+	for y0 := 0; y0 < e.h; y0 += 4 {
+		for x := 0; x < e.w; x++ {
+			for y := y0; y < y0+4 && y < e.h; y++ {
+				i := (y+1)*e.stride + x + 1
+				f := e.flags[i]
+				if f&flagSignificant == 0 || f&flagVisited != 0 {
+					continue
+				}
+				cx := ctxRefinement + 2
+				if f&flagRefined == 0 {
+					cx = ctxRefinement
+					if h, v, dg := e.neighbours(i, y); h+v+dg > 0 {
+						cx++
+					}
+				}
+				e.encodeBit(cx, e.bit(x, y))
+				e.flags[i] |= flagRefined
+			}
+		}
+	}
+}
+
+func (e *blockEncoder) cleanupPass() {
+	for y0 := 0; y0 < e.h; y0 += 4 {
+		for x := 0; x < e.w; x++ {
+			y := y0
+			if y0+4 <= e.h && e.runLengthMode(x, y0) {
+				r := 0
+				for r < 4 && e.bit(x, y0+r) == 0 {
+					r++
+				}
+				if r == 4 {
+					e.encodeBit(ctxRunLength, 0)
+					continue
+				}
+				e.encodeBit(ctxRunLength, 1)
+				e.encodeBit(ctxUniform, r>>1)
+				e.encodeBit(ctxUniform, r&1)
+				y = y0 + r
+				e.setSignificant(x, y, e.encodeSign(x, y))
+				y++
+			}
+			for ; y < y0+4 && y < e.h; y++ {
+				i := (y+1)*e.stride + x + 1
+				if e.flags[i]&(flagSignificant|flagVisited) != 0 {
+					continue
+				}
+				b := e.bit(x, y)
+				e.encodeBit(ctxZeroCoding+e.zeroContext(i, y), b)
+				if b == 1 {
+					e.setSignificant(x, y, e.encodeSign(x, y))
+				}
+			}
+		}
+	}
+	for i := range e.flags {
+		e.flags[i] &^= flagVisited
+	}
+	if e.cbStyle&cbSegmentation != 0 {
+		for _, b := range []int{1, 0, 1, 0} {
+			e.encodeBit(ctxUniform, b)
+		}
+	}
+}
+
+// encodeBlock encodes the quantization indices `q` of a `w` by `h` code-block and returns its
+// codeword segments and the number of coded bit-planes.
+func encodeBlock(q []int32, w, h, band int, cbStyle byte) ([]*segment, int) {
+	e := &blockEncoder{
+		blockDecoder: newBlockDecoder(w, h, band, cbStyle),
+		mags:         make([]int32, len(q)),
+		neg:          make([]bool, len(q)),
+	}
+	var max int32
+	for i, v := range q {
+		if v < 0 {
+			e.neg[i] = true
+			v = -v
+		}
+		e.mags[i] = v
+		if v > max {
+			max = v
+		}
+	}
+	planes := 0
+	for max > 0 {
+		planes++
+		max >>= 1
+	}
+	if planes == 0 {
+		return nil, 0
+	}
+
+	cb := &codeblock{}
+	var cur *segment
+	terminate := func() {
+		if cur == nil {
+			return
+		}
+		if e.useRaw {
+			cur.data = e.raw.out
+		} else {
+			cur.data = e.mq.flush()
+		}
+	}
+	for k := 0; k < 1+3*(planes-1); k++ {
+		seg := cb.lastSegment(cbStyle)
+		if seg != cur {
+			terminate()
+			cur = seg
+			e.useRaw = cbStyle&cbBypass != 0 && k >= 10 && passType(k) != passCleanup
+			if e.useRaw {
+				e.raw = &rawEncoder{}
+			} else {
+				e.mq = newMQEncoder()
+			}
+		}
+		e.plane = planes - 1 - (k+2)/3
+		switch passType(k) {
+		case passSignificance:
+			e.significancePass()
+		case passRefinement:
+			e.refinementPass()
+		case passCleanup:
+			e.cleanupPass()
+		}
+		if cbStyle&cbReset != 0 {
+			e.resetContexts()
+		}
+		seg.passes++
+		cb.passes++
+	}
+	terminate()
+	return cb.segments, planes
+}
+
+// analyze53 applies the reversible 5-3 analysis filter to `x` in place (F.4.8.1).
+func analyze53(x []float32, parity int) {
+	n := len(x)
+	if n == 1 {
+		if parity == 1 {
+			x[0] *= 2
+		}
+		return
+	}
+	for i := 1 - parity; i < n; i += 2 {
+		x[i] -= float32(math.Floor(float64(x[mirror(i-1, n)]+x[mirror(i+1, n)]) / 2))
+	}
+	for i := parity; i < n; i += 2 {
+		x[i] += float32(math.Floor(float64(x[mirror(i-1, n)]+x[mirror(i+1, n)]+2) / 4))
+	}
+}
+
+// analyze97 applies the irreversible 9-7 analysis filter to `x` in place (F.4.8.2).
+func analyze97(x []float32, parity int) {
+	n := len(x)
+	if n == 1 {
+		if parity == 1 {
+			x[0] *= 2
+		}
+		return
+	}
+	even, odd := parity, 1-parity
+	lift := func(start int, c float32) {
+		for i := start; i < n; i += 2 {
+			x[i] += c * (x[mirror(i-1, n)] + x[mirror(i+1, n)])
+		}
+	}
+	lift(odd, dwtAlpha)
+	lift(even, dwtBeta)
+	lift(odd, dwtGamma)
+	lift(even, dwtDelta)
+	for i := even; i < n; i += 2 {
+		x[i] *= 1 / dwtK
+	}
+	for i := odd; i < n; i += 2 {
+		x[i] *= dwtK
+	}
+}
+
+// forwardDWT decomposes the tile-component samples `samples` into the coefficients of its
+// subbands.
+func (tc *tileComponent) forwardDWT(samples []float32) {
+	filter := analyze97
+	if tc.style.reversible {
+		filter = analyze53
+	}
+	cur := samples
+	for r := len(tc.resolutions) - 1; r > 0; r-- {
+		res := tc.resolutions[r]
+		w, h := res.x1-res.x0, res.y1-res.y0
+		col := make([]float32, h)
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				col[y] = cur[y*w+x]
+			}
+			filter(col, res.y0&1)
+			for y := 0; y < h; y++ {
+				cur[y*w+x] = col[y]
+			}
+		}
+		for y := 0; y < h; y++ {
+			filter(cur[y*w:(y+1)*w], res.x0&1)
+		}
+
+		lx, ly := res.x0&1, res.y0&1
+		extract := func(bw, bh, ox, oy int) []float32 {
+			out := make([]float32, bw*bh)
+			for y := 0; y < bh; y++ {
+				for x := 0; x < bw; x++ {
+					out[y*bw+x] = cur[(2*y+oy)*w+2*x+ox]
+				}
+			}
+			return out
+		}
+		for _, b := range res.bands {
+			ox, oy := lx, ly
+			if b.kind == bandHL || b.kind == bandHH {
+				ox = 1 - lx
+			}
+			if b.kind == bandLH || b.kind == bandHH {
+				oy = 1 - ly
+			}
+			b.coeffs = extract(b.x1-b.x0, b.y1-b.y0, ox, oy)
+		}
+		prev := tc.resolutions[r-1]
+		cur = extract(prev.x1-prev.x0, prev.y1-prev.y0, lx, ly)
+	}
+	tc.resolutions[0].bands[0].coeffs = cur
+}
+
+// tagTreeEncoder encodes the values of a tag tree.
+type tagTreeEncoder struct {
+	*tagTree
+	known []bool
+}
+
+func newTagTreeEncoder(w, h int, values []int) *tagTreeEncoder {
+	t := &tagTreeEncoder{tagTree: newTagTree(w, h)}
+	t.known = make([]bool, len(t.nodes))
+	for i := range t.nodes {
+		t.nodes[i].value = tagTreeUnknown
+	}
+	for i, v := range values {
+		for n := i; n >= 0; n = t.nodes[n].parent {
+			if v < t.nodes[n].value {
+				t.nodes[n].value = v
+			}
+		}
+	}
+	return t
+}
+
+func (t *tagTreeEncoder) encode(w *bitWriter, i, threshold int) {
+	var path []int
+	for n := i; n >= 0; n = t.nodes[n].parent {
+		path = append(path, n)
+	}
+	low := 0
+	for k := len(path) - 1; k >= 0; k-- {
+		n := path[k]
+		node := &t.nodes[n]
+		if low > node.low {
+			node.low = low
+		} else {
+			low = node.low
+		}
+		for low < threshold {
+			if low >= node.value {
+				if !t.known[n] {
+					w.writeBit(1)
+					t.known[n] = true
+				}
+				break
+			}
+			w.writeBit(0)
+			low++
+		}
+		node.low = low
+	}
+}
+
+// bitWriter writes packet header bits with bit stuffing.
+type bitWriter struct {
+	out []byte
+	ct  int
+}
+
+func (w *bitWriter) writeBit(bit int) {
+	if w.ct == 0 {
+		w.ct = 8
+		if n := len(w.out); n > 0 && w.out[n-1] == 0xff {
+			w.ct = 7
+		}
+		w.out = append(w.out, 0)
+	}
+	w.ct--
+	w.out[len(w.out)-1] |= byte(bit) << uint(w.ct)
+}
+
+func (w *bitWriter) writeBits(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.writeBit(v >> uint(i) & 1)
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if n := len(w.out); n > 0 && w.out[n-1] == 0xff {
+		w.out = append(w.out, 0)
+	}
+	return w.out
+}
+
+func writeNumPasses(w *bitWriter, n int) {
+	switch {
+	case n == 1:
+		w.writeBit(0)
+	case n == 2:
+		w.writeBits(2, 2)
+	case n <= 5:
+		w.writeBits(0xc|(n-3), 4)
+	case n <= 36:
+		w.writeBits(0x1e0|(n-6), 9)
+	default:
+		w.writeBits(0xff80|(n-37), 16)
+	}
+}
+
+// testParams holds the coding parameters of a test codestream.
+type testParams struct {
+	width, height  int
+	x0, y0         int
+	tileW, tileH   int
+	comps          int
+	precision      int
+	levels         int
+	xcb, ycb       int
+	cbStyle        byte
+	progression    int
+	layers         int
+	mct            bool
+	reversible     bool
+	precincts      []precinctSize
+	sop, eph       bool
+	splitTileParts bool
+}
+
+// encodedBlock holds the encoder state of a code-block.
+type encodedBlock struct {
+	segments   []*segment
+	zeroPlanes int
+	layerEnd   []int // Index of the first coding pass after each layer.
+	sentBytes  []int // Number of bytes of each segment sent in previous layers.
+	included   bool
+	lblock     int
+}
+
+// encodeTest encodes the image `samples` with one plane of unsigned samples per component and
+// returns the codestream.
+func encodeTest(t *testing.T, p testParams, samples [][]int32) []byte {
+	var header bytes.Buffer
+	u16 := func(v int) { binary.Write(&header, binary.BigEndian, uint16(v)) }
+	u32 := func(v int) { binary.Write(&header, binary.BigEndian, uint32(v)) }
+
+	u16(markerSOC)
+	u16(markerSIZ)
+	u16(38 + 3*p.comps)
+	u16(0)
+	u32(p.x0 + p.width)
+	u32(p.y0 + p.height)
+	u32(p.x0)
+	u32(p.y0)
+	u32(p.tileW)
+	u32(p.tileH)
+	u32(0)
+	u32(0)
+	u16(p.comps)
+	for c := 0; c < p.comps; c++ {
+		header.Write([]byte{byte(p.precision - 1), 1, 1})
+	}
+
+	u16(markerCOD)
+	u16(12 + len(p.precincts))
+	scod := 0
+	if p.precincts != nil {
+		scod |= 1
+	}
+	if p.sop {
+		scod |= 2
+	}
+	if p.eph {
+		scod |= 4
+	}
+	transform := 0
+	if p.reversible {
+		transform = 1
+	}
+	header.Write([]byte{byte(scod), byte(p.progression)})
+	u16(p.layers)
+	header.Write([]byte{b2b(p.mct), byte(p.levels), byte(p.xcb - 2), byte(p.ycb - 2), p.cbStyle, byte(transform)})
+	for _, ps := range p.precincts {
+		header.WriteByte(byte(ps.ppy<<4 | ps.ppx))
+	}
+
+	numBands := 1 + 3*p.levels
+	gain := func(b int) int {
+		if b == 0 {
+			return 0
+		}
+		return []int{1, 1, 2}[(b-1)%3]
+	}
+	const guardBits = 3
+	u16(markerQCD)
+	if p.reversible {
+		u16(3 + numBands)
+		header.WriteByte(guardBits << 5)
+		for b := 0; b < numBands; b++ {
+			header.WriteByte(byte((p.precision + gain(b) + 1) << 3))
+		}
+	} else {
+		u16(3 + 2*numBands)
+		header.WriteByte(guardBits<<5 | quantExpanded)
+		for b := 0; b < numBands; b++ {
+			u16((p.precision + gain(b) + 3) << 11)
+		}
+	}
+
+	main := append([]byte(nil), header.Bytes()...)
+	cs, err := parseCodestream(append(main, 0xff, 0xd9), false)
+	if err != nil {
+		t.Fatalf("Invalid test header: %v", err)
+	}
+	out := main
+
+	numX, numY := cs.siz.numTiles()
+	for index := 0; index < numX*numY; index++ {
+		params := &tileParams{main: cs.main, tile: newCodingParams()}
+		tile := newTile(cs, index, params)
+
+		// Component samples.
+		compSamples := make([][]float32, p.comps)
+		for c, tc := range tile.comps {
+			tw := tc.x1 - tc.x0
+			s := make([]float32, tw*(tc.y1-tc.y0))
+			for y := tc.y0; y < tc.y1; y++ {
+				for x := tc.x0; x < tc.x1; x++ {
+					v := samples[c][(y-p.y0)*p.width+x-p.x0] - 1<<uint(p.precision-1)
+					s[(y-tc.y0)*tw+x-tc.x0] = float32(v)
+				}
+			}
+			compSamples[c] = s
+		}
+		if p.mct {
+			a, b, c := compSamples[0], compSamples[1], compSamples[2]
+			for i := range a {
+				r, g, bl := a[i], b[i], c[i]
+				if p.reversible {
+					a[i] = float32(math.Floor(float64(r+2*g+bl) / 4))
+					b[i] = bl - g
+					c[i] = r - g
+				} else {
+					a[i] = 0.299*r + 0.587*g + 0.114*bl
+					b[i] = -0.16875*r - 0.33126*g + 0.5*bl
+					c[i] = 0.5*r - 0.41869*g - 0.08131*bl
+				}
+			}
+		}
+
+		// Code-blocks.
+		blocks := map[*codeblock]*encodedBlock{}
+		for c, tc := range tile.comps {
+			tc.forwardDWT(compSamples[c])
+			for _, res := range tc.resolutions {
+				for _, band := range res.bands {
+					step := tc.quant.step(band.index, band.level, tc.style.levels)
+					mb := tc.quant.guardBits + step.exp - 1
+					delta := 1.0
+					if !p.reversible {
+						g := map[int]int{bandLL: 0, bandHL: 1, bandLH: 1, bandHH: 2}[band.kind]
+						delta = math.Ldexp(1, p.precision+g-step.exp)
+					}
+					bw := band.x1 - band.x0
+					for _, cb := range band.blocks {
+						w, h := cb.x1-cb.x0, cb.y1-cb.y0
+						q := make([]int32, w*h)
+						for y := 0; y < h; y++ {
+							for x := 0; x < w; x++ {
+								v := float64(band.coeffs[(cb.y0-band.y0+y)*bw+cb.x0-band.x0+x]) / delta
+								if v < 0 {
+									q[y*w+x] = -int32(math.Floor(-v))
+								} else {
+									q[y*w+x] = int32(math.Floor(v))
+								}
+							}
+						}
+						segments, planes := encodeBlock(q, w, h, band.kind, p.cbStyle)
+						if planes > mb {
+							t.Fatalf("Too many bit-planes: %d > %d", planes, mb)
+						}
+						eb := &encodedBlock{segments: segments, zeroPlanes: mb - planes, lblock: 3}
+						passes := 0
+						if planes > 0 {
+							passes = 1 + 3*(planes-1)
+						}
+						for l := 0; l < p.layers; l++ {
+							eb.layerEnd = append(eb.layerEnd, passes*(l+1)/p.layers)
+						}
+						eb.sentBytes = make([]int, len(segments))
+						blocks[cb] = eb
+					}
+				}
+			}
+		}
+
+		// Packets.
+		type precinctState struct {
+			inclusion, zeroPlanes []*tagTreeEncoder
+		}
+		states := map[*precinct]*precinctState{}
+		var data []byte
+		for n, pk := range tile.packets(1 << 30) {
+			tc := tile.comps[pk.comp]
+			prec := tc.resolutions[pk.res].precincts[pk.prec]
+			st, ok := states[prec]
+			if !ok {
+				st = &precinctState{}
+				for _, pb := range prec.bands {
+					var incl, zero []int
+					for _, cb := range pb.blocks {
+						eb := blocks[cb]
+						first := p.layers
+						for l := p.layers - 1; l >= 0; l-- {
+							start := 0
+							if l > 0 {
+								start = eb.layerEnd[l-1]
+							}
+							if eb.layerEnd[l] > start {
+								first = l
+							}
+						}
+						incl = append(incl, first)
+						zero = append(zero, eb.zeroPlanes)
+					}
+					st.inclusion = append(st.inclusion, newTagTreeEncoder(pb.cbw, pb.cbh, incl))
+					st.zeroPlanes = append(st.zeroPlanes, newTagTreeEncoder(pb.cbw, pb.cbh, zero))
+				}
+				states[prec] = st
+			}
+
+			if p.sop {
+				data = append(data, 0xff, 0x91, 0, 4, byte(n>>8), byte(n))
+			}
+			w := &bitWriter{}
+			var body []byte
+			w.writeBit(1)
+			for b, pb := range prec.bands {
+				for i, cb := range pb.blocks {
+					eb := blocks[cb]
+					start := 0
+					if pk.layer > 0 {
+						start = eb.layerEnd[pk.layer-1]
+					}
+					end := eb.layerEnd[pk.layer]
+					if !eb.included {
+						st.inclusion[b].encode(w, i, pk.layer+1)
+					} else {
+						w.writeBits(b2i(end > start), 1)
+					}
+					if end == start {
+						continue
+					}
+					if !eb.included {
+						st.zeroPlanes[b].encode(w, i, eb.zeroPlanes+1)
+						eb.included = true
+					}
+					writeNumPasses(w, end-start)
+
+					// Segment contributions.
+					type contribution struct{ passes, length int }
+					var contribs []contribution
+					for s, seg := range eb.segments {
+						lo, hi := maxInt(seg.start, start), minInt(seg.start+seg.passes, end)
+						if lo >= hi {
+							continue
+						}
+						cut := len(seg.data)
+						if hi < seg.start+seg.passes {
+							cut = len(seg.data) * (hi - seg.start) / seg.passes
+						}
+						contribs = append(contribs, contribution{hi - lo, cut - eb.sentBytes[s]})
+						body = append(body, seg.data[eb.sentBytes[s]:cut]...)
+						eb.sentBytes[s] = cut
+					}
+					inc := 0
+					for _, c := range contribs {
+						bits := 0
+						for c.length>>uint(bits) > 0 {
+							bits++
+						}
+						inc = maxInt(inc, bits-floorLog2(c.passes)-eb.lblock)
+					}
+					for k := 0; k < inc; k++ {
+						w.writeBit(1)
+					}
+					w.writeBit(0)
+					eb.lblock += inc
+					for _, c := range contribs {
+						w.writeBits(c.length, eb.lblock+floorLog2(c.passes))
+					}
+				}
+			}
+			data = append(data, w.bytes()...)
+			if p.eph {
+				data = append(data, 0xff, 0x92)
+			}
+			data = append(data, body...)
+		}
+
+		// Tile-parts.
+		parts := [][]byte{data}
+		if p.splitTileParts {
+			parts = [][]byte{data[:len(data)/2], data[len(data)/2:]}
+		}
+		for i, part := range parts {
+			var sot bytes.Buffer
+			binary.Write(&sot, binary.BigEndian, []uint16{markerSOT, 10, uint16(index)})
+			binary.Write(&sot, binary.BigEndian, uint32(14+len(part)))
+			sot.Write([]byte{byte(i), byte(len(parts))})
+			binary.Write(&sot, binary.BigEndian, uint16(markerSOD))
+			out = append(out, sot.Bytes()...)
+			out = append(out, part...)
+		}
+	}
+	return append(out, 0xff, 0xd9)
+}
+
+func b2b(v bool) byte {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func b2i(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// testSamples returns a test image with `comps` planes of `precision` bit samples.
+func testSamples(w, h, comps, precision int) [][]int32 {
+	max := int32(1)<<uint(precision) - 1
+	seed := uint32(1)
+	planes := make([][]int32, comps)
+	for c := range planes {
+		planes[c] = make([]int32, w*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				seed = seed*1103515245 + 12345
+				v := int32((x*7+y*5+c*40)%256) + int32(seed>>28) - 8
+				v = v * max / 255
+				if v < 0 {
+					v = 0
+				} else if v > max {
+					v = max
+				}
+				planes[c][y*w+x] = v
+			}
+		}
+	}
+	return planes
+}
+
+// checkImage checks that the decoded image `img` matches `samples` within `tolerance`.
+func checkImage(t *testing.T, img *Image, samples [][]int32, w, h, tolerance int) {
+	if img.Width != w || img.Height != h || img.ColorComponents != len(samples) {
+		t.Fatalf("Wrong image size %dx%dx%d", img.Width, img.Height, img.ColorComponents)
+	}
+	n := len(samples)
+	maxErr := 0
+	for c := range samples {
+		for i, v := range samples[c] {
+			d := int(img.Data[i*n+c]) - int(v)
+			if d < 0 {
+				d = -d
+			}
+			if d > maxErr {
+				maxErr = d
+			}
+		}
+	}
+	if maxErr > tolerance {
+		t.Errorf("Maximum sample error %d > %d", maxErr, tolerance)
+	}
+}
+
+func TestDecodeReversible(t *testing.T) {
+	cases := map[string]testParams{
+		"single tile": {
+			width: 37, height: 29, x0: 1, y0: 3, tileW: 64, tileH: 64,
+			comps: 1, precision: 8, levels: 3, xcb: 3, ycb: 3,
+			progression: progressionLRCP, layers: 1, reversible: true,
+		},
+		"tiles and layers": {
+			width: 40, height: 35, x0: 3, y0: 2, tileW: 16, tileH: 13,
+			comps: 3, precision: 8, levels: 2, xcb: 3, ycb: 2,
+			cbStyle:     cbTermAll | cbReset | cbSegmentation,
+			progression: progressionRLCP, layers: 2, mct: true, reversible: true,
+			sop: true, eph: true, splitTileParts: true,
+		},
+		"bypass": {
+			width: 33, height: 20, tileW: 33, tileH: 20,
+			comps: 1, precision: 8, levels: 1, xcb: 4, ycb: 4,
+			cbStyle:     cbBypass | cbCausal,
+			progression: progressionLRCP, layers: 3, reversible: true,
+		},
+		"no decomposition": {
+			width: 9, height: 7, x0: 1, y0: 1, tileW: 9, tileH: 7,
+			comps: 2, precision: 8, levels: 0, xcb: 2, ycb: 2,
+			progression: progressionLRCP, layers: 1, reversible: true,
+		},
+	}
+	for _, progression := range []int{progressionRPCL, progressionPCRL, progressionCPRL} {
+		cases[[]string{"RPCL", "PCRL", "CPRL"}[progression-progressionRPCL]] = testParams{
+			width: 45, height: 38, x0: 5, y0: 1, tileW: 32, tileH: 24,
+			comps: 3, precision: 8, levels: 3, xcb: 2, ycb: 3,
+			precincts:   []precinctSize{{2, 2}, {3, 3}, {3, 4}, {4, 3}},
+			progression: progression, layers: 2, mct: true, reversible: true,
+		}
+	}
+
+	for name, p := range cases {
+		t.Run(name, func(t *testing.T) {
+			samples := testSamples(p.width, p.height, p.comps, p.precision)
+			img, err := Decode(encodeTest(t, p, samples))
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if p.comps == 2 {
+				// Two component raw codestreams are grey images with an alpha channel.
+				checkImage(t, img, samples[:1], p.width, p.height, 0)
+				for i, v := range samples[1] {
+					if int32(img.Alpha[i]) != v {
+						t.Fatalf("Wrong alpha sample %d", i)
+					}
+				}
+				return
+			}
+			checkImage(t, img, samples, p.width, p.height, 0)
+		})
+	}
+}
+
+func TestDecodeIrreversible(t *testing.T) {
+	p := testParams{
+		width: 50, height: 41, x0: 2, y0: 5, tileW: 32, tileH: 32,
+		comps: 3, precision: 8, levels: 3, xcb: 4, ycb: 4,
+		progression: progressionLRCP, layers: 1, mct: true,
+	}
+	samples := testSamples(p.width, p.height, p.comps, p.precision)
+	img, err := Decode(encodeTest(t, p, samples))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	checkImage(t, img, samples, p.width, p.height, 2)
+}
+
+func TestDecodePrecision(t *testing.T) {
+	p := testParams{
+		width: 20, height: 10, tileW: 20, tileH: 10,
+		comps: 1, precision: 12, levels: 2, xcb: 3, ycb: 3,
+		progression: progressionLRCP, layers: 1, reversible: true,
+	}
+	samples := testSamples(p.width, p.height, p.comps, p.precision)
+	img, err := Decode(encodeTest(t, p, samples))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	for i, v := range samples[0] {
+		if int32(img.Data[i]) != v>>4 {
+			t.Fatalf("Wrong sample %d: %d != %d", i, img.Data[i], v>>4)
+		}
+	}
+}
+
+// makeBox returns a JP2 box of type `kind`.
+func makeBox(kind string, data []byte) []byte {
+	b := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint32(b, uint32(8+len(data)))
+	copy(b[4:], kind)
+	return append(b, data...)
+}
+
+// makeJP2 wraps the codestream `cs` in a JP2 file with the header boxes `boxes`.
+func makeJP2(cs []byte, boxes ...[]byte) []byte {
+	var header []byte
+	for _, b := range boxes {
+		header = append(header, b...)
+	}
+	var out []byte
+	out = append(out, makeBox("jP  ", jp2Signature)...)
+	out = append(out, makeBox("ftyp", []byte("jp2 \x00\x00\x00\x00jp2 "))...)
+	out = append(out, makeBox("jp2h", header)...)
+	return append(out, makeBox("jp2c", cs)...)
+}
+
+func TestDecodeJP2(t *testing.T) {
+	p := testParams{
+		width: 12, height: 10, tileW: 12, tileH: 10,
+		comps: 2, precision: 8, levels: 1, xcb: 3, ycb: 3,
+		progression: progressionLRCP, layers: 1, reversible: true,
+	}
+	samples := testSamples(p.width, p.height, p.comps, p.precision)
+	cs := encodeTest(t, p, samples)
+
+	// The alpha channel is the first component.
+	colr := []byte{1, 0, 0, 0, 0, 0, enumGray}
+	cdef := []byte{0, 2, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1}
+	data := makeJP2(cs, makeBox("colr", colr), makeBox("cdef", cdef))
+
+	cfg, err := DecodeConfig(data)
+	if err != nil {
+		t.Fatalf("DecodeConfig failed: %v", err)
+	}
+	if cfg.Width != 12 || cfg.Height != 10 || cfg.ColorComponents != 1 || !cfg.HasAlpha {
+		t.Fatalf("Wrong config %+v", cfg)
+	}
+	img, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	checkImage(t, img, samples[1:], p.width, p.height, 0)
+	for i, v := range samples[0] {
+		if int32(img.Alpha[i]) != v {
+			t.Fatalf("Wrong alpha sample %d", i)
+		}
+	}
+}
+
+func TestDecodePalette(t *testing.T) {
+	p := testParams{
+		width: 8, height: 8, tileW: 8, tileH: 8,
+		comps: 1, precision: 2, levels: 1, xcb: 2, ycb: 2,
+		progression: progressionLRCP, layers: 1, reversible: true,
+	}
+	samples := testSamples(p.width, p.height, p.comps, p.precision)
+	cs := encodeTest(t, p, samples)
+
+	colors := [][3]byte{{255, 0, 0}, {0, 255, 0}, {0, 0, 255}, {10, 20, 30}}
+	pclr := []byte{0, 4, 3, 7, 7, 7}
+	for _, c := range colors {
+		pclr = append(pclr, c[:]...)
+	}
+	cmap := []byte{0, 0, 1, 0, 0, 0, 1, 1, 0, 0, 1, 2}
+	colr := []byte{1, 0, 0, 0, 0, 0, enumSRGB}
+	img, err := Decode(makeJP2(cs, makeBox("colr", colr), makeBox("pclr", pclr), makeBox("cmap", cmap)))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if img.ColorComponents != 3 || img.Alpha != nil {
+		t.Fatalf("Wrong config %+v", img.Config)
+	}
+	for i, v := range samples[0] {
+		if !bytes.Equal(img.Data[3*i:3*i+3], colors[v][:]) {
+			t.Fatalf("Wrong colour at %d: % x", i, img.Data[3*i:3*i+3])
+		}
+	}
+}
+
+// TestSamplesSubsampled checks the upsampling of a subsampled component whose first sample is to
+// the right of and below the image origin.
+func TestSamplesSubsampled(t *testing.T) {
+	cs := &codestream{siz: siz{
+		width: 5, height: 3, x0: 1, y0: 1,
+		components: []component{{precision: 8, dx: 2, dy: 2}},
+	}}
+	l := &layout{}
+	out, err := l.samples(cs, [][]int32{{10, 20}}, channel{comp: 0, column: -1})
+	if err != nil {
+		t.Fatalf("samples failed: %v", err)
+	}
+	if !bytes.Equal(out, []byte{10, 10, 10, 20, 10, 10, 10, 20}) {
+		t.Fatalf("Wrong samples % x", out)
+	}
+
+	// The plane is shorter than the component.
+	if _, err := l.samples(cs, [][]int32{{10}}, channel{comp: 0, column: -1}); err == nil {
+		t.Fatalf("samples succeeded with a short plane")
+	}
+}
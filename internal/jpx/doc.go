@@ -0,0 +1,16 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package jpx implements a decoder for JPEG 2000 images as described in ITU-T Recommendation
+// T.800 | ISO/IEC 15444-1, as used by the PDF JPXDecode filter (PDF 32000-1:2008, 7.4.9).
+// Both raw codestreams and codestreams embedded in a JP2 file are supported.
+//
+// The decoder handles multiple tiles and tile-parts, all five progression orders, user defined
+// precincts, the code-block coding style options, both wavelet transforms, scalar quantization,
+// the reversible and irreversible component transforms and region of interest coding.
+// The JP2 palette, component mapping, channel definition and colour specification boxes are used
+// to map the codestream components to the colour channels and alpha channel of the image.
+// Progression order changes and packed packet headers are not supported.
+package jpx
@@ -0,0 +1,135 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import "math"
+
+// Lifting parameters of the irreversible 9-7 filter (Table F.4).
+const (
+	dwtAlpha = -1.586134342059924
+	dwtBeta  = -0.052980118572961
+	dwtGamma = 0.882911075530934
+	dwtDelta = 0.443506852043971
+	dwtK     = 1.230174104914001
+)
+
+// inverseDWT reconstructs the samples of the tile-component from the coefficients of its
+// subbands (F.3.1). The samples are returned in raster order.
+func (tc *tileComponent) inverseDWT() []float32 {
+	ll := tc.resolutions[0].bands[0].coeffs
+	for r := 1; r < len(tc.resolutions); r++ {
+		res := tc.resolutions[r]
+		prev := tc.resolutions[r-1]
+		w, h := res.x1-res.x0, res.y1-res.y0
+		out := make([]float32, w*h)
+
+		// 2D_INTERLEAVE (F.3.3). The low-pass samples are at the even reference coordinates.
+		lx, ly := res.x0&1, res.y0&1
+		interleave := func(coeffs []float32, bw, bh, ox, oy int) {
+			for y := 0; y < bh; y++ {
+				row := out[(2*y+oy)*w:]
+				for x := 0; x < bw; x++ {
+					row[2*x+ox] = coeffs[y*bw+x]
+				}
+			}
+		}
+		interleave(ll, prev.x1-prev.x0, prev.y1-prev.y0, lx, ly)
+		for _, b := range res.bands {
+			ox, oy := lx, ly
+			if b.kind == bandHL || b.kind == bandHH {
+				ox = 1 - lx
+			}
+			if b.kind == bandLH || b.kind == bandHH {
+				oy = 1 - ly
+			}
+			interleave(b.coeffs, b.x1-b.x0, b.y1-b.y0, ox, oy)
+		}
+
+		// HOR_SR and VER_SR (F.3.4, F.3.5).
+		filter := synthesize97
+		if tc.style.reversible {
+			filter = synthesize53
+		}
+		for y := 0; y < h; y++ {
+			filter(out[y*w:(y+1)*w], res.x0&1)
+		}
+		col := make([]float32, h)
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				col[y] = out[y*w+x]
+			}
+			filter(col, res.y0&1)
+			for y := 0; y < h; y++ {
+				out[y*w+x] = col[y]
+			}
+		}
+		ll = out
+	}
+	return ll
+}
+
+// mirror returns the index of sample `i` of a signal of length `n` after symmetric extension
+// (F.3.7).
+func mirror(i, n int) int {
+	if n == 1 {
+		return 0
+	}
+	for i < 0 || i >= n {
+		if i < 0 {
+			i = -i
+		}
+		if i >= n {
+			i = 2*(n-1) - i
+		}
+	}
+	return i
+}
+
+// synthesize53 applies the reversible 5-3 synthesis filter to the signal `x` in place (F.3.8.1).
+// `parity` is the parity of the reference coordinate of the first sample.
+func synthesize53(x []float32, parity int) {
+	n := len(x)
+	if n == 1 {
+		if parity == 1 {
+			x[0] = float32(int32(x[0]) / 2)
+		}
+		return
+	}
+	for i := parity; i < n; i += 2 {
+		x[i] -= float32(math.Floor(float64(x[mirror(i-1, n)]+x[mirror(i+1, n)]+2) / 4))
+	}
+	for i := 1 - parity; i < n; i += 2 {
+		x[i] += float32(math.Floor(float64(x[mirror(i-1, n)]+x[mirror(i+1, n)]) / 2))
+	}
+}
+
+// synthesize97 applies the irreversible 9-7 synthesis filter to the signal `x` in place
+// (F.3.8.2). `parity` is the parity of the reference coordinate of the first sample.
+func synthesize97(x []float32, parity int) {
+	n := len(x)
+	if n == 1 {
+		if parity == 1 {
+			x[0] /= 2
+		}
+		return
+	}
+	even, odd := parity, 1-parity
+	for i := even; i < n; i += 2 {
+		x[i] *= dwtK
+	}
+	for i := odd; i < n; i += 2 {
+		x[i] *= 1 / dwtK
+	}
+	lift := func(start int, c float32) {
+		for i := start; i < n; i += 2 {
+			x[i] -= c * (x[mirror(i-1, n)] + x[mirror(i+1, n)])
+		}
+	}
+	lift(even, dwtDelta)
+	lift(odd, dwtGamma)
+	lift(even, dwtBeta)
+	lift(odd, dwtAlpha)
+}
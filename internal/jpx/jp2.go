@@ -0,0 +1,247 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/unidoc/unipdf/v3/common"
+)
+
+// Enumerated colour spaces of the colour specification box (I.5.3.3).
+const (
+	enumCMYK = 12
+	enumSRGB = 16
+	enumGray = 17
+	enumSYCC = 18
+)
+
+// Channel types of the channel definition box (Table I.16).
+const (
+	channelColor          = 0
+	channelOpacity        = 1
+	channelPremultOpacity = 2
+)
+
+// jp2Signature is the contents of the JPEG 2000 signature box.
+var jp2Signature = []byte{0x0d, 0x0a, 0x87, 0x0a}
+
+// palette holds the contents of a palette box (I.5.3.4).
+type palette struct {
+	entries [][]int // Indexed by column, then entry.
+	depths  []int
+	signed  []bool
+}
+
+// componentMapping is an entry of a component mapping box (I.5.3.5).
+type componentMapping struct {
+	comp    int
+	palette bool
+	column  int
+}
+
+// channelDefinition is an entry of a channel definition box (I.5.3.6).
+type channelDefinition struct {
+	channel int
+	kind    int
+	assoc   int
+}
+
+// jp2Header holds the JP2 header boxes that describe how the codestream components are
+// interpreted.
+type jp2Header struct {
+	enumCS     int // Enumerated colour space, 0 if not specified.
+	iccProfile []byte
+	palette    *palette
+	mapping    []componentMapping
+	channels   []channelDefinition
+}
+
+// box is a JP2 box (I.4).
+type box struct {
+	kind string
+	data []byte
+}
+
+// readBoxes splits `data` into boxes.
+func readBoxes(data []byte) ([]box, error) {
+	var boxes []box
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errEOD
+		}
+		length := uint64(binary.BigEndian.Uint32(data))
+		kind := string(data[4:8])
+		header := uint64(8)
+		switch length {
+		case 0:
+			length = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, errEOD
+			}
+			length = binary.BigEndian.Uint64(data[8:])
+			header = 16
+		}
+		if length < header || length > uint64(len(data)) {
+			return nil, errors.New("invalid JP2 box length")
+		}
+		boxes = append(boxes, box{kind: kind, data: data[header:length]})
+		data = data[length:]
+	}
+	return boxes, nil
+}
+
+// readJP2 returns the header and the codestream of the JP2 file `data`.
+func readJP2(data []byte) (*jp2Header, []byte, error) {
+	boxes, err := readBoxes(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	h := &jp2Header{}
+	var codestream []byte
+	for _, b := range boxes {
+		switch b.kind {
+		case "jP  ":
+			if !bytes.Equal(b.data, jp2Signature) {
+				return nil, nil, errors.New("invalid JP2 signature")
+			}
+		case "jp2h":
+			if err := h.parse(b.data); err != nil {
+				return nil, nil, err
+			}
+		case "jp2c":
+			if codestream == nil {
+				codestream = b.data
+			}
+		}
+	}
+	if codestream == nil {
+		return nil, nil, errors.New("missing JPEG 2000 codestream")
+	}
+	return h, codestream, nil
+}
+
+// parse parses the boxes of the JP2 header box `data`.
+func (h *jp2Header) parse(data []byte) error {
+	boxes, err := readBoxes(data)
+	if err != nil {
+		return err
+	}
+	for _, b := range boxes {
+		d := b.data
+		switch b.kind {
+		case "colr":
+			// Only the first colour specification is used.
+			if h.enumCS != 0 || h.iccProfile != nil || len(d) < 3 {
+				continue
+			}
+			switch d[0] {
+			case 1:
+				if len(d) >= 7 {
+					h.enumCS = int(binary.BigEndian.Uint32(d[3:]))
+				}
+			case 2, 3:
+				h.iccProfile = d[3:]
+			default:
+				common.Log.Debug("Unsupported JP2 colour specification method %d", d[0])
+			}
+		case "pclr":
+			p, err := parsePalette(d)
+			if err != nil {
+				return err
+			}
+			h.palette = p
+		case "cmap":
+			for i := 0; i+4 <= len(d); i += 4 {
+				h.mapping = append(h.mapping, componentMapping{
+					comp:    int(binary.BigEndian.Uint16(d[i:])),
+					palette: d[i+2] == 1,
+					column:  int(d[i+3]),
+				})
+			}
+		case "cdef":
+			if len(d) < 2 {
+				return errInvalidHeader
+			}
+			n := int(binary.BigEndian.Uint16(d))
+			if len(d) < 2+6*n {
+				return errInvalidHeader
+			}
+			for i := 0; i < n; i++ {
+				e := d[2+6*i:]
+				h.channels = append(h.channels, channelDefinition{
+					channel: int(binary.BigEndian.Uint16(e)),
+					kind:    int(binary.BigEndian.Uint16(e[2:])),
+					assoc:   int(binary.BigEndian.Uint16(e[4:])),
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// parsePalette parses the contents `d` of a palette box.
+func parsePalette(d []byte) (*palette, error) {
+	if len(d) < 3 {
+		return nil, errInvalidHeader
+	}
+	entries := int(binary.BigEndian.Uint16(d))
+	columns := int(d[2])
+	if entries == 0 || columns == 0 || len(d) < 3+columns {
+		return nil, errInvalidHeader
+	}
+	p := &palette{entries: make([][]int, columns)}
+	sizes := make([]int, columns)
+	rowSize := 0
+	for i := 0; i < columns; i++ {
+		b := d[3+i]
+		p.depths = append(p.depths, int(b&0x7f)+1)
+		p.signed = append(p.signed, b&0x80 != 0)
+		sizes[i] = (p.depths[i] + 7) / 8
+		rowSize += sizes[i]
+		if p.depths[i] > 32 {
+			return nil, errInvalidHeader
+		}
+	}
+	d = d[3+columns:]
+	if len(d) < entries*rowSize {
+		return nil, errInvalidHeader
+	}
+	for i := range p.entries {
+		p.entries[i] = make([]int, entries)
+	}
+	for e := 0; e < entries; e++ {
+		for i := 0; i < columns; i++ {
+			v := 0
+			for _, b := range d[:sizes[i]] {
+				v = v<<8 | int(b)
+			}
+			d = d[sizes[i]:]
+			p.entries[i][e] = v
+		}
+	}
+	return p, nil
+}
+
+// iccComponents returns the number of colour components of the ICC profile `profile`, or 0 if
+// the colour space of the profile is not recognised.
+func iccComponents(profile []byte) int {
+	if len(profile) < 20 {
+		return 0
+	}
+	switch string(profile[16:20]) {
+	case "GRAY":
+		return 1
+	case "RGB ", "Lab ", "YCbr", "XYZ ":
+		return 3
+	case "CMYK":
+		return 4
+	}
+	return 0
+}
@@ -0,0 +1,197 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+// qeEntry is an entry of the probability estimation table of the MQ decoder.
+type qeEntry struct {
+	qe        uint32
+	nmps      byte
+	nlps      byte
+	switchMPS bool
+}
+
+// qeTable is the Qe value and probability estimation state transition table (Table C.2).
+var qeTable = [...]qeEntry{
+	{0x5601, 1, 1, true},
+	{0x3401, 2, 6, false},
+	{0x1801, 3, 9, false},
+	{0x0AC1, 4, 12, false},
+	{0x0521, 5, 29, false},
+	{0x0221, 38, 33, false},
+	{0x5601, 7, 6, true},
+	{0x5401, 8, 14, false},
+	{0x4801, 9, 14, false},
+	{0x3801, 10, 14, false},
+	{0x3001, 11, 17, false},
+	{0x2401, 12, 18, false},
+	{0x1C01, 13, 20, false},
+	{0x1601, 29, 21, false},
+	{0x5601, 15, 14, true},
+	{0x5401, 16, 14, false},
+	{0x5101, 17, 15, false},
+	{0x4801, 18, 16, false},
+	{0x3801, 19, 17, false},
+	{0x3401, 20, 18, false},
+	{0x3001, 21, 19, false},
+	{0x2801, 22, 19, false},
+	{0x2401, 23, 20, false},
+	{0x2201, 24, 21, false},
+	{0x1C01, 25, 22, false},
+	{0x1801, 26, 23, false},
+	{0x1601, 27, 24, false},
+	{0x1401, 28, 25, false},
+	{0x1201, 29, 26, false},
+	{0x1101, 30, 27, false},
+	{0x0AC1, 31, 28, false},
+	{0x09C1, 32, 29, false},
+	{0x08A1, 33, 30, false},
+	{0x0521, 34, 31, false},
+	{0x0441, 35, 32, false},
+	{0x02A1, 36, 33, false},
+	{0x0221, 37, 34, false},
+	{0x0141, 38, 35, false},
+	{0x0111, 39, 36, false},
+	{0x0085, 40, 37, false},
+	{0x0049, 41, 38, false},
+	{0x0025, 42, 39, false},
+	{0x0015, 43, 40, false},
+	{0x0009, 44, 41, false},
+	{0x0005, 45, 42, false},
+	{0x0001, 45, 43, false},
+	{0x5601, 46, 46, false},
+}
+
+// mqDecoder is the MQ arithmetic decoder (Annex C).
+//
+// A context is stored in a byte as the index of its probability estimation state shifted left by
+// one, ORed with its more probable symbol.
+type mqDecoder struct {
+	data []byte
+	pos  int
+	c    uint32
+	a    uint32
+	ct   int
+}
+
+// newMQDecoder returns an MQ decoder for the codeword segment `data` (INITDEC, C.3.5).
+func newMQDecoder(data []byte) *mqDecoder {
+	d := &mqDecoder{data: data}
+	d.c = uint32(d.byteAt(0)) << 16
+	d.byteIn()
+	d.c <<= 7
+	d.ct -= 7
+	d.a = 0x8000
+	return d
+}
+
+// byteAt returns the byte at position `i`. Reading past the end of the segment yields 0xFF bytes.
+func (d *mqDecoder) byteAt(i int) byte {
+	if i >= len(d.data) {
+		return 0xff
+	}
+	return d.data[i]
+}
+
+// byteIn reads the next byte into the C register (BYTEIN, C.3.4).
+func (d *mqDecoder) byteIn() {
+	if d.byteAt(d.pos) == 0xff {
+		if d.byteAt(d.pos+1) > 0x8f {
+			d.c += 0xff00
+			d.ct = 8
+		} else {
+			d.pos++
+			d.c += uint32(d.byteAt(d.pos)) << 9
+			d.ct = 7
+		}
+	} else {
+		d.pos++
+		d.c += uint32(d.byteAt(d.pos)) << 8
+		d.ct = 8
+	}
+}
+
+// decodeBit decodes a bit with the context `cx[i]` (DECODE, C.3.2).
+func (d *mqDecoder) decodeBit(cx []byte, i int) int {
+	state := &qeTable[cx[i]>>1]
+	mps := int(cx[i] & 1)
+	index := cx[i] >> 1
+
+	var bit int
+	d.a -= state.qe
+	if d.c>>16 < state.qe {
+		// LPS_EXCHANGE.
+		if d.a < state.qe {
+			bit = mps
+			index = state.nmps
+		} else {
+			bit = 1 - mps
+			if state.switchMPS {
+				mps = bit
+			}
+			index = state.nlps
+		}
+		d.a = state.qe
+	} else {
+		d.c -= state.qe << 16
+		if d.a&0x8000 != 0 {
+			return mps
+		}
+		// MPS_EXCHANGE.
+		if d.a < state.qe {
+			bit = 1 - mps
+			if state.switchMPS {
+				mps = bit
+			}
+			index = state.nlps
+		} else {
+			bit = mps
+			index = state.nmps
+		}
+	}
+
+	// RENORMD.
+	for {
+		if d.ct == 0 {
+			d.byteIn()
+		}
+		d.a <<= 1
+		d.c <<= 1
+		d.ct--
+		if d.a&0x8000 != 0 {
+			break
+		}
+	}
+	cx[i] = index<<1 | byte(mps)
+	return bit
+}
+
+// rawDecoder reads the raw bits of the coding passes that bypass the MQ coder (D.6).
+// A bit is stuffed after every 0xFF byte.
+type rawDecoder struct {
+	data []byte
+	pos  int
+	buf  byte
+	ct   int
+}
+
+// decodeBit returns the next raw bit. Reading past the end of the segment yields 1 bits.
+func (d *rawDecoder) decodeBit() int {
+	if d.ct == 0 {
+		last := d.buf
+		if d.pos < len(d.data) {
+			d.buf = d.data[d.pos]
+			d.pos++
+		} else {
+			d.buf = 0xff
+		}
+		d.ct = 8
+		if last == 0xff {
+			d.ct = 7
+		}
+	}
+	d.ct--
+	return int(d.buf>>uint(d.ct)) & 1
+}
@@ -0,0 +1,378 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/common"
+)
+
+var errInvalidPacket = errors.New("invalid JPEG 2000 packet header")
+
+// bitReader reads the bits of a packet header. A zero bit is stuffed after every 0xFF byte
+// (B.10.1).
+type bitReader struct {
+	data []byte
+	pos  int
+	buf  byte
+	ct   int
+}
+
+func (r *bitReader) readBit() (int, error) {
+	if r.ct == 0 {
+		if r.pos >= len(r.data) {
+			return 0, errEOD
+		}
+		r.ct = 8
+		if r.buf == 0xff {
+			r.ct = 7
+		}
+		r.buf = r.data[r.pos]
+		r.pos++
+	}
+	r.ct--
+	return int(r.buf>>uint(r.ct)) & 1, nil
+}
+
+func (r *bitReader) readBits(n int) (int, error) {
+	v := 0
+	for i := 0; i < n; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | bit
+	}
+	return v, nil
+}
+
+// align skips to the end of the packet header. If the last byte of the header is 0xFF, the
+// header includes the following byte with the stuffed bit.
+func (r *bitReader) align() {
+	if r.buf == 0xff {
+		r.pos++
+	}
+	r.buf = 0
+	r.ct = 0
+}
+
+// packet identifies a packet by its layer, resolution level, component and precinct.
+type packet struct {
+	layer, res, comp, prec int
+}
+
+// packets returns the first `limit` packets of the tile in the order of its progression order
+// (B.12).
+func (t *tile) packets(limit int) []packet {
+	cod := t.params.cod()
+	maxRes := 0
+	for _, tc := range t.comps {
+		maxRes = maxInt(maxRes, len(tc.resolutions))
+	}
+
+	var packets []packet
+	switch cod.progression {
+	case progressionLRCP:
+		for l := 0; l < cod.layers; l++ {
+			for r := 0; r < maxRes; r++ {
+				for c, tc := range t.comps {
+					if r < len(tc.resolutions) {
+						for p := range tc.resolutions[r].precincts {
+							packets = append(packets, packet{l, r, c, p})
+							if len(packets) == limit {
+								return packets
+							}
+						}
+					}
+				}
+			}
+		}
+		return packets
+	case progressionRLCP:
+		for r := 0; r < maxRes; r++ {
+			for l := 0; l < cod.layers; l++ {
+				for c, tc := range t.comps {
+					if r < len(tc.resolutions) {
+						for p := range tc.resolutions[r].precincts {
+							packets = append(packets, packet{l, r, c, p})
+							if len(packets) == limit {
+								return packets
+							}
+						}
+					}
+				}
+			}
+		}
+		return packets
+	}
+
+	// The position driven progressions visit each precinct at the position of its upper left
+	// corner on the reference grid, or at the tile corner for the first precincts of a tile.
+	type position struct {
+		x, y, res, comp, prec int
+	}
+	var positions []position
+	for c, tc := range t.comps {
+		for r, res := range tc.resolutions {
+			scale := uint(len(tc.resolutions) - 1 - r)
+			for p := range res.precincts {
+				i, j := p%res.numPrecX, p/res.numPrecX
+				x, y := t.x0, t.y0
+				if i > 0 || res.x0%(1<<uint(res.ppx)) == 0 {
+					x = maxInt(((res.x0>>uint(res.ppx)+i)<<uint(res.ppx))<<scale*tc.dx, t.x0)
+				}
+				if j > 0 || res.y0%(1<<uint(res.ppy)) == 0 {
+					y = maxInt(((res.y0>>uint(res.ppy)+j)<<uint(res.ppy))<<scale*tc.dy, t.y0)
+				}
+				positions = append(positions, position{x, y, r, c, p})
+			}
+		}
+	}
+	var less func(a, b position) bool
+	switch cod.progression {
+	case progressionRPCL:
+		less = func(a, b position) bool {
+			if a.res != b.res {
+				return a.res < b.res
+			}
+			if a.y != b.y {
+				return a.y < b.y
+			}
+			if a.x != b.x {
+				return a.x < b.x
+			}
+			return a.comp < b.comp
+		}
+	case progressionPCRL:
+		less = func(a, b position) bool {
+			if a.y != b.y {
+				return a.y < b.y
+			}
+			if a.x != b.x {
+				return a.x < b.x
+			}
+			if a.comp != b.comp {
+				return a.comp < b.comp
+			}
+			return a.res < b.res
+		}
+	default:
+		less = func(a, b position) bool {
+			if a.comp != b.comp {
+				return a.comp < b.comp
+			}
+			if a.y != b.y {
+				return a.y < b.y
+			}
+			if a.x != b.x {
+				return a.x < b.x
+			}
+			return a.res < b.res
+		}
+	}
+	sort.SliceStable(positions, func(i, j int) bool {
+		return less(positions[i], positions[j])
+	})
+	for _, pos := range positions {
+		for l := 0; l < cod.layers; l++ {
+			packets = append(packets, packet{l, pos.res, pos.comp, pos.prec})
+			if len(packets) == limit {
+				return packets
+			}
+		}
+	}
+	return packets
+}
+
+// decodePackets reads the packets of the tile from the tile data `data` and assigns the coding
+// pass data to the code-blocks (B.9, B.10).
+func (t *tile) decodePackets(data []byte) error {
+	cod := t.params.cod()
+	pos := 0
+	// Every packet takes at least one byte, so a tile with many layers or precincts and little
+	// data is not decoded beyond its data.
+	for _, p := range t.packets(len(data)) {
+		if pos >= len(data) {
+			common.Log.Debug("JPEG 2000 tile data truncated")
+			break
+		}
+		tc := t.comps[p.comp]
+		prec := tc.resolutions[p.res].precincts[p.prec]
+		n, err := tc.decodePacket(prec, p.layer, data[pos:], cod.sop, cod.eph)
+		if err != nil {
+			common.Log.Debug("Invalid JPEG 2000 packet: %v", err)
+			break
+		}
+		pos += n
+	}
+	return nil
+}
+
+// decodePacket decodes the packet of layer `layer` of the precinct `prec` at the start of `data`
+// and returns its length.
+func (tc *tileComponent) decodePacket(prec *precinct, layer int, data []byte, sop, eph bool) (int, error) {
+	pos := 0
+	if sop && len(data) >= 6 && data[0] == 0xff && data[1] == byte(markerSOP&0xff) {
+		pos = 6
+	}
+
+	type contribution struct {
+		seg    *segment
+		passes int
+		length int
+	}
+	var contributions []contribution
+
+	r := &bitReader{data: data, pos: pos}
+	present, err := r.readBit()
+	if err != nil {
+		return 0, err
+	}
+	if present == 1 {
+		for _, pb := range prec.bands {
+			if len(pb.blocks) == 0 {
+				continue
+			}
+			if !pb.initialised {
+				pb.inclusion = newTagTree(pb.cbw, pb.cbh)
+				pb.zeroPlanes = newTagTree(pb.cbw, pb.cbh)
+				pb.initialised = true
+			}
+			for i, cb := range pb.blocks {
+				// Code-block inclusion.
+				var included bool
+				if cb.included {
+					bit, err := r.readBit()
+					if err != nil {
+						return 0, err
+					}
+					included = bit == 1
+				} else {
+					if included, err = pb.inclusion.decode(r, i, layer+1); err != nil {
+						return 0, err
+					}
+				}
+				if !included {
+					continue
+				}
+				if !cb.included {
+					if cb.zeroBitPlanes, err = pb.zeroPlanes.decodeValue(r, i); err != nil {
+						return 0, err
+					}
+					cb.included = true
+				}
+
+				passes, err := readNumPasses(r)
+				if err != nil {
+					return 0, err
+				}
+				for {
+					bit, err := r.readBit()
+					if err != nil {
+						return 0, err
+					}
+					if bit == 0 {
+						break
+					}
+					cb.lblock++
+				}
+
+				// The lengths of the codeword segments that the new coding passes contribute to.
+				for passes > 0 {
+					seg := cb.lastSegment(tc.style.cbStyle)
+					n := minInt(passes, seg.maxPasses-seg.passes)
+					bits := cb.lblock + floorLog2(n)
+					if bits > 31 {
+						return 0, errInvalidPacket
+					}
+					length, err := r.readBits(bits)
+					if err != nil {
+						return 0, err
+					}
+					contributions = append(contributions, contribution{seg, n, length})
+					seg.passes += n
+					cb.passes += n
+					passes -= n
+				}
+			}
+		}
+	}
+	r.align()
+	pos = r.pos
+	if eph && pos+2 <= len(data) && data[pos] == 0xff && data[pos+1] == byte(markerEPH&0xff) {
+		pos += 2
+	}
+
+	// The packet body.
+	for _, c := range contributions {
+		end := pos + c.length
+		if end > len(data) {
+			common.Log.Debug("JPEG 2000 packet body truncated")
+			end = len(data)
+		}
+		c.seg.data = append(c.seg.data, data[pos:end]...)
+		pos = end
+	}
+	return pos, nil
+}
+
+// lastSegment returns the codeword segment that the next coding pass of the code-block belongs
+// to, adding a new segment if the last one is complete. The coding passes are split into
+// segments depending on the code-block style `cbStyle` (D.4.1, Table D.9).
+func (cb *codeblock) lastSegment(cbStyle byte) *segment {
+	if n := len(cb.segments); n > 0 {
+		seg := cb.segments[n-1]
+		if seg.passes < seg.maxPasses {
+			return seg
+		}
+	}
+	seg := &segment{start: cb.passes, maxPasses: 1 << 30}
+	switch {
+	case cbStyle&cbTermAll != 0:
+		seg.maxPasses = 1
+	case cbStyle&cbBypass != 0:
+		if seg.start < 10 {
+			seg.maxPasses = 10 - seg.start
+		} else if passType(seg.start) == passSignificance {
+			seg.maxPasses = 2
+		} else {
+			seg.maxPasses = 1
+		}
+	}
+	cb.segments = append(cb.segments, seg)
+	return seg
+}
+
+// readNumPasses reads the number of new coding passes of a code-block (Table B.4).
+func readNumPasses(r *bitReader) (int, error) {
+	if bit, err := r.readBit(); err != nil || bit == 0 {
+		return 1, err
+	}
+	if bit, err := r.readBit(); err != nil || bit == 0 {
+		return 2, err
+	}
+	v, err := r.readBits(2)
+	if err != nil || v < 3 {
+		return 3 + v, err
+	}
+	if v, err = r.readBits(5); err != nil || v < 31 {
+		return 6 + v, err
+	}
+	v, err = r.readBits(7)
+	return 37 + v, err
+}
+
+// floorLog2 returns floor(log2(n)) for positive `n`.
+func floorLog2(n int) int {
+	k := 0
+	for n > 1 {
+		n >>= 1
+		k++
+	}
+	return k
+}
@@ -0,0 +1,375 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+// Coding pass types (D.3).
+const (
+	passSignificance = iota
+	passRefinement
+	passCleanup
+)
+
+// passType returns the type of the coding pass with index `k`. The first coding pass of a
+// code-block is a cleanup pass.
+func passType(k int) int {
+	if k == 0 {
+		return passCleanup
+	}
+	return (k - 1) % 3
+}
+
+// Contexts of the code-block decoder (Tables D.1 to D.5).
+const (
+	ctxZeroCoding = 0  // 9 contexts.
+	ctxSign       = 9  // 5 contexts.
+	ctxRefinement = 14 // 3 contexts.
+	ctxRunLength  = 17
+	ctxUniform    = 18
+	numContexts   = 19
+)
+
+// Sample state flags of the code-block decoder.
+const (
+	flagSignificant = 1 << iota
+	flagNegative
+	flagVisited
+	flagRefined
+)
+
+// signContexts maps the horizontal and vertical sign contributions, each offset by one, to the
+// sign coding context and the XOR bit (Table D.3).
+var signContexts = [3][3]struct {
+	cx  int
+	xor int
+}{
+	{{13, 1}, {12, 1}, {11, 1}},
+	{{10, 1}, {9, 0}, {10, 0}},
+	{{11, 0}, {12, 0}, {13, 0}},
+}
+
+// blockDecoder decodes the coding passes of a code-block (Annex D).
+type blockDecoder struct {
+	w, h    int
+	stride  int
+	flags   []byte // Sample states with a border of one sample.
+	mag     []int32
+	lsb     []int8 // The least significant bit-plane that has been decoded for each sample.
+	band    int
+	cbStyle byte
+	cx      []byte
+	mq      *mqDecoder
+	raw     *rawDecoder
+	useRaw  bool
+	plane   int
+}
+
+// newBlockDecoder returns a decoder for a `w` by `h` code-block of a subband of orientation
+// `band`.
+func newBlockDecoder(w, h, band int, cbStyle byte) *blockDecoder {
+	d := &blockDecoder{
+		w:       w,
+		h:       h,
+		stride:  w + 2,
+		flags:   make([]byte, (w+2)*(h+2)),
+		mag:     make([]int32, w*h),
+		lsb:     make([]int8, w*h),
+		band:    band,
+		cbStyle: cbStyle,
+		cx:      make([]byte, numContexts),
+	}
+	d.resetContexts()
+	return d
+}
+
+// resetContexts sets the contexts to their initial states (Table D.7).
+func (d *blockDecoder) resetContexts() {
+	for i := range d.cx {
+		d.cx[i] = 0
+	}
+	d.cx[ctxZeroCoding] = 4 << 1
+	d.cx[ctxRunLength] = 3 << 1
+	d.cx[ctxUniform] = 46 << 1
+}
+
+// decode decodes the coding passes in `segments`, whose first bit-plane is `planes`-1.
+func (d *blockDecoder) decode(segments []*segment, planes int) {
+	k := 0
+	for _, seg := range segments {
+		for i := 0; i < seg.passes; i, k = i+1, k+1 {
+			kind := passType(k)
+			d.plane = planes - 1 - (k+2)/3
+			if d.plane < 0 {
+				return
+			}
+			if i == 0 {
+				d.useRaw = d.cbStyle&cbBypass != 0 && k >= 10 && kind != passCleanup
+				if d.useRaw {
+					d.raw = &rawDecoder{data: seg.data}
+				} else {
+					d.mq = newMQDecoder(seg.data)
+				}
+			}
+			switch kind {
+			case passSignificance:
+				d.significancePass()
+			case passRefinement:
+				d.refinementPass()
+			case passCleanup:
+				d.cleanupPass()
+			}
+			if d.cbStyle&cbReset != 0 {
+				d.resetContexts()
+			}
+		}
+	}
+}
+
+// neighbours returns the numbers of significant horizontal, vertical and diagonal neighbours of
+// the sample at `i` (index in flags) in row `y`.
+func (d *blockDecoder) neighbours(i, y int) (int, int, int) {
+	f := d.flags
+	s := d.stride
+	below := d.h > y+1 && !(d.cbStyle&cbCausal != 0 && y%4 == 3)
+	h := int(f[i-1]&flagSignificant) + int(f[i+1]&flagSignificant)
+	v := int(f[i-s] & flagSignificant)
+	dg := int(f[i-s-1]&flagSignificant) + int(f[i-s+1]&flagSignificant)
+	if below {
+		v += int(f[i+s] & flagSignificant)
+		dg += int(f[i+s-1]&flagSignificant) + int(f[i+s+1]&flagSignificant)
+	}
+	return h, v, dg
+}
+
+// zeroContext returns the zero coding context of the sample at `i` in row `y` (Table D.1).
+func (d *blockDecoder) zeroContext(i, y int) int {
+	h, v, dg := d.neighbours(i, y)
+	switch d.band {
+	case bandHL:
+		h, v = v, h
+		fallthrough
+	case bandLL, bandLH:
+		switch {
+		case h == 2:
+			return 8
+		case h == 1 && v >= 1:
+			return 7
+		case h == 1 && dg >= 1:
+			return 6
+		case h == 1:
+			return 5
+		case v == 2:
+			return 4
+		case v == 1:
+			return 3
+		case dg >= 2:
+			return 2
+		default:
+			return dg
+		}
+	}
+	hv := h + v
+	switch {
+	case dg >= 3:
+		return 8
+	case dg == 2 && hv >= 1:
+		return 7
+	case dg == 2:
+		return 6
+	case dg == 1 && hv >= 2:
+		return 5
+	case dg == 1:
+		return 3 + hv
+	case hv >= 2:
+		return 2
+	default:
+		return hv
+	}
+}
+
+// signContribution returns the contribution of the neighbour at `i` to the sign context.
+func (d *blockDecoder) signContribution(i int) int {
+	f := d.flags[i]
+	if f&flagSignificant == 0 {
+		return 0
+	}
+	if f&flagNegative != 0 {
+		return -1
+	}
+	return 1
+}
+
+// signContext returns the sign coding context and the XOR bit of the sample at `i` in row `y`
+// (D.3.2).
+func (d *blockDecoder) signContext(i, y int) (int, int) {
+	s := d.stride
+	h := d.signContribution(i-1) + d.signContribution(i+1)
+	v := d.signContribution(i - s)
+	if d.h > y+1 && !(d.cbStyle&cbCausal != 0 && y%4 == 3) {
+		v += d.signContribution(i + s)
+	}
+	sc := signContexts[clampSign(h)+1][clampSign(v)+1]
+	return sc.cx, sc.xor
+}
+
+// decodeSign decodes the sign bit of the sample at `i` in row `y`.
+func (d *blockDecoder) decodeSign(i, y int) int {
+	if d.useRaw {
+		return d.raw.decodeBit()
+	}
+	cx, xor := d.signContext(i, y)
+	return d.mq.decodeBit(d.cx, cx) ^ xor
+}
+
+func clampSign(v int) int {
+	if v < -1 {
+		return -1
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// setSignificant makes the sample at (`x`, `y`) significant with the sign bit `sign`.
+func (d *blockDecoder) setSignificant(x, y, sign int) {
+	i := (y+1)*d.stride + x + 1
+	d.flags[i] |= flagSignificant
+	if sign == 1 {
+		d.flags[i] |= flagNegative
+	}
+	j := y*d.w + x
+	d.mag[j] |= 1 << uint(d.plane)
+	d.lsb[j] = int8(d.plane)
+}
+
+// decodeBit decodes a bit with context `cx` in a coding pass, or reads a raw bit.
+func (d *blockDecoder) decodeBit(cx int) int {
+	if d.useRaw {
+		return d.raw.decodeBit()
+	}
+	return d.mq.decodeBit(d.cx, cx)
+}
+
+// significancePass decodes a significance propagation pass (D.3.1).
+func (d *blockDecoder) significancePass() {
+	for y0 := 0; y0 < d.h; y0 += 4 {
+		for x := 0; x < d.w; x++ {
+			for y := y0; y < y0+4 && y < d.h; y++ {
+				i := (y+1)*d.stride + x + 1
+				if d.flags[i]&flagSignificant != 0 {
+					continue
+				}
+				cx := d.zeroContext(i, y)
+				if cx == 0 {
+					continue
+				}
+				d.flags[i] |= flagVisited
+				if d.decodeBit(ctxZeroCoding+cx) == 1 {
+					d.setSignificant(x, y, d.decodeSign(i, y))
+				}
+			}
+		}
+	}
+}
+
+// refinementPass decodes a magnitude refinement pass (D.3.3).
+func (d *blockDecoder) refinementPass() {
+	for y0 := 0; y0 < d.h; y0 += 4 {
+		for x := 0; x < d.w; x++ {
+			for y := y0; y < y0+4 && y < d.h; y++ {
+				i := (y+1)*d.stride + x + 1
+				f := d.flags[i]
+				if f&flagSignificant == 0 || f&flagVisited != 0 {
+					continue
+				}
+				cx := ctxRefinement + 2
+				if f&flagRefined == 0 {
+					cx = ctxRefinement
+					if h, v, dg := d.neighbours(i, y); h+v+dg > 0 {
+						cx++
+					}
+				}
+				j := y*d.w + x
+				d.mag[j] |= int32(d.decodeBit(cx)) << uint(d.plane)
+				d.lsb[j] = int8(d.plane)
+				d.flags[i] |= flagRefined
+			}
+		}
+	}
+}
+
+// cleanupPass decodes a cleanup pass (D.3.4).
+func (d *blockDecoder) cleanupPass() {
+	for y0 := 0; y0 < d.h; y0 += 4 {
+		for x := 0; x < d.w; x++ {
+			y := y0
+			if y0+4 <= d.h && d.runLengthMode(x, y0) {
+				if d.decodeBit(ctxRunLength) == 0 {
+					continue
+				}
+				y = y0 + (d.decodeBit(ctxUniform)<<1 | d.decodeBit(ctxUniform))
+				i := (y+1)*d.stride + x + 1
+				d.setSignificant(x, y, d.decodeSign(i, y))
+				y++
+			}
+			for ; y < y0+4 && y < d.h; y++ {
+				i := (y+1)*d.stride + x + 1
+				if d.flags[i]&(flagSignificant|flagVisited) != 0 {
+					continue
+				}
+				if d.decodeBit(ctxZeroCoding+d.zeroContext(i, y)) == 1 {
+					d.setSignificant(x, y, d.decodeSign(i, y))
+				}
+			}
+		}
+	}
+	for i := range d.flags {
+		d.flags[i] &^= flagVisited
+	}
+	if d.cbStyle&cbSegmentation != 0 {
+		for i := 0; i < 4; i++ {
+			d.decodeBit(ctxUniform)
+		}
+	}
+}
+
+// runLengthMode returns true if the column of four samples at (`x`, `y0`) is decoded in run-length
+// mode: none of the samples is significant or has been visited, and all have zero contexts.
+func (d *blockDecoder) runLengthMode(x, y0 int) bool {
+	for y := y0; y < y0+4; y++ {
+		i := (y+1)*d.stride + x + 1
+		if d.flags[i]&(flagSignificant|flagVisited) != 0 {
+			return false
+		}
+		if h, v, dg := d.neighbours(i, y); h+v+dg > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// coefficient returns the reconstructed quantization index of the sample at (`x`, `y`), with the
+// region of interest shift `roiShift` undone (E.1.1.2, H.1).
+func (d *blockDecoder) coefficient(x, y, roiShift int) float32 {
+	j := y*d.w + x
+	mag := d.mag[j]
+	if mag == 0 {
+		return 0
+	}
+	lsb := int(d.lsb[j])
+	if roiShift > 0 && mag >= 1<<uint(roiShift) {
+		mag >>= uint(roiShift)
+		lsb = maxInt(lsb-roiShift, 0)
+	}
+	v := float32(mag)
+	if lsb > 0 {
+		v += float32(int32(1) << uint(lsb-1))
+	}
+	if d.flags[(y+1)*d.stride+x+1]&flagNegative != 0 {
+		v = -v
+	}
+	return v
+}
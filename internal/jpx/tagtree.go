@@ -0,0 +1,103 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+// tagTreeNode is a node of a tag tree.
+type tagTreeNode struct {
+	parent int // Index of the parent node, -1 for the root.
+	low    int // The value is known to be at least low.
+	value  int // The value, or tagTreeUnknown if it has not been decoded yet.
+}
+
+// tagTreeUnknown is the value of a tag tree node whose value has not been decoded.
+const tagTreeUnknown = 1 << 30
+
+// tagTree is a tag tree (B.10.2) over a `w` by `h` array of values.
+type tagTree struct {
+	w     int
+	nodes []tagTreeNode
+}
+
+// newTagTree returns a tag tree for a `w` by `h` array.
+func newTagTree(w, h int) *tagTree {
+	t := &tagTree{w: w}
+	// Leaves are stored first, then the levels towards the root.
+	type level struct {
+		w, h, offset int
+	}
+	var levels []level
+	n := 0
+	for {
+		levels = append(levels, level{w, h, n})
+		n += w * h
+		if w <= 1 && h <= 1 {
+			break
+		}
+		w, h = (w+1)/2, (h+1)/2
+	}
+	t.nodes = make([]tagTreeNode, n)
+	for i, l := range levels {
+		for y := 0; y < l.h; y++ {
+			for x := 0; x < l.w; x++ {
+				node := &t.nodes[l.offset+y*l.w+x]
+				node.value = tagTreeUnknown
+				node.parent = -1
+				if i+1 < len(levels) {
+					p := levels[i+1]
+					node.parent = p.offset + (y/2)*p.w + x/2
+				}
+			}
+		}
+	}
+	return t
+}
+
+// decode decodes the value of leaf `i` as far as needed to compare it with `threshold` and
+// returns true if the value is less than `threshold`.
+func (t *tagTree) decode(r *bitReader, i, threshold int) (bool, error) {
+	var path []int
+	for n := i; n >= 0; n = t.nodes[n].parent {
+		path = append(path, n)
+	}
+	low := 0
+	for k := len(path) - 1; k >= 0; k-- {
+		node := &t.nodes[path[k]]
+		if low > node.low {
+			node.low = low
+		} else {
+			low = node.low
+		}
+		for low < threshold && low < node.value {
+			bit, err := r.readBit()
+			if err != nil {
+				return false, err
+			}
+			if bit == 1 {
+				node.value = low
+			} else {
+				low++
+			}
+		}
+		node.low = low
+	}
+	return t.nodes[i].value < threshold, nil
+}
+
+// decodeValue decodes the value of leaf `i`.
+func (t *tagTree) decodeValue(r *bitReader, i int) (int, error) {
+	for threshold := 1; ; threshold++ {
+		ok, err := t.decode(r, i, threshold)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return t.nodes[i].value, nil
+		}
+		if threshold > 64 {
+			return 0, errInvalidPacket
+		}
+	}
+}
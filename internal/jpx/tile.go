@@ -0,0 +1,233 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+// Subband orientations.
+const (
+	bandLL = iota
+	bandHL
+	bandLH
+	bandHH
+)
+
+// segment is a codeword segment of a code-block: the data of one or more coding passes that are
+// decoded without reinitialising the decoder.
+type segment struct {
+	start     int // Index of the first coding pass of the segment.
+	passes    int // Number of coding passes included so far.
+	maxPasses int // Number of coding passes that the segment can hold.
+	data      []byte
+}
+
+// codeblock is a code-block of a subband.
+type codeblock struct {
+	x0, y0, x1, y1 int // Subband coordinates.
+	included       bool
+	zeroBitPlanes  int
+	lblock         int
+	passes         int
+	segments       []*segment
+}
+
+// precinctBand holds the code-blocks of a subband that belong to a precinct.
+type precinctBand struct {
+	band        *subband
+	cbw, cbh    int // Number of code-blocks horizontally and vertically.
+	blocks      []*codeblock
+	inclusion   *tagTree
+	zeroPlanes  *tagTree
+	initialised bool
+}
+
+// precinct is a precinct of a resolution level.
+type precinct struct {
+	bands []*precinctBand
+}
+
+// subband is a subband of a tile-component.
+type subband struct {
+	kind           int
+	x0, y0, x1, y1 int
+	level          int // Decomposition level nb.
+	index          int // Index of the subband in the quantization parameters.
+	blocks         []*codeblock
+	coeffs         []float32
+}
+
+// resolution is a resolution level of a tile-component.
+type resolution struct {
+	x0, y0, x1, y1     int
+	ppx, ppy           int
+	numPrecX, numPrecY int
+	bands              []*subband
+	precincts          []*precinct
+}
+
+// tileComponent is a component of a tile.
+type tileComponent struct {
+	x0, y0, x1, y1 int
+	dx, dy         int
+	style          *codingStyle
+	quant          *quantization
+	roiShift       int
+	resolutions    []*resolution
+}
+
+// tile is a tile of the image with its tile-components.
+type tile struct {
+	x0, y0, x1, y1 int
+	params         *tileParams
+	comps          []*tileComponent
+}
+
+// newTile returns the tile `index` of the codestream `cs` with the coding parameters `params`,
+// and builds its resolution levels, subbands, precincts and code-blocks (B.5 to B.7).
+func newTile(cs *codestream, index int, params *tileParams) *tile {
+	s := &cs.siz
+	numX, _ := s.numTiles()
+	p, q := index%numX, index/numX
+	t := &tile{
+		x0:     maxInt(s.tileX0+p*s.tileWidth, s.x0),
+		y0:     maxInt(s.tileY0+q*s.tileHeight, s.y0),
+		x1:     minInt(s.tileX0+(p+1)*s.tileWidth, s.width),
+		y1:     minInt(s.tileY0+(q+1)*s.tileHeight, s.height),
+		params: params,
+	}
+	for c, comp := range s.components {
+		tc := &tileComponent{
+			x0:       ceilDiv(t.x0, comp.dx),
+			y0:       ceilDiv(t.y0, comp.dy),
+			x1:       ceilDiv(t.x1, comp.dx),
+			y1:       ceilDiv(t.y1, comp.dy),
+			dx:       comp.dx,
+			dy:       comp.dy,
+			style:    params.style(c),
+			quant:    params.quantization(c),
+			roiShift: params.roiShift(c),
+		}
+		for r := 0; r <= tc.style.levels; r++ {
+			tc.resolutions = append(tc.resolutions, tc.newResolution(r))
+		}
+		t.comps = append(t.comps, tc)
+	}
+	return t
+}
+
+// newResolution builds the resolution level `r` of the tile-component.
+func (tc *tileComponent) newResolution(r int) *resolution {
+	levels := tc.style.levels
+	scale := uint(levels - r)
+	ps := tc.style.precinct(r)
+	res := &resolution{
+		x0:  ceilShift(tc.x0, scale),
+		y0:  ceilShift(tc.y0, scale),
+		x1:  ceilShift(tc.x1, scale),
+		y1:  ceilShift(tc.y1, scale),
+		ppx: ps.ppx,
+		ppy: ps.ppy,
+	}
+	if res.x1 > res.x0 {
+		res.numPrecX = ceilShift(res.x1, uint(res.ppx)) - res.x0>>uint(res.ppx)
+	}
+	if res.y1 > res.y0 {
+		res.numPrecY = ceilShift(res.y1, uint(res.ppy)) - res.y0>>uint(res.ppy)
+	}
+
+	// Subbands (B.5).
+	if r == 0 {
+		res.bands = []*subband{tc.newSubband(bandLL, levels, 0)}
+	} else {
+		nb := levels - r + 1
+		for k, kind := range []int{bandHL, bandLH, bandHH} {
+			res.bands = append(res.bands, tc.newSubband(kind, nb, 1+3*(r-1)+k))
+		}
+	}
+
+	// Precinct and code-block partitions in the subbands (B.6, B.7).
+	ppx, ppy := res.ppx, res.ppy
+	if r > 0 {
+		ppx, ppy = maxInt(ppx-1, 0), maxInt(ppy-1, 0)
+	}
+	xcb, ycb := minInt(tc.style.xcb, ppx), minInt(tc.style.ycb, ppy)
+	px0, py0 := res.x0>>uint(res.ppx), res.y0>>uint(res.ppy)
+	for j := 0; j < res.numPrecY; j++ {
+		for i := 0; i < res.numPrecX; i++ {
+			prec := &precinct{}
+			for _, band := range res.bands {
+				// The precinct area in the subband.
+				bx0 := maxInt((px0+i)<<uint(ppx), band.x0)
+				by0 := maxInt((py0+j)<<uint(ppy), band.y0)
+				bx1 := minInt((px0+i+1)<<uint(ppx), band.x1)
+				by1 := minInt((py0+j+1)<<uint(ppy), band.y1)
+				pb := &precinctBand{band: band}
+				if bx1 > bx0 && by1 > by0 {
+					cx0, cy0 := bx0>>uint(xcb), by0>>uint(ycb)
+					cx1, cy1 := ceilShift(bx1, uint(xcb)), ceilShift(by1, uint(ycb))
+					pb.cbw, pb.cbh = cx1-cx0, cy1-cy0
+					for y := cy0; y < cy1; y++ {
+						for x := cx0; x < cx1; x++ {
+							cb := &codeblock{
+								x0:     maxInt(x<<uint(xcb), bx0),
+								y0:     maxInt(y<<uint(ycb), by0),
+								x1:     minInt((x+1)<<uint(xcb), bx1),
+								y1:     minInt((y+1)<<uint(ycb), by1),
+								lblock: 3,
+							}
+							pb.blocks = append(pb.blocks, cb)
+							band.blocks = append(band.blocks, cb)
+						}
+					}
+				}
+				prec.bands = append(prec.bands, pb)
+			}
+			res.precincts = append(res.precincts, prec)
+		}
+	}
+	return res
+}
+
+// newSubband returns the subband of orientation `kind` at decomposition level `nb` (B.5).
+func (tc *tileComponent) newSubband(kind, nb, index int) *subband {
+	var xob, yob int
+	if kind == bandHL || kind == bandHH {
+		xob = 1
+	}
+	if kind == bandLH || kind == bandHH {
+		yob = 1
+	}
+	var ox, oy int
+	if nb > 0 {
+		ox, oy = xob<<uint(nb-1), yob<<uint(nb-1)
+	}
+	return &subband{
+		kind:  kind,
+		x0:    ceilShift(tc.x0-ox, uint(nb)),
+		y0:    ceilShift(tc.y0-oy, uint(nb)),
+		x1:    ceilShift(tc.x1-ox, uint(nb)),
+		y1:    ceilShift(tc.y1-oy, uint(nb)),
+		level: nb,
+		index: index,
+	}
+}
+
+// ceilShift returns ceil(a / 2^n), also for negative `a`.
+func ceilShift(a int, n uint) int {
+	return -((-a) >> n)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
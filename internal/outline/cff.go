@@ -0,0 +1,538 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package outline
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+)
+
+// CFF is a CFF (Compact Font Format) font program, as used by FontFile3 streams with subtype
+// Type1C or CIDFontType0C and by OpenType fonts.
+type CFF struct {
+	charStrings [][]byte
+	gsubrs      [][]byte
+	subrs       [][]byte   // Local subroutines of a name-keyed font.
+	fdSubrs     [][][]byte // Local subroutines of each font DICT of a CID-keyed font.
+	fdSelect    []byte     // Font DICT index of each glyph of a CID-keyed font.
+	charset     []uint16   // SID, or CID for CID-keyed fonts, of each glyph.
+	strings     [][]byte
+	cidKeyed    bool
+	encoding    map[byte]uint16 // Built-in encoding of a name-keyed font.
+	fontMatrix  [6]float64
+
+	names map[string]uint16 // Glyph index by name, built on demand.
+	cids  map[uint16]uint16 // Glyph index by CID, built on demand.
+}
+
+// Top DICT and Private DICT operators.
+const (
+	opCharset     = 15
+	opEncoding    = 16
+	opCharStrings = 17
+	opPrivate     = 18
+	opSubrs       = 19
+	opFontMatrix  = 1207
+	opROS         = 1230
+	opFDArray     = 1236
+	opFDSelect    = 1237
+)
+
+// ParseCFF parses the CFF font program `data`. Only the first font of a font set is used.
+func ParseCFF(data []byte) (*CFF, error) {
+	if len(data) < 4 {
+		return nil, errInvalidFont
+	}
+	pos := int(data[2])
+	var topDicts, err = [][]byte(nil), error(nil)
+	if _, pos, err = readIndex(data, pos); err != nil { // Name INDEX.
+		return nil, err
+	}
+	if topDicts, pos, err = readIndex(data, pos); err != nil {
+		return nil, err
+	}
+	if len(topDicts) == 0 {
+		return nil, errors.New("CFF without Top DICT")
+	}
+	f := &CFF{fontMatrix: [6]float64{0.001, 0, 0, 0.001, 0, 0}}
+	if f.strings, pos, err = readIndex(data, pos); err != nil {
+		return nil, err
+	}
+	if f.gsubrs, _, err = readIndex(data, pos); err != nil {
+		return nil, err
+	}
+
+	top, err := parseDict(topDicts[0])
+	if err != nil {
+		return nil, err
+	}
+	if m := top[opFontMatrix]; len(m) == 6 {
+		copy(f.fontMatrix[:], m)
+	}
+	offset, ok := dictInt(top, opCharStrings)
+	if !ok {
+		return nil, errors.New("CFF without CharStrings")
+	}
+	if f.charStrings, _, err = readIndex(data, offset); err != nil {
+		return nil, err
+	}
+	numGlyphs := len(f.charStrings)
+
+	if _, f.cidKeyed = top[opROS]; f.cidKeyed {
+		if err := f.parseCIDFonts(data, top, numGlyphs); err != nil {
+			return nil, err
+		}
+	} else if f.subrs, err = readPrivateSubrs(data, top); err != nil {
+		return nil, err
+	}
+
+	charset, _ := dictInt(top, opCharset)
+	if f.charset, err = parseCharset(data, charset, numGlyphs); err != nil {
+		return nil, err
+	}
+	if !f.cidKeyed {
+		encoding, _ := dictInt(top, opEncoding)
+		if encoding > 1 {
+			f.encoding = f.parseEncoding(data, encoding)
+		}
+	}
+	return f, nil
+}
+
+// parseCIDFonts reads the font DICTs and the FDSelect of a CID-keyed font.
+func (f *CFF) parseCIDFonts(data []byte, top map[int][]float64, numGlyphs int) error {
+	offset, ok := dictInt(top, opFDArray)
+	if !ok {
+		return errors.New("CID-keyed CFF without FDArray")
+	}
+	fds, _, err := readIndex(data, offset)
+	if err != nil {
+		return err
+	}
+	for i, fd := range fds {
+		dict, err := parseDict(fd)
+		if err != nil {
+			return err
+		}
+		subrs, err := readPrivateSubrs(data, dict)
+		if err != nil {
+			return err
+		}
+		f.fdSubrs = append(f.fdSubrs, subrs)
+		if m := dict[opFontMatrix]; i == 0 && len(m) == 6 {
+			// The font DICT matrix is applied before the Top DICT matrix, which then defaults to
+			// the identity.
+			var fm [6]float64
+			copy(fm[:], m)
+			topMatrix := [6]float64{1, 0, 0, 1, 0, 0}
+			if m := top[opFontMatrix]; len(m) == 6 {
+				copy(topMatrix[:], m)
+			}
+			f.fontMatrix = concat(fm, topMatrix)
+		}
+	}
+
+	f.fdSelect = make([]byte, numGlyphs)
+	offset, ok = dictInt(top, opFDSelect)
+	if !ok || offset >= len(data) {
+		return nil
+	}
+	d := data[offset:]
+	switch d[0] {
+	case 0:
+		if len(d) < 1+numGlyphs {
+			return errInvalidFont
+		}
+		copy(f.fdSelect, d[1:])
+	case 3:
+		if len(d) < 3 {
+			return errInvalidFont
+		}
+		n := int(binary.BigEndian.Uint16(d[1:]))
+		if len(d) < 5+3*n {
+			return errInvalidFont
+		}
+		for i := 0; i < n; i++ {
+			r := d[3+3*i:]
+			first := int(binary.BigEndian.Uint16(r))
+			next := int(binary.BigEndian.Uint16(r[3:]))
+			for gid := first; gid < next && gid < numGlyphs; gid++ {
+				f.fdSelect[gid] = r[2]
+			}
+		}
+	default:
+		return errors.New("unsupported FDSelect format")
+	}
+	return nil
+}
+
+// readPrivateSubrs returns the local subroutines of the Private DICT referenced by `dict`.
+func readPrivateSubrs(data []byte, dict map[int][]float64) ([][]byte, error) {
+	p := dict[opPrivate]
+	if len(p) != 2 {
+		return nil, nil
+	}
+	size, offset := int(p[0]), int(p[1])
+	if offset < 0 || size < 0 || offset+size > len(data) {
+		return nil, errInvalidFont
+	}
+	private, err := parseDict(data[offset : offset+size])
+	if err != nil {
+		return nil, err
+	}
+	subrs, ok := dictInt(private, opSubrs)
+	if !ok {
+		return nil, nil
+	}
+	s, _, err := readIndex(data, offset+subrs)
+	return s, err
+}
+
+// parseCharset returns the SID or CID of each glyph from the charset at `offset`.
+func parseCharset(data []byte, offset, numGlyphs int) ([]uint16, error) {
+	charset := make([]uint16, numGlyphs)
+	if offset <= 2 {
+		// Predefined charsets. Only ISOAdobe is mapped, the others are used for expert fonts.
+		for gid := range charset {
+			if offset == 0 && gid <= 228 {
+				charset[gid] = uint16(gid)
+			}
+		}
+		return charset, nil
+	}
+	if offset >= len(data) {
+		return nil, errInvalidFont
+	}
+	format := data[offset]
+	d := data[offset+1:]
+	gid := 1
+	switch format {
+	case 0:
+		for ; gid < numGlyphs && len(d) >= 2; gid++ {
+			charset[gid] = binary.BigEndian.Uint16(d)
+			d = d[2:]
+		}
+	case 1, 2:
+		for gid < numGlyphs {
+			size := 3 + int(format) - 1
+			if len(d) < size {
+				return nil, errInvalidFont
+			}
+			first := binary.BigEndian.Uint16(d)
+			nLeft := int(d[2])
+			if format == 2 {
+				nLeft = int(binary.BigEndian.Uint16(d[2:]))
+			}
+			d = d[size:]
+			for i := 0; i <= nLeft && gid < numGlyphs; i++ {
+				charset[gid] = first + uint16(i)
+				gid++
+			}
+		}
+	default:
+		return nil, errors.New("unsupported CFF charset format")
+	}
+	return charset, nil
+}
+
+// parseEncoding returns the custom encoding at `offset` of a name-keyed font.
+func (f *CFF) parseEncoding(data []byte, offset int) map[byte]uint16 {
+	if offset >= len(data) {
+		return nil
+	}
+	enc := make(map[byte]uint16)
+	format := data[offset]
+	d := data[offset+1:]
+	switch format & 0x7f {
+	case 0:
+		if len(d) < 1 || len(d) < 1+int(d[0]) {
+			return nil
+		}
+		for i, code := range d[1 : 1+int(d[0])] {
+			enc[code] = uint16(i + 1)
+		}
+		d = d[1+int(d[0]):]
+	case 1:
+		if len(d) < 1 || len(d) < 1+2*int(d[0]) {
+			return nil
+		}
+		gid := 1
+		for i := 0; i < int(d[0]); i++ {
+			first, nLeft := int(d[1+2*i]), int(d[2+2*i])
+			for c := first; c <= first+nLeft && c < 256; c++ {
+				enc[byte(c)] = uint16(gid)
+				gid++
+			}
+		}
+		d = d[1+2*int(d[0]):]
+	default:
+		return nil
+	}
+	if format&0x80 != 0 && len(d) > 0 {
+		n := int(d[0])
+		for i := 0; i < n && 1+3*i+3 <= len(d); i++ {
+			s := d[1+3*i:]
+			sid := binary.BigEndian.Uint16(s[1:])
+			for gid, g := range f.charset {
+				if g == sid {
+					enc[s[0]] = uint16(gid)
+					break
+				}
+			}
+		}
+	}
+	return enc
+}
+
+// readIndex reads the INDEX at `pos` in `data` and returns its entries and the position after it.
+func readIndex(data []byte, pos int) ([][]byte, int, error) {
+	if pos < 0 || pos+2 > len(data) {
+		return nil, 0, errInvalidFont
+	}
+	count := int(binary.BigEndian.Uint16(data[pos:]))
+	if count == 0 {
+		return nil, pos + 2, nil
+	}
+	if pos+3 > len(data) {
+		return nil, 0, errInvalidFont
+	}
+	offSize := int(data[pos+2])
+	if offSize < 1 || offSize > 4 {
+		return nil, 0, errInvalidFont
+	}
+	offsets := data[pos+3:]
+	if len(offsets) < (count+1)*offSize {
+		return nil, 0, errInvalidFont
+	}
+	readOffset := func(i int) int {
+		v := 0
+		for _, b := range offsets[i*offSize : (i+1)*offSize] {
+			v = v<<8 | int(b)
+		}
+		return v
+	}
+	base := pos + 3 + (count+1)*offSize - 1
+	entries := make([][]byte, count)
+	for i := range entries {
+		start, end := base+readOffset(i), base+readOffset(i+1)
+		if start > end || start < base || end > len(data) {
+			return nil, 0, errInvalidFont
+		}
+		entries[i] = data[start:end]
+	}
+	return entries, base + readOffset(count), nil
+}
+
+// parseDict parses a Top, Font or Private DICT. The operands are keyed by operator, with two byte
+// operators keyed by 1200 plus their second byte.
+func parseDict(d []byte) (map[int][]float64, error) {
+	dict := make(map[int][]float64)
+	var operands []float64
+	for i := 0; i < len(d); {
+		b0 := d[i]
+		switch {
+		case b0 <= 21:
+			op := int(b0)
+			i++
+			if b0 == 12 {
+				if i >= len(d) {
+					return nil, errInvalidFont
+				}
+				op = 1200 + int(d[i])
+				i++
+			}
+			dict[op] = operands
+			operands = nil
+		case b0 == 28:
+			if i+3 > len(d) {
+				return nil, errInvalidFont
+			}
+			operands = append(operands, float64(int16(binary.BigEndian.Uint16(d[i+1:]))))
+			i += 3
+		case b0 == 29:
+			if i+5 > len(d) {
+				return nil, errInvalidFont
+			}
+			operands = append(operands, float64(int32(binary.BigEndian.Uint32(d[i+1:]))))
+			i += 5
+		case b0 == 30:
+			v, n := parseReal(d[i+1:])
+			operands = append(operands, v)
+			i += 1 + n
+		case b0 >= 32 && b0 <= 246:
+			operands = append(operands, float64(int(b0)-139))
+			i++
+		case b0 >= 247 && b0 <= 254:
+			if i+2 > len(d) {
+				return nil, errInvalidFont
+			}
+			v := (int(b0)-247)*256 + int(d[i+1]) + 108
+			if b0 >= 251 {
+				v = -(int(b0)-251)*256 - int(d[i+1]) - 108
+			}
+			operands = append(operands, float64(v))
+			i += 2
+		default:
+			i++
+		}
+	}
+	return dict, nil
+}
+
+// parseReal parses a real number operand and returns it with the number of bytes it used.
+func parseReal(d []byte) (float64, int) {
+	var s []byte
+	n := 0
+	for ; n < len(d); n++ {
+		for _, nibble := range []byte{d[n] >> 4, d[n] & 0xf} {
+			switch {
+			case nibble <= 9:
+				s = append(s, '0'+nibble)
+			case nibble == 0xa:
+				s = append(s, '.')
+			case nibble == 0xb:
+				s = append(s, 'E')
+			case nibble == 0xc:
+				s = append(s, 'E', '-')
+			case nibble == 0xe:
+				s = append(s, '-')
+			case nibble == 0xf:
+				v, _ := strconv.ParseFloat(string(s), 64)
+				return v, n + 1
+			}
+		}
+	}
+	v, _ := strconv.ParseFloat(string(s), 64)
+	return v, n
+}
+
+// dictInt returns the integer operand of `op` in `dict`.
+func dictInt(dict map[int][]float64, op int) (int, bool) {
+	v := dict[op]
+	if len(v) != 1 {
+		return 0, false
+	}
+	return int(v[0]), true
+}
+
+// NumGlyphs returns the number of glyphs in the font.
+func (f *CFF) NumGlyphs() int {
+	return len(f.charStrings)
+}
+
+// FontMatrix returns the transform from glyph space to text space.
+func (f *CFF) FontMatrix() [6]float64 {
+	return f.fontMatrix
+}
+
+// IsCIDKeyed returns true if the glyphs of the font are identified by CID rather than by name.
+func (f *CFF) IsCIDKeyed() bool {
+	return f.cidKeyed
+}
+
+// sidString returns the string with string ID `sid`.
+func (f *CFF) sidString(sid uint16) string {
+	if int(sid) < len(standardStrings) {
+		return standardStrings[sid]
+	}
+	if i := int(sid) - len(standardStrings); i < len(f.strings) {
+		return string(f.strings[i])
+	}
+	return ""
+}
+
+// GlyphIndexByName returns the glyph index of the glyph called `name` in a name-keyed font.
+func (f *CFF) GlyphIndexByName(name string) (uint16, bool) {
+	if f.cidKeyed {
+		return 0, false
+	}
+	if f.names == nil {
+		f.names = make(map[string]uint16, len(f.charset))
+		for gid, sid := range f.charset {
+			f.names[f.sidString(sid)] = uint16(gid)
+		}
+	}
+	gid, ok := f.names[name]
+	return gid, ok
+}
+
+// GlyphIndexByCID returns the glyph index of the glyph with CID `cid`. For name-keyed fonts, the
+// CID is the glyph index.
+func (f *CFF) GlyphIndexByCID(cid uint16) (uint16, bool) {
+	if !f.cidKeyed {
+		return cid, int(cid) < len(f.charStrings)
+	}
+	if f.cids == nil {
+		f.cids = make(map[uint16]uint16, len(f.charset))
+		for gid, c := range f.charset {
+			f.cids[c] = uint16(gid)
+		}
+	}
+	gid, ok := f.cids[cid]
+	return gid, ok
+}
+
+// GlyphIndexByCode returns the glyph index of character code `code` in the built-in encoding of
+// a name-keyed font.
+func (f *CFF) GlyphIndexByCode(code byte) (uint16, bool) {
+	if f.cidKeyed {
+		return 0, false
+	}
+	if f.encoding != nil {
+		gid, ok := f.encoding[code]
+		return gid, ok
+	}
+	name, ok := standardGlyphName(code)
+	if !ok {
+		return 0, false
+	}
+	return f.GlyphIndexByName(name)
+}
+
+// Glyph returns the outline of glyph `gid`.
+func (f *CFF) Glyph(gid uint16) (Glyph, error) {
+	b := &pathBuilder{}
+	if err := f.appendGlyph(b, gid, 0, 0, 0); err != nil {
+		return nil, err
+	}
+	return b.glyph, nil
+}
+
+// appendGlyph appends the outline of glyph `gid` offset by `dx`,`dy` to `b`.
+func (f *CFF) appendGlyph(b *pathBuilder, gid uint16, dx, dy float64, depth int) error {
+	if int(gid) >= len(f.charStrings) {
+		return ErrNoGlyph
+	}
+	subrs := f.subrs
+	if f.cidKeyed && int(gid) < len(f.fdSelect) && int(f.fdSelect[gid]) < len(f.fdSubrs) {
+		subrs = f.fdSubrs[f.fdSelect[gid]]
+	}
+	in := &type2Interpreter{
+		font:  f,
+		b:     b,
+		subrs: subrs,
+		depth: depth,
+	}
+	b.x, b.y, b.open = dx, dy, false
+	err := in.run(f.charStrings[gid], 0)
+	if err == errEndChar {
+		err = nil
+	}
+	return err
+}
+
+// standardGlyphName returns the name of the glyph with code `code` in the Adobe standard encoding.
+func standardGlyphName(code byte) (string, bool) {
+	r, ok := textencoding.NewStandardEncoder().CharcodeToRune(textencoding.CharCode(code))
+	if !ok {
+		return "", false
+	}
+	name, ok := textencoding.RuneToGlyph(r)
+	return string(name), ok
+}
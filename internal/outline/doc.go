@@ -0,0 +1,11 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package outline loads glyph outlines from the font programs that can be embedded in PDF files:
+// TrueType and OpenType fonts (FontFile2 and FontFile3/OpenType), bare CFF fonts (FontFile3/Type1C
+// and FontFile3/CIDFontType0C) and Type 1 fonts (FontFile).
+//
+// The outlines are returned in glyph space, in font units. Hinting instructions are ignored.
+package outline
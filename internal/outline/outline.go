@@ -0,0 +1,75 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package outline
+
+import "errors"
+
+// Op is the kind of a path segment.
+type Op int
+
+// Path segment kinds.
+const (
+	// MoveTo starts a new contour at Points[0]. The previous contour, if any, is implicitly closed.
+	MoveTo Op = iota
+	// LineTo adds a line to Points[0].
+	LineTo
+	// QuadTo adds a quadratic Bézier curve with control point Points[0] ending at Points[1].
+	QuadTo
+	// CubeTo adds a cubic Bézier curve with control points Points[0] and Points[1] ending at
+	// Points[2].
+	CubeTo
+)
+
+// Point is a point in glyph space.
+type Point struct {
+	X, Y float64
+}
+
+// Segment is a segment of a glyph outline.
+type Segment struct {
+	Op     Op
+	Points [3]Point
+}
+
+// Glyph is the outline of a glyph. All contours are closed.
+type Glyph []Segment
+
+var (
+	// ErrNoGlyph is returned when a font does not have the requested glyph.
+	ErrNoGlyph = errors.New("glyph not found")
+
+	errInvalidFont  = errors.New("invalid font program")
+	errInvalidGlyph = errors.New("invalid glyph outline")
+)
+
+// pathBuilder collects the segments of a glyph outline.
+type pathBuilder struct {
+	glyph Glyph
+	x, y  float64 // Current point.
+	open  bool    // True if a contour has been started.
+}
+
+func (b *pathBuilder) moveTo(x, y float64) {
+	b.glyph = append(b.glyph, Segment{Op: MoveTo, Points: [3]Point{{x, y}}})
+	b.x, b.y = x, y
+	b.open = true
+}
+
+func (b *pathBuilder) lineTo(x, y float64) {
+	if !b.open {
+		b.moveTo(b.x, b.y)
+	}
+	b.glyph = append(b.glyph, Segment{Op: LineTo, Points: [3]Point{{x, y}}})
+	b.x, b.y = x, y
+}
+
+func (b *pathBuilder) curveTo(x1, y1, x2, y2, x3, y3 float64) {
+	if !b.open {
+		b.moveTo(b.x, b.y)
+	}
+	b.glyph = append(b.glyph, Segment{Op: CubeTo, Points: [3]Point{{x1, y1}, {x2, y2}, {x3, y3}}})
+	b.x, b.y = x3, y3
+}
@@ -0,0 +1,258 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package outline
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// TestTrueType checks the glyph lookup and outline of a TrueType font.
+func TestTrueType(t *testing.T) {
+	f, err := ParseTrueType(goregular.TTF)
+	if err != nil {
+		t.Fatalf("ParseTrueType failed: %v", err)
+	}
+	if f.UnitsPerEm() != 2048 {
+		t.Errorf("UnitsPerEm = %d, expected 2048", f.UnitsPerEm())
+	}
+	gid, ok := f.GlyphIndex('O')
+	if !ok {
+		t.Fatalf("no glyph for 'O'")
+	}
+	if adv, ok := f.Advance(gid); !ok || adv < 1000 || adv > 2048 {
+		t.Errorf("Advance = %d %t", adv, ok)
+	}
+	if !f.HasCmap(3, 1) {
+		t.Errorf("missing (3,1) cmap")
+	}
+	if g, ok := f.Lookup(3, 1, 'O'); !ok || g != gid {
+		t.Errorf("Lookup(3, 1, 'O') = %d %t, expected %d", g, ok, gid)
+	}
+	g, err := f.Glyph(gid)
+	if err != nil {
+		t.Fatalf("Glyph failed: %v", err)
+	}
+	moves := 0
+	for _, s := range g {
+		if s.Op == MoveTo {
+			moves++
+		}
+		for _, p := range s.Points {
+			if p.X < -100 || p.X > 2048 || p.Y < -100 || p.Y > 2048 {
+				t.Fatalf("point %v outside the em square", p)
+			}
+		}
+	}
+	if moves != 2 {
+		t.Errorf("'O' has %d contours, expected 2", moves)
+	}
+	if _, err := f.Glyph(uint16(f.NumGlyphs())); err != ErrNoGlyph {
+		t.Errorf("expected ErrNoGlyph, got %v", err)
+	}
+}
+
+// cffInt encodes `v` as a 5 byte DICT integer.
+func cffInt(v int) []byte {
+	b := []byte{29, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(v))
+	return b
+}
+
+// charStringInt encodes `v` as an integer of Type 1 and Type 2 charstrings.
+func charStringInt(v int) []byte {
+	switch {
+	case v >= -107 && v <= 107:
+		return []byte{byte(v + 139)}
+	case v >= 108 && v <= 1131:
+		v -= 108
+		return []byte{byte(247 + v/256), byte(v % 256)}
+	case v <= -108 && v >= -1131:
+		v = -v - 108
+		return []byte{byte(251 + v/256), byte(v % 256)}
+	}
+	panic("charstring integer out of range")
+}
+
+// cffIndex encodes `entries` as an INDEX with 1 byte offsets.
+func cffIndex(entries ...[]byte) []byte {
+	if len(entries) == 0 {
+		return []byte{0, 0}
+	}
+	b := []byte{0, byte(len(entries)), 1, 1}
+	offset := 1
+	var data []byte
+	for _, e := range entries {
+		offset += len(e)
+		b = append(b, byte(offset))
+		data = append(data, e...)
+	}
+	return append(b, data...)
+}
+
+// charString builds a charstring from integers and operators.
+func charString(tokens ...interface{}) []byte {
+	var b []byte
+	for _, t := range tokens {
+		switch v := t.(type) {
+		case int:
+			b = append(b, charStringInt(v)...)
+		case byte:
+			b = append(b, v)
+		}
+	}
+	return b
+}
+
+// Charstring operators used by the tests.
+const (
+	csRLineTo   byte = 5
+	csCallSubr  byte = 10
+	csReturn    byte = 11
+	csEndChar   byte = 14
+	csClosePath byte = 9
+	csHSBW      byte = 13
+	csRMoveTo   byte = 21
+)
+
+// TestCFF checks the glyph lookup and the charstring interpreter of a CFF font.
+func TestCFF(t *testing.T) {
+	charStrings := cffIndex(
+		charString(csEndChar),
+		charString(500, 100, 0, csRMoveTo, 200, 0, csRLineTo, 0, 300, csRLineTo, csEndChar),
+		charString(0, 0, csRMoveTo, -107, csCallSubr, csEndChar),
+	)
+	subrs := cffIndex(charString(100, 100, csRLineTo, csReturn))
+	private := append(cffInt(6), opSubrs)
+
+	// Top DICT with fixed size integers, so that its length does not depend on the offsets.
+	const topSize = 5 + 1 + 5 + 1 + 10 + 1
+	header := []byte{1, 0, 4, 1}
+	name := cffIndex([]byte("Test"))
+	strings := cffIndex([]byte("custom"))
+	gsubrs := cffIndex()
+	start := len(header) + len(name) + len(cffIndex(make([]byte, topSize))) + len(strings) + len(gsubrs)
+	charset := []byte{0, 0, 34, 1, 135}
+	charStringsOffset := start + len(charset)
+	privateOffset := charStringsOffset + len(charStrings)
+
+	var top []byte
+	top = append(append(top, cffInt(start)...), opCharset)
+	top = append(append(top, cffInt(charStringsOffset)...), opCharStrings)
+	top = append(append(append(top, cffInt(len(private))...), cffInt(privateOffset)...), opPrivate)
+
+	var data []byte
+	for _, b := range [][]byte{header, name, cffIndex(top), strings, gsubrs, charset, charStrings, private, subrs} {
+		data = append(data, b...)
+	}
+
+	f, err := ParseCFF(data)
+	if err != nil {
+		t.Fatalf("ParseCFF failed: %v", err)
+	}
+	if f.NumGlyphs() != 3 || f.IsCIDKeyed() {
+		t.Fatalf("unexpected font: %d glyphs, CID-keyed %t", f.NumGlyphs(), f.IsCIDKeyed())
+	}
+	for _, tc := range []struct {
+		name string
+		gid  uint16
+	}{{"A", 1}, {"custom", 2}} {
+		if gid, ok := f.GlyphIndexByName(tc.name); !ok || gid != tc.gid {
+			t.Errorf("GlyphIndexByName(%q) = %d %t, expected %d", tc.name, gid, ok, tc.gid)
+		}
+	}
+	if gid, ok := f.GlyphIndexByCode('A'); !ok || gid != 1 {
+		t.Errorf("GlyphIndexByCode('A') = %d %t, expected 1", gid, ok)
+	}
+
+	expected := map[uint16]Glyph{
+		1: {
+			{Op: MoveTo, Points: [3]Point{{100, 0}}},
+			{Op: LineTo, Points: [3]Point{{300, 0}}},
+			{Op: LineTo, Points: [3]Point{{300, 300}}},
+		},
+		2: {
+			{Op: MoveTo, Points: [3]Point{{0, 0}}},
+			{Op: LineTo, Points: [3]Point{{100, 100}}},
+		},
+	}
+	for gid, exp := range expected {
+		g, err := f.Glyph(gid)
+		if err != nil {
+			t.Fatalf("Glyph(%d) failed: %v", gid, err)
+		}
+		if !reflect.DeepEqual(g, exp) {
+			t.Errorf("Glyph(%d) = %v, expected %v", gid, g, exp)
+		}
+	}
+}
+
+// encrypt is the inverse of decrypt with 4 leading zero bytes.
+func encrypt(data []byte, r uint16) []byte {
+	const c1, c2 = 52845, 22719
+	plain := append([]byte{0, 0, 0, 0}, data...)
+	out := make([]byte, len(plain))
+	for i, p := range plain {
+		c := p ^ byte(r>>8)
+		out[i] = c
+		r = (uint16(c)+r)*c1 + c2
+	}
+	return out
+}
+
+// TestType1 checks the encoding, the eexec decryption and the charstring interpreter of a Type 1
+// font.
+func TestType1(t *testing.T) {
+	binaryEntry := func(prefix string, code []byte, suffix string) []byte {
+		enc := encrypt(code, charStringKey)
+		b := []byte(fmt.Sprintf("%s %d RD ", prefix, len(enc)))
+		return append(append(b, enc...), suffix...)
+	}
+	var private bytes.Buffer
+	private.WriteString("dup /Private 8 dict dup begin\n/lenIV 4 def\n/Subrs 1 array\n")
+	private.Write(binaryEntry("dup 0", charString(100, 0, csRLineTo, csReturn), " NP\n"))
+	private.WriteString("ND\n2 index /CharStrings 2 dict dup begin\n")
+	private.Write(binaryEntry("/.notdef", charString(0, 250, csHSBW, csEndChar), " ND\n"))
+	private.Write(binaryEntry("/A", charString(50, 500, csHSBW, 10, 20, csRMoveTo, 0, csCallSubr,
+		0, 100, csRLineTo, csClosePath, csEndChar), " ND\n"))
+	private.WriteString("end\n")
+
+	data := []byte("%!PS-AdobeFont-1.0: Test\n/FontMatrix [0.002 0 0 0.002 0 0] readonly def\n" +
+		"/Encoding 256 array\n0 1 255 {1 index exch /.notdef put} for\ndup 66 /A put\n" +
+		"readonly def\ncurrentfile eexec\n")
+	data = append(data, encrypt(private.Bytes(), eexecKey)...)
+
+	f, err := ParseType1(data)
+	if err != nil {
+		t.Fatalf("ParseType1 failed: %v", err)
+	}
+	if m := f.FontMatrix(); m != [6]float64{0.002, 0, 0, 0.002, 0, 0} {
+		t.Errorf("FontMatrix = %v", m)
+	}
+	if name, ok := f.GlyphName(66); !ok || name != "A" {
+		t.Errorf("GlyphName(66) = %q %t, expected A", name, ok)
+	}
+	if !f.HasGlyph(".notdef") || f.HasGlyph("B") {
+		t.Errorf("unexpected glyph set")
+	}
+	g, err := f.Glyph("A")
+	if err != nil {
+		t.Fatalf("Glyph failed: %v", err)
+	}
+	expected := Glyph{
+		{Op: MoveTo, Points: [3]Point{{60, 20}}},
+		{Op: LineTo, Points: [3]Point{{160, 20}}},
+		{Op: LineTo, Points: [3]Point{{160, 120}}},
+	}
+	if !reflect.DeepEqual(g, expected) {
+		t.Errorf("Glyph = %v, expected %v", g, expected)
+	}
+}
@@ -0,0 +1,692 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package outline
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// TrueType is a TrueType or OpenType font program.
+type TrueType struct {
+	unitsPerEm int
+	numGlyphs  int
+	loca       []uint32
+	glyf       []byte
+	cmaps      []cmapTable
+	names      map[string]uint16 // Glyph names from the post table.
+	cff        *CFF              // The outlines of OpenType fonts with a CFF table.
+	advances   []uint16          // Advance widths from the hmtx table.
+}
+
+// cmapTable is a cmap subtable that maps character codes to glyph indices.
+type cmapTable struct {
+	platform, encoding uint16
+	m                  map[uint32]uint16
+}
+
+// ParseTrueType parses the TrueType or OpenType font program `data`. For font collections, the
+// first font is used.
+func ParseTrueType(data []byte) (*TrueType, error) {
+	tables, err := readTables(data)
+	if err != nil {
+		return nil, err
+	}
+	f := &TrueType{}
+
+	head := tables["head"]
+	if len(head) < 54 {
+		return nil, errors.New("invalid head table")
+	}
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		f.unitsPerEm = 1000
+	}
+	if maxp := tables["maxp"]; len(maxp) >= 6 {
+		f.numGlyphs = int(binary.BigEndian.Uint16(maxp[4:]))
+	}
+
+	if cff, ok := tables["CFF "]; ok {
+		if f.cff, err = ParseCFF(cff); err != nil {
+			return nil, err
+		}
+		f.numGlyphs = f.cff.NumGlyphs()
+	} else {
+		f.glyf = tables["glyf"]
+		longOffsets := binary.BigEndian.Uint16(head[50:]) != 0
+		if f.loca, err = parseLoca(tables["loca"], f.numGlyphs, longOffsets); err != nil {
+			return nil, err
+		}
+	}
+
+	f.cmaps = parseCmap(tables["cmap"])
+	f.names = parsePost(tables["post"])
+	if hhea, hmtx := tables["hhea"], tables["hmtx"]; len(hhea) >= 36 {
+		n := int(binary.BigEndian.Uint16(hhea[34:]))
+		for i := 0; i < n && 4*i+2 <= len(hmtx); i++ {
+			f.advances = append(f.advances, binary.BigEndian.Uint16(hmtx[4*i:]))
+		}
+	}
+	return f, nil
+}
+
+// readTables returns the tables of the font `data` by tag.
+func readTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, errInvalidFont
+	}
+	if string(data[:4]) == "ttcf" {
+		// Font collection: use the first font.
+		if len(data) < 16 || binary.BigEndian.Uint32(data[8:]) == 0 {
+			return nil, errInvalidFont
+		}
+		offset := binary.BigEndian.Uint32(data[12:])
+		if int64(offset)+12 > int64(len(data)) {
+			return nil, errInvalidFont
+		}
+		return readTableDirectory(data, data[offset:])
+	}
+	return readTableDirectory(data, data)
+}
+
+// readTableDirectory reads the table directory `dir` of the font file `data`.
+func readTableDirectory(data, dir []byte) (map[string][]byte, error) {
+	if len(dir) < 12 {
+		return nil, errInvalidFont
+	}
+	numTables := int(binary.BigEndian.Uint16(dir[4:]))
+	if len(dir) < 12+16*numTables {
+		return nil, errInvalidFont
+	}
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		rec := dir[12+16*i:]
+		tag := string(rec[:4])
+		offset := int64(binary.BigEndian.Uint32(rec[8:]))
+		length := int64(binary.BigEndian.Uint32(rec[12:]))
+		if offset+length > int64(len(data)) {
+			// Some subset fonts have truncated tables. Keep what is there.
+			if offset > int64(len(data)) {
+				continue
+			}
+			length = int64(len(data)) - offset
+		}
+		tables[tag] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// parseLoca returns the glyph offsets in the loca table `loca`.
+func parseLoca(loca []byte, numGlyphs int, long bool) ([]uint32, error) {
+	offsets := make([]uint32, 0, numGlyphs+1)
+	if long {
+		if len(loca) < 4*(numGlyphs+1) {
+			return nil, errors.New("invalid loca table")
+		}
+		for i := 0; i <= numGlyphs; i++ {
+			offsets = append(offsets, binary.BigEndian.Uint32(loca[4*i:]))
+		}
+	} else {
+		if len(loca) < 2*(numGlyphs+1) {
+			return nil, errors.New("invalid loca table")
+		}
+		for i := 0; i <= numGlyphs; i++ {
+			offsets = append(offsets, 2*uint32(binary.BigEndian.Uint16(loca[2*i:])))
+		}
+	}
+	return offsets, nil
+}
+
+// parseCmap returns the supported subtables of the cmap table `data`.
+func parseCmap(data []byte) []cmapTable {
+	if len(data) < 4 {
+		return nil
+	}
+	var tables []cmapTable
+	n := int(binary.BigEndian.Uint16(data[2:]))
+	for i := 0; i < n && 4+8*i+8 <= len(data); i++ {
+		rec := data[4+8*i:]
+		offset := binary.BigEndian.Uint32(rec[4:])
+		if int64(offset)+4 > int64(len(data)) {
+			continue
+		}
+		m := parseCmapSubtable(data[offset:])
+		if m == nil {
+			continue
+		}
+		tables = append(tables, cmapTable{
+			platform: binary.BigEndian.Uint16(rec),
+			encoding: binary.BigEndian.Uint16(rec[2:]),
+			m:        m,
+		})
+	}
+	return tables
+}
+
+// parseCmapSubtable parses a cmap subtable of format 0, 4, 6 or 12.
+func parseCmapSubtable(d []byte) map[uint32]uint16 {
+	m := make(map[uint32]uint16)
+	switch binary.BigEndian.Uint16(d) {
+	case 0:
+		if len(d) < 6+256 {
+			return nil
+		}
+		for i, gid := range d[6 : 6+256] {
+			if gid != 0 {
+				m[uint32(i)] = uint16(gid)
+			}
+		}
+	case 4:
+		if len(d) < 14 {
+			return nil
+		}
+		segX2 := int(binary.BigEndian.Uint16(d[6:]))
+		if len(d) < 16+4*segX2 {
+			return nil
+		}
+		ends := d[14:]
+		starts := d[16+segX2:]
+		deltas := d[16+2*segX2:]
+		rangeOffsets := d[16+3*segX2:]
+		for s := 0; s < segX2; s += 2 {
+			end := uint32(binary.BigEndian.Uint16(ends[s:]))
+			start := uint32(binary.BigEndian.Uint16(starts[s:]))
+			delta := binary.BigEndian.Uint16(deltas[s:])
+			ro := int(binary.BigEndian.Uint16(rangeOffsets[s:]))
+			for c := start; c <= end && c != 0xffff; c++ {
+				var gid uint16
+				if ro == 0 {
+					gid = uint16(c) + delta
+				} else {
+					i := 16 + 3*segX2 + s + ro + 2*int(c-start)
+					if i+2 > len(d) {
+						break
+					}
+					gid = binary.BigEndian.Uint16(d[i:])
+					if gid != 0 {
+						gid += delta
+					}
+				}
+				if gid != 0 {
+					m[c] = gid
+				}
+			}
+		}
+	case 6:
+		if len(d) < 10 {
+			return nil
+		}
+		first := uint32(binary.BigEndian.Uint16(d[6:]))
+		count := int(binary.BigEndian.Uint16(d[8:]))
+		for i := 0; i < count && 10+2*i+2 <= len(d); i++ {
+			if gid := binary.BigEndian.Uint16(d[10+2*i:]); gid != 0 {
+				m[first+uint32(i)] = gid
+			}
+		}
+	case 12:
+		if len(d) < 16 {
+			return nil
+		}
+		n := int(binary.BigEndian.Uint32(d[12:]))
+		for i := 0; i < n && 16+12*i+12 <= len(d); i++ {
+			g := d[16+12*i:]
+			start := binary.BigEndian.Uint32(g)
+			end := binary.BigEndian.Uint32(g[4:])
+			gid := binary.BigEndian.Uint32(g[8:])
+			if end < start || end-start > 0xffff {
+				continue
+			}
+			for c := start; c <= end; c++ {
+				m[c] = uint16(gid + c - start)
+			}
+		}
+	default:
+		return nil
+	}
+	return m
+}
+
+// parsePost returns the glyph names of the post table `data`, or nil if it has none.
+func parsePost(data []byte) map[string]uint16 {
+	if len(data) < 32 {
+		return nil
+	}
+	names := make(map[string]uint16)
+	switch binary.BigEndian.Uint32(data) {
+	case 0x00010000:
+		for i, name := range macGlyphNames {
+			names[name] = uint16(i)
+		}
+	case 0x00020000:
+		if len(data) < 34 {
+			return nil
+		}
+		n := int(binary.BigEndian.Uint16(data[32:]))
+		if len(data) < 34+2*n {
+			return nil
+		}
+		var strs []string
+		for d := data[34+2*n:]; len(d) > 0 && len(d) > int(d[0]); d = d[1+int(d[0]):] {
+			strs = append(strs, string(d[1:1+int(d[0])]))
+		}
+		for gid := 0; gid < n; gid++ {
+			i := int(binary.BigEndian.Uint16(data[34+2*gid:]))
+			var name string
+			if i < len(macGlyphNames) {
+				name = macGlyphNames[i]
+			} else if i-len(macGlyphNames) < len(strs) {
+				name = strs[i-len(macGlyphNames)]
+			} else {
+				continue
+			}
+			if _, ok := names[name]; !ok {
+				names[name] = uint16(gid)
+			}
+		}
+	default:
+		return nil
+	}
+	return names
+}
+
+// UnitsPerEm returns the number of font units per em.
+func (f *TrueType) UnitsPerEm() int {
+	return f.unitsPerEm
+}
+
+// NumGlyphs returns the number of glyphs in the font.
+func (f *TrueType) NumGlyphs() int {
+	return f.numGlyphs
+}
+
+// FontMatrix returns the transform from glyph space to text space.
+func (f *TrueType) FontMatrix() [6]float64 {
+	if f.cff != nil {
+		return f.cff.FontMatrix()
+	}
+	s := 1 / float64(f.unitsPerEm)
+	return [6]float64{s, 0, 0, s, 0, 0}
+}
+
+// CFF returns the CFF outlines of an OpenType font, or nil if the font has TrueType outlines.
+func (f *TrueType) CFF() *CFF {
+	return f.cff
+}
+
+// Advance returns the advance width of glyph `gid` in font units.
+func (f *TrueType) Advance(gid uint16) (int, bool) {
+	if len(f.advances) == 0 {
+		return 0, false
+	}
+	if int(gid) >= len(f.advances) {
+		// The last advance width applies to the remaining glyphs.
+		gid = uint16(len(f.advances) - 1)
+	}
+	return int(f.advances[gid]), true
+}
+
+// HasCmap returns true if the font has a cmap subtable for `platform` and `encoding`.
+func (f *TrueType) HasCmap(platform, encoding uint16) bool {
+	for _, t := range f.cmaps {
+		if t.platform == platform && t.encoding == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the glyph index of character code `code` in the cmap subtable for `platform`
+// and `encoding`.
+func (f *TrueType) Lookup(platform, encoding uint16, code uint32) (uint16, bool) {
+	for _, t := range f.cmaps {
+		if t.platform == platform && t.encoding == encoding {
+			gid, ok := t.m[code]
+			return gid, ok
+		}
+	}
+	return 0, false
+}
+
+// GlyphIndex returns the glyph index of `r` in the first Unicode cmap subtable of the font.
+func (f *TrueType) GlyphIndex(r rune) (uint16, bool) {
+	for _, t := range f.cmaps {
+		if t.platform == 0 || (t.platform == 3 && (t.encoding == 1 || t.encoding == 10)) {
+			if gid, ok := t.m[uint32(r)]; ok {
+				return gid, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// GlyphIndexByName returns the glyph index of the glyph called `name`, from the post table or,
+// for OpenType fonts with CFF outlines, the CFF charset.
+func (f *TrueType) GlyphIndexByName(name string) (uint16, bool) {
+	if gid, ok := f.names[name]; ok {
+		return gid, true
+	}
+	if f.cff != nil {
+		return f.cff.GlyphIndexByName(name)
+	}
+	return 0, false
+}
+
+// Glyph returns the outline of glyph `gid`.
+func (f *TrueType) Glyph(gid uint16) (Glyph, error) {
+	if f.cff != nil {
+		return f.cff.Glyph(gid)
+	}
+	b := &pathBuilder{}
+	if err := f.appendGlyph(b, gid, [6]float64{1, 0, 0, 1, 0, 0}, 0); err != nil {
+		return nil, err
+	}
+	return b.glyph, nil
+}
+
+// Composite glyph flags.
+const (
+	argsAreWords   = 0x0001
+	argsAreXY      = 0x0002
+	haveScale      = 0x0008
+	moreComponents = 0x0020
+	haveXYScale    = 0x0040
+	haveTwoByTwo   = 0x0080
+)
+
+// maxCompositeDepth limits the nesting of composite glyphs.
+const maxCompositeDepth = 8
+
+// appendGlyph appends the outline of glyph `gid` transformed by `m` to `b`.
+func (f *TrueType) appendGlyph(b *pathBuilder, gid uint16, m [6]float64, depth int) error {
+	if int(gid) >= f.numGlyphs || int(gid)+1 >= len(f.loca) {
+		return ErrNoGlyph
+	}
+	start, end := f.loca[gid], f.loca[gid+1]
+	if start >= end {
+		// Empty glyph.
+		return nil
+	}
+	if int64(end) > int64(len(f.glyf)) || end-start < 10 {
+		return errInvalidGlyph
+	}
+	g := f.glyf[start:end]
+	numContours := int16(binary.BigEndian.Uint16(g))
+	if numContours >= 0 {
+		return appendSimpleGlyph(b, g, int(numContours), m)
+	}
+	if depth >= maxCompositeDepth {
+		return errInvalidGlyph
+	}
+
+	d := g[10:]
+	for {
+		if len(d) < 4 {
+			return errInvalidGlyph
+		}
+		flags := binary.BigEndian.Uint16(d)
+		component := binary.BigEndian.Uint16(d[2:])
+		d = d[4:]
+		var dx, dy float64
+		if flags&argsAreWords != 0 {
+			if len(d) < 4 {
+				return errInvalidGlyph
+			}
+			dx = float64(int16(binary.BigEndian.Uint16(d)))
+			dy = float64(int16(binary.BigEndian.Uint16(d[2:])))
+			d = d[4:]
+		} else {
+			if len(d) < 2 {
+				return errInvalidGlyph
+			}
+			dx, dy = float64(int8(d[0])), float64(int8(d[1]))
+			d = d[2:]
+		}
+		if flags&argsAreXY == 0 {
+			// Point matching is not supported.
+			dx, dy = 0, 0
+		}
+		a, bb, c, dd := 1.0, 0.0, 0.0, 1.0
+		switch {
+		case flags&haveScale != 0:
+			if len(d) < 2 {
+				return errInvalidGlyph
+			}
+			a = f2dot14(d)
+			dd = a
+			d = d[2:]
+		case flags&haveXYScale != 0:
+			if len(d) < 4 {
+				return errInvalidGlyph
+			}
+			a, dd = f2dot14(d), f2dot14(d[2:])
+			d = d[4:]
+		case flags&haveTwoByTwo != 0:
+			if len(d) < 8 {
+				return errInvalidGlyph
+			}
+			a, bb, c, dd = f2dot14(d), f2dot14(d[2:]), f2dot14(d[4:]), f2dot14(d[6:])
+			d = d[8:]
+		}
+		cm := concat([6]float64{a, bb, c, dd, dx, dy}, m)
+		if err := f.appendGlyph(b, component, cm, depth+1); err != nil && err != ErrNoGlyph {
+			return err
+		}
+		if flags&moreComponents == 0 {
+			return nil
+		}
+	}
+}
+
+// f2dot14 reads a 2.14 fixed point number.
+func f2dot14(d []byte) float64 {
+	return float64(int16(binary.BigEndian.Uint16(d))) / (1 << 14)
+}
+
+// concat returns the transform `m1` followed by `m2`.
+func concat(m1, m2 [6]float64) [6]float64 {
+	return [6]float64{
+		m1[0]*m2[0] + m1[1]*m2[2],
+		m1[0]*m2[1] + m1[1]*m2[3],
+		m1[2]*m2[0] + m1[3]*m2[2],
+		m1[2]*m2[1] + m1[3]*m2[3],
+		m1[4]*m2[0] + m1[5]*m2[2] + m2[4],
+		m1[4]*m2[1] + m1[5]*m2[3] + m2[5],
+	}
+}
+
+// Simple glyph flags.
+const (
+	flagOnCurve = 0x01
+	flagXShort  = 0x02
+	flagYShort  = 0x04
+	flagRepeat  = 0x08
+	flagXSame   = 0x10
+	flagYSame   = 0x20
+)
+
+// appendSimpleGlyph appends the outline of the simple glyph `g` with `numContours` contours
+// transformed by `m` to `b`.
+func appendSimpleGlyph(b *pathBuilder, g []byte, numContours int, m [6]float64) error {
+	d := g[10:]
+	if len(d) < 2*numContours+2 {
+		return errInvalidGlyph
+	}
+	ends := make([]int, numContours)
+	numPoints := 0
+	for i := range ends {
+		ends[i] = int(binary.BigEndian.Uint16(d[2*i:]))
+		if ends[i] < numPoints-1 {
+			return errInvalidGlyph
+		}
+		numPoints = ends[i] + 1
+	}
+	d = d[2*numContours:]
+	insLen := int(binary.BigEndian.Uint16(d))
+	if len(d) < 2+insLen {
+		return errInvalidGlyph
+	}
+	d = d[2+insLen:]
+
+	flags := make([]byte, 0, numPoints)
+	for len(flags) < numPoints {
+		if len(d) == 0 {
+			return errInvalidGlyph
+		}
+		fl := d[0]
+		d = d[1:]
+		flags = append(flags, fl)
+		if fl&flagRepeat != 0 {
+			if len(d) == 0 {
+				return errInvalidGlyph
+			}
+			for n := int(d[0]); n > 0 && len(flags) < numPoints; n-- {
+				flags = append(flags, fl)
+			}
+			d = d[1:]
+		}
+	}
+
+	readCoords := func(short, same byte) ([]int, error) {
+		coords := make([]int, numPoints)
+		v := 0
+		for i, fl := range flags {
+			switch {
+			case fl&short != 0:
+				if len(d) < 1 {
+					return nil, errInvalidGlyph
+				}
+				if fl&same != 0 {
+					v += int(d[0])
+				} else {
+					v -= int(d[0])
+				}
+				d = d[1:]
+			case fl&same == 0:
+				if len(d) < 2 {
+					return nil, errInvalidGlyph
+				}
+				v += int(int16(binary.BigEndian.Uint16(d)))
+				d = d[2:]
+			}
+			coords[i] = v
+		}
+		return coords, nil
+	}
+	xs, err := readCoords(flagXShort, flagXSame)
+	if err != nil {
+		return err
+	}
+	ys, err := readCoords(flagYShort, flagYSame)
+	if err != nil {
+		return err
+	}
+
+	point := func(i int) Point {
+		x, y := float64(xs[i]), float64(ys[i])
+		return Point{m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]}
+	}
+	mid := func(p, q Point) Point {
+		return Point{(p.X + q.X) / 2, (p.Y + q.Y) / 2}
+	}
+
+	start := 0
+	for _, end := range ends {
+		n := end - start + 1
+		if n < 2 {
+			start = end + 1
+			continue
+		}
+		// Find the starting on-curve point, implying one between two off-curve points if needed.
+		first := -1
+		for i := 0; i < n; i++ {
+			if flags[start+i]&flagOnCurve != 0 {
+				first = i
+				break
+			}
+		}
+		var p0 Point
+		if first < 0 {
+			p0 = mid(point(start), point(start+1))
+			first = 1
+		} else {
+			p0 = point(start + first)
+			first++
+		}
+		b.glyph = append(b.glyph, Segment{Op: MoveTo, Points: [3]Point{p0}})
+
+		var ctrl Point
+		haveCtrl := false
+		for k := 0; k < n; k++ {
+			i := start + (first+k)%n
+			p := point(i)
+			if flags[i]&flagOnCurve != 0 {
+				if haveCtrl {
+					b.glyph = append(b.glyph, Segment{Op: QuadTo, Points: [3]Point{ctrl, p}})
+					haveCtrl = false
+				} else {
+					b.glyph = append(b.glyph, Segment{Op: LineTo, Points: [3]Point{p}})
+				}
+				continue
+			}
+			if haveCtrl {
+				b.glyph = append(b.glyph, Segment{Op: QuadTo, Points: [3]Point{ctrl, mid(ctrl, p)}})
+			}
+			ctrl = p
+			haveCtrl = true
+		}
+		if haveCtrl {
+			b.glyph = append(b.glyph, Segment{Op: QuadTo, Points: [3]Point{ctrl, p0}})
+		}
+		start = end + 1
+	}
+	return nil
+}
+
+// macGlyphNames are the 258 standard Macintosh glyph names used in post table formats 1 and 2.
+var macGlyphNames = []string{
+	".notdef", ".null", "nonmarkingreturn", "space", "exclam", "quotedbl",
+	"numbersign", "dollar", "percent", "ampersand", "quotesingle",
+	"parenleft", "parenright", "asterisk", "plus", "comma", "hyphen",
+	"period", "slash", "zero", "one", "two", "three", "four", "five",
+	"six", "seven", "eight", "nine", "colon", "semicolon", "less",
+	"equal", "greater", "question", "at", "A", "B", "C", "D", "E", "F",
+	"G", "H", "I", "J", "K", "L", "M", "N", "O", "P", "Q", "R", "S",
+	"T", "U", "V", "W", "X", "Y", "Z", "bracketleft", "backslash",
+	"bracketright", "asciicircum", "underscore", "grave", "a", "b",
+	"c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o",
+	"p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "braceleft",
+	"bar", "braceright", "asciitilde", "Adieresis", "Aring",
+	"Ccedilla", "Eacute", "Ntilde", "Odieresis", "Udieresis", "aacute",
+	"agrave", "acircumflex", "adieresis", "atilde", "aring",
+	"ccedilla", "eacute", "egrave", "ecircumflex", "edieresis",
+	"iacute", "igrave", "icircumflex", "idieresis", "ntilde", "oacute",
+	"ograve", "ocircumflex", "odieresis", "otilde", "uacute", "ugrave",
+	"ucircumflex", "udieresis", "dagger", "degree", "cent", "sterling",
+	"section", "bullet", "paragraph", "germandbls", "registered",
+	"copyright", "trademark", "acute", "dieresis", "notequal", "AE",
+	"Oslash", "infinity", "plusminus", "lessequal", "greaterequal",
+	"yen", "mu", "partialdiff", "summation", "product", "pi",
+	"integral", "ordfeminine", "ordmasculine", "Omega", "ae", "oslash",
+	"questiondown", "exclamdown", "logicalnot", "radical", "florin",
+	"approxequal", "Delta", "guillemotleft", "guillemotright",
+	"ellipsis", "nonbreakingspace", "Agrave", "Atilde", "Otilde", "OE",
+	"oe", "endash", "emdash", "quotedblleft", "quotedblright",
+	"quoteleft", "quoteright", "divide", "lozenge", "ydieresis",
+	"Ydieresis", "fraction", "currency", "guilsinglleft",
+	"guilsinglright", "fi", "fl", "daggerdbl", "periodcentered",
+	"quotesinglbase", "quotedblbase", "perthousand", "Acircumflex",
+	"Ecircumflex", "Aacute", "Edieresis", "Egrave", "Iacute",
+	"Icircumflex", "Idieresis", "Igrave", "Oacute", "Ocircumflex",
+	"apple", "Ograve", "Uacute", "Ucircumflex", "Ugrave", "dotlessi",
+	"circumflex", "tilde", "macron", "breve", "dotaccent", "ring",
+	"cedilla", "hungarumlaut", "ogonek", "caron", "Lslash", "lslash",
+	"Scaron", "scaron", "Zcaron", "zcaron", "brokenbar", "Eth", "eth",
+	"Yacute", "yacute", "Thorn", "thorn", "minus", "multiply",
+	"onesuperior", "twosuperior", "threesuperior", "onehalf",
+	"onequarter", "threequarters", "franc", "Gbreve", "gbreve",
+	"Idotaccent", "Scedilla", "scedilla", "Cacute", "cacute", "Ccaron",
+	"ccaron", "dcroat",
+}
@@ -0,0 +1,564 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package outline
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+// Type1 is a Type 1 font program (Adobe Type 1 Font Format).
+type Type1 struct {
+	fontMatrix  [6]float64
+	encoding    map[byte]string
+	standard    bool // True if the font uses the standard encoding.
+	subrs       [][]byte
+	charStrings map[string][]byte
+}
+
+// Keys for the eexec and charstring encryption.
+const (
+	eexecKey      = 55665
+	charStringKey = 4330
+)
+
+var (
+	reFontMatrix    = regexp.MustCompile(`/FontMatrix\s*[\[{]([^\]}]*)[\]}]`)
+	reEncodingEntry = regexp.MustCompile(`dup\s+(\d+)\s*/(\S+)\s+put`)
+	reLenIV         = regexp.MustCompile(`/lenIV\s+(-?\d+)`)
+)
+
+// ParseType1 parses the Type 1 font program `data`, either in the PFA/FontFile form or in the PFB
+// form.
+func ParseType1(data []byte) (*Type1, error) {
+	if len(data) >= 2 && data[0] == 0x80 && data[1] == 0x01 {
+		data = stripPFB(data)
+	}
+	idx := bytes.Index(data, []byte("eexec"))
+	if idx < 0 {
+		return nil, errors.New("Type 1 font without eexec section")
+	}
+	clear := data[:idx]
+	encrypted := data[idx+len("eexec"):]
+	for len(encrypted) > 0 && isSpace(encrypted[0]) {
+		encrypted = encrypted[1:]
+	}
+	if isHexPrefix(encrypted) {
+		encrypted = decodeHex(encrypted)
+	}
+	private := decrypt(encrypted, eexecKey, 4)
+
+	f := &Type1{
+		fontMatrix:  [6]float64{0.001, 0, 0, 0.001, 0, 0},
+		charStrings: make(map[string][]byte),
+	}
+	if m := reFontMatrix.FindSubmatch(clear); m != nil {
+		fields := bytes.Fields(m[1])
+		if len(fields) == 6 {
+			for i, v := range fields {
+				f.fontMatrix[i], _ = strconv.ParseFloat(string(v), 64)
+			}
+		}
+	}
+	if bytes.Contains(clear, []byte("/Encoding StandardEncoding")) {
+		f.standard = true
+	} else {
+		f.encoding = make(map[byte]string)
+		for _, m := range reEncodingEntry.FindAllSubmatch(clear, -1) {
+			code, err := strconv.Atoi(string(m[1]))
+			if err == nil && code >= 0 && code < 256 {
+				f.encoding[byte(code)] = string(m[2])
+			}
+		}
+	}
+
+	lenIV := 4
+	if m := reLenIV.FindSubmatch(private); m != nil {
+		lenIV, _ = strconv.Atoi(string(m[1]))
+	}
+	decryptCharString := func(d []byte) []byte {
+		if lenIV < 0 {
+			return d
+		}
+		return decrypt(d, charStringKey, lenIV)
+	}
+
+	if i := bytes.Index(private, []byte("/Subrs")); i >= 0 {
+		p := &type1Scanner{data: private, pos: i + len("/Subrs")}
+		p.token() // Number of subroutines.
+		p.token() // array
+		for p.peek() == "dup" {
+			p.token()
+			n, err1 := strconv.Atoi(p.token())
+			d, err2 := p.binary()
+			if err1 != nil || err2 != nil || n < 0 || n > 65535 {
+				break
+			}
+			for len(f.subrs) <= n {
+				f.subrs = append(f.subrs, nil)
+			}
+			f.subrs[n] = decryptCharString(d)
+			p.skipSubr()
+		}
+	}
+	i := bytes.Index(private, []byte("/CharStrings"))
+	if i < 0 {
+		return nil, errors.New("Type 1 font without CharStrings")
+	}
+	p := &type1Scanner{data: private, pos: i + len("/CharStrings")}
+	for t := p.peek(); t != "" && t[0] != '/'; t = p.peek() {
+		p.token() // Skip "n dict dup begin".
+	}
+	for t := p.peek(); len(t) > 1 && t[0] == '/'; t = p.peek() {
+		name := p.token()[1:]
+		d, err := p.binary()
+		if err != nil {
+			break
+		}
+		f.charStrings[name] = decryptCharString(d)
+		p.skipName()
+	}
+	if len(f.charStrings) == 0 {
+		return nil, errors.New("Type 1 font without glyphs")
+	}
+	return f, nil
+}
+
+// stripPFB returns the font program without the segment headers of the PFB file `data`.
+func stripPFB(data []byte) []byte {
+	var out []byte
+	for len(data) >= 6 && data[0] == 0x80 && data[1] != 3 {
+		n := int(binary.LittleEndian.Uint32(data[2:]))
+		data = data[6:]
+		if n > len(data) {
+			n = len(data)
+		}
+		out = append(out, data[:n]...)
+		data = data[n:]
+	}
+	return out
+}
+
+// decrypt decrypts `data` with the initial key `r` and drops the first `skip` bytes.
+func decrypt(data []byte, r uint16, skip int) []byte {
+	const c1, c2 = 52845, 22719
+	out := make([]byte, len(data))
+	for i, c := range data {
+		out[i] = c ^ byte(r>>8)
+		r = (uint16(c)+r)*c1 + c2
+	}
+	if skip > len(out) {
+		skip = len(out)
+	}
+	return out[skip:]
+}
+
+// isHexPrefix returns true if the eexec section `data` is hex encoded.
+func isHexPrefix(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	for _, c := range data[:4] {
+		if !isHexDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeHex decodes hex encoded `data`, ignoring white space.
+func decodeHex(data []byte) []byte {
+	digits := make([]byte, 0, len(data))
+	for _, c := range data {
+		if isHexDigit(c) {
+			digits = append(digits, c)
+		} else if !isSpace(c) {
+			break
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = digits[:len(digits)-1]
+	}
+	out := make([]byte, len(digits)/2)
+	hex.Decode(out, digits)
+	return out
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+// type1Scanner splits the decrypted private section of a Type 1 font into tokens.
+type type1Scanner struct {
+	data []byte
+	pos  int
+}
+
+// token returns the next token.
+func (p *type1Scanner) token() string {
+	for p.pos < len(p.data) && isSpace(p.data[p.pos]) {
+		p.pos++
+	}
+	start := p.pos
+	for p.pos < len(p.data) && !isSpace(p.data[p.pos]) {
+		p.pos++
+		if p.pos < len(p.data) && p.data[p.pos] == '/' {
+			break
+		}
+	}
+	return string(p.data[start:p.pos])
+}
+
+// peek returns the next token without consuming it.
+func (p *type1Scanner) peek() string {
+	pos := p.pos
+	t := p.token()
+	p.pos = pos
+	return t
+}
+
+// binary reads a "n RD <n bytes>" binary string.
+func (p *type1Scanner) binary() ([]byte, error) {
+	n, err := strconv.Atoi(p.token())
+	if err != nil || n < 0 {
+		return nil, errInvalidFont
+	}
+	p.token() // RD or -|
+	p.pos++   // The single space before the binary data.
+	if p.pos+n > len(p.data) {
+		return nil, errInvalidFont
+	}
+	d := p.data[p.pos : p.pos+n]
+	p.pos += n
+	return d, nil
+}
+
+// skipSubr skips the tokens after the charstring of a subroutine, up to the next subroutine.
+func (p *type1Scanner) skipSubr() {
+	for t := p.peek(); t != "" && t != "dup" && t[0] != '/'; t = p.peek() {
+		p.token()
+	}
+}
+
+// skipName skips the tokens after the charstring of a glyph, up to the next glyph name.
+func (p *type1Scanner) skipName() {
+	for t := p.peek(); t != "" && t[0] != '/' && t != "end"; t = p.peek() {
+		p.token()
+	}
+}
+
+// FontMatrix returns the transform from glyph space to text space.
+func (f *Type1) FontMatrix() [6]float64 {
+	return f.fontMatrix
+}
+
+// GlyphName returns the name of the glyph with code `code` in the built-in encoding of the font.
+func (f *Type1) GlyphName(code byte) (string, bool) {
+	if f.standard {
+		return standardGlyphName(code)
+	}
+	name, ok := f.encoding[code]
+	return name, ok
+}
+
+// HasGlyph returns true if the font has a glyph called `name`.
+func (f *Type1) HasGlyph(name string) bool {
+	_, ok := f.charStrings[name]
+	return ok
+}
+
+// Glyph returns the outline of the glyph called `name`.
+func (f *Type1) Glyph(name string) (Glyph, error) {
+	b := &pathBuilder{}
+	if err := f.appendGlyph(b, name, 0, 0, 0); err != nil {
+		return nil, err
+	}
+	return b.glyph, nil
+}
+
+// appendGlyph appends the outline of glyph `name` offset by `dx`,`dy` to `b`.
+func (f *Type1) appendGlyph(b *pathBuilder, name string, dx, dy float64, depth int) error {
+	code, ok := f.charStrings[name]
+	if !ok {
+		return ErrNoGlyph
+	}
+	in := &type1Interpreter{font: f, b: b, dx: dx, dy: dy, depth: depth}
+	b.open = false
+	err := in.run(code, 0)
+	if err == errEndChar {
+		err = nil
+	}
+	return err
+}
+
+// Limits of the Type 1 charstring interpreter.
+const maxType1Stack = 24
+
+// type1Interpreter interprets Type 1 charstrings (Adobe Type 1 Font Format, chapter 6).
+type type1Interpreter struct {
+	font       *Type1
+	b          *pathBuilder
+	stack      []float64
+	psStack    []float64 // Results of OtherSubrs, retrieved with pop.
+	dx, dy     float64   // Offset of the glyph origin.
+	lsb        float64   // Left side bearing set by hsbw or sbw.
+	flex       bool
+	flexPoints []Point
+	depth      int // Nesting depth of seac compositions.
+}
+
+// moveTo moves the current point, or records a flex point between the OtherSubrs 1 and 0.
+func (in *type1Interpreter) moveTo(x, y float64) {
+	if in.flex {
+		in.b.x, in.b.y = x, y
+		in.flexPoints = append(in.flexPoints, Point{x, y})
+		return
+	}
+	in.b.moveTo(x, y)
+}
+
+// run interprets the charstring `code`. `level` is the subroutine nesting level.
+func (in *type1Interpreter) run(code []byte, level int) error {
+	if level > maxSubrDepth {
+		return errInvalidGlyph
+	}
+	b := in.b
+	for i := 0; i < len(code); {
+		b0 := code[i]
+		i++
+		switch {
+		case b0 >= 32 && b0 <= 246:
+			in.stack = append(in.stack, float64(int(b0)-139))
+			continue
+		case b0 >= 247 && b0 <= 254:
+			if i >= len(code) {
+				return errInvalidGlyph
+			}
+			v := (int(b0)-247)*256 + int(code[i]) + 108
+			if b0 >= 251 {
+				v = -(int(b0)-251)*256 - int(code[i]) - 108
+			}
+			in.stack = append(in.stack, float64(v))
+			i++
+			continue
+		case b0 == 255:
+			if i+4 > len(code) {
+				return errInvalidGlyph
+			}
+			in.stack = append(in.stack, float64(int32(binary.BigEndian.Uint32(code[i:]))))
+			i += 4
+			continue
+		}
+		if len(in.stack) > maxType1Stack {
+			return errInvalidGlyph
+		}
+
+		s := in.stack
+		need := func(n int) bool { return len(s) >= n }
+		switch b0 {
+		case 13: // hsbw
+			if !need(2) {
+				return errInvalidGlyph
+			}
+			in.lsb = s[0]
+			b.x, b.y = in.dx+s[0], in.dy
+		case 21: // rmoveto
+			if !need(2) {
+				return errInvalidGlyph
+			}
+			in.moveTo(b.x+s[0], b.y+s[1])
+		case 22: // hmoveto
+			if !need(1) {
+				return errInvalidGlyph
+			}
+			in.moveTo(b.x+s[0], b.y)
+		case 4: // vmoveto
+			if !need(1) {
+				return errInvalidGlyph
+			}
+			in.moveTo(b.x, b.y+s[0])
+		case 5: // rlineto
+			if !need(2) {
+				return errInvalidGlyph
+			}
+			b.lineTo(b.x+s[0], b.y+s[1])
+		case 6: // hlineto
+			if !need(1) {
+				return errInvalidGlyph
+			}
+			b.lineTo(b.x+s[0], b.y)
+		case 7: // vlineto
+			if !need(1) {
+				return errInvalidGlyph
+			}
+			b.lineTo(b.x, b.y+s[0])
+		case 8: // rrcurveto
+			if !need(6) {
+				return errInvalidGlyph
+			}
+			in.curve(s[0], s[1], s[2], s[3], s[4], s[5])
+		case 30: // vhcurveto
+			if !need(4) {
+				return errInvalidGlyph
+			}
+			in.curve(0, s[0], s[1], s[2], s[3], 0)
+		case 31: // hvcurveto
+			if !need(4) {
+				return errInvalidGlyph
+			}
+			in.curve(s[0], 0, s[1], s[2], 0, s[3])
+		case 9: // closepath
+			b.open = false
+		case 10: // callsubr
+			if !need(1) {
+				return errInvalidGlyph
+			}
+			n := int(s[len(s)-1])
+			in.stack = s[:len(s)-1]
+			if n < 0 || n >= len(in.font.subrs) {
+				return errInvalidGlyph
+			}
+			if err := in.run(in.font.subrs[n], level+1); err != nil {
+				return err
+			}
+			continue
+		case 11: // return
+			return nil
+		case 14: // endchar
+			return errEndChar
+		case 12:
+			if i >= len(code) {
+				return errInvalidGlyph
+			}
+			op := code[i]
+			i++
+			clear, err := in.escape(op)
+			if err != nil {
+				return err
+			}
+			if !clear {
+				continue
+			}
+		}
+		in.stack = in.stack[:0]
+	}
+	return nil
+}
+
+// escape interprets the two byte operator 12 `op` and returns true if it clears the stack.
+func (in *type1Interpreter) escape(op byte) (bool, error) {
+	b := in.b
+	s := in.stack
+	switch op {
+	case 6: // seac
+		if len(s) < 5 {
+			return false, errInvalidGlyph
+		}
+		return false, in.seac(s[0], s[1], s[2], s[3], s[4])
+	case 7: // sbw
+		if len(s) < 4 {
+			return false, errInvalidGlyph
+		}
+		in.lsb = s[0]
+		b.x, b.y = in.dx+s[0], in.dy+s[1]
+	case 12: // div
+		if len(s) < 2 || s[len(s)-1] == 0 {
+			return false, errInvalidGlyph
+		}
+		in.stack = append(s[:len(s)-2], s[len(s)-2]/s[len(s)-1])
+		return false, nil
+	case 16: // callothersubr
+		if len(s) < 2 {
+			return false, errInvalidGlyph
+		}
+		othersubr, n := int(s[len(s)-1]), int(s[len(s)-2])
+		s = s[:len(s)-2]
+		if n < 0 || n > len(s) {
+			return false, errInvalidGlyph
+		}
+		args := s[len(s)-n:]
+		in.stack = s[:len(s)-n]
+		in.callOtherSubr(othersubr, args)
+		return false, nil
+	case 17: // pop
+		if len(in.psStack) > 0 {
+			in.stack = append(in.stack, in.psStack[len(in.psStack)-1])
+			in.psStack = in.psStack[:len(in.psStack)-1]
+		}
+		return false, nil
+	case 33: // setcurrentpoint
+		if len(s) < 2 {
+			return false, errInvalidGlyph
+		}
+		b.x, b.y = in.dx+s[0], in.dy+s[1]
+	}
+	return true, nil
+}
+
+// callOtherSubr runs the standard OtherSubrs that affect the outline: the flex mechanism (0, 1
+// and 2). Other OtherSubrs, including hint replacement (3), return their arguments.
+func (in *type1Interpreter) callOtherSubr(othersubr int, args []float64) {
+	switch othersubr {
+	case 0:
+		// The flex points are the reference point followed by the points of two curves.
+		in.flex = false
+		if p := in.flexPoints; len(p) >= 7 {
+			in.b.curveTo(p[1].X, p[1].Y, p[2].X, p[2].Y, p[3].X, p[3].Y)
+			in.b.curveTo(p[4].X, p[4].Y, p[5].X, p[5].Y, p[6].X, p[6].Y)
+		}
+		in.flexPoints = nil
+		if len(args) == 3 {
+			// The end point is retrieved by "pop pop setcurrentpoint".
+			in.psStack = append(in.psStack, args[2], args[1])
+		}
+	case 1:
+		in.flex = true
+		in.flexPoints = nil
+	case 2:
+	default:
+		in.psStack = append(in.psStack, args...)
+	}
+}
+
+// curve appends a curve with control points relative to the current point and to each other.
+func (in *type1Interpreter) curve(dx1, dy1, dx2, dy2, dx3, dy3 float64) {
+	b := in.b
+	x1, y1 := b.x+dx1, b.y+dy1
+	x2, y2 := x1+dx2, y1+dy2
+	b.curveTo(x1, y1, x2, y2, x2+dx3, y2+dy3)
+}
+
+// seac composes an accented character from the base glyph with standard encoding code `bchar`
+// and the accent glyph with code `achar`, whose left side bearing is `asb`, offset by `adx`,`ady`.
+func (in *type1Interpreter) seac(asb, adx, ady, bchar, achar float64) error {
+	if in.depth >= maxCompositions {
+		return errInvalidGlyph
+	}
+	base, ok := standardGlyphName(byte(bchar))
+	if !ok {
+		return ErrNoGlyph
+	}
+	accent, ok := standardGlyphName(byte(achar))
+	if !ok {
+		return ErrNoGlyph
+	}
+	f := in.font
+	if err := f.appendGlyph(in.b, base, in.dx, in.dy, in.depth+1); err != nil {
+		return err
+	}
+	if err := f.appendGlyph(in.b, accent, in.dx+adx-asb+in.lsb, in.dy+ady, in.depth+1); err != nil {
+		return err
+	}
+	return errEndChar
+}
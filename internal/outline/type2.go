@@ -0,0 +1,419 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package outline
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// errEndChar stops the interpretation of a charstring at the endchar operator.
+var errEndChar = errors.New("endchar")
+
+// Limits of the Type 2 charstring interpreter (Type 2 spec Appendix B).
+const (
+	maxType2Stack   = 48
+	maxSubrDepth    = 10
+	maxCompositions = 4
+)
+
+// type2Interpreter interprets Type 2 charstrings (Adobe Technical Note #5177).
+type type2Interpreter struct {
+	font      *CFF
+	b         *pathBuilder
+	subrs     [][]byte
+	stack     []float64
+	transient [32]float64
+	nStems    int
+	haveWidth bool
+	depth     int // Nesting depth of seac compositions.
+}
+
+// subrBias returns the bias added to subroutine numbers of a subroutine INDEX with `n` entries.
+func subrBias(n int) int {
+	switch {
+	case n < 1240:
+		return 107
+	case n < 33900:
+		return 1131
+	}
+	return 32768
+}
+
+// takeWidth drops the optional advance width from the bottom of the stack of the first stack
+// clearing operator. `hasWidth` reports whether the stack holds the width.
+func (in *type2Interpreter) takeWidth(hasWidth bool) {
+	if !in.haveWidth && hasWidth && len(in.stack) > 0 {
+		in.stack = in.stack[1:]
+	}
+	in.haveWidth = true
+}
+
+// run interprets the charstring `code`. `level` is the subroutine nesting level.
+func (in *type2Interpreter) run(code []byte, level int) error {
+	if level > maxSubrDepth {
+		return errInvalidGlyph
+	}
+	b := in.b
+	for i := 0; i < len(code); {
+		b0 := code[i]
+		i++
+		switch {
+		case b0 == 28:
+			if i+2 > len(code) {
+				return errInvalidGlyph
+			}
+			in.stack = append(in.stack, float64(int16(binary.BigEndian.Uint16(code[i:]))))
+			i += 2
+			continue
+		case b0 >= 32 && b0 <= 246:
+			in.stack = append(in.stack, float64(int(b0)-139))
+			continue
+		case b0 >= 247 && b0 <= 254:
+			if i >= len(code) {
+				return errInvalidGlyph
+			}
+			v := (int(b0)-247)*256 + int(code[i]) + 108
+			if b0 >= 251 {
+				v = -(int(b0)-251)*256 - int(code[i]) - 108
+			}
+			in.stack = append(in.stack, float64(v))
+			i++
+			continue
+		case b0 == 255:
+			if i+4 > len(code) {
+				return errInvalidGlyph
+			}
+			in.stack = append(in.stack, float64(int32(binary.BigEndian.Uint32(code[i:])))/65536)
+			i += 4
+			continue
+		}
+		if len(in.stack) > maxType2Stack {
+			return errInvalidGlyph
+		}
+
+		s := in.stack
+		switch b0 {
+		case 1, 3, 18, 23: // hstem, vstem, hstemhm, vstemhm
+			in.takeWidth(len(s)%2 == 1)
+			in.nStems += len(in.stack) / 2
+		case 19, 20: // hintmask, cntrmask
+			in.takeWidth(len(s)%2 == 1)
+			// Arguments of the hintmask operator are implied vstem hints.
+			in.nStems += len(in.stack) / 2
+			i += (in.nStems + 7) / 8
+		case 21: // rmoveto
+			in.takeWidth(len(s) > 2)
+			s = in.stack
+			if len(s) < 2 {
+				return errInvalidGlyph
+			}
+			b.moveTo(b.x+s[0], b.y+s[1])
+		case 22: // hmoveto
+			in.takeWidth(len(s) > 1)
+			s = in.stack
+			if len(s) < 1 {
+				return errInvalidGlyph
+			}
+			b.moveTo(b.x+s[0], b.y)
+		case 4: // vmoveto
+			in.takeWidth(len(s) > 1)
+			s = in.stack
+			if len(s) < 1 {
+				return errInvalidGlyph
+			}
+			b.moveTo(b.x, b.y+s[0])
+		case 5: // rlineto
+			for ; len(s) >= 2; s = s[2:] {
+				b.lineTo(b.x+s[0], b.y+s[1])
+			}
+		case 6, 7: // hlineto, vlineto
+			horizontal := b0 == 6
+			for ; len(s) >= 1; s = s[1:] {
+				if horizontal {
+					b.lineTo(b.x+s[0], b.y)
+				} else {
+					b.lineTo(b.x, b.y+s[0])
+				}
+				horizontal = !horizontal
+			}
+		case 8: // rrcurveto
+			for ; len(s) >= 6; s = s[6:] {
+				in.curve(s[0], s[1], s[2], s[3], s[4], s[5])
+			}
+		case 24: // rcurveline
+			for ; len(s) >= 8; s = s[6:] {
+				in.curve(s[0], s[1], s[2], s[3], s[4], s[5])
+			}
+			if len(s) >= 2 {
+				b.lineTo(b.x+s[0], b.y+s[1])
+			}
+		case 25: // rlinecurve
+			for ; len(s) >= 8; s = s[2:] {
+				b.lineTo(b.x+s[0], b.y+s[1])
+			}
+			if len(s) >= 6 {
+				in.curve(s[0], s[1], s[2], s[3], s[4], s[5])
+			}
+		case 26: // vvcurveto
+			dx1 := 0.0
+			if len(s)%2 == 1 {
+				dx1, s = s[0], s[1:]
+			}
+			for ; len(s) >= 4; s = s[4:] {
+				in.curve(dx1, s[0], s[1], s[2], 0, s[3])
+				dx1 = 0
+			}
+		case 27: // hhcurveto
+			dy1 := 0.0
+			if len(s)%2 == 1 {
+				dy1, s = s[0], s[1:]
+			}
+			for ; len(s) >= 4; s = s[4:] {
+				in.curve(s[0], dy1, s[1], s[2], s[3], 0)
+				dy1 = 0
+			}
+		case 30, 31: // vhcurveto, hvcurveto
+			horizontal := b0 == 31
+			for ; len(s) >= 4; s = s[4:] {
+				last := 0.0
+				if len(s) == 5 {
+					last = s[4]
+				}
+				if horizontal {
+					in.curve(s[0], 0, s[1], s[2], last, s[3])
+				} else {
+					in.curve(0, s[0], s[1], s[2], s[3], last)
+				}
+				horizontal = !horizontal
+			}
+		case 10, 29: // callsubr, callgsubr
+			if len(s) < 1 {
+				return errInvalidGlyph
+			}
+			subrs := in.subrs
+			if b0 == 29 {
+				subrs = in.font.gsubrs
+			}
+			n := int(s[len(s)-1]) + subrBias(len(subrs))
+			in.stack = s[:len(s)-1]
+			if n < 0 || n >= len(subrs) {
+				return errInvalidGlyph
+			}
+			if err := in.run(subrs[n], level+1); err != nil {
+				return err
+			}
+			continue
+		case 11: // return
+			return nil
+		case 14: // endchar
+			in.takeWidth(len(s) == 1 || len(s) == 5)
+			if s = in.stack; len(s) == 4 {
+				return in.seac(s[0], s[1], s[2], s[3])
+			}
+			return errEndChar
+		case 12:
+			if i >= len(code) {
+				return errInvalidGlyph
+			}
+			b1 := code[i]
+			i++
+			if err := in.escape(b1); err != nil {
+				return err
+			}
+			continue
+		}
+		in.stack = in.stack[:0]
+	}
+	return nil
+}
+
+// escape interprets the two byte operator 12 `op`.
+func (in *type2Interpreter) escape(op byte) error {
+	s := in.stack
+	pop := func() float64 {
+		if len(in.stack) == 0 {
+			return 0
+		}
+		v := in.stack[len(in.stack)-1]
+		in.stack = in.stack[:len(in.stack)-1]
+		return v
+	}
+	push := func(v float64) {
+		in.stack = append(in.stack, v)
+	}
+	switch op {
+	case 35: // flex
+		if len(s) < 12 {
+			return errInvalidGlyph
+		}
+		in.curve(s[0], s[1], s[2], s[3], s[4], s[5])
+		in.curve(s[6], s[7], s[8], s[9], s[10], s[11])
+		in.stack = in.stack[:0]
+	case 34: // hflex
+		if len(s) < 7 {
+			return errInvalidGlyph
+		}
+		in.curve(s[0], 0, s[1], s[2], s[3], 0)
+		in.curve(s[4], 0, s[5], -s[2], s[6], 0)
+		in.stack = in.stack[:0]
+	case 36: // hflex1
+		if len(s) < 9 {
+			return errInvalidGlyph
+		}
+		in.curve(s[0], s[1], s[2], s[3], s[4], 0)
+		in.curve(s[5], 0, s[6], s[7], s[8], -(s[1] + s[3] + s[7]))
+		in.stack = in.stack[:0]
+	case 37: // flex1
+		if len(s) < 11 {
+			return errInvalidGlyph
+		}
+		dx := s[0] + s[2] + s[4] + s[6] + s[8]
+		dy := s[1] + s[3] + s[5] + s[7] + s[9]
+		dx6, dy6 := s[10], -dy
+		if math.Abs(dx) <= math.Abs(dy) {
+			dx6, dy6 = -dx, s[10]
+		}
+		in.curve(s[0], s[1], s[2], s[3], s[4], s[5])
+		in.curve(s[6], s[7], s[8], s[9], dx6, dy6)
+		in.stack = in.stack[:0]
+	case 3: // and
+		b, a := pop(), pop()
+		push(boolFloat(a != 0 && b != 0))
+	case 4: // or
+		b, a := pop(), pop()
+		push(boolFloat(a != 0 || b != 0))
+	case 5: // not
+		push(boolFloat(pop() == 0))
+	case 9: // abs
+		push(math.Abs(pop()))
+	case 10: // add
+		b, a := pop(), pop()
+		push(a + b)
+	case 11: // sub
+		b, a := pop(), pop()
+		push(a - b)
+	case 12: // div
+		b, a := pop(), pop()
+		if b == 0 {
+			return errInvalidGlyph
+		}
+		push(a / b)
+	case 14: // neg
+		push(-pop())
+	case 15: // eq
+		b, a := pop(), pop()
+		push(boolFloat(a == b))
+	case 18: // drop
+		pop()
+	case 20: // put
+		i, v := int(pop()), pop()
+		if i >= 0 && i < len(in.transient) {
+			in.transient[i] = v
+		}
+	case 21: // get
+		i := int(pop())
+		if i < 0 || i >= len(in.transient) {
+			return errInvalidGlyph
+		}
+		push(in.transient[i])
+	case 22: // ifelse
+		v2, v1, s2, s1 := pop(), pop(), pop(), pop()
+		if v1 > v2 {
+			s1 = s2
+		}
+		push(s1)
+	case 23: // random
+		push(0.5)
+	case 24: // mul
+		b, a := pop(), pop()
+		push(a * b)
+	case 26: // sqrt
+		push(math.Sqrt(math.Abs(pop())))
+	case 27: // dup
+		v := pop()
+		push(v)
+		push(v)
+	case 28: // exch
+		b, a := pop(), pop()
+		push(b)
+		push(a)
+	case 29: // index
+		i := int(pop())
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(in.stack) {
+			return errInvalidGlyph
+		}
+		push(in.stack[len(in.stack)-1-i])
+	case 30: // roll
+		j, n := int(pop()), int(pop())
+		if n <= 0 || n > len(in.stack) {
+			return errInvalidGlyph
+		}
+		top := in.stack[len(in.stack)-n:]
+		rolled := make([]float64, n)
+		for k := range top {
+			rolled[((k+j)%n+n)%n] = top[k]
+		}
+		copy(top, rolled)
+	default:
+		// Deprecated and reserved operators clear the stack.
+		in.stack = in.stack[:0]
+	}
+	return nil
+}
+
+// curve appends a curve with control points relative to the current point and to each other.
+func (in *type2Interpreter) curve(dx1, dy1, dx2, dy2, dx3, dy3 float64) {
+	b := in.b
+	x1, y1 := b.x+dx1, b.y+dy1
+	x2, y2 := x1+dx2, y1+dy2
+	b.curveTo(x1, y1, x2, y2, x2+dx3, y2+dy3)
+}
+
+// seac composes an accented character from the base glyph with standard encoding code `bchar`
+// and the accent glyph with code `achar` offset by `adx`,`ady`.
+func (in *type2Interpreter) seac(adx, ady, bchar, achar float64) error {
+	if in.depth >= maxCompositions {
+		return errInvalidGlyph
+	}
+	lookup := func(code float64) (uint16, error) {
+		name, ok := standardGlyphName(byte(code))
+		if !ok {
+			return 0, ErrNoGlyph
+		}
+		gid, ok := in.font.GlyphIndexByName(name)
+		if !ok {
+			return 0, ErrNoGlyph
+		}
+		return gid, nil
+	}
+	base, err := lookup(bchar)
+	if err != nil {
+		return err
+	}
+	accent, err := lookup(achar)
+	if err != nil {
+		return err
+	}
+	if err := in.font.appendGlyph(in.b, base, 0, 0, in.depth+1); err != nil {
+		return err
+	}
+	if err := in.font.appendGlyph(in.b, accent, adx, ady, in.depth+1); err != nil {
+		return err
+	}
+	return errEndChar
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
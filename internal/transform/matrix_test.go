@@ -61,3 +61,43 @@ func makeAngleCase(r, theta float64) angleCase {
 	d := a
 	return angleCase{params{a, b, c, d, 0, 0}, theta}
 }
+
+// TestTransform tests Matrix.Transform and Point.Transform with non-diagonal matrices.
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		params
+		x, y   float64 // Point to transform.
+		xp, yp float64 // Expected transformed point.
+	}{
+		{params: params{1, 0, 0, 1, 0, 0}, x: 1, y: 2, xp: 1, yp: 2},
+		{params: params{0, 1, -1, 0, 0, 0}, x: 1, y: 0, xp: 0, yp: 1},
+		{params: params{0, 1, -1, 0, 0, 0}, x: 0, y: 1, xp: -1, yp: 0},
+		{params: params{1, 2, 3, 4, 5, 6}, x: 1, y: 1, xp: 9, yp: 12},
+		{params: params{1, 0, 0.5, 1, 10, 0}, x: 2, y: 4, xp: 14, yp: 4},
+	}
+
+	const tol = 1.0e-10
+
+	for _, test := range tests {
+		p := test.params
+		m := NewMatrix(p.a, p.b, p.c, p.d, p.tx, p.ty)
+		xp, yp := m.Transform(test.x, test.y)
+		if math.Abs(xp-test.xp) > tol || math.Abs(yp-test.yp) > tol {
+			t.Fatalf("Bad transform: m=%s (%g,%g) expected=(%g,%g) actual=(%g,%g)",
+				m, test.x, test.y, test.xp, test.yp, xp, yp)
+		}
+
+		// Transforming a point is the same as translating the matrix by the point.
+		tm := m.Mult(TranslationMatrix(test.x, test.y))
+		tx, ty := tm.Translation()
+		if math.Abs(xp-tx) > tol || math.Abs(yp-ty) > tol {
+			t.Fatalf("Transform differs from Mult: m=%s (%g,%g) (%g,%g)", m, xp, yp, tx, ty)
+		}
+
+		pt := NewPoint(test.x, test.y)
+		pt.Transform(p.a, p.b, p.c, p.d, p.tx, p.ty)
+		if math.Abs(pt.X-test.xp) > tol || math.Abs(pt.Y-test.yp) > tol {
+			t.Fatalf("Bad point transform: m=%s expected=(%g,%g) actual=%s", m, test.xp, test.yp, pt)
+		}
+	}
+}
@@ -0,0 +1,740 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfActionType represents an action type (S entry of action dictionaries, Table 198 p. 417).
+type PdfActionType string
+
+// Action types supported by the typed action models. Actions of other types are loaded as
+// PdfAction without context and written back unchanged.
+const (
+	ActionTypeGoTo        PdfActionType = "GoTo"
+	ActionTypeGoToR       PdfActionType = "GoToR"
+	ActionTypeGoToE       PdfActionType = "GoToE"
+	ActionTypeLaunch      PdfActionType = "Launch"
+	ActionTypeURI         PdfActionType = "URI"
+	ActionTypeHide        PdfActionType = "Hide"
+	ActionTypeNamed       PdfActionType = "Named"
+	ActionTypeSubmitForm  PdfActionType = "SubmitForm"
+	ActionTypeResetForm   PdfActionType = "ResetForm"
+	ActionTypeImportData  PdfActionType = "ImportData"
+	ActionTypeSetOCGState PdfActionType = "SetOCGState"
+	ActionTypeJavaScript  PdfActionType = "JavaScript"
+)
+
+// PdfAction represents an action in PDF (section 12.6 p. 414).
+type PdfAction struct {
+	// context contains the specific action fields.
+	context PdfModel
+
+	// Entries common to all action dictionaries (Table 193 p. 415).
+
+	S PdfActionType
+
+	// Next are the actions performed after this action, in order.
+	Next []*PdfAction
+
+	container *core.PdfIndirectObject
+}
+
+// GetContext returns the action context which contains the specific type-dependent context.
+// The context represents the subaction, nil for action types without model.
+func (a *PdfAction) GetContext() PdfModel {
+	if a == nil {
+		return nil
+	}
+	return a.context
+}
+
+// SetContext sets the sub action (context).
+func (a *PdfAction) SetContext(ctx PdfModel) {
+	a.context = ctx
+}
+
+// PdfActionGoTo represents a go-to action (section 12.6.4.2 p. 418).
+type PdfActionGoTo struct {
+	*PdfAction
+	D core.PdfObject // Destination: name, byte string or array.
+}
+
+// PdfActionGoToR represents a remote go-to action (section 12.6.4.3 p. 418).
+type PdfActionGoToR struct {
+	*PdfAction
+	F         core.PdfObject
+	D         core.PdfObject
+	NewWindow core.PdfObject
+}
+
+// PdfActionGoToE represents an embedded go-to action (section 12.6.4.4 p. 419).
+type PdfActionGoToE struct {
+	*PdfAction
+	F         core.PdfObject
+	D         core.PdfObject
+	NewWindow core.PdfObject
+	T         core.PdfObject
+}
+
+// PdfActionLaunch represents a launch action (section 12.6.4.5 p. 421).
+type PdfActionLaunch struct {
+	*PdfAction
+	F         core.PdfObject
+	Win       core.PdfObject
+	Mac       core.PdfObject
+	Unix      core.PdfObject
+	NewWindow core.PdfObject
+}
+
+// PdfActionURI represents a URI action (section 12.6.4.7 p. 423).
+type PdfActionURI struct {
+	*PdfAction
+	URI   core.PdfObject
+	IsMap core.PdfObject
+}
+
+// PdfActionHide represents a hide action (section 12.6.4.10 p. 426).
+type PdfActionHide struct {
+	*PdfAction
+	T core.PdfObject
+	H core.PdfObject
+}
+
+// PdfActionNamed represents a named action (section 12.6.4.11 p. 427).
+type PdfActionNamed struct {
+	*PdfAction
+	N core.PdfObject
+}
+
+// PdfActionSubmitForm represents a submit-form action (section 12.7.5.2 p. 449).
+type PdfActionSubmitForm struct {
+	*PdfAction
+	F      core.PdfObject
+	Fields core.PdfObject
+	Flags  core.PdfObject
+}
+
+// PdfActionResetForm represents a reset-form action (section 12.7.5.3 p. 453).
+type PdfActionResetForm struct {
+	*PdfAction
+	Fields core.PdfObject
+	Flags  core.PdfObject
+}
+
+// PdfActionImportData represents an import-data action (section 12.7.5.4 p. 453).
+type PdfActionImportData struct {
+	*PdfAction
+	F core.PdfObject
+}
+
+// PdfActionSetOCGState represents a set-OCG-state action (section 12.6.4.12 p. 427).
+type PdfActionSetOCGState struct {
+	*PdfAction
+	State      core.PdfObject
+	PreserveRB core.PdfObject
+}
+
+// PdfActionJavaScript represents a JavaScript action (section 12.6.4.16 p. 430).
+type PdfActionJavaScript struct {
+	*PdfAction
+	JS core.PdfObject // Text string or stream.
+}
+
+// newPdfAction returns an action of type `s` with a new container.
+func newPdfAction(s PdfActionType) *PdfAction {
+	return &PdfAction{
+		S:         s,
+		container: core.MakeIndirectObject(core.MakeDict()),
+	}
+}
+
+// NewPdfActionGoTo returns a new go-to action.
+func NewPdfActionGoTo() *PdfActionGoTo {
+	action := &PdfActionGoTo{PdfAction: newPdfAction(ActionTypeGoTo)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionGoToR returns a new remote go-to action.
+func NewPdfActionGoToR() *PdfActionGoToR {
+	action := &PdfActionGoToR{PdfAction: newPdfAction(ActionTypeGoToR)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionGoToE returns a new embedded go-to action.
+func NewPdfActionGoToE() *PdfActionGoToE {
+	action := &PdfActionGoToE{PdfAction: newPdfAction(ActionTypeGoToE)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionLaunch returns a new launch action.
+func NewPdfActionLaunch() *PdfActionLaunch {
+	action := &PdfActionLaunch{PdfAction: newPdfAction(ActionTypeLaunch)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionURI returns a new URI action.
+func NewPdfActionURI() *PdfActionURI {
+	action := &PdfActionURI{PdfAction: newPdfAction(ActionTypeURI)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionHide returns a new hide action.
+func NewPdfActionHide() *PdfActionHide {
+	action := &PdfActionHide{PdfAction: newPdfAction(ActionTypeHide)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionNamed returns a new named action.
+func NewPdfActionNamed() *PdfActionNamed {
+	action := &PdfActionNamed{PdfAction: newPdfAction(ActionTypeNamed)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionSubmitForm returns a new submit-form action.
+func NewPdfActionSubmitForm() *PdfActionSubmitForm {
+	action := &PdfActionSubmitForm{PdfAction: newPdfAction(ActionTypeSubmitForm)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionResetForm returns a new reset-form action.
+func NewPdfActionResetForm() *PdfActionResetForm {
+	action := &PdfActionResetForm{PdfAction: newPdfAction(ActionTypeResetForm)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionImportData returns a new import-data action.
+func NewPdfActionImportData() *PdfActionImportData {
+	action := &PdfActionImportData{PdfAction: newPdfAction(ActionTypeImportData)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionSetOCGState returns a new set-OCG-state action.
+func NewPdfActionSetOCGState() *PdfActionSetOCGState {
+	action := &PdfActionSetOCGState{PdfAction: newPdfAction(ActionTypeSetOCGState)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionJavaScript returns a new JavaScript action.
+func NewPdfActionJavaScript() *PdfActionJavaScript {
+	action := &PdfActionJavaScript{PdfAction: newPdfAction(ActionTypeJavaScript)}
+	action.SetContext(action)
+	return action
+}
+
+// NewPdfActionFromObject loads the action from action dictionary `obj`, including the actions of
+// the Next chain.
+func NewPdfActionFromObject(obj core.PdfObject) (*PdfAction, error) {
+	return newPdfActionFromObject(obj, map[*core.PdfObjectDictionary]struct{}{})
+}
+
+// newPdfActionFromObject loads the action from `obj`. The dictionaries of the actions whose Next
+// chains are being loaded are tracked in `ancestors` to break cycles. An action may still appear
+// several times in the Next chains, e.g. when actions share a successor.
+func newPdfActionFromObject(obj core.PdfObject, ancestors map[*core.PdfObjectDictionary]struct{}) (*PdfAction, error) {
+	obj = core.ResolveReference(obj)
+	container, isIndirect := obj.(*core.PdfIndirectObject)
+	if !isIndirect {
+		container = core.MakeIndirectObject(obj)
+	}
+	d, ok := core.GetDict(container)
+	if !ok {
+		common.Log.Debug("ERROR: Action not a dictionary (%T)", obj)
+		return nil, errors.New("type check error")
+	}
+	if _, has := ancestors[d]; has {
+		common.Log.Debug("ERROR: Circular action Next chain")
+		return nil, errors.New("circular action chain")
+	}
+	ancestors[d] = struct{}{}
+	defer delete(ancestors, d)
+
+	if name, ok := core.GetName(d.Get("Type")); ok && *name != "Action" {
+		common.Log.Trace("Unsuspected Type != Action (%s)", *name)
+	}
+	s, ok := core.GetName(d.Get("S"))
+	if !ok {
+		common.Log.Debug("ERROR: Action has no type (S)")
+		return nil, errors.New("missing action type")
+	}
+
+	action := &PdfAction{
+		S:         PdfActionType(*s),
+		container: container,
+	}
+
+	switch next := core.TraceToDirectObject(d.Get("Next")).(type) {
+	case *core.PdfObjectDictionary:
+		nextAction, err := newPdfActionFromObject(d.Get("Next"), ancestors)
+		if err != nil {
+			return nil, err
+		}
+		action.Next = append(action.Next, nextAction)
+	case *core.PdfObjectArray:
+		for _, obj := range next.Elements() {
+			nextAction, err := newPdfActionFromObject(obj, ancestors)
+			if err != nil {
+				return nil, err
+			}
+			action.Next = append(action.Next, nextAction)
+		}
+	}
+
+	switch action.S {
+	case ActionTypeGoTo:
+		ctx := &PdfActionGoTo{PdfAction: action}
+		ctx.D = d.Get("D")
+		action.context = ctx
+	case ActionTypeGoToR:
+		ctx := &PdfActionGoToR{PdfAction: action}
+		ctx.F = d.Get("F")
+		ctx.D = d.Get("D")
+		ctx.NewWindow = d.Get("NewWindow")
+		action.context = ctx
+	case ActionTypeGoToE:
+		ctx := &PdfActionGoToE{PdfAction: action}
+		ctx.F = d.Get("F")
+		ctx.D = d.Get("D")
+		ctx.NewWindow = d.Get("NewWindow")
+		ctx.T = d.Get("T")
+		action.context = ctx
+	case ActionTypeLaunch:
+		ctx := &PdfActionLaunch{PdfAction: action}
+		ctx.F = d.Get("F")
+		ctx.Win = d.Get("Win")
+		ctx.Mac = d.Get("Mac")
+		ctx.Unix = d.Get("Unix")
+		ctx.NewWindow = d.Get("NewWindow")
+		action.context = ctx
+	case ActionTypeURI:
+		ctx := &PdfActionURI{PdfAction: action}
+		ctx.URI = d.Get("URI")
+		ctx.IsMap = d.Get("IsMap")
+		action.context = ctx
+	case ActionTypeHide:
+		ctx := &PdfActionHide{PdfAction: action}
+		ctx.T = d.Get("T")
+		ctx.H = d.Get("H")
+		action.context = ctx
+	case ActionTypeNamed:
+		ctx := &PdfActionNamed{PdfAction: action}
+		ctx.N = d.Get("N")
+		action.context = ctx
+	case ActionTypeSubmitForm:
+		ctx := &PdfActionSubmitForm{PdfAction: action}
+		ctx.F = d.Get("F")
+		ctx.Fields = d.Get("Fields")
+		ctx.Flags = d.Get("Flags")
+		action.context = ctx
+	case ActionTypeResetForm:
+		ctx := &PdfActionResetForm{PdfAction: action}
+		ctx.Fields = d.Get("Fields")
+		ctx.Flags = d.Get("Flags")
+		action.context = ctx
+	case ActionTypeImportData:
+		ctx := &PdfActionImportData{PdfAction: action}
+		ctx.F = d.Get("F")
+		action.context = ctx
+	case ActionTypeSetOCGState:
+		ctx := &PdfActionSetOCGState{PdfAction: action}
+		ctx.State = d.Get("State")
+		ctx.PreserveRB = d.Get("PreserveRB")
+		action.context = ctx
+	case ActionTypeJavaScript:
+		ctx := &PdfActionJavaScript{PdfAction: action}
+		ctx.JS = d.Get("JS")
+		action.context = ctx
+	default:
+		common.Log.Trace("Action type %s loaded without model", action.S)
+	}
+	return action, nil
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (a *PdfAction) GetContainingPdfObject() core.PdfObject {
+	return a.container
+}
+
+// ToPdfObject implements interface PdfModel. The entries of actions without context are written
+// as loaded.
+func (a *PdfAction) ToPdfObject() core.PdfObject {
+	if a.context != nil {
+		return a.context.ToPdfObject()
+	}
+	return a.toPdfObject()
+}
+
+// toPdfObject sets the entries common to all actions in the container dictionary.
+func (a *PdfAction) toPdfObject() core.PdfObject {
+	container := a.container
+	d := container.PdfObject.(*core.PdfObjectDictionary)
+
+	d.Set("Type", core.MakeName("Action"))
+	d.Set("S", core.MakeName(string(a.S)))
+	switch len(a.Next) {
+	case 0:
+		d.Remove("Next")
+	case 1:
+		d.Set("Next", a.Next[0].ToPdfObject())
+	default:
+		next := core.MakeArray()
+		for _, action := range a.Next {
+			next.Append(action.ToPdfObject())
+		}
+		d.Set("Next", next)
+	}
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionGoTo) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("D", a.D)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionGoToR) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("F", a.F)
+	d.SetIfNotNil("D", a.D)
+	d.SetIfNotNil("NewWindow", a.NewWindow)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionGoToE) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("F", a.F)
+	d.SetIfNotNil("D", a.D)
+	d.SetIfNotNil("NewWindow", a.NewWindow)
+	d.SetIfNotNil("T", a.T)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionLaunch) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("F", a.F)
+	d.SetIfNotNil("Win", a.Win)
+	d.SetIfNotNil("Mac", a.Mac)
+	d.SetIfNotNil("Unix", a.Unix)
+	d.SetIfNotNil("NewWindow", a.NewWindow)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionURI) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("URI", a.URI)
+	d.SetIfNotNil("IsMap", a.IsMap)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionHide) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("T", a.T)
+	d.SetIfNotNil("H", a.H)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionNamed) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("N", a.N)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionSubmitForm) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("F", a.F)
+	d.SetIfNotNil("Fields", a.Fields)
+	d.SetIfNotNil("Flags", a.Flags)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionResetForm) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("Fields", a.Fields)
+	d.SetIfNotNil("Flags", a.Flags)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionImportData) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("F", a.F)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionSetOCGState) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("State", a.State)
+	d.SetIfNotNil("PreserveRB", a.PreserveRB)
+	return container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (a *PdfActionJavaScript) ToPdfObject() core.PdfObject {
+	container := a.PdfAction.toPdfObject()
+	d := container.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+	d.SetIfNotNil("JS", a.JS)
+	return container
+}
+
+// PdfAdditionalActions represents an additional-actions dictionary (section 12.6.3 p. 415), which
+// maps trigger events to actions, e.g. "K" (keystroke), "F" (format), "V" (validate) and "C"
+// (calculate) for form fields.
+type PdfAdditionalActions struct {
+	triggers []core.PdfObjectName
+	actions  map[core.PdfObjectName]*PdfAction
+}
+
+// NewPdfAdditionalActions returns an empty additional-actions dictionary.
+func NewPdfAdditionalActions() *PdfAdditionalActions {
+	return &PdfAdditionalActions{actions: map[core.PdfObjectName]*PdfAction{}}
+}
+
+// NewPdfAdditionalActionsFromObject loads the additional-actions dictionary `obj`. Actions that
+// cannot be loaded are skipped.
+func NewPdfAdditionalActionsFromObject(obj core.PdfObject) (*PdfAdditionalActions, error) {
+	d, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Additional actions not a dictionary (%T)", obj)
+		return nil, errors.New("type check error")
+	}
+	aa := NewPdfAdditionalActions()
+	for _, trigger := range d.Keys() {
+		action, err := NewPdfActionFromObject(d.Get(trigger))
+		if err != nil {
+			common.Log.Debug("Skipping %s action: %v", trigger, err)
+			continue
+		}
+		aa.Set(string(trigger), action)
+	}
+	return aa, nil
+}
+
+// Get returns the action of `trigger`, nil if not set.
+func (aa *PdfAdditionalActions) Get(trigger string) *PdfAction {
+	return aa.actions[core.PdfObjectName(trigger)]
+}
+
+// Set sets the action of `trigger`. A nil action removes the trigger.
+func (aa *PdfAdditionalActions) Set(trigger string, action *PdfAction) {
+	key := core.PdfObjectName(trigger)
+	if _, has := aa.actions[key]; has {
+		if action != nil {
+			aa.actions[key] = action
+			return
+		}
+		delete(aa.actions, key)
+		for i, t := range aa.triggers {
+			if t == key {
+				aa.triggers = append(aa.triggers[:i], aa.triggers[i+1:]...)
+				break
+			}
+		}
+		return
+	}
+	if action != nil {
+		aa.triggers = append(aa.triggers, key)
+		aa.actions[key] = action
+	}
+}
+
+// Triggers returns the triggers that have an action, in the order they were set.
+func (aa *PdfAdditionalActions) Triggers() []string {
+	var triggers []string
+	for _, t := range aa.triggers {
+		triggers = append(triggers, string(t))
+	}
+	return triggers
+}
+
+// ToPdfObject returns the additional-actions dictionary.
+func (aa *PdfAdditionalActions) ToPdfObject() core.PdfObject {
+	d := core.MakeDict()
+	for _, t := range aa.triggers {
+		d.Set(t, aa.actions[t].ToPdfObject())
+	}
+	return d
+}
+
+// getAction loads the action of entry `obj`, nil if not set.
+func getAction(obj core.PdfObject) (*PdfAction, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	return NewPdfActionFromObject(obj)
+}
+
+// getAdditionalActions loads the additional actions of entry `obj`, nil if not set.
+func getAdditionalActions(obj core.PdfObject) (*PdfAdditionalActions, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	return NewPdfAdditionalActionsFromObject(obj)
+}
+
+// GetAction returns the action of the link (A entry), nil if not set.
+func (link *PdfAnnotationLink) GetAction() (*PdfAction, error) {
+	return getAction(link.A)
+}
+
+// SetAction sets the action of the link (A entry). A nil action removes it.
+func (link *PdfAnnotationLink) SetAction(action *PdfAction) {
+	link.A = nil
+	if action != nil {
+		link.A = action.ToPdfObject()
+	}
+}
+
+// GetAction returns the action of the screen annotation (A entry), nil if not set.
+func (scr *PdfAnnotationScreen) GetAction() (*PdfAction, error) {
+	return getAction(scr.A)
+}
+
+// SetAction sets the action of the screen annotation (A entry). A nil action removes it.
+func (scr *PdfAnnotationScreen) SetAction(action *PdfAction) {
+	scr.A = nil
+	if action != nil {
+		scr.A = action.ToPdfObject()
+	}
+}
+
+// GetAdditionalActions returns the additional actions of the screen annotation (AA entry), nil
+// if not set.
+func (scr *PdfAnnotationScreen) GetAdditionalActions() (*PdfAdditionalActions, error) {
+	return getAdditionalActions(scr.AA)
+}
+
+// SetAdditionalActions sets the additional actions of the screen annotation (AA entry). A nil
+// value removes them.
+func (scr *PdfAnnotationScreen) SetAdditionalActions(aa *PdfAdditionalActions) {
+	scr.AA = nil
+	if aa != nil {
+		scr.AA = aa.ToPdfObject()
+	}
+}
+
+// GetAction returns the action of the widget annotation (A entry), nil if not set.
+func (widget *PdfAnnotationWidget) GetAction() (*PdfAction, error) {
+	return getAction(widget.A)
+}
+
+// SetAction sets the action of the widget annotation (A entry), e.g. the action of a push button.
+// A nil action removes it.
+func (widget *PdfAnnotationWidget) SetAction(action *PdfAction) {
+	widget.A = nil
+	if action != nil {
+		widget.A = action.ToPdfObject()
+	}
+}
+
+// GetAdditionalActions returns the additional actions of the widget annotation (AA entry), nil
+// if not set.
+func (widget *PdfAnnotationWidget) GetAdditionalActions() (*PdfAdditionalActions, error) {
+	return getAdditionalActions(widget.AA)
+}
+
+// SetAdditionalActions sets the additional actions of the widget annotation (AA entry). A nil
+// value removes them.
+func (widget *PdfAnnotationWidget) SetAdditionalActions(aa *PdfAdditionalActions) {
+	widget.AA = nil
+	if aa != nil {
+		widget.AA = aa.ToPdfObject()
+	}
+}
+
+// GetAdditionalActions returns the additional actions of the field (AA entry), e.g. the
+// keystroke, format, validate and calculate actions, nil if not set.
+func (f *PdfField) GetAdditionalActions() (*PdfAdditionalActions, error) {
+	return getAdditionalActions(f.AA)
+}
+
+// SetAdditionalActions sets the additional actions of the field (AA entry). A nil value removes
+// them.
+func (f *PdfField) SetAdditionalActions(aa *PdfAdditionalActions) {
+	f.AA = nil
+	if aa != nil {
+		f.AA = aa.ToPdfObject()
+	}
+}
+
+// GetAction returns the action of the outline item (A entry), nil if not set.
+func (oi *PdfOutlineItem) GetAction() (*PdfAction, error) {
+	return getAction(oi.A)
+}
+
+// SetAction sets the action of the outline item (A entry). A nil action removes it.
+func (oi *PdfOutlineItem) SetAction(action *PdfAction) {
+	oi.A = nil
+	if action != nil {
+		oi.A = action.ToPdfObject()
+	}
+}
+
+// GetOpenAction returns the action performed when the document is opened (OpenAction entry of
+// the catalog), nil if not set. A destination is returned as a go-to action.
+func (r *PdfReader) GetOpenAction() (*PdfAction, error) {
+	obj := r.catalog.Get("OpenAction")
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case nil:
+		return nil, nil
+	case *core.PdfObjectArray:
+		action := NewPdfActionGoTo()
+		action.D = t
+		return action.PdfAction, nil
+	case *core.PdfObjectDictionary:
+		return NewPdfActionFromObject(obj)
+	default:
+		return nil, fmt.Errorf("invalid OpenAction type %T", t)
+	}
+}
+
+// SetOpenAction sets the action performed when the document is opened (OpenAction entry of the
+// catalog), e.g. a go-to action to a page destination. A nil action removes it.
+func (w *PdfWriter) SetOpenAction(action *PdfAction) error {
+	if action == nil {
+		w.catalog.Remove("OpenAction")
+		return nil
+	}
+	obj := action.ToPdfObject()
+	w.catalog.Set("OpenAction", obj)
+	return w.addObjects(obj)
+}
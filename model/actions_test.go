@@ -0,0 +1,178 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+func TestPdfActionRoundTrip(t *testing.T) {
+	uri := model.NewPdfActionURI()
+	uri.URI = core.MakeString("https://unidoc.io")
+	named := model.NewPdfActionNamed()
+	named.N = core.MakeName("NextPage")
+	js := model.NewPdfActionJavaScript()
+	js.JS = core.MakeString("app.alert('Done');")
+	uri.Next = []*model.PdfAction{named.PdfAction, js.PdfAction}
+
+	action, err := model.NewPdfActionFromObject(uri.ToPdfObject())
+	require.NoError(t, err)
+	require.Equal(t, model.ActionTypeURI, action.S)
+	read, ok := action.GetContext().(*model.PdfActionURI)
+	require.True(t, ok)
+	require.Equal(t, "https://unidoc.io", read.URI.(*core.PdfObjectString).Str())
+	require.Len(t, action.Next, 2)
+	require.Equal(t, model.ActionTypeNamed, action.Next[0].S)
+	readJS, ok := action.Next[1].GetContext().(*model.PdfActionJavaScript)
+	require.True(t, ok)
+	require.Equal(t, "app.alert('Done');", readJS.JS.(*core.PdfObjectString).Str())
+
+	// A single next action is written as a dictionary.
+	uri.Next = uri.Next[:1]
+	d, ok := core.GetDict(uri.ToPdfObject())
+	require.True(t, ok)
+	_, ok = core.GetDict(d.Get("Next"))
+	require.True(t, ok)
+}
+
+func TestPdfActionUnknownType(t *testing.T) {
+	d := core.MakeDict()
+	d.Set("S", core.MakeName("Sound"))
+	d.Set("Volume", core.MakeFloat(0.5))
+
+	action, err := model.NewPdfActionFromObject(d)
+	require.NoError(t, err)
+	require.Nil(t, action.GetContext())
+	written, ok := core.GetDict(action.ToPdfObject())
+	require.True(t, ok)
+	volume, err := core.GetNumberAsFloat(written.Get("Volume"))
+	require.NoError(t, err)
+	require.Equal(t, 0.5, volume)
+}
+
+func TestPdfActionCircularNext(t *testing.T) {
+	d := core.MakeDict()
+	d.Set("S", core.MakeName("Named"))
+	d.Set("N", core.MakeName("NextPage"))
+	d.Set("Next", d)
+	_, err := model.NewPdfActionFromObject(d)
+	require.Error(t, err)
+
+	// The same action may follow several actions of a chain without forming a cycle.
+	shared := core.MakeIndirectObject(core.MakeDict())
+	sharedDict := shared.PdfObject.(*core.PdfObjectDictionary)
+	sharedDict.Set("S", core.MakeName("Named"))
+	sharedDict.Set("N", core.MakeName("PrevPage"))
+	middle := core.MakeDict()
+	middle.Set("S", core.MakeName("Named"))
+	middle.Set("N", core.MakeName("FirstPage"))
+	middle.Set("Next", shared)
+	first := core.MakeDict()
+	first.Set("S", core.MakeName("Named"))
+	first.Set("N", core.MakeName("LastPage"))
+	first.Set("Next", core.MakeArray(shared, shared, middle))
+	action, err := model.NewPdfActionFromObject(first)
+	require.NoError(t, err)
+	require.Len(t, action.Next, 3)
+	require.Len(t, action.Next[2].Next, 1)
+	for _, next := range []*model.PdfAction{action.Next[0], action.Next[1], action.Next[2].Next[0]} {
+		named, ok := next.GetContext().(*model.PdfActionNamed)
+		require.True(t, ok)
+		require.Equal(t, "PrevPage", named.N.String())
+	}
+
+	// A cycle through an array of next actions is still detected.
+	sharedDict.Set("Next", core.MakeArray(middle))
+	_, err = model.NewPdfActionFromObject(first)
+	require.Error(t, err)
+}
+
+func TestPdfAdditionalActions(t *testing.T) {
+	format := model.NewPdfActionJavaScript()
+	format.JS = core.MakeString(`AFNumber_Format(2, 0, 0, 0, "", true);`)
+	keystroke := model.NewPdfActionJavaScript()
+	keystroke.JS = core.MakeString(`AFNumber_Keystroke(2, 0, 0, 0, "", true);`)
+	aa := model.NewPdfAdditionalActions()
+	aa.Set("K", keystroke.PdfAction)
+	aa.Set("F", format.PdfAction)
+	aa.Set("V", format.PdfAction)
+	aa.Set("V", nil)
+
+	field := model.NewPdfField()
+	field.SetAdditionalActions(aa)
+	read, err := field.GetAdditionalActions()
+	require.NoError(t, err)
+	require.Equal(t, []string{"K", "F"}, read.Triggers())
+	require.Equal(t, model.ActionTypeJavaScript, read.Get("F").S)
+	require.Nil(t, read.Get("V"))
+}
+
+func TestPdfActionReadWrite(t *testing.T) {
+	w := model.NewPdfWriter()
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+
+	link := model.NewPdfAnnotationLink()
+	link.Rect = core.MakeArrayFromFloats([]float64{0, 0, 100, 20})
+	uri := model.NewPdfActionURI()
+	uri.URI = core.MakeString("https://unidoc.io")
+	link.SetAction(uri.PdfAction)
+	page.AddAnnotation(link.PdfAnnotation)
+	require.NoError(t, w.AddPage(page))
+
+	open := model.NewPdfActionGoTo()
+	open.D = core.MakeArray(page.GetPageAsIndirectObject(), core.MakeName("Fit"))
+	require.NoError(t, w.SetOpenAction(open.PdfAction))
+
+	bookmark := model.NewPdfOutlineItem()
+	bookmark.Title = core.MakeString("Start")
+	named := model.NewPdfActionNamed()
+	named.N = core.MakeName("FirstPage")
+	bookmark.SetAction(named.PdfAction)
+	outline := model.NewPdfOutline()
+	outline.First = &bookmark.PdfOutlineTreeNode
+	outline.Last = &bookmark.PdfOutlineTreeNode
+	bookmark.Parent = &outline.PdfOutlineTreeNode
+	w.AddOutlineTree(&outline.PdfOutlineTreeNode)
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	action, err := reader.GetOpenAction()
+	require.NoError(t, err)
+	goTo, ok := action.GetContext().(*model.PdfActionGoTo)
+	require.True(t, ok)
+	dest, ok := core.GetArray(goTo.D)
+	require.True(t, ok)
+	require.Equal(t, 2, dest.Len())
+
+	readPage, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annots, err := readPage.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 1)
+	readLink, ok := annots[0].GetContext().(*model.PdfAnnotationLink)
+	require.True(t, ok)
+	action, err = readLink.GetAction()
+	require.NoError(t, err)
+	readURI, ok := action.GetContext().(*model.PdfActionURI)
+	require.True(t, ok)
+	require.Equal(t, "https://unidoc.io", readURI.URI.(*core.PdfObjectString).Str())
+
+	item, ok := core.GetDict(reader.GetOutlineTree().First.GetContainingPdfObject())
+	require.True(t, ok)
+	action, err = model.NewPdfActionFromObject(item.Get("A"))
+	require.NoError(t, err)
+	require.Equal(t, model.ActionTypeNamed, action.S)
+}
@@ -27,6 +27,8 @@ type PdfAppender struct {
 	pages    []*PdfPage
 	acroForm *PdfAcroForm
 
+	names catalogNames
+
 	xrefs          core.XrefTable
 	xrefOffset     int64
 	greatestObjNum int
@@ -520,6 +522,20 @@ func (a *PdfAppender) ReplaceAcroForm(acroForm *PdfAcroForm) {
 	a.acroForm = acroForm
 }
 
+// AttachFile embeds the file specified by `fs` in the document. The file is added to the existing
+// EmbeddedFiles name tree and, if it has an AFRelationship, to the associated files (AF) array of
+// the catalog.
+func (a *PdfAppender) AttachFile(fs *PdfFilespec) error {
+	return a.names.attachFile(a.roReader.catalog, fs)
+}
+
+// SetNameTree replaces the name tree `name` of the names dictionary of the catalog
+// (Section 7.7.4), e.g. "JavaScript", with the tree built by `tree`. Use
+// NewPdfNameTreeBuilderFromTree to update the existing tree.
+func (a *PdfAppender) SetNameTree(name core.PdfObjectName, tree *PdfNameTreeBuilder) {
+	a.names.setTree(name, tree)
+}
+
 // Write writes the Appender output to io.Writer.
 // It can only be called once and further invocations will result in an error.
 func (a *PdfAppender) Write(w io.Writer) error {
@@ -569,6 +585,9 @@ func (a *PdfAppender) Write(w io.Writer) error {
 		writer.catalog.Set("AcroForm", a.acroForm.ToPdfObject())
 		a.updateObjectsDeep(a.acroForm.ToPdfObject(), nil)
 	}
+	for _, obj := range a.names.apply(catalog, writer.catalog) {
+		a.updateObjectsDeep(obj, nil)
+	}
 
 	a.addNewObject(writer.infoObj)
 	a.addNewObject(writer.root)
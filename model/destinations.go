@@ -0,0 +1,195 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfDestinationType represents the way a destination displays its page (Table 151 p. 374).
+type PdfDestinationType string
+
+// Destination types.
+const (
+	DestinationXYZ   PdfDestinationType = "XYZ"   // Parameters: Left, Top, Zoom.
+	DestinationFit   PdfDestinationType = "Fit"   // No parameters.
+	DestinationFitH  PdfDestinationType = "FitH"  // Parameters: Top.
+	DestinationFitV  PdfDestinationType = "FitV"  // Parameters: Left.
+	DestinationFitR  PdfDestinationType = "FitR"  // Parameters: Left, Bottom, Right, Top.
+	DestinationFitB  PdfDestinationType = "FitB"  // No parameters.
+	DestinationFitBH PdfDestinationType = "FitBH" // Parameters: Top.
+	DestinationFitBV PdfDestinationType = "FitBV" // Parameters: Left.
+)
+
+// params returns the number of parameters of the destination type, -1 for unknown types.
+func (t PdfDestinationType) params() int {
+	switch t {
+	case DestinationFit, DestinationFitB:
+		return 0
+	case DestinationFitH, DestinationFitV, DestinationFitBH, DestinationFitBV:
+		return 1
+	case DestinationXYZ:
+		return 3
+	case DestinationFitR:
+		return 4
+	}
+	return -1
+}
+
+// PdfDestination represents an explicit destination (section 12.3.2.2 p. 374): a page and the
+// location and magnification it is displayed with. Nil parameters are null in the destination
+// array, leaving the current value of the viewer unchanged.
+type PdfDestination struct {
+	// Page is the page object of the destination, or the page index for destinations of remote
+	// go-to actions.
+	Page core.PdfObject
+
+	// PageNumber is the number of the page in the document, starting from 1. Set when loaded by
+	// a PdfReader, 0 if the page is not in the document.
+	PageNumber int
+
+	Type   PdfDestinationType
+	Left   *float64
+	Bottom *float64
+	Right  *float64
+	Top    *float64
+	Zoom   *float64
+}
+
+// NewPdfDestination returns a destination to `page` of type `destType` with parameters `params`,
+// in the order of the destination array, e.g. left, top and zoom for DestinationXYZ.
+func NewPdfDestination(page core.PdfObject, destType PdfDestinationType, params ...float64) (*PdfDestination, error) {
+	dest := &PdfDestination{Page: page, Type: destType}
+	if n := destType.params(); n != len(params) {
+		common.Log.Debug("ERROR: Destination %s expects %d parameters (got %d)", destType, n, len(params))
+		return nil, errors.New("invalid destination parameters")
+	}
+	for i, ptr := range dest.paramPtrs() {
+		v := params[i]
+		*ptr = &v
+	}
+	return dest, nil
+}
+
+// NewPdfDestinationFromObject loads the destination from destination array `obj`, or the D entry
+// of dictionary `obj` (section 12.3.2.3 p. 376).
+func NewPdfDestinationFromObject(obj core.PdfObject) (*PdfDestination, error) {
+	if d, ok := core.GetDict(obj); ok {
+		obj = d.Get("D")
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok || arr.Len() < 2 {
+		common.Log.Debug("ERROR: Invalid destination: %v", obj)
+		return nil, errors.New("invalid destination")
+	}
+	name, ok := core.GetName(arr.Get(1))
+	if !ok {
+		common.Log.Debug("ERROR: Invalid destination type: %v", arr.Get(1))
+		return nil, errors.New("invalid destination type")
+	}
+
+	dest := &PdfDestination{Page: arr.Get(0), Type: PdfDestinationType(*name)}
+	if dest.Type.params() < 0 {
+		common.Log.Debug("ERROR: Unsupported destination type %s", dest.Type)
+		return nil, fmt.Errorf("unsupported destination type %s", dest.Type)
+	}
+	// Missing trailing parameters are handled as null.
+	for i, ptr := range dest.paramPtrs() {
+		if i+2 >= arr.Len() {
+			break
+		}
+		if v, err := core.GetNumberAsFloat(arr.Get(i + 2)); err == nil {
+			*ptr = &v
+		}
+	}
+	return dest, nil
+}
+
+// paramPtrs returns pointers to the parameter fields of the destination type, in the order of
+// the destination array.
+func (dest *PdfDestination) paramPtrs() []**float64 {
+	switch dest.Type {
+	case DestinationXYZ:
+		return []**float64{&dest.Left, &dest.Top, &dest.Zoom}
+	case DestinationFitH, DestinationFitBH:
+		return []**float64{&dest.Top}
+	case DestinationFitV, DestinationFitBV:
+		return []**float64{&dest.Left}
+	case DestinationFitR:
+		return []**float64{&dest.Left, &dest.Bottom, &dest.Right, &dest.Top}
+	}
+	return nil
+}
+
+// ToPdfObject returns the destination array.
+func (dest *PdfDestination) ToPdfObject() core.PdfObject {
+	page := dest.Page
+	if page == nil {
+		page = core.MakeNull()
+	}
+	arr := core.MakeArray(page, core.MakeName(string(dest.Type)))
+	for _, ptr := range dest.paramPtrs() {
+		if *ptr == nil {
+			arr.Append(core.MakeNull())
+		} else {
+			arr.Append(core.MakeFloat(**ptr))
+		}
+	}
+	return arr
+}
+
+// GetNamedDestinations returns the named destinations of the document, from the Dests
+// dictionary of the catalog and the Dests name tree of the names dictionary, which takes
+// precedence. Destinations that cannot be loaded are skipped.
+func (r *PdfReader) GetNamedDestinations() (map[string]*PdfDestination, error) {
+	pageNumbers := map[*core.PdfObjectDictionary]int{}
+	for i, page := range r.pageList {
+		if d, ok := core.GetDict(page); ok {
+			pageNumbers[d] = i + 1
+		}
+	}
+
+	dests := map[string]*PdfDestination{}
+	add := func(name string, obj core.PdfObject) error {
+		dest, err := NewPdfDestinationFromObject(obj)
+		if err != nil {
+			common.Log.Debug("Skipping destination %q: %v", name, err)
+			return nil
+		}
+		if d, ok := core.GetDict(dest.Page); ok {
+			dest.PageNumber = pageNumbers[d]
+		}
+		dests[name] = dest
+		return nil
+	}
+
+	if d, ok := core.GetDict(r.catalog.Get("Dests")); ok {
+		for _, key := range d.Keys() {
+			add(string(key), d.Get(key))
+		}
+	}
+	tree, err := r.GetNameTree("Dests")
+	if err != nil {
+		return nil, err
+	}
+	if tree != nil {
+		if err := tree.Walk(add); err != nil {
+			return nil, err
+		}
+	}
+	return dests, nil
+}
+
+// AddNamedDestination adds destination `dest` as `name` to the Dests name tree of the names
+// dictionary. Links and outline items can target it by name, e.g. with a link annotation Dest
+// of core.MakeString(name), which stays valid when pages are inserted before the destination.
+func (w *PdfWriter) AddNamedDestination(name string, dest *PdfDestination) {
+	w.names.tree(w.catalog, "Dests").Set(name, dest.ToPdfObject())
+}
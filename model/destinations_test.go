@@ -0,0 +1,89 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+func TestPdfDestination(t *testing.T) {
+	page := core.MakeIndirectObject(core.MakeDict())
+	testcases := []struct {
+		destType model.PdfDestinationType
+		params   []float64
+		expected string
+	}{
+		{model.DestinationXYZ, []float64{10, 20, 0}, "[/XYZ 10 20 0]"},
+		{model.DestinationFit, nil, "[/Fit]"},
+		{model.DestinationFitH, []float64{700}, "[/FitH 700]"},
+		{model.DestinationFitV, []float64{50}, "[/FitV 50]"},
+		{model.DestinationFitR, []float64{1, 2, 3, 4}, "[/FitR 1 2 3 4]"},
+		{model.DestinationFitB, nil, "[/FitB]"},
+		{model.DestinationFitBH, []float64{700}, "[/FitBH 700]"},
+		{model.DestinationFitBV, []float64{50}, "[/FitBV 50]"},
+	}
+	for _, tcase := range testcases {
+		dest, err := model.NewPdfDestination(page, tcase.destType, tcase.params...)
+		require.NoError(t, err)
+		arr := dest.ToPdfObject().(*core.PdfObjectArray)
+		require.Equal(t, tcase.expected, core.MakeArray(arr.Elements()[1:]...).WriteString())
+
+		read, err := model.NewPdfDestinationFromObject(arr)
+		require.NoError(t, err)
+		require.Equal(t, dest, read)
+	}
+
+	_, err := model.NewPdfDestination(page, model.DestinationFitR, 1, 2)
+	require.Error(t, err)
+
+	// Null parameters keep the current values.
+	arr := core.MakeArray(page, core.MakeName("XYZ"), core.MakeNull(), core.MakeInteger(500))
+	dest, err := model.NewPdfDestinationFromObject(arr)
+	require.NoError(t, err)
+	require.Nil(t, dest.Left)
+	require.Equal(t, 500.0, *dest.Top)
+	require.Nil(t, dest.Zoom)
+	require.Equal(t, "[/XYZ null 500 null]", core.MakeArray(dest.ToPdfObject().(*core.PdfObjectArray).Elements()[1:]...).WriteString())
+}
+
+func TestNamedDestinations(t *testing.T) {
+	w := model.NewPdfWriter()
+	var pages []*model.PdfPage
+	for i := 0; i < 2; i++ {
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+		require.NoError(t, w.AddPage(page))
+		pages = append(pages, page)
+	}
+	dest, err := model.NewPdfDestination(pages[1].GetPageAsIndirectObject(), model.DestinationFitH, 150)
+	require.NoError(t, err)
+	w.AddNamedDestination("summary", dest)
+
+	link := model.NewPdfAnnotationLink()
+	link.Rect = core.MakeArrayFromFloats([]float64{0, 0, 100, 20})
+	link.Dest = core.MakeString("summary")
+	pages[0].AddAnnotation(link.PdfAnnotation)
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	dests, err := reader.GetNamedDestinations()
+	require.NoError(t, err)
+	require.Len(t, dests, 1)
+	read := dests["summary"]
+	require.NotNil(t, read)
+	require.Equal(t, 2, read.PageNumber)
+	require.Equal(t, model.DestinationFitH, read.Type)
+	require.Equal(t, 150.0, *read.Top)
+}
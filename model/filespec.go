@@ -0,0 +1,306 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"crypto/md5"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// AFRelationship specifies the relationship between an associated file and the PDF component
+// referring to it (PDF 2.0 Section 14.13, PDF/A-3).
+type AFRelationship string
+
+// Associated file relationships.
+const (
+	AFRelationshipSource           AFRelationship = "Source"
+	AFRelationshipData             AFRelationship = "Data"
+	AFRelationshipAlternative      AFRelationship = "Alternative"
+	AFRelationshipSupplement       AFRelationship = "Supplement"
+	AFRelationshipEncryptedPayload AFRelationship = "EncryptedPayload"
+	AFRelationshipFormData         AFRelationship = "FormData"
+	AFRelationshipSchema           AFRelationship = "Schema"
+	AFRelationshipUnspecified      AFRelationship = "Unspecified"
+)
+
+// PdfFilespec represents a file specification dictionary (Section 7.11.3).
+type PdfFilespec struct {
+	// FileName is the name of the file (F and UF entries).
+	FileName string
+	// Description is the description of the file (Desc entry).
+	Description string
+	// AFRelationship is the relationship of the file to the document. Files with a relationship
+	// are listed in the associated files (AF) array of the catalog when attached.
+	AFRelationship AFRelationship
+	// EmbeddedFile is the embedded file stream. Nil if the file is not embedded.
+	EmbeddedFile *PdfEmbeddedFile
+
+	container *core.PdfIndirectObject
+}
+
+// PdfEmbeddedFile represents an embedded file stream (Section 7.11.4).
+type PdfEmbeddedFile struct {
+	// Subtype is the MIME type of the file, e.g. "text/xml".
+	Subtype string
+	// Content is the decoded content of the file.
+	Content []byte
+	// CheckSum is the MD5 checksum of Content. It is computed on output when not set.
+	CheckSum []byte
+
+	CreationDate *PdfDate
+	ModDate      *PdfDate
+
+	// stream is the embedded file stream the file was loaded from or last written to, and
+	// encoded holds the fields it was built with.
+	stream  *core.PdfObjectStream
+	encoded *PdfEmbeddedFile
+}
+
+// NewPdfFilespec returns a file specification embedding `content` with name `fileName`.
+// The modification date of the embedded file is set to the current time.
+func NewPdfFilespec(fileName string, content []byte) *PdfFilespec {
+	ef := &PdfEmbeddedFile{Content: content}
+	if date, err := NewPdfDateFromTime(time.Now()); err == nil {
+		ef.ModDate = &date
+	}
+	return &PdfFilespec{
+		FileName:     fileName,
+		EmbeddedFile: ef,
+		container:    core.MakeIndirectObject(core.MakeDict()),
+	}
+}
+
+// newPdfFilespecFromObject loads a file specification from `obj`, which is either a file
+// specification dictionary or a string holding the file name.
+func newPdfFilespecFromObject(obj core.PdfObject) (*PdfFilespec, error) {
+	fs := &PdfFilespec{}
+	if container, ok := core.GetIndirect(obj); ok {
+		fs.container = container
+	} else {
+		fs.container = core.MakeIndirectObject(core.MakeDict())
+	}
+
+	obj = core.TraceToDirectObject(obj)
+	if str, ok := obj.(*core.PdfObjectString); ok {
+		fs.FileName = str.Decoded()
+		return fs, nil
+	}
+	dict, ok := obj.(*core.PdfObjectDictionary)
+	if !ok {
+		common.Log.Debug("ERROR: Invalid file specification (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+	fs.container.PdfObject = dict
+
+	// UF takes precedence over F, which takes precedence over the platform specific names.
+	for _, key := range []core.PdfObjectName{"UF", "F", "Unix", "Mac", "DOS"} {
+		if str, ok := core.GetString(dict.Get(key)); ok {
+			fs.FileName = str.Decoded()
+			break
+		}
+	}
+	if str, ok := core.GetString(dict.Get("Desc")); ok {
+		fs.Description = str.Decoded()
+	}
+	if name, ok := core.GetNameVal(dict.Get("AFRelationship")); ok {
+		fs.AFRelationship = AFRelationship(name)
+	}
+
+	if ef, ok := core.GetDict(dict.Get("EF")); ok {
+		streamObj := ef.Get("UF")
+		if streamObj == nil {
+			streamObj = ef.Get("F")
+		}
+		if stream, ok := core.GetStream(streamObj); ok {
+			embedded, err := newPdfEmbeddedFileFromStream(stream)
+			if err != nil {
+				return nil, err
+			}
+			fs.EmbeddedFile = embedded
+		}
+	}
+	return fs, nil
+}
+
+// newPdfEmbeddedFileFromStream loads an embedded file from an embedded file stream.
+func newPdfEmbeddedFileFromStream(stream *core.PdfObjectStream) (*PdfEmbeddedFile, error) {
+	content, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode embedded file: %v", err)
+		return nil, err
+	}
+	ef := &PdfEmbeddedFile{Content: content, stream: stream}
+	defer ef.setEncoded()
+	if subtype, ok := core.GetNameVal(stream.Get("Subtype")); ok {
+		ef.Subtype = subtype
+	}
+
+	params, ok := core.GetDict(stream.Get("Params"))
+	if !ok {
+		return ef, nil
+	}
+	if str, ok := core.GetString(params.Get("CheckSum")); ok {
+		ef.CheckSum = str.Bytes()
+	}
+	if str, ok := core.GetStringVal(params.Get("CreationDate")); ok {
+		if date, err := NewPdfDate(str); err == nil {
+			ef.CreationDate = &date
+		} else {
+			common.Log.Debug("ERROR: Invalid embedded file creation date: %v", err)
+		}
+	}
+	if str, ok := core.GetStringVal(params.Get("ModDate")); ok {
+		if date, err := NewPdfDate(str); err == nil {
+			ef.ModDate = &date
+		} else {
+			common.Log.Debug("ERROR: Invalid embedded file modification date: %v", err)
+		}
+	}
+	return ef, nil
+}
+
+// GetContainingPdfObject returns the container of the file specification (indirect object).
+func (fs *PdfFilespec) GetContainingPdfObject() core.PdfObject {
+	return fs.container
+}
+
+// ToPdfObject returns the file specification dictionary as an indirect object. Entries of a
+// loaded file specification that are not modelled by PdfFilespec are preserved.
+func (fs *PdfFilespec) ToPdfObject() core.PdfObject {
+	if fs.container == nil {
+		fs.container = core.MakeIndirectObject(core.MakeDict())
+	}
+	dict, ok := fs.container.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		dict = core.MakeDict()
+		fs.container.PdfObject = dict
+	}
+	dict.Set("Type", core.MakeName("Filespec"))
+	dict.Set("F", core.MakeEncodedString(fs.FileName, false))
+	dict.Set("UF", core.MakeEncodedString(fs.FileName, true))
+	if fs.Description != "" {
+		dict.Set("Desc", core.MakeEncodedString(fs.Description, true))
+	} else {
+		dict.Remove("Desc")
+	}
+	if fs.AFRelationship != "" {
+		dict.Set("AFRelationship", core.MakeName(string(fs.AFRelationship)))
+	} else {
+		dict.Remove("AFRelationship")
+	}
+
+	if fs.EmbeddedFile == nil {
+		dict.Remove("EF")
+		return fs.container
+	}
+	stream, err := fs.EmbeddedFile.ToPdfObject()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to encode embedded file %q: %v", fs.FileName, err)
+		return fs.container
+	}
+	ef, ok := core.GetDict(dict.Get("EF"))
+	if !ok {
+		ef = core.MakeDict()
+		dict.Set("EF", ef)
+	}
+	ef.Set("F", stream)
+	ef.Set("UF", stream)
+	return fs.container
+}
+
+// ToPdfObject returns the embedded file stream of `ef`. The stream `ef` was loaded from or
+// last written to is returned as is when `ef` has not changed since. Otherwise the content is
+// compressed with the Flate filter into a new stream, which keeps the entries of the previous
+// stream and its parameters that are not modelled by PdfEmbeddedFile.
+func (ef *PdfEmbeddedFile) ToPdfObject() (*core.PdfObjectStream, error) {
+	if ef.stream != nil && !ef.modified() {
+		return ef.stream, nil
+	}
+
+	stream, err := core.MakeStream(ef.Content, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	params := core.MakeDict()
+	if ef.stream != nil {
+		for _, key := range ef.stream.Keys() {
+			switch key {
+			case "Length", "Filter", "DecodeParms", "DL", "F", "FFilter", "FDecodeParms":
+				continue
+			}
+			stream.Set(key, ef.stream.Get(key))
+		}
+		if prev, ok := core.GetDict(ef.stream.Get("Params")); ok {
+			params.Merge(prev)
+		}
+	}
+	stream.Set("Type", core.MakeName("EmbeddedFile"))
+	if ef.Subtype != "" {
+		stream.Set("Subtype", core.MakeName(ef.Subtype))
+	} else {
+		stream.Remove("Subtype")
+	}
+
+	checksum := ef.CheckSum
+	if checksum == nil {
+		sum := md5.Sum(ef.Content)
+		checksum = sum[:]
+	}
+	params.Set("Size", core.MakeInteger(int64(len(ef.Content))))
+	params.Set("CheckSum", core.MakeHexString(string(checksum)))
+	if ef.CreationDate != nil {
+		params.Set("CreationDate", ef.CreationDate.ToPdfObject())
+	} else {
+		params.Remove("CreationDate")
+	}
+	if ef.ModDate != nil {
+		params.Set("ModDate", ef.ModDate.ToPdfObject())
+	} else {
+		params.Remove("ModDate")
+	}
+	stream.Set("Params", params)
+
+	ef.stream = stream
+	ef.setEncoded()
+	return stream, nil
+}
+
+// setEncoded records the fields of `ef` as those its stream was built with.
+func (ef *PdfEmbeddedFile) setEncoded() {
+	encoded := &PdfEmbeddedFile{
+		Subtype:  ef.Subtype,
+		Content:  append([]byte(nil), ef.Content...),
+		CheckSum: append([]byte(nil), ef.CheckSum...),
+	}
+	if ef.CreationDate != nil {
+		date := *ef.CreationDate
+		encoded.CreationDate = &date
+	}
+	if ef.ModDate != nil {
+		date := *ef.ModDate
+		encoded.ModDate = &date
+	}
+	ef.encoded = encoded
+}
+
+// modified returns true if the fields of `ef` differ from those its stream was built with.
+func (ef *PdfEmbeddedFile) modified() bool {
+	prev := ef.encoded
+	if prev == nil {
+		return true
+	}
+	sameDate := func(a, b *PdfDate) bool {
+		return a == b || a != nil && b != nil && *a == *b
+	}
+	return ef.Subtype != prev.Subtype ||
+		!bytes.Equal(ef.Content, prev.Content) ||
+		!bytes.Equal(ef.CheckSum, prev.CheckSum) ||
+		!sameDate(ef.CreationDate, prev.CreationDate) || !sameDate(ef.ModDate, prev.ModDate)
+}
@@ -0,0 +1,215 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+func TestWriterAttachFile(t *testing.T) {
+	created, err := model.NewPdfDateFromTime(time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	xml := []byte("<rsm:CrossIndustryInvoice/>")
+	invoice := model.NewPdfFilespec("factur-x.xml", xml)
+	invoice.Description = "Factur-X invoice"
+	invoice.AFRelationship = model.AFRelationshipAlternative
+	invoice.EmbeddedFile.Subtype = "text/xml"
+	invoice.EmbeddedFile.CreationDate = &created
+
+	notes := model.NewPdfFilespec("notes.txt", []byte("Notes"))
+
+	w := model.NewPdfWriter()
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+	require.NoError(t, w.AddPage(page))
+	require.NoError(t, w.AttachFile(notes))
+	require.NoError(t, w.AttachFile(invoice))
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	files, err := reader.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	// Name tree entries are sorted by name.
+	fs := files[0]
+	require.Equal(t, "factur-x.xml", fs.FileName)
+	require.Equal(t, "Factur-X invoice", fs.Description)
+	require.Equal(t, model.AFRelationshipAlternative, fs.AFRelationship)
+	require.NotNil(t, fs.EmbeddedFile)
+	require.Equal(t, xml, fs.EmbeddedFile.Content)
+	require.Equal(t, "text/xml", fs.EmbeddedFile.Subtype)
+	sum := md5.Sum(xml)
+	require.Equal(t, sum[:], fs.EmbeddedFile.CheckSum)
+	require.NotNil(t, fs.EmbeddedFile.CreationDate)
+	require.True(t, created.ToGoTime().Equal(fs.EmbeddedFile.CreationDate.ToGoTime()))
+	require.NotNil(t, fs.EmbeddedFile.ModDate)
+
+	require.Equal(t, "notes.txt", files[1].FileName)
+	require.Equal(t, []byte("Notes"), files[1].EmbeddedFile.Content)
+	require.Empty(t, files[1].AFRelationship)
+
+	// Only files with a relationship are associated with the document.
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	catalog, ok := core.GetDict(trailer.Get("Root"))
+	require.True(t, ok)
+	af, ok := core.GetArray(catalog.Get("AF"))
+	require.True(t, ok)
+	require.Equal(t, 1, af.Len())
+}
+
+func TestWriterAttachFileDuplicate(t *testing.T) {
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AttachFile(model.NewPdfFilespec("a.txt", []byte("1"))))
+	require.Error(t, w.AttachFile(model.NewPdfFilespec("a.txt", []byte("2"))))
+}
+
+func TestAppenderAttachFile(t *testing.T) {
+	// Create a document with an attachment.
+	w := model.NewPdfWriter()
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+	require.NoError(t, w.AddPage(page))
+	require.NoError(t, w.AttachFile(model.NewPdfFilespec("b.txt", []byte("first"))))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	// Append a second attachment.
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	fs := model.NewPdfFilespec("a.xml", []byte("<second/>"))
+	fs.AFRelationship = model.AFRelationshipSource
+	require.NoError(t, appender.AttachFile(fs))
+
+	var out bytes.Buffer
+	require.NoError(t, appender.Write(&out))
+	require.True(t, bytes.HasPrefix(out.Bytes(), buf.Bytes()))
+
+	reader, err = model.NewPdfReader(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	files, err := reader.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, "a.xml", files[0].FileName)
+	require.Equal(t, []byte("<second/>"), files[0].EmbeddedFile.Content)
+	require.Equal(t, model.AFRelationshipSource, files[0].AFRelationship)
+	require.Equal(t, "b.txt", files[1].FileName)
+	require.Equal(t, []byte("first"), files[1].EmbeddedFile.Content)
+}
+
+func TestReaderEmbeddedFilesInvalid(t *testing.T) {
+	files := model.NewPdfNameTreeBuilder()
+	files.Set("a.txt", model.NewPdfFilespec("a.txt", []byte("a")).ToPdfObject())
+	files.Set("b.txt", core.MakeInteger(1))
+	files.Set("c.txt", model.NewPdfFilespec("c.txt", []byte("c")).ToPdfObject())
+
+	w := model.NewPdfWriter()
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+	require.NoError(t, w.AddPage(page))
+	w.SetNameTree("EmbeddedFiles", files)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	// The invalid file specification is skipped.
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	embedded, err := reader.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, embedded, 2)
+	require.Equal(t, "a.txt", embedded[0].FileName)
+	require.Equal(t, []byte("c"), embedded[1].EmbeddedFile.Content)
+}
+
+func TestFilespecToPdfObjectPreservesEntries(t *testing.T) {
+	stream, err := core.MakeStream([]byte("raw"), core.NewRawEncoder())
+	require.NoError(t, err)
+	stream.Set("Type", core.MakeName("EmbeddedFile"))
+	params := core.MakeDict()
+	params.Set("Mac", core.MakeDict())
+	params.Set("CheckSum", core.MakeHexString("original"))
+	stream.Set("Params", params)
+	ef := core.MakeDict()
+	ef.Set("F", stream)
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("Filespec"))
+	dict.Set("FS", core.MakeName("URL"))
+	dict.Set("F", core.MakeString("data.bin"))
+	dict.Set("CI", core.MakeDict())
+	dict.Set("EF", ef)
+	files := model.NewPdfNameTreeBuilder()
+	files.Set("data.bin", core.MakeIndirectObject(dict))
+
+	w := model.NewPdfWriter()
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+	require.NoError(t, w.AddPage(page))
+	w.SetNameTree("EmbeddedFiles", files)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	embedded, err := reader.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, embedded, 1)
+	fs := embedded[0]
+	loaded, err := fs.EmbeddedFile.ToPdfObject()
+	require.NoError(t, err)
+
+	// The unmodelled entries and the unchanged embedded file stream are kept.
+	fs.Description = "Data"
+	out, ok := core.GetDict(fs.ToPdfObject())
+	require.True(t, ok)
+	require.Equal(t, "URL", out.Get("FS").String())
+	require.NotNil(t, out.Get("CI"))
+	require.Equal(t, "Data", out.Get("Desc").(*core.PdfObjectString).Decoded())
+	outEF, ok := core.GetDict(out.Get("EF"))
+	require.True(t, ok)
+	require.True(t, outEF.Get("F") == loaded)
+	require.Nil(t, loaded.Get("Filter"))
+
+	// A modified embedded file is encoded into a new stream keeping the unmodelled parameters.
+	fs.EmbeddedFile.Content[0] = 'R'
+	fs.EmbeddedFile.CheckSum = nil
+	out, ok = core.GetDict(fs.ToPdfObject())
+	require.True(t, ok)
+	outEF, ok = core.GetDict(out.Get("EF"))
+	require.True(t, ok)
+	modified, ok := core.GetStream(outEF.Get("F"))
+	require.True(t, ok)
+	require.False(t, modified == loaded)
+	content, err := core.DecodeStream(modified)
+	require.NoError(t, err)
+	require.Equal(t, []byte("Raw"), content)
+	outParams, ok := core.GetDict(modified.Get("Params"))
+	require.True(t, ok)
+	require.NotNil(t, outParams.Get("Mac"))
+	sum := md5.Sum([]byte("Raw"))
+	require.Equal(t, string(sum[:]), outParams.Get("CheckSum").(*core.PdfObjectString).Str())
+
+	// The new stream is reused while the embedded file is unchanged.
+	out, ok = core.GetDict(fs.ToPdfObject())
+	require.True(t, ok)
+	outEF, ok = core.GetDict(out.Get("EF"))
+	require.True(t, ok)
+	require.True(t, outEF.Get("F") == modified)
+}
@@ -302,6 +302,10 @@ type pdfCIDFontType2 struct {
 	// TODO(dennwc): it is used only in GetGlyphCharMetrics
 	//  			 we can precompute metrics and drop it
 	runeToWidthMap map[rune]int
+
+	// program is the TrueType font program the font was created from, if any. It allows the
+	// embedded font program to be subset on write.
+	program *ttfProgram
 }
 
 // pdfCIDFontType2FromSkeleton returns a pdfCIDFontType2 with its common fields initalized.
@@ -557,6 +561,7 @@ func NewCompositePdfFontFromTTFFile(filePath string) (*PdfFont, error) {
 	}
 	stream.PdfObjectDictionary.Set("Length1", core.MakeInteger(int64(len(ttfBytes))))
 	descriptor.FontFile2 = stream
+	cidfont.program = &ttfProgram{data: ttfBytes, ttf: ttf}
 
 	if ttf.Bold {
 		descriptor.StemV = core.MakeInteger(120)
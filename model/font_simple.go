@@ -60,6 +60,10 @@ type pdfFontSimple struct {
 
 	// Standard 14 fonts metrics
 	fontMetrics map[rune]fonts.CharMetrics
+
+	// program is the TrueType font program the font was created from, if any. It allows the
+	// embedded font program to be subset on write.
+	program *ttfProgram
 }
 
 // pdfCIDFontType0FromSkeleton returns a pdfFontSimple with its common fields initalized.
@@ -470,6 +474,7 @@ func NewPdfFontFromTTFFile(filePath string) (*PdfFont, error) {
 	}
 	stream.PdfObjectDictionary.Set("Length1", core.MakeInteger(int64(len(ttfBytes))))
 	descriptor.FontFile2 = stream
+	truefont.program = &ttfProgram{data: ttfBytes, ttf: ttf}
 
 	if ttf.Bold {
 		descriptor.StemV = core.MakeInteger(120)
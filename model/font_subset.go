@@ -0,0 +1,436 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+
+	"github.com/unidoc/unipdf/v3/internal/cmap"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/model/internal/fonts"
+)
+
+// ttfProgram is the TrueType font program that a font was created from.
+type ttfProgram struct {
+	data []byte
+	ttf  fonts.TtfType
+}
+
+// ttfSubset holds the font dictionary entries of a font whose embedded TrueType font program has
+// been replaced by a subset.
+//
+// 9.6.4 Font Subsets (page 258)
+// For a font subset, the PostScript name of the font—the value of the font’s BaseFont entry and the
+// font descriptor’s FontName entry—shall begin with a tag followed by a plus sign (+). The tag shall
+// consist of exactly six uppercase letters.
+type ttfSubset struct {
+	basefont  string
+	fontFile  *core.PdfObjectStream
+	widths    *core.PdfObjectArray  // W array of CIDFontType2 fonts, Widths array of simple fonts.
+	toUnicode *core.PdfObjectStream // nil for simple fonts.
+
+	// FirstChar and LastChar of simple fonts, trimmed to the codes that have been used.
+	firstChar, lastChar int64
+}
+
+// addUsedCodes records the character codes shown in the content of `page` with the fonts of its
+// resources that were set with PdfPageResources.SetFont. The fonts are not subset if the content
+// cannot be decoded.
+func (w *PdfWriter) addUsedCodes(page *PdfPage) {
+	resources := page.Resources
+	if resources == nil || len(resources.fonts) == 0 {
+		return
+	}
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode page content, fonts are not subset: %v", err)
+		for _, font := range resources.fonts {
+			w.usedCodes[font] = nil
+		}
+		return
+	}
+
+	for name, strs := range shownStrings([]byte(content)) {
+		font, ok := resources.GetFont(core.PdfObjectName(name))
+		if !ok {
+			continue
+		}
+		codes, has := w.usedCodes[font]
+		if has && codes == nil {
+			continue
+		}
+		if codes == nil {
+			codes = make(map[textencoding.CharCode]struct{})
+			w.usedCodes[font] = codes
+		}
+		for _, str := range strs {
+			for _, code := range font.BytesToCharcodes(str) {
+				codes[code] = struct{}{}
+			}
+		}
+	}
+}
+
+// shownStrings returns the strings shown by the text showing operators of content stream
+// `content`, keyed by the resource name of the font they are shown with.
+func shownStrings(content []byte) map[string][][]byte {
+	shown := make(map[string][][]byte)
+	var font string
+	var fontStack []string
+	var names []string
+	var strs [][]byte
+	for i := 0; i < len(content); {
+		c := content[i]
+		start := i
+		switch {
+		case core.IsWhiteSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\r' && content[i] != '\n' {
+				i++
+			}
+		case c == '(':
+			i = skipLiteralString(content, i)
+			strs = append(strs, decodeLiteralString(content[start:i]))
+		case c == '<' && i+1 < len(content) && content[i+1] == '<', c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			for i < len(content) && content[i] != '>' {
+				i++
+			}
+			i++
+			strs = append(strs, decodeHexString(content[start:i]))
+		case c == '[' || c == ']' || c == '{' || c == '}' || c == ')' || c == '>':
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isContentDelimiter(content[i]) {
+				i++
+			}
+			names = append(names, string(content[start+1:i]))
+		default:
+			for i < len(content) && !isContentDelimiter(content[i]) {
+				i++
+			}
+			word := string(content[start:i])
+			if _, err := strconv.ParseFloat(word, 64); err == nil {
+				continue
+			}
+			switch word {
+			case "true", "false", "null":
+				continue
+			case "Tf":
+				if len(names) > 0 {
+					font = names[len(names)-1]
+				}
+			case "Tj", "TJ", "'", "\"":
+				if font != "" {
+					shown[font] = append(shown[font], strs...)
+				}
+			case "q":
+				fontStack = append(fontStack, font)
+			case "Q":
+				if n := len(fontStack); n > 0 {
+					font, fontStack = fontStack[n-1], fontStack[:n-1]
+				}
+			case "ID":
+				i = skipInlineImageData(content, i)
+			}
+			names, strs = names[:0], strs[:0]
+		}
+	}
+	return shown
+}
+
+// decodeLiteralString returns the bytes of the literal string `str`, including its parentheses.
+func decodeLiteralString(str []byte) []byte {
+	str = bytes.TrimPrefix(str, []byte("("))
+	str = bytes.TrimSuffix(str, []byte(")"))
+	var b []byte
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c != '\\' || i+1 == len(str) {
+			b = append(b, c)
+			continue
+		}
+		i++
+		switch c = str[i]; c {
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case '\r':
+			// A backslash at the end of a line continues the string on the next line.
+			if i+1 < len(str) && str[i+1] == '\n' {
+				i++
+			}
+		case '\n':
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			code := c - '0'
+			for j := 0; j < 2 && i+1 < len(str) && str[i+1] >= '0' && str[i+1] <= '7'; j++ {
+				i++
+				code = code*8 + str[i] - '0'
+			}
+			b = append(b, code)
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// decodeHexString returns the bytes of the hexadecimal string `str`, including its angle brackets.
+func decodeHexString(str []byte) []byte {
+	digits := make([]byte, 0, len(str))
+	for _, c := range str {
+		if ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	hex.Decode(b, digits)
+	return b
+}
+
+// sortedCodes returns the character codes of `codes` in increasing order.
+func sortedCodes(codes map[textencoding.CharCode]struct{}) []textencoding.CharCode {
+	sorted := make([]textencoding.CharCode, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+// makeTTFSubset returns the subset of the embedded font program of `font` containing the glyphs
+// of the character codes `codes`, which are in increasing order.
+// A nil subset is returned if `font` was not created from a TrueType font file.
+func (font *PdfFont) makeTTFSubset(codes []textencoding.CharCode) (*ttfSubset, error) {
+	switch t := font.context.(type) {
+	case *pdfFontType0:
+		if t.DescendantFont == nil {
+			return nil, nil
+		}
+		cidfont, ok := t.DescendantFont.context.(*pdfCIDFontType2)
+		if !ok || cidfont.program == nil {
+			return nil, nil
+		}
+		if _, ok := t.encoder.(textencoding.TrueTypeFontEncoder); !ok {
+			return nil, nil
+		}
+		// The character codes are the glyph indices (Identity-H).
+		gids := make(map[fonts.GID]struct{}, len(codes))
+		for _, code := range codes {
+			gids[fonts.GID(code)] = struct{}{}
+		}
+		runes := make(map[rune]fonts.GID)
+		for r, gid := range cidfont.program.ttf.Chars {
+			if _, ok := gids[gid]; ok {
+				runes[r] = gid
+			}
+		}
+		subset, err := newTTFSubset(cidfont.program, cidfont.basefont, runes)
+		if err != nil {
+			return nil, err
+		}
+		subset.widths = makeSubsetWidthArr(cidfont.program.ttf, runes)
+		subset.toUnicode, err = makeSubsetToUnicode(runes)
+		if err != nil {
+			return nil, err
+		}
+		return subset, nil
+	case *pdfFontSimple:
+		if t.program == nil || t.encoder == nil {
+			return nil, nil
+		}
+		runes := make(map[rune]fonts.GID)
+		for _, code := range codes {
+			r, ok := t.encoder.CharcodeToRune(code)
+			if !ok {
+				continue
+			}
+			if gid, ok := t.program.ttf.Chars[r]; ok {
+				runes[r] = gid
+			}
+		}
+		subset, err := newTTFSubset(t.program, t.basefont, runes)
+		if err != nil {
+			return nil, err
+		}
+		if len(codes) > 0 {
+			first, last := codes[0], codes[len(codes)-1]
+			widths := make([]float64, 0, last-first+1)
+			for code := first; code <= last; code++ {
+				widths = append(widths, t.charWidths[code])
+			}
+			subset.widths = core.MakeArrayFromFloats(widths)
+			subset.firstChar, subset.lastChar = int64(first), int64(last)
+		}
+		return subset, nil
+	}
+	return nil, nil
+}
+
+// newTTFSubset subsets `program` to the glyphs of `runes` and returns the subset with a tagged
+// `basefont` name.
+func newTTFSubset(program *ttfProgram, basefont string, runes map[rune]fonts.GID) (*ttfSubset, error) {
+	data, err := fonts.SubsetTTF(program.data, runes)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := core.MakeStream(data, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	stream.PdfObjectDictionary.Set("Length1", core.MakeInteger(int64(len(data))))
+
+	return &ttfSubset{
+		basefont: makeSubsetTag(runes) + "+" + basefont,
+		fontFile: stream,
+	}, nil
+}
+
+// makeSubsetTag returns a six uppercase letter font subset tag. The tag is derived from the glyphs in
+// the subset so that the output is reproducible.
+func makeSubsetTag(runes map[rune]fonts.GID) string {
+	gids := sortedSubsetGIDs(runes)
+	h := fnv.New64a()
+	for _, gid := range gids {
+		h.Write([]byte{byte(gid >> 8), byte(gid)})
+	}
+	sum := h.Sum64()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(sum%26)
+		sum /= 26
+	}
+	return string(tag)
+}
+
+// sortedSubsetGIDs returns the distinct GIDs in `runes` in increasing order.
+func sortedSubsetGIDs(runes map[rune]fonts.GID) []fonts.GID {
+	seen := make(map[fonts.GID]struct{}, len(runes))
+	gids := make([]fonts.GID, 0, len(runes))
+	for _, gid := range runes {
+		if _, ok := seen[gid]; ok {
+			continue
+		}
+		seen[gid] = struct{}{}
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool {
+		return gids[i] < gids[j]
+	})
+	return gids
+}
+
+// makeSubsetWidthArr returns a CIDFont W array with the widths of the glyphs of `runes`.
+// The CIDs are the GIDs as subset fonts use the Identity CIDToGIDMap.
+//
+// 9.7.4.3 Glyph Metrics in CIDFonts
+// c [w1 w2 ... wn] specifies the widths for n consecutive CIDs, starting with c.
+func makeSubsetWidthArr(ttf fonts.TtfType, runes map[rune]fonts.GID) *core.PdfObjectArray {
+	k := 1000.0 / float64(ttf.UnitsPerEm)
+	arr := core.MakeArray()
+
+	gids := sortedSubsetGIDs(runes)
+	for i := 0; i < len(gids); {
+		j := i + 1
+		for j < len(gids) && gids[j] == gids[j-1]+1 {
+			j++
+		}
+		widths := core.MakeArray()
+		for _, gid := range gids[i:j] {
+			var w float64
+			if int(gid) < len(ttf.Widths) {
+				w = k * float64(ttf.Widths[gid])
+			}
+			widths.Append(core.MakeInteger(int64(w)))
+		}
+		arr.Append(core.MakeInteger(int64(gids[i])), widths)
+		i = j
+	}
+	return arr
+}
+
+// makeSubsetToUnicode returns a ToUnicode CMap stream that maps the 2-byte character codes (GIDs)
+// of a subset composite font to `runes`.
+func makeSubsetToUnicode(runes map[rune]fonts.GID) (*core.PdfObjectStream, error) {
+	codeToUnicode := make(map[cmap.CharCode]rune, len(runes))
+	for r, gid := range runes {
+		code := cmap.CharCode(gid)
+		// Several runes may map to one glyph. Keep the lowest for reproducible output.
+		if r0, ok := codeToUnicode[code]; ok && r0 < r {
+			continue
+		}
+		codeToUnicode[code] = r
+	}
+	return core.MakeStream(cmap.NewToUnicodeCMap(codeToUnicode).Bytes(), nil)
+}
+
+// apply replaces the font program related entries of the font dictionary `d` with those of the
+// subset `s`. `d` is expected to be the writer's copy of the font dictionary.
+func (s *ttfSubset) apply(d *core.PdfObjectDictionary) {
+	d.Set("BaseFont", core.MakeName(s.basefont))
+	setStream := func(obj core.PdfObject, src *core.PdfObjectStream) {
+		if stream, ok := core.GetStream(obj); ok && src != nil {
+			stream.PdfObjectDictionary = src.PdfObjectDictionary
+			stream.Stream = src.Stream
+		}
+	}
+	setStream(d.Get("ToUnicode"), s.toUnicode)
+	setWidths := func(d *core.PdfObjectDictionary, key core.PdfObjectName) {
+		if ind, ok := d.Get(key).(*core.PdfIndirectObject); ok {
+			ind.PdfObject = s.widths
+		} else {
+			d.Set(key, s.widths)
+		}
+	}
+
+	descendants, ok := core.GetArray(d.Get("DescendantFonts"))
+	if ok && descendants.Len() > 0 {
+		dd, ok := core.GetDict(descendants.Get(0))
+		if !ok {
+			common.Log.Debug("ERROR: Invalid descendant font %T", descendants.Get(0))
+			return
+		}
+		dd.Set("BaseFont", core.MakeName(s.basefont))
+		if s.widths != nil {
+			setWidths(dd, "W")
+		}
+		d = dd
+	} else if s.widths != nil {
+		d.Set("FirstChar", core.MakeInteger(s.firstChar))
+		d.Set("LastChar", core.MakeInteger(s.lastChar))
+		setWidths(d, "Widths")
+	}
+
+	descriptor, ok := core.GetDict(d.Get("FontDescriptor"))
+	if !ok {
+		common.Log.Debug("ERROR: Missing font descriptor")
+		return
+	}
+	descriptor.Set("FontName", core.MakeName(s.basefont))
+	setStream(descriptor.Get("FontFile2"), s.fontFile)
+}
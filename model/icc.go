@@ -0,0 +1,128 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// sRGBProfileDescription is the description of the sRGB ICC profile and the output condition
+// identifier of the PDF/A output intent.
+const sRGBProfileDescription = "sRGB IEC61966-2.1"
+
+// newSRGBProfile returns an ICC version 2.1 display profile for the sRGB color space
+// (IEC 61966-2.1), with the primaries adapted to the D50 illuminant of the profile connection space.
+func newSRGBProfile() []byte {
+	type iccTag struct {
+		sig  string
+		data []byte
+	}
+
+	trc := iccCurve(1024, func(x float64) float64 {
+		if x <= 0.04045 {
+			return x / 12.92
+		}
+		return math.Pow((x+0.055)/1.055, 2.4)
+	})
+	tags := []iccTag{
+		{"desc", iccTextDescription(sRGBProfileDescription)},
+		{"cprt", iccText("No copyright, use freely")},
+		{"wtpt", iccXYZ(0.9505, 1.0, 1.0891)},
+		{"rXYZ", iccXYZ(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", iccXYZ(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", iccXYZ(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	// Tag data follows the header and the tag table, 4-byte aligned. Identical data is shared.
+	offset := 128 + 4 + 12*len(tags)
+	var table, data bytes.Buffer
+	binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	offsets := map[*byte]int{}
+	for _, tag := range tags {
+		tagOffset, shared := offsets[&tag.data[0]]
+		if !shared {
+			tagOffset = offset + data.Len()
+			offsets[&tag.data[0]] = tagOffset
+			data.Write(tag.data)
+			for data.Len()%4 != 0 {
+				data.WriteByte(0)
+			}
+		}
+		table.WriteString(tag.sig)
+		binary.Write(&table, binary.BigEndian, uint32(tagOffset))
+		binary.Write(&table, binary.BigEndian, uint32(len(tag.data)))
+	}
+
+	size := offset + data.Len()
+	var header bytes.Buffer
+	binary.Write(&header, binary.BigEndian, uint32(size))
+	header.Write(make([]byte, 4))                                           // Preferred CMM.
+	binary.Write(&header, binary.BigEndian, uint32(0x02100000))             // Version 2.1.
+	header.WriteString("mntrRGB XYZ ")                                      // Display device, RGB to XYZ.
+	binary.Write(&header, binary.BigEndian, []uint16{1998, 2, 9, 6, 49, 0}) // Creation date.
+	header.WriteString("acsp")
+	header.Write(make([]byte, 68-header.Len()))   // Platform, flags, device, attributes and intent.
+	header.Write(iccXYZ(0.9642, 1.0, 0.8249)[8:]) // D50 illuminant.
+	header.Write(make([]byte, 128-header.Len()))
+
+	profile := make([]byte, 0, size)
+	profile = append(profile, header.Bytes()...)
+	profile = append(profile, table.Bytes()...)
+	profile = append(profile, data.Bytes()...)
+	return profile
+}
+
+// iccXYZ returns an ICC XYZType tag with a single XYZ value.
+func iccXYZ(x, y, z float64) []byte {
+	var b bytes.Buffer
+	b.WriteString("XYZ ")
+	b.Write(make([]byte, 4))
+	for _, v := range []float64{x, y, z} {
+		binary.Write(&b, binary.BigEndian, int32(math.Round(v*65536)))
+	}
+	return b.Bytes()
+}
+
+// iccCurve returns an ICC curveType tag sampling `f` over [0, 1] at `n` points.
+func iccCurve(n int, f func(x float64) float64) []byte {
+	var b bytes.Buffer
+	b.WriteString("curv")
+	b.Write(make([]byte, 4))
+	binary.Write(&b, binary.BigEndian, uint32(n))
+	for i := 0; i < n; i++ {
+		v := f(float64(i) / float64(n-1))
+		binary.Write(&b, binary.BigEndian, uint16(math.Round(v*65535)))
+	}
+	return b.Bytes()
+}
+
+// iccText returns an ICC textType tag.
+func iccText(s string) []byte {
+	var b bytes.Buffer
+	b.WriteString("text")
+	b.Write(make([]byte, 4))
+	b.WriteString(s)
+	b.WriteByte(0)
+	return b.Bytes()
+}
+
+// iccTextDescription returns an ICC version 2 textDescriptionType tag with ASCII description `s`.
+func iccTextDescription(s string) []byte {
+	var b bytes.Buffer
+	b.WriteString("desc")
+	b.Write(make([]byte, 4))
+	binary.Write(&b, binary.BigEndian, uint32(len(s)+1))
+	b.WriteString(s)
+	b.WriteByte(0)
+	// No Unicode and ScriptCode descriptions.
+	b.Write(make([]byte, 4+4+2+1+67))
+	return b.Bytes()
+}
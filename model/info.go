@@ -0,0 +1,281 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfInfoTrapped specifies whether a document has been modified to include trapping information
+// (Trapped entry of the document information dictionary).
+type PdfInfoTrapped string
+
+// Trapped values.
+const (
+	TrappedTrue    PdfInfoTrapped = "True"
+	TrappedFalse   PdfInfoTrapped = "False"
+	TrappedUnknown PdfInfoTrapped = "Unknown"
+)
+
+// PdfInfo represents the document information dictionary (section 14.3.3 of the PDF32000_2008
+// specification). Empty strings and zero dates are not set in the dictionary.
+type PdfInfo struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     string
+	Creator      string
+	Producer     string
+	CreationDate time.Time
+	ModDate      time.Time
+	Trapped      PdfInfoTrapped
+
+	// Entries with custom keys, in the order they were set.
+	custom *core.PdfObjectDictionary
+}
+
+// Keys of the standard entries of the document information dictionary.
+var pdfInfoKeys = map[core.PdfObjectName]struct{}{
+	"Title": {}, "Author": {}, "Subject": {}, "Keywords": {}, "Creator": {}, "Producer": {},
+	"CreationDate": {}, "ModDate": {}, "Trapped": {},
+}
+
+// NewPdfInfo returns an empty document information dictionary.
+func NewPdfInfo() *PdfInfo {
+	return &PdfInfo{custom: core.MakeDict()}
+}
+
+// NewPdfInfoFromObject loads the document information dictionary from `obj`. Entries that
+// cannot be decoded are skipped.
+func NewPdfInfoFromObject(obj core.PdfObject) (*PdfInfo, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Info is not a dictionary: %T", obj)
+		return nil, errors.New("type check error")
+	}
+
+	info := NewPdfInfo()
+	texts := []struct {
+		key   core.PdfObjectName
+		value *string
+	}{
+		{"Title", &info.Title},
+		{"Author", &info.Author},
+		{"Subject", &info.Subject},
+		{"Keywords", &info.Keywords},
+		{"Creator", &info.Creator},
+		{"Producer", &info.Producer},
+	}
+	for _, t := range texts {
+		if s, ok := core.GetString(dict.Get(t.key)); ok {
+			*t.value = s.Decoded()
+		}
+	}
+
+	dates := []struct {
+		key   core.PdfObjectName
+		value *time.Time
+	}{
+		{"CreationDate", &info.CreationDate},
+		{"ModDate", &info.ModDate},
+	}
+	for _, d := range dates {
+		s, ok := core.GetString(dict.Get(d.key))
+		if !ok {
+			continue
+		}
+		date, err := NewPdfDate(s.Str())
+		if err != nil {
+			common.Log.Debug("Invalid Info %s date %q: %v", d.key, s.Str(), err)
+			continue
+		}
+		*d.value = date.ToGoTime()
+	}
+
+	// Trapped is a boolean before PDF 1.3.
+	switch t := core.TraceToDirectObject(dict.Get("Trapped")).(type) {
+	case *core.PdfObjectName:
+		info.Trapped = PdfInfoTrapped(*t)
+	case *core.PdfObjectBool:
+		info.Trapped = TrappedFalse
+		if *t {
+			info.Trapped = TrappedTrue
+		}
+	}
+
+	for _, key := range dict.Keys() {
+		if _, has := pdfInfoKeys[key]; has {
+			continue
+		}
+		if s, ok := core.GetString(dict.Get(key)); ok {
+			info.custom.Set(key, s)
+		}
+	}
+	return info, nil
+}
+
+// SetCustomInfo sets the entry with custom key `name` to `value`. An empty value removes the
+// entry.
+func (info *PdfInfo) SetCustomInfo(name, value string) {
+	if info.custom == nil {
+		info.custom = core.MakeDict()
+	}
+	key := core.PdfObjectName(name)
+	if value == "" {
+		info.custom.Remove(key)
+		return
+	}
+	info.custom.Set(key, core.MakeEncodedString(value, !isPDFDocEncodable(value)))
+}
+
+// CustomInfo returns the value of the entry with custom key `name`, an empty string if not set.
+func (info *PdfInfo) CustomInfo(name string) string {
+	if info.custom == nil {
+		return ""
+	}
+	s, ok := core.GetString(info.custom.Get(core.PdfObjectName(name)))
+	if !ok {
+		return ""
+	}
+	return s.Decoded()
+}
+
+// CustomKeys returns the custom keys of the entries, in the order they were set.
+func (info *PdfInfo) CustomKeys() []string {
+	if info.custom == nil {
+		return nil
+	}
+	var keys []string
+	for _, key := range info.custom.Keys() {
+		keys = append(keys, string(key))
+	}
+	return keys
+}
+
+// ToPdfObject returns the document information dictionary.
+func (info *PdfInfo) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	texts := []struct {
+		key   core.PdfObjectName
+		value string
+	}{
+		{"Title", info.Title},
+		{"Author", info.Author},
+		{"Subject", info.Subject},
+		{"Keywords", info.Keywords},
+		{"Creator", info.Creator},
+		{"Producer", info.Producer},
+	}
+	for _, t := range texts {
+		if t.value != "" {
+			dict.Set(t.key, core.MakeEncodedString(t.value, !isPDFDocEncodable(t.value)))
+		}
+	}
+
+	dates := []struct {
+		key   core.PdfObjectName
+		value time.Time
+	}{
+		{"CreationDate", info.CreationDate},
+		{"ModDate", info.ModDate},
+	}
+	for _, d := range dates {
+		if d.value.IsZero() {
+			continue
+		}
+		if date, err := NewPdfDateFromTime(d.value); err == nil {
+			dict.Set(d.key, date.ToPdfObject())
+		}
+	}
+
+	if info.Trapped != "" {
+		dict.Set("Trapped", core.MakeName(string(info.Trapped)))
+	}
+	if info.custom != nil {
+		for _, key := range info.custom.Keys() {
+			dict.Set(key, info.custom.Get(key))
+		}
+	}
+	return dict
+}
+
+// GetPdfInfo returns the document information dictionary of the trailer, nil if the document
+// has none.
+func (r *PdfReader) GetPdfInfo() (*PdfInfo, error) {
+	trailer, err := r.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	obj := trailer.Get("Info")
+	if ref, ok := obj.(*core.PdfObjectReference); ok {
+		obj, _, err = r.resolveReference(ref)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	return NewPdfInfoFromObject(obj)
+}
+
+// SetPdfInfo sets the document information dictionary of the output. Entries that are not set
+// default to the values of the package level setters (e.g. SetPdfAuthor), so that documents with
+// different metadata can be generated concurrently. As for SetPdfProducer, a custom Producer
+// requires a license.
+func (w *PdfWriter) SetPdfInfo(info *PdfInfo) {
+	merged := defaultPdfInfo()
+	texts := []struct {
+		value  string
+		merged *string
+	}{
+		{info.Title, &merged.Title},
+		{info.Author, &merged.Author},
+		{info.Subject, &merged.Subject},
+		{info.Keywords, &merged.Keywords},
+		{info.Creator, &merged.Creator},
+	}
+	for _, t := range texts {
+		if t.value != "" {
+			*t.merged = t.value
+		}
+	}
+	if info.Producer != "" {
+		merged.Producer = pdfProducerOrDefault(info.Producer)
+	}
+	if !info.CreationDate.IsZero() {
+		merged.CreationDate = info.CreationDate
+	}
+	if !info.ModDate.IsZero() {
+		merged.ModDate = info.ModDate
+	}
+	merged.Trapped = info.Trapped
+	merged.custom = core.MakeDict()
+	if info.custom != nil {
+		for _, key := range info.custom.Keys() {
+			merged.custom.Set(key, info.custom.Get(key))
+		}
+	}
+	w.infoObj.PdfObject = merged.ToPdfObject()
+}
+
+// defaultPdfInfo returns the document information set by the package level setters.
+func defaultPdfInfo() *PdfInfo {
+	info := NewPdfInfo()
+	info.Title = getPdfTitle()
+	info.Author = getPdfAuthor()
+	info.Subject = getPdfSubject()
+	info.Keywords = getPdfKeywords()
+	info.Creator = getPdfCreator()
+	info.Producer = getPdfProducer()
+	info.CreationDate = getPdfCreationDate()
+	info.ModDate = getPdfModifiedDate()
+	return info
+}
@@ -0,0 +1,93 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// writePdfInfo writes an empty document with document information `info` and reads it back.
+func writePdfInfo(t *testing.T, info *model.PdfInfo) *model.PdfInfo {
+	w := model.NewPdfWriter()
+	if info != nil {
+		w.SetPdfInfo(info)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	read, err := reader.GetPdfInfo()
+	require.NoError(t, err)
+	require.NotNil(t, read)
+	return read
+}
+
+func TestPdfInfoRoundTrip(t *testing.T) {
+	date := time.Date(2020, 3, 4, 10, 20, 30, 0, time.UTC)
+	info := model.NewPdfInfo()
+	info.Title = "Relevé de compte"
+	info.Author = "Jane Doe"
+	info.Subject = "Statement"
+	info.Keywords = "bank, statement"
+	info.Creator = "Statements 1.0"
+	info.Producer = "Statements"
+	info.CreationDate = date
+	info.ModDate = date.Add(time.Hour)
+	info.Trapped = model.TrappedFalse
+	info.SetCustomInfo("InvoiceNumber", "F-2020-001")
+	info.SetCustomInfo("Department", "Accounting")
+	info.SetCustomInfo("Removed", "value")
+	info.SetCustomInfo("Removed", "")
+
+	read := writePdfInfo(t, info)
+	require.Equal(t, info.Title, read.Title)
+	require.Equal(t, info.Author, read.Author)
+	require.Equal(t, info.Subject, read.Subject)
+	require.Equal(t, info.Keywords, read.Keywords)
+	require.Equal(t, info.Creator, read.Creator)
+	require.Equal(t, info.Producer, read.Producer)
+	require.True(t, info.CreationDate.Equal(read.CreationDate))
+	require.True(t, info.ModDate.Equal(read.ModDate))
+	require.Equal(t, model.TrappedFalse, read.Trapped)
+	require.Equal(t, []string{"InvoiceNumber", "Department"}, read.CustomKeys())
+	require.Equal(t, "F-2020-001", read.CustomInfo("InvoiceNumber"))
+	require.Empty(t, read.CustomInfo("Removed"))
+}
+
+func TestPdfInfoDefaults(t *testing.T) {
+	// Entries that are not set default to the package level settings.
+	read := writePdfInfo(t, model.NewPdfInfo())
+	require.NotEmpty(t, read.Creator)
+	require.NotEmpty(t, read.Producer)
+	require.Empty(t, read.Title)
+	require.Equal(t, writePdfInfo(t, nil), read)
+}
+
+func TestPdfInfoConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	titles := make([]string, 8)
+	for i := range titles {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			info := model.NewPdfInfo()
+			info.Title = fmt.Sprintf("Document %d", i)
+			titles[i] = writePdfInfo(t, info).Title
+		}(i)
+	}
+	wg.Wait()
+	for i, title := range titles {
+		require.Equal(t, fmt.Sprintf("Document %d", i), title)
+	}
+}
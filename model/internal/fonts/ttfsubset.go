@@ -0,0 +1,441 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fonts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// subsetTables lists the TrueType tables that are retained in a subset font program. These are the
+// tables needed for rendering glyphs embedded in a PDF file (9.9 Embedded Font Programs).
+// "cmap", "glyf", "head", "hmtx", "loca", "maxp" and "post" are rebuilt; the others are copied.
+var subsetTables = []string{
+	"OS/2", "cmap", "cvt ", "fpgm", "gasp", "glyf", "head", "hhea", "hmtx", "loca", "maxp",
+	"name", "post", "prep",
+}
+
+// Composite glyph flags (TrueType 'glyf' table).
+const (
+	glyfArg1And2AreWords   = 0x0001
+	glyfWeHaveAScale       = 0x0008
+	glyfMoreComponents     = 0x0020
+	glyfWeHaveAnXAndYScale = 0x0040
+	glyfWeHaveATwoByTwo    = 0x0080
+)
+
+// ttfChecksumMagic is used to compute the checkSumAdjustment field of the "head" table, which is
+// found at offset ttfHeadChecksumOffset.
+const (
+	ttfChecksumMagic      = 0xB1B0AFBA
+	ttfHeadChecksumOffset = 8
+)
+
+// ttfTable is a raw TrueType table.
+type ttfTable struct {
+	tag  string
+	data []byte
+}
+
+// SubsetTTF returns a subset of the TrueType font program `data` containing only the glyphs of the
+// runes in `runes` (a rune to GID map), the .notdef glyph and any glyphs referenced by composite
+// glyphs in the subset.
+// Glyph indices are preserved so that character codes which map to GIDs directly (Identity
+// CIDToGIDMap) remain valid. Unused glyphs are emptied and glyphs after the highest retained GID
+// are dropped. The "cmap" table is rebuilt to map only `runes`.
+func SubsetTTF(data []byte, runes map[rune]GID) ([]byte, error) {
+	tables, err := readTTFTables(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "loca", "maxp", "glyf"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("subset: required table %q missing", tag)
+		}
+	}
+	head := tables["head"]
+	hhea := tables["hhea"]
+	maxp := tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errors.New("subset: truncated font header tables")
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	numHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	longLoca := binary.BigEndian.Uint16(head[50:]) != 0
+
+	offsets, err := parseLoca(tables["loca"], numGlyphs, longLoca)
+	if err != nil {
+		return nil, err
+	}
+	glyf := tables["glyf"]
+	glyph := func(gid int) []byte {
+		if gid >= numGlyphs {
+			return nil
+		}
+		start, end := offsets[gid], offsets[gid+1]
+		if start >= end || int(end) > len(glyf) {
+			return nil
+		}
+		return glyf[start:end]
+	}
+
+	// Collect the retained glyphs, including the components of composite glyphs.
+	keep := map[int]bool{0: true}
+	var queue []int
+	for _, gid := range runes {
+		if int(gid) < numGlyphs && !keep[int(gid)] {
+			keep[int(gid)] = true
+			queue = append(queue, int(gid))
+		}
+	}
+	for len(queue) > 0 {
+		gid := queue[0]
+		queue = queue[1:]
+		for _, c := range compositeComponents(glyph(gid)) {
+			if c < numGlyphs && !keep[c] {
+				keep[c] = true
+				queue = append(queue, c)
+			}
+		}
+	}
+	newNumGlyphs := 0
+	for gid := range keep {
+		if gid+1 > newNumGlyphs {
+			newNumGlyphs = gid + 1
+		}
+	}
+
+	// glyf and loca (long format).
+	var newGlyf bytes.Buffer
+	newLoca := make([]byte, 4*(newNumGlyphs+1))
+	for gid := 0; gid < newNumGlyphs; gid++ {
+		binary.BigEndian.PutUint32(newLoca[4*gid:], uint32(newGlyf.Len()))
+		if !keep[gid] {
+			continue
+		}
+		newGlyf.Write(glyph(gid))
+		for newGlyf.Len()%4 != 0 {
+			newGlyf.WriteByte(0)
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[4*newNumGlyphs:], uint32(newGlyf.Len()))
+
+	// hmtx with a full metric for every glyph.
+	hmtx := tables["hmtx"]
+	newHmtx := make([]byte, 4*newNumGlyphs)
+	for gid := 0; gid < newNumGlyphs; gid++ {
+		var advance, lsb uint16
+		if gid < numHMetrics {
+			if 4*gid+4 <= len(hmtx) {
+				advance = binary.BigEndian.Uint16(hmtx[4*gid:])
+				lsb = binary.BigEndian.Uint16(hmtx[4*gid+2:])
+			}
+		} else {
+			if 4*numHMetrics <= len(hmtx) && numHMetrics > 0 {
+				advance = binary.BigEndian.Uint16(hmtx[4*(numHMetrics-1):])
+			}
+			o := 4*numHMetrics + 2*(gid-numHMetrics)
+			if o+2 <= len(hmtx) {
+				lsb = binary.BigEndian.Uint16(hmtx[o:])
+			}
+		}
+		binary.BigEndian.PutUint16(newHmtx[4*gid:], advance)
+		binary.BigEndian.PutUint16(newHmtx[4*gid+2:], lsb)
+	}
+
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint32(newHead[ttfHeadChecksumOffset:], 0)
+	binary.BigEndian.PutUint16(newHead[50:], 1)
+	newHhea := append([]byte(nil), hhea...)
+	binary.BigEndian.PutUint16(newHhea[34:], uint16(newNumGlyphs))
+	newMaxp := append([]byte(nil), maxp...)
+	binary.BigEndian.PutUint16(newMaxp[4:], uint16(newNumGlyphs))
+
+	retained := make(map[rune]GID, len(runes))
+	for r, gid := range runes {
+		if keep[int(gid)] {
+			retained[r] = gid
+		}
+	}
+
+	out := map[string][]byte{
+		"glyf": newGlyf.Bytes(),
+		"loca": newLoca,
+		"hmtx": newHmtx,
+		"head": newHead,
+		"hhea": newHhea,
+		"maxp": newMaxp,
+		"cmap": makeCmapTable(retained),
+		"post": makePostTable(tables["post"]),
+	}
+	var list []ttfTable
+	for _, tag := range subsetTables {
+		if d, ok := out[tag]; ok {
+			list = append(list, ttfTable{tag: tag, data: d})
+		} else if d, ok := tables[tag]; ok {
+			list = append(list, ttfTable{tag: tag, data: d})
+		}
+	}
+	return writeTTF(list), nil
+}
+
+// readTTFTables returns the tables of the TrueType font program `data` keyed by tag.
+func readTTFTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("subset: font data too short")
+	}
+	if string(data[:4]) == "OTTO" {
+		return nil, errors.New("fonts based on PostScript outlines are not supported")
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+16*numTables {
+		return nil, errors.New("subset: truncated table directory")
+	}
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		rec := data[12+16*i:]
+		tag := string(rec[:4])
+		offset := int(binary.BigEndian.Uint32(rec[8:]))
+		length := int(binary.BigEndian.Uint32(rec[12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("subset: table %q out of range", tag)
+		}
+		tables[tag] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// parseLoca returns the glyph offsets in the "loca" table `loca`.
+func parseLoca(loca []byte, numGlyphs int, long bool) ([]uint32, error) {
+	offsets := make([]uint32, numGlyphs+1)
+	for i := range offsets {
+		if long {
+			if 4*i+4 > len(loca) {
+				return nil, errors.New("subset: truncated loca table")
+			}
+			offsets[i] = binary.BigEndian.Uint32(loca[4*i:])
+		} else {
+			if 2*i+2 > len(loca) {
+				return nil, errors.New("subset: truncated loca table")
+			}
+			offsets[i] = 2 * uint32(binary.BigEndian.Uint16(loca[2*i:]))
+		}
+	}
+	return offsets, nil
+}
+
+// compositeComponents returns the GIDs of the components of glyph `g` if it is a composite glyph.
+func compositeComponents(g []byte) []int {
+	if len(g) < 10 || int16(binary.BigEndian.Uint16(g)) >= 0 {
+		return nil
+	}
+	var gids []int
+	pos := 10
+	for pos+4 <= len(g) {
+		flags := binary.BigEndian.Uint16(g[pos:])
+		gids = append(gids, int(binary.BigEndian.Uint16(g[pos+2:])))
+		pos += 4
+		if flags&glyfArg1And2AreWords != 0 {
+			pos += 4
+		} else {
+			pos += 2
+		}
+		switch {
+		case flags&glyfWeHaveAScale != 0:
+			pos += 2
+		case flags&glyfWeHaveAnXAndYScale != 0:
+			pos += 4
+		case flags&glyfWeHaveATwoByTwo != 0:
+			pos += 8
+		}
+		if flags&glyfMoreComponents == 0 {
+			break
+		}
+	}
+	return gids
+}
+
+// cmapSegment is a range of consecutive runes mapped to consecutive GIDs.
+type cmapSegment struct {
+	start, end rune
+	gid        GID
+}
+
+// maxCmapFormat4Segments is the largest number of segments that fits in a format 4 cmap subtable.
+const maxCmapFormat4Segments = (0xffff - 16) / 8
+
+// makeCmapTable returns a "cmap" table with Windows Unicode subtables mapping `runes`. A format 4
+// subtable covers the Basic Multilingual Plane and a format 12 subtable is added when there are
+// runes beyond it or too many ranges to fit in the format 4 subtable.
+func makeCmapTable(runes map[rune]GID) []byte {
+	sorted := make([]rune, 0, len(runes))
+	for r := range runes {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var segs []cmapSegment
+	for _, r := range sorted {
+		gid := runes[r]
+		if n := len(segs); n > 0 && segs[n-1].end+1 == r &&
+			int(segs[n-1].gid)+int(r-segs[n-1].start) == int(gid) {
+			segs[n-1].end = r
+			continue
+		}
+		segs = append(segs, cmapSegment{start: r, end: r, gid: gid})
+	}
+
+	var bmp []cmapSegment
+	needFormat12 := false
+	for _, seg := range segs {
+		if seg.end > 0xfffe {
+			needFormat12 = true
+			continue
+		}
+		bmp = append(bmp, seg)
+	}
+	if len(bmp) > maxCmapFormat4Segments-1 {
+		bmp = bmp[:maxCmapFormat4Segments-1]
+		needFormat12 = true
+	}
+
+	write16 := func(b *bytes.Buffer, v uint16) { binary.Write(b, binary.BigEndian, v) }
+	write32 := func(b *bytes.Buffer, v uint32) { binary.Write(b, binary.BigEndian, v) }
+
+	// Format 4 with the required final 0xFFFF segment.
+	segCount := len(bmp) + 1
+	searchRange, entrySelector := 2, 0
+	for searchRange*2 <= 2*segCount {
+		searchRange *= 2
+		entrySelector++
+	}
+	var f4 bytes.Buffer
+	write16(&f4, 4)
+	write16(&f4, uint16(16+8*segCount))
+	write16(&f4, 0) // language
+	write16(&f4, uint16(2*segCount))
+	write16(&f4, uint16(searchRange))
+	write16(&f4, uint16(entrySelector))
+	write16(&f4, uint16(2*segCount-searchRange))
+	for _, seg := range bmp {
+		write16(&f4, uint16(seg.end))
+	}
+	write16(&f4, 0xffff)
+	write16(&f4, 0) // reservedPad
+	for _, seg := range bmp {
+		write16(&f4, uint16(seg.start))
+	}
+	write16(&f4, 0xffff)
+	for _, seg := range bmp {
+		write16(&f4, uint16(seg.gid)-uint16(seg.start)) // idDelta
+	}
+	write16(&f4, 1)
+	for i := 0; i < segCount; i++ {
+		write16(&f4, 0) // idRangeOffset
+	}
+
+	var f12 bytes.Buffer
+	if needFormat12 {
+		write16(&f12, 12)
+		write16(&f12, 0)
+		write32(&f12, uint32(16+12*len(segs)))
+		write32(&f12, 0) // language
+		write32(&f12, uint32(len(segs)))
+		for _, seg := range segs {
+			write32(&f12, uint32(seg.start))
+			write32(&f12, uint32(seg.end))
+			write32(&f12, uint32(seg.gid))
+		}
+	}
+
+	numSubtables := 1
+	if needFormat12 {
+		numSubtables = 2
+	}
+	var b bytes.Buffer
+	write16(&b, 0)
+	write16(&b, uint16(numSubtables))
+	offset := uint32(4 + 8*numSubtables)
+	write16(&b, 3)
+	write16(&b, 1)
+	write32(&b, offset)
+	if needFormat12 {
+		write16(&b, 3)
+		write16(&b, 10)
+		write32(&b, offset+uint32(f4.Len()))
+	}
+	b.Write(f4.Bytes())
+	b.Write(f12.Bytes())
+	return b.Bytes()
+}
+
+// makePostTable returns a version 3 "post" table (no glyph names) with the header fields of `post`.
+func makePostTable(post []byte) []byte {
+	p := make([]byte, 32)
+	if len(post) >= 32 {
+		copy(p, post[:32])
+	}
+	binary.BigEndian.PutUint32(p, 0x00030000)
+	return p
+}
+
+// ttfChecksum returns the TrueType checksum of `data`.
+func ttfChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var v [4]byte
+		copy(v[:], data[i:])
+		sum += binary.BigEndian.Uint32(v[:])
+	}
+	return sum
+}
+
+// writeTTF serializes `tables` as a TrueType font program. `tables` must be sorted by tag.
+func writeTTF(tables []ttfTable) []byte {
+	numTables := len(tables)
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= numTables {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 16
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, uint32(0x00010000))
+	binary.Write(&b, binary.BigEndian, uint16(numTables))
+	binary.Write(&b, binary.BigEndian, uint16(searchRange))
+	binary.Write(&b, binary.BigEndian, uint16(entrySelector))
+	binary.Write(&b, binary.BigEndian, uint16(16*numTables-searchRange))
+
+	offset := 12 + 16*numTables
+	headOffset := -1
+	for _, t := range tables {
+		if t.tag == "head" {
+			headOffset = offset
+		}
+		b.WriteString(t.tag)
+		binary.Write(&b, binary.BigEndian, ttfChecksum(t.data))
+		binary.Write(&b, binary.BigEndian, uint32(offset))
+		binary.Write(&b, binary.BigEndian, uint32(len(t.data)))
+		offset += (len(t.data) + 3) &^ 3
+	}
+	for _, t := range tables {
+		b.Write(t.data)
+		for b.Len()%4 != 0 {
+			b.WriteByte(0)
+		}
+	}
+
+	data := b.Bytes()
+	if headOffset >= 0 {
+		adjust := ttfChecksumMagic - ttfChecksum(data)
+		binary.BigEndian.PutUint32(data[headOffset+ttfHeadChecksumOffset:], adjust)
+	}
+	return data
+}
@@ -0,0 +1,47 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fonts
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubsetTTF(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join(fontDir, "FreeSans.ttf"))
+	require.NoError(t, err)
+
+	ttf, err := TtfParse(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	runes := make(map[rune]GID)
+	for _, r := range "Hello ёx" {
+		gid, ok := ttf.Chars[r]
+		require.True(t, ok)
+		runes[r] = gid
+	}
+
+	subset, err := SubsetTTF(data, runes)
+	require.NoError(t, err)
+	require.True(t, len(subset) < len(data)/4, "subset %d bytes, original %d bytes", len(subset), len(data))
+
+	sttf, err := TtfParse(bytes.NewReader(subset))
+	require.NoError(t, err)
+	require.Equal(t, ttf.UnitsPerEm, sttf.UnitsPerEm)
+	require.Equal(t, ttf.PostScriptName, sttf.PostScriptName)
+	require.Len(t, sttf.Chars, len(runes))
+	for r, gid := range runes {
+		require.Equal(t, gid, sttf.Chars[r], "rune %q", r)
+		require.Equal(t, ttf.Widths[gid], sttf.Widths[gid], "rune %q", r)
+	}
+
+	// The checksum of the whole font must be the magic number.
+	require.Equal(t, uint32(ttfChecksumMagic), ttfChecksum(subset))
+}
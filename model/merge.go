@@ -0,0 +1,809 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// MergeInput is a document merged by Merge.
+type MergeInput struct {
+	Reader *PdfReader
+
+	// Pages are the numbers of the pages to merge, in order, starting from 1. All the pages are
+	// merged if empty.
+	Pages []int
+
+	// Title is the title of the outline item the outline of the document is placed under.
+	// Defaults to the Title of the document information dictionary, or "Document N" for the Nth
+	// input.
+	Title string
+}
+
+// PageRange returns the page numbers from `first` to `last` included, e.g. for MergeInput.Pages.
+func PageRange(first, last int) []int {
+	var pages []int
+	for i := first; i <= last; i++ {
+		pages = append(pages, i)
+	}
+	return pages
+}
+
+// Merge returns a writer with the pages of `inputs`, in order. Unlike adding the pages of
+// readers to a writer, Merge also carries over:
+//   - the outline of each document, placed under an outline item to the first page of the document,
+//   - the named destinations, renamed with a suffix when a previous document uses the same name,
+//   - the form fields, renaming top level fields whose name is used by a previous document,
+//   - the destinations of links and outline items, including destinations by page index, updated
+//     to the merged pages. Destinations to pages that are not merged are removed.
+//
+// Fonts and images that are identical in several documents are written once. The structure
+// trees, page labels and open actions of the documents are not merged. The readers are not
+// modified, except for their forms being written back to their dictionaries, and the encrypted
+// ones must be decrypted first.
+func Merge(inputs []MergeInput) (*PdfWriter, error) {
+	m := newMerger()
+	for i, input := range inputs {
+		if err := m.merge(input, i+1); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.finish(); err != nil {
+		return nil, err
+	}
+	return m.w, nil
+}
+
+// merger merges documents into a writer.
+type merger struct {
+	w *PdfWriter
+
+	// Set when splitting a document: the resources are pruned and the outline is not placed
+	// under an outline item.
+	split bool
+
+	// Copies of the fonts and images of the documents, keyed by content.
+	shared map[string]core.PdfObject
+
+	// Names of the destinations and top level fields merged.
+	destNames  map[string]struct{}
+	fieldNames map[string]struct{}
+
+	outline     *PdfOutline
+	outlineLast *PdfOutlineItem
+
+	// Interactive form.
+	fields          *core.PdfObjectArray
+	co              *core.PdfObjectArray
+	drFonts         *core.PdfObjectDictionary
+	da              core.PdfObject
+	needAppearances bool
+}
+
+// newMerger returns a merger to a new writer.
+func newMerger() *merger {
+	w := NewPdfWriter()
+	return &merger{
+		w:          &w,
+		shared:     map[string]core.PdfObject{},
+		destNames:  map[string]struct{}{},
+		fieldNames: map[string]struct{}{},
+		outline:    NewPdfOutline(),
+		fields:     core.MakeArray(),
+		co:         core.MakeArray(),
+		drFonts:    core.MakeDict(),
+	}
+}
+
+// mergeCopier copies the objects of a document merged by a merger.
+type mergeCopier struct {
+	m *merger
+	r *PdfReader
+
+	// Copies of the pages merged, by page dictionary of the document.
+	pages map[*core.PdfObjectDictionary]*core.PdfIndirectObject
+	// Copies of the objects, by object of the document.
+	copies map[core.PdfObject]core.PdfObject
+	// Widget annotations of the pages merged.
+	widgets map[*core.PdfObjectDictionary]struct{}
+	// Names of the named destinations in the output, by name in the document.
+	destNames map[string]string
+	// Named destinations of the document to pages not merged.
+	lostDests map[string]struct{}
+	// Copies of the link annotations whose destinations have been updated.
+	fixed map[*core.PdfObjectDictionary]struct{}
+}
+
+// merge adds the pages of `input`, the `num`th input, with their outline, destinations and
+// fields.
+func (m *merger) merge(input MergeInput, num int) error {
+	r := input.Reader
+	if r == nil {
+		return fmt.Errorf("merge input %d has no reader", num)
+	}
+	numPages, err := r.GetNumPages()
+	if err != nil {
+		return err
+	}
+	pageNums := input.Pages
+	if len(pageNums) == 0 {
+		pageNums = PageRange(1, numPages)
+	}
+
+	c := &mergeCopier{
+		m:         m,
+		r:         r,
+		pages:     map[*core.PdfObjectDictionary]*core.PdfIndirectObject{},
+		copies:    map[core.PdfObject]core.PdfObject{},
+		widgets:   map[*core.PdfObjectDictionary]struct{}{},
+		destNames: map[string]string{},
+		lostDests: map[string]struct{}{},
+		fixed:     map[*core.PdfObjectDictionary]struct{}{},
+	}
+
+	// The containers of the pages are created first for the objects targeting them.
+	var srcPages []*core.PdfObjectDictionary
+	var dstPages []*core.PdfIndirectObject
+	for _, n := range pageNums {
+		if n < 1 || n > numPages {
+			common.Log.Debug("ERROR: Page %d of merge input %d out of range (%d pages)", n, num, numPages)
+			return fmt.Errorf("page %d of merge input %d out of range", n, num)
+		}
+		src, ok := core.GetDict(r.pageList[n-1])
+		if !ok {
+			return errors.New("invalid page dictionary")
+		}
+		dst := core.MakeIndirectObject(core.MakeDict())
+		if _, has := c.pages[src]; !has {
+			c.pages[src] = dst
+		}
+		srcPages = append(srcPages, src)
+		dstPages = append(dstPages, dst)
+	}
+
+	// The reader loads the form and field dictionaries into its form model, they are written
+	// back before the widgets referring to them are copied.
+	if r.AcroForm != nil {
+		r.AcroForm.ToPdfObject()
+	}
+
+	// Named destinations are renamed before the links targeting them are copied.
+	dests, order := c.namedDestinations()
+	for _, name := range order {
+		if !c.targetsMergedPage(dests[name]) {
+			c.lostDests[name] = struct{}{}
+			continue
+		}
+		c.destNames[name] = uniqueName(m.destNames, name)
+	}
+
+	for i, src := range srcPages {
+		page, err := c.copyPage(src, dstPages[i])
+		if err != nil {
+			return err
+		}
+		if err := m.w.AddPage(page); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range order {
+		if _, lost := c.lostDests[name]; lost {
+			continue
+		}
+		if dest := c.fixDest(c.copy(dests[name])); dest != nil {
+			m.w.names.tree(m.w.catalog, "Dests").Set(c.destNames[name], dest)
+		}
+	}
+
+	if len(dstPages) > 0 {
+		m.mergeOutline(c, input.Title, num, dstPages[0])
+	}
+	m.mergeForm(c)
+	return nil
+}
+
+// pageView returns a dictionary with the entries of page dictionary `src`, with the attributes
+// inherited from the page tree and without the page tree and article threads entries. The
+// unused resources are pruned if `prune` is set.
+func pageView(src *core.PdfObjectDictionary, prune bool) *core.PdfObjectDictionary {
+	view := core.MakeDict()
+	for _, key := range src.Keys() {
+		switch key {
+		case "Parent", "B":
+			// The page tree and the article threads are not merged.
+			continue
+		}
+		view.Set(key, src.Get(key))
+	}
+	visited := map[*core.PdfObjectDictionary]struct{}{src: {}}
+	parent, ok := core.GetDict(src.Get("Parent"))
+	for ok {
+		if _, has := visited[parent]; has {
+			break
+		}
+		visited[parent] = struct{}{}
+		for _, key := range []core.PdfObjectName{"Resources", "MediaBox", "CropBox", "Rotate"} {
+			if view.Get(key) == nil && parent.Get(key) != nil {
+				view.Set(key, parent.Get(key))
+			}
+		}
+		parent, ok = core.GetDict(parent.Get("Parent"))
+	}
+	if prune {
+		view.SetIfNotNil("Resources", prunedPageResources(view))
+	}
+	return view
+}
+
+// copyPage copies page dictionary `src` into container `dst`, including the attributes inherited
+// from the page tree, and returns the page model of the copy.
+func (c *mergeCopier) copyPage(src *core.PdfObjectDictionary, dst *core.PdfIndirectObject) (*PdfPage, error) {
+	view := pageView(src, c.m.split)
+	d := dst.PdfObject.(*core.PdfObjectDictionary)
+	for _, key := range view.Keys() {
+		d.Set(key, c.copy(view.Get(key)))
+	}
+
+	if annots, ok := core.GetArray(src.Get("Annots")); ok {
+		for _, obj := range annots.Elements() {
+			if annot, ok := core.GetDict(obj); ok {
+				if subtype, _ := core.GetNameVal(annot.Get("Subtype")); subtype == "Widget" {
+					c.widgets[annot] = struct{}{}
+				}
+			}
+		}
+	}
+	if annots, ok := core.GetArray(d.Get("Annots")); ok {
+		for _, obj := range annots.Elements() {
+			if annot, ok := core.GetDict(obj); ok {
+				c.fixLink(annot)
+			}
+		}
+	}
+
+	page, err := c.r.newPdfPageFromDict(d)
+	if err != nil {
+		return nil, err
+	}
+	page.setContainer(dst)
+	return page, nil
+}
+
+// copy returns the copy of `obj`. Pages are replaced by their copies, or null if not merged,
+// and fonts and images identical to those of a previous document by their copies.
+func (c *mergeCopier) copy(obj core.PdfObject) core.PdfObject {
+	obj = core.ResolveReference(obj)
+	if copied, has := c.copies[obj]; has {
+		return copied
+	}
+
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		if d, ok := t.PdfObject.(*core.PdfObjectDictionary); ok {
+			if page, has := c.pages[d]; has {
+				return page
+			}
+			if name, _ := core.GetNameVal(d.Get("Type")); name == "Page" || name == "Pages" {
+				return core.MakeNull()
+			}
+		}
+		key := sharedKey(t)
+		if shared, has := c.m.shared[key]; has && key != "" {
+			c.copies[obj] = shared
+			return shared
+		}
+		copied := &core.PdfIndirectObject{}
+		c.copies[obj] = copied
+		copied.PdfObject = c.copy(t.PdfObject)
+		if key != "" {
+			c.m.shared[key] = copied
+		}
+		return copied
+	case *core.PdfObjectStream:
+		key := sharedKey(t)
+		if shared, has := c.m.shared[key]; has && key != "" {
+			c.copies[obj] = shared
+			return shared
+		}
+		copied := &core.PdfObjectStream{Stream: t.Stream}
+		c.copies[obj] = copied
+		d := t.PdfObjectDictionary
+		if c.m.split {
+			d = prunedFormDict(t)
+		}
+		copied.PdfObjectDictionary = c.copy(d).(*core.PdfObjectDictionary)
+		if key != "" {
+			c.m.shared[key] = copied
+		}
+		return copied
+	case *core.PdfObjectDictionary:
+		copied := core.MakeDict()
+		c.copies[obj] = copied
+		for _, key := range t.Keys() {
+			// The structure trees are not merged.
+			if key == "StructParent" || key == "StructParents" {
+				continue
+			}
+			copied.Set(key, c.copy(t.Get(key)))
+		}
+		return copied
+	case *core.PdfObjectArray:
+		copied := core.MakeArray()
+		c.copies[obj] = copied
+		for _, elem := range t.Elements() {
+			copied.Append(c.copy(elem))
+		}
+		return copied
+	}
+	return obj
+}
+
+// sharedKey returns a key identifying the content of font dictionary or image `obj`, an empty
+// string for other objects.
+func sharedKey(obj core.PdfObject) string {
+	kind := ""
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		if d, ok := t.PdfObject.(*core.PdfObjectDictionary); ok {
+			if name, _ := core.GetNameVal(d.Get("Type")); name == "Font" {
+				kind = "font"
+			}
+		}
+	case *core.PdfObjectStream:
+		if name, _ := core.GetNameVal(t.Get("Subtype")); name == "Image" {
+			kind = "image"
+		}
+	}
+	if kind == "" {
+		return ""
+	}
+	h := sha256.New()
+	writeCanonical(h, obj, map[core.PdfObject]struct{}{})
+	return kind + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// writeCanonical writes a representation of `obj` to `h` that does not depend on the object
+// numbers. The objects being written are tracked in `visiting` to break cycles.
+func writeCanonical(h hash.Hash, obj core.PdfObject, visiting map[core.PdfObject]struct{}) {
+	obj = core.ResolveReference(obj)
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		if _, has := visiting[t]; has {
+			h.Write([]byte("R"))
+			return
+		}
+		visiting[t] = struct{}{}
+		writeCanonical(h, t.PdfObject, visiting)
+		delete(visiting, t)
+	case *core.PdfObjectStream:
+		if _, has := visiting[t]; has {
+			h.Write([]byte("R"))
+			return
+		}
+		visiting[t] = struct{}{}
+		writeCanonical(h, t.PdfObjectDictionary, visiting)
+		h.Write([]byte("stream" + strconv.Itoa(len(t.Stream))))
+		h.Write(t.Stream)
+		delete(visiting, t)
+	case *core.PdfObjectDictionary:
+		h.Write([]byte("<<"))
+		for _, key := range t.Keys() {
+			if key == "Parent" {
+				continue
+			}
+			h.Write([]byte(key.WriteString()))
+			writeCanonical(h, t.Get(key), visiting)
+		}
+		h.Write([]byte(">>"))
+	case *core.PdfObjectArray:
+		h.Write([]byte("["))
+		for _, elem := range t.Elements() {
+			writeCanonical(h, elem, visiting)
+		}
+		h.Write([]byte("]"))
+	case nil:
+		h.Write([]byte("null"))
+	default:
+		h.Write([]byte(" " + obj.WriteString()))
+	}
+}
+
+// namedDestinations returns the named destinations of the document, from the Dests dictionary
+// of the catalog and the Dests name tree, and their names in order.
+func (c *mergeCopier) namedDestinations() (map[string]core.PdfObject, []string) {
+	dests := map[string]core.PdfObject{}
+	var order []string
+	add := func(name string, obj core.PdfObject) error {
+		if _, has := dests[name]; !has {
+			order = append(order, name)
+		}
+		dests[name] = obj
+		return nil
+	}
+	if d, ok := core.GetDict(c.r.catalog.Get("Dests")); ok {
+		for _, key := range d.Keys() {
+			add(string(key), d.Get(key))
+		}
+	}
+	tree, err := c.r.GetNameTree("Dests")
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid Dests name tree: %v", err)
+	} else if tree != nil {
+		tree.Walk(add)
+	}
+	return dests, order
+}
+
+// fixLink updates the destination of the copy of link annotation `annot`.
+func (c *mergeCopier) fixLink(annot *core.PdfObjectDictionary) {
+	if subtype, _ := core.GetNameVal(annot.Get("Subtype")); subtype != "Link" {
+		return
+	}
+	if _, has := c.fixed[annot]; has {
+		return
+	}
+	c.fixed[annot] = struct{}{}
+
+	if dest := annot.Get("Dest"); dest != nil {
+		if fixed := c.fixDest(dest); fixed != nil {
+			annot.Set("Dest", fixed)
+		} else {
+			annot.Remove("Dest")
+		}
+	}
+	if action := annot.Get("A"); action != nil {
+		c.fixAction(action, map[*core.PdfObjectDictionary]struct{}{})
+	}
+}
+
+// fixAction updates the destinations of the go-to actions of the copy of action `obj` and of
+// its Next chain. Returns true if `obj` is a go-to action whose destination has been removed.
+func (c *mergeCopier) fixAction(obj core.PdfObject, visited map[*core.PdfObjectDictionary]struct{}) bool {
+	d, ok := core.GetDict(obj)
+	if !ok {
+		return false
+	}
+	if _, has := visited[d]; has {
+		return false
+	}
+	visited[d] = struct{}{}
+
+	removed := false
+	if s, _ := core.GetNameVal(d.Get("S")); s == string(ActionTypeGoTo) {
+		if fixed := c.fixDest(d.Get("D")); fixed != nil {
+			d.Set("D", fixed)
+		} else {
+			// The go-to action does nothing without destination.
+			d.Set("S", core.MakeName(string(ActionTypeNamed)))
+			d.Set("N", core.MakeName("NoOp"))
+			d.Remove("D")
+			removed = true
+		}
+	}
+	switch next := core.TraceToDirectObject(d.Get("Next")).(type) {
+	case *core.PdfObjectDictionary:
+		c.fixAction(next, visited)
+	case *core.PdfObjectArray:
+		for _, elem := range next.Elements() {
+			c.fixAction(elem, visited)
+		}
+	}
+	return removed
+}
+
+// targetsMergedPage returns true unless destination `obj` of the document targets a page that is
+// not merged.
+func (c *mergeCopier) targetsMergedPage(obj core.PdfObject) bool {
+	if d, ok := core.GetDict(obj); ok {
+		obj = d.Get("D")
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok || arr.Len() == 0 {
+		return false
+	}
+	switch page := core.TraceToDirectObject(arr.Get(0)).(type) {
+	case *core.PdfObjectDictionary:
+		_, has := c.pages[page]
+		return has
+	case *core.PdfObjectInteger:
+		idx := int(*page)
+		if idx < 0 || idx >= len(c.r.pageList) {
+			return false
+		}
+		src, _ := core.GetDict(c.r.pageList[idx])
+		_, has := c.pages[src]
+		return has
+	}
+	return false
+}
+
+// fixDest returns the copy of destination `obj` updated to the output: the names of named
+// destinations are updated and pages indices are replaced by the pages. Returns nil if the
+// page of the destination is not merged.
+func (c *mergeCopier) fixDest(obj core.PdfObject) core.PdfObject {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectString:
+		if _, lost := c.lostDests[t.Str()]; lost {
+			return nil
+		}
+		if name, has := c.destNames[t.Str()]; has {
+			return core.MakeString(name)
+		}
+		return t
+	case *core.PdfObjectName:
+		if _, lost := c.lostDests[string(*t)]; lost {
+			return nil
+		}
+		if name, has := c.destNames[string(*t)]; has {
+			return core.MakeName(name)
+		}
+		return t
+	case *core.PdfObjectDictionary:
+		dest := c.fixDest(t.Get("D"))
+		if dest == nil {
+			return nil
+		}
+		t.Set("D", dest)
+		return obj
+	case *core.PdfObjectArray:
+		if t.Len() == 0 {
+			return nil
+		}
+		switch page := t.Get(0).(type) {
+		case *core.PdfObjectNull:
+			return nil
+		case *core.PdfObjectInteger:
+			// Page index, e.g. in the internal links of the creator.
+			idx := int(*page)
+			if idx < 0 || idx >= len(c.r.pageList) {
+				return nil
+			}
+			src, _ := core.GetDict(c.r.pageList[idx])
+			dst, has := c.pages[src]
+			if !has {
+				return nil
+			}
+			t.Set(0, dst)
+		}
+		return t
+	}
+	return nil
+}
+
+// mergeOutline adds the outline of the document under an outline item titled `title` targeting
+// `firstPage`, the first merged page of the `num`th input.
+func (m *merger) mergeOutline(c *mergeCopier, title string, num int, firstPage *core.PdfIndirectObject) {
+	if title == "" {
+		if info, err := c.r.GetPdfInfo(); err == nil && info != nil {
+			title = info.Title
+		}
+	}
+	if title == "" {
+		title = "Document " + strconv.Itoa(num)
+	}
+
+	var children []*PdfOutlineItem
+	if outlines, ok := core.GetDict(c.r.catalog.Get("Outlines")); ok {
+		children = c.outlineItems(outlines.Get("First"), map[*core.PdfObjectDictionary]struct{}{})
+	}
+	if m.split {
+		for _, child := range children {
+			m.addOutlineItem(child)
+		}
+		return
+	}
+
+	item := NewPdfOutlineItem()
+	item.Title = core.MakeEncodedString(title, !isPDFDocEncodable(title))
+	item.Dest = core.MakeArray(firstPage, core.MakeName("Fit"))
+	linkOutlineItems(&item.PdfOutlineTreeNode, item, children, false)
+	m.addOutlineItem(item)
+}
+
+// addOutlineItem adds `item` to the top level of the outline.
+func (m *merger) addOutlineItem(item *PdfOutlineItem) {
+	item.Parent = &m.outline.PdfOutlineTreeNode
+	if m.outlineLast == nil {
+		m.outline.First = &item.PdfOutlineTreeNode
+	} else {
+		m.outlineLast.Next = &item.PdfOutlineTreeNode
+		item.Prev = &m.outlineLast.PdfOutlineTreeNode
+	}
+	m.outline.Last = &item.PdfOutlineTreeNode
+	m.outlineLast = item
+}
+
+// outlineItems returns copies of the outline item `first` and its siblings, with their children.
+func (c *mergeCopier) outlineItems(first core.PdfObject, visited map[*core.PdfObjectDictionary]struct{}) []*PdfOutlineItem {
+	var items []*PdfOutlineItem
+	d, ok := core.GetDict(first)
+	for ok {
+		if _, has := visited[d]; has {
+			common.Log.Debug("ERROR: Circular outline")
+			break
+		}
+		visited[d] = struct{}{}
+
+		item := NewPdfOutlineItem()
+		item.Title = core.MakeString("")
+		if title, ok := core.GetString(d.Get("Title")); ok {
+			item.Title = title
+		}
+		lost := false
+		if dest := d.Get("Dest"); dest != nil {
+			item.Dest = c.fixDest(c.copy(dest))
+			lost = item.Dest == nil
+		}
+		if action := d.Get("A"); action != nil {
+			item.A = c.copy(action)
+			lost = c.fixAction(item.A, map[*core.PdfObjectDictionary]struct{}{}) || lost
+		}
+		if color := d.Get("C"); color != nil {
+			item.C = c.copy(color)
+		}
+		if flags := d.Get("F"); flags != nil {
+			item.F = c.copy(flags)
+		}
+		children := c.outlineItems(d.Get("First"), visited)
+		count, _ := core.GetIntVal(d.Get("Count"))
+		linkOutlineItems(&item.PdfOutlineTreeNode, item, children, count < 0)
+
+		// The parts of a split document only keep the items to their pages.
+		if !c.m.split || !lost || len(children) > 0 {
+			items = append(items, item)
+		}
+		d, ok = core.GetDict(d.Get("Next"))
+	}
+	return items
+}
+
+// linkOutlineItems sets `children` as the children of outline item `item` with tree node
+// `node`, and the count of its descendants, negative if the item is `closed`.
+func linkOutlineItems(node *PdfOutlineTreeNode, item *PdfOutlineItem, children []*PdfOutlineItem, closed bool) {
+	if len(children) == 0 {
+		return
+	}
+	var descendants int64
+	for i, child := range children {
+		child.Parent = node
+		if i > 0 {
+			child.Prev = &children[i-1].PdfOutlineTreeNode
+			children[i-1].Next = &child.PdfOutlineTreeNode
+		}
+		descendants++
+		if child.Count != nil && *child.Count > 0 {
+			descendants += *child.Count
+		}
+	}
+	node.First = &children[0].PdfOutlineTreeNode
+	node.Last = &children[len(children)-1].PdfOutlineTreeNode
+	if closed {
+		descendants = -descendants
+	}
+	item.Count = &descendants
+}
+
+// mergeForm adds the fields of the document that have widgets on the pages merged. Top level
+// fields are renamed when a previous document has a field with the same name.
+func (m *merger) mergeForm(c *mergeCopier) {
+	form, ok := core.GetDict(c.r.catalog.Get("AcroForm"))
+	if !ok {
+		return
+	}
+	fields, _ := core.GetArray(form.Get("Fields"))
+	merged := false
+	for _, obj := range fields.Elements() {
+		field, ok := core.GetDict(obj)
+		if !ok || !c.hasWidget(field, map[*core.PdfObjectDictionary]struct{}{}) {
+			continue
+		}
+		copied, _ := core.GetDict(c.copy(obj))
+		if t, ok := core.GetString(field.Get("T")); ok {
+			name := uniqueName(m.fieldNames, t.Decoded())
+			if name != t.Decoded() {
+				common.Log.Debug("Renaming field %q to %q", t.Decoded(), name)
+				copied.Set("T", core.MakeEncodedString(name, !isPDFDocEncodable(name)))
+			}
+		}
+		m.fields.Append(c.copy(obj))
+		merged = true
+	}
+	if !merged {
+		return
+	}
+
+	// Calculation order of the fields merged.
+	if co, ok := core.GetArray(form.Get("CO")); ok {
+		for _, obj := range co.Elements() {
+			if copied, has := c.copies[core.ResolveReference(obj)]; has {
+				m.co.Append(copied)
+			}
+		}
+	}
+	if dr, ok := core.GetDict(form.Get("DR")); ok {
+		if fonts, ok := core.GetDict(dr.Get("Font")); ok {
+			for _, key := range fonts.Keys() {
+				if m.drFonts.Get(key) == nil {
+					m.drFonts.Set(key, c.copy(fonts.Get(key)))
+				}
+			}
+		}
+	}
+	if m.da == nil && form.Get("DA") != nil {
+		m.da = c.copy(form.Get("DA"))
+	}
+	if need, _ := core.GetBoolVal(form.Get("NeedAppearances")); need {
+		m.needAppearances = true
+	}
+}
+
+// hasWidget returns true if field `field` or one of its descendants has a widget on the pages
+// merged.
+func (c *mergeCopier) hasWidget(field *core.PdfObjectDictionary, visited map[*core.PdfObjectDictionary]struct{}) bool {
+	if _, has := visited[field]; has {
+		return false
+	}
+	visited[field] = struct{}{}
+	if _, has := c.widgets[field]; has {
+		return true
+	}
+	kids, _ := core.GetArray(field.Get("Kids"))
+	for _, obj := range kids.Elements() {
+		if kid, ok := core.GetDict(obj); ok && c.hasWidget(kid, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// finish sets the outline and the interactive form of the output.
+func (m *merger) finish() error {
+	if m.outline.First != nil {
+		m.w.AddOutlineTree(&m.outline.PdfOutlineTreeNode)
+	}
+	if m.fields.Len() == 0 {
+		return nil
+	}
+	form := core.MakeDict()
+	form.Set("Fields", m.fields)
+	if m.co.Len() > 0 {
+		form.Set("CO", m.co)
+	}
+	if len(m.drFonts.Keys()) > 0 {
+		dr := core.MakeDict()
+		dr.Set("Font", m.drFonts)
+		form.Set("DR", dr)
+	}
+	form.SetIfNotNil("DA", m.da)
+	if m.needAppearances {
+		form.Set("NeedAppearances", core.MakeBool(true))
+	}
+	obj := core.MakeIndirectObject(form)
+	m.w.catalog.Set("AcroForm", obj)
+	return m.w.addObjects(obj)
+}
+
+// uniqueName returns `name`, with a suffix if already in `used`, and adds it to `used`.
+func uniqueName(used map[string]struct{}, name string) string {
+	unique := name
+	for i := 2; ; i++ {
+		if _, has := used[unique]; !has {
+			break
+		}
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	used[unique] = struct{}{}
+	return unique
+}
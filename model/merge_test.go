@@ -0,0 +1,193 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// makeMergeDocument returns a reader of a 2 page document with an outline, named destination
+// "intro" to page 2, a link to it on page 1, a link to page 2 by index and a text field `field`.
+func makeMergeDocument(t *testing.T, title, field string) *model.PdfReader {
+	w := model.NewPdfWriter()
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+
+	var pages []*model.PdfPage
+	for i := 0; i < 2; i++ {
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+		require.NoError(t, page.AddFont("F1", font.ToPdfObject()))
+		require.NoError(t, page.SetContentStreams([]string{"BT /F1 12 Tf 10 10 Td (" + title + ") Tj ET"}, core.NewRawEncoder()))
+		pages = append(pages, page)
+	}
+
+	dest, err := model.NewPdfDestination(pages[1].GetPageAsIndirectObject(), model.DestinationFit)
+	require.NoError(t, err)
+	w.AddNamedDestination("intro", dest)
+
+	named := model.NewPdfAnnotationLink()
+	named.Rect = core.MakeArrayFromFloats([]float64{0, 0, 100, 20})
+	named.Dest = core.MakeString("intro")
+	pages[0].AddAnnotation(named.PdfAnnotation)
+	index := model.NewPdfAnnotationLink()
+	index.Rect = core.MakeArrayFromFloats([]float64{0, 20, 100, 40})
+	index.Dest = core.MakeArray(core.MakeInteger(1), core.MakeName("Fit"))
+	pages[0].AddAnnotation(index.PdfAnnotation)
+
+	pdfField := model.NewPdfField()
+	pdfField.T = core.MakeString(field)
+	pdfField.FT = core.MakeName("Tx")
+	widget := model.NewPdfAnnotationWidget()
+	widget.Rect = core.MakeArrayFromFloats([]float64{0, 50, 100, 70})
+	widget.Parent = pdfField.GetContainingPdfObject()
+	pdfField.Annotations = append(pdfField.Annotations, widget)
+	pages[0].AddAnnotation(widget.PdfAnnotation)
+	form := model.NewPdfAcroForm()
+	*form.Fields = append(*form.Fields, pdfField)
+
+	for _, page := range pages {
+		require.NoError(t, w.AddPage(page))
+	}
+	require.NoError(t, w.SetForms(form))
+
+	outline := model.NewPdfOutline()
+	var prev *model.PdfOutlineItem
+	for i, page := range pages {
+		item := model.NewPdfOutlineItem()
+		item.Title = core.MakeString("Section " + string(rune('A'+i)))
+		item.Dest = core.MakeArray(page.GetPageAsIndirectObject(), core.MakeName("Fit"))
+		item.Parent = &outline.PdfOutlineTreeNode
+		if prev == nil {
+			outline.First = &item.PdfOutlineTreeNode
+		} else {
+			prev.Next = &item.PdfOutlineTreeNode
+			item.Prev = &prev.PdfOutlineTreeNode
+		}
+		outline.Last = &item.PdfOutlineTreeNode
+		prev = item
+	}
+	w.AddOutlineTree(&outline.PdfOutlineTreeNode)
+
+	info := model.NewPdfInfo()
+	info.Title = title
+	w.SetPdfInfo(info)
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	return reader
+}
+
+func TestMerge(t *testing.T) {
+	first := makeMergeDocument(t, "First", "name")
+	second := makeMergeDocument(t, "Second", "name")
+
+	w, err := model.Merge([]model.MergeInput{
+		{Reader: first},
+		{Reader: second, Pages: []int{2, 1}, Title: "Appendix"},
+	})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 4, numPages)
+
+	// Outline of each document under a bookmark.
+	var titles []string
+	item, ok := core.GetDict(reader.GetOutlineTree().First.GetContainingPdfObject())
+	for ok {
+		title, _ := core.GetString(item.Get("Title"))
+		titles = append(titles, title.Decoded())
+		count, _ := core.GetIntVal(item.Get("Count"))
+		require.Equal(t, 2, count)
+		item, ok = core.GetDict(item.Get("Next"))
+	}
+	require.Equal(t, []string{"First", "Appendix"}, titles)
+
+	// Named destinations renamed in the second document.
+	dests, err := reader.GetNamedDestinations()
+	require.NoError(t, err)
+	require.Len(t, dests, 2)
+	require.Equal(t, 2, dests["intro"].PageNumber)
+	require.Equal(t, 3, dests["intro_2"].PageNumber)
+
+	// Links of the first page of the second document, page 4 of the output.
+	page, err := reader.GetPage(4)
+	require.NoError(t, err)
+	annots, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 3)
+	named := annots[0].GetContext().(*model.PdfAnnotationLink)
+	require.Equal(t, "intro_2", named.Dest.(*core.PdfObjectString).Str())
+	index := annots[1].GetContext().(*model.PdfAnnotationLink)
+	dest, err := model.NewPdfDestinationFromObject(index.Dest)
+	require.NoError(t, err)
+	target, err := reader.GetPage(3)
+	require.NoError(t, err)
+	require.Equal(t, target.GetPageDict(), core.TraceToDirectObject(dest.Page))
+
+	// Colliding field renamed.
+	form := reader.AcroForm
+	require.NotNil(t, form)
+	fields := form.AllFields()
+	require.Len(t, fields, 2)
+	name, err := fields[0].FullName()
+	require.NoError(t, err)
+	require.Equal(t, "name", name)
+	name, err = fields[1].FullName()
+	require.NoError(t, err)
+	require.Equal(t, "name_2", name)
+
+	// Identical fonts written once.
+	var fonts []core.PdfObject
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		font, ok := page.Resources.GetFontByName("F1")
+		require.True(t, ok)
+		fonts = append(fonts, font)
+	}
+	for _, font := range fonts[1:] {
+		require.True(t, font == fonts[0])
+	}
+}
+
+func TestMergePageRange(t *testing.T) {
+	doc := makeMergeDocument(t, "First", "name")
+	w, err := model.Merge([]model.MergeInput{{Reader: doc, Pages: model.PageRange(1, 1)}})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	// Destinations to the page not merged are removed.
+	dests, err := reader.GetNamedDestinations()
+	require.NoError(t, err)
+	require.Len(t, dests, 0)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annots, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 3)
+	index := annots[1].GetContext().(*model.PdfAnnotationLink)
+	require.Nil(t, index.Dest)
+
+	_, err = model.Merge([]model.MergeInput{{Reader: doc, Pages: []int{3}}})
+	require.Error(t, err)
+}
@@ -50,11 +50,26 @@ func findImages(objects []core.PdfObject) []*imageInfo {
 			continue
 		}
 		img := &imageInfo{BitsPerComponent: 8, Stream: stream}
-		if img.ColorSpace, err = model.DetermineColorspaceNameFromPdfObject(stream.PdfObjectDictionary.Get("ColorSpace")); err != nil {
+		jpxEnc, isJPX := getJPXEncoder(stream)
+		if isJPX && jpxEnc.SMaskInData != 0 {
+			common.Log.Debug("Optimization is not supported for JPX images with SMaskInData")
+			continue
+		}
+		csObj := stream.PdfObjectDictionary.Get("ColorSpace")
+		if csObj == nil && isJPX && jpxEnc.ICCProfile == nil {
+			// JPX images may take their color space from the JPEG 2000 data.
+			switch jpxEnc.ColorComponents {
+			case 1:
+				img.ColorSpace = "DeviceGray"
+			case 3:
+				img.ColorSpace = "DeviceRGB"
+			}
+		} else if img.ColorSpace, err = model.DetermineColorspaceNameFromPdfObject(csObj); err != nil {
 			common.Log.Error("Error determine color space %s", err)
 			continue
 		}
-		if val, ok := core.GetIntVal(stream.PdfObjectDictionary.Get("BitsPerComponent")); ok {
+		// JPX images are decoded with 8 bits per component whatever BitsPerComponent says.
+		if val, ok := core.GetIntVal(stream.PdfObjectDictionary.Get("BitsPerComponent")); ok && !isJPX {
 			img.BitsPerComponent = val
 		}
 		if val, ok := core.GetIntVal(stream.PdfObjectDictionary.Get("Width")); ok {
@@ -78,6 +93,20 @@ func findImages(objects []core.PdfObject) []*imageInfo {
 	return images
 }
 
+// getJPXEncoder returns the JPX decoder of `stream` if it is a JPXDecode image.
+func getJPXEncoder(stream *core.PdfObjectStream) (*core.JPXEncoder, bool) {
+	filter, ok := core.GetName(stream.PdfObjectDictionary.Get("Filter"))
+	if !ok || string(*filter) != core.StreamEncodingFilterNameJPX {
+		return nil, false
+	}
+	encoder, err := core.NewEncoderFromStream(stream)
+	if err != nil {
+		return nil, false
+	}
+	jpxEnc, ok := encoder.(*core.JPXEncoder)
+	return jpxEnc, ok
+}
+
 // Optimize optimizes PDF objects to decrease PDF size.
 func (i *Image) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfObject, err error) {
 	if i.ImageQuality <= 0 {
@@ -126,6 +155,8 @@ func (i *Image) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfO
 		newStream.PdfObjectDictionary.Merge(stream.PdfObjectDictionary)
 		fn := core.PdfObjectName(encoder.GetFilterName())
 		newStream.PdfObjectDictionary.Set(core.PdfObjectName("Filter"), &fn)
+		newStream.PdfObjectDictionary.Set("ColorSpace", core.MakeName(string(img.ColorSpace)))
+		newStream.PdfObjectDictionary.Set("BitsPerComponent", core.MakeInteger(int64(img.BitsPerComponent)))
 		ln := core.PdfObjectInteger(int64(len(streamData)))
 		newStream.PdfObjectDictionary.Set(core.PdfObjectName("Length"), &ln)
 		replaceTable[stream] = newStream
@@ -70,6 +70,10 @@ func scaleImage(stream *core.PdfObjectStream, scale float64) error {
 	encoderParams.Set("Quality", core.MakeInteger(100))
 	encoderParams.Set("Predictor", core.MakeInteger(1))
 
+	if _, ok := xImg.Filter.(*core.JPXEncoder); ok {
+		// JPX encoding is not supported so scaled JPX images are stored as DCT images.
+		xImg.Filter = core.NewDCTEncoder()
+	}
 	xImg.Filter.UpdateParams(encoderParams)
 
 	// Update image
@@ -10,11 +10,16 @@ import (
 )
 
 // OutlineDest represents the destination of an outline item.
-// It holds the page and the position on the page an outline item points to.
+// It holds the page and the position on the page an outline item points to,
+// or the name of a named destination.
 type OutlineDest struct {
 	Page int64
 	X    float64
 	Y    float64
+
+	// Name is the name of the destination. If set, the page and position
+	// of the named destination are used.
+	Name string
 }
 
 // NewOutlineDest returns a new outline destination which can be used
@@ -27,8 +32,17 @@ func NewOutlineDest(page int64, x, y float64) OutlineDest {
 	}
 }
 
+// NewOutlineNamedDest returns a new outline destination which targets the
+// named destination `name` (see PdfWriter.AddNamedDestination).
+func NewOutlineNamedDest(name string) OutlineDest {
+	return OutlineDest{Name: name}
+}
+
 // ToPdfObject returns a PDF object representation of the outline destination.
 func (od OutlineDest) ToPdfObject() core.PdfObject {
+	if od.Name != "" {
+		return core.MakeString(od.Name)
+	}
 	return core.MakeArray(
 		core.MakeInteger(od.Page),
 		core.MakeName("XYZ"),
@@ -0,0 +1,201 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PageLabelStyle is the numbering style of the page labels of a page range (Table 159).
+type PageLabelStyle string
+
+// Page label numbering styles.
+const (
+	PageLabelStyleNone         PageLabelStyle = ""  // Labels consist of the prefix only.
+	PageLabelStyleDecimal      PageLabelStyle = "D" // 1, 2, 3, ...
+	PageLabelStyleUpperRoman   PageLabelStyle = "R" // I, II, III, ...
+	PageLabelStyleLowerRoman   PageLabelStyle = "r" // i, ii, iii, ...
+	PageLabelStyleUpperLetters PageLabelStyle = "A" // A to Z, then AA to ZZ, ...
+	PageLabelStyleLowerLetters PageLabelStyle = "a" // a to z, then aa to zz, ...
+)
+
+// PdfPageLabel represents a page label dictionary (Section 12.4.2), which defines the labels of
+// the pages of a range of pages.
+type PdfPageLabel struct {
+	Style  PageLabelStyle
+	Prefix string
+	// Start is the number of the first page of the range. Values less than 1 are treated as 1.
+	Start int
+}
+
+// newPdfPageLabelFromObject loads a page label from a page label dictionary.
+func newPdfPageLabelFromObject(obj core.PdfObject) (PdfPageLabel, error) {
+	label := PdfPageLabel{Start: 1}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Page label not a dictionary (%T)", obj)
+		return label, core.ErrTypeError
+	}
+	if style, ok := core.GetNameVal(dict.Get("S")); ok {
+		label.Style = PageLabelStyle(style)
+	}
+	if prefix, ok := core.GetString(dict.Get("P")); ok {
+		label.Prefix = prefix.Decoded()
+	}
+	if start, ok := core.GetIntVal(dict.Get("St")); ok {
+		label.Start = start
+	}
+	return label, nil
+}
+
+// ToPdfObject returns the page label dictionary of `l`.
+func (l PdfPageLabel) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("PageLabel"))
+	if l.Style != PageLabelStyleNone {
+		dict.Set("S", core.MakeName(string(l.Style)))
+	}
+	if l.Prefix != "" {
+		dict.Set("P", core.MakeEncodedString(l.Prefix, !isPDFDocEncodable(l.Prefix)))
+	}
+	if l.Start > 1 {
+		dict.Set("St", core.MakeInteger(int64(l.Start)))
+	}
+	return dict
+}
+
+// Label returns the label of the page at offset `offset` from the first page of the range.
+func (l PdfPageLabel) Label(offset int) string {
+	n := l.Start
+	if n < 1 {
+		n = 1
+	}
+	n += offset
+
+	var num string
+	switch l.Style {
+	case PageLabelStyleDecimal:
+		num = strconv.Itoa(n)
+	case PageLabelStyleUpperRoman:
+		num = toRoman(n)
+	case PageLabelStyleLowerRoman:
+		num = strings.ToLower(toRoman(n))
+	case PageLabelStyleUpperLetters:
+		num = toLetters(n)
+	case PageLabelStyleLowerLetters:
+		num = strings.ToLower(toLetters(n))
+	}
+	return l.Prefix + num
+}
+
+// toRoman returns `n` as an uppercase roman numeral.
+func toRoman(n int) string {
+	var (
+		values  = []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+		symbols = []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	)
+	var b strings.Builder
+	for i, v := range values {
+		for n >= v {
+			b.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return b.String()
+}
+
+// toLetters returns `n` in uppercase letters: A to Z for 1 to 26, AA to ZZ for 27 to 52, etc.
+func toLetters(n int) string {
+	if n < 1 {
+		return ""
+	}
+	letter := string(rune('A' + (n-1)%26))
+	return strings.Repeat(letter, (n-1)/26+1)
+}
+
+// pageLabelRange is a page label with the index of the first page of its range.
+type pageLabelRange struct {
+	pageIndex int
+	label     PdfPageLabel
+}
+
+// pageLabels returns the labels of `numPages` pages with the page label ranges `ranges`, which are
+// sorted by page index. Pages before the first range are labelled with decimal page numbers.
+func pageLabels(ranges []pageLabelRange, numPages int) []string {
+	labels := make([]string, numPages)
+	r := -1
+	for i := range labels {
+		for r+1 < len(ranges) && ranges[r+1].pageIndex <= i {
+			r++
+		}
+		if r < 0 {
+			labels[i] = strconv.Itoa(i + 1)
+			continue
+		}
+		labels[i] = ranges[r].label.Label(i - ranges[r].pageIndex)
+	}
+	return labels
+}
+
+// GetPageLabels returns the page labels of the pages of the document as displayed by viewers,
+// i.e. as defined by the PageLabels number tree of the catalog (Section 12.4.2). Pages without a
+// label are labelled with their page number.
+func (r *PdfReader) GetPageLabels() ([]string, error) {
+	var ranges []pageLabelRange
+	if obj := r.catalog.Get("PageLabels"); obj != nil {
+		tree, err := NewPdfNumberTreeFromObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		err = tree.Walk(func(pageIndex int, value core.PdfObject) error {
+			label, err := newPdfPageLabelFromObject(value)
+			if err != nil {
+				return err
+			}
+			ranges = append(ranges, pageLabelRange{pageIndex: pageIndex, label: label})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].pageIndex < ranges[j].pageIndex
+	})
+	return pageLabels(ranges, len(r.PageList)), nil
+}
+
+// AddPageLabelRange labels the pages from page number `pageNum` up to the start of the next range
+// with `label`. Pages before the first range are labelled with decimal page numbers.
+func (w *PdfWriter) AddPageLabelRange(pageNum int, label PdfPageLabel) error {
+	if pageNum < 1 {
+		return errors.New("page numbering must start at 1")
+	}
+	if w.pageLabels == nil {
+		w.pageLabels = NewPdfNumberTreeBuilder()
+	}
+	w.pageLabels.Set(pageNum-1, label.ToPdfObject())
+	return nil
+}
+
+// pageLabelsToPdfObject returns the PageLabels number tree of the writer, or nil if no page labels
+// are defined.
+func (w *PdfWriter) pageLabelsToPdfObject() core.PdfObject {
+	if w.pageLabels == nil || w.pageLabels.Len() == 0 {
+		return nil
+	}
+	// The first page must be in a range.
+	if _, has := w.pageLabels.Get(0); !has {
+		w.pageLabels.Set(0, PdfPageLabel{Style: PageLabelStyleDecimal}.ToPdfObject())
+	}
+	return w.pageLabels.ToPdfObject()
+}
@@ -0,0 +1,87 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+func TestPageLabelFormat(t *testing.T) {
+	testcases := []struct {
+		label  model.PdfPageLabel
+		offset int
+		want   string
+	}{
+		{model.PdfPageLabel{Style: model.PageLabelStyleDecimal}, 0, "1"},
+		{model.PdfPageLabel{Style: model.PageLabelStyleDecimal, Start: 10}, 2, "12"},
+		{model.PdfPageLabel{Style: model.PageLabelStyleDecimal, Prefix: "A-", Start: 8}, 0, "A-8"},
+		{model.PdfPageLabel{Style: model.PageLabelStyleLowerRoman}, 3, "iv"},
+		{model.PdfPageLabel{Style: model.PageLabelStyleUpperRoman, Start: 1994}, 0, "MCMXCIV"},
+		{model.PdfPageLabel{Style: model.PageLabelStyleUpperLetters}, 0, "A"},
+		{model.PdfPageLabel{Style: model.PageLabelStyleUpperLetters}, 26, "AA"},
+		{model.PdfPageLabel{Style: model.PageLabelStyleLowerLetters}, 53, "bbb"},
+		{model.PdfPageLabel{Prefix: "Cover"}, 1, "Cover"},
+	}
+	for _, tc := range testcases {
+		require.Equal(t, tc.want, tc.label.Label(tc.offset))
+	}
+}
+
+func TestPageLabelsRoundTrip(t *testing.T) {
+	w := model.NewPdfWriter()
+	for i := 0; i < 7; i++ {
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+		require.NoError(t, w.AddPage(page))
+	}
+	require.Error(t, w.AddPageLabelRange(0, model.PdfPageLabel{}))
+	require.NoError(t, w.AddPageLabelRange(2, model.PdfPageLabel{Style: model.PageLabelStyleLowerRoman}))
+	require.NoError(t, w.AddPageLabelRange(4, model.PdfPageLabel{Style: model.PageLabelStyleDecimal}))
+	require.NoError(t, w.AddPageLabelRange(6, model.PdfPageLabel{
+		Style:  model.PageLabelStyleDecimal,
+		Prefix: "A-",
+		Start:  8,
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	labels, err := reader.GetPageLabels()
+	require.NoError(t, err)
+	// The writer adds a decimal range for the first page.
+	require.Equal(t, []string{"1", "i", "ii", "1", "2", "A-8", "A-9"}, labels)
+}
+
+func TestPageLabelsMissing(t *testing.T) {
+	w := model.NewPdfWriter()
+	for i := 0; i < 2; i++ {
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+		require.NoError(t, w.AddPage(page))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	catalog, ok := core.GetDict(trailer.Get("Root"))
+	require.True(t, ok)
+	require.Nil(t, catalog.Get("PageLabels"))
+
+	labels, err := reader.GetPageLabels()
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2"}, labels)
+}
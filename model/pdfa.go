@@ -0,0 +1,683 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfAConformance is a PDF/A (ISO 19005) conformance level of the output of a PdfWriter.
+type PdfAConformance int
+
+// PDF/A conformance levels. Level B (basic) requires the visual appearance of the document to be
+// preserved over time.
+const (
+	PdfAConformanceNone PdfAConformance = iota
+	PdfAConformance1B
+	PdfAConformance2B
+	PdfAConformance3B
+)
+
+// String returns the name of the conformance level, e.g. "PDF/A-1b".
+func (c PdfAConformance) String() string {
+	if c.part() == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("PDF/A-%db", c.part())
+}
+
+// part returns the part of ISO 19005 of the conformance level, 0 if none.
+func (c PdfAConformance) part() int {
+	switch c {
+	case PdfAConformance1B:
+		return 1
+	case PdfAConformance2B:
+		return 2
+	case PdfAConformance3B:
+		return 3
+	}
+	return 0
+}
+
+// PdfAError is returned by PdfWriter.Write when the document cannot be written in compliance
+// with the PDF/A conformance level of the writer.
+type PdfAError struct {
+	Conformance PdfAConformance
+
+	// Violations describes the content that is not compliant, one entry per problem.
+	Violations []string
+}
+
+// Error implements the error interface.
+func (e *PdfAError) Error() string {
+	return fmt.Sprintf("document is not %s compliant: %s", e.Conformance, strings.Join(e.Violations, "; "))
+}
+
+// SetPdfAConformance sets the PDF/A conformance level of the output. The writer embeds an sRGB
+// output intent and XMP metadata identifying the level, embeds the font programs of the fonts
+// without subsetting, converts DeviceCMYK colors of the content and LZW compressed streams, and
+// sets the PDF version accordingly. Write fails with a *PdfAError listing the problems if the
+// document cannot be made compliant, e.g. due to fonts that are not embedded, encryption or,
+// for PDF/A-1, transparency.
+func (w *PdfWriter) SetPdfAConformance(conformance PdfAConformance) {
+	w.pdfa = conformance
+}
+
+// pdfaWriter applies the PDF/A conformance level of a PdfWriter and collects the violations.
+type pdfaWriter struct {
+	w           *PdfWriter
+	conformance PdfAConformance
+
+	violations []string
+	reported   map[string]bool
+
+	// Content streams of pages, form XObjects and tiling patterns.
+	contents []*core.PdfObjectStream
+	visited  map[core.PdfObject]bool
+}
+
+// newPdfAWriter returns a new pdfaWriter for the conformance level of `w`.
+func newPdfAWriter(w *PdfWriter) *pdfaWriter {
+	return &pdfaWriter{
+		w:           w,
+		conformance: w.pdfa,
+		reported:    map[string]bool{},
+		visited:     map[core.PdfObject]bool{},
+	}
+}
+
+// addViolation records a violation, once per message.
+func (p *pdfaWriter) addViolation(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if p.reported[msg] {
+		return
+	}
+	common.Log.Debug("ERROR: %s: %s", p.conformance, msg)
+	p.reported[msg] = true
+	p.violations = append(p.violations, msg)
+}
+
+// err returns a *PdfAError if violations were recorded, nil otherwise.
+func (p *pdfaWriter) err() error {
+	if len(p.violations) == 0 {
+		return nil
+	}
+	return &PdfAError{Conformance: p.conformance, Violations: p.violations}
+}
+
+// prepare sets the PDF version and the file identifiers. Called before the objects are copied for
+// writing. Write restores the version and the file identifiers of the writer once written.
+func (p *pdfaWriter) prepare() error {
+	w := p.w
+	if p.conformance.part() == 1 {
+		w.SetVersion(1, 4)
+	} else if w.majorVersion == 1 && w.minorVersion < 7 {
+		w.SetVersion(1, 7)
+	}
+
+	if w.crypter != nil {
+		p.addViolation("encryption is not allowed")
+	}
+
+	if w.ids == nil {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+		w.ids = core.MakeArray(core.MakeHexString(string(id)), core.MakeHexString(string(id)))
+	}
+	return nil
+}
+
+// apply sets the document information dates and the output intent of the copy of the catalog
+// `catalog` that is written. Called on the copies of the objects, followed by the writing of the
+// XMP metadata identifying the conformance level, so that writing does not change the document.
+func (p *pdfaWriter) apply(catalog *core.PdfObjectDictionary) error {
+	w := p.w
+	info, ok := core.GetDict(w.infoObj)
+	if !ok {
+		info = core.MakeDict()
+		w.infoObj.PdfObject = info
+	}
+	now, _ := NewPdfDateFromTime(time.Now())
+	if info.Get("CreationDate") == nil {
+		info.Set("CreationDate", now.ToPdfObject())
+	}
+	if info.Get("ModDate") == nil {
+		info.Set("ModDate", now.ToPdfObject())
+	}
+
+	// The output intent of a previous write, or of the document, is kept.
+	intents, ok := core.GetArray(catalog.Get("OutputIntents"))
+	if !ok {
+		intents = core.MakeArray()
+		catalog.Set("OutputIntents", intents)
+	}
+	for _, obj := range intents.Elements() {
+		if d, ok := core.GetDict(obj); ok {
+			if s, _ := core.GetNameVal(d.Get("S")); s == "GTS_PDFA1" {
+				return nil
+			}
+		}
+	}
+
+	profile, err := core.MakeStream(newSRGBProfile(), core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+	profile.Set("N", core.MakeInteger(3))
+	intent := core.MakeDict()
+	intent.Set("Type", core.MakeName("OutputIntent"))
+	intent.Set("S", core.MakeName("GTS_PDFA1"))
+	intent.Set("OutputConditionIdentifier", core.MakeString(sRGBProfileDescription))
+	intent.Set("Info", core.MakeString(sRGBProfileDescription))
+	intent.Set("DestOutputProfile", profile)
+	intents.Append(core.MakeIndirectObject(intent))
+	return w.addObjects(intents)
+}
+
+// convert checks the objects to be written and converts the content that can be made compliant.
+// Called on the copies of the objects, before optimization.
+func (p *pdfaWriter) convert() {
+	for _, obj := range p.w.objects {
+		p.walk(obj)
+	}
+	for _, stream := range p.contents {
+		p.convertContentStream(stream)
+	}
+}
+
+// checkWritten checks the objects after optimization.
+func (p *pdfaWriter) checkWritten() {
+	if p.conformance.part() != 1 {
+		return
+	}
+	for _, obj := range p.w.objects {
+		if _, ok := obj.(*core.PdfObjectStreams); ok {
+			p.addViolation("object streams are not allowed")
+			return
+		}
+	}
+}
+
+// walk checks the dictionaries of `obj` and its descendants.
+func (p *pdfaWriter) walk(obj core.PdfObject) {
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		if p.visited[t] {
+			return
+		}
+		p.visited[t] = true
+		p.walk(t.PdfObject)
+	case *core.PdfObjectStream:
+		if p.visited[t] {
+			return
+		}
+		p.visited[t] = true
+		p.visited[t.PdfObjectDictionary] = true
+		p.checkDict(t.PdfObjectDictionary, t)
+		for _, key := range t.Keys() {
+			p.walk(t.Get(key))
+		}
+	case *core.PdfObjectDictionary:
+		if p.visited[t] {
+			return
+		}
+		p.visited[t] = true
+		p.checkDict(t, nil)
+		for _, key := range t.Keys() {
+			p.walk(t.Get(key))
+		}
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			p.walk(o)
+		}
+	}
+}
+
+// checkDict checks dictionary `dict`, the dictionary of `stream` if not nil.
+func (p *pdfaWriter) checkDict(dict *core.PdfObjectDictionary, stream *core.PdfObjectStream) {
+	typ, _ := core.GetNameVal(dict.Get("Type"))
+	subtype, _ := core.GetNameVal(dict.Get("Subtype"))
+
+	if stream != nil {
+		p.checkFilters(stream)
+		patternType, _ := core.GetIntVal(dict.Get("PatternType"))
+		switch {
+		case subtype == "Image":
+			p.checkImage(stream)
+		case subtype == "Form" || patternType == 1:
+			p.contents = append(p.contents, stream)
+		}
+	}
+
+	switch typ {
+	case "Font":
+		p.checkFont(dict)
+	case "Page":
+		switch t := core.TraceToDirectObject(dict.Get("Contents")).(type) {
+		case *core.PdfObjectStream:
+			p.contents = append(p.contents, t)
+		case *core.PdfObjectArray:
+			for _, o := range t.Elements() {
+				if stream, ok := core.GetStream(o); ok {
+					p.contents = append(p.contents, stream)
+				}
+			}
+		}
+	case "Annot":
+		p.checkAnnotation(dict, subtype)
+	}
+
+	if s, _ := core.GetNameVal(dict.Get("S")); s == "JavaScript" {
+		p.addViolation("JavaScript actions are not allowed")
+	}
+	if dict.Get("EF") != nil {
+		p.checkEmbeddedFile(dict)
+	}
+	if usesDeviceCMYK(dict.Get("ColorSpace")) {
+		p.addViolation("DeviceCMYK color space is used in %s dictionary", describeDict(typ, subtype))
+	}
+	if p.conformance.part() == 1 {
+		p.checkTransparency(dict, stream != nil && subtype == "Image")
+	}
+}
+
+// describeDict returns a description of a dictionary with type `typ` and subtype `subtype` for
+// violation messages.
+func describeDict(typ, subtype string) string {
+	switch {
+	case typ != "" && subtype != "":
+		return typ + " " + subtype
+	case subtype != "":
+		return subtype
+	case typ != "":
+		return typ
+	}
+	return "a"
+}
+
+// usesDeviceCMYK returns true if color space `obj` is or is based on DeviceCMYK, which requires a
+// CMYK output intent.
+func usesDeviceCMYK(obj core.PdfObject) bool {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectName:
+		return *t == "DeviceCMYK"
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			if usesDeviceCMYK(o) {
+				return true
+			}
+		}
+	case *core.PdfObjectDictionary:
+		// Color space resources.
+		for _, key := range t.Keys() {
+			if usesDeviceCMYK(t.Get(key)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkFilters re-encodes LZW compressed `stream` with Flate, as LZW is not allowed.
+func (p *pdfaWriter) checkFilters(stream *core.PdfObjectStream) {
+	lzw := false
+	switch t := core.TraceToDirectObject(stream.Get("Filter")).(type) {
+	case *core.PdfObjectName:
+		lzw = *t == core.StreamEncodingFilterNameLZW
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			if name, _ := core.GetNameVal(o); name == core.StreamEncodingFilterNameLZW {
+				lzw = true
+			}
+		}
+	}
+	if !lzw {
+		return
+	}
+
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		p.addViolation("LZW compressed stream cannot be decoded: %v", err)
+		return
+	}
+	if err := setFlateStreamData(stream, data); err != nil {
+		p.addViolation("LZW compressed stream cannot be re-encoded: %v", err)
+	}
+}
+
+// setFlateStreamData sets the data of `stream` to `data` compressed with Flate.
+func setFlateStreamData(stream *core.PdfObjectStream, data []byte) error {
+	encoder := core.NewFlateEncoder()
+	encoded, err := encoder.EncodeBytes(data)
+	if err != nil {
+		return err
+	}
+	stream.Stream = encoded
+	stream.Set("Filter", core.MakeName(core.StreamEncodingFilterNameFlate))
+	stream.Remove("DecodeParms")
+	stream.Set("Length", core.MakeInteger(int64(len(encoded))))
+	return nil
+}
+
+// checkImage converts the samples of DeviceCMYK image `stream` to DeviceRGB.
+func (p *pdfaWriter) checkImage(stream *core.PdfObjectStream) {
+	if name, _ := core.GetNameVal(stream.Get("ColorSpace")); name != "DeviceCMYK" {
+		return
+	}
+	bpc, _ := core.GetIntVal(stream.Get("BitsPerComponent"))
+	width, _ := core.GetIntVal(stream.Get("Width"))
+	height, _ := core.GetIntVal(stream.Get("Height"))
+	if bpc != 8 || stream.Get("Decode") != nil || !isLosslessStream(stream) {
+		return
+	}
+
+	data, err := core.DecodeStream(stream)
+	if err != nil || len(data) < 4*width*height {
+		return
+	}
+	rgb := make([]byte, 3*width*height)
+	for i := 0; i < width*height; i++ {
+		r, g, b := cmykToRGB(
+			float64(data[4*i])/255, float64(data[4*i+1])/255,
+			float64(data[4*i+2])/255, float64(data[4*i+3])/255)
+		rgb[3*i] = byte(math.Round(r * 255))
+		rgb[3*i+1] = byte(math.Round(g * 255))
+		rgb[3*i+2] = byte(math.Round(b * 255))
+	}
+	if err := setFlateStreamData(stream, rgb); err != nil {
+		return
+	}
+	stream.Set("ColorSpace", core.MakeName("DeviceRGB"))
+}
+
+// isLosslessStream returns true if `stream` is not encoded or only with lossless generic filters,
+// so that it can be decoded and re-encoded without loss.
+func isLosslessStream(stream *core.PdfObjectStream) bool {
+	lossless := map[string]bool{
+		core.StreamEncodingFilterNameFlate:     true,
+		core.StreamEncodingFilterNameLZW:       true,
+		core.StreamEncodingFilterNameRunLength: true,
+		core.StreamEncodingFilterNameASCIIHex:  true,
+		core.StreamEncodingFilterNameASCII85:   true,
+	}
+	switch t := core.TraceToDirectObject(stream.Get("Filter")).(type) {
+	case nil:
+		return true
+	case *core.PdfObjectName:
+		return lossless[string(*t)]
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			if name, _ := core.GetNameVal(o); !lossless[name] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// cmykToRGB converts CMYK color components in range [0, 1] to RGB.
+func cmykToRGB(c, m, y, k float64) (float64, float64, float64) {
+	return (1 - c) * (1 - k), (1 - m) * (1 - k), (1 - y) * (1 - k)
+}
+
+// checkFont checks that the font program of font dictionary `dict` is embedded.
+func (p *pdfaWriter) checkFont(dict *core.PdfObjectDictionary) {
+	subtype, _ := core.GetNameVal(dict.Get("Subtype"))
+	switch subtype {
+	case "Type1", "MMType1", "TrueType", "CIDFontType0", "CIDFontType2":
+	default:
+		// Type 0 fonts are checked by their descendant font, Type 3 glyphs are content streams.
+		return
+	}
+	if descriptor, ok := core.GetDict(dict.Get("FontDescriptor")); ok {
+		for _, key := range []core.PdfObjectName{"FontFile", "FontFile2", "FontFile3"} {
+			if _, ok := core.GetStream(descriptor.Get(key)); ok {
+				return
+			}
+		}
+	}
+	baseFont, _ := core.GetNameVal(dict.Get("BaseFont"))
+	p.addViolation("font %s is not embedded", baseFont)
+}
+
+// checkAnnotation sets the flags of annotation `dict` so that it is printed and not hidden.
+func (p *pdfaWriter) checkAnnotation(dict *core.PdfObjectDictionary, subtype string) {
+	if subtype == "Popup" {
+		return
+	}
+	const (
+		flagInvisible    = 1
+		flagHidden       = 2
+		flagPrint        = 4
+		flagNoView       = 32
+		flagToggleNoView = 256
+	)
+	flags, _ := core.GetIntVal(dict.Get("F"))
+	flags = flags&^(flagInvisible|flagHidden|flagNoView|flagToggleNoView) | flagPrint
+	dict.Set("F", core.MakeInteger(int64(flags)))
+}
+
+// checkEmbeddedFile checks file specification `dict` with embedded files.
+func (p *pdfaWriter) checkEmbeddedFile(dict *core.PdfObjectDictionary) {
+	name := ""
+	if s, ok := core.GetString(dict.Get("UF")); ok {
+		name = s.Decoded()
+	} else if s, ok := core.GetString(dict.Get("F")); ok {
+		name = s.Decoded()
+	}
+	var subtype string
+	if ef, ok := core.GetDict(dict.Get("EF")); ok {
+		if stream, ok := core.GetStream(ef.Get("F")); ok {
+			subtype, _ = core.GetNameVal(stream.Get("Subtype"))
+		}
+	}
+
+	switch p.conformance.part() {
+	case 1:
+		p.addViolation("embedded file %q is not allowed", name)
+	case 2:
+		if subtype != "application/pdf" {
+			p.addViolation("embedded file %q is not a PDF/A document", name)
+		}
+	case 3:
+		if dict.Get("AFRelationship") == nil {
+			p.addViolation("embedded file %q has no AFRelationship", name)
+		}
+		if subtype == "" {
+			p.addViolation("embedded file %q has no MIME type", name)
+		}
+	}
+}
+
+// checkTransparency checks that `dict` does not use transparency, which is not allowed in
+// PDF/A-1. `isImage` is true for image dictionaries, which cannot have soft masks.
+func (p *pdfaWriter) checkTransparency(dict *core.PdfObjectDictionary, isImage bool) {
+	if smask := dict.Get("SMask"); smask != nil {
+		if name, _ := core.GetNameVal(smask); isImage || name != "None" {
+			p.addViolation("transparency is not allowed (soft mask)")
+		}
+	}
+	for _, key := range []core.PdfObjectName{"CA", "ca"} {
+		if alpha, err := core.GetNumberAsFloat(core.TraceToDirectObject(dict.Get(key))); err == nil && alpha != 1 {
+			p.addViolation("transparency is not allowed (constant alpha %v)", alpha)
+		}
+	}
+	if bm, ok := core.GetNameVal(dict.Get("BM")); ok && bm != "Normal" && bm != "Compatible" {
+		p.addViolation("transparency is not allowed (blend mode %s)", bm)
+	}
+	if group, ok := core.GetDict(dict.Get("Group")); ok {
+		if s, _ := core.GetNameVal(group.Get("S")); s == "Transparency" {
+			p.addViolation("transparency is not allowed (transparency group)")
+		}
+	}
+}
+
+// convertContentStream converts the DeviceCMYK color operators of content `stream` to DeviceRGB.
+func (p *pdfaWriter) convertContentStream(stream *core.PdfObjectStream) {
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		p.addViolation("content stream cannot be decoded: %v", err)
+		return
+	}
+	converted, changed, usesCMYK := convertCMYKOperators(data)
+	if usesCMYK {
+		p.addViolation("DeviceCMYK color space is used in content stream")
+	}
+	if !changed {
+		return
+	}
+	if err := setFlateStreamData(stream, converted); err != nil {
+		p.addViolation("content stream cannot be encoded: %v", err)
+	}
+}
+
+// convertCMYKOperators replaces the k and K operators of content stream `content` with the
+// equivalent rg and RG operators. Returns the converted content, whether it changed and whether
+// the content also selects the DeviceCMYK color space otherwise (cs, CS or inline images), which
+// cannot be converted.
+func convertCMYKOperators(content []byte) (converted []byte, changed, usesCMYK bool) {
+	type token struct {
+		start int
+		num   bool
+		val   float64
+		name  string
+	}
+
+	var out bytes.Buffer
+	var operands []token
+	written := 0
+	for i := 0; i < len(content); {
+		c := content[i]
+		start := i
+		switch {
+		case core.IsWhiteSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\r' && content[i] != '\n' {
+				i++
+			}
+		case c == '(':
+			i = skipLiteralString(content, i)
+			operands = append(operands, token{start: start})
+		case c == '<' && i+1 < len(content) && content[i+1] == '<', c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			for i < len(content) && content[i] != '>' {
+				i++
+			}
+			i++
+			operands = append(operands, token{start: start})
+		case c == '[' || c == ']' || c == '{' || c == '}' || c == ')' || c == '>':
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isContentDelimiter(content[i]) {
+				i++
+			}
+			operands = append(operands, token{start: start, name: string(content[start+1 : i])})
+		default:
+			for i < len(content) && !isContentDelimiter(content[i]) {
+				i++
+			}
+			word := string(content[start:i])
+			if v, err := strconv.ParseFloat(word, 64); err == nil {
+				operands = append(operands, token{start: start, num: true, val: v})
+				continue
+			}
+			switch word {
+			case "true", "false", "null":
+				operands = append(operands, token{start: start})
+				continue
+			case "k", "K":
+				n := len(operands)
+				if n < 4 || !operands[n-4].num || !operands[n-3].num || !operands[n-2].num || !operands[n-1].num {
+					break
+				}
+				r, g, b := cmykToRGB(operands[n-4].val, operands[n-3].val, operands[n-2].val, operands[n-1].val)
+				op := "rg"
+				if word == "K" {
+					op = "RG"
+				}
+				out.Write(content[written:operands[n-4].start])
+				fmt.Fprintf(&out, "%s %s %s %s", formatColor(r), formatColor(g), formatColor(b), op)
+				written = i
+				changed = true
+			case "cs", "CS":
+				if n := len(operands); n > 0 && operands[n-1].name == "DeviceCMYK" {
+					usesCMYK = true
+				}
+			case "ID":
+				// Inline image: the operands are the key/value pairs of the image dictionary.
+				for j := 0; j+1 < len(operands); j++ {
+					key, val := operands[j].name, operands[j+1].name
+					if (key == "CS" || key == "ColorSpace") && (val == "CMYK" || val == "DeviceCMYK") {
+						usesCMYK = true
+					}
+				}
+				i = skipInlineImageData(content, i)
+			}
+			operands = operands[:0]
+		}
+	}
+	if !changed {
+		return content, false, usesCMYK
+	}
+	out.Write(content[written:])
+	return out.Bytes(), true, usesCMYK
+}
+
+// formatColor formats color component `v` for content streams.
+func formatColor(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10000)/10000, 'f', -1, 64)
+}
+
+// isContentDelimiter returns true if `c` ends a name, number or operator of a content stream.
+func isContentDelimiter(c byte) bool {
+	return core.IsWhiteSpace(c) || core.IsDelimiter(c)
+}
+
+// skipLiteralString returns the index after the literal string starting at `content[i]`.
+func skipLiteralString(content []byte, i int) int {
+	depth := 0
+	for ; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// skipInlineImageData returns the index after the EI operator ending the inline image data that
+// follows the ID operator ending at `content[i]`.
+func skipInlineImageData(content []byte, i int) int {
+	for i++; i+2 <= len(content); i++ {
+		if content[i] == 'E' && content[i+1] == 'I' && core.IsWhiteSpace(content[i-1]) &&
+			(i+2 == len(content) || isContentDelimiter(content[i+2])) {
+			return i + 2
+		}
+	}
+	return len(content)
+}
@@ -0,0 +1,158 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+func TestSRGBProfile(t *testing.T) {
+	profile := newSRGBProfile()
+	require.Equal(t, len(profile), int(binary.BigEndian.Uint32(profile[0:4])))
+	require.Equal(t, "mntrRGB XYZ ", string(profile[12:24]))
+	require.Equal(t, "acsp", string(profile[36:40]))
+	require.Zero(t, len(profile)%4)
+
+	// The tone reproduction curves share their data.
+	require.Equal(t, uint32(9), binary.BigEndian.Uint32(profile[128:132]))
+	offsets := map[string]uint32{}
+	for i := 0; i < 9; i++ {
+		entry := profile[132+12*i:]
+		offsets[string(entry[0:4])] = binary.BigEndian.Uint32(entry[4:8])
+	}
+	require.Equal(t, offsets["rTRC"], offsets["gTRC"])
+	require.Equal(t, offsets["rTRC"], offsets["bTRC"])
+	require.Equal(t, "desc", string(profile[offsets["desc"]:offsets["desc"]+4]))
+}
+
+func TestConvertCMYKOperators(t *testing.T) {
+	testcases := []struct {
+		content  string
+		expected string
+		changed  bool
+		usesCMYK bool
+	}{
+		{"0 0 0 1 k 10 10 m 1 0 0.5 0 K S", "0 0 0 rg 10 10 m 0 1 0.5 RG S", true, false},
+		{"BT (0 0 0 1 k) Tj ET 0.5 g", "BT (0 0 0 1 k) Tj ET 0.5 g", false, false},
+		{"/DeviceCMYK cs 1 0 0 0 sc", "/DeviceCMYK cs 1 0 0 0 sc", false, true},
+		{"BI /W 1 /H 1 /CS /CMYK /BPC 8 ID \x00 k\xff EI 0 0 0 0 k", "BI /W 1 /H 1 /CS /CMYK /BPC 8 ID \x00 k\xff EI 1 1 1 rg", true, true},
+		{"% 0 0 0 1 k\n/P <</MCID 0>> BDC 0.2 0.4 0 0.5 k EMC", "% 0 0 0 1 k\n/P <</MCID 0>> BDC 0.4 0.3 0.5 rg EMC", true, false},
+	}
+	for _, tc := range testcases {
+		converted, changed, usesCMYK := convertCMYKOperators([]byte(tc.content))
+		require.Equal(t, tc.expected, string(converted), tc.content)
+		require.Equal(t, tc.changed, changed, tc.content)
+		require.Equal(t, tc.usesCMYK, usesCMYK, tc.content)
+	}
+}
+
+func TestWriterPdfA(t *testing.T) {
+	w := NewPdfWriter()
+	w.SetPdfAConformance(PdfAConformance2B)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, "1.7", reader.PdfVersion().String())
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	ids, ok := core.GetArray(trailer.Get("ID"))
+	require.True(t, ok)
+	require.Equal(t, 2, ids.Len())
+
+	catalog, ok := core.GetDict(trailer.Get("Root"))
+	require.True(t, ok)
+	metadata, ok := core.GetStream(catalog.Get("Metadata"))
+	require.True(t, ok)
+	xmp := string(metadata.Stream)
+	require.Contains(t, xmp, "<pdfaid:part>2</pdfaid:part>")
+	require.Contains(t, xmp, "<pdfaid:conformance>B</pdfaid:conformance>")
+	require.Contains(t, xmp, "<xmp:CreateDate>")
+
+	intents, ok := core.GetArray(catalog.Get("OutputIntents"))
+	require.True(t, ok)
+	require.Equal(t, 1, intents.Len())
+	intent, ok := core.GetDict(intents.Get(0))
+	require.True(t, ok)
+	s, _ := core.GetNameVal(intent.Get("S"))
+	require.Equal(t, "GTS_PDFA1", s)
+	profile, ok := core.GetStream(intent.Get("DestOutputProfile"))
+	require.True(t, ok)
+	data, err := core.DecodeStream(profile)
+	require.NoError(t, err)
+	require.Equal(t, newSRGBProfile(), data)
+}
+
+// TestWriterPdfAWriteTwice checks that writing does not change the document of the writer.
+func TestWriterPdfAWriteTwice(t *testing.T) {
+	w := NewPdfWriter()
+	w.SetPdfAConformance(PdfAConformance1B)
+	catalog := w.catalog
+	info, ok := core.GetDict(w.infoObj)
+	require.True(t, ok)
+
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+		reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, "1.4", reader.PdfVersion().String())
+		trailer, err := reader.GetTrailer()
+		require.NoError(t, err)
+		root, ok := core.GetDict(trailer.Get("Root"))
+		require.True(t, ok)
+		intents, ok := core.GetArray(root.Get("OutputIntents"))
+		require.True(t, ok)
+		require.Equal(t, 1, intents.Len())
+	}
+	require.Nil(t, catalog.Get("OutputIntents"))
+	require.Nil(t, catalog.Get("Metadata"))
+	require.Nil(t, info.Get("CreationDate"))
+	require.Equal(t, 3, w.minorVersion)
+	require.Nil(t, w.ids)
+}
+
+func TestWriterPdfAViolations(t *testing.T) {
+	newWriter := func() *PdfWriter {
+		w := NewPdfWriter()
+		page := NewPdfPage()
+		page.MediaBox = &PdfRectangle{Urx: 200, Ury: 200}
+		gs := core.MakeDict()
+		gs.Set("ca", core.MakeFloat(0.5))
+		page.Resources.AddExtGState("GS0", gs)
+		page.AddContentStreamByString("/GS0 gs 0 0 0 1 k 0 0 100 100 re f")
+		require.NoError(t, w.AddPage(page))
+		return &w
+	}
+
+	// Transparency is allowed in PDF/A-2, the unlicensed watermark font is not embedded.
+	w := newWriter()
+	w.SetPdfAConformance(PdfAConformance2B)
+	err := w.Write(&bytes.Buffer{})
+	pdfaErr, ok := err.(*PdfAError)
+	require.True(t, ok, "%v", err)
+	require.Equal(t, []string{"font Helvetica is not embedded"}, pdfaErr.Violations)
+
+	w = newWriter()
+	w.SetPdfAConformance(PdfAConformance1B)
+	require.NoError(t, w.Encrypt([]byte("user"), []byte("owner"), nil))
+	err = w.Write(&bytes.Buffer{})
+	pdfaErr, ok = err.(*PdfAError)
+	require.True(t, ok, "%v", err)
+	require.Equal(t, PdfAConformance1B, pdfaErr.Conformance)
+	require.Contains(t, pdfaErr.Violations, "encryption is not allowed")
+	require.Contains(t, pdfaErr.Violations, "font Helvetica is not embedded")
+	require.Contains(t, pdfaErr.Violations, "transparency is not allowed (constant alpha 0.5)")
+	require.True(t, strings.HasPrefix(err.Error(), "document is not PDF/A-1b compliant: "))
+}
@@ -6,6 +6,8 @@
 package model
 
 import (
+	"crypto"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
@@ -150,6 +152,40 @@ func (r *PdfReader) Decrypt(password []byte) (bool, error) {
 	return true, nil
 }
 
+// DecryptWithCertificate decrypts a PDF file encrypted with the public-key security handler (Adobe.PubSec)
+// with the recipient certificate `cert` and its private key `pkey`. Returns true if successful, false if
+// the file is not encrypted for `cert`. The permissions granted to the recipient are returned by
+// GetAccessPermissions afterwards. Only RSA keys are supported.
+func (r *PdfReader) DecryptWithCertificate(cert *x509.Certificate, pkey crypto.PrivateKey) (bool, error) {
+	success, err := r.parser.DecryptWithCertificate(cert, pkey)
+	if err != nil {
+		return false, err
+	}
+	if !success {
+		return false, nil
+	}
+
+	err = r.loadStructure()
+	if err != nil {
+		common.Log.Debug("ERROR: Fail to load structure (%s)", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetAccessPermissions returns the access permissions of the file. Unencrypted files grant all
+// permissions. For files encrypted with the public-key security handler these are the permissions
+// granted to the recipient after DecryptWithCertificate, otherwise the P entry of the encryption
+// dictionary.
+func (r *PdfReader) GetAccessPermissions() security.Permissions {
+	crypter := r.parser.GetCrypter()
+	if crypter == nil {
+		return security.PermOwner
+	}
+	return crypter.GetAccessPermissions()
+}
+
 // CheckAccessRights checks access rights and permissions for a specified password.  If either user/owner
 // password is specified,  full rights are granted, otherwise the access rights are specified by the
 // Permissions flag.
@@ -729,6 +765,45 @@ func (r *PdfReader) GetOCProperties() (core.PdfObject, error) {
 	return obj, nil
 }
 
+// GetEmbeddedFiles returns the file specifications of the files embedded in the document, i.e.
+// the entries of the EmbeddedFiles name tree of the catalog, in the order of the tree. Invalid
+// file specifications are skipped.
+func (r *PdfReader) GetEmbeddedFiles() ([]*PdfFilespec, error) {
+	tree, err := r.GetNameTree("EmbeddedFiles")
+	if err != nil || tree == nil {
+		return nil, err
+	}
+
+	var files []*PdfFilespec
+	err = tree.Walk(func(key string, value core.PdfObject) error {
+		fs, err := newPdfFilespecFromObject(value)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid embedded file %q, skipping: %v", key, err)
+			return nil
+		}
+		files = append(files, fs)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// GetNameTree returns the name tree `name` of the names dictionary of the catalog
+// (Section 7.7.4), e.g. "Dests" or "JavaScript". Returns nil if the document has no such tree.
+func (r *PdfReader) GetNameTree(name core.PdfObjectName) (*PdfNameTree, error) {
+	names, ok := core.GetDict(r.catalog.Get("Names"))
+	if !ok {
+		return nil, nil
+	}
+	obj := names.Get(name)
+	if obj == nil {
+		return nil, nil
+	}
+	return NewPdfNameTreeFromObject(obj)
+}
+
 // Inspect inspects the object types, subtypes and content in the PDF file returning a map of
 // object type to number of instances of each.
 func (r *PdfReader) Inspect() (map[string]int, error) {
@@ -29,6 +29,8 @@ type PdfPageResources struct {
 
 	// Loaded objects.
 	colorspace *PdfPageResourcesColorspaces
+	// fonts maps font objects to the font models set with SetFont.
+	fonts map[core.PdfObject]*PdfFont
 }
 
 // NewPdfPageResources returns a new PdfPageResources object.
@@ -281,6 +283,31 @@ func (r *PdfPageResources) SetFontByName(keyName core.PdfObjectName, obj core.Pd
 	return nil
 }
 
+// SetFont sets the font specified by keyName to `font`. Unlike SetFontByName, the font model is
+// retained along with its PDF object which allows PdfWriter to subset the embedded font program.
+func (r *PdfPageResources) SetFont(keyName core.PdfObjectName, font *PdfFont) error {
+	obj := font.ToPdfObject()
+	if err := r.SetFontByName(keyName, obj); err != nil {
+		return err
+	}
+	if r.fonts == nil {
+		r.fonts = make(map[core.PdfObject]*PdfFont)
+	}
+	r.fonts[obj] = font
+	return nil
+}
+
+// GetFont returns the font model that was set for keyName with SetFont. Returns a bool value
+// indicating whether or not the font model was found.
+func (r *PdfPageResources) GetFont(keyName core.PdfObjectName) (*PdfFont, bool) {
+	obj, has := r.GetFontByName(keyName)
+	if !has {
+		return nil, false
+	}
+	font, has := r.fonts[obj]
+	return font, has
+}
+
 // GetColorspaceByName returns the colorspace with the specified name from the page resources.
 func (r *PdfPageResources) GetColorspaceByName(keyName core.PdfObjectName) (PdfColorspace, bool) {
 	colorspace, err := r.GetColorspaces()
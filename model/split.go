@@ -0,0 +1,482 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// SplitPart is a part of a document, see PdfReader.SplitByOutline and PdfReader.SplitBySize.
+type SplitPart struct {
+	// Pages are the numbers of the pages of the part, in order, starting from 1.
+	Pages []int
+
+	// Title is the title of the outline item the part starts at, empty for the pages before the
+	// first outline item.
+	Title string
+}
+
+// Split returns a writer with pages `pages` of the document, numbered from 1. Unlike adding the
+// pages to a writer, only the fonts, XObjects, patterns, shadings, color spaces, graphics states
+// and properties used by the content streams of the pages and the appearance streams of their
+// annotations are written. The outline items, named destinations, links and form fields to the
+// pages are kept as with Merge, as well as the document information.
+func (r *PdfReader) Split(pages []int) (*PdfWriter, error) {
+	if len(pages) == 0 {
+		return nil, errors.New("no pages to split")
+	}
+	m := newMerger()
+	m.split = true
+	if err := m.merge(MergeInput{Reader: r, Pages: pages}, 1); err != nil {
+		return nil, err
+	}
+	if err := m.finish(); err != nil {
+		return nil, err
+	}
+	info, err := r.GetPdfInfo()
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid document information: %v", err)
+	} else if info != nil {
+		m.w.SetPdfInfo(info)
+	}
+	return m.w, nil
+}
+
+// SplitByRanges returns a writer for each of `ranges` of page numbers, e.g. made with PageRange.
+// See Split.
+func (r *PdfReader) SplitByRanges(ranges ...[]int) ([]*PdfWriter, error) {
+	var writers []*PdfWriter
+	for _, pages := range ranges {
+		w, err := r.Split(pages)
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, w)
+	}
+	return writers, nil
+}
+
+// SplitByOutline returns the parts of the document starting at the pages of the outline items of
+// level `level`, 1 for the top level items. The pages before the first item form a part without
+// title. Items whose page cannot be determined, or that do not follow the page of the previous
+// item, are skipped. Write the parts with Split.
+func (r *PdfReader) SplitByOutline(level int) ([]*SplitPart, error) {
+	if level < 1 {
+		return nil, fmt.Errorf("invalid outline level %d", level)
+	}
+	numPages, err := r.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	named, err := r.GetNamedDestinations()
+	if err != nil {
+		return nil, err
+	}
+	pageNumbers := map[*core.PdfObjectDictionary]int{}
+	for i, page := range r.pageList {
+		if d, ok := core.GetDict(page); ok {
+			pageNumbers[d] = i + 1
+		}
+	}
+
+	var items []*core.PdfObjectDictionary
+	if outlines, ok := core.GetDict(r.catalog.Get("Outlines")); ok {
+		items = outlineLevelItems(outlines.Get("First"), level, map[*core.PdfObjectDictionary]struct{}{})
+	}
+
+	var parts []*SplitPart
+	last := 0
+	for _, item := range items {
+		page := outlineItemPage(item, named, pageNumbers)
+		if page <= last || page > numPages {
+			continue
+		}
+		if len(parts) == 0 && page > 1 {
+			parts = append(parts, &SplitPart{Pages: PageRange(1, page-1)})
+		}
+		if len(parts) > 0 {
+			prev := parts[len(parts)-1]
+			prev.Pages = PageRange(prev.Pages[0], page-1)
+		}
+		title := ""
+		if s, ok := core.GetString(item.Get("Title")); ok {
+			title = s.Decoded()
+		}
+		parts = append(parts, &SplitPart{Pages: PageRange(page, numPages), Title: title})
+		last = page
+	}
+	if len(parts) == 0 && numPages > 0 {
+		parts = append(parts, &SplitPart{Pages: PageRange(1, numPages)})
+	}
+	return parts, nil
+}
+
+// outlineLevelItems returns the outline items of level `level` under outline item `first` and its
+// siblings, of level 1, in order.
+func outlineLevelItems(first core.PdfObject, level int, visited map[*core.PdfObjectDictionary]struct{}) []*core.PdfObjectDictionary {
+	var items []*core.PdfObjectDictionary
+	d, ok := core.GetDict(first)
+	for ok {
+		if _, has := visited[d]; has {
+			common.Log.Debug("ERROR: Circular outline")
+			break
+		}
+		visited[d] = struct{}{}
+		if level == 1 {
+			items = append(items, d)
+		} else {
+			items = append(items, outlineLevelItems(d.Get("First"), level-1, visited)...)
+		}
+		d, ok = core.GetDict(d.Get("Next"))
+	}
+	return items
+}
+
+// outlineItemPage returns the number of the page targeted by outline item `item` through its
+// destination or go-to action, 0 if unknown.
+func outlineItemPage(item *core.PdfObjectDictionary, named map[string]*PdfDestination,
+	pageNumbers map[*core.PdfObjectDictionary]int) int {
+	obj := item.Get("Dest")
+	if action, ok := core.GetDict(item.Get("A")); ok && obj == nil {
+		if s, _ := core.GetNameVal(action.Get("S")); s == string(ActionTypeGoTo) {
+			obj = action.Get("D")
+		}
+	}
+
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectString:
+		if dest, has := named[t.Str()]; has {
+			return dest.PageNumber
+		}
+		return 0
+	case *core.PdfObjectName:
+		if dest, has := named[string(*t)]; has {
+			return dest.PageNumber
+		}
+		return 0
+	}
+	dest, err := NewPdfDestinationFromObject(obj)
+	if err != nil {
+		return 0
+	}
+	switch page := core.TraceToDirectObject(dest.Page).(type) {
+	case *core.PdfObjectDictionary:
+		return pageNumbers[page]
+	case *core.PdfObjectInteger:
+		return int(*page) + 1
+	}
+	return 0
+}
+
+// SplitBySize returns parts of consecutive pages of the document whose size when written with
+// Split does not exceed `maxSize` bytes. The size is estimated from the objects used by the
+// pages, counting the objects shared by the pages of a part once. A page exceeding `maxSize` on
+// its own forms a part.
+func (r *PdfReader) SplitBySize(maxSize int64) ([]*SplitPart, error) {
+	// Estimated size of the objects of a document besides its pages: catalog, page tree,
+	// document information, cross references and trailer.
+	const documentSize = 1024
+
+	numPages, err := r.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []*SplitPart
+	var part *SplitPart
+	var size int64
+	var written map[core.PdfObject]struct{}
+	for n := 1; n <= numPages; n++ {
+		src, ok := core.GetDict(r.pageList[n-1])
+		if !ok {
+			return nil, errors.New("invalid page dictionary")
+		}
+		view := pageView(src, true)
+		objects := map[core.PdfObject]int64{}
+		addObjectSizes(view, objects)
+		pageSize := func() int64 {
+			added := int64(len(view.WriteString()))
+			for obj, objSize := range objects {
+				if _, has := written[obj]; !has {
+					added += objSize
+				}
+			}
+			return added
+		}
+
+		added := pageSize()
+		if part != nil && size+added > maxSize {
+			part = nil
+		}
+		if part == nil {
+			part = &SplitPart{}
+			parts = append(parts, part)
+			size = documentSize
+			written = map[core.PdfObject]struct{}{}
+			added = pageSize()
+		}
+		for obj := range objects {
+			written[obj] = struct{}{}
+		}
+		size += added
+		part.Pages = append(part.Pages, n)
+	}
+	return parts, nil
+}
+
+// addObjectSizes adds the indirect objects and streams used by `obj`, except pages, to `sizes`
+// with their estimated written size. The resources of form XObjects are pruned as with Split.
+func addObjectSizes(obj core.PdfObject, sizes map[core.PdfObject]int64) {
+	// Estimated size of the object number, generation and keywords of an indirect object.
+	const indirectSize = 20
+
+	switch t := core.ResolveReference(obj).(type) {
+	case *core.PdfIndirectObject:
+		if _, has := sizes[t]; has {
+			return
+		}
+		if d, ok := t.PdfObject.(*core.PdfObjectDictionary); ok {
+			if name, _ := core.GetNameVal(d.Get("Type")); name == "Page" || name == "Pages" {
+				return
+			}
+		}
+		sizes[t] = indirectSize + int64(len(t.PdfObject.WriteString()))
+		addObjectSizes(t.PdfObject, sizes)
+	case *core.PdfObjectStream:
+		if _, has := sizes[t]; has {
+			return
+		}
+		d := prunedFormDict(t)
+		sizes[t] = indirectSize + int64(len(d.WriteString())+len(t.Stream))
+		addObjectSizes(d, sizes)
+	case *core.PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			addObjectSizes(t.Get(key), sizes)
+		}
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			addObjectSizes(elem, sizes)
+		}
+	}
+}
+
+// prunedResourceCategories are the entries of resource dictionaries whose resources are used by
+// name in content streams.
+var prunedResourceCategories = []core.PdfObjectName{
+	"ExtGState", "ColorSpace", "Pattern", "Shading", "XObject", "Font", "Properties",
+}
+
+// prunedPageResources returns the resources of page dictionary `page` without the resources unused
+// by its content streams and the appearance streams of its annotations.
+func prunedPageResources(page *core.PdfObjectDictionary) core.PdfObject {
+	res := page.Get("Resources")
+	resDict, ok := core.GetDict(res)
+	if !ok {
+		return res
+	}
+
+	var streams []*core.PdfObjectStream
+	switch t := core.TraceToDirectObject(page.Get("Contents")).(type) {
+	case *core.PdfObjectStream:
+		streams = append(streams, t)
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			if stream, ok := core.GetStream(elem); ok {
+				streams = append(streams, stream)
+			}
+		}
+	}
+	// Appearance streams without resources use the resources of the page.
+	annots, _ := core.GetArray(page.Get("Annots"))
+	for _, obj := range annots.Elements() {
+		annot, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		ap, _ := core.GetDict(annot.Get("AP"))
+		if ap == nil {
+			continue
+		}
+		for _, key := range ap.Keys() {
+			appearances := []core.PdfObject{ap.Get(key)}
+			if states, ok := core.GetDict(ap.Get(key)); ok {
+				appearances = nil
+				for _, state := range states.Keys() {
+					appearances = append(appearances, states.Get(state))
+				}
+			}
+			for _, appearance := range appearances {
+				if stream, ok := core.GetStream(appearance); ok && stream.Get("Resources") == nil {
+					streams = append(streams, stream)
+				}
+			}
+		}
+	}
+
+	names := usedResourceNames(streams, resDict)
+	if names == nil {
+		return res
+	}
+	return pruneResources(resDict, names)
+}
+
+// prunedFormDict returns the dictionary of form XObject `stream` without the resources unused by
+// its content, the dictionary of `stream` for other streams.
+func prunedFormDict(stream *core.PdfObjectStream) *core.PdfObjectDictionary {
+	d := stream.PdfObjectDictionary
+	if subtype, _ := core.GetNameVal(d.Get("Subtype")); subtype != "Form" {
+		return d
+	}
+	resDict, ok := core.GetDict(d.Get("Resources"))
+	if !ok {
+		return d
+	}
+	names := usedResourceNames([]*core.PdfObjectStream{stream}, resDict)
+	if names == nil {
+		return d
+	}
+	view := core.MakeDict()
+	view.Merge(d)
+	view.Set("Resources", pruneResources(resDict, names))
+	return view
+}
+
+// usedResourceNames returns the names used by content streams `streams` with resources `resDict`,
+// including those of the form XObjects they draw that have no resources of their own. Returns
+// nil if a content stream cannot be decoded.
+func usedResourceNames(streams []*core.PdfObjectStream, resDict *core.PdfObjectDictionary) map[string]struct{} {
+	names := map[string]struct{}{}
+	for _, stream := range streams {
+		content, err := core.DecodeStream(stream)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to decode content stream, resources not pruned: %v", err)
+			return nil
+		}
+		contentNames(content, names)
+	}
+
+	// The form XObjects without resources use the resources of their parent.
+	xobjects, _ := core.GetDict(resDict.Get("XObject"))
+	if xobjects == nil {
+		return names
+	}
+	scanned := map[core.PdfObjectName]struct{}{}
+	for changed := true; changed; {
+		changed = false
+		for _, key := range xobjects.Keys() {
+			if _, used := names[string(key)]; !used {
+				continue
+			}
+			if _, has := scanned[key]; has {
+				continue
+			}
+			scanned[key] = struct{}{}
+			form, ok := core.GetStream(xobjects.Get(key))
+			if !ok || form.Get("Resources") != nil {
+				continue
+			}
+			if subtype, _ := core.GetNameVal(form.Get("Subtype")); subtype != "Form" {
+				continue
+			}
+			content, err := core.DecodeStream(form)
+			if err != nil {
+				common.Log.Debug("ERROR: Unable to decode form XObject, resources not pruned: %v", err)
+				return nil
+			}
+			contentNames(content, names)
+			changed = true
+		}
+	}
+	return names
+}
+
+// pruneResources returns a copy of resource dictionary `resDict` with only the resources named
+// `names` in the categories used by name in content streams. The default color spaces are kept.
+func pruneResources(resDict *core.PdfObjectDictionary, names map[string]struct{}) *core.PdfObjectDictionary {
+	pruned := core.MakeDict()
+	pruned.Merge(resDict)
+	for _, category := range prunedResourceCategories {
+		resources, ok := core.GetDict(resDict.Get(category))
+		if !ok {
+			continue
+		}
+		used := core.MakeDict()
+		for _, key := range resources.Keys() {
+			_, has := names[string(key)]
+			if category == "ColorSpace" {
+				has = has || key == "DefaultGray" || key == "DefaultRGB" || key == "DefaultCMYK"
+			}
+			if has {
+				used.Set(key, resources.Get(key))
+			}
+		}
+		if len(used.Keys()) == 0 {
+			pruned.Remove(category)
+		} else {
+			pruned.Set(category, used)
+		}
+	}
+	return pruned
+}
+
+// contentNames adds the names of content stream `content` to `names`.
+func contentNames(content []byte, names map[string]struct{}) {
+	for i := 0; i < len(content); {
+		c := content[i]
+		start := i
+		switch {
+		case c == '%':
+			for i < len(content) && content[i] != '\r' && content[i] != '\n' {
+				i++
+			}
+		case c == '(':
+			i = skipLiteralString(content, i)
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '<':
+			for i < len(content) && content[i] != '>' {
+				i++
+			}
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isContentDelimiter(content[i]) {
+				i++
+			}
+			names[decodeContentName(content[start+1:i])] = struct{}{}
+		case isContentDelimiter(c):
+			i++
+		default:
+			for i < len(content) && !isContentDelimiter(content[i]) {
+				i++
+			}
+			if string(content[start:i]) == "ID" {
+				i = skipInlineImageData(content, i)
+			}
+		}
+	}
+}
+
+// decodeContentName returns name `name` of a content stream with its #xx escapes decoded.
+func decodeContentName(name []byte) string {
+	var decoded []byte
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) {
+			if v, err := strconv.ParseUint(string(name[i+1:i+3]), 16, 8); err == nil {
+				decoded = append(decoded, byte(v))
+				i += 2
+				continue
+			}
+		}
+		decoded = append(decoded, name[i])
+	}
+	return string(decoded)
+}
@@ -0,0 +1,143 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// makeSplitDocument returns a reader of a 3 page document whose pages share resources with fonts
+// F1 to F3 and image Im1. Page N uses font FN and page 3 draws the image. The outline has items
+// A to page 1 and B to page 2, with child B.1 to page 3.
+func makeSplitDocument(t *testing.T) *model.PdfReader {
+	res := model.NewPdfPageResources()
+	for i, name := range []model.StdFontName{model.HelveticaName, model.CourierName, model.TimesRomanName} {
+		font, err := model.NewStandard14Font(name)
+		require.NoError(t, err)
+		require.NoError(t, res.SetFontByName(core.PdfObjectName("F"+string(rune('1'+i))), font.ToPdfObject()))
+	}
+	data := make([]byte, 30000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	img, err := core.MakeStream(data, nil)
+	require.NoError(t, err)
+	img.Set("Type", core.MakeName("XObject"))
+	img.Set("Subtype", core.MakeName("Image"))
+	img.Set("Width", core.MakeInteger(100))
+	img.Set("Height", core.MakeInteger(100))
+	img.Set("ColorSpace", core.MakeName("DeviceRGB"))
+	img.Set("BitsPerComponent", core.MakeInteger(8))
+	require.NoError(t, res.SetXObjectByName("Im1", img))
+
+	w := model.NewPdfWriter()
+	for i := 1; i <= 3; i++ {
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+		page.Resources = res
+		content := "BT /F" + string(rune('0'+i)) + " 12 Tf 10 10 Td (Page) Tj ET"
+		if i == 3 {
+			content += " q 100 0 0 100 50 50 cm /Im1 Do Q"
+		}
+		require.NoError(t, page.SetContentStreams([]string{content}, core.NewRawEncoder()))
+		require.NoError(t, w.AddPage(page))
+	}
+
+	outline := model.NewOutline()
+	outline.Add(model.NewOutlineItem("A", model.NewOutlineDest(0, 0, 200)))
+	b := model.NewOutlineItem("B", model.NewOutlineDest(1, 0, 200))
+	b.Add(model.NewOutlineItem("B.1", model.NewOutlineDest(2, 0, 200)))
+	outline.Add(b)
+	w.AddOutlineTree(&outline.ToPdfOutline().PdfOutlineTreeNode)
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	return reader
+}
+
+// writeSplit writes `w` and returns the reader of the output and its size.
+func writeSplit(t *testing.T, w *model.PdfWriter) (*model.PdfReader, int) {
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	return reader, buf.Len()
+}
+
+func TestSplit(t *testing.T) {
+	doc := makeSplitDocument(t)
+
+	w, err := doc.Split([]int{1})
+	require.NoError(t, err)
+	reader, size := writeSplit(t, w)
+	require.True(t, size < 10000)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 1, numPages)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	_, has := page.Resources.GetFontByName("F1")
+	require.True(t, has)
+	_, has = page.Resources.GetFontByName("F2")
+	require.False(t, has)
+	require.False(t, page.Resources.HasXObjectByName("Im1"))
+
+	// Only the outline items to the pages of the part are kept.
+	item, ok := core.GetDict(reader.GetOutlineTree().First.GetContainingPdfObject())
+	require.True(t, ok)
+	title, _ := core.GetString(item.Get("Title"))
+	require.Equal(t, "A", title.Decoded())
+	require.Nil(t, item.Get("Next"))
+
+	writers, err := doc.SplitByRanges(model.PageRange(1, 2), []int{3})
+	require.NoError(t, err)
+	require.Len(t, writers, 2)
+	reader, size = writeSplit(t, writers[1])
+	require.True(t, size > 30000)
+	page, err = reader.GetPage(1)
+	require.NoError(t, err)
+	require.True(t, page.Resources.HasXObjectByName("Im1"))
+	_, has = page.Resources.GetFontByName("F1")
+	require.False(t, has)
+}
+
+func TestSplitByOutline(t *testing.T) {
+	doc := makeSplitDocument(t)
+
+	parts, err := doc.SplitByOutline(1)
+	require.NoError(t, err)
+	require.Equal(t, []*model.SplitPart{
+		{Pages: []int{1}, Title: "A"},
+		{Pages: []int{2, 3}, Title: "B"},
+	}, parts)
+
+	parts, err = doc.SplitByOutline(2)
+	require.NoError(t, err)
+	require.Equal(t, []*model.SplitPart{
+		{Pages: []int{1, 2}},
+		{Pages: []int{3}, Title: "B.1"},
+	}, parts)
+}
+
+func TestSplitBySize(t *testing.T) {
+	doc := makeSplitDocument(t)
+
+	parts, err := doc.SplitBySize(20000)
+	require.NoError(t, err)
+	require.Equal(t, []*model.SplitPart{{Pages: []int{1, 2}}, {Pages: []int{3}}}, parts)
+
+	parts, err = doc.SplitBySize(100000)
+	require.NoError(t, err)
+	require.Equal(t, []*model.SplitPart{{Pages: []int{1, 2, 3}}}, parts)
+}
@@ -0,0 +1,459 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfStructTreeRoot represents the structure tree root of a tagged PDF (Section 14.7.2).
+type PdfStructTreeRoot struct {
+	// Kids are the top level structure elements, usually a single Document element.
+	Kids []*PdfStructElement
+	// RoleMap maps custom structure types to standard structure types.
+	RoleMap map[string]string
+}
+
+// StandardType returns the standard structure type that structure type `s` is mapped to by the
+// role map of the structure tree root, or `s` if it is not mapped.
+func (root *PdfStructTreeRoot) StandardType(s string) string {
+	visited := map[string]bool{}
+	for !visited[s] {
+		visited[s] = true
+		mapped, has := root.RoleMap[s]
+		if !has {
+			break
+		}
+		s = mapped
+	}
+	return s
+}
+
+// PdfStructElement represents a structure element (Section 14.7.2).
+type PdfStructElement struct {
+	// S is the structure type, e.g. P, H1, Table or Figure (Section 14.8.4).
+	S string
+
+	ID         string // Element identifier.
+	T          string // Title.
+	Lang       string // Language, e.g. "en-US".
+	Alt        string // Alternate description, e.g. of a Figure.
+	ActualText string // Replacement text.
+
+	// Attributes are the attribute objects of the element (Section 14.7.5).
+	Attributes []*PdfStructAttributes
+
+	// Parent is the parent structure element. Nil for the kids of the structure tree root.
+	Parent *PdfStructElement
+	// Kids are the structure elements and marked content belonging to the element, in logical
+	// reading order.
+	Kids []*PdfStructKid
+}
+
+// PdfStructAttributes is an attribute object of a structure element (Section 14.7.5).
+type PdfStructAttributes struct {
+	// Owner is the owner of the attributes, e.g. Layout, List or Table.
+	Owner string
+	// Values maps the attribute names to their values.
+	Values map[string]core.PdfObject
+}
+
+// ToPdfObject returns the attribute object dictionary of `a`.
+func (a *PdfStructAttributes) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("O", core.MakeName(a.Owner))
+	keys := make([]string, 0, len(a.Values))
+	for key := range a.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		dict.Set(core.PdfObjectName(key), a.Values[key])
+	}
+	return dict
+}
+
+// PdfStructKid is a kid of a structure element. It is either a structure element (Element set),
+// a PDF object such as an annotation (Object set) or a marked-content sequence identified by its
+// marked-content identifier (MCID) on a page.
+type PdfStructKid struct {
+	Element *PdfStructElement
+	Object  core.PdfObject
+
+	Page *PdfPage
+	MCID int
+}
+
+// NewPdfStructElement returns a new structure element of type `s`.
+func NewPdfStructElement(s string) *PdfStructElement {
+	return &PdfStructElement{S: s}
+}
+
+// AddKid appends structure element `kid` to the kids of `e` and sets its parent to `e`.
+func (e *PdfStructElement) AddKid(kid *PdfStructElement) {
+	kid.Parent = e
+	e.Kids = append(e.Kids, &PdfStructKid{Element: kid})
+}
+
+// AddMarkedContent appends the marked-content sequence with identifier `mcid` on `page` to the
+// kids of `e`.
+func (e *PdfStructElement) AddMarkedContent(page *PdfPage, mcid int) {
+	e.Kids = append(e.Kids, &PdfStructKid{Page: page, MCID: mcid})
+}
+
+// SetStructTreeRoot sets the structure tree of the output PDF, marking it as a tagged PDF. The
+// marked-content kids of the structure elements must refer to pages added to the writer.
+func (w *PdfWriter) SetStructTreeRoot(root *PdfStructTreeRoot) {
+	w.structTreeRoot = root
+}
+
+// structTreeWriter converts a structure tree to PDF objects, collecting the parent tree entries
+// of the marked content of each page.
+type structTreeWriter struct {
+	// parents maps the pages to the parent structure elements of their marked content, indexed
+	// by MCID.
+	parents map[*core.PdfIndirectObject][]core.PdfObject
+}
+
+// writeStructTree sets the StructTreeRoot and MarkInfo entries of the catalog and the
+// StructParents entries of the pages with marked content.
+func (w *PdfWriter) writeStructTree() error {
+	root := w.structTreeRoot
+	tw := &structTreeWriter{parents: map[*core.PdfIndirectObject][]core.PdfObject{}}
+
+	rootObj := core.MakeIndirectObject(core.MakeDict())
+	k := core.MakeArray()
+	for _, e := range root.Kids {
+		obj, err := tw.elementToPdfObject(e, rootObj)
+		if err != nil {
+			return err
+		}
+		k.Append(obj)
+	}
+
+	// The parent tree is keyed by the StructParents entries of the pages, which are numbered in
+	// page order.
+	pagesDict, ok := core.GetDict(w.pages)
+	if !ok {
+		return errors.New("invalid Pages obj (not a dict)")
+	}
+	kids, ok := core.GetArray(pagesDict.Get("Kids"))
+	if !ok {
+		return errors.New("invalid Pages Kids obj (not an array)")
+	}
+	parentTree := NewPdfNumberTreeBuilder()
+	for _, kid := range kids.Elements() {
+		pageObj, ok := kid.(*core.PdfIndirectObject)
+		if !ok {
+			continue
+		}
+		parents, has := tw.parents[pageObj]
+		if !has {
+			continue
+		}
+		delete(tw.parents, pageObj)
+
+		key := parentTree.Len()
+		if pageDict, ok := core.GetDict(pageObj); ok {
+			pageDict.Set("StructParents", core.MakeInteger(int64(key)))
+		}
+		parentTree.Set(key, core.MakeIndirectObject(core.MakeArray(parents...)))
+	}
+	if len(tw.parents) > 0 {
+		common.Log.Debug("ERROR: Marked content on %d pages not added to the writer", len(tw.parents))
+		return errors.New("marked content on page not added to the writer")
+	}
+
+	dict := rootObj.PdfObject.(*core.PdfObjectDictionary)
+	dict.Set("Type", core.MakeName("StructTreeRoot"))
+	dict.Set("K", k)
+	dict.Set("ParentTree", parentTree.ToPdfObject())
+	dict.Set("ParentTreeNextKey", core.MakeInteger(int64(parentTree.Len())))
+	if len(root.RoleMap) > 0 {
+		roleMap := core.MakeDict()
+		for from, to := range root.RoleMap {
+			roleMap.Set(core.PdfObjectName(from), core.MakeName(to))
+		}
+		dict.Set("RoleMap", roleMap)
+	}
+
+	markInfo := core.MakeDict()
+	markInfo.Set("Marked", core.MakeBool(true))
+	w.catalog.Set("MarkInfo", markInfo)
+	w.catalog.Set("StructTreeRoot", rootObj)
+	return w.addObjects(rootObj)
+}
+
+// elementToPdfObject returns the structure element dictionary of `e` with parent `parent`.
+func (tw *structTreeWriter) elementToPdfObject(e *PdfStructElement, parent core.PdfObject) (*core.PdfIndirectObject, error) {
+	container := core.MakeIndirectObject(core.MakeDict())
+	dict := container.PdfObject.(*core.PdfObjectDictionary)
+	dict.Set("Type", core.MakeName("StructElem"))
+	dict.Set("S", core.MakeName(e.S))
+	dict.Set("P", parent)
+	if e.ID != "" {
+		dict.Set("ID", core.MakeString(e.ID))
+	}
+	if e.T != "" {
+		dict.Set("T", core.MakeEncodedString(e.T, true))
+	}
+	if e.Lang != "" {
+		dict.Set("Lang", core.MakeString(e.Lang))
+	}
+	if e.Alt != "" {
+		dict.Set("Alt", core.MakeEncodedString(e.Alt, true))
+	}
+	if e.ActualText != "" {
+		dict.Set("ActualText", core.MakeEncodedString(e.ActualText, true))
+	}
+	if len(e.Attributes) > 0 {
+		a := core.MakeArray()
+		for _, attrs := range e.Attributes {
+			a.Append(attrs.ToPdfObject())
+		}
+		if a.Len() == 1 {
+			dict.Set("A", a.Get(0))
+		} else {
+			dict.Set("A", a)
+		}
+	}
+
+	// The page of the element is the page of its first marked content. The marked content on
+	// other pages is referenced by marked-content reference dictionaries.
+	var page *core.PdfIndirectObject
+	k := core.MakeArray()
+	for _, kid := range e.Kids {
+		if kid.Element != nil {
+			obj, err := tw.elementToPdfObject(kid.Element, container)
+			if err != nil {
+				return nil, err
+			}
+			k.Append(obj)
+			continue
+		}
+		if kid.Object != nil {
+			objr := core.MakeDict()
+			objr.Set("Type", core.MakeName("OBJR"))
+			if kid.Page != nil {
+				objr.Set("Pg", kid.Page.GetPageAsIndirectObject())
+			}
+			objr.Set("Obj", kid.Object)
+			k.Append(objr)
+			continue
+		}
+		if kid.Page == nil || kid.MCID < 0 {
+			return nil, errors.New("invalid marked content reference")
+		}
+
+		pageObj := kid.Page.GetPageAsIndirectObject()
+		if page == nil {
+			page = pageObj
+			dict.Set("Pg", page)
+		}
+		if pageObj == page {
+			k.Append(core.MakeInteger(int64(kid.MCID)))
+		} else {
+			mcr := core.MakeDict()
+			mcr.Set("Type", core.MakeName("MCR"))
+			mcr.Set("Pg", pageObj)
+			mcr.Set("MCID", core.MakeInteger(int64(kid.MCID)))
+			k.Append(mcr)
+		}
+
+		parents := tw.parents[pageObj]
+		for len(parents) <= kid.MCID {
+			parents = append(parents, core.MakeNull())
+		}
+		parents[kid.MCID] = container
+		tw.parents[pageObj] = parents
+	}
+
+	switch k.Len() {
+	case 0:
+	case 1:
+		dict.Set("K", k.Get(0))
+	default:
+		dict.Set("K", k)
+	}
+	return container, nil
+}
+
+// GetStructTreeRoot returns the structure tree of a tagged document, or nil if the document has no
+// structure tree.
+func (r *PdfReader) GetStructTreeRoot() (*PdfStructTreeRoot, error) {
+	obj := r.catalog.Get("StructTreeRoot")
+	if obj == nil {
+		return nil, nil
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: StructTreeRoot not a dictionary (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+
+	root := &PdfStructTreeRoot{}
+	if roleMap, ok := core.GetDict(dict.Get("RoleMap")); ok {
+		root.RoleMap = map[string]string{}
+		for _, key := range roleMap.Keys() {
+			if s, ok := core.GetNameVal(roleMap.Get(key)); ok {
+				root.RoleMap[string(key)] = s
+			}
+		}
+	}
+
+	sr := &structTreeReader{r: r, visited: map[*core.PdfObjectDictionary]bool{}}
+	for _, kid := range structKidObjects(dict.Get("K")) {
+		kidDict, ok := core.GetDict(kid)
+		if !ok {
+			continue
+		}
+		e, err := sr.newPdfStructElementFromDict(kidDict, nil)
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			root.Kids = append(root.Kids, e)
+		}
+	}
+	return root, nil
+}
+
+// structTreeReader loads the structure elements of a structure tree.
+type structTreeReader struct {
+	r       *PdfReader
+	visited map[*core.PdfObjectDictionary]bool
+}
+
+// structKidObjects returns the kids of a K entry, which is either a single kid or an array.
+func structKidObjects(obj core.PdfObject) []core.PdfObject {
+	if arr, ok := core.GetArray(obj); ok {
+		return arr.Elements()
+	}
+	if obj == nil {
+		return nil
+	}
+	return []core.PdfObject{obj}
+}
+
+// newPdfStructElementFromDict loads the structure element `dict` with parent `parent`. Returns nil
+// if the dictionary was already loaded (loop in the structure tree).
+func (sr *structTreeReader) newPdfStructElementFromDict(dict *core.PdfObjectDictionary, parent *PdfStructElement) (*PdfStructElement, error) {
+	if sr.visited[dict] {
+		common.Log.Debug("ERROR: Loop in structure tree")
+		return nil, nil
+	}
+	sr.visited[dict] = true
+
+	s, ok := core.GetNameVal(dict.Get("S"))
+	if !ok {
+		common.Log.Debug("ERROR: Structure element missing S")
+		return nil, core.ErrTypeError
+	}
+	e := &PdfStructElement{S: s, Parent: parent}
+	if str, ok := core.GetString(dict.Get("ID")); ok {
+		e.ID = str.Str()
+	}
+	if str, ok := core.GetString(dict.Get("T")); ok {
+		e.T = str.Decoded()
+	}
+	if str, ok := core.GetString(dict.Get("Lang")); ok {
+		e.Lang = str.Decoded()
+	}
+	if str, ok := core.GetString(dict.Get("Alt")); ok {
+		e.Alt = str.Decoded()
+	}
+	if str, ok := core.GetString(dict.Get("ActualText")); ok {
+		e.ActualText = str.Decoded()
+	}
+
+	// Attribute objects may be followed by revision numbers, which are ignored.
+	for _, obj := range structKidObjects(dict.Get("A")) {
+		attrDict, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		attrs := &PdfStructAttributes{Values: map[string]core.PdfObject{}}
+		for _, key := range attrDict.Keys() {
+			if key == "O" {
+				attrs.Owner, _ = core.GetNameVal(attrDict.Get(key))
+				continue
+			}
+			attrs.Values[string(key)] = attrDict.Get(key)
+		}
+		e.Attributes = append(e.Attributes, attrs)
+	}
+
+	page := sr.page(dict.Get("Pg"))
+	for _, obj := range structKidObjects(dict.Get("K")) {
+		if mcid, ok := core.GetIntVal(obj); ok {
+			if page == nil {
+				common.Log.Debug("ERROR: Marked content without page (MCID %d)", mcid)
+				continue
+			}
+			e.AddMarkedContent(page, mcid)
+			continue
+		}
+
+		kidDict, ok := core.GetDict(obj)
+		if !ok {
+			common.Log.Debug("ERROR: Invalid structure element kid (%T)", obj)
+			continue
+		}
+		kidPage := page
+		if pg := kidDict.Get("Pg"); pg != nil {
+			kidPage = sr.page(pg)
+		}
+
+		switch typ, _ := core.GetNameVal(kidDict.Get("Type")); typ {
+		case "MCR":
+			mcid, ok := core.GetIntVal(kidDict.Get("MCID"))
+			if !ok || kidPage == nil {
+				common.Log.Debug("ERROR: Invalid marked-content reference")
+				continue
+			}
+			if kidDict.Get("Stm") != nil {
+				// Marked content of other content streams is not supported.
+				common.Log.Debug("Marked-content reference to a content stream not supported")
+				continue
+			}
+			e.AddMarkedContent(kidPage, mcid)
+		case "OBJR":
+			e.Kids = append(e.Kids, &PdfStructKid{Object: kidDict.Get("Obj"), Page: kidPage})
+		default:
+			kid, err := sr.newPdfStructElementFromDict(kidDict, e)
+			if err != nil {
+				return nil, err
+			}
+			if kid != nil {
+				e.Kids = append(e.Kids, &PdfStructKid{Element: kid})
+			}
+		}
+	}
+	return e, nil
+}
+
+// page returns the page of the page object `obj`, or nil if not a page of the document.
+func (sr *structTreeReader) page(obj core.PdfObject) *PdfPage {
+	var objNum int64
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		objNum = t.ObjectNumber
+	case *core.PdfIndirectObject:
+		objNum = t.ObjectNumber
+	default:
+		return nil
+	}
+	for i, pageObj := range sr.r.pageList {
+		if pageObj.ObjectNumber == objNum && i < len(sr.r.PageList) {
+			return sr.r.PageList[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+func TestWriterStructTree(t *testing.T) {
+	w := model.NewPdfWriter()
+	var pages []*model.PdfPage
+	for i := 0; i < 3; i++ {
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+		require.NoError(t, w.AddPage(page))
+		pages = append(pages, page)
+	}
+
+	// A paragraph continued from the first to the third page.
+	doc := model.NewPdfStructElement("Document")
+	h := model.NewPdfStructElement("H1")
+	h.AddMarkedContent(pages[0], 0)
+	doc.AddKid(h)
+	p := model.NewPdfStructElement("P")
+	p.Lang = "en-US"
+	p.AddMarkedContent(pages[0], 1)
+	p.AddMarkedContent(pages[2], 0)
+	doc.AddKid(p)
+	w.SetStructTreeRoot(&model.PdfStructTreeRoot{
+		Kids:    []*model.PdfStructElement{doc},
+		RoleMap: map[string]string{"Title": "H1"},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	catalog, ok := core.GetDict(trailer.Get("Root"))
+	require.True(t, ok)
+	root, ok := core.GetDict(catalog.Get("StructTreeRoot"))
+	require.True(t, ok)
+	next, ok := core.GetIntVal(root.Get("ParentTreeNextKey"))
+	require.True(t, ok)
+	require.Equal(t, 2, next)
+	roleMap, ok := core.GetDict(root.Get("RoleMap"))
+	require.True(t, ok)
+	role, _ := core.GetNameVal(roleMap.Get("Title"))
+	require.Equal(t, "H1", role)
+
+	// Pages with marked content are numbered in page order.
+	for i, want := range []int{0, -1, 1} {
+		page, err := reader.GetPage(i + 1)
+		require.NoError(t, err)
+		key, ok := core.GetIntVal(page.GetPageDict().Get("StructParents"))
+		if want < 0 {
+			require.False(t, ok)
+			continue
+		}
+		require.True(t, ok)
+		require.Equal(t, want, key)
+	}
+
+	parentTree, err := model.NewPdfNumberTreeFromObject(root.Get("ParentTree"))
+	require.NoError(t, err)
+	obj, has := parentTree.Get(0)
+	require.True(t, has)
+	parents, ok := core.GetArray(obj)
+	require.True(t, ok)
+	require.Equal(t, 2, parents.Len())
+	pDict, ok := core.GetDict(parents.Get(1))
+	require.True(t, ok)
+	s, _ := core.GetNameVal(pDict.Get("S"))
+	require.Equal(t, "P", s)
+
+	// The marked content on the third page is referenced by a marked-content reference.
+	k, ok := core.GetArray(pDict.Get("K"))
+	require.True(t, ok)
+	require.Equal(t, 2, k.Len())
+	mcid, ok := core.GetIntVal(k.Get(0))
+	require.True(t, ok)
+	require.Equal(t, 1, mcid)
+	mcr, ok := core.GetDict(k.Get(1))
+	require.True(t, ok)
+	mcid, ok = core.GetIntVal(mcr.Get("MCID"))
+	require.True(t, ok)
+	require.Equal(t, 0, mcid)
+
+	obj, has = parentTree.Get(1)
+	require.True(t, has)
+	parents, ok = core.GetArray(obj)
+	require.True(t, ok)
+	require.Equal(t, 1, parents.Len())
+}
+
+func TestWriterStructTreePageNotAdded(t *testing.T) {
+	w := model.NewPdfWriter()
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+	require.NoError(t, w.AddPage(page))
+
+	p := model.NewPdfStructElement("P")
+	p.AddMarkedContent(model.NewPdfPage(), 0)
+	w.SetStructTreeRoot(&model.PdfStructTreeRoot{Kids: []*model.PdfStructElement{p}})
+
+	var buf bytes.Buffer
+	require.Error(t, w.Write(&buf))
+}
+
+func TestReaderStructTree(t *testing.T) {
+	w := model.NewPdfWriter()
+	var pages []*model.PdfPage
+	for i := 0; i < 2; i++ {
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+		require.NoError(t, w.AddPage(page))
+		pages = append(pages, page)
+	}
+
+	doc := model.NewPdfStructElement("Document")
+	doc.Lang = "en-US"
+	title := model.NewPdfStructElement("Title")
+	title.ID = "title1"
+	title.T = "Titel über"
+	title.AddMarkedContent(pages[0], 0)
+	doc.AddKid(title)
+	figure := model.NewPdfStructElement("Figure")
+	figure.Alt = "Company logo"
+	figure.Attributes = []*model.PdfStructAttributes{{
+		Owner:  "Layout",
+		Values: map[string]core.PdfObject{"Placement": core.MakeName("Block")},
+	}}
+	figure.AddMarkedContent(pages[0], 1)
+	figure.AddMarkedContent(pages[1], 0)
+	doc.AddKid(figure)
+	link := model.NewPdfStructElement("Link")
+	link.ActualText = "example.com"
+	link.Kids = append(link.Kids, &model.PdfStructKid{Object: core.MakeIndirectObject(core.MakeDict()), Page: pages[1]})
+	doc.AddKid(link)
+	w.SetStructTreeRoot(&model.PdfStructTreeRoot{
+		Kids:    []*model.PdfStructElement{doc},
+		RoleMap: map[string]string{"Title": "Heading", "Heading": "H1"},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	root, err := reader.GetStructTreeRoot()
+	require.NoError(t, err)
+	require.NotNil(t, root)
+	require.Equal(t, "H1", root.StandardType("Title"))
+	require.Equal(t, "P", root.StandardType("P"))
+
+	require.Len(t, root.Kids, 1)
+	doc = root.Kids[0]
+	require.Equal(t, "Document", doc.S)
+	require.Equal(t, "en-US", doc.Lang)
+	require.Len(t, doc.Kids, 3)
+
+	title = doc.Kids[0].Element
+	require.Equal(t, doc, title.Parent)
+	require.Equal(t, "title1", title.ID)
+	require.Equal(t, "Titel über", title.T)
+	require.Len(t, title.Kids, 1)
+	require.Equal(t, reader.PageList[0], title.Kids[0].Page)
+	require.Equal(t, 0, title.Kids[0].MCID)
+
+	figure = doc.Kids[1].Element
+	require.Equal(t, "Company logo", figure.Alt)
+	require.Len(t, figure.Attributes, 1)
+	require.Equal(t, "Layout", figure.Attributes[0].Owner)
+	placement, _ := core.GetNameVal(figure.Attributes[0].Values["Placement"])
+	require.Equal(t, "Block", placement)
+	require.Len(t, figure.Kids, 2)
+	require.Equal(t, reader.PageList[0], figure.Kids[0].Page)
+	require.Equal(t, 1, figure.Kids[0].MCID)
+	require.Equal(t, reader.PageList[1], figure.Kids[1].Page)
+	require.Equal(t, 0, figure.Kids[1].MCID)
+
+	link = doc.Kids[2].Element
+	require.Equal(t, "example.com", link.ActualText)
+	require.Len(t, link.Kids, 1)
+	require.NotNil(t, link.Kids[0].Object)
+	require.Equal(t, reader.PageList[1], link.Kids[0].Page)
+}
+
+func TestReaderStructTreeUntagged(t *testing.T) {
+	w := model.NewPdfWriter()
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+	require.NoError(t, w.AddPage(page))
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	root, err := reader.GetStructTreeRoot()
+	require.NoError(t, err)
+	require.Nil(t, root)
+}
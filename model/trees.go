@@ -0,0 +1,521 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// DefaultTreeNodeSize is the default maximum number of entries of the leaf nodes and of kids of
+// the intermediate nodes of the trees built by PdfNameTreeBuilder and PdfNumberTreeBuilder.
+const DefaultTreeNodeSize = 32
+
+// PdfNameTree provides read access to a name tree (Section 7.9.6). The nodes of the tree are
+// resolved lazily while traversing it, e.g. Get only loads the nodes whose Limits include the key.
+//
+// The keys of a name tree are strings, which are ordered by their raw bytes. The keys used by
+// PdfNameTree are the raw bytes of the strings. Use core.MakeEncodedString(s, true).Str() to
+// look up text strings encoded as UTF-16BE.
+type PdfNameTree struct {
+	tree pdfTree
+}
+
+// PdfNumberTree provides read access to a number tree (Section 7.9.7). The nodes of the tree are
+// resolved lazily while traversing it, e.g. Get only loads the nodes whose Limits include the key.
+type PdfNumberTree struct {
+	tree pdfTree
+}
+
+// NewPdfNameTreeFromObject returns the name tree with root node `obj`.
+func NewPdfNameTreeFromObject(obj core.PdfObject) (*PdfNameTree, error) {
+	if _, ok := core.GetDict(obj); !ok {
+		common.Log.Debug("ERROR: Name tree root not a dictionary (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+	return &PdfNameTree{tree: pdfTree{root: obj, leafKey: "Names"}}, nil
+}
+
+// NewPdfNumberTreeFromObject returns the number tree with root node `obj`.
+func NewPdfNumberTreeFromObject(obj core.PdfObject) (*PdfNumberTree, error) {
+	if _, ok := core.GetDict(obj); !ok {
+		common.Log.Debug("ERROR: Number tree root not a dictionary (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+	return &PdfNumberTree{tree: pdfTree{root: obj, leafKey: "Nums"}}, nil
+}
+
+// GetContainingPdfObject returns the root node of the tree.
+func (t *PdfNameTree) GetContainingPdfObject() core.PdfObject {
+	return t.tree.root
+}
+
+// Get returns the value of `key`. The returned bool is false if the tree has no entry for `key`.
+func (t *PdfNameTree) Get(key string) (core.PdfObject, bool) {
+	return t.tree.find(func(obj core.PdfObject) (int, bool) {
+		k, ok := core.GetStringVal(obj)
+		return strings.Compare(k, key), ok
+	})
+}
+
+// Walk calls `f` for each entry of the tree, in the order of the tree. The traversal stops when
+// `f` returns an error, which is returned by Walk.
+func (t *PdfNameTree) Walk(f func(key string, value core.PdfObject) error) error {
+	return t.tree.walk(func(keyObj, value core.PdfObject) error {
+		key, ok := core.GetStringVal(keyObj)
+		if !ok {
+			common.Log.Debug("ERROR: Invalid name tree key (%T)", keyObj)
+			return nil
+		}
+		return f(key, value)
+	})
+}
+
+// Keys returns the keys of the tree, in the order of the tree.
+func (t *PdfNameTree) Keys() []string {
+	var keys []string
+	t.Walk(func(key string, _ core.PdfObject) error {
+		keys = append(keys, key)
+		return nil
+	})
+	return keys
+}
+
+// GetContainingPdfObject returns the root node of the tree.
+func (t *PdfNumberTree) GetContainingPdfObject() core.PdfObject {
+	return t.tree.root
+}
+
+// Get returns the value of `key`. The returned bool is false if the tree has no entry for `key`.
+func (t *PdfNumberTree) Get(key int) (core.PdfObject, bool) {
+	return t.tree.find(func(obj core.PdfObject) (int, bool) {
+		k, ok := core.GetIntVal(obj)
+		switch {
+		case k < key:
+			return -1, ok
+		case k > key:
+			return 1, ok
+		}
+		return 0, ok
+	})
+}
+
+// Walk calls `f` for each entry of the tree, in the order of the tree. The traversal stops when
+// `f` returns an error, which is returned by Walk.
+func (t *PdfNumberTree) Walk(f func(key int, value core.PdfObject) error) error {
+	return t.tree.walk(func(keyObj, value core.PdfObject) error {
+		key, ok := core.GetIntVal(keyObj)
+		if !ok {
+			common.Log.Debug("ERROR: Invalid number tree key (%T)", keyObj)
+			return nil
+		}
+		return f(key, value)
+	})
+}
+
+// Keys returns the keys of the tree, in the order of the tree.
+func (t *PdfNumberTree) Keys() []int {
+	var keys []int
+	t.Walk(func(key int, _ core.PdfObject) error {
+		keys = append(keys, key)
+		return nil
+	})
+	return keys
+}
+
+// pdfTree implements the traversal of name and number trees, which only differ in the key type
+// and the name of the array holding the entries of the leaf nodes.
+type pdfTree struct {
+	root    core.PdfObject
+	leafKey core.PdfObjectName // Names or Nums.
+}
+
+// walk calls `f` for the key and value of each entry of the tree.
+func (t pdfTree) walk(f func(key, value core.PdfObject) error) error {
+	visited := map[*core.PdfObjectDictionary]struct{}{}
+
+	var walk func(obj core.PdfObject) error
+	walk = func(obj core.PdfObject) error {
+		node, ok := core.GetDict(obj)
+		if !ok {
+			common.Log.Debug("ERROR: Invalid tree node (%T)", obj)
+			return nil
+		}
+		if _, seen := visited[node]; seen {
+			common.Log.Debug("ERROR: Tree loop detected")
+			return nil
+		}
+		visited[node] = struct{}{}
+
+		if entries, ok := core.GetArray(node.Get(t.leafKey)); ok {
+			elements := entries.Elements()
+			for i := 0; i+1 < len(elements); i += 2 {
+				if err := f(elements[i], elements[i+1]); err != nil {
+					return err
+				}
+			}
+		}
+		if kids, ok := core.GetArray(node.Get("Kids")); ok {
+			for _, kid := range kids.Elements() {
+				if err := walk(kid); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(t.root)
+}
+
+// find returns the value of the entry whose key compares equal to the searched key. `cmp` returns
+// the sign of the comparison of a key of the tree to the searched key, and false if the key is
+// invalid. Kids whose Limits exclude the searched key are not visited.
+func (t pdfTree) find(cmp func(key core.PdfObject) (int, bool)) (core.PdfObject, bool) {
+	visited := map[*core.PdfObjectDictionary]struct{}{}
+
+	var find func(obj core.PdfObject) (core.PdfObject, bool)
+	find = func(obj core.PdfObject) (core.PdfObject, bool) {
+		node, ok := core.GetDict(obj)
+		if !ok {
+			return nil, false
+		}
+		if _, seen := visited[node]; seen {
+			common.Log.Debug("ERROR: Tree loop detected")
+			return nil, false
+		}
+		visited[node] = struct{}{}
+
+		if entries, ok := core.GetArray(node.Get(t.leafKey)); ok {
+			elements := entries.Elements()
+			for i := 0; i+1 < len(elements); i += 2 {
+				if c, ok := cmp(elements[i]); ok && c == 0 {
+					return elements[i+1], true
+				}
+			}
+		}
+		kids, ok := core.GetArray(node.Get("Kids"))
+		if !ok {
+			return nil, false
+		}
+		for _, kid := range kids.Elements() {
+			// Kids without valid Limits are searched as well.
+			if kidDict, ok := core.GetDict(kid); ok {
+				if limits, ok := core.GetArray(kidDict.Get("Limits")); ok && limits.Len() == 2 {
+					lo, okLo := cmp(limits.Get(0))
+					hi, okHi := cmp(limits.Get(1))
+					if okLo && okHi && (lo > 0 || hi < 0) {
+						continue
+					}
+				}
+			}
+			if value, found := find(kid); found {
+				return value, true
+			}
+		}
+		return nil, false
+	}
+	return find(t.root)
+}
+
+// PdfNameTreeBuilder builds balanced name trees.
+type PdfNameTreeBuilder struct {
+	// MaxNodeSize is the maximum number of entries of a leaf node and of kids of an intermediate
+	// node. DefaultTreeNodeSize is used when less than 2.
+	MaxNodeSize int
+
+	entries map[string]core.PdfObject
+}
+
+// PdfNumberTreeBuilder builds balanced number trees.
+type PdfNumberTreeBuilder struct {
+	// MaxNodeSize is the maximum number of entries of a leaf node and of kids of an intermediate
+	// node. DefaultTreeNodeSize is used when less than 2.
+	MaxNodeSize int
+
+	entries map[int]core.PdfObject
+}
+
+// NewPdfNameTreeBuilder returns a builder of an empty name tree.
+func NewPdfNameTreeBuilder() *PdfNameTreeBuilder {
+	return &PdfNameTreeBuilder{entries: map[string]core.PdfObject{}}
+}
+
+// NewPdfNameTreeBuilderFromTree returns a builder with the entries of `tree`.
+func NewPdfNameTreeBuilderFromTree(tree *PdfNameTree) *PdfNameTreeBuilder {
+	b := NewPdfNameTreeBuilder()
+	tree.Walk(func(key string, value core.PdfObject) error {
+		if _, has := b.entries[key]; !has {
+			b.entries[key] = value
+		}
+		return nil
+	})
+	return b
+}
+
+// Set sets the value of `key` to `value`. The key is the raw bytes of the key string.
+func (b *PdfNameTreeBuilder) Set(key string, value core.PdfObject) {
+	b.entries[key] = value
+}
+
+// Get returns the value of `key`. The returned bool is false if there is no entry for `key`.
+func (b *PdfNameTreeBuilder) Get(key string) (core.PdfObject, bool) {
+	value, has := b.entries[key]
+	return value, has
+}
+
+// Remove removes the entry of `key`.
+func (b *PdfNameTreeBuilder) Remove(key string) {
+	delete(b.entries, key)
+}
+
+// Len returns the number of entries.
+func (b *PdfNameTreeBuilder) Len() int {
+	return len(b.entries)
+}
+
+// ToPdfObject builds the tree and returns its root node.
+func (b *PdfNameTreeBuilder) ToPdfObject() *core.PdfIndirectObject {
+	keys := make([]string, 0, len(b.entries))
+	for key := range b.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]core.PdfObject, 0, 2*len(keys))
+	for _, key := range keys {
+		entries = append(entries, core.MakeString(key), b.entries[key])
+	}
+	return buildTree(entries, "Names", b.MaxNodeSize)
+}
+
+// NewPdfNumberTreeBuilder returns a builder of an empty number tree.
+func NewPdfNumberTreeBuilder() *PdfNumberTreeBuilder {
+	return &PdfNumberTreeBuilder{entries: map[int]core.PdfObject{}}
+}
+
+// NewPdfNumberTreeBuilderFromTree returns a builder with the entries of `tree`.
+func NewPdfNumberTreeBuilderFromTree(tree *PdfNumberTree) *PdfNumberTreeBuilder {
+	b := NewPdfNumberTreeBuilder()
+	tree.Walk(func(key int, value core.PdfObject) error {
+		if _, has := b.entries[key]; !has {
+			b.entries[key] = value
+		}
+		return nil
+	})
+	return b
+}
+
+// Set sets the value of `key` to `value`.
+func (b *PdfNumberTreeBuilder) Set(key int, value core.PdfObject) {
+	b.entries[key] = value
+}
+
+// Get returns the value of `key`. The returned bool is false if there is no entry for `key`.
+func (b *PdfNumberTreeBuilder) Get(key int) (core.PdfObject, bool) {
+	value, has := b.entries[key]
+	return value, has
+}
+
+// Remove removes the entry of `key`.
+func (b *PdfNumberTreeBuilder) Remove(key int) {
+	delete(b.entries, key)
+}
+
+// Len returns the number of entries.
+func (b *PdfNumberTreeBuilder) Len() int {
+	return len(b.entries)
+}
+
+// ToPdfObject builds the tree and returns its root node.
+func (b *PdfNumberTreeBuilder) ToPdfObject() *core.PdfIndirectObject {
+	keys := make([]int, 0, len(b.entries))
+	for key := range b.entries {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	entries := make([]core.PdfObject, 0, 2*len(keys))
+	for _, key := range keys {
+		entries = append(entries, core.MakeInteger(int64(key)), b.entries[key])
+	}
+	return buildTree(entries, "Nums", b.MaxNodeSize)
+}
+
+// buildTree builds a balanced tree from the sorted key-value pairs `entries` and returns the root
+// node. The leaf nodes hold the entries in the `leafKey` array. All leaf nodes are at the same
+// depth and each node has at most `maxSize` entries or kids.
+func buildTree(entries []core.PdfObject, leafKey core.PdfObjectName, maxSize int) *core.PdfIndirectObject {
+	if maxSize < 2 {
+		maxSize = DefaultTreeNodeSize
+	}
+
+	// node is a node of the tree with the lowest and highest keys of its entries.
+	type node struct {
+		obj    *core.PdfIndirectObject
+		lo, hi core.PdfObject
+	}
+
+	n := len(entries) / 2
+	if n <= maxSize {
+		root := core.MakeDict()
+		root.Set(leafKey, core.MakeArray(entries...))
+		return core.MakeIndirectObject(root)
+	}
+
+	var level []node
+	for _, r := range splitEvenly(n, maxSize) {
+		leaf := entries[2*r[0] : 2*r[1]]
+		lo, hi := leaf[0], leaf[len(leaf)-2]
+		dict := core.MakeDict()
+		dict.Set(leafKey, core.MakeArray(leaf...))
+		dict.Set("Limits", core.MakeArray(lo, hi))
+		level = append(level, node{obj: core.MakeIndirectObject(dict), lo: lo, hi: hi})
+	}
+
+	for len(level) > maxSize {
+		var parents []node
+		for _, r := range splitEvenly(len(level), maxSize) {
+			kids := core.MakeArray()
+			for _, kid := range level[r[0]:r[1]] {
+				kids.Append(kid.obj)
+			}
+			lo, hi := level[r[0]].lo, level[r[1]-1].hi
+			dict := core.MakeDict()
+			dict.Set("Kids", kids)
+			dict.Set("Limits", core.MakeArray(lo, hi))
+			parents = append(parents, node{obj: core.MakeIndirectObject(dict), lo: lo, hi: hi})
+		}
+		level = parents
+	}
+
+	kids := core.MakeArray()
+	for _, kid := range level {
+		kids.Append(kid.obj)
+	}
+	root := core.MakeDict()
+	root.Set("Kids", kids)
+	return core.MakeIndirectObject(root)
+}
+
+// splitEvenly splits the range [0, n) into the smallest number of consecutive ranges of at most
+// `maxSize` elements, with sizes that differ by at most one. Returns the [start, end) pairs.
+func splitEvenly(n, maxSize int) [][2]int {
+	count := (n + maxSize - 1) / maxSize
+	ranges := make([][2]int, count)
+	start := 0
+	for i := range ranges {
+		size := n / count
+		if i < n%count {
+			size++
+		}
+		ranges[i] = [2]int{start, start + size}
+		start += size
+	}
+	return ranges
+}
+
+// catalogNames holds the name trees of the names dictionary of the catalog (Section 7.7.4) and
+// the associated files added by a PdfWriter or a PdfAppender.
+type catalogNames struct {
+	trees map[core.PdfObjectName]*PdfNameTreeBuilder
+	order []core.PdfObjectName
+	files []*PdfFilespec
+}
+
+// tree returns the builder of name tree `name`. A new builder is initialized with the entries of
+// the tree in the names dictionary of `catalog`.
+func (n *catalogNames) tree(catalog *core.PdfObjectDictionary, name core.PdfObjectName) *PdfNameTreeBuilder {
+	if b, has := n.trees[name]; has {
+		return b
+	}
+	b := NewPdfNameTreeBuilder()
+	if names, ok := core.GetDict(catalog.Get("Names")); ok {
+		if tree, err := NewPdfNameTreeFromObject(names.Get(name)); err == nil {
+			b = NewPdfNameTreeBuilderFromTree(tree)
+		}
+	}
+	n.setTree(name, b)
+	return b
+}
+
+// setTree sets the builder of name tree `name` to `b`.
+func (n *catalogNames) setTree(name core.PdfObjectName, b *PdfNameTreeBuilder) {
+	if n.trees == nil {
+		n.trees = map[core.PdfObjectName]*PdfNameTreeBuilder{}
+	}
+	if _, has := n.trees[name]; !has {
+		n.order = append(n.order, name)
+	}
+	n.trees[name] = b
+}
+
+// attachFile adds `fs` to the EmbeddedFiles name tree, which is initialized from `catalog`.
+func (n *catalogNames) attachFile(catalog *core.PdfObjectDictionary, fs *PdfFilespec) error {
+	if fs == nil || fs.EmbeddedFile == nil {
+		return errors.New("file specification without embedded file")
+	}
+	key := core.MakeEncodedString(fs.FileName, !isPDFDocEncodable(fs.FileName)).Str()
+	tree := n.tree(catalog, "EmbeddedFiles")
+	if _, has := tree.Get(key); has {
+		common.Log.Debug("ERROR: Duplicate embedded file name %q", fs.FileName)
+		return errors.New("duplicate embedded file name")
+	}
+	if fs.container == nil {
+		fs.container = core.MakeIndirectObject(core.MakeDict())
+	}
+	tree.Set(key, fs.container)
+	n.files = append(n.files, fs)
+	return nil
+}
+
+// apply sets the Names and AF entries of `catalog` to the names dictionary and associated files
+// array of `orig` updated with the name trees and files of `n`. Returns the objects that were set.
+func (n *catalogNames) apply(orig, catalog *core.PdfObjectDictionary) []core.PdfObject {
+	if len(n.trees) == 0 {
+		return nil
+	}
+
+	names := core.MakeDict()
+	if existing, ok := core.GetDict(orig.Get("Names")); ok {
+		names.Merge(existing)
+	}
+	for _, name := range n.order {
+		names.Set(name, n.trees[name].ToPdfObject())
+	}
+	catalog.Set("Names", names)
+	objects := []core.PdfObject{names}
+
+	af := core.MakeArray()
+	if existing, ok := core.GetArray(orig.Get("AF")); ok {
+		af.Append(existing.Elements()...)
+	}
+	for _, fs := range n.files {
+		obj := fs.ToPdfObject()
+		if fs.AFRelationship != "" {
+			af.Append(obj)
+		}
+	}
+	if af.Len() > 0 {
+		catalog.Set("AF", af)
+		objects = append(objects, af)
+	}
+	return objects
+}
+
+// isPDFDocEncodable returns true if `s` only contains printable ASCII characters, which are
+// encoded identically in PDFDocEncoding.
+func isPDFDocEncodable(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
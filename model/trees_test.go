@@ -0,0 +1,188 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// treeDepths returns the depths of the leaf nodes of the tree with root `obj` and checks that the
+// nodes have at most `maxSize` entries or kids.
+func treeDepths(t *testing.T, obj core.PdfObject, leafKey core.PdfObjectName, maxSize, depth int) []int {
+	node, ok := core.GetDict(obj)
+	require.True(t, ok)
+	if kids, ok := core.GetArray(node.Get("Kids")); ok {
+		require.True(t, kids.Len() <= maxSize)
+		var depths []int
+		for _, kid := range kids.Elements() {
+			_, isIndirect := kid.(*core.PdfIndirectObject)
+			require.True(t, isIndirect)
+			kidDict, _ := core.GetDict(kid)
+			require.NotNil(t, kidDict.Get("Limits"))
+			depths = append(depths, treeDepths(t, kid, leafKey, maxSize, depth+1)...)
+		}
+		return depths
+	}
+	entries, ok := core.GetArray(node.Get(leafKey))
+	require.True(t, ok)
+	require.True(t, entries.Len() <= 2*maxSize)
+	return []int{depth}
+}
+
+func TestNameTreeBuilder(t *testing.T) {
+	b := model.NewPdfNameTreeBuilder()
+	b.MaxNodeSize = 4
+	for i := 99; i >= 0; i-- {
+		b.Set(fmt.Sprintf("key%03d", i), core.MakeInteger(int64(i)))
+	}
+	b.Set("key050", core.MakeInteger(-50))
+	b.Remove("key099")
+	require.Equal(t, 99, b.Len())
+
+	root := b.ToPdfObject()
+	require.Nil(t, root.PdfObject.(*core.PdfObjectDictionary).Get("Limits"))
+
+	// All leaves are at the same depth.
+	depths := treeDepths(t, root, "Names", 4, 0)
+	for _, d := range depths {
+		require.Equal(t, depths[0], d)
+	}
+	require.Equal(t, 3, depths[0])
+
+	tree, err := model.NewPdfNameTreeFromObject(root)
+	require.NoError(t, err)
+	keys := tree.Keys()
+	require.Len(t, keys, 99)
+	for i, key := range keys {
+		require.Equal(t, fmt.Sprintf("key%03d", i), key)
+	}
+
+	val, found := tree.Get("key007")
+	require.True(t, found)
+	intVal, _ := core.GetIntVal(val)
+	require.Equal(t, 7, intVal)
+	val, found = tree.Get("key050")
+	require.True(t, found)
+	intVal, _ = core.GetIntVal(val)
+	require.Equal(t, -50, intVal)
+	_, found = tree.Get("key099")
+	require.False(t, found)
+	_, found = tree.Get("abc")
+	require.False(t, found)
+
+	// Rebuilding from the tree keeps the entries.
+	rebuilt := model.NewPdfNameTreeBuilderFromTree(tree)
+	require.Equal(t, 99, rebuilt.Len())
+}
+
+func TestNameTreeSingleLeaf(t *testing.T) {
+	b := model.NewPdfNameTreeBuilder()
+	b.Set("b", core.MakeInteger(2))
+	b.Set("a", core.MakeInteger(1))
+	root := b.ToPdfObject()
+	dict := root.PdfObject.(*core.PdfObjectDictionary)
+	require.Nil(t, dict.Get("Kids"))
+	require.Equal(t, "[(a) 1 (b) 2]", dict.Get("Names").WriteString())
+}
+
+func TestNumberTreeBuilder(t *testing.T) {
+	b := model.NewPdfNumberTreeBuilder()
+	b.MaxNodeSize = 3
+	for i := 0; i < 50; i++ {
+		b.Set(i*10, core.MakeInteger(int64(i)))
+	}
+
+	root := b.ToPdfObject()
+	depths := treeDepths(t, root, "Nums", 3, 0)
+	for _, d := range depths {
+		require.Equal(t, depths[0], d)
+	}
+
+	tree, err := model.NewPdfNumberTreeFromObject(root)
+	require.NoError(t, err)
+	keys := tree.Keys()
+	require.Len(t, keys, 50)
+	require.Equal(t, 0, keys[0])
+	require.Equal(t, 490, keys[49])
+
+	val, found := tree.Get(120)
+	require.True(t, found)
+	intVal, _ := core.GetIntVal(val)
+	require.Equal(t, 12, intVal)
+	_, found = tree.Get(125)
+	require.False(t, found)
+	_, found = tree.Get(-1)
+	require.False(t, found)
+}
+
+// Kids without Limits are searched as well.
+func TestNumberTreeMissingLimits(t *testing.T) {
+	leaf1 := core.MakeDict()
+	leaf1.Set("Nums", core.MakeArray(core.MakeInteger(1), core.MakeName("a")))
+	leaf2 := core.MakeDict()
+	leaf2.Set("Nums", core.MakeArray(core.MakeInteger(5), core.MakeName("b")))
+	leaf2.Set("Limits", core.MakeArray(core.MakeInteger(5), core.MakeInteger(5)))
+	root := core.MakeDict()
+	root.Set("Kids", core.MakeArray(core.MakeIndirectObject(leaf1), core.MakeIndirectObject(leaf2)))
+
+	tree, err := model.NewPdfNumberTreeFromObject(root)
+	require.NoError(t, err)
+	val, found := tree.Get(1)
+	require.True(t, found)
+	require.Equal(t, "/a", val.WriteString())
+	val, found = tree.Get(5)
+	require.True(t, found)
+	require.Equal(t, "/b", val.WriteString())
+}
+
+func TestWriterSetNameTree(t *testing.T) {
+	js := model.NewPdfNameTreeBuilder()
+	js.MaxNodeSize = 2
+	for i := 0; i < 5; i++ {
+		action := core.MakeDict()
+		action.Set("S", core.MakeName("JavaScript"))
+		action.Set("JS", core.MakeString(fmt.Sprintf("app.alert(%d);", i)))
+		js.Set(fmt.Sprintf("script%d", i), action)
+	}
+
+	w := model.NewPdfWriter()
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+	require.NoError(t, w.AddPage(page))
+	w.SetNameTree("JavaScript", js)
+	require.NoError(t, w.AttachFile(model.NewPdfFilespec("a.txt", []byte("a"))))
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	tree, err := reader.GetNameTree("JavaScript")
+	require.NoError(t, err)
+	require.NotNil(t, tree)
+	require.Equal(t, []string{"script0", "script1", "script2", "script3", "script4"}, tree.Keys())
+	val, found := tree.Get("script3")
+	require.True(t, found)
+	action, ok := core.GetDict(val)
+	require.True(t, ok)
+	jsStr, _ := core.GetStringVal(action.Get("JS"))
+	require.Equal(t, "app.alert(3);", jsStr)
+
+	files, err := reader.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	tree, err = reader.GetNameTree("Dests")
+	require.NoError(t, err)
+	require.Nil(t, tree)
+}
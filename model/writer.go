@@ -25,6 +25,7 @@ import (
 	"github.com/unidoc/unipdf/v3/core"
 	"github.com/unidoc/unipdf/v3/core/security"
 	"github.com/unidoc/unipdf/v3/core/security/crypt"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
 )
 
 var pdfAuthor = ""
@@ -93,13 +94,19 @@ func getPdfModifiedDate() time.Time {
 
 // SetPdfModifiedDate sets the ModDate attribute of the output PDF.
 func SetPdfModifiedDate(modifiedDate time.Time) {
-	pdfCreationDate = modifiedDate
+	pdfModifiedDate = modifiedDate
 }
 
 func getPdfProducer() string {
+	return pdfProducerOrDefault(pdfProducer)
+}
+
+// pdfProducerOrDefault returns `producer` if set and allowed by the license, the default
+// producer otherwise.
+func pdfProducerOrDefault(producer string) string {
 	licenseKey := license.GetLicenseKey()
-	if len(pdfProducer) > 0 && (licenseKey.IsLicensed() || flag.Lookup("test.v") != nil) {
-		return pdfProducer
+	if len(producer) > 0 && (licenseKey.IsLicensed() || flag.Lookup("test.v") != nil) {
+		return producer
 	}
 
 	// Return default.
@@ -167,6 +174,21 @@ type PdfWriter struct {
 	// Forms.
 	acroForm *PdfAcroForm
 
+	// Name trees and embedded files.
+	names catalogNames
+
+	// Page labels, keyed by the index of the first page of each range.
+	pageLabels *PdfNumberTreeBuilder
+
+	// Structure tree of tagged output.
+	structTreeRoot *PdfStructTreeRoot
+
+	// PDF/A conformance level of the output.
+	pdfa PdfAConformance
+
+	// XMP metadata of the catalog.
+	xmp *XMPMetadata
+
 	optimizer              Optimizer
 	crossReferenceMap      map[int]crossReference
 	writeOffset            int64 // used by PdfAppender
@@ -180,6 +202,12 @@ type PdfWriter struct {
 
 	// Cache of objects traversed while resolving references.
 	traversed map[core.PdfObject]struct{}
+
+	// Font subsetting. Maps font objects to the font models of the added pages and the font models
+	// to the character codes shown with them. The codes of a font are nil if they are unknown.
+	subsetFonts bool
+	fonts       map[core.PdfObject]*PdfFont
+	usedCodes   map[*PdfFont]map[textencoding.CharCode]struct{}
 }
 
 // NewPdfWriter initializes a new PdfWriter.
@@ -190,42 +218,17 @@ func NewPdfWriter() PdfWriter {
 	w.objects = []core.PdfObject{}
 	w.pendingObjects = map[core.PdfObject][]*core.PdfObjectDictionary{}
 	w.traversed = map[core.PdfObject]struct{}{}
+	w.subsetFonts = true
+	w.fonts = map[core.PdfObject]*PdfFont{}
+	w.usedCodes = map[*PdfFont]map[textencoding.CharCode]struct{}{}
 
 	// PDF Version. Can be changed if using more advanced features in PDF.
 	// By default it is set to 1.3.
 	w.majorVersion = 1
 	w.minorVersion = 3
 
-	// Creation info.
-	infoDict := core.MakeDict()
-	metadata := []struct {
-		key   core.PdfObjectName
-		value string
-	}{
-		{"Producer", getPdfProducer()},
-		{"Creator", getPdfCreator()},
-		{"Author", getPdfAuthor()},
-		{"Subject", getPdfSubject()},
-		{"Title", getPdfTitle()},
-		{"Keywords", getPdfKeywords()},
-	}
-	for _, tuple := range metadata {
-		if tuple.value != "" {
-			infoDict.Set(tuple.key, core.MakeString(tuple.value))
-		}
-	}
-
-	// Set creation and modified dates.
-	if creationDate := getPdfCreationDate(); !creationDate.IsZero() {
-		if cd, err := NewPdfDateFromTime(creationDate); err == nil {
-			infoDict.Set("CreationDate", cd.ToPdfObject())
-		}
-	}
-	if modifiedDate := getPdfModifiedDate(); !modifiedDate.IsZero() {
-		if md, err := NewPdfDateFromTime(modifiedDate); err == nil {
-			infoDict.Set("ModDate", md.ToPdfObject())
-		}
-	}
+	// Creation info. Defaults to the package level settings, see SetPdfInfo.
+	infoDict := defaultPdfInfo().ToPdfObject()
 
 	infoObj := core.PdfIndirectObject{}
 	infoObj.PdfObject = infoDict
@@ -357,7 +360,8 @@ func copyObject(obj core.PdfObject, objectToObjectCopyMap map[core.PdfObject]cor
 }
 
 // copyObjects makes objects copy and set as working.
-func (w *PdfWriter) copyObjects() {
+// Returns the map of the original objects to their copies.
+func (w *PdfWriter) copyObjects() map[core.PdfObject]core.PdfObject {
 	objectToObjectCopyMap := make(map[core.PdfObject]core.PdfObject)
 	objects := make([]core.PdfObject, len(w.objects))
 	objectsMap := make(map[core.PdfObject]struct{}, len(w.objects))
@@ -387,6 +391,7 @@ func (w *PdfWriter) copyObjects() {
 		}
 		w.appendReplaceMap = appendReplaceMap
 	}
+	return objectToObjectCopyMap
 }
 
 // SetVersion sets the PDF version of the output file.
@@ -409,6 +414,20 @@ func (w *PdfWriter) SetOCProperties(ocProperties core.PdfObject) error {
 	return nil
 }
 
+// AttachFile embeds the file specified by `fs` in the output PDF by adding it to the EmbeddedFiles
+// name tree of the document. Files with an AFRelationship are also listed in the associated files
+// (AF) array of the catalog, as required for PDF/A-3 attachments such as ZUGFeRD/Factur-X invoices.
+func (w *PdfWriter) AttachFile(fs *PdfFilespec) error {
+	return w.names.attachFile(w.catalog, fs)
+}
+
+// SetNameTree sets the name tree `name` of the names dictionary of the catalog (Section 7.7.4),
+// e.g. "JavaScript", to the tree built by `tree`. Replacing the EmbeddedFiles tree drops the files
+// attached before.
+func (w *PdfWriter) SetNameTree(name core.PdfObjectName, tree *PdfNameTreeBuilder) {
+	w.names.setTree(name, tree)
+}
+
 // SetOptimizer sets the optimizer to optimize PDF before writing.
 func (w *PdfWriter) SetOptimizer(optimizer Optimizer) {
 	w.optimizer = optimizer
@@ -419,6 +438,43 @@ func (w *PdfWriter) GetOptimizer() Optimizer {
 	return w.optimizer
 }
 
+// SetFontSubsetting sets whether the embedded TrueType font programs of fonts created from font files
+// (NewPdfFontFromTTFFile, NewCompositePdfFontFromTTFFile) are replaced by subsets containing only
+// the glyphs shown in the content of the pages added to the writer. Only fonts set in page
+// resources with PdfPageResources.SetFont are subset. Font subsetting is enabled by default.
+func (w *PdfWriter) SetFontSubsetting(enable bool) {
+	w.subsetFonts = enable
+}
+
+// subsetFontObjects replaces the font programs of the fonts of the added pages by subsets. `copies`
+// maps the original objects to the copies that are written and only the copies are modified.
+func (w *PdfWriter) subsetFontObjects(copies map[core.PdfObject]core.PdfObject) error {
+	for obj, font := range w.fonts {
+		fontCopy, ok := copies[obj]
+		if !ok {
+			continue
+		}
+		d, ok := core.GetDict(fontCopy)
+		if !ok {
+			continue
+		}
+		codes, has := w.usedCodes[font]
+		if has && codes == nil {
+			continue
+		}
+		subset, err := font.makeTTFSubset(sortedCodes(codes))
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to subset font %s: %v", font, err)
+			return err
+		}
+		if subset == nil {
+			continue
+		}
+		subset.apply(d)
+	}
+	return nil
+}
+
 func (w *PdfWriter) hasObject(obj core.PdfObject) bool {
 	_, found := w.objectsMap[obj]
 	return found
@@ -610,6 +666,13 @@ func (w *PdfWriter) AddPage(page *PdfPage) error {
 	// Update the count.
 	*pageCount = *pageCount + 1
 
+	if page.Resources != nil {
+		for obj, font := range page.Resources.fonts {
+			w.fonts[obj] = font
+		}
+		w.addUsedCodes(page)
+	}
+
 	w.addObject(pageObj)
 
 	// Traverse the page and record all object references.
@@ -836,6 +899,14 @@ func (w *PdfWriter) updateObjectNumbers() {
 type EncryptOptions struct {
 	Permissions security.Permissions
 	Algorithm   EncryptionAlgorithm
+
+	// Recipients enables the public-key security handler (Adobe.PubSec): the output is encrypted
+	// for the certificates of the recipients, each with its own permissions, instead of the
+	// passwords. Permissions is not used in this case.
+	Recipients []security.PubSecRecipient
+	// SubFilter of the public-key security handler: security.PubSecSubFilterS5 (default) or
+	// security.PubSecSubFilterS4. RC4_128bit always uses security.PubSecSubFilterS4.
+	SubFilter string
 }
 
 // EncryptionAlgorithm is used in EncryptOptions to change the default algorithm used to encrypt the document.
@@ -850,7 +921,9 @@ const (
 	AES_256bit
 )
 
-// Encrypt encrypts the output file with a specified user/owner password.
+// Encrypt encrypts the output file with a specified user/owner password. If `options` specifies
+// Recipients, the output file is encrypted for the recipients' certificates and the passwords are
+// ignored.
 func (w *PdfWriter) Encrypt(userPass, ownerPass []byte, options *EncryptOptions) error {
 	algo := RC4_128bit
 	if options != nil {
@@ -872,7 +945,21 @@ func (w *PdfWriter) Encrypt(userPass, ownerPass []byte, options *EncryptOptions)
 	default:
 		return fmt.Errorf("unsupported algorithm: %v", options.Algorithm)
 	}
-	crypter, info, err := core.PdfCryptNewEncrypt(cf, userPass, ownerPass, perm)
+	var crypter *core.PdfCrypt
+	var info *core.EncryptInfo
+	var err error
+	if options != nil && len(options.Recipients) > 0 {
+		subFilter := options.SubFilter
+		if subFilter == "" {
+			subFilter = security.PubSecSubFilterS5
+		}
+		if algo == RC4_128bit {
+			subFilter = security.PubSecSubFilterS4
+		}
+		crypter, info, err = core.PdfCryptNewEncryptPubSec(cf, options.Recipients, subFilter)
+	} else {
+		crypter, info, err = core.PdfCryptNewEncrypt(cf, userPass, ownerPass, perm)
+	}
 	if err != nil {
 		return err
 	}
@@ -946,6 +1033,41 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		}
 	}
 
+	// Structure tree.
+	if w.structTreeRoot != nil {
+		if err := w.writeStructTree(); err != nil {
+			return err
+		}
+	}
+
+	// Page labels.
+	if pageLabels := w.pageLabelsToPdfObject(); pageLabels != nil {
+		w.catalog.Set("PageLabels", pageLabels)
+		if err := w.addObjects(pageLabels); err != nil {
+			return err
+		}
+	}
+
+	// Name trees and embedded files.
+	for _, obj := range w.names.apply(w.catalog, w.catalog) {
+		if err := w.addObjects(obj); err != nil {
+			return err
+		}
+	}
+
+	// PDF/A version and file identifiers, which only apply to this output.
+	var pdfa *pdfaWriter
+	if w.pdfa != PdfAConformanceNone {
+		majorVersion, minorVersion, ids := w.majorVersion, w.minorVersion, w.ids
+		defer func() {
+			w.majorVersion, w.minorVersion, w.ids = majorVersion, minorVersion, ids
+		}()
+		pdfa = newPdfAWriter(w)
+		if err := pdfa.prepare(); err != nil {
+			return err
+		}
+	}
+
 	// Check pending objects prior to write.
 	for pendingObj, pendingObjDicts := range w.pendingObjects {
 		if !w.hasObject(pendingObj) {
@@ -968,7 +1090,34 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 	// Make a copy of objects prior to optimizing as this can alter the objects.
 	// TODO: Copying wastes memory. Might be worth making user responsible for handling properly.
 	//       Is copy needed for optimization?
-	w.copyObjects()
+	copies := w.copyObjects()
+
+	// PDF/A output intent and XMP metadata, set on the copies.
+	if w.xmp != nil || pdfa != nil {
+		catalog, ok := core.GetDict(w.root)
+		if !ok {
+			return ErrTypeCheck
+		}
+		if pdfa != nil {
+			if err := pdfa.apply(catalog); err != nil {
+				return err
+			}
+		}
+		if err := w.writeXMPMetadata(catalog); err != nil {
+			return err
+		}
+	}
+
+	// PDF/A requires the complete font programs to be embedded.
+	if w.subsetFonts && pdfa == nil {
+		if err := w.subsetFontObjects(copies); err != nil {
+			return err
+		}
+	}
+
+	if pdfa != nil {
+		pdfa.convert()
+	}
 
 	if w.optimizer != nil {
 		var err error
@@ -983,12 +1132,23 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		w.objectsMap = objMap
 	}
 
+	if pdfa != nil {
+		pdfa.checkWritten()
+		if err := pdfa.err(); err != nil {
+			return err
+		}
+	}
+
 	w.writePos = w.writeOffset
 	w.writer = bufio.NewWriter(writer)
 	useCrossReferenceStream := w.majorVersion > 1 || (w.majorVersion == 1 && w.minorVersion > 4)
 	if w.useCrossReferenceStream != nil {
 		useCrossReferenceStream = *w.useCrossReferenceStream
 	}
+	if w.pdfa.part() == 1 {
+		// PDF/A-1 is based on PDF 1.4, which has no cross reference streams.
+		useCrossReferenceStream = false
+	}
 
 	// Make a map of objects within object streams (if used).
 	objectsInObjectStreams := make(map[core.PdfObject]bool)
@@ -1141,6 +1301,8 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		// If encrypted!
 		if w.crypter != nil {
 			crossReferenceStream.Set("Encrypt", w.encryptObj)
+		}
+		if w.ids != nil {
 			crossReferenceStream.Set("ID", w.ids)
 			common.Log.Trace("Ids: %s", w.ids)
 		}
@@ -1194,6 +1356,8 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		// If encrypted!
 		if w.crypter != nil {
 			trailer.Set("Encrypt", w.encryptObj)
+		}
+		if w.ids != nil {
 			trailer.Set("ID", w.ids)
 			common.Log.Trace("Ids: %s", w.ids)
 		}
@@ -7,10 +7,22 @@ package model
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/core/security"
+	"github.com/unidoc/unipdf/v3/model/internal/fonts"
 )
 
 // Tests loading annotations from file, writing back out and reloading.
@@ -104,3 +116,265 @@ func TestReadWriteAnnotations(t *testing.T) {
 		checkAnnots(reader, false)
 	}
 }
+
+// Tests that the TrueType font programs of composite fonts are subset on write.
+func TestWriterFontSubsetting(t *testing.T) {
+	writeFont := func(subset bool) (*PdfFont, int) {
+		font, err := NewCompositePdfFontFromTTFFile("../creator/testdata/FreeSans.ttf")
+		require.NoError(t, err)
+
+		encoded := font.Encoder().Encode("Hello world")
+		page := NewPdfPage()
+		require.NoError(t, page.Resources.SetFont("F1", font))
+		content := fmt.Sprintf("BT /F1 12 Tf 10 10 Td <%X> Tj ET", encoded)
+		require.NoError(t, page.AddContentStreamByString(content))
+
+		w := NewPdfWriter()
+		w.SetFontSubsetting(subset)
+		require.NoError(t, w.AddPage(page))
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+
+		reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		page, err = reader.GetPage(1)
+		require.NoError(t, err)
+		obj, ok := page.Resources.GetFontByName("F1")
+		require.True(t, ok)
+		font, err = NewPdfFontFromPdfObject(obj)
+		require.NoError(t, err)
+		return font, buf.Len()
+	}
+
+	font, size := writeFont(true)
+	fullFont, fullSize := writeFont(false)
+	require.True(t, size < fullSize/4, "subset %d bytes, full %d bytes", size, fullSize)
+	require.Equal(t, "FreeSans", fullFont.BaseFont())
+	require.Regexp(t, "^[A-Z]{6}\\+FreeSans$", font.BaseFont())
+
+	type0, ok := font.context.(*pdfFontType0)
+	require.True(t, ok)
+	require.Equal(t, font.BaseFont(), type0.DescendantFont.BaseFont())
+	descriptor := type0.DescendantFont.FontDescriptor()
+	require.NotNil(t, descriptor)
+	require.Equal(t, font.BaseFont(), descriptor.FontName.String())
+	_, err := fonts.NewFontFile2FromPdfObject(descriptor.FontFile2)
+	require.NoError(t, err)
+
+	text, _, numMisses := font.CharcodeBytesToUnicode(fullFont.Encoder().Encode("Hello"))
+	require.Equal(t, 0, numMisses)
+	require.Equal(t, "Hello", text)
+
+	// The widths of the used glyphs are retained.
+	for _, r := range "Helo wrd" {
+		exp, ok := fullFont.GetRuneMetrics(r)
+		require.True(t, ok)
+		code, ok := fullFont.Encoder().RuneToCharcode(r)
+		require.True(t, ok)
+		metrics, ok := font.GetCharMetrics(code)
+		require.True(t, ok)
+		require.Equal(t, exp.Wx, metrics.Wx, "rune %q", r)
+	}
+}
+
+// newTestCertificate returns a self-signed certificate and its private key.
+func newTestCertificate(t *testing.T, name string, serial int64) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// Tests writing files encrypted with the public-key security handler and decrypting them with the
+// recipients' certificates.
+func TestEncryptPubSec(t *testing.T) {
+	owner, ownerKey := newTestCertificate(t, "Owner", 1)
+	reader, readerKey := newTestCertificate(t, "Reader", 2)
+	other, otherKey := newTestCertificate(t, "Other", 3)
+	readerPerms := security.PermPrinting | security.PermExtractGraphics
+
+	const content = "BT /F1 12 Tf 100 700 Td (Confidential) Tj ET"
+	cases := []struct {
+		name      string
+		algorithm EncryptionAlgorithm
+		subFilter string
+	}{
+		{"RC4 s4", RC4_128bit, ""},
+		{"AESV2 s4", AES_128bit, security.PubSecSubFilterS4},
+		{"AESV2 s5", AES_128bit, security.PubSecSubFilterS5},
+		{"AESV3 s5", AES_256bit, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := NewPdfWriter()
+			page := NewPdfPage()
+			require.NoError(t, page.SetContentStreams([]string{content}, core.NewRawEncoder()))
+			require.NoError(t, w.AddPage(page))
+			err := w.Encrypt(nil, nil, &EncryptOptions{
+				Algorithm: c.algorithm,
+				SubFilter: c.subFilter,
+				Recipients: []security.PubSecRecipient{
+					{Certificate: owner, Permissions: security.PermOwner},
+					{Certificate: reader, Permissions: readerPerms},
+				},
+			})
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, w.Write(&buf))
+			require.False(t, bytes.Contains(buf.Bytes(), []byte("Confidential")))
+
+			open := func() *PdfReader {
+				r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+				require.NoError(t, err)
+				isEncrypted, err := r.IsEncrypted()
+				require.NoError(t, err)
+				require.True(t, isEncrypted)
+				return r
+			}
+
+			r := open()
+			_, err = r.Decrypt([]byte(""))
+			require.Error(t, err)
+			ok, err := r.DecryptWithCertificate(other, otherKey)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			for _, recipient := range []struct {
+				cert  *x509.Certificate
+				key   *rsa.PrivateKey
+				perms security.Permissions
+			}{
+				{owner, ownerKey, security.PermOwner},
+				{reader, readerKey, readerPerms},
+			} {
+				r := open()
+				ok, err := r.DecryptWithCertificate(recipient.cert, recipient.key)
+				require.NoError(t, err)
+				require.True(t, ok)
+				require.Equal(t, recipient.perms, r.GetAccessPermissions())
+
+				page, err := r.GetPage(1)
+				require.NoError(t, err)
+				contents, err := page.GetAllContentStreams()
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(contents, content))
+			}
+		})
+	}
+}
+
+// Tests that the TrueType font programs of simple fonts are subset to the used codes on write.
+func TestWriterSimpleFontSubsetting(t *testing.T) {
+	writeFont := func(subset bool) (*core.PdfObjectDictionary, int) {
+		font, err := NewPdfFontFromTTFFile("../creator/testdata/FreeSans.ttf")
+		require.NoError(t, err)
+
+		encoded := font.Encoder().Encode("Hello")
+		page := NewPdfPage()
+		require.NoError(t, page.Resources.SetFont("F1", font))
+		content := fmt.Sprintf("BT /F1 12 Tf 10 10 Td <%X> Tj ET", encoded)
+		require.NoError(t, page.AddContentStreamByString(content))
+
+		w := NewPdfWriter()
+		w.SetFontSubsetting(subset)
+		require.NoError(t, w.AddPage(page))
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+
+		reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		page, err = reader.GetPage(1)
+		require.NoError(t, err)
+		obj, ok := page.Resources.GetFontByName("F1")
+		require.True(t, ok)
+		d, ok := core.GetDict(obj)
+		require.True(t, ok)
+		return d, buf.Len()
+	}
+
+	d, size := writeFont(true)
+	full, fullSize := writeFont(false)
+	require.True(t, size < fullSize/4, "subset %d bytes, full %d bytes", size, fullSize)
+	require.Regexp(t, "^[A-Z]{6}\\+FreeSans$", d.Get("BaseFont").String())
+
+	// FirstChar..LastChar are trimmed from 32..255 to "H" (72) .. "o" (111).
+	first, _ := core.GetIntVal(d.Get("FirstChar"))
+	last, _ := core.GetIntVal(d.Get("LastChar"))
+	require.Equal(t, 72, first)
+	require.Equal(t, 111, last)
+	widths, ok := core.GetArray(d.Get("Widths"))
+	require.True(t, ok)
+	require.Equal(t, 40, widths.Len())
+	fullWidths, ok := core.GetArray(full.Get("Widths"))
+	require.True(t, ok)
+	require.Equal(t, fullWidths.Get(72-32), widths.Get(0))
+	require.Equal(t, fullWidths.Get(111-32), widths.Get(39))
+}
+
+func TestShownStrings(t *testing.T) {
+	// The font of "y" is restored by Q. The strings of dictionaries, inline images and comments
+	// are not shown.
+	content := `BT /F1 12 Tf (a\(b\)\\c\101\n) Tj [(d) -250 <6566 67>] TJ ET
+q BT /F2 10 Tf 0 0 Td (x) ' ET Q
+BT 1 2 (y) " /Span <</ActualText (z)>> BDC ET
+BI /W 1 /H 1 /BPC 8 ID (w) Tj EI
+% (v) Tj
+/F3 9 Tf`
+	require.Equal(t, map[string][][]byte{
+		"F1": {[]byte("a(b)\\cA\n"), []byte("d"), []byte("efg"), []byte("y")},
+		"F2": {[]byte("x")},
+	}, shownStrings([]byte(content)))
+}
+
+// Tests that the used glyphs of a font are recorded per writer.
+func TestWriterFontSubsettingPerWriter(t *testing.T) {
+	font, err := NewPdfFontFromTTFFile("../creator/testdata/FreeSans.ttf")
+	require.NoError(t, err)
+	makePage := func(text string) *PdfPage {
+		page := NewPdfPage()
+		require.NoError(t, page.Resources.SetFont("F1", font))
+		content := fmt.Sprintf("BT /F1 12 Tf 10 10 Td <%X> Tj ET", font.Encoder().Encode(text))
+		require.NoError(t, page.AddContentStreamByString(content))
+		return page
+	}
+	writePage := func(page *PdfPage) *core.PdfObjectDictionary {
+		w := NewPdfWriter()
+		require.NoError(t, w.AddPage(page))
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+
+		reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		page, err = reader.GetPage(1)
+		require.NoError(t, err)
+		obj, ok := page.Resources.GetFontByName("F1")
+		require.True(t, ok)
+		d, ok := core.GetDict(obj)
+		require.True(t, ok)
+		return d
+	}
+
+	// Both texts are encoded with the font before either document is written.
+	abPage, yzPage := makePage("AB"), makePage("yz")
+	ab, yz := writePage(abPage), writePage(yzPage)
+	for _, tc := range []struct {
+		d           *core.PdfObjectDictionary
+		first, last int
+	}{{ab, 'A', 'B'}, {yz, 'y', 'z'}} {
+		first, _ := core.GetIntVal(tc.d.Get("FirstChar"))
+		last, _ := core.GetIntVal(tc.d.Get("LastChar"))
+		require.Equal(t, tc.first, first)
+		require.Equal(t, tc.last, last)
+	}
+	require.NotEqual(t, ab.Get("BaseFont").String(), yz.Get("BaseFont").String())
+}
@@ -0,0 +1,652 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// XMP namespaces of the typed properties of XMPMetadata.
+const (
+	XMPNamespaceRDF     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	XMPNamespaceDC      = "http://purl.org/dc/elements/1.1/"
+	XMPNamespaceXMP     = "http://ns.adobe.com/xap/1.0/"
+	XMPNamespacePDF     = "http://ns.adobe.com/pdf/1.3/"
+	XMPNamespacePDFAID  = "http://www.aiim.org/pdfa/ns/id/"
+	XMPNamespacePDFUAID = "http://www.aiim.org/pdfua/ns/id/"
+)
+
+// XMPMetadata is an XMP metadata packet (ISO 16684-1), the content of the metadata streams of the
+// document catalog and of pages. The properties of the Dublin Core (dc), XMP basic (xmp), Adobe
+// PDF (pdf) and PDF/A and PDF/UA identification (pdfaid, pdfuaid) schemas are typed, the other
+// properties are kept as custom properties.
+type XMPMetadata struct {
+	// Dublin Core.
+	Title       string   // dc:title, default language.
+	Creators    []string // dc:creator, the authors.
+	Description string   // dc:description, default language.
+	Subjects    []string // dc:subject.
+	Format      string   // dc:format, the MIME type, e.g. "application/pdf".
+
+	// XMP basic.
+	CreatorTool  string    // xmp:CreatorTool, the application that created the original document.
+	CreateDate   time.Time // xmp:CreateDate.
+	ModifyDate   time.Time // xmp:ModifyDate.
+	MetadataDate time.Time // xmp:MetadataDate.
+
+	// Adobe PDF.
+	Producer   string // pdf:Producer, the application that produced the PDF.
+	Keywords   string // pdf:Keywords.
+	PDFVersion string // pdf:PDFVersion.
+
+	// PDF/A and PDF/UA identification.
+	PdfAPart        int    // pdfaid:part, 0 if not set.
+	PdfAConformance string // pdfaid:conformance, e.g. "B".
+	PdfUAPart       int    // pdfuaid:part, 0 if not set.
+
+	// Custom are the other properties.
+	Custom []*XMPProperty
+}
+
+// XMPProperty is a simple or array XMP property. Properties read with structure values, or arrays of
+// them, have neither a Value nor Items and are written back unchanged.
+type XMPProperty struct {
+	// Namespace is the namespace URI of the property.
+	Namespace string
+	// Prefix is the namespace prefix used for writing the property, e.g. "dc".
+	Prefix string
+	// Name is the name of the property within the namespace.
+	Name string
+
+	// Value is the value of simple properties.
+	Value string
+
+	// Array is the array type of array properties: "Seq", "Bag" or "Alt". Empty for simple
+	// properties.
+	Array string
+	// Items are the items of array properties.
+	Items []string
+
+	// structure is the structure value read, nil for simple and array properties.
+	structure *xmpStructure
+}
+
+// xmpStructure is a property element with a structure value, kept as read.
+type xmpStructure struct {
+	node *xmpNode
+	// prefixes are the namespace prefixes of the packet, keyed by namespace.
+	prefixes map[string]string
+}
+
+// NewXMPMetadata returns a new empty XMP metadata packet for PDF documents.
+func NewXMPMetadata() *XMPMetadata {
+	return &XMPMetadata{Format: "application/pdf"}
+}
+
+// SetCustom sets the custom property `name` of namespace `namespace` with prefix `prefix` to the
+// simple value `value`.
+func (m *XMPMetadata) SetCustom(namespace, prefix, name, value string) {
+	if p := m.GetCustom(namespace, name); p != nil {
+		p.Value, p.Array, p.Items = value, "", nil
+		return
+	}
+	m.Custom = append(m.Custom, &XMPProperty{Namespace: namespace, Prefix: prefix, Name: name, Value: value})
+}
+
+// GetCustom returns the custom property `name` of namespace `namespace`, nil if not set.
+func (m *XMPMetadata) GetCustom(namespace, name string) *XMPProperty {
+	for _, p := range m.Custom {
+		if p.Namespace == namespace && p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// ParseXMPMetadata parses the XMP packet `data`. Properties with structure values, such as
+// xmpMM:History or the pdfaExtension schemas, are kept as custom properties and written back
+// unchanged by Bytes.
+func ParseXMPMetadata(data []byte) (*XMPMetadata, error) {
+	var root xmpNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		common.Log.Debug("ERROR: Invalid XMP packet: %v", err)
+		return nil, err
+	}
+
+	m := &XMPMetadata{}
+	prefixes := map[string]string{}
+	var collect func(n *xmpNode)
+	collect = func(n *xmpNode) {
+		for _, attr := range n.Attrs {
+			if attr.Name.Space == "xmlns" {
+				prefixes[attr.Value] = attr.Name.Local
+			}
+		}
+		for i := range n.Nodes {
+			collect(&n.Nodes[i])
+		}
+	}
+	collect(&root)
+	var parse func(n *xmpNode, inRDF bool)
+	parse = func(n *xmpNode, inRDF bool) {
+		isRDF := n.XMLName == xml.Name{Space: XMPNamespaceRDF, Local: "RDF"}
+		if !inRDF || n.XMLName != (xml.Name{Space: XMPNamespaceRDF, Local: "Description"}) {
+			for i := range n.Nodes {
+				parse(&n.Nodes[i], inRDF || isRDF)
+			}
+			return
+		}
+
+		// Properties in attribute form.
+		for _, attr := range n.Attrs {
+			if attr.Name.Space == "" || attr.Name.Space == "xmlns" || attr.Name.Space == XMPNamespaceRDF {
+				continue
+			}
+			m.setProperty(&XMPProperty{Namespace: attr.Name.Space, Name: attr.Name.Local, Value: attr.Value}, prefixes)
+		}
+		// Properties in element form.
+		for i := range n.Nodes {
+			m.setProperty(n.Nodes[i].property(prefixes), prefixes)
+		}
+	}
+	parse(&root, false)
+	return m, nil
+}
+
+// xmpNode is an element of an XMP packet.
+type xmpNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []xmpNode  `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+// property returns the property of property element `n` of a packet with namespace prefixes
+// `prefixes`.
+func (n *xmpNode) property(prefixes map[string]string) *XMPProperty {
+	p := &XMPProperty{Namespace: n.XMLName.Space, Name: n.XMLName.Local}
+	for _, attr := range n.Attrs {
+		if attr.Name == (xml.Name{Space: XMPNamespaceRDF, Local: "resource"}) {
+			p.Value = attr.Value
+			return p
+		}
+	}
+	if len(n.Nodes) == 0 {
+		p.Value = strings.TrimSpace(n.Text)
+		return p
+	}
+
+	structure := &XMPProperty{Namespace: p.Namespace, Name: p.Name,
+		structure: &xmpStructure{node: n, prefixes: prefixes}}
+	array := &n.Nodes[0]
+	switch array.XMLName {
+	case xml.Name{Space: XMPNamespaceRDF, Local: "Seq"},
+		xml.Name{Space: XMPNamespaceRDF, Local: "Bag"},
+		xml.Name{Space: XMPNamespaceRDF, Local: "Alt"}:
+	default:
+		return structure
+	}
+	p.Array = array.XMLName.Local
+	for _, item := range array.Nodes {
+		if item.XMLName != (xml.Name{Space: XMPNamespaceRDF, Local: "li"}) {
+			continue
+		}
+		if len(item.Nodes) > 0 {
+			return structure
+		}
+		p.Items = append(p.Items, strings.TrimSpace(item.Text))
+	}
+	return p
+}
+
+// setProperty sets property `p` read from a packet with namespace prefixes `prefixes`, keyed by
+// namespace.
+func (m *XMPMetadata) setProperty(p *XMPProperty, prefixes map[string]string) {
+	if p.structure != nil {
+		p.Prefix = prefixes[p.Namespace]
+		m.Custom = append(m.Custom, p)
+		return
+	}
+	value := p.Value
+	if len(p.Items) > 0 {
+		value = p.Items[0]
+	}
+	date := func() time.Time {
+		t, err := parseXMPDate(value)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid XMP date %s: %q", p.Name, value)
+		}
+		return t
+	}
+	integer := func() int {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid XMP integer %s: %q", p.Name, value)
+		}
+		return i
+	}
+
+	switch p.Namespace + " " + p.Name {
+	case XMPNamespaceDC + " title":
+		m.Title = value
+	case XMPNamespaceDC + " creator":
+		m.Creators = p.Items
+		if p.Array == "" && value != "" {
+			m.Creators = []string{value}
+		}
+	case XMPNamespaceDC + " description":
+		m.Description = value
+	case XMPNamespaceDC + " subject":
+		m.Subjects = p.Items
+		if p.Array == "" && value != "" {
+			m.Subjects = []string{value}
+		}
+	case XMPNamespaceDC + " format":
+		m.Format = value
+	case XMPNamespaceXMP + " CreatorTool":
+		m.CreatorTool = value
+	case XMPNamespaceXMP + " CreateDate":
+		m.CreateDate = date()
+	case XMPNamespaceXMP + " ModifyDate":
+		m.ModifyDate = date()
+	case XMPNamespaceXMP + " MetadataDate":
+		m.MetadataDate = date()
+	case XMPNamespacePDF + " Producer":
+		m.Producer = value
+	case XMPNamespacePDF + " Keywords":
+		m.Keywords = value
+	case XMPNamespacePDF + " PDFVersion":
+		m.PDFVersion = value
+	case XMPNamespacePDFAID + " part":
+		m.PdfAPart = integer()
+	case XMPNamespacePDFAID + " conformance":
+		m.PdfAConformance = value
+	case XMPNamespacePDFUAID + " part":
+		m.PdfUAPart = integer()
+	default:
+		p.Prefix = prefixes[p.Namespace]
+		m.Custom = append(m.Custom, p)
+	}
+}
+
+// parseXMPDate parses XMP date `s`, which can omit the time or be less precise.
+func parseXMPDate(s string) (time.Time, error) {
+	layouts := []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04:05",
+		"2006-01-02",
+		"2006-01",
+		"2006",
+	}
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// xmpNamespace is a namespace of the properties written by Bytes.
+type xmpNamespace struct {
+	uri, prefix string
+	props       []*XMPProperty
+}
+
+// Bytes returns the XMP packet of `m`, with padding allowing in-place updates.
+func (m *XMPMetadata) Bytes() ([]byte, error) {
+	simple := func(namespace, name, value string) *XMPProperty {
+		if value == "" {
+			return nil
+		}
+		return &XMPProperty{Namespace: namespace, Name: name, Value: value}
+	}
+	array := func(namespace, name, typ string, items ...string) *XMPProperty {
+		if len(items) == 0 || len(items) == 1 && items[0] == "" {
+			return nil
+		}
+		return &XMPProperty{Namespace: namespace, Name: name, Array: typ, Items: items}
+	}
+	date := func(namespace, name string, t time.Time) *XMPProperty {
+		if t.IsZero() {
+			return nil
+		}
+		return simple(namespace, name, t.Format(time.RFC3339))
+	}
+	integer := func(namespace, name string, i int) *XMPProperty {
+		if i == 0 {
+			return nil
+		}
+		return simple(namespace, name, strconv.Itoa(i))
+	}
+
+	namespaces := []*xmpNamespace{
+		{uri: XMPNamespacePDFAID, prefix: "pdfaid"},
+		{uri: XMPNamespacePDFUAID, prefix: "pdfuaid"},
+		{uri: XMPNamespaceDC, prefix: "dc"},
+		{uri: XMPNamespaceXMP, prefix: "xmp"},
+		{uri: XMPNamespacePDF, prefix: "pdf"},
+	}
+	typed := []*XMPProperty{
+		integer(XMPNamespacePDFAID, "part", m.PdfAPart),
+		simple(XMPNamespacePDFAID, "conformance", m.PdfAConformance),
+		integer(XMPNamespacePDFUAID, "part", m.PdfUAPart),
+		simple(XMPNamespaceDC, "format", m.Format),
+		array(XMPNamespaceDC, "title", "Alt", m.Title),
+		array(XMPNamespaceDC, "creator", "Seq", m.Creators...),
+		array(XMPNamespaceDC, "description", "Alt", m.Description),
+		array(XMPNamespaceDC, "subject", "Bag", m.Subjects...),
+		simple(XMPNamespaceXMP, "CreatorTool", m.CreatorTool),
+		date(XMPNamespaceXMP, "CreateDate", m.CreateDate),
+		date(XMPNamespaceXMP, "ModifyDate", m.ModifyDate),
+		date(XMPNamespaceXMP, "MetadataDate", m.MetadataDate),
+		simple(XMPNamespacePDF, "Producer", m.Producer),
+		simple(XMPNamespacePDF, "Keywords", m.Keywords),
+		simple(XMPNamespacePDF, "PDFVersion", m.PDFVersion),
+	}
+
+	add := func(p *XMPProperty) error {
+		for _, ns := range namespaces {
+			if ns.uri == p.Namespace {
+				ns.props = append(ns.props, p)
+				return nil
+			}
+		}
+		if p.Prefix == "" {
+			return fmt.Errorf("XMP property %s without namespace prefix", p.Name)
+		}
+		namespaces = append(namespaces, &xmpNamespace{uri: p.Namespace, prefix: p.Prefix, props: []*XMPProperty{p}})
+		return nil
+	}
+	for _, p := range typed {
+		if p != nil {
+			add(p)
+		}
+	}
+	for _, p := range m.Custom {
+		if p.Namespace == "" || p.Name == "" {
+			return nil, errors.New("XMP property without namespace or name")
+		}
+		if err := add(p); err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	fmt.Fprintf(&b, " <rdf:RDF xmlns:rdf=\"%s\">\n", XMPNamespaceRDF)
+	for _, ns := range namespaces {
+		if len(ns.props) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "  <rdf:Description rdf:about=\"\" xmlns:%s=\"%s\">\n", ns.prefix, xmlEscape(ns.uri))
+		for _, p := range ns.props {
+			name := ns.prefix + ":" + p.Name
+			if p.structure != nil {
+				b.WriteString("   ")
+				p.structure.write(&b, ns.prefix)
+				b.WriteString("\n")
+				continue
+			}
+			if p.Array == "" {
+				fmt.Fprintf(&b, "   <%s>%s</%s>\n", name, xmlEscape(p.Value), name)
+				continue
+			}
+			fmt.Fprintf(&b, "   <%s><rdf:%s>", name, p.Array)
+			for _, item := range p.Items {
+				if p.Array == "Alt" {
+					fmt.Fprintf(&b, "<rdf:li xml:lang=\"x-default\">%s</rdf:li>", xmlEscape(item))
+				} else {
+					fmt.Fprintf(&b, "<rdf:li>%s</rdf:li>", xmlEscape(item))
+				}
+			}
+			fmt.Fprintf(&b, "</rdf:%s></%s>\n", p.Array, name)
+		}
+		b.WriteString("  </rdf:Description>\n")
+	}
+	b.WriteString(" </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	for i := 0; i < 20; i++ {
+		b.WriteString(strings.Repeat(" ", 99) + "\n")
+	}
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return b.Bytes(), nil
+}
+
+// xmlNamespace is the namespace of the xml prefix.
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// write writes the property element of `s` with namespace prefix `prefix` to `b`. The namespaces
+// of the elements and attributes of the value are declared on the property element.
+func (s *xmpStructure) write(b *bytes.Buffer, prefix string) {
+	decls := map[string]string{XMPNamespaceRDF: "rdf", xmlNamespace: "xml", s.node.XMLName.Space: prefix}
+	taken := map[string]bool{"rdf": true, "xml": true, prefix: true}
+	var declared []string
+	qname := func(name xml.Name) string {
+		if name.Space == "" {
+			return name.Local
+		}
+		p, ok := decls[name.Space]
+		if !ok {
+			p = s.prefixes[name.Space]
+			for i := 1; p == "" || taken[p]; i++ {
+				p = fmt.Sprintf("ns%d", i)
+			}
+			decls[name.Space], taken[p] = p, true
+			declared = append(declared, name.Space)
+		}
+		return p + ":" + name.Local
+	}
+	isDecl := func(attr xml.Attr) bool {
+		return attr.Name.Space == "xmlns" || attr.Name == xml.Name{Local: "xmlns"}
+	}
+	// The names are resolved first so that the namespaces can be declared on the property element.
+	var resolve func(n *xmpNode)
+	resolve = func(n *xmpNode) {
+		qname(n.XMLName)
+		for _, attr := range n.Attrs {
+			if !isDecl(attr) {
+				qname(attr.Name)
+			}
+		}
+		for i := range n.Nodes {
+			resolve(&n.Nodes[i])
+		}
+	}
+	resolve(s.node)
+
+	var write func(n *xmpNode, root bool)
+	write = func(n *xmpNode, root bool) {
+		name := qname(n.XMLName)
+		b.WriteString("<" + name)
+		if root {
+			for _, uri := range declared {
+				fmt.Fprintf(b, " xmlns:%s=\"%s\"", decls[uri], xmlEscape(uri))
+			}
+		}
+		for _, attr := range n.Attrs {
+			if !isDecl(attr) {
+				fmt.Fprintf(b, " %s=\"%s\"", qname(attr.Name), xmlEscape(attr.Value))
+			}
+		}
+		if len(n.Nodes) == 0 && n.Text == "" {
+			b.WriteString("/>")
+			return
+		}
+		b.WriteString(">")
+		if len(n.Nodes) == 0 {
+			b.WriteString(xmlEscape(n.Text))
+		}
+		for i := range n.Nodes {
+			write(&n.Nodes[i], false)
+		}
+		b.WriteString("</" + name + ">")
+	}
+	write(s.node, true)
+}
+
+// ToStream returns a metadata stream containing the XMP packet of `m`. The stream is not
+// compressed so that the metadata can be read without PDF tools.
+func (m *XMPMetadata) ToStream() (*core.PdfObjectStream, error) {
+	data, err := m.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	stream, err := core.MakeStream(data, nil)
+	if err != nil {
+		return nil, err
+	}
+	stream.Set("Type", core.MakeName("Metadata"))
+	stream.Set("Subtype", core.MakeName("XML"))
+	return stream, nil
+}
+
+// newXMPMetadataFromObject returns the XMP metadata of metadata stream `obj`, nil if `obj` is nil.
+func newXMPMetadataFromObject(obj core.PdfObject) (*XMPMetadata, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	stream, ok := core.GetStream(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Metadata not a stream: %T", obj)
+		return nil, ErrTypeCheck
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	return ParseXMPMetadata(data)
+}
+
+// GetXMPMetadata returns the XMP metadata of the document catalog, nil if the document has no
+// metadata stream.
+func (r *PdfReader) GetXMPMetadata() (*XMPMetadata, error) {
+	return newXMPMetadataFromObject(r.catalog.Get("Metadata"))
+}
+
+// GetXMPMetadata returns the XMP metadata of the page, nil if the page has no metadata stream.
+func (p *PdfPage) GetXMPMetadata() (*XMPMetadata, error) {
+	return newXMPMetadataFromObject(p.Metadata)
+}
+
+// SetXMPMetadata sets the metadata stream of the page to the XMP packet of `m`.
+func (p *PdfPage) SetXMPMetadata(m *XMPMetadata) error {
+	stream, err := m.ToStream()
+	if err != nil {
+		return err
+	}
+	p.Metadata = stream
+	return nil
+}
+
+// SetXMPMetadata sets the XMP metadata of the document catalog. On Write, the properties that
+// are not set are initialized from the document information dictionary, which is then updated
+// with the properties so that both stay equivalent: Title, Author (dc:creator), Subject
+// (dc:description), Keywords, Creator (xmp:CreatorTool), CreationDate and ModDate. pdf:Producer
+// is set to the Producer of the information dictionary, as set by SetPdfProducer or SetPdfInfo.
+func (w *PdfWriter) SetXMPMetadata(m *XMPMetadata) {
+	w.xmp = m
+}
+
+// writeXMPMetadata sets the metadata stream of `catalog` to the XMP metadata of the writer,
+// synchronized with the document information dictionary and identifying the PDF/A conformance
+// level. Called on the copies of the catalog and the document information dictionary.
+func (w *PdfWriter) writeXMPMetadata(catalog *core.PdfObjectDictionary) error {
+	m := NewXMPMetadata()
+	if w.xmp != nil {
+		// The writer must not modify the metadata of the user.
+		copied := *w.xmp
+		m = &copied
+	}
+	info, ok := core.GetDict(w.infoObj)
+	if !ok {
+		info = core.MakeDict()
+		w.infoObj.PdfObject = info
+	}
+	m.syncInfo(info)
+	if part := w.pdfa.part(); part != 0 {
+		m.PdfAPart = part
+		m.PdfAConformance = "B"
+	}
+
+	stream, err := m.ToStream()
+	if err != nil {
+		return err
+	}
+	catalog.Set("Metadata", stream)
+	return w.addObjects(stream)
+}
+
+// syncInfo initializes the properties of `m` that are not set from document information
+// dictionary `info`, then sets the entries of `info` to the properties.
+func (m *XMPMetadata) syncInfo(info *core.PdfObjectDictionary) {
+	text := func(key core.PdfObjectName, value *string) {
+		if *value == "" {
+			if s, ok := core.GetString(info.Get(key)); ok {
+				*value = s.Decoded()
+			}
+		}
+		if *value != "" {
+			info.Set(key, core.MakeEncodedString(*value, !isPDFDocEncodable(*value)))
+		}
+	}
+	date := func(key core.PdfObjectName, value *time.Time) {
+		if value.IsZero() {
+			if s, ok := core.GetString(info.Get(key)); ok {
+				if d, err := NewPdfDate(s.Str()); err == nil {
+					*value = d.ToGoTime()
+				}
+			}
+		}
+		if !value.IsZero() {
+			if d, err := NewPdfDateFromTime(*value); err == nil {
+				info.Set(key, d.ToPdfObject())
+			}
+		}
+	}
+
+	text("Title", &m.Title)
+	author := strings.Join(m.Creators, ", ")
+	text("Author", &author)
+	if len(m.Creators) == 0 && author != "" {
+		m.Creators = []string{author}
+	}
+	text("Subject", &m.Description)
+	text("Keywords", &m.Keywords)
+	text("Creator", &m.CreatorTool)
+	// The Producer of the information dictionary, set by the writer as allowed by the license, is
+	// the source of truth.
+	if s, ok := core.GetString(info.Get("Producer")); ok && s.Decoded() != "" {
+		m.Producer = s.Decoded()
+	} else {
+		m.Producer = pdfProducerOrDefault(m.Producer)
+		info.Set("Producer", core.MakeEncodedString(m.Producer, !isPDFDocEncodable(m.Producer)))
+	}
+	date("CreationDate", &m.CreateDate)
+	date("ModDate", &m.ModifyDate)
+	if m.MetadataDate.IsZero() {
+		m.MetadataDate = m.ModifyDate
+	}
+}
+
+// xmlEscape returns `s` escaped for XML character data.
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
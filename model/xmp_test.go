@@ -0,0 +1,168 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+func TestXMPMetadataRoundTrip(t *testing.T) {
+	date := time.Date(2020, 3, 4, 10, 20, 30, 0, time.FixedZone("", 3600))
+	m := model.NewXMPMetadata()
+	m.Title = "Statement <March>"
+	m.Creators = []string{"Jane Doe", "John Doe"}
+	m.Description = "Monthly statement"
+	m.Subjects = []string{"bank", "statement"}
+	m.CreatorTool = "Statements 1.0"
+	m.CreateDate = date
+	m.ModifyDate = date.Add(time.Hour)
+	m.Producer = "UniPDF"
+	m.Keywords = "bank, statement"
+	m.PdfAPart = 3
+	m.PdfAConformance = "B"
+	m.SetCustom("urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#", "fx", "DocumentType", "INVOICE")
+	m.Custom = append(m.Custom, &model.XMPProperty{
+		Namespace: "http://ns.adobe.com/xap/1.0/rights/",
+		Prefix:    "xmpRights",
+		Name:      "Owner",
+		Array:     "Bag",
+		Items:     []string{"Bank & Co"},
+	})
+
+	data, err := m.Bytes()
+	require.NoError(t, err)
+	parsed, err := model.ParseXMPMetadata(data)
+	require.NoError(t, err)
+	require.True(t, parsed.CreateDate.Equal(m.CreateDate))
+	require.True(t, parsed.ModifyDate.Equal(m.ModifyDate))
+	parsed.CreateDate, parsed.ModifyDate = m.CreateDate, m.ModifyDate
+	require.Equal(t, m, parsed)
+}
+
+func TestParseXMPMetadata(t *testing.T) {
+	packet := `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/" pdfaid:part="2" pdfaid:conformance="U"/>
+ <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/"
+   xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/">
+  <dc:title><rdf:Alt><rdf:li xml:lang="x-default">Invoice &amp; statement</rdf:li></rdf:Alt></dc:title>
+  <dc:creator><rdf:Seq><rdf:li>Jane Doe</rdf:li></rdf:Seq></dc:creator>
+  <xmpMM:DocumentID>uuid:1234</xmpMM:DocumentID>
+  <xmpMM:DerivedFrom rdf:parseType="Resource"><stRef:documentID xmlns:stRef="http://ns.adobe.com/xap/1.0/sType/ResourceRef#">uuid:0</stRef:documentID></xmpMM:DerivedFrom>
+  <xmpMM:History><rdf:Seq><rdf:li rdf:parseType="Resource" xmlns:stEvt="http://ns.adobe.com/xap/1.0/sType/ResourceEvent#"><stEvt:action>created</stEvt:action></rdf:li></rdf:Seq></xmpMM:History>
+ </rdf:Description>
+</rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+	m, err := model.ParseXMPMetadata([]byte(packet))
+	require.NoError(t, err)
+	require.Equal(t, 2, m.PdfAPart)
+	require.Equal(t, "U", m.PdfAConformance)
+	require.Equal(t, "Invoice & statement", m.Title)
+	require.Equal(t, []string{"Jane Doe"}, m.Creators)
+
+	require.Len(t, m.Custom, 3)
+	require.Equal(t, &model.XMPProperty{
+		Namespace: "http://ns.adobe.com/xap/1.0/mm/",
+		Prefix:    "xmpMM",
+		Name:      "DocumentID",
+		Value:     "uuid:1234",
+	}, m.Custom[0])
+
+	// The structure values of DerivedFrom and of the items of History are written back.
+	require.Equal(t, "DerivedFrom", m.Custom[1].Name)
+	require.Equal(t, "History", m.Custom[2].Name)
+	require.Empty(t, m.Custom[2].Items)
+	m.Title = "Invoice"
+	data, err := m.Bytes()
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<xmpMM:DerivedFrom xmlns:stRef="http://ns.adobe.com/xap/1.0/sType/ResourceRef#" `+
+		`rdf:parseType="Resource"><stRef:documentID>uuid:0</stRef:documentID></xmpMM:DerivedFrom>`)
+	require.Contains(t, string(data), `<stEvt:action>created</stEvt:action>`)
+	parsed, err := model.ParseXMPMetadata(data)
+	require.NoError(t, err)
+	require.Equal(t, "Invoice", parsed.Title)
+	require.Len(t, parsed.Custom, 3)
+	data2, err := parsed.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(data2))
+}
+
+func TestWriterXMPMetadata(t *testing.T) {
+	w := model.NewPdfWriter()
+	m := model.NewXMPMetadata()
+	m.Title = "Statement"
+	m.Creators = []string{"Jane Doe", "John Doe"}
+	w.SetXMPMetadata(m)
+
+	page := model.NewPdfPage()
+	pageMetadata := model.NewXMPMetadata()
+	pageMetadata.Title = "Summary"
+	require.NoError(t, page.SetXMPMetadata(pageMetadata))
+	require.NoError(t, w.AddPage(page))
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	require.Equal(t, []string{"Jane Doe", "John Doe"}, m.Creators)
+	require.Empty(t, m.Producer)
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	written, err := reader.GetXMPMetadata()
+	require.NoError(t, err)
+	require.Equal(t, "Statement", written.Title)
+
+	// The information dictionary is updated with the metadata and vice versa.
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	info, ok := core.GetDict(trailer.Get("Info"))
+	require.True(t, ok)
+	title, _ := core.GetString(info.Get("Title"))
+	require.Equal(t, "Statement", title.Decoded())
+	author, _ := core.GetString(info.Get("Author"))
+	require.Equal(t, "Jane Doe, John Doe", author.Decoded())
+	producer, _ := core.GetString(info.Get("Producer"))
+	require.NotEmpty(t, producer.Decoded())
+	require.Equal(t, producer.Decoded(), written.Producer)
+
+	readPage, err := reader.GetPage(1)
+	require.NoError(t, err)
+	readPageMetadata, err := readPage.GetXMPMetadata()
+	require.NoError(t, err)
+	require.Equal(t, "Summary", readPageMetadata.Title)
+
+	// The Producer of the metadata does not bypass the Producer of the information dictionary.
+	w = model.NewPdfWriter()
+	m = model.NewXMPMetadata()
+	m.Producer = "Spoofed"
+	w.SetXMPMetadata(m)
+	buf.Reset()
+	require.NoError(t, w.Write(&buf))
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	written, err = reader.GetXMPMetadata()
+	require.NoError(t, err)
+	require.Equal(t, producer.Decoded(), written.Producer)
+}
+
+func TestReaderXMPMetadataMissing(t *testing.T) {
+	w := model.NewPdfWriter()
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	m, err := reader.GetXMPMetadata()
+	require.NoError(t, err)
+	require.Nil(t, m)
+}
@@ -410,13 +410,20 @@ func NewXObjectImageFromStream(stream *core.PdfObjectStream) (*XObjectImage, err
 			return nil, err
 		}
 		img.ColorSpace = cs
+	} else if jpxEnc, ok := encoder.(*core.JPXEncoder); ok && jpxEnc.ColorComponents > 0 {
+		// JPX images may take their colorspace from the JPEG 2000 data.
+		img.ColorSpace = newJPXColorspace(jpxEnc)
 	} else {
 		// If not specified, assume gray..
 		common.Log.Debug("XObject Image colorspace not specified - assuming 1 color component")
 		img.ColorSpace = NewPdfColorspaceDeviceGray()
 	}
 
-	if obj := core.TraceToDirectObject(dict.Get("BitsPerComponent")); obj != nil {
+	if jpxEnc, ok := encoder.(*core.JPXEncoder); ok {
+		// BitsPerComponent is ignored for JPX images, whose samples are decoded to 8 bits.
+		bpc := int64(jpxEnc.BitsPerComponent)
+		img.BitsPerComponent = &bpc
+	} else if obj := core.TraceToDirectObject(dict.Get("BitsPerComponent")); obj != nil {
 		iObj, ok := obj.(*core.PdfObjectInteger)
 		if !ok {
 			return nil, errors.New("invalid image height object")
@@ -446,6 +453,31 @@ func NewXObjectImageFromStream(stream *core.PdfObjectStream) (*XObjectImage, err
 	return img, nil
 }
 
+// newJPXColorspace returns the colorspace of a JPX image whose image dictionary has no ColorSpace
+// entry, based on the colour specification of the JPEG 2000 data.
+func newJPXColorspace(enc *core.JPXEncoder) PdfColorspace {
+	var cs PdfColorspace
+	switch enc.ColorComponents {
+	case 3:
+		cs = NewPdfColorspaceDeviceRGB()
+	case 4:
+		cs = NewPdfColorspaceDeviceCMYK()
+	default:
+		cs = NewPdfColorspaceDeviceGray()
+	}
+	if enc.ICCProfile == nil {
+		return cs
+	}
+
+	icc, err := NewPdfColorspaceICCBased(cs.GetNumComponents())
+	if err != nil {
+		return cs
+	}
+	icc.Alternate = cs
+	icc.Data = enc.ICCProfile
+	return icc
+}
+
 // SetImage updates XObject Image with new image data.
 func (ximg *XObjectImage) SetImage(img *Image, cs PdfColorspace) error {
 	encoded, err := ximg.Filter.EncodeBytes(img.Data)
@@ -524,7 +556,19 @@ func (ximg *XObjectImage) ToImage() (*Image, error) {
 
 	image.ColorComponents = ximg.ColorSpace.GetNumComponents()
 
-	decoded, err := core.DecodeStream(ximg.primitive)
+	var decoded []byte
+	var err error
+	if jpxEnc, ok := ximg.Filter.(*core.JPXEncoder); ok {
+		// The opacity channel of a JPX image is its soft mask if SMaskInData is set.
+		var alpha []byte
+		decoded, alpha, err = jpxEnc.DecodeWithAlpha(ximg.primitive.Stream)
+		if err == nil && jpxEnc.SMaskInData > 0 && alpha != nil {
+			image.alphaData = alpha
+			image.hasAlpha = true
+		}
+	} else {
+		decoded, err = core.DecodeStream(ximg.primitive)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,663 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redact
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// maxFormDepth is the maximum nesting depth of the form XObjects redacted. Intersecting forms
+// nested deeper are removed.
+const maxFormDepth = 10
+
+// redactor removes the content inside regions from a content stream.
+type redactor struct {
+	// regions are the redacted regions in the default user space of the page.
+	regions []model.PdfRectangle
+
+	// base maps the user space of the content stream to the default user space of the page.
+	base transform.Matrix
+
+	// resources are the resources of the content stream, owned by the redacted content.
+	resources *model.PdfPageResources
+
+	// textSlack is the uncertainty in text space of the text position, which is not advanced by
+	// the strings shown in fonts that cannot be loaded. It is reset when a new line is started.
+	textSlack float64
+
+	depth   int
+	changed bool
+}
+
+// pathState is the path under construction.
+type pathState struct {
+	subpaths []subpathState
+	clip     bool // Whether W or W* follows the path.
+	clipOp   int  // Index of the W or W* operator.
+}
+
+// subpathState is a subpath of the path under construction, begun by m or re.
+type subpathState struct {
+	ops   []int              // Indices of the path construction operators.
+	box   model.PdfRectangle // Bounding box in the default user space of the page.
+	valid bool               // Whether box holds points.
+}
+
+// add adds the path construction operator at index `i` with the points `box` to the path. m and
+// re begin a new subpath.
+func (p *pathState) add(operand string, i int, box *model.PdfRectangle) {
+	if len(p.subpaths) == 0 || operand == "m" || operand == "re" {
+		p.subpaths = append(p.subpaths, subpathState{})
+	}
+	sp := &p.subpaths[len(p.subpaths)-1]
+	sp.ops = append(sp.ops, i)
+	if box == nil {
+		return
+	}
+	if !sp.valid {
+		sp.box, sp.valid = *box, true
+		return
+	}
+	sp.box = union(sp.box, *box)
+}
+
+// redact returns `content` with the content inside the regions of `rd` removed.
+func (rd *redactor) redact(content string) (string, error) {
+	parsed, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return "", err
+	}
+	ops := *parsed
+
+	// out[i] holds the operations replacing operation i.
+	out := make([][]*contentstream.ContentStreamOperation, len(ops))
+	index := make(map[*contentstream.ContentStreamOperation]int, len(ops))
+	for i, op := range ops {
+		out[i] = []*contentstream.ContentStreamOperation{op}
+		index[op] = i
+	}
+	// Names of the XObjects still drawn and of the XObjects no longer drawn somewhere.
+	used := map[core.PdfObjectName]bool{}
+	removed := map[core.PdfObjectName]bool{}
+	var path pathState
+
+	proc := contentstream.NewContentStreamProcessor(ops)
+	proc.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+			resources *model.PdfPageResources) error {
+			i := index[op]
+			m := rd.base.Mult(gs.CTM)
+			switch op.Operand {
+			case "m", "l", "c", "v", "y", "h", "re":
+				var box *model.PdfRectangle
+				if points, ok := pathPoints(op); ok && len(points) > 0 {
+					b := boundingBox(m, points...)
+					box = &b
+				}
+				path.add(op.Operand, i, box)
+			case "W", "W*":
+				path.clip, path.clipOp = true, i
+			case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+				rd.paintPath(op, i, gs, m, &path, out, ops)
+				path = pathState{}
+			case "BT", "ET", "Td", "TD", "Tm", "T*":
+				rd.textSlack = 0
+			case "Tj", "TJ", "'", "\"":
+				if repl, ok := rd.redactText(op, gs, m); ok {
+					out[i] = repl
+					rd.changed = true
+				}
+			case "BI":
+				if rd.intersects(boundingBox(m, 0, 0, 1, 0, 0, 1, 1, 1)) {
+					out[i] = nil
+					rd.changed = true
+				}
+			case "Do":
+				if len(op.Params) != 1 {
+					break
+				}
+				name, ok := core.GetName(op.Params[0])
+				if !ok {
+					break
+				}
+				repl, err := rd.redactXObject(*name, m)
+				if err != nil {
+					return err
+				}
+				if repl == *name {
+					used[*name] = true
+					break
+				}
+				removed[*name] = true
+				rd.changed = true
+				out[i] = nil
+				if repl != "" {
+					out[i] = []*contentstream.ContentStreamOperation{
+						{Operand: "Do", Params: []core.PdfObject{core.MakeName(string(repl))}},
+					}
+				}
+			}
+			return nil
+		})
+	if err := proc.Process(rd.resources); err != nil {
+		return "", err
+	}
+
+	// The XObjects no longer drawn are removed from the resources so that they are not written.
+	if xobjects, ok := core.GetDict(rd.resources.XObject); ok {
+		for name := range removed {
+			if !used[name] {
+				xobjects.Remove(name)
+			}
+		}
+	}
+
+	var redacted contentstream.ContentStreamOperations
+	for _, repl := range out {
+		redacted = append(redacted, repl...)
+	}
+	return redacted.String(), nil
+}
+
+// paintPath handles the path painting operator `op` at index `i` of `ops`, painting `path`.
+// The subpaths intersecting regions are removed, as clipping them would leave their coordinates
+// in the content. The replacement operations are set in `out`.
+func (rd *redactor) paintPath(op *contentstream.ContentStreamOperation, i int,
+	gs contentstream.GraphicsState, m transform.Matrix, path *pathState,
+	out [][]*contentstream.ContentStreamOperation, ops []*contentstream.ContentStreamOperation) {
+	if op.Operand == "n" {
+		return
+	}
+	pad := 0.0
+	switch op.Operand {
+	case "S", "s", "B", "B*", "b", "b*":
+		// Stroked paths extend by half the line width.
+		pad = gs.LineWidth / 2 * math.Max(m.ScalingFactorX(), m.ScalingFactorY())
+	}
+	var kept []int
+	removed, lastRemoved := false, false
+	for _, sp := range path.subpaths {
+		box := sp.box
+		box = model.PdfRectangle{Llx: box.Llx - pad, Lly: box.Lly - pad, Urx: box.Urx + pad, Ury: box.Ury + pad}
+		lastRemoved = sp.valid && rd.intersects(box)
+		if lastRemoved {
+			removed = true
+			continue
+		}
+		kept = append(kept, sp.ops...)
+	}
+	if !removed {
+		return
+	}
+	rd.changed = true
+
+	var repl []*contentstream.ContentStreamOperation
+	for _, sp := range path.subpaths {
+		for _, j := range sp.ops {
+			out[j] = nil
+		}
+	}
+	if len(kept) > 0 {
+		for _, j := range kept {
+			repl = append(repl, ops[j])
+		}
+		// s, b and b* close the last subpath, which is not the last subpath kept.
+		if lastRemoved {
+			switch op.Operand {
+			case "s":
+				op = &contentstream.ContentStreamOperation{Operand: "S"}
+			case "b":
+				op = &contentstream.ContentStreamOperation{Operand: "B"}
+			case "b*":
+				op = &contentstream.ContentStreamOperation{Operand: "B*"}
+			}
+		}
+		repl = append(repl, op)
+	}
+	if path.clip {
+		// The clipping path is kept, without painting it.
+		for _, sp := range path.subpaths {
+			for _, j := range sp.ops {
+				repl = append(repl, ops[j])
+			}
+		}
+		repl = append(repl, ops[path.clipOp], &contentstream.ContentStreamOperation{Operand: "n"})
+		out[path.clipOp] = nil
+	}
+	out[i] = repl
+}
+
+// redactText returns the operations replacing the text showing operator `op` without the glyphs
+// inside the regions, or false if no glyph is removed. The glyphs removed are replaced by
+// TJ displacements so that the position of the remaining text does not change. If the font
+// cannot be loaded, or the text follows such text on the same line, the whole text is removed when
+// it could intersect a region.
+func (rd *redactor) redactText(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+	m transform.Matrix) ([]*contentstream.ContentStreamOperation, bool) {
+	if len(op.Params) == 0 {
+		return nil, false
+	}
+	// ' and " move to the next line, " also sets the word and character spacing.
+	var repl []*contentstream.ContentStreamOperation
+	switch op.Operand {
+	case "'":
+		rd.textSlack = 0
+		repl = append(repl, &contentstream.ContentStreamOperation{Operand: "T*"})
+	case "\"":
+		if len(op.Params) != 3 {
+			return nil, false
+		}
+		rd.textSlack = 0
+		repl = append(repl,
+			&contentstream.ContentStreamOperation{Operand: "Tw", Params: op.Params[:1]},
+			&contentstream.ContentStreamOperation{Operand: "Tc", Params: op.Params[1:2]},
+			&contentstream.ContentStreamOperation{Operand: "T*"})
+	}
+	var elements []core.PdfObject
+	if op.Operand == "TJ" {
+		arr, ok := core.GetArray(op.Params[0])
+		if !ok {
+			return nil, false
+		}
+		elements = arr.Elements()
+	} else {
+		elements = op.Params[len(op.Params)-1:]
+	}
+
+	ts := gs.TextState
+	trm := m.Mult(ts.Tm)
+	font := ts.Font
+	fs, th := ts.FontSize, ts.HorizontalScaling/100
+	if font == nil || rd.textSlack > 0 {
+		// The position of the text or the glyph widths are unknown, so the text is removed if it
+		// could intersect a region: it may start anywhere within the slack of the position and
+		// each byte in a font that cannot be loaded is taken to be up to 1 em wide, plus the
+		// spacing, in either direction.
+		extent := 0.0
+		for _, obj := range elements {
+			if val, err := core.GetNumberAsFloat(obj); err == nil {
+				extent += math.Abs(val / 1000 * fs * th)
+			} else if s, ok := core.GetString(obj); ok {
+				extent += textExtent(font, s.Bytes(), ts)
+			}
+		}
+		slack := rd.textSlack
+		// The processor does not advance the text position past text in a font that cannot be
+		// loaded, so the following text on the line may be anywhere within its extent.
+		if font == nil {
+			rd.textSlack += extent
+		}
+		extent += slack
+		h := math.Abs(fs)
+		box := boundingBox(trm, -extent, ts.Rise-h, extent, ts.Rise-h, -extent, ts.Rise+h, extent, ts.Rise+h)
+		if rd.intersects(box) {
+			return repl, true
+		}
+		return nil, false
+	}
+
+	size := 1
+	if font.IsCID() {
+		size = 2
+	}
+	var vals []core.PdfObject
+	var kept []byte
+	var adjust, pos float64
+	flush := func() {
+		if len(kept) > 0 {
+			vals = append(vals, core.MakeStringFromBytes(kept))
+			kept = nil
+		}
+	}
+	removed := false
+	for _, obj := range elements {
+		if val, err := core.GetNumberAsFloat(obj); err == nil {
+			flush()
+			adjust += val
+			pos -= val / 1000 * fs * th
+			continue
+		}
+		s, ok := core.GetString(obj)
+		if !ok {
+			continue
+		}
+		data := s.Bytes()
+		for k, code := range font.BytesToCharcodes(data) {
+			metrics, _ := font.GetCharMetrics(code)
+			w := metrics.Wx / 1000 * fs
+			tx := w + ts.CharSpacing
+			// Word spacing applies to the single-byte code 32 only.
+			if code == 32 && size == 1 {
+				tx += ts.WordSpacing
+			}
+			box := boundingBox(trm, pos, ts.Rise-0.2*fs, pos+w*th, ts.Rise-0.2*fs,
+				pos, ts.Rise+0.8*fs, pos+w*th, ts.Rise+0.8*fs)
+			pos += tx * th
+			if rd.intersects(box) {
+				removed = true
+				flush()
+				if fs != 0 {
+					adjust -= tx / fs * 1000
+				}
+				continue
+			}
+			if adjust != 0 {
+				vals = append(vals, core.MakeFloat(adjust))
+				adjust = 0
+			}
+			start, end := k*size, (k+1)*size
+			if start < len(data) {
+				if end > len(data) {
+					end = len(data)
+				}
+				kept = append(kept, data[start:end]...)
+			}
+		}
+	}
+	if !removed {
+		return nil, false
+	}
+	flush()
+	if adjust != 0 {
+		vals = append(vals, core.MakeFloat(adjust))
+	}
+	repl = append(repl, &contentstream.ContentStreamOperation{
+		Operand: "TJ",
+		Params:  []core.PdfObject{core.MakeArray(vals...)},
+	})
+	return repl, true
+}
+
+// textExtent returns an upper bound of the width in text space of the string `data` shown in
+// `font` with the text state `ts`. Each byte is taken to be up to 1 em wide if `font` is nil.
+func textExtent(font *model.PdfFont, data []byte, ts contentstream.TextState) float64 {
+	fs, th := ts.FontSize, ts.HorizontalScaling/100
+	spacing := math.Abs(ts.CharSpacing) + math.Abs(ts.WordSpacing)
+	if font == nil {
+		return float64(len(data)) * (math.Abs(fs) + spacing) * math.Abs(th)
+	}
+	extent := 0.0
+	for _, code := range font.BytesToCharcodes(data) {
+		metrics, _ := font.GetCharMetrics(code)
+		extent += (math.Abs(metrics.Wx/1000*fs) + spacing) * math.Abs(th)
+	}
+	return extent
+}
+
+// redactXObject redacts the XObject `name` drawn with matrix `m`. It returns `name` if the
+// XObject is not changed, the name of the redacted copy of the XObject added to the resources
+// or an empty name if the XObject is removed.
+func (rd *redactor) redactXObject(name core.PdfObjectName, m transform.Matrix) (core.PdfObjectName, error) {
+	stream, xtype := rd.resources.GetXObjectByName(name)
+	switch xtype {
+	case model.XObjectTypeImage:
+		hits, covered := rd.hits(boundingBox(m, 0, 0, 1, 0, 0, 1, 1, 1))
+		if len(hits) == 0 {
+			return name, nil
+		}
+		if covered {
+			return "", nil
+		}
+		return rd.redactImage(stream, m, hits)
+	case model.XObjectTypeForm:
+		xform, err := model.NewXObjectFormFromStream(stream)
+		if err != nil {
+			return "", err
+		}
+		if xform.Matrix != nil {
+			arr, ok := core.GetArray(xform.Matrix)
+			if !ok || arr.Len() != 6 {
+				return "", errors.New("invalid form matrix")
+			}
+			f, err := arr.ToFloat64Array()
+			if err != nil {
+				return "", err
+			}
+			m = m.Mult(transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5]))
+		}
+		bbox, ok := core.GetArray(xform.BBox)
+		if !ok {
+			return "", errors.New("invalid form bounding box")
+		}
+		rect, err := model.NewPdfRectangle(*bbox)
+		if err != nil {
+			return "", err
+		}
+		hits, covered := rd.hits(boundingBox(m, rect.Llx, rect.Lly, rect.Urx, rect.Lly,
+			rect.Llx, rect.Ury, rect.Urx, rect.Ury))
+		if len(hits) == 0 {
+			return name, nil
+		}
+		if covered || rd.depth >= maxFormDepth {
+			return "", nil
+		}
+		return rd.redactForm(stream, xform, m)
+	}
+	return name, nil
+}
+
+// redactImage adds to the resources a copy of the image XObject `stream` drawn with matrix `m`
+// with the pixels inside the regions `hits` set to 0, and returns its name. Images that cannot
+// be decoded and image masks are removed.
+func (rd *redactor) redactImage(stream *core.PdfObjectStream, m transform.Matrix,
+	hits []model.PdfRectangle) (core.PdfObjectName, error) {
+	inv, ok := inverse(m)
+	if !ok {
+		return "", nil
+	}
+	ximg, err := model.NewXObjectImageFromStream(stream)
+	if err != nil || ximg.ColorSpace == nil {
+		common.Log.Debug("Unable to decode redacted image, removing it: %v", err)
+		return "", nil
+	}
+	img, err := ximg.ToImage()
+	if err != nil {
+		common.Log.Debug("Unable to decode redacted image, removing it: %v", err)
+		return "", nil
+	}
+
+	width, height := int(img.Width), int(img.Height)
+	bpc, comps := int(img.BitsPerComponent), img.ColorComponents
+	stride := (width*comps*bpc + 7) / 8
+	if width <= 0 || height <= 0 || bpc <= 0 || len(img.Data) < stride*height {
+		common.Log.Debug("Invalid redacted image data, removing it")
+		return "", nil
+	}
+	for _, hit := range hits {
+		// Image space is the unit square, with the first row of samples at the top.
+		box := boundingBox(inv, hit.Llx, hit.Lly, hit.Urx, hit.Lly, hit.Llx, hit.Ury, hit.Urx, hit.Ury)
+		x0 := clamp(int(math.Floor(box.Llx*float64(width))), width)
+		x1 := clamp(int(math.Ceil(box.Urx*float64(width))), width)
+		y0 := clamp(int(math.Floor((1-box.Ury)*float64(height))), height)
+		y1 := clamp(int(math.Ceil((1-box.Lly)*float64(height))), height)
+		for y := y0; y < y1; y++ {
+			for bit := x0 * comps * bpc; bit < x1*comps*bpc; bit++ {
+				img.Data[y*stride+bit/8] &^= 0x80 >> uint(bit%8)
+			}
+		}
+	}
+
+	redacted, err := model.UpdateXObjectImageFromImage(ximg, img, ximg.ColorSpace, core.NewFlateEncoder())
+	if err != nil {
+		return "", err
+	}
+	redacted.Decode = ximg.Decode
+	redacted.Intent = ximg.Intent
+	redacted.Interpolate = ximg.Interpolate
+	redacted.Mask = ximg.Mask
+	name := rd.resources.GenerateXObjectName()
+	if err := rd.resources.SetXObjectImageByName(name, redacted); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// redactForm adds to the resources a redacted copy of the form XObject `xform` of `stream` whose
+// user space is mapped to the default user space of the page by `m`, and returns its name.
+func (rd *redactor) redactForm(stream *core.PdfObjectStream, xform *model.XObjectForm,
+	m transform.Matrix) (core.PdfObjectName, error) {
+	content, err := xform.GetContentStream()
+	if err != nil {
+		return "", err
+	}
+	resources := xform.Resources
+	if resources == nil {
+		resources = rd.resources
+	}
+	resources, err = copyResources(resources)
+	if err != nil {
+		return "", err
+	}
+	sub := &redactor{regions: rd.regions, base: m, resources: resources, depth: rd.depth + 1}
+	redacted, err := sub.redact(string(content))
+	if err != nil {
+		return "", err
+	}
+
+	dict := core.MakeDict()
+	dict.Merge(stream.PdfObjectDictionary)
+	copied, err := model.NewXObjectFormFromStream(&core.PdfObjectStream{PdfObjectDictionary: dict, Stream: stream.Stream})
+	if err != nil {
+		return "", err
+	}
+	copied.Resources = resources
+	if err := copied.SetContentStream([]byte(redacted), core.NewFlateEncoder()); err != nil {
+		return "", err
+	}
+	name := rd.resources.GenerateXObjectName()
+	if err := rd.resources.SetXObjectFormByName(name, copied); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// hits returns the regions intersecting `box` and whether one of them contains `box`.
+func (rd *redactor) hits(box model.PdfRectangle) ([]model.PdfRectangle, bool) {
+	var hits []model.PdfRectangle
+	covered := false
+	for _, region := range rd.regions {
+		if !intersects(box, region) {
+			continue
+		}
+		hits = append(hits, region)
+		if box.Llx >= region.Llx && box.Urx <= region.Urx && box.Lly >= region.Lly && box.Ury <= region.Ury {
+			covered = true
+		}
+	}
+	return hits, covered
+}
+
+// intersects returns true if `box` intersects a region.
+func (rd *redactor) intersects(box model.PdfRectangle) bool {
+	for _, region := range rd.regions {
+		if intersects(box, region) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyResources returns a copy of `resources` whose fonts and XObjects can be changed without
+// changing `resources`.
+func copyResources(resources *model.PdfPageResources) (*model.PdfPageResources, error) {
+	dict, ok := core.GetDict(resources.ToPdfObject())
+	if !ok {
+		return nil, core.ErrTypeError
+	}
+	copied := core.MakeDict()
+	for _, key := range dict.Keys() {
+		val := dict.Get(key)
+		if key == "Font" || key == "XObject" {
+			if sub, ok := core.GetDict(val); ok {
+				subCopy := core.MakeDict()
+				subCopy.Merge(sub)
+				val = subCopy
+			}
+		}
+		copied.Set(key, val)
+	}
+	return model.NewPdfPageResourcesFromDict(copied)
+}
+
+// pathPoints returns the coordinates of the points of the path construction operator `op`.
+func pathPoints(op *contentstream.ContentStreamOperation) ([]float64, bool) {
+	f, err := core.GetNumbersAsFloat(op.Params)
+	if err != nil {
+		return nil, false
+	}
+	if op.Operand == "re" {
+		if len(f) != 4 {
+			return nil, false
+		}
+		x, y, w, h := f[0], f[1], f[2], f[3]
+		return []float64{x, y, x + w, y, x, y + h, x + w, y + h}, true
+	}
+	if len(f)%2 != 0 {
+		return nil, false
+	}
+	return f, true
+}
+
+// boundingBox returns the bounding box of the points of coordinates `xy` transformed by `m`.
+func boundingBox(m transform.Matrix, xy ...float64) model.PdfRectangle {
+	box := model.PdfRectangle{Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1)}
+	for i := 0; i+1 < len(xy); i += 2 {
+		x, y := m.Transform(xy[i], xy[i+1])
+		box.Llx, box.Urx = math.Min(box.Llx, x), math.Max(box.Urx, x)
+		box.Lly, box.Ury = math.Min(box.Lly, y), math.Max(box.Ury, y)
+	}
+	return box
+}
+
+// union returns the bounding box of `a` and `b`.
+func union(a, b model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(a.Llx, b.Llx), Lly: math.Min(a.Lly, b.Lly),
+		Urx: math.Max(a.Urx, b.Urx), Ury: math.Max(a.Ury, b.Ury),
+	}
+}
+
+// intersects returns true if `a` and `b` overlap.
+func intersects(a, b model.PdfRectangle) bool {
+	return overlaps(a.Llx, a.Urx, b.Llx, b.Urx) && overlaps(a.Lly, a.Ury, b.Lly, b.Ury)
+}
+
+// overlaps returns true if the ranges `a0` to `a1` and `b0` to `b1` overlap. An empty range, such
+// as the width of a vertical line, overlaps the ranges containing it.
+func overlaps(a0, a1, b0, b1 float64) bool {
+	if a0 == a1 {
+		return a0 >= b0 && a0 <= b1
+	}
+	return a0 < b1 && b0 < a1
+}
+
+// inverse returns the inverse of `m`, or false if `m` is not invertible.
+func inverse(m transform.Matrix) (transform.Matrix, bool) {
+	a, b, c, d, e, f := m[0], m[1], m[3], m[4], m[6], m[7]
+	det := a*d - b*c
+	if math.Abs(det) < 1e-12 {
+		return transform.Matrix{}, false
+	}
+	return transform.NewMatrix(d/det, -b/det, -c/det, a/det, (c*f-d*e)/det, (b*e-a*f)/det), true
+}
+
+// clamp returns `v` clamped to the range 0 to `max`.
+func clamp(v, max int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,27 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package redact removes content from pages. Unlike drawing a box over content, redaction
+// removes the text glyphs, vector paths and image pixels inside the redacted regions from the
+// content streams, so that the content can neither be seen nor extracted, and then paints the
+// overlay of the regions. The subpaths of vector paths that cross a region are removed whole.
+//
+// The regions come from Redact annotations, applied and removed by ApplyRedactions, or are
+// specified directly with RedactPage.
+//
+// Example:
+//
+//	page, err := reader.GetPage(1)
+//	...
+//	// Apply the Redact annotations of the page.
+//	numRedactions, err := redact.ApplyRedactions(page)
+//	...
+//	// Redact an area and fill it with black.
+//	err = redact.RedactPage(page, []redact.Region{{
+//		Rect:  model.PdfRectangle{Llx: 100, Lly: 500, Urx: 300, Ury: 520},
+//		Color: model.NewPdfColorDeviceRGB(0, 0, 0),
+//	}})
+//	...
+package redact
@@ -0,0 +1,308 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Alignments of the overlay text, as the Q entry of Redact annotations.
+const (
+	AlignLeft   = 0
+	AlignCenter = 1
+	AlignRight  = 2
+)
+
+// Region is an area of a page whose content is removed, and its overlay.
+type Region struct {
+	// Rect is the area, in the default user space of the page.
+	Rect model.PdfRectangle
+
+	// Color is the color the area is filled with after its content is removed. The area is left
+	// empty if nil.
+	Color *model.PdfColorDeviceRGB
+
+	// OverlayText is drawn in Helvetica in the area, clipped to it.
+	OverlayText string
+
+	// TextColor is the color of OverlayText, black if nil.
+	TextColor *model.PdfColorDeviceRGB
+
+	// FontSize is the font size of OverlayText. If 0, the text is sized to fit the area.
+	FontSize float64
+
+	// Alignment is the horizontal alignment of OverlayText: AlignLeft, AlignCenter or
+	// AlignRight. The text is centered vertically.
+	Alignment int
+}
+
+// RedactPage removes the content of `page` inside `regions` and draws the overlay of the regions.
+// Text glyphs inside the regions are removed from the text showing operators, image pixels inside
+// the regions are set to 0 and paths inside the regions are removed. Images and paths partially
+// inside the regions are kept outside of them. Form XObjects are redacted recursively. The
+// annotations of the page are not changed.
+func RedactPage(page *model.PdfPage, regions []Region) error {
+	if len(regions) == 0 {
+		return nil
+	}
+	rects := make([]model.PdfRectangle, len(regions))
+	for i, region := range regions {
+		rects[i] = normalize(region.Rect)
+	}
+
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	resources := model.NewPdfPageResources()
+	if page.Resources != nil {
+		if resources, err = copyResources(page.Resources); err != nil {
+			return err
+		}
+	}
+	rd := &redactor{regions: rects, base: transform.IdentityMatrix(), resources: resources}
+	redacted, err := rd.redact(content)
+	if err != nil {
+		return err
+	}
+	overlay, err := drawOverlay(regions, resources)
+	if err != nil {
+		return err
+	}
+
+	page.Resources = resources
+	return page.SetContentStreams([]string{"q\n" + redacted + "Q\n" + overlay}, core.NewFlateEncoder())
+}
+
+// ApplyRedactions applies the Redact annotations of `page` with RedactPage and removes them and
+// their pop-up annotations from the page. It returns the number of Redact annotations applied.
+// The RO overlay appearance and the Repeat entry of the annotations are not used.
+func ApplyRedactions(page *model.PdfPage) (int, error) {
+	annots, err := page.GetAnnotations()
+	if err != nil {
+		return 0, err
+	}
+	var regions []Region
+	var redactAnnots []*model.PdfAnnotationRedact
+	for _, annot := range annots {
+		redact, ok := annot.GetContext().(*model.PdfAnnotationRedact)
+		if !ok {
+			continue
+		}
+		annotRegions, err := RegionsFromAnnotation(redact)
+		if err != nil {
+			return 0, err
+		}
+		regions = append(regions, annotRegions...)
+		redactAnnots = append(redactAnnots, redact)
+	}
+	if len(redactAnnots) == 0 {
+		return 0, nil
+	}
+	if err := RedactPage(page, regions); err != nil {
+		return 0, err
+	}
+
+	var kept []*model.PdfAnnotation
+	for _, annot := range annots {
+		if !isRedaction(annot, redactAnnots) {
+			kept = append(kept, annot)
+		}
+	}
+	if len(kept) == 0 {
+		page.Annots = nil
+	}
+	page.SetAnnotations(kept)
+	return len(redactAnnots), nil
+}
+
+// isRedaction returns true if `annot` is one of `redactAnnots` or one of their pop-ups.
+func isRedaction(annot *model.PdfAnnotation, redactAnnots []*model.PdfAnnotationRedact) bool {
+	popup, isPopup := annot.GetContext().(*model.PdfAnnotationPopup)
+	for _, redact := range redactAnnots {
+		if annot == redact.PdfAnnotation {
+			return true
+		}
+		if redact.Popup != nil && annot == redact.Popup.PdfAnnotation {
+			return true
+		}
+		if isPopup && popup.Parent != nil && popup.Parent == redact.GetContainingPdfObject() {
+			return true
+		}
+	}
+	return false
+}
+
+// RegionsFromAnnotation returns the regions redacted by `annot`: one per quadrilateral of its
+// QuadPoints, or its Rect. The regions are filled with its IC interior color and show its
+// OverlayText with the font size and color of its DA default appearance, aligned by its Q.
+func RegionsFromAnnotation(annot *model.PdfAnnotationRedact) ([]Region, error) {
+	var region Region
+	if arr, ok := core.GetArray(annot.IC); ok && arr.Len() == 3 {
+		c, err := arr.ToFloat64Array()
+		if err != nil {
+			return nil, err
+		}
+		region.Color = model.NewPdfColorDeviceRGB(c[0], c[1], c[2])
+	}
+	if s, ok := core.GetString(annot.OverlayText); ok {
+		region.OverlayText = s.Decoded()
+	}
+	if q, ok := core.GetIntVal(annot.Q); ok {
+		region.Alignment = q
+	}
+	if s, ok := core.GetString(annot.DA); ok {
+		region.FontSize, region.TextColor = parseDA(s.Str())
+	}
+
+	var rects []model.PdfRectangle
+	if arr, ok := core.GetArray(annot.QuadPoints); ok {
+		points, err := arr.ToFloat64Array()
+		if err != nil {
+			return nil, err
+		}
+		if len(points)%8 != 0 {
+			common.Log.Debug("ERROR: Invalid number of quad points %d", len(points))
+			return nil, errors.New("invalid quad points")
+		}
+		for i := 0; i < len(points); i += 8 {
+			rects = append(rects, boundingBox(transform.IdentityMatrix(), points[i:i+8]...))
+		}
+	}
+	if len(rects) == 0 {
+		arr, ok := core.GetArray(annot.Rect)
+		if !ok {
+			common.Log.Debug("ERROR: Redact annotation without Rect or QuadPoints")
+			return nil, errors.New("redact annotation area missing")
+		}
+		rect, err := model.NewPdfRectangle(*arr)
+		if err != nil {
+			return nil, err
+		}
+		rects = append(rects, *rect)
+	}
+
+	regions := make([]Region, len(rects))
+	for i, rect := range rects {
+		regions[i] = region
+		regions[i].Rect = rect
+	}
+	return regions, nil
+}
+
+// parseDA returns the font size and the color set by the default appearance string `da`, 0 and
+// nil when not set.
+func parseDA(da string) (float64, *model.PdfColorDeviceRGB) {
+	ops, err := contentstream.NewContentStreamParser(da).Parse()
+	if err != nil {
+		common.Log.Debug("Invalid default appearance %q: %v", da, err)
+		return 0, nil
+	}
+	var size float64
+	var color *model.PdfColorDeviceRGB
+	for _, op := range *ops {
+		switch op.Operand {
+		case "Tf":
+			if len(op.Params) == 2 {
+				size, _ = core.GetNumberAsFloat(op.Params[1])
+			}
+		case "g":
+			if f, err := core.GetNumbersAsFloat(op.Params); err == nil && len(f) == 1 {
+				color = model.NewPdfColorDeviceRGB(f[0], f[0], f[0])
+			}
+		case "rg":
+			if f, err := core.GetNumbersAsFloat(op.Params); err == nil && len(f) == 3 {
+				color = model.NewPdfColorDeviceRGB(f[0], f[1], f[2])
+			}
+		case "k":
+			if f, err := core.GetNumbersAsFloat(op.Params); err == nil && len(f) == 4 {
+				color = model.NewPdfColorDeviceRGB((1-f[0])*(1-f[3]), (1-f[1])*(1-f[3]), (1-f[2])*(1-f[3]))
+			}
+		}
+	}
+	return size, color
+}
+
+// drawOverlay returns the content drawing the overlay of `regions`, adding the font of the
+// overlay text to `resources`.
+func drawOverlay(regions []Region, resources *model.PdfPageResources) (string, error) {
+	cc := contentstream.NewContentCreator()
+	var font *model.PdfFont
+	var fontName core.PdfObjectName
+	for _, region := range regions {
+		rect := normalize(region.Rect)
+		if c := region.Color; c != nil {
+			cc.Add_q().Add_rg(c.R(), c.G(), c.B()).
+				Add_re(rect.Llx, rect.Lly, rect.Width(), rect.Height()).Add_f().Add_Q()
+		}
+		if region.OverlayText == "" {
+			continue
+		}
+
+		if font == nil {
+			var err error
+			font, err = model.NewStandard14Font(model.HelveticaName)
+			if err != nil {
+				return "", err
+			}
+			for i := 1; ; i++ {
+				fontName = core.PdfObjectName(fmt.Sprintf("Font%d", i))
+				if !resources.HasFontByName(fontName) {
+					break
+				}
+			}
+			if err := resources.SetFontByName(fontName, font.ToPdfObject()); err != nil {
+				return "", err
+			}
+		}
+		var width float64
+		for _, r := range region.OverlayText {
+			metrics, _ := font.GetRuneMetrics(r)
+			width += metrics.Wx / 1000
+		}
+		size := region.FontSize
+		if size <= 0 {
+			size = 0.8 * rect.Height()
+			if width > 0 {
+				size = math.Min(size, rect.Width()/width)
+			}
+		}
+		x := rect.Llx
+		switch region.Alignment {
+		case AlignCenter:
+			x += (rect.Width() - width*size) / 2
+		case AlignRight:
+			x += rect.Width() - width*size
+		}
+		// Centers the capital letters, of height 0.7 in Helvetica.
+		y := rect.Lly + (rect.Height()-0.7*size)/2
+		textColor := region.TextColor
+		if textColor == nil {
+			textColor = model.NewPdfColorDeviceRGB(0, 0, 0)
+		}
+		cc.Add_q().Add_re(rect.Llx, rect.Lly, rect.Width(), rect.Height()).Add_W().Add_n().
+			Add_BT().Add_Tf(fontName, size).Add_rg(textColor.R(), textColor.G(), textColor.B()).
+			Add_Td(x, y).Add_Tj(*core.MakeStringFromBytes(font.Encoder().Encode(region.OverlayText))).
+			Add_ET().Add_Q()
+	}
+	return cc.String(), nil
+}
+
+// normalize returns `rect` with its lower left corner before its upper right corner.
+func normalize(rect model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(rect.Llx, rect.Urx), Lly: math.Min(rect.Lly, rect.Ury),
+		Urx: math.Max(rect.Llx, rect.Urx), Ury: math.Max(rect.Lly, rect.Ury),
+	}
+}
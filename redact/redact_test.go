@@ -0,0 +1,192 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redact
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/extractor"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// makePage returns the page of a written document with two lines of text, two paths, a 10x10
+// white image Im1 at 200, 0 scaled to 100x100, and a Redact annotation over "John Smith".
+func makePage(t *testing.T) *model.PdfPage {
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+	res := model.NewPdfPageResources()
+	require.NoError(t, res.SetFontByName("F1", font.ToPdfObject()))
+	img, err := core.MakeStream(bytes.Repeat([]byte{255}, 100), nil)
+	require.NoError(t, err)
+	img.Set("Type", core.MakeName("XObject"))
+	img.Set("Subtype", core.MakeName("Image"))
+	img.Set("Width", core.MakeInteger(10))
+	img.Set("Height", core.MakeInteger(10))
+	img.Set("ColorSpace", core.MakeName("DeviceGray"))
+	img.Set("BitsPerComponent", core.MakeInteger(8))
+	require.NoError(t, res.SetXObjectByName("Im1", img))
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 400, Ury: 200}
+	page.Resources = res
+	content := "BT /F1 12 Tf 10 100 Td (Name: John Smith) Tj ET\n" +
+		"BT /F1 12 Tf 10 50 Td (Keep this line) Tj ET\n" +
+		"150 40 20 20 re f\n" +
+		"150 150 100 5 re f\n" +
+		"q 100 0 0 100 200 0 cm /Im1 Do Q\n"
+	require.NoError(t, page.SetContentStreams([]string{content}, core.NewRawEncoder()))
+
+	// "Name: " is 38.68 wide at 12 points.
+	annot := model.NewPdfAnnotationRedact()
+	annot.Rect = core.MakeArrayFromFloats([]float64{47, 95, 200, 160})
+	annot.IC = core.MakeArrayFromFloats([]float64{0, 0, 0})
+	annot.OverlayText = core.MakeString("REDACTED")
+	annot.DA = core.MakeString("/Helv 10 Tf 1 g")
+	page.AddAnnotation(annot.PdfAnnotation)
+
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err = reader.GetPage(1)
+	require.NoError(t, err)
+	return page
+}
+
+// pageText returns the text extracted from `page`.
+func pageText(t *testing.T, page *model.PdfPage) string {
+	ex, err := extractor.New(page)
+	require.NoError(t, err)
+	text, err := ex.ExtractText()
+	require.NoError(t, err)
+	return text
+}
+
+func TestApplyRedactions(t *testing.T) {
+	page := makePage(t)
+	require.Contains(t, pageText(t, page), "John Smith")
+
+	n, err := ApplyRedactions(page)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	annots, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Empty(t, annots)
+
+	text := pageText(t, page)
+	require.Contains(t, text, "Name:")
+	require.Contains(t, text, "Keep this line")
+	require.NotContains(t, text, "John")
+	require.NotContains(t, text, "Smith")
+
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	// The path partially inside the region is removed and the overlay is painted.
+	require.NotContains(t, content, "150 150 100 5 re")
+	require.Contains(t, content, "0 0 0 rg\n47 95 153 65 re\nf")
+	require.Contains(t, content, "1 1 1 rg")
+
+	// Nothing to apply once the annotations are removed.
+	n, err = ApplyRedactions(page)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	require.NotContains(t, buf.String(), "/Redact")
+}
+
+func TestRedactPage(t *testing.T) {
+	page := makePage(t)
+	err := RedactPage(page, []Region{
+		{Rect: model.PdfRectangle{Llx: 140, Lly: 30, Urx: 180, Ury: 70}},
+		{Rect: model.PdfRectangle{Llx: 250, Lly: 0, Urx: 320, Ury: 50}},
+	})
+	require.NoError(t, err)
+	require.Contains(t, pageText(t, page), "John Smith")
+
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, content, "150 40 20 20 re")
+	require.Contains(t, content, "150 150 100 5 re")
+	require.False(t, strings.Contains(content, "/Im1 Do"))
+
+	// The image is replaced by a copy with the lower right quarter blanked.
+	require.False(t, page.Resources.HasXObjectByName("Im1"))
+	ximg, err := page.Resources.GetXObjectImageByName("XObj1")
+	require.NoError(t, err)
+	img, err := ximg.ToImage()
+	require.NoError(t, err)
+	require.Equal(t, byte(255), img.Data[0])
+	require.Equal(t, byte(255), img.Data[4*10+9])
+	require.Equal(t, byte(0), img.Data[5*10+5])
+	require.Equal(t, byte(0), img.Data[9*10+9])
+
+	// Images inside a region are removed.
+	require.NoError(t, RedactPage(page, []Region{{Rect: model.PdfRectangle{Llx: 190, Urx: 310, Ury: 110}}}))
+	content, err = page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, content, " Do")
+	require.False(t, page.Resources.HasXObjectByName("XObj1"))
+}
+
+func TestRedactPaths(t *testing.T) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 400, Ury: 200}
+	page.Resources = model.NewPdfPageResources()
+	content := "10 10 m 50 10 l 100 100 m 140 100 l s\n" +
+		"0 0 50 50 re 100 80 20 20 re W f\n"
+	require.NoError(t, page.SetContentStreams([]string{content}, core.NewRawEncoder()))
+	require.NoError(t, RedactPage(page, []Region{{Rect: model.PdfRectangle{Llx: 90, Lly: 90, Urx: 150, Ury: 110}}}))
+
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	// The subpaths intersecting the region are removed. The last subpath kept is not closed.
+	require.Contains(t, content, "10 10 m\n50 10 l\nS\n")
+	require.NotContains(t, content, "100 100 m")
+	require.NotContains(t, content, "140 100 l")
+	// The clipping path is kept without painting it.
+	require.Contains(t, content, "0 0 50 50 re\nf\n0 0 50 50 re\n100 80 20 20 re\nW\nn\n")
+}
+
+// TestRedactTextWithoutFont checks that text in a font that cannot be loaded is removed when it
+// could intersect a region, even if it starts outside it.
+func TestRedactTextWithoutFont(t *testing.T) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 400, Ury: 200}
+	page.Resources = model.NewPdfPageResources()
+	content := "BT /F9 12 Tf 10 100 Td (Secret text) Tj ET\n" +
+		"BT /F9 12 Tf 10 20 Td (Public text) Tj ET\n"
+	require.NoError(t, page.SetContentStreams([]string{content}, core.NewRawEncoder()))
+	require.NoError(t, RedactPage(page, []Region{{Rect: model.PdfRectangle{Llx: 60, Lly: 95, Urx: 80, Ury: 110}}}))
+
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, content, "Secret")
+	require.Contains(t, content, "(Public text) Tj")
+
+	// The text position is not advanced past text in a font that cannot be loaded, so the
+	// following strings on the line may be inside the region, until the next line is started.
+	content = "BT /F9 12 Tf 10 100 Td (AAAAAAAAAAAAAAAAAAAAAAAA) Tj (Secret) Tj 0 -80 Td (Public) Tj ET\n" +
+		"BT /F1 12 Tf 10 150 Td (AAAAAAAAAAAAAAAAAAAAAAAA) Tj /F9 12 Tf (Also secret) Tj ET\n"
+	require.NoError(t, page.SetContentStreams([]string{content}, core.NewRawEncoder()))
+	require.NoError(t, RedactPage(page, []Region{{Rect: model.PdfRectangle{Llx: 200, Lly: 0, Urx: 300, Ury: 200}}}))
+
+	content, err = page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, content, "Secret")
+	require.NotContains(t, content, "secret")
+	require.Contains(t, content, "(Public) Tj")
+}
@@ -0,0 +1,20 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package render rasterizes PDF pages to images.
+//
+// Pages are drawn on a white background with anti-aliasing. Paths, clipping, images and image
+// masks, shadings and patterns, text with embedded TrueType, Type 1 and CFF fonts, Type 3 fonts,
+// transparency groups, soft masks and blend modes are supported. Text in fonts that are not
+// embedded is drawn with a built-in substitute font.
+//
+// Example:
+//
+//	img, err := render.RenderPage(page, 150)
+//	if err != nil {
+//		return err
+//	}
+//	err = png.Encode(w, img)
+package render
@@ -0,0 +1,547 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/outline"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Font descriptor flags (Table 123).
+const (
+	flagFixedPitch = 1 << 0
+	flagSymbolic   = 1 << 2
+	flagItalic     = 1 << 6
+	flagForceBold  = 1 << 18
+)
+
+// renderFont is a font with the glyph outlines needed to draw text.
+type renderFont struct {
+	font  *model.PdfFont // Nil for Type 3 fonts and fonts that could not be loaded.
+	cid   bool
+	type3 *type3Font
+
+	// Embedded font program. At most one of these is set.
+	tt  *outline.TrueType
+	cff *outline.CFF
+	t1  *outline.Type1
+
+	symbolic bool
+	// hasEncoding is true if the font dictionary has an Encoding entry.
+	hasEncoding bool
+	// baseEncoding is the base encoding of a simple font, or nil.
+	baseEncoding textencoding.SimpleEncoder
+	differences  map[textencoding.CharCode]textencoding.GlyphName
+	// cidToGID maps CIDs to glyph indices of CIDFontType2 fonts. It is nil for the identity
+	// mapping.
+	cidToGID []uint16
+
+	// fallback is the substitute font used for glyphs that are not embedded.
+	fallback *outline.TrueType
+
+	glyphs map[textencoding.CharCode]*path
+}
+
+// loadFont returns the font for the font dictionary `obj`.
+func (r *renderer) loadFont(obj core.PdfObject) *renderFont {
+	if f, ok := r.fonts[obj]; ok {
+		return f
+	}
+	f := newRenderFont(obj)
+	r.fonts[obj] = f
+	return f
+}
+
+func newRenderFont(obj core.PdfObject) *renderFont {
+	f := &renderFont{glyphs: map[textencoding.CharCode]*path{}}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Font not a dictionary: %T", obj)
+		f.fallback = fallbackFont(false, false, false)
+		return f
+	}
+	subtype, _ := core.GetNameVal(dict.Get("Subtype"))
+	if subtype == "Type3" {
+		f.type3 = newType3Font(dict)
+		return f
+	}
+
+	font, err := model.NewPdfFontFromPdfObject(obj)
+	if err != nil {
+		common.Log.Debug("Font not fully supported: %v", err)
+	}
+	if font != nil && font.Encoder() != nil {
+		f.font = font
+	}
+
+	descriptor, _ := core.GetDict(dict.Get("FontDescriptor"))
+	if subtype == "Type0" {
+		f.cid = true
+		if arr, ok := core.GetArray(dict.Get("DescendantFonts")); ok && arr.Len() > 0 {
+			if desc, ok := core.GetDict(arr.Get(0)); ok {
+				descriptor, _ = core.GetDict(desc.Get("FontDescriptor"))
+				f.cidToGID = readCIDToGIDMap(desc.Get("CIDToGIDMap"))
+			}
+		}
+	} else {
+		f.readEncoding(dict.Get("Encoding"))
+	}
+
+	var flags int
+	var fontName string
+	if descriptor != nil {
+		flags, _ = core.GetIntVal(descriptor.Get("Flags"))
+		fontName, _ = core.GetNameVal(descriptor.Get("FontName"))
+		f.loadProgram(descriptor)
+	}
+	if fontName == "" {
+		fontName, _ = core.GetNameVal(dict.Get("BaseFont"))
+	}
+	f.symbolic = flags&flagSymbolic != 0 || fontName == "Symbol" || fontName == "ZapfDingbats"
+
+	if f.tt == nil && f.cff == nil && f.t1 == nil {
+		weight, _ := core.GetNumberAsFloat(descriptorEntry(descriptor, "FontWeight"))
+		bold := flags&flagForceBold != 0 || weight >= 600 || strings.Contains(fontName, "Bold")
+		italic := flags&flagItalic != 0 || strings.Contains(fontName, "Italic") ||
+			strings.Contains(fontName, "Oblique")
+		fixed := flags&flagFixedPitch != 0 || strings.Contains(fontName, "Courier")
+		f.fallback = fallbackFont(bold, italic, fixed)
+	}
+	return f
+}
+
+// descriptorEntry returns the entry `key` of the font descriptor `d`, which may be nil.
+func descriptorEntry(d *core.PdfObjectDictionary, key core.PdfObjectName) core.PdfObject {
+	if d == nil {
+		return nil
+	}
+	return d.Get(key)
+}
+
+// loadProgram parses the font program embedded in the font descriptor `descriptor`.
+func (f *renderFont) loadProgram(descriptor *core.PdfObjectDictionary) {
+	for _, key := range []core.PdfObjectName{"FontFile", "FontFile2", "FontFile3"} {
+		stream, ok := core.GetStream(descriptor.Get(key))
+		if !ok {
+			continue
+		}
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			common.Log.Debug("ERROR: Decoding font program: %v", err)
+			return
+		}
+		subtype, _ := core.GetNameVal(stream.Get("Subtype"))
+		switch {
+		case key == "FontFile":
+			f.t1, err = outline.ParseType1(data)
+		case key == "FontFile2" || subtype == "OpenType":
+			f.tt, err = outline.ParseTrueType(data)
+		default:
+			f.cff, err = outline.ParseCFF(data)
+		}
+		if err != nil {
+			common.Log.Debug("ERROR: Parsing font program %s: %v", key, err)
+		}
+		return
+	}
+}
+
+// readEncoding reads the Encoding entry `obj` of a simple font.
+func (f *renderFont) readEncoding(obj core.PdfObject) {
+	obj = core.ResolveReference(obj)
+	if obj == nil {
+		return
+	}
+	f.hasEncoding = true
+	baseName, ok := core.GetNameVal(obj)
+	if dict, isDict := core.GetDict(obj); isDict {
+		baseName, ok = core.GetNameVal(dict.Get("BaseEncoding"))
+		if arr, ok := core.GetArray(dict.Get("Differences")); ok {
+			diffs, err := textencoding.FromFontDifferences(arr)
+			if err != nil {
+				common.Log.Debug("ERROR: Invalid Differences: %v", err)
+			}
+			f.differences = diffs
+		}
+	}
+	if ok {
+		enc, err := textencoding.NewSimpleTextEncoder(baseName, nil)
+		if err == nil {
+			f.baseEncoding = enc
+		}
+	}
+}
+
+// readCIDToGIDMap reads the CIDToGIDMap stream `obj` of a CIDFontType2 font.
+func readCIDToGIDMap(obj core.PdfObject) []uint16 {
+	stream, ok := core.GetStream(obj)
+	if !ok {
+		return nil
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Decoding CIDToGIDMap: %v", err)
+		return nil
+	}
+	m := make([]uint16, len(data)/2)
+	for i := range m {
+		m[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+	}
+	return m
+}
+
+// codes returns the character codes of the string `data`.
+func (f *renderFont) codes(data []byte) []textencoding.CharCode {
+	if f.font != nil {
+		return f.font.BytesToCharcodes(data)
+	}
+	codes := make([]textencoding.CharCode, len(data))
+	for i, b := range data {
+		codes[i] = textencoding.CharCode(b)
+	}
+	return codes
+}
+
+// width returns the advance width of `code` in text space units.
+func (f *renderFont) width(code textencoding.CharCode) float64 {
+	if f.type3 != nil {
+		return f.type3.width(code)
+	}
+	if f.font != nil {
+		if m, ok := f.font.GetCharMetrics(code); ok && m.Wx > 0 {
+			return m.Wx / 1000
+		}
+	}
+	if f.tt != nil {
+		if gid, ok := f.glyphIndex(code); ok {
+			if adv, ok := f.tt.Advance(gid); ok {
+				return float64(adv) / float64(f.tt.UnitsPerEm())
+			}
+		}
+	}
+	return 0
+}
+
+// glyphName returns the glyph name of `code` given by the encoding of a simple font.
+func (f *renderFont) glyphName(code textencoding.CharCode) (string, bool) {
+	if name, ok := f.differences[code]; ok {
+		return string(name), true
+	}
+	enc := f.baseEncoding
+	if enc == nil {
+		if f.hasEncoding || f.t1 != nil || f.cff != nil || f.symbolic {
+			// Fonts with embedded Type 1 programs use the built-in encoding of the program.
+			return "", false
+		}
+		enc, _ = textencoding.NewSimpleTextEncoder("StandardEncoding", nil)
+		if enc == nil {
+			return "", false
+		}
+	}
+	r, ok := enc.CharcodeToRune(code)
+	if !ok {
+		return "", false
+	}
+	name, ok := textencoding.RuneToGlyph(r)
+	return string(name), ok
+}
+
+// glyphIndex returns the glyph index of `code` in the embedded TrueType or CFF font program.
+func (f *renderFont) glyphIndex(code textencoding.CharCode) (uint16, bool) {
+	if f.cid {
+		// Only the Identity CMaps are supported, so the codes are CIDs.
+		cid := uint16(code)
+		switch {
+		case f.cff != nil && f.cff.IsCIDKeyed():
+			return f.cff.GlyphIndexByCID(cid)
+		case f.tt != nil && f.cidToGID != nil:
+			if int(cid) < len(f.cidToGID) {
+				return f.cidToGID[cid], true
+			}
+			return 0, false
+		}
+		return cid, true
+	}
+
+	if f.cff != nil {
+		if name, ok := f.glyphName(code); ok {
+			if gid, ok := f.cff.GlyphIndexByName(name); ok {
+				return gid, true
+			}
+		}
+		return f.cff.GlyphIndexByCode(byte(code))
+	}
+	if f.tt == nil {
+		return 0, false
+	}
+	// Glyph selection for TrueType fonts (9.6.6.4).
+	tt := f.tt
+	if !f.symbolic || f.hasEncoding {
+		if name, ok := f.glyphName(code); ok {
+			if r, ok := textencoding.GlyphToRune(textencoding.GlyphName(name)); ok {
+				if gid, ok := tt.Lookup(3, 1, uint32(r)); ok {
+					return gid, true
+				}
+			}
+			if gid, ok := tt.GlyphIndexByName(name); ok {
+				return gid, true
+			}
+		}
+	}
+	for _, base := range []uint32{0, 0xf000, 0xf100, 0xf200} {
+		if gid, ok := tt.Lookup(3, 0, base+uint32(code)); ok {
+			return gid, true
+		}
+	}
+	if gid, ok := tt.Lookup(1, 0, uint32(code)); ok {
+		return gid, true
+	}
+	if f.font != nil {
+		if r, ok := f.font.Encoder().CharcodeToRune(code); ok {
+			if gid, ok := tt.GlyphIndex(r); ok {
+				return gid, true
+			}
+		}
+	}
+	if !tt.HasCmap(3, 0) && !tt.HasCmap(3, 1) && !tt.HasCmap(1, 0) {
+		return uint16(code), true
+	}
+	return 0, false
+}
+
+// glyph returns the outline of `code` in text space for a font size of 1, or nil if the font
+// has no glyph for it.
+func (f *renderFont) glyph(code textencoding.CharCode) *path {
+	if p, ok := f.glyphs[code]; ok {
+		return p
+	}
+	p := f.loadGlyph(code)
+	f.glyphs[code] = p
+	return p
+}
+
+func (f *renderFont) loadGlyph(code textencoding.CharCode) *path {
+	var g outline.Glyph
+	var m [6]float64
+	var err error
+	switch {
+	case f.t1 != nil:
+		name, ok := f.glyphName(code)
+		if !ok || !f.t1.HasGlyph(name) {
+			name, ok = f.t1.GlyphName(byte(code))
+		}
+		if !ok {
+			return nil
+		}
+		g, err = f.t1.Glyph(name)
+		m = f.t1.FontMatrix()
+	case f.cff != nil:
+		gid, ok := f.glyphIndex(code)
+		if !ok {
+			return nil
+		}
+		g, err = f.cff.Glyph(gid)
+		m = f.cff.FontMatrix()
+	case f.tt != nil:
+		gid, ok := f.glyphIndex(code)
+		if !ok {
+			return nil
+		}
+		g, err = f.tt.Glyph(gid)
+		m = f.tt.FontMatrix()
+	case f.fallback != nil:
+		return f.fallbackGlyph(code)
+	default:
+		return nil
+	}
+	if err != nil {
+		common.Log.Debug("Glyph for code %d not available: %v", code, err)
+		return nil
+	}
+	return glyphPath(g, transform.NewMatrix(m[0], m[1], m[2], m[3], m[4], m[5]))
+}
+
+// fallbackGlyph returns the glyph of the substitute font for `code`, scaled horizontally to the
+// width of the glyph in the PDF font.
+func (f *renderFont) fallbackGlyph(code textencoding.CharCode) *path {
+	var r rune
+	ok := false
+	if !f.cid {
+		if name, found := f.glyphName(code); found {
+			r, ok = textencoding.GlyphToRune(textencoding.GlyphName(name))
+		}
+	}
+	if !ok && f.font != nil && !f.cid {
+		r, ok = f.font.Encoder().CharcodeToRune(code)
+	}
+	if !ok && f.font != nil {
+		if runes := f.font.CharcodesToUnicode([]textencoding.CharCode{code}); len(runes) == 1 {
+			r, ok = runes[0], runes[0] != 0 && runes[0] != '�'
+		}
+	}
+	if !ok {
+		return nil
+	}
+	fb := f.fallback
+	gid, ok := fb.GlyphIndex(r)
+	if !ok {
+		return nil
+	}
+	g, err := fb.Glyph(gid)
+	if err != nil {
+		return nil
+	}
+	m := fb.FontMatrix()
+	sx := 1.0
+	if adv, ok := fb.Advance(gid); ok && adv > 0 {
+		if w := f.width(code); w > 0 {
+			sx = w / (float64(adv) * m[0])
+			if sx < 0.5 {
+				sx = 0.5
+			} else if sx > 2 {
+				sx = 2
+			}
+		}
+	}
+	return glyphPath(g, transform.NewMatrix(m[0]*sx, 0, 0, m[3], 0, 0))
+}
+
+// glyphPath returns the outline `g` transformed by `m` as a path.
+func glyphPath(g outline.Glyph, m transform.Matrix) *path {
+	p := &path{}
+	for _, s := range g {
+		pts := [3]point{}
+		for i, q := range s.Points {
+			pts[i].x, pts[i].y = m.Transform(q.X, q.Y)
+		}
+		switch s.Op {
+		case outline.MoveTo:
+			p.close()
+			p.moveTo(pts[0].x, pts[0].y)
+		case outline.LineTo:
+			p.lineTo(pts[0].x, pts[0].y)
+		case outline.QuadTo:
+			c, q, e := p.cur, pts[0], pts[1]
+			p.curveTo(c.x+2*(q.x-c.x)/3, c.y+2*(q.y-c.y)/3, e.x+2*(q.x-e.x)/3, e.y+2*(q.y-e.y)/3, e.x, e.y)
+		case outline.CubeTo:
+			p.curveTo(pts[0].x, pts[0].y, pts[1].x, pts[1].y, pts[2].x, pts[2].y)
+		}
+	}
+	p.close()
+	return p
+}
+
+// fallbackFonts holds the parsed substitute fonts.
+var fallbackFonts struct {
+	once  sync.Once
+	fonts [6]*outline.TrueType
+}
+
+// fallbackFont returns the substitute font for a font with the given style.
+func fallbackFont(bold, italic, fixed bool) *outline.TrueType {
+	fallbackFonts.once.Do(func() {
+		for i, data := range [][]byte{goregular.TTF, gobold.TTF, goitalic.TTF, gobolditalic.TTF,
+			gomono.TTF, gomonobold.TTF} {
+			f, err := outline.ParseTrueType(data)
+			if err != nil {
+				common.Log.Debug("ERROR: Parsing substitute font: %v", err)
+				continue
+			}
+			fallbackFonts.fonts[i] = f
+		}
+	})
+	i := 0
+	switch {
+	case fixed && bold:
+		i = 5
+	case fixed:
+		i = 4
+	default:
+		if bold {
+			i |= 1
+		}
+		if italic {
+			i |= 2
+		}
+	}
+	return fallbackFonts.fonts[i]
+}
+
+// type3Font is a font whose glyphs are defined by content streams.
+type type3Font struct {
+	charProcs   *core.PdfObjectDictionary
+	matrix      transform.Matrix
+	differences map[textencoding.CharCode]textencoding.GlyphName
+	firstChar   int
+	widths      []float64
+	resources   *model.PdfPageResources
+}
+
+func newType3Font(dict *core.PdfObjectDictionary) *type3Font {
+	f := &type3Font{matrix: transform.NewMatrix(0.001, 0, 0, 0.001, 0, 0)}
+	f.charProcs, _ = core.GetDict(dict.Get("CharProcs"))
+	if arr, ok := core.GetArray(dict.Get("FontMatrix")); ok {
+		if vals, err := arr.ToFloat64Array(); err == nil && len(vals) == 6 {
+			f.matrix = matrixFromFloats(vals)
+		}
+	}
+	if enc, ok := core.GetDict(dict.Get("Encoding")); ok {
+		if arr, ok := core.GetArray(enc.Get("Differences")); ok {
+			f.differences, _ = textencoding.FromFontDifferences(arr)
+		}
+	}
+	f.firstChar, _ = core.GetIntVal(dict.Get("FirstChar"))
+	if arr, ok := core.GetArray(dict.Get("Widths")); ok {
+		f.widths, _ = arr.ToFloat64Array()
+	}
+	if res, ok := core.GetDict(dict.Get("Resources")); ok {
+		f.resources, _ = model.NewPdfPageResourcesFromDict(res)
+	}
+	return f
+}
+
+// width returns the advance width of `code` in text space units.
+func (f *type3Font) width(code textencoding.CharCode) float64 {
+	i := int(code) - f.firstChar
+	if i < 0 || i >= len(f.widths) {
+		return 0
+	}
+	x, _ := f.matrix.Transform(f.widths[i], 0)
+	x0, _ := f.matrix.Transform(0, 0)
+	return x - x0
+}
+
+// charProc returns the glyph description of `code`.
+func (f *type3Font) charProc(code textencoding.CharCode) (string, bool) {
+	name, ok := f.differences[code]
+	if !ok || f.charProcs == nil {
+		return "", false
+	}
+	stream, ok := core.GetStream(f.charProcs.Get(core.PdfObjectName(name)))
+	if !ok {
+		return "", false
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Decoding glyph description: %v", err)
+		return "", false
+	}
+	return string(data), true
+}
@@ -0,0 +1,449 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"errors"
+	"image"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// rasterImage is a decoded image with 8 bit RGB samples.
+type rasterImage struct {
+	w, h int
+	// pix holds the RGB samples row by row. It is nil for stencil masks.
+	pix []uint8
+	// alpha holds the opacity of the samples, or nil if the image is opaque. For stencil masks it
+	// is 255 where the fill colour is painted.
+	alpha []uint8
+}
+
+// drawImage draws the image XObject `ximg` contained in `stream`.
+func (run *contentRun) drawImage(ximg *model.XObjectImage, stream *core.PdfObjectStream,
+	gs contentstream.GraphicsState, ctm transform.Matrix) {
+	img, ok := run.r.images[stream]
+	if !ok {
+		var err error
+		img, err = decodeXObjectImage(ximg, stream)
+		if err != nil {
+			common.Log.Debug("ERROR: Decoding image: %v", err)
+		}
+		run.r.images[stream] = img
+	}
+	if img != nil {
+		run.drawRaster(img, gs, ctm)
+	}
+}
+
+// drawInlineImage draws the inline image `iimg`.
+func (run *contentRun) drawInlineImage(iimg *contentstream.ContentStreamInlineImage,
+	gs contentstream.GraphicsState, ctm transform.Matrix) {
+	img, err := iimg.ToImage(run.resources)
+	if err != nil {
+		common.Log.Debug("ERROR: Decoding inline image: %v", err)
+		return
+	}
+	w, h := int(img.Width), int(img.Height)
+	var decode []float64
+	if arr, ok := core.GetArray(iimg.Decode); ok {
+		decode, _ = arr.ToFloat64Array()
+	}
+	var raster *rasterImage
+	if isMask, _ := iimg.IsMask(); isMask {
+		raster = stencilImage(img.Data, w, h, decode)
+	} else {
+		var cs model.PdfColorspace = model.NewPdfColorspaceDeviceGray()
+		if iimg.ColorSpace != nil {
+			if cs, err = iimg.GetColorSpace(run.resources); err != nil {
+				common.Log.Debug("ERROR: Inline image color space: %v", err)
+				return
+			}
+		}
+		raster, err = colorImage(img.Data, w, h, int(img.BitsPerComponent), cs, decode, nil)
+		if err != nil {
+			common.Log.Debug("ERROR: Decoding inline image: %v", err)
+			return
+		}
+	}
+	run.drawRaster(raster, gs, ctm)
+}
+
+// decodeXObjectImage decodes the image XObject `ximg` with its masks.
+func decodeXObjectImage(ximg *model.XObjectImage, stream *core.PdfObjectStream) (*rasterImage, error) {
+	if ximg.Width == nil || ximg.Height == nil {
+		return nil, errors.New("image size missing")
+	}
+	w, h := int(*ximg.Width), int(*ximg.Height)
+	if w <= 0 || h <= 0 || w*h > maxPixels {
+		return nil, errors.New("invalid image size")
+	}
+	var decode []float64
+	if arr, ok := core.GetArray(ximg.Decode); ok {
+		decode, _ = arr.ToFloat64Array()
+	}
+
+	if b, ok := core.GetBoolVal(ximg.ImageMask); ok && b {
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		return stencilImage(data, w, h, decode), nil
+	}
+
+	img, err := ximg.ToImage()
+	if err != nil {
+		return nil, err
+	}
+	var colorKey []float64
+	if arr, ok := core.GetArray(ximg.Mask); ok {
+		colorKey, _ = arr.ToFloat64Array()
+	}
+	raster, err := colorImage(img.Data, w, h, int(img.BitsPerComponent), ximg.ColorSpace, decode, colorKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// The opacity channel of JPX images is only available through AlphaMap.
+	var alpha []uint8
+	img.AlphaMap(func(a byte) byte {
+		alpha = append(alpha, a)
+		return a
+	})
+	if len(alpha) == w*h {
+		raster.alpha = alpha
+	}
+
+	if s, ok := core.GetStream(ximg.SMask); ok {
+		if mask := decodeSoftMask(s); mask != nil {
+			raster.alpha = resampleAlpha(mask.pix, mask.w, mask.h, 3, w, h)
+		}
+	} else if s, ok := core.GetStream(ximg.Mask); ok {
+		if mask := decodeStencilMask(s); mask != nil {
+			stencil := resampleAlpha(mask.alpha, mask.w, mask.h, 1, w, h)
+			if raster.alpha == nil {
+				raster.alpha = stencil
+			} else {
+				for i := range raster.alpha {
+					raster.alpha[i] = mul8(raster.alpha[i], stencil[i])
+				}
+			}
+		}
+	}
+	return raster, nil
+}
+
+// decodeSoftMask decodes the soft mask image `stream`. The opacity is in the red channel of the
+// returned image.
+func decodeSoftMask(stream *core.PdfObjectStream) *rasterImage {
+	ximg, err := model.NewXObjectImageFromStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid soft mask: %v", err)
+		return nil
+	}
+	ximg.ColorSpace = model.NewPdfColorspaceDeviceGray()
+	ximg.SMask, ximg.Mask = nil, nil
+	mask, err := decodeXObjectImage(ximg, stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Decoding soft mask: %v", err)
+		return nil
+	}
+	return mask
+}
+
+// decodeStencilMask decodes the explicit mask image `stream`.
+func decodeStencilMask(stream *core.PdfObjectStream) *rasterImage {
+	w, okW := core.GetIntVal(stream.Get("Width"))
+	h, okH := core.GetIntVal(stream.Get("Height"))
+	if !okW || !okH || w <= 0 || h <= 0 || w*h > maxPixels {
+		common.Log.Debug("ERROR: Invalid mask size")
+		return nil
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Decoding mask: %v", err)
+		return nil
+	}
+	var decode []float64
+	if arr, ok := core.GetArray(stream.Get("Decode")); ok {
+		decode, _ = arr.ToFloat64Array()
+	}
+	return stencilImage(data, w, h, decode)
+}
+
+// resampleAlpha returns the channel of the `w`x`h` samples `src` with `n` channels scaled to
+// `dw`x`dh` samples.
+func resampleAlpha(src []uint8, w, h, n, dw, dh int) []uint8 {
+	out := make([]uint8, dw*dh)
+	for y := 0; y < dh; y++ {
+		sy := y * h / dh
+		for x := 0; x < dw; x++ {
+			sx := x * w / dw
+			if i := (sy*w + sx) * n; i < len(src) {
+				out[y*dw+x] = src[i]
+			}
+		}
+	}
+	return out
+}
+
+// stencilImage returns the stencil mask with the 1 bit samples `data`. Samples of 0 are painted
+// unless `decode` is [1 0].
+func stencilImage(data []byte, w, h int, decode []float64) *rasterImage {
+	paintBit := byte(0)
+	if len(decode) == 2 && decode[0] > decode[1] {
+		paintBit = 1
+	}
+	stride := (w + 7) / 8
+	img := &rasterImage{w: w, h: h, alpha: make([]uint8, w*h)}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*stride + x/8
+			if i >= len(data) {
+				return img
+			}
+			if (data[i]>>uint(7-x%8))&1 == paintBit {
+				img.alpha[y*w+x] = 255
+			}
+		}
+	}
+	return img
+}
+
+// readSamples returns the `n` component samples of a `w`x`h` image with `bpc` bits per
+// component. Rows start at byte boundaries.
+func readSamples(data []byte, w, h, n, bpc int) []uint32 {
+	samples := make([]uint32, w*h*n)
+	stride := (w*n*bpc + 7) / 8
+	mask := uint32(1)<<uint(bpc) - 1
+	for y := 0; y < h; y++ {
+		row := data[minInt(y*stride, len(data)):minInt((y+1)*stride, len(data))]
+		for i := 0; i < w*n; i++ {
+			bit := i * bpc
+			pos := bit / 8
+			var v uint32
+			switch {
+			case bpc == 16 && pos+1 < len(row):
+				v = uint32(row[pos])<<8 | uint32(row[pos+1])
+			case bpc < 16 && pos < len(row):
+				v = uint32(row[pos]) >> uint(8-bpc-bit%8) & mask
+			}
+			samples[y*w*n+i] = v
+		}
+	}
+	return samples
+}
+
+// colorImage converts the samples `data` of a `w`x`h` image in colour space `cs` to RGB. The
+// samples are mapped by the Decode array `decode` and the samples within the colour key mask
+// ranges `colorKey`, if any, are transparent.
+func colorImage(data []byte, w, h, bpc int, cs model.PdfColorspace, decode, colorKey []float64) (*rasterImage, error) {
+	if cs == nil {
+		return nil, errors.New("image color space missing")
+	}
+	switch bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		return nil, errors.New("invalid bits per component")
+	}
+	n := cs.GetNumComponents()
+	samples := readSamples(data, w, h, n, bpc)
+	maxVal := float64(uint32(1)<<uint(bpc) - 1)
+	img := &rasterImage{w: w, h: h, pix: make([]uint8, 3*w*h)}
+
+	if len(colorKey) == 2*n {
+		img.alpha = make([]uint8, w*h)
+		for i := range img.alpha {
+			for k := 0; k < n; k++ {
+				v := float64(samples[i*n+k])
+				if v < colorKey[2*k] || v > colorKey[2*k+1] {
+					img.alpha[i] = 255
+					break
+				}
+			}
+		}
+	}
+
+	device := cs
+	if icc, ok := cs.(*model.PdfColorspaceICCBased); ok {
+		// ICC profiles are not interpreted.
+		device = icc.Alternate
+		if device == nil {
+			switch icc.N {
+			case 1:
+				device = model.NewPdfColorspaceDeviceGray()
+			case 3:
+				device = model.NewPdfColorspaceDeviceRGB()
+			case 4:
+				device = model.NewPdfColorspaceDeviceCMYK()
+			}
+		}
+	}
+	switch device.(type) {
+	case *model.PdfColorspaceDeviceGray, *model.PdfColorspaceDeviceRGB, *model.PdfColorspaceDeviceCMYK:
+		if device.GetNumComponents() != n {
+			break
+		}
+		var c [4]float64
+		for i := 0; i < w*h; i++ {
+			for k := 0; k < n; k++ {
+				v := float64(samples[i*n+k]) / maxVal
+				if len(decode) == 2*n {
+					v = decode[2*k] + v*(decode[2*k+1]-decode[2*k])
+				}
+				c[k] = clamp01(v)
+			}
+			p := img.pix[3*i : 3*i+3]
+			switch n {
+			case 1:
+				p[0] = to8(c[0])
+				p[1], p[2] = p[0], p[0]
+			case 3:
+				p[0], p[1], p[2] = to8(c[0]), to8(c[1]), to8(c[2])
+			case 4:
+				k := 1 - c[3]
+				p[0], p[1], p[2] = to8((1-c[0])*k), to8((1-c[1])*k), to8((1-c[2])*k)
+			}
+		}
+		return img, nil
+	}
+
+	// Other colour spaces are converted by the model.
+	mimg := model.Image{Width: int64(w), Height: int64(h), BitsPerComponent: int64(bpc), ColorComponents: n}
+	mimg.SetSamples(samples)
+	rgbImg, err := cs.ImageToRGB(mimg)
+	if err != nil {
+		return nil, err
+	}
+	out := rgbImg.GetSamples()
+	outMax := float64(uint32(1)<<uint(rgbImg.BitsPerComponent) - 1)
+	for i := range img.pix {
+		if i < len(out) {
+			img.pix[i] = to8(float64(out[i]) / outMax)
+		}
+	}
+	return img, nil
+}
+
+// drawRaster draws `img` into the unit square of the user space given by `ctm`.
+func (run *contentRun) drawRaster(img *rasterImage, gs contentstream.GraphicsState, ctm transform.Matrix) {
+	if img.w == 0 || img.h == 0 {
+		return
+	}
+	inv, ok := invert(ctm)
+	if !ok {
+		return
+	}
+	var p path
+	p.rect(0, 0, 1, 1)
+	shape := rasterize(p.transform(ctm).flatten(flatness), nonZero, run.r.dst.Rect)
+	if shape.Rect.Empty() {
+		return
+	}
+
+	// Images that are much larger than their size on the page are reduced first to avoid
+	// aliasing.
+	fx := int(float64(img.w) / math.Hypot(ctm[0], ctm[1]))
+	fy := int(float64(img.h) / math.Hypot(ctm[3], ctm[4]))
+	if fx >= 2 || fy >= 2 {
+		img = img.reduce(maxInt(fx, 1), maxInt(fy, 1))
+	}
+
+	w, h := float64(img.w), float64(img.h)
+	index := func(x, y int) int {
+		u, v := inv.Transform(float64(x)+0.5, float64(y)+0.5)
+		ix := clampInt(u*w, 0, img.w-1)
+		iy := clampInt((1-v)*h, 0, img.h-1)
+		return iy*img.w + ix
+	}
+
+	if img.pix == nil {
+		mask := image.NewAlpha(shape.Rect)
+		for y := shape.Rect.Min.Y; y < shape.Rect.Max.Y; y++ {
+			for x := shape.Rect.Min.X; x < shape.Rect.Max.X; x++ {
+				i := shape.PixOffset(x, y)
+				if shape.Pix[i] != 0 {
+					mask.Pix[i] = mul8(shape.Pix[i], img.alpha[index(x, y)])
+				}
+			}
+		}
+		run.fillMask(mask, gs, run.fillPaint(gs), gs.FillAlpha)
+		return
+	}
+	c := run.compositor(gs, gs.FillAlpha)
+	c.paint(intersectMasks(shape, run.clipMask(gs)), func(x, y int) (rgb, float64) {
+		i := index(x, y)
+		col := rgb{float64(img.pix[3*i]) / 255, float64(img.pix[3*i+1]) / 255, float64(img.pix[3*i+2]) / 255}
+		if img.alpha == nil {
+			return col, 1
+		}
+		return col, float64(img.alpha[i]) / 255
+	})
+}
+
+// reduce returns `img` scaled down by the factors `fx` and `fy` with a box filter.
+func (img *rasterImage) reduce(fx, fy int) *rasterImage {
+	w, h := (img.w+fx-1)/fx, (img.h+fy-1)/fy
+	out := &rasterImage{w: w, h: h}
+	if img.pix != nil {
+		out.pix = make([]uint8, 3*w*h)
+	}
+	if img.alpha != nil {
+		out.alpha = make([]uint8, w*h)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum [4]int
+			n := 0
+			for sy := y * fy; sy < minInt((y+1)*fy, img.h); sy++ {
+				for sx := x * fx; sx < minInt((x+1)*fx, img.w); sx++ {
+					i := sy*img.w + sx
+					a := 255
+					if img.alpha != nil {
+						a = int(img.alpha[i])
+					}
+					if img.pix != nil {
+						// Colours are weighted by their opacity.
+						for k := 0; k < 3; k++ {
+							sum[k] += int(img.pix[3*i+k]) * a
+						}
+					}
+					sum[3] += a
+					n++
+				}
+			}
+			o := y*w + x
+			if out.alpha != nil {
+				out.alpha[o] = uint8(sum[3] / n)
+			}
+			if out.pix != nil && sum[3] > 0 {
+				for k := 0; k < 3; k++ {
+					out.pix[3*o+k] = uint8(sum[k] / sum[3])
+				}
+			}
+		}
+	}
+	return out
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,275 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"image"
+	"math"
+)
+
+// blendMode is a blend mode of the transparent imaging model (11.3.5).
+type blendMode int
+
+const (
+	blendNormal blendMode = iota
+	blendMultiply
+	blendScreen
+	blendOverlay
+	blendDarken
+	blendLighten
+	blendColorDodge
+	blendColorBurn
+	blendHardLight
+	blendSoftLight
+	blendDifference
+	blendExclusion
+	blendHue
+	blendSaturation
+	blendColor
+	blendLuminosity
+)
+
+// blendModes maps the names of the blend modes to their values. Compatible is equivalent to
+// Normal.
+var blendModes = map[string]blendMode{
+	"Normal":     blendNormal,
+	"Compatible": blendNormal,
+	"Multiply":   blendMultiply,
+	"Screen":     blendScreen,
+	"Overlay":    blendOverlay,
+	"Darken":     blendDarken,
+	"Lighten":    blendLighten,
+	"ColorDodge": blendColorDodge,
+	"ColorBurn":  blendColorBurn,
+	"HardLight":  blendHardLight,
+	"SoftLight":  blendSoftLight,
+	"Difference": blendDifference,
+	"Exclusion":  blendExclusion,
+	"Hue":        blendHue,
+	"Saturation": blendSaturation,
+	"Color":      blendColor,
+	"Luminosity": blendLuminosity,
+}
+
+// rgb is a colour with components in [0, 1].
+type rgb [3]float64
+
+// colorSource returns the colour and the opacity of the source at device pixel `x`,`y`.
+type colorSource func(x, y int) (rgb, float64)
+
+// compositor paints sources onto a layer through a shape mask.
+type compositor struct {
+	dst      *image.RGBA
+	alpha    float64 // Constant opacity.
+	blend    blendMode
+	softMask *image.Alpha // Soft mask in device space, or nil.
+}
+
+// solid paints the colour `c` through `mask`.
+func (c *compositor) solid(mask *image.Alpha, col rgb) {
+	c.paint(mask, func(x, y int) (rgb, float64) { return col, 1 })
+}
+
+// paint paints `src` through `mask`, which is nil to paint everywhere.
+func (c *compositor) paint(mask *image.Alpha, src colorSource) {
+	r := c.dst.Rect
+	if mask != nil {
+		r = r.Intersect(mask.Rect)
+	}
+	if c.softMask != nil {
+		r = r.Intersect(c.softMask.Rect)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			m := c.alpha
+			if mask != nil {
+				m *= float64(mask.Pix[mask.PixOffset(x, y)]) / 255
+			}
+			if c.softMask != nil {
+				m *= float64(c.softMask.Pix[c.softMask.PixOffset(x, y)]) / 255
+			}
+			if m <= 0 {
+				continue
+			}
+			col, a := src(x, y)
+			if a *= m; a <= 0 {
+				continue
+			}
+			c.compositePixel(x, y, col, a)
+		}
+	}
+}
+
+// compositePixel composites the colour `s` with opacity `as` over the pixel `x`,`y` (11.3.3).
+func (c *compositor) compositePixel(x, y int, s rgb, as float64) {
+	i := c.dst.PixOffset(x, y)
+	p := c.dst.Pix[i : i+4 : i+4]
+	ab := float64(p[3]) / 255
+	if c.blend == blendNormal || ab == 0 {
+		for k := 0; k < 3; k++ {
+			p[k] = to8(s[k]*as + float64(p[k])/255*(1-as))
+		}
+		p[3] = to8(as + ab*(1-as))
+		return
+	}
+	var b rgb
+	for k := range b {
+		b[k] = float64(p[k]) / 255 / ab
+	}
+	mixed := blendColors(c.blend, b, s)
+	ar := as + ab - as*ab
+	for k := 0; k < 3; k++ {
+		// Premultiplied result of the general compositing formula.
+		v := (1-ab)*as*s[k] + (1-as)*ab*b[k] + as*ab*mixed[k]
+		p[k] = to8(v)
+	}
+	p[3] = to8(ar)
+}
+
+// to8 converts `v` in [0, 1] to a byte.
+func to8(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return uint8(v*255 + 0.5)
+}
+
+// blendColors returns the result of blending the backdrop `b` and the source `s` with `mode`.
+func blendColors(mode blendMode, b, s rgb) rgb {
+	switch mode {
+	case blendHue:
+		return setLum(setSat(s, sat(b)), lum(b))
+	case blendSaturation:
+		return setLum(setSat(b, sat(s)), lum(b))
+	case blendColor:
+		return setLum(s, lum(b))
+	case blendLuminosity:
+		return setLum(b, lum(s))
+	}
+	var out rgb
+	for k := range out {
+		out[k] = blendSeparable(mode, b[k], s[k])
+	}
+	return out
+}
+
+// blendSeparable returns the result of the separable blend mode `mode` (Table 136).
+func blendSeparable(mode blendMode, b, s float64) float64 {
+	switch mode {
+	case blendMultiply:
+		return b * s
+	case blendScreen:
+		return b + s - b*s
+	case blendOverlay:
+		return blendSeparable(blendHardLight, s, b)
+	case blendDarken:
+		return math.Min(b, s)
+	case blendLighten:
+		return math.Max(b, s)
+	case blendColorDodge:
+		if b == 0 {
+			return 0
+		}
+		if s >= 1 {
+			return 1
+		}
+		return math.Min(1, b/(1-s))
+	case blendColorBurn:
+		if b >= 1 {
+			return 1
+		}
+		if s <= 0 {
+			return 0
+		}
+		return 1 - math.Min(1, (1-b)/s)
+	case blendHardLight:
+		if s <= 0.5 {
+			return b * 2 * s
+		}
+		return blendSeparable(blendScreen, b, 2*s-1)
+	case blendSoftLight:
+		if s <= 0.5 {
+			return b - (1-2*s)*b*(1-b)
+		}
+		d := math.Sqrt(b)
+		if b <= 0.25 {
+			d = ((16*b-12)*b + 4) * b
+		}
+		return b + (2*s-1)*(d-b)
+	case blendDifference:
+		return math.Abs(b - s)
+	case blendExclusion:
+		return b + s - 2*b*s
+	}
+	return s
+}
+
+// Helper functions of the non-separable blend modes (11.3.5.3).
+
+func lum(c rgb) float64 {
+	return 0.3*c[0] + 0.59*c[1] + 0.11*c[2]
+}
+
+func setLum(c rgb, l float64) rgb {
+	d := l - lum(c)
+	return clipColor(rgb{c[0] + d, c[1] + d, c[2] + d})
+}
+
+func clipColor(c rgb) rgb {
+	l := lum(c)
+	n := math.Min(c[0], math.Min(c[1], c[2]))
+	x := math.Max(c[0], math.Max(c[1], c[2]))
+	for k := range c {
+		if n < 0 && l != n {
+			c[k] = l + (c[k]-l)*l/(l-n)
+		}
+		if x > 1 && x != l {
+			c[k] = l + (c[k]-l)*(1-l)/(x-l)
+		}
+	}
+	return c
+}
+
+func sat(c rgb) float64 {
+	return math.Max(c[0], math.Max(c[1], c[2])) - math.Min(c[0], math.Min(c[1], c[2]))
+}
+
+func setSat(c rgb, s float64) rgb {
+	max, mid, min := 0, 1, 2
+	if c[max] < c[mid] {
+		max, mid = mid, max
+	}
+	if c[mid] < c[min] {
+		mid, min = min, mid
+	}
+	if c[max] < c[mid] {
+		max, mid = mid, max
+	}
+	var out rgb
+	if c[max] > c[min] {
+		out[mid] = (c[mid] - c[min]) * s / (c[max] - c[min])
+		out[max] = s
+	}
+	return out
+}
+
+// compositeLayer composites the premultiplied layer `src` onto the compositor's destination.
+func (c *compositor) compositeLayer(src *image.RGBA) {
+	c.paint(nil, func(x, y int) (rgb, float64) {
+		if !(image.Point{x, y}).In(src.Rect) {
+			return rgb{}, 0
+		}
+		p := src.Pix[src.PixOffset(x, y):]
+		a := float64(p[3]) / 255
+		if a == 0 {
+			return rgb{}, 0
+		}
+		return rgb{float64(p[0]) / 255 / a, float64(p[1]) / 255 / a, float64(p[2]) / 255 / a}, a
+	})
+}
@@ -0,0 +1,192 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"math"
+
+	"github.com/unidoc/unipdf/v3/internal/transform"
+)
+
+// point is a point in user or device space.
+type point struct {
+	x, y float64
+}
+
+// segmentOp is the kind of a path segment.
+type segmentOp int
+
+const (
+	segMoveTo segmentOp = iota
+	segLineTo
+	segCubeTo
+	segClose
+)
+
+// segment is a segment of a path. Curves have two control points and an end point.
+type segment struct {
+	op  segmentOp
+	pts [3]point
+}
+
+// path is a path under construction by the path construction operators.
+type path struct {
+	segs  []segment
+	cur   point
+	start point
+}
+
+func (p *path) moveTo(x, y float64) {
+	p.segs = append(p.segs, segment{op: segMoveTo, pts: [3]point{{x, y}}})
+	p.cur = point{x, y}
+	p.start = p.cur
+}
+
+func (p *path) lineTo(x, y float64) {
+	if len(p.segs) == 0 {
+		p.moveTo(x, y)
+		return
+	}
+	p.segs = append(p.segs, segment{op: segLineTo, pts: [3]point{{x, y}}})
+	p.cur = point{x, y}
+}
+
+func (p *path) curveTo(x1, y1, x2, y2, x3, y3 float64) {
+	if len(p.segs) == 0 {
+		p.moveTo(x1, y1)
+	}
+	p.segs = append(p.segs, segment{op: segCubeTo, pts: [3]point{{x1, y1}, {x2, y2}, {x3, y3}}})
+	p.cur = point{x3, y3}
+}
+
+func (p *path) close() {
+	if len(p.segs) == 0 {
+		return
+	}
+	p.segs = append(p.segs, segment{op: segClose})
+	p.cur = p.start
+}
+
+// rect appends a closed rectangle subpath.
+func (p *path) rect(x, y, w, h float64) {
+	p.moveTo(x, y)
+	p.lineTo(x+w, y)
+	p.lineTo(x+w, y+h)
+	p.lineTo(x, y+h)
+	p.close()
+}
+
+func (p *path) empty() bool {
+	return len(p.segs) == 0
+}
+
+// transform returns the path transformed by `m`.
+func (p *path) transform(m transform.Matrix) *path {
+	out := &path{segs: make([]segment, len(p.segs))}
+	for i, s := range p.segs {
+		out.segs[i].op = s.op
+		for j, pt := range s.pts {
+			out.segs[i].pts[j].x, out.segs[i].pts[j].y = m.Transform(pt.x, pt.y)
+		}
+	}
+	return out
+}
+
+// polyline is a flattened subpath.
+type polyline struct {
+	pts    []point
+	closed bool
+}
+
+// flatten returns the subpaths of `p` with the curves approximated by lines that deviate by at
+// most `tolerance` from them.
+func (p *path) flatten(tolerance float64) []polyline {
+	var lines []polyline
+	var cur *polyline
+	var pos point
+	for _, s := range p.segs {
+		switch s.op {
+		case segMoveTo:
+			lines = append(lines, polyline{pts: []point{s.pts[0]}})
+			cur = &lines[len(lines)-1]
+			pos = s.pts[0]
+		case segLineTo:
+			cur.pts = append(cur.pts, s.pts[0])
+			pos = s.pts[0]
+		case segCubeTo:
+			cur.pts = flattenCubic(cur.pts, pos, s.pts[0], s.pts[1], s.pts[2], tolerance)
+			pos = s.pts[2]
+		case segClose:
+			cur.closed = true
+			// Segments after closepath start a new subpath at the same point.
+			pos = cur.pts[0]
+			lines = append(lines, polyline{pts: []point{pos}})
+			cur = &lines[len(lines)-1]
+		}
+	}
+	// Drop the subpaths that only consist of the point added after a closepath.
+	out := lines[:0]
+	for i, l := range lines {
+		if len(l.pts) == 1 && i > 0 && lines[i-1].closed && !l.closed {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// flattenCubic appends the points of a cubic Bézier curve from `p0` to `p3` to `pts`.
+func flattenCubic(pts []point, p0, p1, p2, p3 point, tolerance float64) []point {
+	// The deviation of n line segments from the curve is bounded by max|B''|/(8n²), and |B''|
+	// is bounded by 6 times the largest second difference of the control points.
+	dd := math.Max(
+		math.Hypot(p0.x-2*p1.x+p2.x, p0.y-2*p1.y+p2.y),
+		math.Hypot(p1.x-2*p2.x+p3.x, p1.y-2*p2.y+p3.y))
+	n := int(math.Ceil(math.Sqrt(6 * dd / (8 * tolerance))))
+	if n < 1 || math.IsNaN(dd) {
+		n = 1
+	} else if n > 500 {
+		n = 500
+	}
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		u := 1 - t
+		a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+		pts = append(pts, point{
+			a*p0.x + b*p1.x + c*p2.x + d*p3.x,
+			a*p0.y + b*p1.y + c*p2.y + d*p3.y,
+		})
+	}
+	return pts
+}
+
+// mul returns the matrix that transforms by `a` and then by `b`.
+func mul(a, b transform.Matrix) transform.Matrix {
+	return b.Mult(a)
+}
+
+// invert returns the inverse of `m`.
+func invert(m transform.Matrix) (transform.Matrix, bool) {
+	det := m[0]*m[4] - m[1]*m[3]
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) {
+		return transform.IdentityMatrix(), false
+	}
+	a, b, c, d := m[4]/det, -m[1]/det, -m[3]/det, m[0]/det
+	return transform.NewMatrix(a, b, c, d, -(m[6]*a + m[7]*c), -(m[6]*b + m[7]*d)), true
+}
+
+// scaleFactor returns the geometric mean of the scaling of `m`.
+func scaleFactor(m transform.Matrix) float64 {
+	return math.Sqrt(math.Abs(m[0]*m[4] - m[1]*m[3]))
+}
+
+// matrixFromFloats returns the matrix given by the 6 numbers `f`, or the identity.
+func matrixFromFloats(f []float64) transform.Matrix {
+	if len(f) != 6 {
+		return transform.IdentityMatrix()
+	}
+	return transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5])
+}
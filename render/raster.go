@@ -0,0 +1,194 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// fillRule is the rule that determines which points are inside a path.
+type fillRule int
+
+const (
+	nonZero fillRule = iota
+	evenOdd
+)
+
+// subScanlines is the number of samples per pixel in the vertical direction. Coverage in the
+// horizontal direction is computed exactly.
+const subScanlines = 5
+
+// edge is a non-horizontal polygon edge with y0 < y1.
+type edge struct {
+	x0, y0, x1, y1 float64
+	slope          float64 // dx/dy
+	dir            int     // +1 for upward edges, -1 for downward edges.
+}
+
+// crossing is the intersection of an edge with a sub-scanline.
+type crossing struct {
+	x   float64
+	dir int
+}
+
+// rasterize returns the coverage of the polygons `polys` in device space under `rule`, limited
+// to `bounds`. The polygons are implicitly closed.
+func rasterize(polys []polyline, rule fillRule, bounds image.Rectangle) *image.Alpha {
+	var edges []edge
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, poly := range polys {
+		n := len(poly.pts)
+		for i, p := range poly.pts {
+			q := poly.pts[(i+1)%n]
+			if math.IsNaN(p.x) || math.IsNaN(p.y) || math.IsNaN(q.x) || math.IsNaN(q.y) {
+				continue
+			}
+			minX, maxX = math.Min(minX, p.x), math.Max(maxX, p.x)
+			minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+			if p.y == q.y {
+				continue
+			}
+			e := edge{x0: p.x, y0: p.y, x1: q.x, y1: q.y, dir: 1}
+			if p.y > q.y {
+				e = edge{x0: q.x, y0: q.y, x1: p.x, y1: p.y, dir: -1}
+			}
+			e.slope = (e.x1 - e.x0) / (e.y1 - e.y0)
+			edges = append(edges, e)
+		}
+	}
+	r := image.Rect(clampInt(minX, bounds.Min.X, bounds.Max.X), clampInt(minY, bounds.Min.Y, bounds.Max.Y),
+		clampInt(math.Ceil(maxX)+1, bounds.Min.X, bounds.Max.X), clampInt(math.Ceil(maxY), bounds.Min.Y, bounds.Max.Y))
+	mask := image.NewAlpha(r)
+	if r.Empty() || len(edges) == 0 {
+		return mask
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+
+	w := r.Dx()
+	acc := make([]float32, w+1)
+	cover := make([]float32, w+2)
+	var active []*edge
+	var crossings []crossing
+	next := 0
+	const weight = 1.0 / subScanlines
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for i := range acc {
+			acc[i] = 0
+		}
+		for i := range cover {
+			cover[i] = 0
+		}
+		for k := 0; k < subScanlines; k++ {
+			sy := float64(y) + (float64(k)+0.5)/subScanlines
+
+			// Update the active edges.
+			for next < len(edges) && edges[next].y0 <= sy {
+				active = append(active, &edges[next])
+				next++
+			}
+			crossings = crossings[:0]
+			j := 0
+			for _, e := range active {
+				if e.y1 <= sy {
+					continue
+				}
+				active[j] = e
+				j++
+				if e.y0 <= sy {
+					crossings = append(crossings, crossing{e.x0 + (sy-e.y0)*e.slope, e.dir})
+				}
+			}
+			active = active[:j]
+			if len(crossings) < 2 {
+				continue
+			}
+			sort.Slice(crossings, func(a, b int) bool { return crossings[a].x < crossings[b].x })
+
+			winding := 0
+			for i, c := range crossings[:len(crossings)-1] {
+				winding += c.dir
+				inside := winding != 0
+				if rule == evenOdd {
+					inside = winding%2 != 0
+				}
+				if !inside {
+					continue
+				}
+				x0 := math.Max(c.x-float64(r.Min.X), 0)
+				x1 := math.Min(crossings[i+1].x-float64(r.Min.X), float64(w))
+				if x1 <= x0 {
+					continue
+				}
+				i0, i1 := int(x0), int(x1)
+				if i0 == i1 {
+					acc[i0] += float32((x1 - x0) * weight)
+					continue
+				}
+				acc[i0] += float32((float64(i0+1) - x0) * weight)
+				cover[i0+1] += weight
+				cover[i1] -= weight
+				if i1 < w {
+					acc[i1] += float32((x1 - float64(i1)) * weight)
+				}
+			}
+		}
+
+		row := mask.Pix[(y-r.Min.Y)*mask.Stride:]
+		var sum float32
+		for x := 0; x < w; x++ {
+			sum += cover[x]
+			v := acc[x] + sum
+			if v > 1 {
+				v = 1
+			}
+			if v > 0 {
+				row[x] = uint8(v*255 + 0.5)
+			}
+		}
+	}
+	return mask
+}
+
+// clampInt returns the integer part of `v` clamped to [`min`, `max`].
+func clampInt(v float64, min, max int) int {
+	if v < float64(min) || math.IsNaN(v) {
+		return min
+	}
+	if v > float64(max) {
+		return max
+	}
+	return int(v)
+}
+
+// intersectMasks returns the product of the masks `a` and `b`. A nil mask covers everything.
+func intersectMasks(a, b *image.Alpha) *image.Alpha {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	r := a.Rect.Intersect(b.Rect)
+	out := image.NewAlpha(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		ra := a.Pix[a.PixOffset(r.Min.X, y):]
+		rb := b.Pix[b.PixOffset(r.Min.X, y):]
+		ro := out.Pix[out.PixOffset(r.Min.X, y):]
+		for x := 0; x < r.Dx(); x++ {
+			ro[x] = mul8(ra[x], rb[x])
+		}
+	}
+	return out
+}
+
+// mul8 returns the product of `a` and `b` as fractions of 255.
+func mul8(a, b uint8) uint8 {
+	v := uint32(a)*uint32(b) + 128
+	return uint8((v + v>>8) >> 8)
+}
@@ -0,0 +1,256 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// testPage returns a 100x100 point page with the content stream `contents`.
+func testPage(t *testing.T, contents string) *model.PdfPage {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 100, Ury: 100}
+	if err := page.SetContentStreams([]string{contents}, core.NewRawEncoder()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return page
+}
+
+// checkPixel checks that the pixel at `x`,`y` of `img` is within a small tolerance of `want`.
+func checkPixel(t *testing.T, img *image.RGBA, x, y int, want color.RGBA) {
+	t.Helper()
+	got := img.RGBAAt(x, y)
+	diff := func(a, b uint8) int {
+		d := int(a) - int(b)
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	if diff(got.R, want.R) > 2 || diff(got.G, want.G) > 2 || diff(got.B, want.B) > 2 {
+		t.Errorf("Pixel (%d,%d): got %v, want %v", x, y, got, want)
+	}
+}
+
+var (
+	white = color.RGBA{255, 255, 255, 255}
+	black = color.RGBA{0, 0, 0, 255}
+	red   = color.RGBA{255, 0, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+)
+
+func TestRenderFill(t *testing.T) {
+	page := testPage(t, "1 0 0 rg 10 10 50 50 re f")
+	img, err := RenderPage(page, 72)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 100 {
+		t.Fatalf("Wrong size: %v", b)
+	}
+	// The y axis of the image points downwards.
+	checkPixel(t, img, 30, 70, red)
+	checkPixel(t, img, 30, 20, white)
+	checkPixel(t, img, 80, 70, white)
+}
+
+func TestRenderResolution(t *testing.T) {
+	page := testPage(t, "0 0 1 rg 0 0 50 50 re f")
+	img, err := RenderPage(page, 144)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 200 {
+		t.Fatalf("Wrong size: %v", b)
+	}
+	checkPixel(t, img, 50, 150, blue)
+	checkPixel(t, img, 150, 50, white)
+
+	if _, err := RenderPage(page, 0); err == nil {
+		t.Errorf("Expected an error for an invalid resolution")
+	}
+}
+
+func TestRenderFillRules(t *testing.T) {
+	// Two nested squares with the same orientation: the inner square is filled with the
+	// nonzero winding rule and left empty with the even-odd rule.
+	const square = "10 10 m 90 10 l 90 90 l 10 90 l h 30 30 m 70 30 l 70 70 l 30 70 l h "
+	img, err := RenderPage(testPage(t, square+"f"), 72)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	checkPixel(t, img, 50, 50, black)
+	checkPixel(t, img, 20, 50, black)
+
+	img, err = RenderPage(testPage(t, square+"f*"), 72)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	checkPixel(t, img, 50, 50, white)
+	checkPixel(t, img, 20, 50, black)
+}
+
+func TestRenderClip(t *testing.T) {
+	page := testPage(t, "q 0 0 50 100 re W n 1 0 0 rg 0 0 100 100 re f Q 0 0 1 rg 60 0 10 10 re f")
+	img, err := RenderPage(page, 72)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	checkPixel(t, img, 25, 50, red)
+	checkPixel(t, img, 75, 50, white)
+	// The clip is restored by Q.
+	checkPixel(t, img, 65, 95, blue)
+}
+
+func TestRenderStroke(t *testing.T) {
+	page := testPage(t, "1 0 0 RG 10 w 0 50 m 100 50 l S")
+	img, err := RenderPage(page, 72)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	checkPixel(t, img, 50, 50, red)
+	checkPixel(t, img, 50, 47, red)
+	checkPixel(t, img, 50, 40, white)
+}
+
+func TestRenderRotate(t *testing.T) {
+	page := testPage(t, "1 0 0 rg 0 0 50 50 re f")
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 200, Ury: 100}
+	rotate := int64(90)
+	page.Rotate = &rotate
+	img, err := RenderPage(page, 72)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 200 {
+		t.Fatalf("Wrong size: %v", b)
+	}
+	// The lower left corner of the page is at the top left after a clockwise rotation.
+	checkPixel(t, img, 25, 25, red)
+	checkPixel(t, img, 75, 175, white)
+}
+
+func TestRenderAlpha(t *testing.T) {
+	page := testPage(t, "/GS0 gs 1 0 0 rg 0 0 100 100 re f")
+	page.Resources = model.NewPdfPageResources()
+	gs := core.MakeDict()
+	gs.Set("ca", core.MakeFloat(0.5))
+	if err := page.AddExtGState("GS0", gs); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	img, err := RenderPage(page, 72)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	checkPixel(t, img, 50, 50, color.RGBA{255, 128, 128, 255})
+}
+
+// TestRenderFormState checks that form XObjects are painted in the graphics state of the Do
+// operator.
+func TestRenderFormState(t *testing.T) {
+	page := testPage(t, "q 0 0 50 100 re W n 1 0 0 RG 10 w /GS0 gs /Fm1 Do Q 0 0 1 RG /Fm1 Do")
+	page.Resources = model.NewPdfPageResources()
+	gs := core.MakeDict()
+	gs.Set("CA", core.MakeFloat(0.5))
+	if err := page.AddExtGState("GS0", gs); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+	if err := xform.SetContentStream([]byte("0 50 m 100 50 l S"), core.NewRawEncoder()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := page.Resources.SetXObjectFormByName("Fm1", xform); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	img, err := RenderPage(page, 72)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// The form inherits the colour, line width, alpha and clipping path.
+	checkPixel(t, img, 25, 47, color.RGBA{255, 128, 128, 255})
+	checkPixel(t, img, 25, 40, white)
+	checkPixel(t, img, 75, 40, white)
+	// The state is restored by Q: the line is 1 point wide and opaque.
+	if c := img.RGBAAt(75, 50); c.B != 255 || c.R > 200 || c.R != c.G {
+		t.Errorf("Pixel (75,50): got %v, want blue", c)
+	}
+	checkPixel(t, img, 75, 47, white)
+}
+
+func TestRenderAxialShading(t *testing.T) {
+	fn := core.MakeDict()
+	fn.Set("FunctionType", core.MakeInteger(2))
+	fn.Set("Domain", core.MakeArrayFromFloats([]float64{0, 1}))
+	fn.Set("C0", core.MakeArrayFromFloats([]float64{1, 0, 0}))
+	fn.Set("C1", core.MakeArrayFromFloats([]float64{0, 0, 1}))
+	fn.Set("N", core.MakeInteger(1))
+
+	sh := core.MakeDict()
+	sh.Set("ShadingType", core.MakeInteger(2))
+	sh.Set("ColorSpace", core.MakeName("DeviceRGB"))
+	sh.Set("Coords", core.MakeArrayFromFloats([]float64{0, 0, 100, 0}))
+	sh.Set("Function", fn)
+
+	shadings := core.MakeDict()
+	shadings.Set("Sh0", sh)
+	resDict := core.MakeDict()
+	resDict.Set("Shading", shadings)
+	resources, err := model.NewPdfPageResourcesFromDict(resDict)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	page := testPage(t, "/Sh0 sh")
+	page.Resources = resources
+	img, err := RenderPage(page, 72)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	checkPixel(t, img, 0, 50, red)
+	checkPixel(t, img, 99, 50, blue)
+	mid := img.RGBAAt(50, 50)
+	if mid.R < 100 || mid.R > 155 || mid.B < 100 || mid.B > 155 {
+		t.Errorf("Wrong colour at the middle: %v", mid)
+	}
+}
+
+func TestRenderFile(t *testing.T) {
+	f, err := os.Open("../model/testdata/lorem.pdf")
+	if err != nil {
+		t.Skipf("Test file not available: %v", err)
+	}
+	defer f.Close()
+
+	reader, err := model.NewPdfReader(f)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	page, err := reader.GetPage(1)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	img, err := RenderPage(page, 36)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	dark := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i] < 128 {
+			dark++
+		}
+	}
+	if dark == 0 {
+		t.Errorf("Rendered page is blank")
+	}
+}
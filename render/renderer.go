@@ -0,0 +1,844 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// maxDepth is the maximum nesting depth of form XObjects, patterns, soft masks and Type 3 glyphs.
+const maxDepth = 16
+
+// maxPixels is the largest number of pixels of a rendered page.
+const maxPixels = 1 << 28
+
+// flatness is the maximum deviation in device pixels of flattened curves from the curves.
+const flatness = 0.2
+
+// RenderPage renders `page` at a resolution of `dpi` pixels per inch and returns the image. The
+// image covers the crop box of the page on a white background and is rotated by the page's
+// Rotate entry. The visible annotations with appearance streams are drawn over the contents.
+func RenderPage(page *model.PdfPage, dpi float64) (*image.RGBA, error) {
+	if dpi <= 0 || math.IsNaN(dpi) || math.IsInf(dpi, 0) {
+		return nil, errors.New("invalid resolution")
+	}
+	box, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	if page.CropBox != nil {
+		box = page.CropBox
+	}
+	llx, lly := math.Min(box.Llx, box.Urx), math.Min(box.Lly, box.Ury)
+	urx, ury := math.Max(box.Llx, box.Urx), math.Max(box.Lly, box.Ury)
+
+	rotate := 0
+	if page.Rotate != nil {
+		rotate = (int(*page.Rotate)%360 + 360) % 360
+		if rotate%90 != 0 {
+			common.Log.Debug("Invalid page rotation %d", *page.Rotate)
+			rotate = 0
+		}
+	}
+
+	// The device space has its origin at the top left corner of the displayed page and the y
+	// axis pointing downwards.
+	s := dpi / 72
+	w, h := (urx-llx)*s, (ury-lly)*s
+	var device transform.Matrix
+	switch rotate {
+	case 0:
+		device = transform.NewMatrix(s, 0, 0, -s, -llx*s, ury*s)
+	case 90:
+		device = transform.NewMatrix(0, s, s, 0, -lly*s, -llx*s)
+		w, h = h, w
+	case 180:
+		device = transform.NewMatrix(-s, 0, 0, s, urx*s, -lly*s)
+	case 270:
+		device = transform.NewMatrix(0, -s, -s, 0, ury*s, urx*s)
+		w, h = h, w
+	}
+	width, height := int(math.Ceil(w-0.01)), int(math.Ceil(h-0.01))
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	if float64(width)*float64(height) > maxPixels {
+		return nil, errors.New("page too large")
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Rect, image.White, image.Point{}, draw.Src)
+
+	r := newRenderer(dst)
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.runContent(contents, page.Resources, device, newState()); err != nil {
+		common.Log.Debug("ERROR: Rendering page contents: %v", err)
+	}
+	r.drawAnnotations(page, device)
+	return dst, nil
+}
+
+// renderer draws content streams onto a layer.
+type renderer struct {
+	dst    *image.RGBA
+	fonts  map[core.PdfObject]*renderFont
+	images map[*core.PdfObjectStream]*rasterImage
+	depth  int
+}
+
+func newRenderer(dst *image.RGBA) *renderer {
+	return &renderer{
+		dst:    dst,
+		fonts:  map[core.PdfObject]*renderFont{},
+		images: map[*core.PdfObjectStream]*rasterImage{},
+	}
+}
+
+// layer returns a renderer that draws onto `dst` and shares the caches of `r`.
+func (r *renderer) layer(dst *image.RGBA) *renderer {
+	return &renderer{dst: dst, fonts: r.fonts, images: r.images, depth: r.depth + 1}
+}
+
+// paint is a colour or a pattern with which paths are filled or stroked.
+type paint struct {
+	color   rgb
+	pattern *model.PdfPattern
+	// base maps the pattern space of `pattern` to device space.
+	base transform.Matrix
+	// resources are the resources of the content stream in which the pattern was selected.
+	resources *model.PdfPageResources
+}
+
+// state is the graphics state in which a content stream is painted. The content stream processor
+// tracks the graphics state within the content stream, starting from `gs`. The other fields hold
+// what the processor cannot carry into a content stream that is painted by another one, such as a
+// form XObject, a tiling pattern or the glyph description of a Type 3 font.
+type state struct {
+	// gs is the graphics state at the start of the content stream, with the identity CTM and no
+	// clipping path. It is nil for the initial graphics state of a page.
+	gs *contentstream.GraphicsState
+	// clip is the clipping path in device space, or nil for no clipping.
+	clip *image.Alpha
+	// softMask is the soft mask of `gs` in device space.
+	softMask *image.Alpha
+
+	// fill and strokePaint are the paints of the colours of `gs`. Patterns are looked up in the
+	// resources of the content stream in which they were selected.
+	fill, strokePaint *paint
+	// lockColor is set for content streams that may not set colours, such as the glyph
+	// descriptions of Type 3 fonts that set d1 and uncoloured tiling patterns. They are painted
+	// with `fill` and `strokePaint`.
+	lockColor bool
+}
+
+func newState() *state {
+	return &state{}
+}
+
+// clipEntry is the mask in device space of the intersection of the clipping paths of the graphics
+// state up to and including the clipping path of the first segment `key`.
+type clipEntry struct {
+	key     *contentstream.PathSegment
+	evenOdd bool
+	mask    *image.Alpha
+}
+
+// contentRun holds the state of the rendering of a content stream.
+type contentRun struct {
+	r         *renderer
+	proc      *contentstream.ContentStreamProcessor
+	resources *model.PdfPageResources
+	// base maps the initial user space of the content stream to device space.
+	base transform.Matrix
+	st   *state
+	// clips caches the masks of the clipping paths of the processor's graphics state.
+	clips []clipEntry
+	// softMasks are the soft masks in device space of the soft mask dictionaries of the graphics
+	// state.
+	softMasks map[*core.PdfObjectDictionary]*image.Alpha
+	// textClip is the intersection of the glyph outlines added to the clipping path by the text
+	// rendering modes 4-7, which the processor does not track. textClips holds the values saved
+	// by q.
+	textClip  *image.Alpha
+	textClips []*image.Alpha
+	text      textObject
+	// type3 is set when rendering the glyph description of a Type 3 font.
+	type3     bool
+	lockColor bool
+}
+
+// runContent renders the content stream `contents` with `resources`. `base` maps the user space
+// of the content stream to device space. The content stream is painted in the graphics state `st`.
+func (r *renderer) runContent(contents string, resources *model.PdfPageResources,
+	base transform.Matrix, st *state) error {
+	return r.runContentWith(contents, resources, base, st, false)
+}
+
+// runContentWith renders a content stream like runContent. `type3` is set for the glyph
+// descriptions of Type 3 fonts.
+func (r *renderer) runContentWith(contents string, resources *model.PdfPageResources,
+	base transform.Matrix, st *state, type3 bool) error {
+	if r.depth > maxDepth {
+		common.Log.Debug("ERROR: Content nested too deeply")
+		return nil
+	}
+	ops, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return err
+	}
+	proc := contentstream.NewContentStreamProcessor(*ops)
+	run := &contentRun{
+		r:         r,
+		proc:      proc,
+		resources: resources,
+		base:      base,
+		st:        st,
+		softMasks: map[*core.PdfObjectDictionary]*image.Alpha{},
+		type3:     type3,
+		lockColor: st.lockColor,
+	}
+	if st.gs != nil {
+		proc.SetInitialState(*st.gs)
+		if st.gs.SoftMask != nil {
+			run.softMasks[st.gs.SoftMask] = st.softMask
+		}
+	}
+	proc.AddHandler(contentstream.HandlerConditionEnumAllOperands, "", run.handle)
+	return proc.Process(resources)
+}
+
+// handle renders the operation `op`.
+func (run *contentRun) handle(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+	resources *model.PdfPageResources) error {
+	ctm := mul(gs.CTM, run.base)
+	switch op.Operand {
+	case "q":
+		run.textClips = append(run.textClips, run.textClip)
+	case "Q":
+		if n := len(run.textClips); n > 0 {
+			run.textClip = run.textClips[n-1]
+			run.textClips = run.textClips[:n-1]
+		}
+	case "gs":
+		// The soft mask is set up with the CTM current when the ExtGState is set (11.6.5.2).
+		if gs.SoftMask != nil {
+			if _, ok := run.softMasks[gs.SoftMask]; !ok {
+				run.softMasks[gs.SoftMask] = run.softMask(gs.SoftMask, ctm)
+			}
+		}
+
+	case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*":
+		run.paintPath(op.Operand, gs, ctm)
+
+	case "BT", "ET", "Tj", "TJ", "'", "\"":
+		run.handleText(op, gs, ctm)
+	case "d0", "d1":
+		// Glyphs described with d1 are painted in the current colour.
+		if run.type3 && op.Operand == "d1" {
+			run.lockColor = true
+		}
+
+	case "Do":
+		name, ok := nameParam(op.Params, 0)
+		if !ok {
+			return nil
+		}
+		run.doXObject(name, gs, ctm)
+	case "sh":
+		name, ok := nameParam(op.Params, 0)
+		if !ok {
+			return nil
+		}
+		shading, ok := resources.GetShadingByName(name)
+		if !ok {
+			common.Log.Debug("Shading %s not found", name)
+			return nil
+		}
+		c := run.compositor(gs, gs.FillAlpha)
+		run.drawShading(shading, ctm, run.clipMask(gs), c, false)
+	case "BI":
+		if len(op.Params) != 1 {
+			return nil
+		}
+		if img, ok := op.Params[0].(*contentstream.ContentStreamInlineImage); ok {
+			run.drawInlineImage(img, gs, ctm)
+		}
+	}
+	return nil
+}
+
+// nameParam returns the `i`th parameter of `params` if it is a name.
+func nameParam(params []core.PdfObject, i int) (core.PdfObjectName, bool) {
+	if i >= len(params) {
+		return "", false
+	}
+	name, ok := core.GetName(params[i])
+	if !ok {
+		return "", false
+	}
+	return *name, true
+}
+
+// inherit returns the state in which a content stream painted by the content stream of `run` in
+// the graphics state `gs` is painted.
+func (run *contentRun) inherit(gs contentstream.GraphicsState) *state {
+	fill, stroke := run.fillPaint(gs), run.strokePaintFor(gs)
+	st := &state{
+		clip:        run.clipMask(gs),
+		softMask:    run.softMasks[gs.SoftMask],
+		fill:        &fill,
+		strokePaint: &stroke,
+		lockColor:   run.lockColor,
+	}
+	gs.CTM = transform.IdentityMatrix()
+	gs.ClipPath = nil
+	st.gs = &gs
+	return st
+}
+
+// clipMask returns the clipping path of `gs` in device space, or nil for no clipping.
+func (run *contentRun) clipMask(gs contentstream.GraphicsState) *image.Alpha {
+	// The clipping paths of the graphics state only change by appending a path or restoring a
+	// graphics state with fewer paths, so the masks of the common paths are kept.
+	n := 0
+	for n < len(gs.ClipPath) && n < len(run.clips) {
+		cp, entry := gs.ClipPath[n], run.clips[n]
+		if entry.key != firstSegment(cp.Path) || entry.evenOdd != cp.EvenOdd {
+			break
+		}
+		n++
+	}
+	run.clips = run.clips[:n]
+	mask := run.st.clip
+	if n > 0 {
+		mask = run.clips[n-1].mask
+	}
+	for _, cp := range gs.ClipPath[n:] {
+		rule := nonZero
+		if cp.EvenOdd {
+			rule = evenOdd
+		}
+		p := devicePath(cp.Path).transform(run.base)
+		mask = intersectMasks(mask, rasterize(p.flatten(flatness), rule, run.r.dst.Rect))
+		run.clips = append(run.clips, clipEntry{key: firstSegment(cp.Path), evenOdd: cp.EvenOdd, mask: mask})
+	}
+	return intersectMasks(mask, run.textClip)
+}
+
+// firstSegment returns the first segment of `p`, which identifies the path, or nil if `p` is
+// empty.
+func firstSegment(p contentstream.Path) *contentstream.PathSegment {
+	if len(p.Segments) == 0 {
+		return nil
+	}
+	return &p.Segments[0]
+}
+
+// devicePath returns the path of the content stream processor `p`.
+func devicePath(p contentstream.Path) *path {
+	out := &path{}
+	pt := func(seg contentstream.PathSegment, i int) (float64, float64) {
+		return seg.Points[i].X, seg.Points[i].Y
+	}
+	for _, seg := range p.Segments {
+		switch seg.Type {
+		case contentstream.PathSegmentMoveTo:
+			out.moveTo(pt(seg, 0))
+		case contentstream.PathSegmentLineTo:
+			out.lineTo(pt(seg, 0))
+		case contentstream.PathSegmentCurveTo:
+			x1, y1 := pt(seg, 0)
+			x2, y2 := pt(seg, 1)
+			x3, y3 := pt(seg, 2)
+			out.curveTo(x1, y1, x2, y2, x3, y3)
+		case contentstream.PathSegmentClose:
+			out.close()
+		}
+	}
+	return out
+}
+
+// paintPath handles the path painting operators. The clipping path set by W or W* is applied by
+// the processor after the path is painted.
+func (run *contentRun) paintPath(operand string, gs contentstream.GraphicsState, ctm transform.Matrix) {
+	// The processor's path is in the initial user space of the content stream.
+	p := devicePath(run.proc.CurrentPath())
+	if p.empty() {
+		return
+	}
+	switch operand {
+	case "s", "b", "b*":
+		p.close()
+	}
+	rule := nonZero
+	if operand == "f*" || operand == "B*" || operand == "b*" {
+		rule = evenOdd
+	}
+	fill := operand != "S" && operand != "s"
+	stroke := operand == "S" || operand == "s" || operand == "B" || operand == "B*" ||
+		operand == "b" || operand == "b*"
+
+	if fill {
+		mask := rasterize(p.transform(run.base).flatten(flatness), rule, run.r.dst.Rect)
+		run.fillMask(mask, gs, run.fillPaint(gs), gs.FillAlpha)
+	}
+	if stroke {
+		// Paths are stroked in user space.
+		if inv, ok := invert(gs.CTM); ok {
+			run.strokePath(p.transform(inv), gs, ctm, run.strokePaintFor(gs))
+		}
+	}
+}
+
+// strokePath strokes `p` in user space with the transform `ctm` to device space and the line
+// parameters of `gs`.
+func (run *contentRun) strokePath(p *path, gs contentstream.GraphicsState, ctm transform.Matrix, pt paint) {
+	scale := scaleFactor(ctm)
+	if scale == 0 || math.IsNaN(scale) {
+		return
+	}
+	style := strokeStyle{
+		width:      math.Abs(gs.LineWidth),
+		cap:        gs.LineCap,
+		join:       gs.LineJoin,
+		miterLimit: gs.MiterLimit,
+		dash:       gs.DashArray,
+		phase:      gs.DashPhase,
+	}
+	// Lines thinner than a device pixel are drawn one pixel wide.
+	if style.width*scale < 1 {
+		style.width = 1 / scale
+	}
+	lines := p.flatten(flatness / scale)
+	polys := strokePolylines(lines, style, scale)
+	for i := range polys {
+		for j, q := range polys[i].pts {
+			polys[i].pts[j].x, polys[i].pts[j].y = ctm.Transform(q.x, q.y)
+		}
+	}
+	mask := rasterize(polys, nonZero, run.r.dst.Rect)
+	run.fillMask(mask, gs, pt, gs.StrokeAlpha)
+}
+
+// compositor returns a compositor for the graphics state `gs` with constant opacity `alpha`.
+func (run *contentRun) compositor(gs contentstream.GraphicsState, alpha float64) *compositor {
+	blend, ok := blendModes[string(gs.BlendMode)]
+	if !ok {
+		common.Log.Debug("Unsupported blend mode %s", gs.BlendMode)
+		blend = blendNormal
+	}
+	return &compositor{dst: run.r.dst, alpha: clamp01(alpha), blend: blend, softMask: run.softMasks[gs.SoftMask]}
+}
+
+// fillMask paints `pt` with opacity `alpha` through `mask` and the clipping path of `gs`.
+func (run *contentRun) fillMask(mask *image.Alpha, gs contentstream.GraphicsState, pt paint, alpha float64) {
+	mask = intersectMasks(mask, run.clipMask(gs))
+	c := run.compositor(gs, alpha)
+	if pt.pattern == nil {
+		c.solid(mask, pt.color)
+		return
+	}
+	run.drawPattern(pt, mask, c)
+}
+
+// fillPaint returns the non-stroking paint of `gs`.
+func (run *contentRun) fillPaint(gs contentstream.GraphicsState) paint {
+	return run.paintFor(gs.ColorspaceNonStroking, gs.ColorNonStroking, run.st.fill, nonStrokingColor(run.st.gs))
+}
+
+// strokePaintFor returns the stroking paint of `gs`.
+func (run *contentRun) strokePaintFor(gs contentstream.GraphicsState) paint {
+	return run.paintFor(gs.ColorspaceStroking, gs.ColorStroking, run.st.strokePaint, strokingColor(run.st.gs))
+}
+
+// paintFor returns the paint for the colour `c` in colour space `cs`. `inherited` is the paint of
+// the colour `initial` of the initial graphics state of the content stream, if any.
+func (run *contentRun) paintFor(cs model.PdfColorspace, c model.PdfColor, inherited *paint,
+	initial model.PdfColor) paint {
+	if inherited != nil {
+		// Patterns of the initial colours are looked up in the resources of the content stream
+		// in which they were selected.
+		if run.lockColor {
+			return *inherited
+		}
+		if pc, ok := c.(*model.PdfColorPattern); ok {
+			if ipc, ok := initial.(*model.PdfColorPattern); ok && pc == ipc {
+				return *inherited
+			}
+		}
+	}
+	return run.toPaint(cs, c)
+}
+
+// nonStrokingColor returns the non-stroking colour of `gs`, or nil if `gs` is nil.
+func nonStrokingColor(gs *contentstream.GraphicsState) model.PdfColor {
+	if gs == nil {
+		return nil
+	}
+	return gs.ColorNonStroking
+}
+
+// strokingColor returns the stroking colour of `gs`, or nil if `gs` is nil.
+func strokingColor(gs *contentstream.GraphicsState) model.PdfColor {
+	if gs == nil {
+		return nil
+	}
+	return gs.ColorStroking
+}
+
+// toPaint returns the paint for the colour `c` in colour space `cs`.
+func (run *contentRun) toPaint(cs model.PdfColorspace, c model.PdfColor) paint {
+	if pcs, ok := cs.(*model.PdfColorspaceSpecialPattern); ok {
+		pc, ok := c.(*model.PdfColorPattern)
+		if !ok {
+			return paint{}
+		}
+		pattern, ok := run.resources.GetPatternByName(pc.PatternName)
+		if !ok {
+			common.Log.Debug("Pattern %s not found", pc.PatternName)
+			return paint{}
+		}
+		pt := paint{pattern: pattern, base: run.base, resources: run.resources}
+		if pc.Color != nil && pcs.UnderlyingCS != nil {
+			pt.color = colorToRGB(pcs.UnderlyingCS, pc.Color)
+		}
+		return pt
+	}
+	return paint{color: colorToRGB(cs, c)}
+}
+
+// colorToRGB converts the colour `c` in colour space `cs` to RGB. Colours that cannot be
+// converted are black.
+func colorToRGB(cs model.PdfColorspace, c model.PdfColor) rgb {
+	if cs == nil || c == nil {
+		return rgb{}
+	}
+	conv, err := cs.ColorToRGB(c)
+	if err != nil {
+		common.Log.Debug("Color conversion failed: %v", err)
+		return rgb{}
+	}
+	col, ok := conv.(*model.PdfColorDeviceRGB)
+	if !ok {
+		return rgb{}
+	}
+	return rgb{clamp01(col.R()), clamp01(col.G()), clamp01(col.B())}
+}
+
+// clamp01 returns `v` clamped to [0, 1].
+func clamp01(v float64) float64 {
+	if v < 0 || math.IsNaN(v) {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// softMask returns the soft mask in device space of the soft mask dictionary `dict` that is set
+// with the transform `ctm`.
+func (run *contentRun) softMask(dict *core.PdfObjectDictionary, ctm transform.Matrix) *image.Alpha {
+	r := run.r
+	stream, ok := core.GetStream(dict.Get("G"))
+	if !ok {
+		common.Log.Debug("Soft mask without group")
+		return nil
+	}
+	xform, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		common.Log.Debug("Invalid soft mask group: %v", err)
+		return nil
+	}
+	luminosity := true
+	if name, ok := core.GetName(dict.Get("S")); ok && *name == "Alpha" {
+		luminosity = false
+	}
+
+	layer := image.NewRGBA(r.dst.Rect)
+	if luminosity {
+		// The group is composited over its backdrop colour, which is black by default.
+		var backdrop rgb
+		if arr, ok := core.GetArray(dict.Get("BC")); ok {
+			backdrop = groupColor(xform, arr)
+		}
+		col := [4]uint8{to8(backdrop[0]), to8(backdrop[1]), to8(backdrop[2]), 255}
+		for i := 0; i < len(layer.Pix); i += 4 {
+			copy(layer.Pix[i:i+4], col[:])
+		}
+	}
+	resources := xform.Resources
+	if resources == nil {
+		resources = run.resources
+	}
+	lr := r.layer(layer)
+	if err := lr.runForm(xform, resources, ctm, newState()); err != nil {
+		common.Log.Debug("ERROR: Rendering soft mask: %v", err)
+	}
+
+	mask := image.NewAlpha(r.dst.Rect)
+	for i := range mask.Pix {
+		p := layer.Pix[4*i : 4*i+4]
+		if luminosity {
+			mask.Pix[i] = uint8((299*uint32(p[0]) + 587*uint32(p[1]) + 114*uint32(p[2]) + 500) / 1000)
+		} else {
+			mask.Pix[i] = p[3]
+		}
+	}
+	return mask
+}
+
+// groupColor returns the colour with components `arr` in the colour space of the transparency
+// group of `xform`.
+func groupColor(xform *model.XObjectForm, arr *core.PdfObjectArray) rgb {
+	vals, err := arr.ToFloat64Array()
+	if err != nil {
+		return rgb{}
+	}
+	var cs model.PdfColorspace
+	if group, ok := core.GetDict(xform.Group); ok {
+		if obj := group.Get("CS"); obj != nil {
+			cs, _ = model.NewPdfColorspaceFromPdfObject(obj)
+		}
+	}
+	if cs == nil {
+		switch len(vals) {
+		case 1:
+			cs = model.NewPdfColorspaceDeviceGray()
+		case 3:
+			cs = model.NewPdfColorspaceDeviceRGB()
+		case 4:
+			cs = model.NewPdfColorspaceDeviceCMYK()
+		default:
+			return rgb{}
+		}
+	}
+	c, err := cs.ColorFromFloats(vals)
+	if err != nil {
+		return rgb{}
+	}
+	return colorToRGB(cs, c)
+}
+
+// doXObject handles the Do operator for the XObject `name`.
+func (run *contentRun) doXObject(name core.PdfObjectName, gs contentstream.GraphicsState, ctm transform.Matrix) {
+	stream, xtype := run.resources.GetXObjectByName(name)
+	switch xtype {
+	case model.XObjectTypeImage:
+		ximg, err := run.resources.GetXObjectImageByName(name)
+		if err != nil || ximg == nil {
+			common.Log.Debug("Invalid image XObject %s: %v", name, err)
+			return
+		}
+		run.drawImage(ximg, stream, gs, ctm)
+	case model.XObjectTypeForm:
+		xform, err := run.resources.GetXObjectFormByName(name)
+		if err != nil || xform == nil {
+			common.Log.Debug("Invalid form XObject %s: %v", name, err)
+			return
+		}
+		run.drawForm(xform, gs, ctm)
+	default:
+		common.Log.Debug("XObject %s not found", name)
+	}
+}
+
+// drawForm draws the form XObject `xform` in the graphics state `gs`.
+func (run *contentRun) drawForm(xform *model.XObjectForm, gs contentstream.GraphicsState, ctm transform.Matrix) {
+	st := run.inherit(gs)
+	resources := xform.Resources
+	if resources == nil {
+		resources = run.resources
+	}
+
+	c := run.compositor(gs, gs.FillAlpha)
+	if !isTransparencyGroup(xform) || (c.alpha == 1 && c.blend == blendNormal && c.softMask == nil) {
+		if err := run.r.runForm(xform, resources, ctm, st); err != nil {
+			common.Log.Debug("ERROR: Rendering form: %v", err)
+		}
+		return
+	}
+
+	// The group is rendered to a transparent layer and then composited as a whole. The group's
+	// alpha constant, blend mode and soft mask apply to the group and are reset within it.
+	layer := image.NewRGBA(run.r.dst.Rect)
+	inner := *st
+	innerGS := *st.gs
+	innerGS.FillAlpha, innerGS.StrokeAlpha, innerGS.BlendMode, innerGS.SoftMask = 1, 1, "Normal", nil
+	inner.gs, inner.clip, inner.softMask = &innerGS, nil, nil
+	if err := run.r.layer(layer).runForm(xform, resources, ctm, &inner); err != nil {
+		common.Log.Debug("ERROR: Rendering transparency group: %v", err)
+	}
+	if st.clip != nil {
+		applyMask(layer, st.clip)
+	}
+	c.compositeLayer(layer)
+}
+
+// isTransparencyGroup returns true if `xform` is a transparency group XObject.
+func isTransparencyGroup(xform *model.XObjectForm) bool {
+	group, ok := core.GetDict(xform.Group)
+	if !ok {
+		return false
+	}
+	name, ok := core.GetName(group.Get("S"))
+	return ok && *name == "Transparency"
+}
+
+// applyMask multiplies the premultiplied pixels of `layer` with `mask`.
+func applyMask(layer *image.RGBA, mask *image.Alpha) {
+	r := layer.Rect
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var m uint8
+			if (image.Point{x, y}).In(mask.Rect) {
+				m = mask.Pix[mask.PixOffset(x, y)]
+			}
+			if m == 255 {
+				continue
+			}
+			p := layer.Pix[layer.PixOffset(x, y):]
+			for k := 0; k < 4; k++ {
+				p[k] = mul8(p[k], m)
+			}
+		}
+	}
+}
+
+// runForm renders the form XObject `xform` with the transform `ctm` from its form space, as
+// given by its Matrix, to device space. The form is clipped to its bounding box.
+func (r *renderer) runForm(xform *model.XObjectForm, resources *model.PdfPageResources,
+	ctm transform.Matrix, st *state) error {
+	base := ctm
+	if arr, ok := core.GetArray(xform.Matrix); ok {
+		if f, err := arr.ToFloat64Array(); err == nil {
+			base = mul(matrixFromFloats(f), ctm)
+		}
+	}
+	if arr, ok := core.GetArray(xform.BBox); ok {
+		if f, err := arr.ToFloat64Array(); err == nil && len(f) == 4 {
+			var p path
+			p.rect(f[0], f[1], f[2]-f[0], f[3]-f[1])
+			mask := rasterize(p.transform(base).flatten(flatness), nonZero, r.dst.Rect)
+			st.clip = intersectMasks(st.clip, mask)
+		}
+	}
+	contents, err := xform.GetContentStream()
+	if err != nil {
+		return err
+	}
+	r.depth++
+	defer func() { r.depth-- }()
+	return r.runContent(string(contents), resources, base, st)
+}
+
+// drawAnnotations draws the normal appearances of the visible annotations of `page`.
+func (r *renderer) drawAnnotations(page *model.PdfPage, device transform.Matrix) {
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		common.Log.Debug("ERROR: Reading annotations: %v", err)
+		return
+	}
+	for _, annot := range annotations {
+		// Hidden (bit 2) and NoView (bit 6) annotations are not drawn.
+		if flags, ok := core.GetIntVal(annot.F); ok && flags&(1<<1|1<<5) != 0 {
+			continue
+		}
+		stream := appearanceStream(annot)
+		if stream == nil {
+			continue
+		}
+		xform, err := model.NewXObjectFormFromStream(stream)
+		if err != nil {
+			common.Log.Debug("Invalid appearance stream: %v", err)
+			continue
+		}
+		rect, ok := core.GetArray(annot.Rect)
+		if !ok {
+			continue
+		}
+		rf, err := rect.ToFloat64Array()
+		if err != nil || len(rf) != 4 {
+			continue
+		}
+		bbox, ok := core.GetArray(xform.BBox)
+		if !ok {
+			continue
+		}
+		bf, err := bbox.ToFloat64Array()
+		if err != nil || len(bf) != 4 {
+			continue
+		}
+
+		// The bounding box transformed by the form matrix is mapped to the annotation rectangle
+		// (12.5.5).
+		m := transform.IdentityMatrix()
+		if arr, ok := core.GetArray(xform.Matrix); ok {
+			if f, err := arr.ToFloat64Array(); err == nil {
+				m = matrixFromFloats(f)
+			}
+		}
+		minX, minY := math.Inf(1), math.Inf(1)
+		maxX, maxY := math.Inf(-1), math.Inf(-1)
+		for _, c := range [][2]float64{{bf[0], bf[1]}, {bf[2], bf[1]}, {bf[2], bf[3]}, {bf[0], bf[3]}} {
+			x, y := m.Transform(c[0], c[1])
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+			minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+		}
+		if maxX <= minX || maxY <= minY {
+			continue
+		}
+		llx, lly := math.Min(rf[0], rf[2]), math.Min(rf[1], rf[3])
+		urx, ury := math.Max(rf[0], rf[2]), math.Max(rf[1], rf[3])
+		sx, sy := (urx-llx)/(maxX-minX), (ury-lly)/(maxY-minY)
+		a := transform.NewMatrix(sx, 0, 0, sy, llx-minX*sx, lly-minY*sy)
+
+		resources := xform.Resources
+		if resources == nil {
+			resources = page.Resources
+		}
+		if err := r.runForm(xform, resources, mul(a, device), newState()); err != nil {
+			common.Log.Debug("ERROR: Rendering annotation appearance: %v", err)
+		}
+	}
+}
+
+// appearanceStream returns the normal appearance stream of `annot` for its appearance state.
+func appearanceStream(annot *model.PdfAnnotation) *core.PdfObjectStream {
+	ap, ok := core.GetDict(annot.AP)
+	if !ok {
+		return nil
+	}
+	n := core.ResolveReference(ap.Get("N"))
+	if stream, ok := core.GetStream(n); ok {
+		return stream
+	}
+	states, ok := core.GetDict(n)
+	if !ok {
+		return nil
+	}
+	as, ok := core.GetName(annot.AS)
+	if !ok {
+		return nil
+	}
+	stream, _ := core.GetStream(states.Get(*as))
+	return stream
+}
@@ -0,0 +1,749 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"image"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// maxTileSize is the largest width and height in pixels of a rendered pattern cell.
+const maxTileSize = 2048
+
+// drawPattern paints the pattern of `pt` through `mask` with the compositor `c`.
+func (run *contentRun) drawPattern(pt paint, mask *image.Alpha, c *compositor) {
+	pattern := pt.pattern
+	switch {
+	case pattern.IsShading():
+		sp := pattern.GetAsShadingPattern()
+		if sp.Shading == nil {
+			return
+		}
+		m := pt.base
+		if sp.Matrix != nil {
+			if f, err := sp.Matrix.ToFloat64Array(); err == nil {
+				m = mul(matrixFromFloats(f), pt.base)
+			}
+		}
+		run.drawShading(sp.Shading, m, mask, c, true)
+	case pattern.IsTiling():
+		run.drawTiling(pattern.GetAsTilingPattern(), pt, mask, c)
+	}
+}
+
+// drawTiling paints the tiling pattern `tp` through `mask`. The pattern cell is rendered to an
+// image which is repeated over the painted area.
+func (run *contentRun) drawTiling(tp *model.PdfTilingPattern, pt paint, mask *image.Alpha, c *compositor) {
+	if tp.BBox == nil || tp.XStep == nil || tp.YStep == nil {
+		return
+	}
+	xstep, ystep := math.Abs(float64(*tp.XStep)), math.Abs(float64(*tp.YStep))
+	if xstep == 0 || ystep == 0 {
+		return
+	}
+	m := pt.base
+	if tp.Matrix != nil {
+		if f, err := tp.Matrix.ToFloat64Array(); err == nil {
+			m = mul(matrixFromFloats(f), pt.base)
+		}
+	}
+	inv, ok := invert(m)
+	if !ok {
+		return
+	}
+	bx, by := math.Min(tp.BBox.Llx, tp.BBox.Urx), math.Min(tp.BBox.Lly, tp.BBox.Ury)
+
+	// The cell image maps a step of the pattern to its size in device pixels.
+	tw := clampInt(math.Ceil(xstep*math.Hypot(m[0], m[1])), 1, maxTileSize)
+	th := clampInt(math.Ceil(ystep*math.Hypot(m[3], m[4])), 1, maxTileSize)
+	cell := transform.NewMatrix(float64(tw)/xstep, 0, 0, -float64(th)/ystep,
+		-bx*float64(tw)/xstep, (by+ystep)*float64(th)/ystep)
+	tile := image.NewRGBA(image.Rect(0, 0, tw, th))
+
+	contents, err := tp.GetContentStream()
+	if err != nil {
+		common.Log.Debug("ERROR: Tiling pattern contents: %v", err)
+		return
+	}
+	resources := tp.Resources
+	if resources == nil {
+		resources = pt.resources
+	}
+	tr := run.r.layer(tile)
+	// The cells of the neighbouring tiles overlap the cell if the bounding box is larger than
+	// the steps.
+	for _, k := range []float64{-1, 0, 1} {
+		for _, l := range []float64{-1, 0, 1} {
+			base := mul(transform.TranslationMatrix(k*xstep, l*ystep), cell)
+			st := newState()
+			if !tp.IsColored() {
+				// Uncoloured patterns are painted with the colour given with the pattern.
+				col := paint{color: pt.color}
+				st.fill, st.strokePaint, st.lockColor = &col, &col, true
+			}
+			var p path
+			p.rect(tp.BBox.Llx, tp.BBox.Lly, tp.BBox.Urx-tp.BBox.Llx, tp.BBox.Ury-tp.BBox.Lly)
+			st.clip = rasterize(p.transform(base).flatten(flatness), nonZero, tile.Rect)
+			if err := tr.runContent(string(contents), resources, base, st); err != nil {
+				common.Log.Debug("ERROR: Rendering pattern cell: %v", err)
+			}
+		}
+	}
+
+	c.paint(mask, func(x, y int) (rgb, float64) {
+		u, v := inv.Transform(float64(x)+0.5, float64(y)+0.5)
+		u = math.Mod(u-bx, xstep)
+		if u < 0 {
+			u += xstep
+		}
+		v = math.Mod(v-by, ystep)
+		if v < 0 {
+			v += ystep
+		}
+		tx := clampInt(u*float64(tw)/xstep, 0, tw-1)
+		ty := clampInt((ystep-v)*float64(th)/ystep, 0, th-1)
+		p := tile.Pix[tile.PixOffset(tx, ty):]
+		a := float64(p[3]) / 255
+		if a == 0 {
+			return rgb{}, 0
+		}
+		return rgb{float64(p[0]) / 255 / a, float64(p[1]) / 255 / a, float64(p[2]) / 255 / a}, a
+	})
+}
+
+// drawShading paints `shading` through `mask` with the compositor `c`. `m` maps the shading
+// space to device space. The background colour of the shading is only painted if `background`
+// is set, as it is for shading patterns.
+func (run *contentRun) drawShading(shading *model.PdfShading, m transform.Matrix, mask *image.Alpha,
+	c *compositor, background bool) {
+	inv, ok := invert(m)
+	if !ok {
+		return
+	}
+	cs := shading.ColorSpace
+	if cs == nil {
+		common.Log.Debug("Shading without color space")
+		return
+	}
+	if shading.BBox != nil {
+		b := shading.BBox
+		var p path
+		p.rect(b.Llx, b.Lly, b.Urx-b.Llx, b.Ury-b.Lly)
+		mask = intersectMasks(mask, rasterize(p.transform(m).flatten(flatness), nonZero, run.r.dst.Rect))
+	}
+	var bg *rgb
+	if background && shading.Background != nil {
+		if vals, err := shading.Background.ToFloat64Array(); err == nil {
+			if col, err := cs.ColorFromFloats(vals); err == nil {
+				v := colorToRGB(cs, col)
+				bg = &v
+			}
+		}
+	}
+
+	var src colorSource
+	switch s := shading.GetContext().(type) {
+	case *model.PdfShadingType1:
+		src = functionShading(s, cs, inv)
+	case *model.PdfShadingType2:
+		src = axialShading(s, cs, inv)
+	case *model.PdfShadingType3:
+		src = radialShading(s, cs, inv)
+	case *model.PdfShadingType4, *model.PdfShadingType5, *model.PdfShadingType6, *model.PdfShadingType7:
+		src = run.meshShading(shading, m, mask)
+	default:
+		common.Log.Debug("Unsupported shading type %T", s)
+	}
+	if src == nil {
+		return
+	}
+	if bg != nil {
+		inner := src
+		src = func(x, y int) (rgb, float64) {
+			if col, a := inner(x, y); a > 0 {
+				return col, a
+			}
+			return *bg, 1
+		}
+	}
+	c.paint(mask, src)
+}
+
+// shadingColor returns the colour of a shading with colour space `cs` for the input values
+// `in`, which are colour components if `funcs` is empty.
+func shadingColor(cs model.PdfColorspace, funcs []model.PdfFunction, in []float64) rgb {
+	vals := in
+	if len(funcs) == 1 {
+		out, err := funcs[0].Evaluate(in)
+		if err != nil {
+			return rgb{}
+		}
+		vals = out
+	} else if len(funcs) > 1 {
+		vals = make([]float64, 0, len(funcs))
+		for _, f := range funcs {
+			out, err := f.Evaluate(in)
+			if err != nil || len(out) == 0 {
+				return rgb{}
+			}
+			vals = append(vals, out[0])
+		}
+	}
+	if len(vals) > cs.GetNumComponents() {
+		vals = vals[:cs.GetNumComponents()]
+	}
+	col, err := cs.ColorFromFloats(vals)
+	if err != nil {
+		return rgb{}
+	}
+	return colorToRGB(cs, col)
+}
+
+// floats returns the numbers in `arr`, or `def` if `arr` is nil or invalid.
+func floats(arr *core.PdfObjectArray, def ...float64) []float64 {
+	if arr == nil {
+		return def
+	}
+	f, err := arr.ToFloat64Array()
+	if err != nil || len(f) < len(def) {
+		return def
+	}
+	return f
+}
+
+// extend returns the Extend entry of axial and radial shadings.
+func extend(arr *core.PdfObjectArray) (bool, bool) {
+	if arr == nil || arr.Len() != 2 {
+		return false, false
+	}
+	b0, _ := core.GetBoolVal(arr.Get(0))
+	b1, _ := core.GetBoolVal(arr.Get(1))
+	return b0, b1
+}
+
+// shadingLUT returns a table of the colours of a shading for `n` values of the parameter in
+// [t0, t1].
+func shadingLUT(cs model.PdfColorspace, funcs []model.PdfFunction, t0, t1 float64, n int) []rgb {
+	lut := make([]rgb, n)
+	for i := range lut {
+		t := t0 + (t1-t0)*float64(i)/float64(n-1)
+		lut[i] = shadingColor(cs, funcs, []float64{t})
+	}
+	return lut
+}
+
+// functionShading returns the colour source of a function-based shading.
+func functionShading(s *model.PdfShadingType1, cs model.PdfColorspace, inv transform.Matrix) colorSource {
+	domain := floats(s.Domain, 0, 1, 0, 1)
+	toDomain := inv
+	if s.Matrix != nil {
+		if f, err := s.Matrix.ToFloat64Array(); err == nil {
+			if mi, ok := invert(matrixFromFloats(f)); ok {
+				toDomain = mul(inv, mi)
+			}
+		}
+	}
+	return func(x, y int) (rgb, float64) {
+		u, v := toDomain.Transform(float64(x)+0.5, float64(y)+0.5)
+		if u < domain[0] || u > domain[1] || v < domain[2] || v > domain[3] {
+			return rgb{}, 0
+		}
+		return shadingColor(cs, s.Function, []float64{u, v}), 1
+	}
+}
+
+// axialShading returns the colour source of an axial shading.
+func axialShading(s *model.PdfShadingType2, cs model.PdfColorspace, inv transform.Matrix) colorSource {
+	coords := floats(s.Coords)
+	if len(coords) != 4 {
+		common.Log.Debug("Invalid axial shading coordinates")
+		return nil
+	}
+	domain := floats(s.Domain, 0, 1)
+	e0, e1 := extend(s.Extend)
+	lut := shadingLUT(cs, s.Function, domain[0], domain[1], 512)
+	dx, dy := coords[2]-coords[0], coords[3]-coords[1]
+	d2 := dx*dx + dy*dy
+	return func(x, y int) (rgb, float64) {
+		u, v := inv.Transform(float64(x)+0.5, float64(y)+0.5)
+		var s float64
+		if d2 > 0 {
+			s = ((u-coords[0])*dx + (v-coords[1])*dy) / d2
+		}
+		if s < 0 {
+			if !e0 {
+				return rgb{}, 0
+			}
+			s = 0
+		} else if s > 1 {
+			if !e1 {
+				return rgb{}, 0
+			}
+			s = 1
+		}
+		return lut[int(s*float64(len(lut)-1)+0.5)], 1
+	}
+}
+
+// radialShading returns the colour source of a radial shading.
+func radialShading(s *model.PdfShadingType3, cs model.PdfColorspace, inv transform.Matrix) colorSource {
+	coords := floats(s.Coords)
+	if len(coords) != 6 {
+		common.Log.Debug("Invalid radial shading coordinates")
+		return nil
+	}
+	domain := floats(s.Domain, 0, 1)
+	e0, e1 := extend(s.Extend)
+	lut := shadingLUT(cs, s.Function, domain[0], domain[1], 512)
+	x0, y0, r0 := coords[0], coords[1], coords[2]
+	cdx, cdy, dr := coords[3]-x0, coords[4]-y0, coords[5]-r0
+	a := cdx*cdx + cdy*cdy - dr*dr
+
+	// valid returns the parameter for the circle with parameter `s`, if it is painted.
+	valid := func(s float64) (float64, bool) {
+		if r0+s*dr < 0 {
+			return 0, false
+		}
+		switch {
+		case s < 0:
+			return 0, e0
+		case s > 1:
+			return 1, e1
+		}
+		return s, true
+	}
+	return func(x, y int) (rgb, float64) {
+		u, v := inv.Transform(float64(x)+0.5, float64(y)+0.5)
+		pdx, pdy := u-x0, v-y0
+		b := pdx*cdx + pdy*cdy + r0*dr
+		c := pdx*pdx + pdy*pdy - r0*r0
+		// The circles through the point satisfy a·s² - 2b·s + c = 0. The circle with the
+		// largest parameter is painted.
+		var cands [2]float64
+		n := 0
+		if math.Abs(a) < 1e-9 {
+			if b != 0 {
+				cands[0], n = c/(2*b), 1
+			}
+		} else {
+			disc := b*b - a*c
+			if disc < 0 {
+				return rgb{}, 0
+			}
+			sq := math.Sqrt(disc)
+			s1, s2 := (b+sq)/a, (b-sq)/a
+			if s2 > s1 {
+				s1, s2 = s2, s1
+			}
+			cands, n = [2]float64{s1, s2}, 2
+		}
+		for _, s := range cands[:n] {
+			if t, ok := valid(s); ok {
+				return lut[int(t*float64(len(lut)-1)+0.5)], 1
+			}
+		}
+		return rgb{}, 0
+	}
+}
+
+// meshVertex is a vertex of a shading mesh in device space.
+type meshVertex struct {
+	p point
+	c []float64 // Colour components or the parametric value.
+}
+
+// meshReader reads the packed data of mesh shadings.
+type meshReader struct {
+	data   []byte
+	pos    int // Bit position.
+	decode []float64
+	bpc    int // Bits per coordinate.
+	bpcomp int // Bits per colour component.
+	bpf    int // Bits per flag.
+	ncomp  int // Number of colour values per vertex.
+}
+
+func (r *meshReader) bits(n int) (uint64, bool) {
+	if n <= 0 || n > 32 || r.pos+n > 8*len(r.data) {
+		return 0, false
+	}
+	var v uint64
+	for i := 0; i < n; i++ {
+		b := r.data[(r.pos+i)/8] >> uint(7-(r.pos+i)%8) & 1
+		v = v<<1 | uint64(b)
+	}
+	r.pos += n
+	return v, true
+}
+
+// align moves to the next byte boundary.
+func (r *meshReader) align() {
+	r.pos = (r.pos + 7) / 8 * 8
+}
+
+// value reads an `n` bit value and maps it to the range of the Decode array at index `i`.
+func (r *meshReader) value(n, i int) (float64, bool) {
+	v, ok := r.bits(n)
+	if !ok || 2*i+1 >= len(r.decode) {
+		return 0, false
+	}
+	lo, hi := r.decode[2*i], r.decode[2*i+1]
+	return lo + float64(v)*(hi-lo)/float64(uint64(1)<<uint(n)-1), true
+}
+
+func (r *meshReader) flag() (int, bool) {
+	v, ok := r.bits(r.bpf)
+	return int(v), ok
+}
+
+// point reads a point and transforms it by `m`.
+func (r *meshReader) point(m transform.Matrix) (point, bool) {
+	x, ok1 := r.value(r.bpc, 0)
+	y, ok2 := r.value(r.bpc, 1)
+	x, y = m.Transform(x, y)
+	return point{x, y}, ok1 && ok2
+}
+
+func (r *meshReader) color() ([]float64, bool) {
+	c := make([]float64, r.ncomp)
+	for i := range c {
+		v, ok := r.value(r.bpcomp, 2+i)
+		if !ok {
+			return nil, false
+		}
+		c[i] = v
+	}
+	return c, true
+}
+
+// meshShading returns the colour source of a mesh shading (types 4 to 7). The mesh is drawn to
+// a buffer covering the bounds of `mask`.
+func (run *contentRun) meshShading(shading *model.PdfShading, m transform.Matrix, mask *image.Alpha) colorSource {
+	stream, ok := core.GetStream(shading.GetContainingPdfObject())
+	if !ok {
+		common.Log.Debug("Mesh shading not a stream")
+		return nil
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Decoding mesh shading: %v", err)
+		return nil
+	}
+	cs := shading.ColorSpace
+	r := &meshReader{data: data, ncomp: cs.GetNumComponents()}
+	var funcs []model.PdfFunction
+	var decode *core.PdfObjectArray
+	var bpc, bpcomp, bpf, perRow *core.PdfObjectInteger
+	switch s := shading.GetContext().(type) {
+	case *model.PdfShadingType4:
+		decode, bpc, bpcomp, bpf, funcs = s.Decode, s.BitsPerCoordinate, s.BitsPerComponent, s.BitsPerFlag, s.Function
+	case *model.PdfShadingType5:
+		decode, bpc, bpcomp, perRow, funcs = s.Decode, s.BitsPerCoordinate, s.BitsPerComponent, s.VerticesPerRow, s.Function
+	case *model.PdfShadingType6:
+		decode, bpc, bpcomp, bpf, funcs = s.Decode, s.BitsPerCoordinate, s.BitsPerComponent, s.BitsPerFlag, s.Function
+	case *model.PdfShadingType7:
+		decode, bpc, bpcomp, bpf, funcs = s.Decode, s.BitsPerCoordinate, s.BitsPerComponent, s.BitsPerFlag, s.Function
+	}
+	if decode == nil || bpc == nil || bpcomp == nil {
+		common.Log.Debug("Invalid mesh shading")
+		return nil
+	}
+	r.decode = floats(decode)
+	r.bpc, r.bpcomp = int(*bpc), int(*bpcomp)
+	if bpf != nil {
+		r.bpf = int(*bpf)
+	}
+	if len(funcs) > 0 {
+		r.ncomp = 1
+	}
+
+	bounds := run.r.dst.Rect
+	if mask != nil {
+		bounds = bounds.Intersect(mask.Rect)
+	}
+	buf := image.NewRGBA(bounds)
+	colorOf := func(c []float64) rgb { return shadingColor(cs, funcs, c) }
+	switch shading.GetContext().(type) {
+	case *model.PdfShadingType4:
+		drawFreeFormMesh(buf, r, m, colorOf)
+	case *model.PdfShadingType5:
+		if perRow == nil || *perRow < 2 {
+			return nil
+		}
+		drawLatticeMesh(buf, r, m, int(*perRow), colorOf)
+	case *model.PdfShadingType6:
+		drawPatchMesh(buf, r, m, false, colorOf)
+	case *model.PdfShadingType7:
+		drawPatchMesh(buf, r, m, true, colorOf)
+	}
+	return func(x, y int) (rgb, float64) {
+		if !(image.Point{x, y}).In(buf.Rect) {
+			return rgb{}, 0
+		}
+		p := buf.Pix[buf.PixOffset(x, y):]
+		if p[3] == 0 {
+			return rgb{}, 0
+		}
+		return rgb{float64(p[0]) / 255, float64(p[1]) / 255, float64(p[2]) / 255}, 1
+	}
+}
+
+// drawFreeFormMesh draws a free-form triangle mesh (type 4).
+func drawFreeFormMesh(buf *image.RGBA, r *meshReader, m transform.Matrix, colorOf func([]float64) rgb) {
+	var a, b, c meshVertex
+	// pending is the number of vertices still needed to complete a new triangle.
+	pending := 0
+	started := false
+	for {
+		f, ok := r.flag()
+		if !ok {
+			return
+		}
+		p, ok := r.point(m)
+		if !ok {
+			return
+		}
+		col, ok := r.color()
+		if !ok {
+			return
+		}
+		r.align()
+		v := meshVertex{p, col}
+
+		if pending > 0 {
+			if pending == 2 {
+				b = v
+			} else {
+				c = v
+				fillTriangle(buf, a, b, c, colorOf)
+				started = true
+			}
+			pending--
+			continue
+		}
+		switch {
+		case f == 0:
+			a, pending, started = v, 2, false
+		case f == 1 && started:
+			// The new triangle shares the edge bc of the previous one.
+			a, b, c = b, c, v
+			fillTriangle(buf, a, b, c, colorOf)
+		case f == 2 && started:
+			// The new triangle shares the edge ac of the previous one.
+			b, c = c, v
+			fillTriangle(buf, a, b, c, colorOf)
+		}
+	}
+}
+
+// drawLatticeMesh draws a lattice-form triangle mesh (type 5).
+func drawLatticeMesh(buf *image.RGBA, r *meshReader, m transform.Matrix, perRow int, colorOf func([]float64) rgb) {
+	var prev []meshVertex
+	for {
+		row := make([]meshVertex, 0, perRow)
+		for len(row) < perRow {
+			p, ok := r.point(m)
+			if !ok {
+				return
+			}
+			c, ok := r.color()
+			if !ok {
+				return
+			}
+			r.align()
+			row = append(row, meshVertex{p, c})
+		}
+		if prev != nil {
+			for i := 0; i+1 < perRow; i++ {
+				fillTriangle(buf, prev[i], prev[i+1], row[i], colorOf)
+				fillTriangle(buf, prev[i+1], row[i+1], row[i], colorOf)
+			}
+		}
+		prev = row
+	}
+}
+
+// fillTriangle draws the Gouraud-shaded triangle `a`,`b`,`c` into `buf`.
+func fillTriangle(buf *image.RGBA, a, b, c meshVertex, colorOf func([]float64) rgb) {
+	minX := clampInt(math.Floor(math.Min(a.p.x, math.Min(b.p.x, c.p.x))), buf.Rect.Min.X, buf.Rect.Max.X)
+	maxX := clampInt(math.Ceil(math.Max(a.p.x, math.Max(b.p.x, c.p.x))), buf.Rect.Min.X, buf.Rect.Max.X)
+	minY := clampInt(math.Floor(math.Min(a.p.y, math.Min(b.p.y, c.p.y))), buf.Rect.Min.Y, buf.Rect.Max.Y)
+	maxY := clampInt(math.Ceil(math.Max(a.p.y, math.Max(b.p.y, c.p.y))), buf.Rect.Min.Y, buf.Rect.Max.Y)
+	det := (b.p.y-c.p.y)*(a.p.x-c.p.x) + (c.p.x-b.p.x)*(a.p.y-c.p.y)
+	if det == 0 || math.IsNaN(det) {
+		return
+	}
+	const eps = 1e-6
+	comps := make([]float64, len(a.c))
+	for y := minY; y < maxY; y++ {
+		py := float64(y) + 0.5
+		for x := minX; x < maxX; x++ {
+			px := float64(x) + 0.5
+			l1 := ((b.p.y-c.p.y)*(px-c.p.x) + (c.p.x-b.p.x)*(py-c.p.y)) / det
+			l2 := ((c.p.y-a.p.y)*(px-c.p.x) + (a.p.x-c.p.x)*(py-c.p.y)) / det
+			l3 := 1 - l1 - l2
+			if l1 < -eps || l2 < -eps || l3 < -eps {
+				continue
+			}
+			for i := range comps {
+				comps[i] = l1*a.c[i] + l2*b.c[i] + l3*c.c[i]
+			}
+			col := colorOf(comps)
+			p := buf.Pix[buf.PixOffset(x, y):]
+			p[0], p[1], p[2], p[3] = to8(col[0]), to8(col[1]), to8(col[2]), 255
+		}
+	}
+}
+
+// patch is a tensor-product patch with control points p[i][j] and the colours of the corners
+// p00, p03, p33 and p30.
+type patch struct {
+	p [4][4]point
+	c [4][]float64
+}
+
+// patchOrder is the order of the control points of a patch in the data of patch meshes as
+// indices into patch.p. The first 4 points are shared with the previous patch if the flag is
+// not 0, and the last 4 are only present in tensor-product patches.
+var patchOrder = [16][2]int{
+	{0, 0}, {0, 1}, {0, 2}, {0, 3}, {1, 3}, {2, 3}, {3, 3}, {3, 2},
+	{3, 1}, {3, 0}, {2, 0}, {1, 0}, {1, 1}, {1, 2}, {2, 2}, {2, 1},
+}
+
+// drawPatchMesh draws a Coons (type 6) or tensor-product (type 7) patch mesh.
+func drawPatchMesh(buf *image.RGBA, r *meshReader, m transform.Matrix, tensor bool, colorOf func([]float64) rgb) {
+	var prev *patch
+	for {
+		f, ok := r.flag()
+		if !ok {
+			return
+		}
+		var pt patch
+		first, firstColor := 0, 0
+		if f != 0 {
+			if prev == nil {
+				return
+			}
+			// The edge and the two corner colours shared with the previous patch.
+			var edge [4][2]int
+			switch f {
+			case 1:
+				edge = [4][2]int{{0, 3}, {1, 3}, {2, 3}, {3, 3}}
+				pt.c[0], pt.c[1] = prev.c[1], prev.c[2]
+			case 2:
+				edge = [4][2]int{{3, 3}, {3, 2}, {3, 1}, {3, 0}}
+				pt.c[0], pt.c[1] = prev.c[2], prev.c[3]
+			case 3:
+				edge = [4][2]int{{3, 0}, {2, 0}, {1, 0}, {0, 0}}
+				pt.c[0], pt.c[1] = prev.c[3], prev.c[0]
+			default:
+				return
+			}
+			for i, e := range edge {
+				pt.p[0][i] = prev.p[e[0]][e[1]]
+			}
+			first, firstColor = 4, 2
+		}
+		n := 12
+		if tensor {
+			n = 16
+		}
+		for i := first; i < n; i++ {
+			p, ok := r.point(m)
+			if !ok {
+				return
+			}
+			pt.p[patchOrder[i][0]][patchOrder[i][1]] = p
+		}
+		for i := firstColor; i < 4; i++ {
+			c, ok := r.color()
+			if !ok {
+				return
+			}
+			pt.c[i] = c
+		}
+		r.align()
+		if !tensor {
+			pt.coonsInterior()
+		}
+		pt.draw(buf, colorOf)
+		prev = &pt
+	}
+}
+
+// coonsInterior sets the interior control points of a Coons patch (8.7.4.5.8).
+func (pt *patch) coonsInterior() {
+	p := &pt.p
+	comb := func(a, b1, b2, c1, c2, d1, d2, e point) point {
+		return point{
+			(-4*a.x + 6*(b1.x+b2.x) - 2*(c1.x+c2.x) + 3*(d1.x+d2.x) - e.x) / 9,
+			(-4*a.y + 6*(b1.y+b2.y) - 2*(c1.y+c2.y) + 3*(d1.y+d2.y) - e.y) / 9,
+		}
+	}
+	p[1][1] = comb(p[0][0], p[0][1], p[1][0], p[0][3], p[3][0], p[3][1], p[1][3], p[3][3])
+	p[1][2] = comb(p[0][3], p[0][2], p[1][3], p[0][0], p[3][3], p[3][2], p[1][0], p[3][0])
+	p[2][1] = comb(p[3][0], p[3][1], p[2][0], p[3][3], p[0][0], p[0][1], p[2][3], p[0][3])
+	p[2][2] = comb(p[3][3], p[3][2], p[2][3], p[3][0], p[0][3], p[0][2], p[2][0], p[0][0])
+}
+
+// draw draws the patch as a grid of Gouraud-shaded triangles.
+func (pt *patch) draw(buf *image.RGBA, colorOf func([]float64) rgb) {
+	if len(pt.c[0]) == 0 || len(pt.c[1]) != len(pt.c[0]) || len(pt.c[2]) != len(pt.c[0]) ||
+		len(pt.c[3]) != len(pt.c[0]) {
+		return
+	}
+	// The grid resolution depends on the size of the patch in device space.
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i := range pt.p {
+		for _, q := range pt.p[i] {
+			minX, maxX = math.Min(minX, q.x), math.Max(maxX, q.x)
+			minY, maxY = math.Min(minY, q.y), math.Max(maxY, q.y)
+		}
+	}
+	n := clampInt(math.Max(maxX-minX, maxY-minY)/4, 2, 64)
+
+	grid := make([][]meshVertex, n+1)
+	for i := range grid {
+		u := float64(i) / float64(n)
+		grid[i] = make([]meshVertex, n+1)
+		for j := range grid[i] {
+			v := float64(j) / float64(n)
+			var q point
+			bu, bv := bernstein(u), bernstein(v)
+			for a := 0; a < 4; a++ {
+				for b := 0; b < 4; b++ {
+					w := bu[a] * bv[b]
+					q.x += w * pt.p[a][b].x
+					q.y += w * pt.p[a][b].y
+				}
+			}
+			c := make([]float64, len(pt.c[0]))
+			for k := range c {
+				// Corners: c0 at (0,0), c1 at (0,1), c2 at (1,1), c3 at (1,0).
+				c[k] = (1-u)*(1-v)*pt.c[0][k] + (1-u)*v*pt.c[1][k] + u*v*pt.c[2][k] + u*(1-v)*pt.c[3][k]
+			}
+			grid[i][j] = meshVertex{q, c}
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			fillTriangle(buf, grid[i][j], grid[i+1][j], grid[i][j+1], colorOf)
+			fillTriangle(buf, grid[i+1][j], grid[i+1][j+1], grid[i][j+1], colorOf)
+		}
+	}
+}
+
+// bernstein returns the cubic Bernstein polynomials at `t`.
+func bernstein(t float64) [4]float64 {
+	s := 1 - t
+	return [4]float64{s * s * s, 3 * s * s * t, 3 * s * t * t, t * t * t}
+}
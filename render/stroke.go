@@ -0,0 +1,286 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"math"
+)
+
+// Line cap styles (8.4.3.3).
+const (
+	capButt = iota
+	capRound
+	capSquare
+)
+
+// Line join styles (8.4.3.4).
+const (
+	joinMiter = iota
+	joinRound
+	joinBevel
+)
+
+// strokeStyle holds the graphics state parameters that control stroking.
+type strokeStyle struct {
+	width      float64
+	cap        int
+	join       int
+	miterLimit float64
+	dash       []float64
+	phase      float64
+}
+
+// stroker converts polylines in user space to polygons that cover their stroke.
+type stroker struct {
+	strokeStyle
+	hw         float64 // Half of the line width.
+	circleSegs int     // Number of segments of round caps and joins.
+	polys      []polyline
+}
+
+// strokePolylines returns polygons in user space whose nonzero fill covers the stroke of `lines`
+// with style `st`. `scale` is the device pixels per user space unit, which determines the
+// precision of round caps and joins.
+func strokePolylines(lines []polyline, st strokeStyle, scale float64) []polyline {
+	s := &stroker{strokeStyle: st, hw: st.width / 2}
+	r := s.hw * scale
+	s.circleSegs = 8
+	if r > 0.5 {
+		s.circleSegs = int(math.Ceil(math.Pi / math.Acos(1-0.2/r)))
+	}
+	if s.circleSegs < 8 {
+		s.circleSegs = 8
+	} else if s.circleSegs > 256 {
+		s.circleSegs = 256
+	}
+
+	for _, l := range lines {
+		l = dedupe(l)
+		for _, d := range s.applyDash(l) {
+			s.strokePolyline(dedupe(d))
+		}
+	}
+	return s.polys
+}
+
+// dedupe returns `l` without consecutive duplicate points.
+func dedupe(l polyline) polyline {
+	out := polyline{closed: l.closed}
+	for i, p := range l.pts {
+		if i > 0 && p == out.pts[len(out.pts)-1] {
+			continue
+		}
+		out.pts = append(out.pts, p)
+	}
+	if l.closed && len(out.pts) > 1 && out.pts[0] == out.pts[len(out.pts)-1] {
+		out.pts = out.pts[:len(out.pts)-1]
+	}
+	return out
+}
+
+// applyDash splits `l` into the dashes of the dash pattern.
+func (s *stroker) applyDash(l polyline) []polyline {
+	total := 0.0
+	for _, d := range s.dash {
+		if d < 0 {
+			return []polyline{l}
+		}
+		total += d
+	}
+	if total <= 0 || len(l.pts) < 2 {
+		return []polyline{l}
+	}
+	dash := s.dash
+	if len(dash)%2 == 1 {
+		dash = append(append([]float64(nil), dash...), dash...)
+		total *= 2
+	}
+
+	// Find the dash at the phase.
+	idx := 0
+	remaining := dash[0]
+	phase := math.Mod(s.phase, total)
+	if phase < 0 {
+		phase += total
+	}
+	for phase > 0 {
+		if phase < remaining {
+			remaining -= phase
+			break
+		}
+		phase -= remaining
+		idx = (idx + 1) % len(dash)
+		remaining = dash[idx]
+	}
+
+	pts := l.pts
+	if l.closed {
+		pts = append(append([]point(nil), pts...), pts[0])
+	}
+	var out []polyline
+	on := idx%2 == 0
+	cur := polyline{}
+	if on {
+		cur.pts = []point{pts[0]}
+	}
+	for i := 0; i+1 < len(pts); i++ {
+		p, q := pts[i], pts[i+1]
+		segLen := math.Hypot(q.x-p.x, q.y-p.y)
+		pos := 0.0
+		for segLen-pos > remaining {
+			pos += remaining
+			t := pos / segLen
+			m := point{p.x + (q.x-p.x)*t, p.y + (q.y-p.y)*t}
+			if on {
+				cur.pts = append(cur.pts, m)
+				out = append(out, cur)
+				cur = polyline{}
+			} else {
+				cur.pts = []point{m}
+			}
+			on = !on
+			idx = (idx + 1) % len(dash)
+			remaining = dash[idx]
+		}
+		remaining -= segLen - pos
+		if on {
+			cur.pts = append(cur.pts, q)
+		}
+	}
+	if on && len(cur.pts) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// strokePolyline adds the polygons of the stroke of `l`.
+func (s *stroker) strokePolyline(l polyline) {
+	pts := l.pts
+	if len(pts) == 0 {
+		return
+	}
+	if len(pts) == 1 {
+		// A degenerate subpath is drawn as a dot with round and square caps.
+		p := pts[0]
+		switch s.cap {
+		case capRound:
+			s.circle(p)
+		case capSquare:
+			s.add(point{p.x - s.hw, p.y - s.hw}, point{p.x + s.hw, p.y - s.hw},
+				point{p.x + s.hw, p.y + s.hw}, point{p.x - s.hw, p.y + s.hw})
+		}
+		return
+	}
+
+	n := len(pts)
+	segs := n - 1
+	if l.closed {
+		segs = n
+	}
+	for i := 0; i < segs; i++ {
+		p, q := pts[i], pts[(i+1)%n]
+		nx, ny := s.normal(p, q)
+		s.add(point{p.x + nx, p.y + ny}, point{q.x + nx, q.y + ny},
+			point{q.x - nx, q.y - ny}, point{p.x - nx, p.y - ny})
+	}
+	for i := 0; i < n; i++ {
+		if !l.closed && (i == 0 || i == n-1) {
+			continue
+		}
+		s.joinAt(pts[(i+n-1)%n], pts[i], pts[(i+1)%n])
+	}
+	if !l.closed {
+		s.capAt(pts[1], pts[0])
+		s.capAt(pts[n-2], pts[n-1])
+	}
+}
+
+// normal returns the normal of the segment from `p` to `q` scaled to half the line width.
+func (s *stroker) normal(p, q point) (float64, float64) {
+	dx, dy := q.x-p.x, q.y-p.y
+	d := math.Hypot(dx, dy)
+	return -dy / d * s.hw, dx / d * s.hw
+}
+
+// capAt adds the cap at the end `q` of the segment from `p`.
+func (s *stroker) capAt(p, q point) {
+	switch s.cap {
+	case capRound:
+		s.circle(q)
+	case capSquare:
+		nx, ny := s.normal(p, q)
+		// The direction of the segment scaled to half the line width.
+		dx, dy := ny, -nx
+		s.add(point{q.x + nx, q.y + ny}, point{q.x + nx + dx, q.y + ny + dy},
+			point{q.x - nx + dx, q.y - ny + dy}, point{q.x - nx, q.y - ny})
+	}
+}
+
+// joinAt adds the join at `v` between the segments from `p` and to `q`.
+func (s *stroker) joinAt(p, v, q point) {
+	ax, ay := s.normal(p, v)
+	bx, by := s.normal(v, q)
+	cross := ax*by - ay*bx
+	dot := ax*bx + ay*by
+	if math.Abs(cross) < 1e-9*s.hw*s.hw && dot > 0 {
+		return
+	}
+	if s.join == joinRound {
+		s.circle(v)
+		return
+	}
+	// The join is on the outer side of the turn.
+	if cross > 0 {
+		ax, ay, bx, by = -ax, -ay, -bx, -by
+	}
+	outerA := point{v.x + ax, v.y + ay}
+	outerB := point{v.x + bx, v.y + by}
+	if s.join == joinMiter {
+		// The ratio of the miter length to the line width is 1/sin(φ/2), with φ the angle
+		// between the segments.
+		cosPhi := -dot / (s.hw * s.hw)
+		sinHalf := math.Sqrt(math.Max(0, (1-cosPhi)/2))
+		if sinHalf > 0 && 1/sinHalf <= s.miterLimit {
+			mx, my := ax+bx, ay+by
+			ml := math.Hypot(mx, my)
+			if ml > 0 {
+				k := s.hw / sinHalf / ml
+				s.add(v, outerA, point{v.x + mx*k, v.y + my*k}, outerB)
+				return
+			}
+		}
+	}
+	s.add(v, outerA, outerB)
+}
+
+// circle adds a circle around `c` with a diameter of the line width.
+func (s *stroker) circle(c point) {
+	pts := make([]point, s.circleSegs)
+	for i := range pts {
+		a := 2 * math.Pi * float64(i) / float64(len(pts))
+		pts[i] = point{c.x + s.hw*math.Cos(a), c.y + s.hw*math.Sin(a)}
+	}
+	s.add(pts...)
+}
+
+// add adds the polygon `pts` with counterclockwise orientation, so that the overlapping polygons
+// of the stroke do not cancel under the nonzero winding rule.
+func (s *stroker) add(pts ...point) {
+	area := 0.0
+	for i, p := range pts {
+		q := pts[(i+1)%len(pts)]
+		area += p.x*q.y - q.x*p.y
+	}
+	if area == 0 || math.IsNaN(area) {
+		return
+	}
+	if area < 0 {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	s.polys = append(s.polys, polyline{pts: pts, closed: true})
+}
@@ -0,0 +1,144 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+)
+
+// textObject holds the state of a text object (between BT and ET) that the content stream
+// processor does not track.
+type textObject struct {
+	// clip collects the glyph outlines in device space that are added to the clipping path at
+	// the end of the text object.
+	clip     []polyline
+	clipping bool
+}
+
+// handleText handles the BT and ET operators and the text showing operators.
+func (run *contentRun) handleText(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+	ctm transform.Matrix) {
+	t := &run.text
+	switch op.Operand {
+	case "BT":
+		*t = textObject{}
+		return
+	case "ET":
+		if t.clipping {
+			mask := rasterize(t.clip, nonZero, run.r.dst.Rect)
+			run.textClip = intersectMasks(run.textClip, mask)
+		}
+		*t = textObject{}
+		return
+	}
+	if len(op.Params) == 0 {
+		return
+	}
+
+	// The processor passes the text matrix at the start of the text and the text state set by
+	// ' and ".
+	ts := gs.TextState
+	tm := ts.Tm
+	if op.Operand != "TJ" {
+		if s, ok := core.GetString(op.Params[len(op.Params)-1]); ok {
+			run.showText(s.Bytes(), gs, ctm, &tm)
+		}
+		return
+	}
+	arr, ok := core.GetArray(op.Params[0])
+	if !ok {
+		return
+	}
+	for _, o := range arr.Elements() {
+		if s, ok := core.GetString(o); ok {
+			run.showText(s.Bytes(), gs, ctm, &tm)
+		} else if v, err := core.GetNumberAsFloat(o); err == nil {
+			tm = mul(transform.TranslationMatrix(-v/1000*ts.FontSize*ts.HorizontalScaling/100, 0), tm)
+		}
+	}
+}
+
+// showText draws the glyphs of the string `data` with the text matrix `tm` and advances `tm`.
+func (run *contentRun) showText(data []byte, gs contentstream.GraphicsState, ctm transform.Matrix,
+	tm *transform.Matrix) {
+	ts := gs.TextState
+	if ts.FontObject == nil {
+		common.Log.Debug("Text shown without a font")
+		return
+	}
+	font := run.r.loadFont(ts.FontObject)
+	if ts.RenderMode >= 4 {
+		run.text.clipping = true
+	}
+	scale := ts.HorizontalScaling / 100
+	for _, code := range font.codes(data) {
+		// Text rendering matrix without the CTM (9.4.4).
+		trm := mul(transform.NewMatrix(ts.FontSize*scale, 0, 0, ts.FontSize, 0, ts.Rise), *tm)
+		if font.type3 != nil {
+			run.drawType3Glyph(font.type3, code, trm, gs, ctm)
+		} else {
+			run.drawGlyph(font.glyph(code), trm, gs, ctm)
+		}
+
+		tx := font.width(code)*ts.FontSize + ts.CharSpacing
+		if !font.cid && code == 32 {
+			tx += ts.WordSpacing
+		}
+		*tm = mul(transform.TranslationMatrix(tx*scale, 0), *tm)
+	}
+}
+
+// drawGlyph paints the glyph outline `g` in text space with the text rendering matrix `trm`
+// according to the text rendering mode.
+func (run *contentRun) drawGlyph(g *path, trm transform.Matrix, gs contentstream.GraphicsState,
+	ctm transform.Matrix) {
+	if g == nil || g.empty() {
+		return
+	}
+	mode := gs.TextState.RenderMode
+	fill := mode == 0 || mode == 2 || mode == 4 || mode == 6
+	stroke := mode == 1 || mode == 2 || mode == 5 || mode == 6
+
+	device := g.transform(mul(trm, ctm))
+	if fill {
+		mask := rasterize(device.flatten(flatness), nonZero, run.r.dst.Rect)
+		run.fillMask(mask, gs, run.fillPaint(gs), gs.FillAlpha)
+	}
+	if stroke {
+		run.strokePath(g.transform(trm), gs, ctm, run.strokePaintFor(gs))
+	}
+	if mode >= 4 {
+		run.text.clip = append(run.text.clip, device.flatten(flatness)...)
+	}
+}
+
+// drawType3Glyph renders the glyph description of `code` of the Type 3 font `font`.
+func (run *contentRun) drawType3Glyph(font *type3Font, code textencoding.CharCode, trm transform.Matrix,
+	gs contentstream.GraphicsState, ctm transform.Matrix) {
+	if mode := gs.TextState.RenderMode; mode == 3 || mode == 7 {
+		return
+	}
+	contents, ok := font.charProc(code)
+	if !ok {
+		return
+	}
+	st := run.inherit(gs)
+	resources := font.resources
+	if resources == nil {
+		resources = run.resources
+	}
+	base := mul(mul(font.matrix, trm), ctm)
+	r := run.r
+	r.depth++
+	defer func() { r.depth-- }()
+	if err := r.runContentWith(contents, resources, base, st, true); err != nil {
+		common.Log.Debug("ERROR: Rendering Type 3 glyph: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package validate checks PDF documents for conformance to PDF/A (ISO 19005) and PDF/UA
+// (ISO 14289) profiles. The checks cover the requirements that can be verified from the
+// objects of the document, such as embedded fonts, forbidden filters and actions, metadata and
+// output intents and the logical structure, and report each problem found as a violation with
+// the number of the offending object and a reference to the clause of the standard.
+//
+// The checks are not exhaustive: a document without violations is not guaranteed to conform.
+//
+// Example:
+//
+//	reader, err := model.NewPdfReader(f)
+//	...
+//	report, err := validate.Validate(reader, validate.PdfA2B)
+//	...
+//	for _, v := range report.Violations {
+//		fmt.Println(v)
+//	}
+package validate
@@ -0,0 +1,125 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package validate
+
+import (
+	"strings"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// checkMetadata checks the XMP metadata of the document: the PDF/A and PDF/UA identification,
+// the title and the consistency with the document information dictionary.
+func (v *validator) checkMetadata() {
+	obj := v.catalog.Get("Metadata")
+	stream, ok := core.GetStream(obj)
+	if !ok {
+		v.addViolation(ruleMetadata, 0, "catalog has no metadata stream")
+		v.addViolation(rulePdfUAID, 0, "catalog has no metadata stream")
+		v.addViolation(ruleTitle, 0, "catalog has no metadata stream")
+		return
+	}
+	num := objectNumber(obj)
+	if v.profile == PdfA1B && stream.Get("Filter") != nil {
+		v.addViolation(ruleMetadata, num, "metadata stream is filtered")
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		v.addViolation(ruleMetadata, num, "metadata stream cannot be decoded: %v", err)
+		return
+	}
+	xmp, err := model.ParseXMPMetadata(data)
+	if err != nil {
+		v.addViolation(ruleMetadata, num, "metadata is not valid XMP: %v", err)
+		return
+	}
+
+	if part := v.profile.pdfaPart(); part != 0 {
+		if xmp.PdfAPart != part {
+			v.addViolation(rulePdfAID, num, "XMP pdfaid:part is %d, expected %d", xmp.PdfAPart, part)
+		}
+		// Levels A and U also meet the requirements of level B.
+		conformance := xmp.PdfAConformance
+		if conformance != "A" && conformance != "B" && (part == 1 || conformance != "U") {
+			v.addViolation(rulePdfAID, num, "XMP pdfaid:conformance is %q, expected B", conformance)
+		}
+	}
+	if xmp.PdfUAPart != 1 {
+		v.addViolation(rulePdfUAID, num, "XMP pdfuaid:part is %d, expected 1", xmp.PdfUAPart)
+	}
+
+	if xmp.Title == "" {
+		v.addViolation(ruleTitle, num, "XMP metadata has no dc:title")
+	}
+	display := false
+	if prefs, ok := core.GetDict(v.catalog.Get("ViewerPreferences")); ok {
+		display, _ = core.GetBoolVal(prefs.Get("DisplayDocTitle"))
+	}
+	if !display {
+		v.addViolation(ruleTitle, objectNumber(v.catalog.Get("ViewerPreferences")), "viewer preferences do not display the document title (DisplayDocTitle)")
+	}
+
+	if v.checks(ruleMetadataInfo) {
+		v.checkInfo(xmp)
+	}
+}
+
+// checkInfo checks that the entries of the document information dictionary are equivalent to
+// the properties of XMP metadata `xmp`.
+func (v *validator) checkInfo(xmp *model.XMPMetadata) {
+	infoObj := v.trailer.Get("Info")
+	info, ok := core.GetDict(infoObj)
+	if !ok {
+		return
+	}
+	num := objectNumber(infoObj)
+
+	texts := []struct {
+		key   core.PdfObjectName
+		prop  string
+		value string
+	}{
+		{"Title", "dc:title", xmp.Title},
+		{"Author", "dc:creator", strings.Join(xmp.Creators, ", ")},
+		{"Subject", "dc:description", xmp.Description},
+		{"Keywords", "pdf:Keywords", xmp.Keywords},
+		{"Creator", "xmp:CreatorTool", xmp.CreatorTool},
+		{"Producer", "pdf:Producer", xmp.Producer},
+	}
+	for _, t := range texts {
+		s, ok := core.GetString(info.Get(t.key))
+		if !ok {
+			continue
+		}
+		if t.value == "" {
+			v.addViolation(ruleMetadataInfo, num, "Info %s has no equivalent XMP property %s", t.key, t.prop)
+		} else if s.Decoded() != t.value {
+			v.addViolation(ruleMetadataInfo, num, "Info %s is not equivalent to XMP property %s", t.key, t.prop)
+		}
+	}
+
+	dates := []struct {
+		key   core.PdfObjectName
+		prop  string
+		value time.Time
+	}{
+		{"CreationDate", "xmp:CreateDate", xmp.CreateDate},
+		{"ModDate", "xmp:ModifyDate", xmp.ModifyDate},
+	}
+	for _, d := range dates {
+		s, ok := core.GetString(info.Get(d.key))
+		if !ok {
+			continue
+		}
+		if d.value.IsZero() {
+			v.addViolation(ruleMetadataInfo, num, "Info %s has no equivalent XMP property %s", d.key, d.prop)
+		} else if date, err := model.NewPdfDate(s.Str()); err != nil || !date.ToGoTime().Equal(d.value) {
+			v.addViolation(ruleMetadataInfo, num, "Info %s is not equivalent to XMP property %s", d.key, d.prop)
+		}
+	}
+}
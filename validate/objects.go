@@ -0,0 +1,366 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package validate
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// checkObjects checks the objects of the document: fonts, filters, actions, embedded files,
+// transparency, and the device color spaces used against the output intent.
+func (v *validator) checkObjects() {
+	v.deviceColors = map[string]int{}
+
+	nums := v.reader.GetObjectNums()
+	objects := make(map[int]core.PdfObject, len(nums))
+	for _, num := range nums {
+		obj, err := v.reader.GetIndirectObjectByNumber(num)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to load object %d: %v", num, err)
+			continue
+		}
+		objects[num] = obj
+
+		// Page content streams are identified by the pages referring to them.
+		if dict, ok := core.GetDict(obj); ok {
+			if typ, _ := core.GetNameVal(dict.Get("Type")); typ == "Page" {
+				v.addContents(dict.Get("Contents"))
+			}
+		}
+	}
+
+	for _, num := range nums {
+		switch t := objects[num].(type) {
+		case *core.PdfIndirectObject:
+			v.walk(num, t.PdfObject, nil)
+		case *core.PdfObjectStream:
+			typ, _ := core.GetNameVal(t.Get("Type"))
+			if typ == "ObjStm" || typ == "XRef" {
+				continue
+			}
+			v.checkStream(num, t)
+			v.walk(num, t.PdfObjectDictionary, t)
+		}
+	}
+
+	if names, ok := core.GetDict(v.catalog.Get("Names")); ok {
+		num := objectNumber(v.catalog.Get("Names"))
+		if num == 0 {
+			num = v.catalogNum
+		}
+		if names.Get("JavaScript") != nil {
+			v.addViolation(ruleJavaScript, num, "names dictionary has JavaScript")
+		}
+		if v.profile == PdfA1B && names.Get("EmbeddedFiles") != nil {
+			v.addViolation(ruleEmbeddedFiles, num, "names dictionary has EmbeddedFiles")
+		}
+	}
+
+	v.checkOutputIntents()
+}
+
+// addContents records the object numbers of the content streams of page contents `obj`.
+func (v *validator) addContents(obj core.PdfObject) {
+	if arr, ok := core.GetArray(obj); ok {
+		for _, o := range arr.Elements() {
+			v.addContents(o)
+		}
+		return
+	}
+	if num := objectNumber(obj); num != 0 {
+		v.contents[num] = true
+	}
+}
+
+// walk checks the dictionaries of direct object `obj` and its direct descendants, contained in
+// object number `num`. `stream` is the stream of `obj` if it is a stream dictionary.
+func (v *validator) walk(num int, obj core.PdfObject, stream *core.PdfObjectStream) {
+	switch t := obj.(type) {
+	case *core.PdfObjectDictionary:
+		v.checkDict(num, t, stream)
+		for _, key := range t.Keys() {
+			v.walk(num, t.Get(key), nil)
+		}
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			v.walk(num, o, nil)
+		}
+	}
+}
+
+// checkDict checks dictionary `dict` of object number `num`, the dictionary of `stream` if not
+// nil.
+func (v *validator) checkDict(num int, dict *core.PdfObjectDictionary, stream *core.PdfObjectStream) {
+	typ, _ := core.GetNameVal(dict.Get("Type"))
+	if typ == "Font" {
+		v.checkFont(num, dict)
+	}
+	if s, _ := core.GetNameVal(dict.Get("S")); s == "JavaScript" {
+		v.addViolation(ruleJavaScript, num, "JavaScript action")
+	}
+	if dict.Get("EF") != nil {
+		v.checkEmbeddedFile(num, dict)
+	}
+	v.addDeviceColors(num, dict.Get("ColorSpace"))
+	if group, ok := core.GetDict(dict.Get("Group")); ok {
+		v.addDeviceColors(num, group.Get("CS"))
+	}
+	if v.checks(ruleTransparency) {
+		subtype, _ := core.GetNameVal(dict.Get("Subtype"))
+		v.checkTransparency(num, dict, stream != nil && subtype == "Image")
+	}
+}
+
+// checkFont checks that the font program of font dictionary `dict` is embedded.
+func (v *validator) checkFont(num int, dict *core.PdfObjectDictionary) {
+	subtype, _ := core.GetNameVal(dict.Get("Subtype"))
+	switch subtype {
+	case "Type1", "MMType1", "TrueType", "CIDFontType0", "CIDFontType2":
+	default:
+		return
+	}
+	if descriptor, ok := core.GetDict(dict.Get("FontDescriptor")); ok {
+		for _, key := range []core.PdfObjectName{"FontFile", "FontFile2", "FontFile3"} {
+			if _, ok := core.GetStream(descriptor.Get(key)); ok {
+				return
+			}
+		}
+	}
+	baseFont, _ := core.GetNameVal(dict.Get("BaseFont"))
+	v.addViolation(ruleFontEmbedded, num, "font %s is not embedded", baseFont)
+}
+
+// checkEmbeddedFile checks file specification `dict` with embedded files.
+func (v *validator) checkEmbeddedFile(num int, dict *core.PdfObjectDictionary) {
+	name := ""
+	if s, ok := core.GetString(dict.Get("UF")); ok {
+		name = s.Decoded()
+	} else if s, ok := core.GetString(dict.Get("F")); ok {
+		name = s.Decoded()
+	}
+	var subtype string
+	if ef, ok := core.GetDict(dict.Get("EF")); ok {
+		if stream, ok := core.GetStream(ef.Get("F")); ok {
+			subtype, _ = core.GetNameVal(stream.Get("Subtype"))
+		}
+	}
+
+	switch v.profile {
+	case PdfA1B:
+		v.addViolation(ruleEmbeddedFiles, num, "embedded file %q", name)
+	case PdfA2B:
+		if subtype != "application/pdf" {
+			v.addViolation(ruleEmbeddedFiles, num, "embedded file %q is not a PDF/A document", name)
+		}
+	case PdfA3B:
+		if dict.Get("AFRelationship") == nil {
+			v.addViolation(ruleEmbeddedFiles, num, "embedded file %q has no AFRelationship", name)
+		}
+		if subtype == "" {
+			v.addViolation(ruleEmbeddedFiles, num, "embedded file %q has no MIME type (Subtype)", name)
+		}
+		if dict.Get("F") == nil || dict.Get("UF") == nil {
+			v.addViolation(ruleEmbeddedFiles, num, "file specification of %q has no F and UF entries", name)
+		}
+	}
+}
+
+// checkTransparency checks that `dict` does not use transparency. `isImage` is true for image
+// dictionaries, which cannot have soft masks.
+func (v *validator) checkTransparency(num int, dict *core.PdfObjectDictionary, isImage bool) {
+	if smask := dict.Get("SMask"); smask != nil {
+		if name, _ := core.GetNameVal(smask); isImage || name != "None" {
+			v.addViolation(ruleTransparency, num, "soft mask (SMask)")
+		}
+	}
+	for _, key := range []core.PdfObjectName{"CA", "ca"} {
+		if alpha, err := core.GetNumberAsFloat(core.TraceToDirectObject(dict.Get(key))); err == nil && alpha != 1 {
+			v.addViolation(ruleTransparency, num, "constant alpha %s %v", key, alpha)
+		}
+	}
+	if bm, ok := core.GetNameVal(dict.Get("BM")); ok && bm != "Normal" && bm != "Compatible" {
+		v.addViolation(ruleTransparency, num, "blend mode %s", bm)
+	}
+	if group, ok := core.GetDict(dict.Get("Group")); ok {
+		if s, _ := core.GetNameVal(group.Get("S")); s == "Transparency" {
+			v.addViolation(ruleTransparency, num, "transparency group")
+		}
+	}
+}
+
+// checkStream checks the filters of `stream` with object number `num`, and the colors used by
+// its content if it is a content stream.
+func (v *validator) checkStream(num int, stream *core.PdfObjectStream) {
+	filters := []core.PdfObject{stream.Get("Filter")}
+	if arr, ok := core.GetArray(stream.Get("Filter")); ok {
+		filters = arr.Elements()
+	}
+	for _, filter := range filters {
+		if name, _ := core.GetNameVal(filter); name == core.StreamEncodingFilterNameLZW {
+			v.addViolation(ruleLZW, num, "LZWDecode filter")
+		}
+	}
+
+	subtype, _ := core.GetNameVal(stream.Get("Subtype"))
+	patternType, _ := core.GetIntVal(stream.Get("PatternType"))
+	if !v.contents[num] && subtype != "Form" && patternType != 1 {
+		return
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode content stream %d: %v", num, err)
+		return
+	}
+	ops, err := contentstream.NewContentStreamParser(string(data)).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to parse content stream %d: %v", num, err)
+		return
+	}
+	for _, op := range *ops {
+		switch op.Operand {
+		case "g", "G":
+			v.addDeviceColor(num, "DeviceGray")
+		case "rg", "RG":
+			v.addDeviceColor(num, "DeviceRGB")
+		case "k", "K":
+			v.addDeviceColor(num, "DeviceCMYK")
+		case "cs", "CS":
+			if len(op.Params) == 1 {
+				v.addDeviceColors(num, op.Params[0])
+			}
+		case "BI":
+			if len(op.Params) != 1 {
+				continue
+			}
+			if img, ok := op.Params[0].(*contentstream.ContentStreamInlineImage); ok {
+				cs, _ := core.GetNameVal(img.ColorSpace)
+				if abbr, has := inlineColorSpaces[cs]; has {
+					cs = abbr
+				}
+				v.addDeviceColor(num, cs)
+			}
+		}
+	}
+}
+
+// inlineColorSpaces maps the abbreviated color space names of inline images to their full names.
+var inlineColorSpaces = map[string]string{
+	"G":    "DeviceGray",
+	"RGB":  "DeviceRGB",
+	"CMYK": "DeviceCMYK",
+}
+
+// addDeviceColors records the device color spaces of color space `obj` used by object `num`.
+// Color space arrays and color space resource dictionaries are searched for device color spaces
+// used as base or alternate color spaces.
+func (v *validator) addDeviceColors(num int, obj core.PdfObject) {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectName:
+		v.addDeviceColor(num, string(*t))
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			v.addDeviceColors(num, o)
+		}
+	case *core.PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			v.addDeviceColors(num, t.Get(key))
+		}
+	}
+}
+
+// addDeviceColor records the use of color space `cs` by object `num` if it is a device color
+// space.
+func (v *validator) addDeviceColor(num int, cs string) {
+	switch cs {
+	case "DeviceGray", "DeviceRGB", "DeviceCMYK":
+		if _, has := v.deviceColors[cs]; !has {
+			v.deviceColors[cs] = num
+		}
+	}
+}
+
+// checkOutputIntents checks the PDF/A output intent of the document and that the device color
+// spaces used are compatible with it.
+func (v *validator) checkOutputIntents() {
+	if !v.checks(ruleOutputIntent) {
+		return
+	}
+
+	// Number of color components of the output intent, 0 if none.
+	components := 0
+	var profile core.PdfObject
+	intents, _ := core.GetArray(v.catalog.Get("OutputIntents"))
+	for _, obj := range intents.Elements() {
+		num := objectNumber(obj)
+		intent, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		if s, _ := core.GetNameVal(intent.Get("S")); s != "GTS_PDFA1" {
+			continue
+		}
+		destProfile := core.ResolveReference(intent.Get("DestOutputProfile"))
+		if profile != nil {
+			if destProfile != profile {
+				v.addViolation(ruleOutputIntent, num, "PDF/A output intents have different destination profiles")
+			}
+			continue
+		}
+		profile = destProfile
+
+		if _, ok := core.GetString(intent.Get("OutputConditionIdentifier")); !ok {
+			v.addViolation(ruleOutputIntent, num, "output intent has no OutputConditionIdentifier")
+		}
+		stream, ok := core.GetStream(destProfile)
+		if !ok {
+			v.addViolation(ruleOutputIntent, num, "output intent has no destination profile (DestOutputProfile)")
+			continue
+		}
+		if n := objectNumber(intent.Get("DestOutputProfile")); n != 0 {
+			num = n
+		}
+		data, err := core.DecodeStream(stream)
+		if err != nil || len(data) < 128 || string(data[36:40]) != "acsp" {
+			v.addViolation(ruleOutputIntent, num, "destination profile is not a valid ICC profile")
+			continue
+		}
+		if class := string(data[12:16]); class != "prtr" && class != "mntr" {
+			v.addViolation(ruleOutputIntent, num, "destination profile has device class %q, expected output or display", class)
+		}
+		maxVersion := 4
+		if v.profile == PdfA1B {
+			maxVersion = 2
+		}
+		if version := int(data[8]); version > maxVersion {
+			v.addViolation(ruleOutputIntent, num, "destination profile has ICC version %d, at most %d allowed", version, maxVersion)
+		}
+		switch string(data[16:20]) {
+		case "GRAY":
+			components = 1
+		case "RGB ":
+			components = 3
+		case "CMYK":
+			components = 4
+		default:
+			v.addViolation(ruleOutputIntent, num, "destination profile has unsupported color space %q", data[16:20])
+		}
+		if n, ok := core.GetIntVal(stream.Get("N")); ok && components != 0 && n != components {
+			v.addViolation(ruleOutputIntent, num, "destination profile has N %d, expected %d", n, components)
+		}
+	}
+
+	if num, used := v.deviceColors["DeviceRGB"]; used && components != 3 {
+		v.addViolation(ruleDeviceColor, num, "DeviceRGB is used without RGB output intent")
+	}
+	if num, used := v.deviceColors["DeviceCMYK"]; used && components != 4 {
+		v.addViolation(ruleDeviceColor, num, "DeviceCMYK is used without CMYK output intent")
+	}
+	if num, used := v.deviceColors["DeviceGray"]; used && components == 0 {
+		v.addViolation(ruleDeviceColor, num, "DeviceGray is used without output intent")
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package validate
+
+import (
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// standardStructureTypes are the standard structure types (Section 14.8.4).
+var standardStructureTypes = map[string]bool{
+	"Document": true, "Part": true, "Art": true, "Sect": true, "Div": true, "BlockQuote": true,
+	"Caption": true, "TOC": true, "TOCI": true, "Index": true, "NonStruct": true, "Private": true,
+	"P": true, "H": true, "H1": true, "H2": true, "H3": true, "H4": true, "H5": true, "H6": true,
+	"L": true, "LI": true, "Lbl": true, "LBody": true,
+	"Table": true, "TR": true, "TH": true, "TD": true, "THead": true, "TBody": true, "TFoot": true,
+	"Span": true, "Quote": true, "Note": true, "Reference": true, "BibEntry": true, "Code": true,
+	"Link": true, "Annot": true, "Ruby": true, "RB": true, "RT": true, "RP": true,
+	"Warichu": true, "WT": true, "WP": true, "Figure": true, "Formula": true, "Form": true,
+}
+
+// checkStructure checks the logical structure of the document for PDF/UA: the document must be
+// tagged, declare its language and use standard structure types, and figures need alternate
+// descriptions.
+func (v *validator) checkStructure() error {
+	marked := false
+	if markInfo, ok := core.GetDict(v.catalog.Get("MarkInfo")); ok {
+		marked, _ = core.GetBoolVal(markInfo.Get("Marked"))
+	}
+	if !marked {
+		v.addViolation(ruleMarked, 0, "document is not marked as tagged (MarkInfo Marked)")
+	}
+	if lang, ok := core.GetString(v.catalog.Get("Lang")); !ok || lang.Decoded() == "" {
+		v.addViolation(ruleLang, 0, "catalog has no natural language (Lang)")
+	}
+
+	obj := v.catalog.Get("StructTreeRoot")
+	rootDict, ok := core.GetDict(obj)
+	if !ok {
+		v.addViolation(ruleStructTree, 0, "document has no structure tree (StructTreeRoot)")
+		return nil
+	}
+	root, err := v.reader.GetStructTreeRoot()
+	if err != nil {
+		return err
+	}
+
+	visited := map[*core.PdfObjectDictionary]bool{}
+	var walk func(obj core.PdfObject, num int)
+	walk = func(obj core.PdfObject, num int) {
+		if arr, ok := core.GetArray(obj); ok {
+			for _, o := range arr.Elements() {
+				walk(o, num)
+			}
+			return
+		}
+		dict, ok := core.GetDict(obj)
+		if !ok || visited[dict] {
+			return
+		}
+		visited[dict] = true
+		if n := objectNumber(obj); n != 0 {
+			num = n
+		}
+		s, ok := core.GetNameVal(dict.Get("S"))
+		if !ok {
+			// Marked-content and object references.
+			return
+		}
+
+		typ := root.StandardType(s)
+		if !standardStructureTypes[typ] {
+			v.addViolation(ruleStandardType, num, "structure type %s is not mapped to a standard type", s)
+		}
+		if typ == "Figure" && dict.Get("Alt") == nil && dict.Get("ActualText") == nil {
+			v.addViolation(ruleFigureAlt, num, "%s element has no alternate description (Alt)", s)
+		}
+		walk(dict.Get("K"), num)
+	}
+	walk(rootDict.Get("K"), objectNumber(obj))
+	return nil
+}
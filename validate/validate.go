@@ -0,0 +1,275 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package validate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Profile is a conformance profile that documents can be validated against.
+type Profile int
+
+// Validation profiles.
+const (
+	PdfA1B Profile = iota + 1 // PDF/A-1b, ISO 19005-1 level B.
+	PdfA2B                    // PDF/A-2b, ISO 19005-2 level B.
+	PdfA3B                    // PDF/A-3b, ISO 19005-3 level B.
+	PdfUA1                    // PDF/UA-1, ISO 14289-1.
+)
+
+// String returns the name of the profile, e.g. "PDF/A-1b".
+func (p Profile) String() string {
+	switch p {
+	case PdfA1B:
+		return "PDF/A-1b"
+	case PdfA2B:
+		return "PDF/A-2b"
+	case PdfA3B:
+		return "PDF/A-3b"
+	case PdfUA1:
+		return "PDF/UA-1"
+	}
+	return fmt.Sprintf("Profile(%d)", int(p))
+}
+
+// pdfaPart returns the part of ISO 19005 of PDF/A profiles, 0 for other profiles.
+func (p Profile) pdfaPart() int {
+	switch p {
+	case PdfA1B:
+		return 1
+	case PdfA2B:
+		return 2
+	case PdfA3B:
+		return 3
+	}
+	return 0
+}
+
+// standard returns the designation of the standard of the profile.
+func (p Profile) standard() string {
+	switch p {
+	case PdfA1B:
+		return "ISO 19005-1:2005"
+	case PdfA2B:
+		return "ISO 19005-2:2011"
+	case PdfA3B:
+		return "ISO 19005-3:2012"
+	case PdfUA1:
+		return "ISO 14289-1:2014"
+	}
+	return ""
+}
+
+// Violation is a requirement of a profile that a document does not meet.
+type Violation struct {
+	// Clause is the clause of the standard defining the requirement, e.g. "ISO 19005-1:2005 6.3.4".
+	Clause string
+
+	// ObjectNumber is the number of the indirect object violating the requirement, 0 if the
+	// violation does not concern a specific object or the object is direct in the trailer.
+	ObjectNumber int
+
+	// Message describes the violation.
+	Message string
+}
+
+// String returns a description of the violation with its clause and object number.
+func (v Violation) String() string {
+	if v.ObjectNumber == 0 {
+		return fmt.Sprintf("%s: %s", v.Clause, v.Message)
+	}
+	return fmt.Sprintf("%s: object %d: %s", v.Clause, v.ObjectNumber, v.Message)
+}
+
+// Report is the result of the validation of a document against a profile.
+type Report struct {
+	Profile Profile
+
+	// Violations are the violations found, ordered by the check and object number.
+	Violations []Violation
+}
+
+// Compliant returns true if no violations were found.
+func (r *Report) Compliant() bool {
+	return len(r.Violations) == 0
+}
+
+// rule identifies a requirement checked by the validator.
+type rule int
+
+const (
+	ruleFileID rule = iota
+	ruleEncryption
+	ruleLZW
+	ruleEmbeddedFiles
+	ruleOutputIntent
+	ruleDeviceColor
+	ruleTransparency
+	ruleFontEmbedded
+	ruleJavaScript
+	ruleMetadata
+	ruleMetadataInfo
+	rulePdfAID
+	rulePdfUAID
+	ruleMarked
+	ruleStructTree
+	ruleStandardType
+	ruleTitle
+	ruleLang
+	ruleFigureAlt
+)
+
+// Clauses of the requirements by profile. Rules without clause are not checked for the profile.
+var (
+	pdfa1Clauses = map[rule]string{
+		ruleFileID:        "6.1.3",
+		ruleEncryption:    "6.1.3",
+		ruleLZW:           "6.1.10",
+		ruleEmbeddedFiles: "6.1.11",
+		ruleOutputIntent:  "6.2.2",
+		ruleDeviceColor:   "6.2.3.3",
+		ruleFontEmbedded:  "6.3.4",
+		ruleTransparency:  "6.4",
+		ruleJavaScript:    "6.6.1",
+		ruleMetadata:      "6.7.2",
+		ruleMetadataInfo:  "6.7.3",
+		rulePdfAID:        "6.7.11",
+	}
+	pdfa2Clauses = map[rule]string{
+		ruleFileID:        "6.1.3",
+		ruleEncryption:    "6.1.3",
+		ruleLZW:           "6.1.7.2",
+		ruleOutputIntent:  "6.2.3",
+		ruleDeviceColor:   "6.2.4.3",
+		ruleFontEmbedded:  "6.2.11.4.1",
+		ruleJavaScript:    "6.6.1",
+		ruleMetadata:      "6.6.2.1",
+		ruleMetadataInfo:  "6.6.3",
+		rulePdfAID:        "6.6.4",
+		ruleEmbeddedFiles: "6.8",
+	}
+	pdfua1Clauses = map[rule]string{
+		rulePdfUAID:      "5",
+		ruleMarked:       "7.1",
+		ruleStructTree:   "7.1",
+		ruleStandardType: "7.1",
+		ruleTitle:        "7.1",
+		ruleLang:         "7.2",
+		ruleFigureAlt:    "7.3",
+		ruleFontEmbedded: "7.21.4.1",
+	}
+)
+
+// Validate checks the document of `r` against `profile` and returns the violations found.
+// Encrypted documents must be decrypted first.
+func Validate(r *model.PdfReader, profile Profile) (*Report, error) {
+	var clauses map[rule]string
+	switch profile {
+	case PdfA1B:
+		clauses = pdfa1Clauses
+	case PdfA2B, PdfA3B:
+		clauses = pdfa2Clauses
+	case PdfUA1:
+		clauses = pdfua1Clauses
+	default:
+		return nil, fmt.Errorf("unsupported profile %s", profile)
+	}
+
+	trailer, err := r.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	catalog, ok := core.GetDict(trailer.Get("Root"))
+	if !ok {
+		common.Log.Debug("ERROR: Missing catalog: %v", trailer.Get("Root"))
+		return nil, errors.New("missing catalog")
+	}
+
+	v := &validator{
+		reader:     r,
+		profile:    profile,
+		clauses:    clauses,
+		trailer:    trailer,
+		catalog:    catalog,
+		catalogNum: objectNumber(trailer.Get("Root")),
+		contents:   map[int]bool{},
+	}
+	v.checkTrailer()
+	v.checkObjects()
+	v.checkMetadata()
+	if profile == PdfUA1 {
+		if err := v.checkStructure(); err != nil {
+			return nil, err
+		}
+	}
+	return &Report{Profile: profile, Violations: v.violations}, nil
+}
+
+// validator checks a document against a profile.
+type validator struct {
+	reader  *model.PdfReader
+	profile Profile
+	clauses map[rule]string
+
+	trailer    *core.PdfObjectDictionary
+	catalog    *core.PdfObjectDictionary
+	catalogNum int
+
+	// Numbers of the page content streams.
+	contents map[int]bool
+	// Device color spaces used, mapped to the number of the first object using them.
+	deviceColors map[string]int
+
+	violations []Violation
+}
+
+// checks returns true if `rule` is checked for the profile.
+func (v *validator) checks(rule rule) bool {
+	_, has := v.clauses[rule]
+	return has
+}
+
+// addViolation records a violation of `rule` by object number `objNum`.
+func (v *validator) addViolation(rule rule, objNum int, format string, args ...interface{}) {
+	clause, has := v.clauses[rule]
+	if !has {
+		return
+	}
+	v.violations = append(v.violations, Violation{
+		Clause:       v.profile.standard() + " " + clause,
+		ObjectNumber: objNum,
+		Message:      fmt.Sprintf(format, args...),
+	})
+}
+
+// objectNumber returns the object number of indirect object or reference `obj`, 0 for direct
+// objects.
+func objectNumber(obj core.PdfObject) int {
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		return int(t.ObjectNumber)
+	case *core.PdfIndirectObject:
+		return int(t.ObjectNumber)
+	case *core.PdfObjectStream:
+		return int(t.ObjectNumber)
+	}
+	return 0
+}
+
+// checkTrailer checks the file identifiers and the encryption of the document.
+func (v *validator) checkTrailer() {
+	if v.trailer.Get("Encrypt") != nil {
+		v.addViolation(ruleEncryption, objectNumber(v.trailer.Get("Encrypt")), "document is encrypted")
+	}
+	if ids, ok := core.GetArray(v.trailer.Get("ID")); !ok || ids.Len() != 2 {
+		v.addViolation(ruleFileID, 0, "trailer has no file identifier (ID)")
+	}
+}
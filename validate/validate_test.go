@@ -0,0 +1,130 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package validate
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/creator"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// validateOutput writes `w` and validates the output against `profile`.
+func validateOutput(t *testing.T, w *model.PdfWriter, profile Profile) *Report {
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	report, err := Validate(reader, profile)
+	require.NoError(t, err)
+	return report
+}
+
+// violationStrings returns the descriptions of the violations of `report`.
+func violationStrings(report *Report) []string {
+	var s []string
+	for _, v := range report.Violations {
+		s = append(s, v.String())
+	}
+	return s
+}
+
+func TestValidatePdfA(t *testing.T) {
+	w := model.NewPdfWriter()
+	w.SetPdfAConformance(model.PdfAConformance2B)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	report, err := Validate(reader, PdfA2B)
+	require.NoError(t, err)
+	require.True(t, report.Compliant(), "%v", violationStrings(report))
+
+	// The metadata identify the document as PDF/A-2b.
+	report, err = Validate(reader, PdfA1B)
+	require.NoError(t, err)
+	require.Len(t, report.Violations, 1)
+	require.Equal(t, "ISO 19005-1:2005 6.7.11", report.Violations[0].Clause)
+	require.Contains(t, report.Violations[0].Message, "pdfaid:part is 2, expected 1")
+}
+
+func TestValidatePdfAViolations(t *testing.T) {
+	w := model.NewPdfWriter()
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+	gs := core.MakeDict()
+	gs.Set("BM", core.MakeName("Multiply"))
+	require.NoError(t, page.Resources.AddExtGState("GS0", gs))
+	page.AddContentStreamByString("/GS0 gs 0 0 0 1 k 0 0 100 100 re f")
+	require.NoError(t, w.AddPage(page))
+	w.SetNameTree("JavaScript", model.NewPdfNameTreeBuilder())
+
+	report := validateOutput(t, &w, PdfA1B)
+	violations := map[string]Violation{}
+	for _, v := range report.Violations {
+		violations[v.Message] = v
+	}
+	for _, expected := range []struct {
+		clause, message string
+		hasObject       bool
+	}{
+		{"6.1.3", "trailer has no file identifier (ID)", false},
+		{"6.3.4", "font Helvetica is not embedded", true},
+		{"6.4", "blend mode Multiply", true},
+		{"6.6.1", "names dictionary has JavaScript", true},
+		{"6.2.3.3", "DeviceRGB is used without RGB output intent", true},
+		{"6.2.3.3", "DeviceCMYK is used without CMYK output intent", true},
+		{"6.7.2", "catalog has no metadata stream", false},
+	} {
+		v, has := violations[expected.message]
+		require.True(t, has, "%s: %v", expected.message, violationStrings(report))
+		require.Equal(t, "ISO 19005-1:2005 "+expected.clause, v.Clause)
+		require.Equal(t, expected.hasObject, v.ObjectNumber != 0, v.String())
+	}
+
+	// Transparency is allowed in PDF/A-2.
+	for _, v := range validateOutput(t, &w, PdfA2B).Violations {
+		require.NotEqual(t, "blend mode Multiply", v.Message)
+	}
+}
+
+func TestValidatePdfUA(t *testing.T) {
+	c := creator.New()
+	c.Tagged = true
+	require.NoError(t, c.Draw(c.NewParagraph("Statement")))
+	img, err := c.NewImageFromFile("../creator/testdata/logo.png")
+	require.NoError(t, err)
+	require.NoError(t, c.Draw(img))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	report, err := Validate(reader, PdfUA1)
+	require.NoError(t, err)
+
+	violations := violationStrings(report)
+	for _, expected := range []string{
+		"ISO 14289-1:2014 7.2: catalog has no natural language (Lang)",
+		"ISO 14289-1:2014 5: catalog has no metadata stream",
+		"ISO 14289-1:2014 7.1: catalog has no metadata stream",
+	} {
+		require.Contains(t, violations, expected)
+	}
+	var figureViolation bool
+	for _, v := range report.Violations {
+		if v.Message == "Figure element has no alternate description (Alt)" {
+			figureViolation = v.Clause == "ISO 14289-1:2014 7.3" && v.ObjectNumber != 0
+		}
+		require.NotEqual(t, "ISO 14289-1:2014 7.1: document is not marked as tagged (MarkInfo Marked)", v.String())
+	}
+	require.True(t, figureViolation, "%v", violations)
+}